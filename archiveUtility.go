@@ -0,0 +1,616 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"go-image-board/storage"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+//archiveFormat identifies archives written by -export, archiveVersion is incremented whenever their layout changes
+const (
+	archiveFormat  = "go-image-board archive"
+	archiveVersion = 1
+)
+
+//Files in an archive. Records are held one JSON object per line
+const (
+	archiveManifestFile          = "manifest.json"
+	archiveUsersFile             = "users.jsonl"
+	archiveTagsFile              = "tags.jsonl"
+	archiveImagesFile            = "images.jsonl"
+	archiveImageTagsFile         = "image_tags.jsonl"
+	archiveVotesFile             = "votes.jsonl"
+	archiveCollectionsFile       = "collections.jsonl"
+	archiveCollectionMembersFile = "collection_members.jsonl"
+	archiveAuditLogsFile         = "audit_logs.jsonl"
+	//archiveFilesDirectory holds images and thumbnails under the names they have in storage
+	archiveFilesDirectory = "files/"
+)
+
+//archiveManifest describes an archive, and how many records of each kind it holds
+type archiveManifest struct {
+	Format             string
+	Version            int
+	ApplicationVersion string
+	Created            time.Time
+	IncludesSecrets    bool
+	Records            map[string]uint64
+}
+
+//archiveTag is a tag or alias as held in an archive
+type archiveTag struct {
+	ID          uint64
+	Name        string
+	Description string
+	UploaderID  uint64
+	UploadTime  time.Time
+	AliasedID   uint64
+	IsAlias     bool
+}
+
+//archiveImage is an image as held in an archive, its file is held under its Location
+type archiveImage struct {
+	ID          uint64
+	Name        string
+	Location    string
+	Description string
+	UploaderID  uint64
+	UploadTime  time.Time
+	Rating      string
+	Source      string
+	HasdHash    bool
+	HHash       uint64
+	VHash       uint64
+	//Media info is zero in archives made before it was recorded, the media-info job fills it in after restoring
+	Width    uint64
+	Height   uint64
+	FileSize uint64
+	MIMEType string
+	Duration float64
+	Bitrate  uint64
+	//Metadata is nil if the image has none
+	Metadata *interfaces.ImageMetadata
+	//BlurHash and Colors are empty in archives made before they were recorded, the dHash job fills them in after restoring
+	BlurHash string
+	Colors   []interfaces.ImageColor
+}
+
+//archiveCollection is a collection as held in an archive
+type archiveCollection struct {
+	ID          uint64
+	Name        string
+	Description string
+	UploaderID  uint64
+	UploadTime  time.Time
+}
+
+//archiveWriter adds records and files to an archive, counting the records in its manifest
+type archiveWriter struct {
+	zipWriter *zip.Writer
+	manifest  archiveManifest
+}
+
+//writeRecords adds a file of records to the archive, Write is given a function that adds one record
+func (Archive *archiveWriter) writeRecords(Name string, Write func(Record func(interface{}) error) error) error {
+	entry, err := Archive.zipWriter.CreateHeader(&zip.FileHeader{Name: Name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(entry)
+	Archive.manifest.Records[Name] = 0
+	return Write(func(Value interface{}) error {
+		Archive.manifest.Records[Name]++
+		return encoder.Encode(Value)
+	})
+}
+
+//writeFile copies a file from storage into the archive, without compression as images already are compressed
+func (Archive *archiveWriter) writeFile(Name string) error {
+	file, err := storage.StorageInterface.Open(Name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	fileInfo, err := storage.StorageInterface.Stat(Name)
+	if err != nil {
+		return err
+	}
+	entry, err := Archive.zipWriter.CreateHeader(&zip.FileHeader{Name: archiveFilesDirectory + Name, Method: zip.Store, Modified: fileInfo.ModTime})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, file)
+	return err
+}
+
+//forEachImage calls Process with every image in DB, one page at a time
+func forEachImage(DB interfaces.DBInterface, Process func(interfaces.ImageInformation) error) error {
+	for count, maxCount := uint64(0), uint64(1); count < maxCount; count += config.Configuration.PageStride {
+		var images []interfaces.ImageInformation
+		var err error
+		images, maxCount, err = DB.SearchImages(nil, count, config.Configuration.PageStride)
+		if err != nil {
+			return err
+		}
+		for _, imageInfo := range images {
+			if err := Process(imageInfo); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//forEachCollection calls Process with every collection in DB, one page at a time
+func forEachCollection(DB interfaces.DBInterface, Process func(interfaces.CollectionInformation) error) error {
+	for count, maxCount := uint64(0), uint64(1); count < maxCount; count += config.Configuration.PageStride {
+		var collections []interfaces.CollectionInformation
+		var err error
+		collections, maxCount, err = DB.GetCollections(count, config.Configuration.PageStride)
+		if err != nil {
+			return err
+		}
+		for _, collectionInfo := range collections {
+			if err := Process(collectionInfo); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//exportArchive writes every user, tag, image, collection, vote, and audit log, along with the images and thumbnails, to one archive at ArchivePath
+//Password hashes and security answers are only included when IncludeSecrets is set
+func exportArchive(ArchivePath string, IncludeSecrets bool) {
+	logging.WriteLog(logging.LogLevelInfo, "archiveUtility/exportArchive", "0", logging.ResultInfo, []string{"Exporting to", ArchivePath, "including secrets", strconv.FormatBool(IncludeSecrets)})
+	//Write next to the destination first, so a failed export does not replace a previous one
+	temporaryPath := ArchivePath + ".tmp"
+	manifest, err := writeArchive(temporaryPath, IncludeSecrets)
+	if err == nil {
+		err = os.Rename(temporaryPath, ArchivePath)
+	}
+	if err != nil {
+		os.Remove(temporaryPath)
+		logging.WriteLog(logging.LogLevelError, "archiveUtility/exportArchive", "0", logging.ResultFailure, []string{"Export failed", err.Error()})
+		return
+	}
+	logging.WriteLog(logging.LogLevelInfo, "archiveUtility/exportArchive", "0", logging.ResultSuccess, []string{"Finished export.", describeArchiveRecords(manifest)})
+}
+
+//writeArchive writes the archive for exportArchive, and returns its manifest
+func writeArchive(ArchivePath string, IncludeSecrets bool) (archiveManifest, error) {
+	archiveFile, err := os.OpenFile(ArchivePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0660)
+	if err != nil {
+		return archiveManifest{}, err
+	}
+	defer archiveFile.Close()
+	archive := archiveWriter{
+		zipWriter: zip.NewWriter(archiveFile),
+		manifest:  archiveManifest{Format: archiveFormat, Version: archiveVersion, ApplicationVersion: config.ApplicationVersion, Created: time.Now().UTC(), IncludesSecrets: IncludeSecrets, Records: make(map[string]uint64)},
+	}
+
+	err = archive.writeRecords(archiveUsersFile, func(Record func(interface{}) error) error {
+		for count, maxCount := uint64(0), uint64(1); count < maxCount; count += config.Configuration.PageStride {
+			var users []interfaces.UserBackup
+			users, maxCount, err = database.DBInterface.GetUserBackups(count, config.Configuration.PageStride)
+			if err != nil {
+				return err
+			}
+			for _, user := range users {
+				if IncludeSecrets == false {
+					user.PasswordHash = ""
+					user.SecQuestionOne, user.SecQuestionTwo, user.SecQuestionThree = "", "", ""
+					user.SecAnswerOne, user.SecAnswerTwo, user.SecAnswerThree = "", "", ""
+				}
+				if err := Record(user); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return archive.manifest, err
+	}
+
+	err = archive.writeRecords(archiveTagsFile, func(Record func(interface{}) error) error {
+		tags, err := database.DBInterface.GetAllTags()
+		if err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			//GetAllTags does not include the uploader or alias
+			tagInfo, err := database.DBInterface.GetTag(tag.ID, false)
+			if err != nil {
+				return err
+			}
+			if err := Record(archiveTag{ID: tag.ID, Name: tagInfo.Name, Description: tagInfo.Description, UploaderID: tagInfo.UploaderID, UploadTime: tagInfo.UploadTime, AliasedID: tagInfo.AliasedID, IsAlias: tagInfo.IsAlias}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return archive.manifest, err
+	}
+
+	err = archive.writeRecords(archiveImagesFile, func(Record func(interface{}) error) error {
+		return forEachImage(database.DBInterface, func(imageInfo interfaces.ImageInformation) error {
+			//Search results do not include the description
+			imageInfo, err := database.DBInterface.GetImage(imageInfo.ID)
+			if err != nil {
+				return err
+			}
+			record := archiveImage{ID: imageInfo.ID, Name: imageInfo.Name, Location: imageInfo.Location, Description: imageInfo.Description, UploaderID: imageInfo.UploaderID, UploadTime: imageInfo.UploadTime, Rating: imageInfo.Rating, Source: imageInfo.Source, Width: imageInfo.Width, Height: imageInfo.Height, FileSize: imageInfo.FileSize, MIMEType: imageInfo.MIMEType, Duration: imageInfo.Duration, Bitrate: imageInfo.Bitrate, BlurHash: imageInfo.BlurHash}
+			if hHash, vHash, err := database.DBInterface.GetImagedHash(imageInfo.ID); err == nil {
+				record.HasdHash, record.HHash, record.VHash = true, hHash, vHash
+			}
+			if metadata, err := database.DBInterface.GetImageMetadata(imageInfo.ID); err == nil {
+				record.Metadata = &metadata
+			}
+			if record.Colors, err = database.DBInterface.GetImageColors(imageInfo.ID); err != nil {
+				return err
+			}
+			return Record(record)
+		})
+	})
+	if err != nil {
+		return archive.manifest, err
+	}
+
+	err = archive.writeRecords(archiveImageTagsFile, func(Record func(interface{}) error) error {
+		return forEachImage(database.DBInterface, func(imageInfo interfaces.ImageInformation) error {
+			links, err := database.DBInterface.GetImageTagLinks(imageInfo.ID)
+			if err != nil {
+				return err
+			}
+			for _, link := range links {
+				if err := Record(link); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return archive.manifest, err
+	}
+
+	err = archive.writeRecords(archiveVotesFile, func(Record func(interface{}) error) error {
+		return forEachImage(database.DBInterface, func(imageInfo interfaces.ImageInformation) error {
+			votes, err := database.DBInterface.GetImageVotes(imageInfo.ID)
+			if err != nil {
+				return err
+			}
+			for _, vote := range votes {
+				if err := Record(vote); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return archive.manifest, err
+	}
+
+	err = archive.writeRecords(archiveCollectionsFile, func(Record func(interface{}) error) error {
+		return forEachCollection(database.DBInterface, func(collectionInfo interfaces.CollectionInformation) error {
+			//Collection lists do not include the uploader
+			collectionInfo, err := database.DBInterface.GetCollection(collectionInfo.ID)
+			if err != nil {
+				return err
+			}
+			return Record(archiveCollection{ID: collectionInfo.ID, Name: collectionInfo.Name, Description: collectionInfo.Description, UploaderID: collectionInfo.UploaderID, UploadTime: collectionInfo.UploadTime})
+		})
+	})
+	if err != nil {
+		return archive.manifest, err
+	}
+
+	err = archive.writeRecords(archiveCollectionMembersFile, func(Record func(interface{}) error) error {
+		return forEachCollection(database.DBInterface, func(collectionInfo interfaces.CollectionInformation) error {
+			links, err := database.DBInterface.GetCollectionMemberLinks(collectionInfo.ID)
+			if err != nil {
+				return err
+			}
+			for _, link := range links {
+				if err := Record(link); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return archive.manifest, err
+	}
+
+	err = archive.writeRecords(archiveAuditLogsFile, func(Record func(interface{}) error) error {
+		for count, maxCount := uint64(0), uint64(1); count < maxCount; count += config.Configuration.PageStride {
+			var auditLogs []interfaces.AuditLogInformation
+			auditLogs, maxCount, err = database.DBInterface.GetAuditLogs(count, config.Configuration.PageStride)
+			if err != nil {
+				return err
+			}
+			for _, auditLog := range auditLogs {
+				if err := Record(auditLog); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return archive.manifest, err
+	}
+
+	//Files are written last, so the records can be read without passing over them
+	err = forEachImage(database.DBInterface, func(imageInfo interfaces.ImageInformation) error {
+		if err := archive.writeFile(imageInfo.Location); err != nil {
+			logging.WriteLog(logging.LogLevelWarning, "archiveUtility/writeArchive", "0", logging.ResultFailure, []string{"Image file not exported", imageInfo.Location, err.Error()})
+			return nil
+		}
+		if exists, _ := storage.Exists(storage.ThumbnailName(imageInfo.Location)); exists {
+			return archive.writeFile(storage.ThumbnailName(imageInfo.Location))
+		}
+		return nil
+	})
+	if err != nil {
+		return archive.manifest, err
+	}
+
+	manifestEntry, err := archive.zipWriter.Create(archiveManifestFile)
+	if err != nil {
+		return archive.manifest, err
+	}
+	encoder := json.NewEncoder(manifestEntry)
+	encoder.SetIndent("", "\t")
+	if err := encoder.Encode(archive.manifest); err != nil {
+		return archive.manifest, err
+	}
+	if err := archive.zipWriter.Close(); err != nil {
+		return archive.manifest, err
+	}
+	return archive.manifest, archiveFile.Sync()
+}
+
+//describeArchiveRecords lists how many records of each kind an archive holds
+func describeArchiveRecords(Manifest archiveManifest) string {
+	ToReturn := ""
+	for _, name := range []string{archiveUsersFile, archiveTagsFile, archiveImagesFile, archiveImageTagsFile, archiveVotesFile, archiveCollectionsFile, archiveCollectionMembersFile, archiveAuditLogsFile} {
+		ToReturn += name + ": " + strconv.FormatUint(Manifest.Records[name], 10) + " "
+	}
+	return ToReturn[:len(ToReturn)-1]
+}
+
+//readArchiveRecords calls Restore with each record in one of an archive's files, in order
+func readArchiveRecords[T any](Archive *zip.ReadCloser, Name string, Restore func(T) error) error {
+	entry, err := Archive.Open(Name)
+	if err != nil {
+		return err
+	}
+	defer entry.Close()
+	decoder := json.NewDecoder(entry)
+	for {
+		var record T
+		if err := decoder.Decode(&record); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errors.New(Name + ": " + err.Error())
+		}
+		if err := Restore(record); err != nil {
+			return err
+		}
+	}
+}
+
+//importArchive restores an archive written by exportArchive into an empty board, using whichever database and storage are configured
+//Users in an archive without secrets are given TemporaryPassword, and have no security questions
+func importArchive(ArchivePath string, TemporaryPassword string) {
+	logging.WriteLog(logging.LogLevelInfo, "archiveUtility/importArchive", "0", logging.ResultInfo, []string{"Restoring from", ArchivePath})
+	manifest, err := restoreArchive(ArchivePath, TemporaryPassword)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "archiveUtility/importArchive", "0", logging.ResultFailure, []string{"Restore failed", err.Error()})
+		return
+	}
+	logging.WriteLog(logging.LogLevelInfo, "archiveUtility/importArchive", "0", logging.ResultSuccess, []string{"Finished restore.", describeArchiveRecords(manifest)})
+}
+
+//checkBoardEmpty returns an error if DB already has users, images, tags, or collections, which restored IDs could collide with
+func checkBoardEmpty(DB interfaces.DBInterface) error {
+	_, userCount, err := DB.GetUserBackups(0, 1)
+	if err != nil {
+		return err
+	}
+	_, imageCount, err := DB.SearchImages(nil, 0, 1)
+	if err != nil {
+		return err
+	}
+	tags, err := DB.GetAllTags()
+	if err != nil {
+		return err
+	}
+	_, collectionCount, err := DB.GetCollections(0, 1)
+	if err != nil {
+		return err
+	}
+	if userCount != 0 || imageCount != 0 || len(tags) != 0 || collectionCount != 0 {
+		return errors.New("archives can only be restored into an empty board, this one has " + strconv.FormatUint(userCount, 10) + " users, " + strconv.FormatUint(imageCount, 10) + " images, " + strconv.Itoa(len(tags)) + " tags, and " + strconv.FormatUint(collectionCount, 10) + " collections")
+	}
+	return nil
+}
+
+//restoreArchive restores the archive for importArchive, and returns its manifest
+func restoreArchive(ArchivePath string, TemporaryPassword string) (archiveManifest, error) {
+	var manifest archiveManifest
+	archive, err := zip.OpenReader(ArchivePath)
+	if err != nil {
+		return manifest, err
+	}
+	defer archive.Close()
+	manifestEntry, err := archive.Open(archiveManifestFile)
+	if err != nil {
+		return manifest, errors.New("not an archive written by -export, " + err.Error())
+	}
+	err = json.NewDecoder(manifestEntry).Decode(&manifest)
+	manifestEntry.Close()
+	if err != nil {
+		return manifest, errors.New("failed to read manifest, " + err.Error())
+	}
+	if manifest.Format != archiveFormat || manifest.Version > archiveVersion {
+		return manifest, errors.New("unsupported archive " + manifest.Format + " version " + strconv.Itoa(manifest.Version))
+	}
+	if err := checkBoardEmpty(database.DBInterface); err != nil {
+		return manifest, err
+	}
+
+	temporaryHash := ""
+	if manifest.IncludesSecrets == false {
+		if TemporaryPassword == "" {
+			return manifest, errors.New("this archive was exported without passwords, use -password to give restored users a temporary one")
+		}
+		if err := database.DBInterface.ValidatePasswordStrength(TemporaryPassword); err != nil {
+			return manifest, err
+		}
+		//Same cost as the SQL plugins use for passwords
+		hash, err := bcrypt.GenerateFromPassword([]byte(TemporaryPassword), 14)
+		if err != nil {
+			return manifest, err
+		}
+		temporaryHash = string(hash)
+	}
+	err = readArchiveRecords(archive, archiveUsersFile, func(User interfaces.UserBackup) error {
+		if User.PasswordHash == "" {
+			User.PasswordHash = temporaryHash
+		}
+		return database.DBInterface.RestoreUser(User)
+	})
+	if err != nil {
+		return manifest, err
+	}
+
+	err = readArchiveRecords(archive, archiveTagsFile, func(Tag archiveTag) error {
+		return database.DBInterface.RestoreTag(interfaces.TagInformation{ID: Tag.ID, Name: Tag.Name, Description: Tag.Description, UploaderID: Tag.UploaderID, UploadTime: Tag.UploadTime, AliasedID: Tag.AliasedID, IsAlias: Tag.IsAlias})
+	})
+	if err != nil {
+		return manifest, err
+	}
+
+	err = readArchiveRecords(archive, archiveImagesFile, func(Image archiveImage) error {
+		//Files are placed to match this board's StorageLayout, which may differ from the exporting board's
+		location := storage.ImageLocation(Image.Location)
+		if err := restoreArchiveFile(archive, Image.Location, location); err != nil {
+			logging.WriteLog(logging.LogLevelWarning, "archiveUtility/restoreArchive", "0", logging.ResultFailure, []string{"Image file not restored", Image.Location, err.Error()})
+		}
+		if err := restoreArchiveFile(archive, storage.ThumbnailName(Image.Location), storage.ThumbnailName(location)); err != nil && errors.Is(err, os.ErrNotExist) == false {
+			return err
+		}
+		err := database.DBInterface.RestoreImage(interfaces.ImageInformation{ID: Image.ID, Name: Image.Name, Location: location, Description: Image.Description, UploaderID: Image.UploaderID, UploadTime: Image.UploadTime, Rating: Image.Rating, Source: Image.Source, Width: Image.Width, Height: Image.Height, FileSize: Image.FileSize, MIMEType: Image.MIMEType, Duration: Image.Duration, Bitrate: Image.Bitrate, BlurHash: Image.BlurHash})
+		if err != nil {
+			return err
+		}
+		if Image.Metadata != nil {
+			Image.Metadata.ImageID = Image.ID
+			if err := database.DBInterface.SetImageMetadata(*Image.Metadata); err != nil {
+				return err
+			}
+		}
+		if len(Image.Colors) > 0 {
+			if err := database.DBInterface.SetImageColors(Image.ID, Image.BlurHash, Image.Colors); err != nil {
+				return err
+			}
+		}
+		if Image.HasdHash == false {
+			return nil
+		}
+		return database.DBInterface.SetImagedHash(Image.ID, Image.HHash, Image.VHash)
+	})
+	if err != nil {
+		return manifest, err
+	}
+
+	//Links are restored an image or collection at a time, as they are written
+	var imageTags []interfaces.ImageTagLink
+	err = readArchiveRecords(archive, archiveImageTagsFile, func(Link interfaces.ImageTagLink) error {
+		if len(imageTags) > 0 && imageTags[0].ImageID != Link.ImageID {
+			if err := database.DBInterface.RestoreImageTags(imageTags); err != nil {
+				return err
+			}
+			imageTags = nil
+		}
+		imageTags = append(imageTags, Link)
+		return nil
+	})
+	if err == nil {
+		err = database.DBInterface.RestoreImageTags(imageTags)
+	}
+	if err != nil {
+		return manifest, err
+	}
+
+	votedImages := make(map[uint64]bool)
+	err = readArchiveRecords(archive, archiveVotesFile, func(Vote interfaces.ImageVote) error {
+		votedImages[Vote.ImageID] = true
+		return database.DBInterface.RestoreImageVote(Vote)
+	})
+	if err != nil {
+		return manifest, err
+	}
+	for votedImageID := range votedImages {
+		if err := database.DBInterface.UpdateScoreOnImage(votedImageID); err != nil {
+			return manifest, err
+		}
+	}
+
+	err = readArchiveRecords(archive, archiveCollectionsFile, func(Collection archiveCollection) error {
+		return database.DBInterface.RestoreCollection(interfaces.CollectionInformation{ID: Collection.ID, Name: Collection.Name, Description: Collection.Description, UploaderID: Collection.UploaderID, UploadTime: Collection.UploadTime})
+	})
+	if err != nil {
+		return manifest, err
+	}
+
+	var collectionMembers []interfaces.CollectionMemberLink
+	err = readArchiveRecords(archive, archiveCollectionMembersFile, func(Link interfaces.CollectionMemberLink) error {
+		if len(collectionMembers) > 0 && collectionMembers[0].CollectionID != Link.CollectionID {
+			if err := database.DBInterface.RestoreCollectionMembers(collectionMembers); err != nil {
+				return err
+			}
+			collectionMembers = nil
+		}
+		collectionMembers = append(collectionMembers, Link)
+		return nil
+	})
+	if err == nil {
+		err = database.DBInterface.RestoreCollectionMembers(collectionMembers)
+	}
+	if err != nil {
+		return manifest, err
+	}
+
+	err = readArchiveRecords(archive, archiveAuditLogsFile, func(Log interfaces.AuditLogInformation) error {
+		return database.DBInterface.RestoreAuditLog(Log)
+	})
+	return manifest, err
+}
+
+//restoreArchiveFile saves a file held in an archive to storage under a new name
+func restoreArchiveFile(Archive *zip.ReadCloser, Name string, NewName string) error {
+	entry, err := Archive.Open(archiveFilesDirectory + Name)
+	if err != nil {
+		return err
+	}
+	defer entry.Close()
+	return storage.StorageInterface.Save(NewName, entry)
+}
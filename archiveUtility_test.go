@@ -0,0 +1,141 @@
+package main
+
+import (
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/plugins/localstorageplugin"
+	"go-image-board/plugins/memoryplugin"
+	"go-image-board/storage"
+	"path/filepath"
+	"testing"
+)
+
+//resetArchiveTest replaces the database and image directory with empty ones, using Layout for new files
+func resetArchiveTest(t *testing.T, Layout string) {
+	t.Helper()
+	config.Configuration.StorageLayout = Layout
+	config.Configuration.ImageDirectory = t.TempDir()
+	storage.StorageInterface = &localstorageplugin.LocalStoragePlugin{}
+	if err := storage.StorageInterface.Init(); err != nil {
+		t.Fatalf("storage Init: %v", err)
+	}
+	database.DBInterface = &memoryplugin.MemoryPlugin{}
+	if err := database.DBInterface.InitDatabase(); err != nil {
+		t.Fatalf("InitDatabase: %v", err)
+	}
+}
+
+func TestExportImportArchive(t *testing.T) {
+	setupImportTest(t)
+	defer func() { config.Configuration.StorageLayout = "" }()
+	importPath := t.TempDir()
+	writeTestFile(t, importPath, "a.png", testPNG(t, 10))
+	writeTestFile(t, importPath, "a.png.txt", []byte("red blue\nsource: https://example.com/a\nrating: safe\n"))
+	writeTestFile(t, importPath, "set/1.png", testPNG(t, 20))
+	writeTestFile(t, importPath, "set/1.png.txt", []byte("night\ncollection: Night Set\n"))
+	writeTestFile(t, importPath, "set/2.png", testPNG(t, 30))
+	writeTestFile(t, importPath, "set/2.png.txt", []byte("night moon\ncollection: Night Set\n"))
+	importDirectory(importPath, "importer", filepath.Join(t.TempDir(), "progress.tsv"), false)
+
+	userID, err := database.DBInterface.GetUserID("importer")
+	if err != nil {
+		t.Fatalf("GetUserID: %v", err)
+	}
+	first, err := database.DBInterface.GetImageByFileName(hashName(t, testPNG(t, 10)))
+	if err != nil {
+		t.Fatalf("GetImageByFileName: %v", err)
+	}
+	if err := database.DBInterface.UpdateUserVoteScore(userID, first.ID, 5); err != nil {
+		t.Fatalf("UpdateUserVoteScore: %v", err)
+	}
+	redTag, err := database.DBInterface.GetTagByName("red")
+	if err != nil {
+		t.Fatalf("GetTagByName: %v", err)
+	}
+	aliasID, err := database.DBInterface.NewTag("crimson", "", userID)
+	if err != nil {
+		t.Fatalf("NewTag: %v", err)
+	}
+	if err := database.DBInterface.UpdateTag(aliasID, "crimson", "", redTag.ID, true, userID); err != nil {
+		t.Fatalf("UpdateTag: %v", err)
+	}
+	collection, err := database.DBInterface.GetCollectionByName("Night Set")
+	if err != nil {
+		t.Fatalf("GetCollectionByName: %v", err)
+	}
+	originalMembers, _, err := database.DBInterface.GetCollectionMembers(collection.ID, 0, 10)
+	if err != nil || len(originalMembers) != 2 {
+		t.Fatalf("GetCollectionMembers: %+v, %v", originalMembers, err)
+	}
+	if err := database.DBInterface.AddAuditLog(userID, "TEST", "before export"); err != nil {
+		t.Fatalf("AddAuditLog: %v", err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "board.zip")
+	secretArchivePath := filepath.Join(t.TempDir(), "secret.zip")
+	exportArchive(archivePath, false)
+	exportArchive(secretArchivePath, true)
+
+	//Without passwords in the archive, restoring needs a temporary one
+	resetArchiveTest(t, "sharded")
+	importArchive(archivePath, "")
+	if _, err := database.DBInterface.GetUserID("importer"); err == nil {
+		t.Fatalf("restored an archive without passwords, and without a temporary password")
+	}
+	importArchive(archivePath, "Temporary1")
+	if err := database.DBInterface.ValidateUser("importer", []byte("Temporary1")); err != nil {
+		t.Errorf("ValidateUser with temporary password: %v", err)
+	}
+	if _, _, _, err := database.DBInterface.GetSecurityQuestions("importer"); err == nil {
+		t.Errorf("security questions restored from an archive without secrets")
+	}
+
+	//Restoring again into the same board is refused
+	importArchive(archivePath, "Temporary1")
+	if _, count, err := database.DBInterface.SearchImages(nil, 0, 10); err != nil || count != 3 {
+		t.Fatalf("images restored: %d, %v", count, err)
+	}
+
+	restored, err := database.DBInterface.GetImage(first.ID)
+	if err != nil {
+		t.Fatalf("GetImage of restored image: %v", err)
+	}
+	if restored.Location != storage.LayoutLocation(first.Location, "sharded") || restored.Source != first.Source || restored.Rating != first.Rating || restored.UploaderID != userID || restored.UploadTime.Equal(first.UploadTime) == false || restored.BlurHash != first.BlurHash {
+		t.Errorf("restored image %+v, exported %+v", restored, first)
+	}
+	for _, name := range []string{restored.Location, storage.ThumbnailName(restored.Location)} {
+		if exists, err := storage.Exists(name); err != nil || exists == false {
+			t.Errorf("restored file %s: %v, %v", name, exists, err)
+		}
+	}
+	if colors, err := database.DBInterface.GetImageColors(first.ID); err != nil || len(colors) == 0 {
+		t.Errorf("restored colors: %+v, %v", colors, err)
+	}
+	if tags := imageTagNames(t, first.ID); tags != "blue red" {
+		t.Errorf("restored tags: %q", tags)
+	}
+	if score, err := database.DBInterface.GetUserVoteScore(userID, first.ID); err != nil || score != 5 {
+		t.Errorf("restored vote: %d, %v", score, err)
+	}
+	if restored.ScoreTotal != 5 || restored.ScoreVoters != 1 {
+		t.Errorf("restored image score: %d from %d voters", restored.ScoreTotal, restored.ScoreVoters)
+	}
+	if alias, err := database.DBInterface.GetTag(aliasID, false); err != nil || alias.IsAlias == false || alias.AliasedID != redTag.ID {
+		t.Errorf("restored alias: %+v, %v", alias, err)
+	}
+	members, _, err := database.DBInterface.GetCollectionMembers(collection.ID, 0, 10)
+	if err != nil || len(members) != 2 || members[0].ID != originalMembers[0].ID || members[1].ID != originalMembers[1].ID {
+		t.Errorf("restored collection members: %+v, %v", members, err)
+	}
+	auditLogs, _, err := database.DBInterface.GetAuditLogs(0, 0)
+	if err != nil || len(auditLogs) == 0 || auditLogs[len(auditLogs)-1].Info != "before export" {
+		t.Errorf("restored audit logs: %+v, %v", auditLogs, err)
+	}
+
+	//With secrets, the original password still works
+	resetArchiveTest(t, "flat")
+	importArchive(secretArchivePath, "")
+	if err := database.DBInterface.ValidateUser("importer", []byte("password")); err != nil {
+		t.Errorf("ValidateUser with exported password hash: %v", err)
+	}
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/importers"
+	"go-image-board/interfaces"
+	"go-image-board/jobs"
+	"go-image-board/logging"
+	"go-image-board/routers"
+	"go-image-board/storage"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+//Export formats accepted by -importformat
+const (
+	importFormatDanbooru = "danbooru"
+	importFormatGelbooru = "gelbooru"
+	importFormatHydrus   = "hydrus"
+)
+
+//Scores from other boards are clamped to the range of a single vote
+const (
+	minimumImportedScore = -10
+	maximumImportedScore = 10
+)
+
+//booruImport holds the state of importing one export from another board
+type booruImport struct {
+	User       interfaces.UserInformation
+	Format     string
+	DryRun     bool
+	CanRate    bool
+	tagIDs     map[string]uint64
+	postImages map[string]uint64
+	counts     map[string]uint64
+}
+
+//readBooruExport reads an export in the given format
+func readBooruExport(Format string, Directory string, MetadataPath string, PoolsPath string, TagsPath string) (importers.Export, error) {
+	switch Format {
+	case importFormatDanbooru:
+		if MetadataPath == "" {
+			return importers.Export{}, errors.New("danbooru imports require the posts file, use -importmetadata")
+		}
+		return importers.ReadDanbooru(MetadataPath, PoolsPath, Directory)
+	case importFormatGelbooru:
+		if MetadataPath == "" {
+			return importers.Export{}, errors.New("gelbooru imports require the posts file, use -importmetadata")
+		}
+		return importers.ReadGelbooru(MetadataPath, TagsPath, Directory)
+	case importFormatHydrus:
+		return importers.ReadHydrus(Directory)
+	}
+	return importers.Export{}, errors.New("unknown import format " + Format + ", expected danbooru, gelbooru, or hydrus")
+}
+
+//importBooruExport uploads the files of another board's export from Directory as UserName, then applies its tags, ratings, scores and pools
+//Files already on this board are not uploaded again, but still receive the export's details, so an interrupted import can be run again
+//When DryRun is set nothing is changed, the export is only read and checked
+func importBooruExport(Format string, Directory string, MetadataPath string, PoolsPath string, TagsPath string, UserName string, DryRun bool) {
+	if UserName == "" {
+		logging.WriteLog(logging.LogLevelError, "booruImportUtility/importBooruExport", "0", logging.ResultFailure, []string{"A username is required to import, use -username"})
+		return
+	}
+	userID, err := database.DBInterface.GetUserID(UserName)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "booruImportUtility/importBooruExport", "0", logging.ResultFailure, []string{"Failed to find user to import as", UserName, err.Error()})
+		return
+	}
+	userPermission, err := database.DBInterface.GetUserPermissionSet(UserName)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "booruImportUtility/importBooruExport", "0", logging.ResultFailure, []string{"Failed to get permissions of user to import as", UserName, err.Error()})
+		return
+	}
+	export, err := readBooruExport(Format, Directory, MetadataPath, PoolsPath, TagsPath)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "booruImportUtility/importBooruExport", "0", logging.ResultFailure, []string{"Failed to read export", err.Error()})
+		return
+	}
+
+	//Check everything the export needs up front, rather than failing part way through
+	permissions := interfaces.UserPermission(userPermission)
+	neededPermissions := map[string]interfaces.UserPermission{"upload images": interfaces.UploadImage, "add tags": interfaces.AddTags, "tag images": interfaces.ModifyImageTags}
+	for _, post := range export.Posts {
+		if post.Score != 0 {
+			neededPermissions["score images"] = interfaces.ScoreImage
+			break
+		}
+	}
+	if len(export.Pools) > 0 {
+		neededPermissions["add collections"] = interfaces.AddCollections
+		neededPermissions["add collection members"] = interfaces.ModifyCollectionMembers
+	}
+	for description, permission := range neededPermissions {
+		if permissions.HasPermission(permission) == false {
+			logging.WriteLog(logging.LogLevelError, "booruImportUtility/importBooruExport", "0", logging.ResultFailure, []string{UserName, "does not have permission to", description})
+			return
+		}
+	}
+
+	importer := booruImport{
+		User:       interfaces.UserInformation{Name: UserName, ID: userID},
+		Format:     Format,
+		DryRun:     DryRun,
+		CanRate:    permissions.HasPermission(interfaces.ModifyImageTags) || config.Configuration.UsersControlOwnObjects,
+		tagIDs:     make(map[string]uint64),
+		postImages: make(map[string]uint64),
+		counts:     make(map[string]uint64),
+	}
+	logging.WriteLog(logging.LogLevelInfo, "booruImportUtility/importBooruExport", "0", logging.ResultInfo, []string{"Importing", strconv.Itoa(len(export.Posts)), "posts and", strconv.Itoa(len(export.Pools)), "pools from", Format, "as", UserName, "dry run", strconv.FormatBool(DryRun)})
+	for _, post := range export.Posts {
+		importedBefore := importer.counts[importStatusImported]
+		importer.importPost(post)
+		//Throttle how fast thumbnails are queued, as import does not wait on them
+		//Only posts just imported queue thumbnails, so only they are counted
+		if importer.counts[importStatusImported] != importedBefore && importer.counts[importStatusImported]%config.Configuration.PageStride == 0 {
+			jobs.Wait()
+		}
+	}
+	jobs.Wait()
+	for _, pool := range export.Pools {
+		importer.importPool(pool)
+	}
+
+	importedLabel := "imported"
+	if DryRun {
+		importedLabel = "to import"
+	}
+	logging.WriteLog(logging.LogLevelInfo, "booruImportUtility/importBooruExport", "0", logging.ResultSuccess, []string{"Finished import.",
+		strconv.FormatUint(importer.counts[importStatusImported], 10), importedLabel + ",",
+		strconv.FormatUint(importer.counts[importStatusDuplicate], 10), "duplicates,",
+		strconv.FormatUint(importer.counts[importStatusFailed], 10), "failed,",
+		strconv.FormatUint(importer.counts[importStatusUnsupported], 10), "missing or unsupported,",
+		strconv.FormatUint(importer.counts["tags"], 10), "new tags,",
+		strconv.FormatUint(importer.counts["collections"], 10), "new collections."})
+}
+
+//importPost uploads a post's file if it is not already on this board, then applies the post's details to it
+func (Importer *booruImport) importPost(Post importers.Post) {
+	if Post.FilePath == "" {
+		Importer.counts[importStatusUnsupported]++
+		logging.WriteLog(logging.LogLevelWarning, "booruImportUtility/importPost", "0", logging.ResultFailure, []string{"Post", Post.ID, "has no supported file", Post.FilePath})
+		return
+	}
+	imageID, status, err := Importer.uploadPost(Post)
+	Importer.counts[status]++
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "booruImportUtility/importPost", "0", logging.ResultFailure, []string{"Post", Post.ID, Post.FilePath, err.Error()})
+		return
+	}
+	if imageID != 0 {
+		Importer.postImages[Post.ID] = imageID
+	}
+	if err := Importer.applyPostDetails(imageID, Post); err != nil {
+		logging.WriteLog(logging.LogLevelError, "booruImportUtility/importPost", "0", logging.ResultFailure, []string{"Post", Post.ID, "details not imported", err.Error()})
+		return
+	}
+	logging.WriteLog(logging.LogLevelDebug, "booruImportUtility/importPost", "0", logging.ResultSuccess, []string{"Post", Post.ID, status, strconv.FormatUint(imageID, 10)})
+}
+
+//uploadPost uploads a post's file, and returns the ID of the image along with whether it was imported or a duplicate
+//On a dry run, the ID of new images is 0
+func (Importer *booruImport) uploadPost(Post importers.Post) (uint64, string, error) {
+	fileData, err := os.ReadFile(Post.FilePath)
+	if err != nil {
+		return 0, importStatusFailed, err
+	}
+	mediaType, content, _, err := routers.PrepareUpload(Post.FilePath, bytes.NewReader(fileData))
+	if err != nil {
+		return 0, importStatusUnsupported, err
+	}
+	hashName, err := routers.GetNewImageName(mediaType.WithExtension(Post.FilePath), content)
+	if err != nil {
+		return 0, importStatusFailed, err
+	}
+	exists, err := storage.Exists(storage.ImageLocation(hashName))
+	if err != nil {
+		return 0, importStatusFailed, err
+	}
+	if exists {
+		duplicateInfo, err := database.DBInterface.GetImageByFileName(storage.ImageLocation(hashName))
+		if err != nil {
+			return 0, importStatusFailed, err
+		}
+		return duplicateInfo.ID, importStatusDuplicate, nil
+	}
+	if Importer.DryRun {
+		return 0, importStatusImported, nil
+	}
+
+	fileName := filepath.Base(Post.FilePath)
+	lastID, duplicateIDs, err := routers.HandleImageUploadRequest(nil, Importer.User, "", "", []routers.UploadingFile{{Name: fileName, Data: fileData}}, Post.Source)
+	if duplicateID, isDuplicate := duplicateIDs[fileName]; isDuplicate {
+		return duplicateID, importStatusDuplicate, nil
+	}
+	if lastID == 0 {
+		if err == nil {
+			err = errors.New("upload failed")
+		}
+		return 0, importStatusFailed, err
+	}
+	return lastID, importStatusImported, nil
+}
+
+//applyPostDetails adds a post's tags, rating, and score to an image, skipping tags the image already has
+func (Importer *booruImport) applyPostDetails(ImageID uint64, Post importers.Post) error {
+	currentTags := make(map[uint64]bool)
+	if ImageID != 0 {
+		imageTags, err := database.DBInterface.GetImageTags(ImageID)
+		if err != nil {
+			return err
+		}
+		for _, tag := range imageTags {
+			currentTags[tag.ID] = true
+		}
+	}
+	var tagIDs []uint64
+	for _, tag := range Post.Tags {
+		tagID, err := Importer.getTagID(tag)
+		if err != nil {
+			logging.WriteLog(logging.LogLevelWarning, "booruImportUtility/applyPostDetails", "0", logging.ResultFailure, []string{"Tag skipped", tag.Name, err.Error()})
+			continue
+		}
+		if currentTags[tagID] == false {
+			currentTags[tagID] = true
+			tagIDs = append(tagIDs, tagID)
+		}
+	}
+	if Importer.DryRun || ImageID == 0 {
+		return nil
+	}
+
+	if len(tagIDs) > 0 {
+		if err := database.DBInterface.AddTag(tagIDs, ImageID, Importer.User.ID); err != nil {
+			return err
+		}
+	}
+	if Post.Rating != "" && Importer.CanRate {
+		if err := database.DBInterface.SetImageRating(ImageID, strings.ToLower(Post.Rating)); err != nil {
+			return err
+		}
+	}
+	if Post.Score != 0 {
+		score := Post.Score
+		if score < minimumImportedScore {
+			score = minimumImportedScore
+		} else if score > maximumImportedScore {
+			score = maximumImportedScore
+		}
+		if err := database.DBInterface.UpdateUserVoteScore(Importer.User.ID, ImageID, score); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//getTagID returns the ID of a tag, creating it with its category as the description if it does not exist
+//Aliases resolve to the tag they alias. On a dry run, tags that would be created are given ID 0
+func (Importer *booruImport) getTagID(Tag importers.Tag) (uint64, error) {
+	name := importers.CleanTagName(Tag.Name)
+	if tagID, isKnown := Importer.tagIDs[name]; isKnown {
+		return tagID, nil
+	}
+	if len(name) < 3 || len(name) > 255 {
+		return 0, errors.New("tag name must be between 3 and 255 characters once cleaned, got " + name)
+	}
+	tagInfo, err := database.DBInterface.GetTagByName(name)
+	if err == nil {
+		if tagInfo.IsAlias {
+			Importer.tagIDs[name] = tagInfo.AliasedID
+		} else {
+			Importer.tagIDs[name] = tagInfo.ID
+		}
+		return Importer.tagIDs[name], nil
+	}
+
+	Importer.counts["tags"]++
+	if Importer.DryRun {
+		Importer.tagIDs[name] = 0
+		return 0, nil
+	}
+	description := ""
+	if Tag.Category != importers.CategoryGeneral {
+		description = strings.ToUpper(Tag.Category[:1]) + Tag.Category[1:] + " tag imported from " + Importer.Format
+	}
+	tagID, err := database.DBInterface.NewTag(name, description, Importer.User.ID)
+	if err != nil {
+		Importer.counts["tags"]--
+		return 0, err
+	}
+	Importer.tagIDs[name] = tagID
+	return tagID, nil
+}
+
+//importPool adds the images of a pool's posts to a collection of the same name, in the pool's order, creating the collection if needed
+func (Importer *booruImport) importPool(Pool importers.Pool) {
+	var imageIDs []uint64
+	inPool := make(map[uint64]bool)
+	for _, postID := range Pool.PostIDs {
+		if imageID := Importer.postImages[postID]; imageID != 0 && inPool[imageID] == false {
+			inPool[imageID] = true
+			imageIDs = append(imageIDs, imageID)
+		}
+	}
+
+	collectionInfo, err := database.DBInterface.GetCollectionByName(Pool.Name)
+	if err != nil {
+		Importer.counts["collections"]++
+		if Importer.DryRun {
+			return
+		}
+		collectionInfo.ID, err = database.DBInterface.NewCollection(Pool.Name, truncateUTF8(Pool.Description, 255), Importer.User.ID)
+		if err != nil {
+			Importer.counts["collections"]--
+			logging.WriteLog(logging.LogLevelError, "booruImportUtility/importPool", "0", logging.ResultFailure, []string{"Failed to create collection for pool", Pool.Name, err.Error()})
+			return
+		}
+	} else {
+		//Skip images already in the collection from an earlier run
+		for count, maxCount := uint64(0), uint64(1); count < maxCount; count += config.Configuration.PageStride {
+			var members []interfaces.ImageInformation
+			members, maxCount, err = database.DBInterface.GetCollectionMembers(collectionInfo.ID, count, config.Configuration.PageStride)
+			if err != nil {
+				logging.WriteLog(logging.LogLevelError, "booruImportUtility/importPool", "0", logging.ResultFailure, []string{"Failed to get members of collection", Pool.Name, err.Error()})
+				return
+			}
+			for _, member := range members {
+				inPool[member.ID] = false
+			}
+		}
+		newIDs := imageIDs[:0]
+		for _, imageID := range imageIDs {
+			if inPool[imageID] {
+				newIDs = append(newIDs, imageID)
+			}
+		}
+		imageIDs = newIDs
+	}
+	if len(imageIDs) == 0 || Importer.DryRun {
+		return
+	}
+	if err := database.DBInterface.AddCollectionMember(collectionInfo.ID, imageIDs, Importer.User.ID); err != nil {
+		logging.WriteLog(logging.LogLevelError, "booruImportUtility/importPool", "0", logging.ResultFailure, []string{"Failed to add images to collection", Pool.Name, err.Error()})
+	}
+}
+
+//truncateUTF8 shortens Text to at most MaxLength bytes without splitting a character
+func truncateUTF8(Text string, MaxLength int) string {
+	if len(Text) <= MaxLength {
+		return Text
+	}
+	Text = Text[:MaxLength]
+	for len(Text) > 0 && utf8.ValidString(Text) == false {
+		Text = Text[:len(Text)-1]
+	}
+	return Text
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"go-image-board/database"
+	"go-image-board/storage"
+	"path/filepath"
+	"testing"
+)
+
+func TestImportBooruExport(t *testing.T) {
+	setupImportTest(t)
+	fileDirectory := t.TempDir()
+	writeTestFile(t, fileDirectory, "101.png", testPNG(t, 10))
+	writeTestFile(t, fileDirectory, "102.png", testPNG(t, 20))
+	userID, err := database.DBInterface.GetUserID("importer")
+	if err != nil {
+		t.Fatalf("GetUserID: %v", err)
+	}
+	postsPath := filepath.Join("importers", "testdata", "danbooru", "posts.json")
+	poolsPath := filepath.Join("importers", "testdata", "danbooru", "pools.json")
+
+	//A dry run changes nothing
+	importBooruExport(importFormatDanbooru, fileDirectory, postsPath, poolsPath, "", "importer", true)
+	if _, count, err := database.DBInterface.SearchImages(nil, 0, 10); err != nil || count != 0 {
+		t.Errorf("dry run uploaded images: %d, %v", count, err)
+	}
+	if _, err := database.DBInterface.GetTagByName("hatsune_miku"); err == nil {
+		t.Errorf("dry run created a tag")
+	}
+
+	//Running twice gives the same result as once
+	for run := 1; run <= 2; run++ {
+		importBooruExport(importFormatDanbooru, fileDirectory, postsPath, poolsPath, "", "importer", false)
+		if _, count, err := database.DBInterface.SearchImages(nil, 0, 10); err != nil || count != 2 {
+			t.Fatalf("run %d: images imported: %d, %v", run, count, err)
+		}
+
+		first, err := database.DBInterface.GetImageByFileName(storage.ImageLocation(hashName(t, testPNG(t, 10))))
+		if err != nil {
+			t.Fatalf("run %d: GetImageByFileName of post 101: %v", run, err)
+		}
+		if first.Source != "https://example.com/101" || first.Rating != "sensitive" {
+			t.Errorf("run %d: post 101 details: source %q, rating %q", run, first.Source, first.Rating)
+		}
+		if tags := imageTagNames(t, first.ID); tags != "1girl artist_name hatsune_miku highres long_hair vocaloid" {
+			t.Errorf("run %d: post 101 tags: %q", run, tags)
+		}
+		if score, err := database.DBInterface.GetUserVoteScore(userID, first.ID); err != nil || score != 10 {
+			t.Errorf("run %d: post 101 vote: %d, %v", run, score, err)
+		}
+
+		second, err := database.DBInterface.GetImageByFileName(storage.ImageLocation(hashName(t, testPNG(t, 20))))
+		if err != nil {
+			t.Fatalf("run %d: GetImageByFileName of post 102: %v", run, err)
+		}
+		if tags := imageTagNames(t, second.ID); tags != "long_hair solo" || second.Rating != "explicit" {
+			t.Errorf("run %d: post 102 details: tags %q, rating %q", run, tags, second.Rating)
+		}
+		if score, err := database.DBInterface.GetUserVoteScore(userID, second.ID); err != nil || score != -10 {
+			t.Errorf("run %d: post 102 vote: %d, %v", run, score, err)
+		}
+
+		//Pools keep their order, and skip posts without files
+		collection, err := database.DBInterface.GetCollectionByName("Miku Series")
+		if err != nil {
+			t.Fatalf("run %d: GetCollectionByName: %v", run, err)
+		}
+		members, _, err := database.DBInterface.GetCollectionMembers(collection.ID, 0, 10)
+		if err != nil || len(members) != 2 || members[0].ID != second.ID || members[1].ID != first.ID {
+			t.Errorf("run %d: collection members: %+v, %v", run, members, err)
+		}
+	}
+
+	//Categories are kept in the description of new tags
+	tag, err := database.DBInterface.GetTagByName("hatsune_miku")
+	if err != nil || tag.Description != "Character tag imported from danbooru" {
+		t.Errorf("hatsune_miku tag: %+v, %v", tag, err)
+	}
+}
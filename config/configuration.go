@@ -13,6 +13,10 @@ import (
 
 //ConfigurationSettings contains the structure of all the settings that will be loaded at runtime.
 type ConfigurationSettings struct {
+	//DBPlugin selects the database backend, either "mariadb", "postgres", "sqlite", or "memory"
+	DBPlugin string
+	//DBPath is the path to the database file when using the sqlite plugin
+	DBPath string
 	//DBName is the name of the db used for this instance
 	DBName string
 	//DBUser is the user name used to auth to the db
@@ -23,8 +27,26 @@ type ConfigurationSettings struct {
 	DBPort string
 	//DBHost hostname of the database server
 	DBHost string
-	//ImageDirectory path to where images are stored
+	//DBSSLMode is the sslmode used when connecting to a postgres server
+	DBSSLMode string
+	//StoragePlugin selects where images and thumbnails are stored, either "local" or "s3"
+	StoragePlugin string
+	//ImageDirectory path to where images are stored when using the local storage plugin
 	ImageDirectory string
+	//StorageLayout selects how new images are arranged in storage, either "flat" or "sharded", run with -migratelayout after changing it
+	StorageLayout string
+	//S3Endpoint host and optional port of the S3 compatible object store, such as "s3.amazonaws.com" or "minio:9000"
+	S3Endpoint string
+	//S3Bucket is the bucket images and thumbnails are stored in
+	S3Bucket string
+	//S3Region is the region of the bucket, may be left blank for MinIO
+	S3Region string
+	//S3AccessKeyID is the access key used to auth to the object store
+	S3AccessKeyID string
+	//S3SecretAccessKey is the secret key used to auth to the object store
+	S3SecretAccessKey string
+	//S3UseSSL connects to the object store over https
+	S3UseSSL bool
 	//Address hostname/port that this server should listen on
 	Address string
 	//ReadTimeout timeout allowed for reads
@@ -43,6 +65,10 @@ type ConfigurationSettings struct {
 	HTTPRoot string
 	//MaxUploadBytes maximum allowed bytes for an upload
 	MaxUploadBytes int64
+	//MaxArchiveEntries maximum number of files a zip or cbz upload may hold
+	MaxArchiveEntries int
+	//MaxArchiveBytes maximum bytes the files of a zip or cbz upload may add up to once unpacked
+	MaxArchiveBytes int64
 	//AllowAccountCreation if true, random users can create accounts, otherwise only mods can create users
 	AllowAccountCreation bool
 	//AccountRequiredToView if true, users must authenticate to access nearly any part of the server
@@ -51,16 +77,44 @@ type ConfigurationSettings struct {
 	MaxThumbnailWidth uint
 	//MaxThumbnailHeight Maximum height for automatically generated thumbnails
 	MaxThumbnailHeight uint
+	//ThumbnailSizes Named thumbnail sizes, such as small or large, served from /thumbs/{size}/{file} and made the first time they are requested
+	ThumbnailSizes map[string]ThumbnailSize
+	//ThumbnailFormat Format of thumbnails made for ThumbnailSizes, either "jpeg", "png", or "webp-lossless"
+	ThumbnailFormat string
+	//ThumbnailQuality Quality of jpeg thumbnails, from 1 to 100
+	ThumbnailQuality int
 	//DefaultPermissions these permissions are assigned to all new users automatically
 	DefaultPermissions uint64
 	//UsersControlOwnObjects if this is set, permission checks are ignored for users that are trying to manage resources they contributed
 	UsersControlOwnObjects bool
 	//FFMPEGPath Path to the FFMPEG application
 	FFMPEGPath string
-	//UseFFMPEG If set, when joined with FFMPEGPath, videos that are uploaded will have a thumbnail generated using FFMPEG
+	//UseFFMPEG If set, when joined with FFMPEGPath, videos and MP3 or Ogg audio that are uploaded will have a thumbnail generated using FFMPEG
 	UseFFMPEG bool
+	//TranscodeFormat Format video that browsers cannot play, such as avi, is transcoded to when UseFFMPEG is set, either "mp4" for H.264 or "webm" for VP9
+	TranscodeFormat string
+	//AllowedMediaTypes Which types of file may be uploaded, such as png or webm. Files are recognized by their content, not their name
+	AllowedMediaTypes []string
+	//StripImageMetadata If set, uploaded photos have metadata that could identify where they were taken or by whom, such as GPS coordinates, removed. Camera details are still shown
+	StripImageMetadata bool
+	//SuggestAudioTags If set, the upload form offers the artist, album, and genre tagged in audio files as tags for them
+	SuggestAudioTags bool
 	//PageStride How many images to show on one page
 	PageStride uint64
+	//JobWorkers How many background jobs, such as generating thumbnails, may run at once
+	JobWorkers uint64
+	//JobMaxAttempts How many times a failing background job is tried before it is marked as failed
+	JobMaxAttempts uint64
+	//AuditRetention How long audit logs are kept
+	AuditRetention time.Duration
+	//AuditCleanupInterval How often audit logs older than AuditRetention are removed, negative to never remove them
+	AuditCleanupInterval time.Duration
+	//OrphanScanInterval How often files without an image in the database are moved to quarantine, negative to never scan
+	OrphanScanInterval time.Duration
+	//ScoreRecalculationInterval How often the score of every image is recalculated from its votes, negative to never recalculate
+	ScoreRecalculationInterval time.Duration
+	//CollectionTagRepairInterval How often the tags of every collection are fixed, negative to never fix them
+	CollectionTagRepairInterval time.Duration
 	//APIThrottle How much time, in milliseconds, users using the API must wait between requests
 	APIThrottle int64
 	//UseTLS Enables TLS encryption on server
@@ -79,6 +133,14 @@ type ConfigurationSettings struct {
 	LoggingBlackList string
 }
 
+//ThumbnailSize is how large the thumbnails of a size in ThumbnailSizes may be, thumbnails keep the shape of their image
+type ThumbnailSize struct {
+	//Width Maximum width of the thumbnail
+	Width uint
+	//Height Maximum height of the thumbnail
+	Height uint
+}
+
 //SessionStore contains cookie information
 var SessionStore *sessions.CookieStore
 
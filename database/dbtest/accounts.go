@@ -0,0 +1,206 @@
+package dbtest
+
+import (
+	"go-image-board/interfaces"
+	"testing"
+)
+
+func testUsers(t *testing.T, DB interfaces.DBInterface) {
+	password := []byte("Password1")
+	if err := DB.CreateUser("Tester", password, "tester@example.com", 0); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := DB.CreateUser("tester", password, "other@example.com", 0); err == nil {
+		t.Errorf("CreateUser accepted a name differing only by case")
+	}
+	if err := DB.CreateUser("Other", password, "TESTER@example.com", 0); err == nil {
+		t.Errorf("CreateUser accepted an email differing only by case")
+	}
+
+	if err := DB.ValidateUser("Tester", password); err != nil {
+		t.Errorf("ValidateUser with the right password: %v", err)
+	}
+	if err := DB.ValidateUser("Tester", []byte("Password2")); err == nil {
+		t.Errorf("ValidateUser accepted the wrong password")
+	}
+	if err := DB.ValidateUser("Nobody", password); err == nil {
+		t.Errorf("ValidateUser accepted a user that does not exist")
+	}
+
+	userID, err := DB.GetUserID("Tester")
+	if err != nil || userID == 0 {
+		t.Fatalf("GetUserID: %d, %v", userID, err)
+	}
+	if _, err := DB.GetUserID("Nobody"); err == nil {
+		t.Errorf("GetUserID found a user that does not exist")
+	}
+	userInfo, err := DB.GetUser(userID)
+	if err != nil || userInfo.Name != "Tester" || userInfo.Disabled {
+		t.Errorf("GetUser: %+v, %v", userInfo, err)
+	}
+
+	//Permissions
+	if err := DB.SetUserPermissionSet(userID, uint64(interfaces.ModifyImageTags|interfaces.UploadImage)); err != nil {
+		t.Fatalf("SetUserPermissionSet: %v", err)
+	}
+	permissions, err := DB.GetUserPermissionSet("Tester")
+	if err != nil || permissions != interfaces.ModifyImageTags|interfaces.UploadImage {
+		t.Errorf("GetUserPermissionSet: %d, %v", permissions, err)
+	}
+
+	//Tokens are tied to the IP they were issued to, and revoking clears them
+	token, err := DB.GenerateToken("Tester", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	if err := DB.ValidateToken("Tester", token, "127.0.0.1"); err != nil {
+		t.Errorf("ValidateToken with the issued token: %v", err)
+	}
+	if err := DB.ValidateToken("Tester", token, "127.0.0.2"); err == nil {
+		t.Errorf("ValidateToken accepted a token from a different IP")
+	}
+	if err := DB.ValidateToken("Tester", "", "127.0.0.1"); err == nil {
+		t.Errorf("ValidateToken accepted a blank token")
+	}
+	if err := DB.RevokeToken("Tester"); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+	if err := DB.ValidateToken("Tester", token, "127.0.0.1"); err == nil {
+		t.Errorf("ValidateToken accepted a revoked token")
+	}
+
+	//Search ignores case
+	users, count, err := DB.SearchUsers("TEST", 0, 10)
+	if err != nil || count != 1 || len(users) != 1 || users[0].ID != userID {
+		t.Errorf("SearchUsers: %+v, %d, %v", users, count, err)
+	}
+
+	//Disabled users can no longer sign in
+	if err := DB.SetUserDisableState(userID, true); err != nil {
+		t.Fatalf("SetUserDisableState: %v", err)
+	}
+	if err := DB.ValidateUser("Tester", password); err == nil {
+		t.Errorf("ValidateUser accepted a disabled user")
+	}
+}
+
+func testTags(t *testing.T, DB interfaces.DBInterface) {
+	blueSky := mustNewTag(t, DB, " Blue Sky ")
+	tagInfo, err := DB.GetTag(blueSky, false)
+	if err != nil || tagInfo.Name != "blue_sky" || tagInfo.Exists == false {
+		t.Errorf("GetTag after creating \" Blue Sky \": %+v, %v", tagInfo, err)
+	}
+	if _, err := DB.NewTag("BLUE_SKY", "", 0); err == nil {
+		t.Errorf("NewTag accepted a duplicate name")
+	}
+	if _, err := DB.NewTag("ab", "", 0); err == nil {
+		t.Errorf("NewTag accepted a name shorter than 3 characters")
+	}
+	tagInfo, err = DB.GetTagByName("blue_sky")
+	if err != nil || tagInfo.ID != blueSky {
+		t.Errorf("GetTagByName: %+v, %v", tagInfo, err)
+	}
+	if _, err := DB.GetTag(blueSky+100, false); err == nil {
+		t.Errorf("GetTag found a tag that does not exist")
+	}
+
+	blueWater := mustNewTag(t, DB, "blue_water")
+	green := mustNewTag(t, DB, "green")
+	allTags, err := DB.GetAllTags()
+	if err != nil {
+		t.Fatalf("GetAllTags: %v", err)
+	}
+	var allNames []string
+	for _, tag := range allTags {
+		allNames = append(allNames, tag.Name)
+	}
+	expectStrings(t, "GetAllTags", allNames, "blue_sky", "blue_water", "green")
+
+	//Usage counts, and a tag in use cannot be deleted
+	image := mustNewImage(t, DB, "tagged")
+	mustAddTag(t, DB, image, blueWater)
+	tagInfo, err = DB.GetTag(blueWater, true)
+	if err != nil || tagInfo.UseCount != 1 {
+		t.Errorf("GetTag with count: %+v, %v", tagInfo, err)
+	}
+	if err := DB.DeleteTag(blueWater); err == nil {
+		t.Errorf("DeleteTag removed a tag that is in use")
+	}
+	if err := DB.DeleteTag(green); err != nil {
+		t.Errorf("DeleteTag: %v", err)
+	}
+	if _, err := DB.GetTag(green, false); err == nil {
+		t.Errorf("GetTag found a deleted tag")
+	}
+
+	//Search by name, optionally only from the start of the name, and optionally by usage
+	tags, count, err := DB.SearchTags("blue", 0, 10, true, false)
+	if err != nil || count != 2 {
+		t.Fatalf("SearchTags: %d, %v", count, err)
+	}
+	expectStrings(t, "SearchTags by name", tagNames(tags), "blue_sky", "blue_water")
+	tags, _, err = DB.SearchTags("sky", 0, 10, true, false)
+	if err != nil || len(tags) != 0 {
+		t.Errorf("SearchTags matching from the start: %+v, %v", tags, err)
+	}
+	tags, _, err = DB.SearchTags("sky", 0, 10, false, false)
+	if err != nil || len(tags) != 1 || tags[0].ID != blueSky {
+		t.Errorf("SearchTags matching anywhere: %+v, %v", tags, err)
+	}
+	tags, _, err = DB.SearchTags("blue", 0, 10, false, true)
+	if err != nil || len(tags) != 1 || tags[0].ID != blueWater {
+		t.Errorf("SearchTags sorted by usage: %+v, %v", tags, err)
+	}
+}
+
+func testAliases(t *testing.T, DB interfaces.DBInterface) {
+	cat := mustNewTag(t, DB, "cat")
+	kitty := mustNewTag(t, DB, "kitty")
+	dog := mustNewTag(t, DB, "dog")
+	kittyImage := mustNewImage(t, DB, "kitty")
+	catImage := mustNewImage(t, DB, "cat")
+	dogImage := mustNewImage(t, DB, "dog")
+	untaggedImage := mustNewImage(t, DB, "untagged")
+	mustAddTag(t, DB, kittyImage, kitty)
+	mustAddTag(t, DB, catImage, cat)
+	mustAddTag(t, DB, dogImage, dog)
+
+	//Aliasing moves existing uses over to the aliased tag
+	if err := DB.UpdateTag(kitty, "kitty", "", cat, true, 0); err != nil {
+		t.Fatalf("UpdateTag to alias: %v", err)
+	}
+	eventually(t, "kitty uses to move to cat", func() bool {
+		tags, err := DB.GetImageTags(kittyImage)
+		return err == nil && len(tags) == 1 && tags[0].ID == cat
+	})
+	tagInfo, err := DB.GetTag(kitty, true)
+	if err != nil || tagInfo.IsAlias == false || tagInfo.AliasedID != cat || tagInfo.UseCount != 0 {
+		t.Errorf("GetTag of alias: %+v, %v", tagInfo, err)
+	}
+
+	//An alias cannot point at another alias
+	if err := DB.UpdateTag(dog, "dog", "", kitty, true, 0); err == nil {
+		t.Errorf("UpdateTag aliased a tag to an alias")
+	}
+
+	//Adding an alias adds the tag it points at
+	mustAddTag(t, DB, dogImage, kitty)
+	expectStrings(t, "tags after adding an alias", imageTagNames(t, DB, dogImage), "cat", "dog")
+
+	//Queries include the aliased tag, and search with it
+	expectStrings(t, "GetQueryTags of an alias", tagNames(mustQueryTags(t, DB, "kitty", false)), "cat", "kitty")
+	expectIDs(t, "search by alias", searchImageIDs(t, DB, "kitty"), dogImage, catImage, kittyImage)
+	expectIDs(t, "search excluding alias", searchImageIDs(t, DB, "-kitty"), untaggedImage)
+	for _, tag := range mustQueryTags(t, DB, "-kitty", false) {
+		if tag.Exclude == false {
+			t.Errorf("GetQueryTags lost the exclusion on %q", tag.Name)
+		}
+	}
+
+	//BulkAddTag resolves aliases on both sides
+	bird := mustNewTag(t, DB, "bird")
+	if err := DB.BulkAddTag(bird, kitty, 0); err != nil {
+		t.Fatalf("BulkAddTag: %v", err)
+	}
+	expectIDs(t, "search after BulkAddTag", searchImageIDs(t, DB, "bird"), dogImage, catImage, kittyImage)
+}
@@ -0,0 +1,152 @@
+package dbtest
+
+import (
+	"go-image-board/interfaces"
+	"testing"
+	"time"
+)
+
+func testBackup(t *testing.T, DB interfaces.DBInterface) {
+	password := []byte("Password1")
+	if err := DB.CreateUser("Tester", password, "tester@example.com", uint64(interfaces.ScoreImage)); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := DB.SetSecurityQuestions("Tester", "One?", "Two?", "Three?", []byte("a"), []byte("b"), []byte("c"), nil); err != nil {
+		t.Fatalf("SetSecurityQuestions: %v", err)
+	}
+	users, count, err := DB.GetUserBackups(0, 10)
+	if err != nil || count != 1 || len(users) != 1 {
+		t.Fatalf("GetUserBackups did not return only the created user: %+v, %d, %v", users, count, err)
+	}
+	user := users[0]
+	if user.Name != "Tester" || user.EMail != "tester@example.com" || user.Permissions != uint64(interfaces.ScoreImage) || user.PasswordHash == "" || user.SecQuestionTwo != "Two?" || user.SecAnswerThree == "" {
+		t.Errorf("GetUserBackups: %+v", user)
+	}
+
+	imageID := mustNewImage(t, DB, "voted")
+	if err := DB.UpdateUserVoteScore(user.ID, imageID, 7); err != nil {
+		t.Fatalf("UpdateUserVoteScore: %v", err)
+	}
+	votes, err := DB.GetImageVotes(imageID)
+	if err != nil || len(votes) != 1 || votes[0].UserID != user.ID || votes[0].ImageID != imageID || votes[0].Score != 7 || votes[0].CreationTime.IsZero() {
+		t.Errorf("GetImageVotes: %+v, %v", votes, err)
+	}
+
+	for _, info := range []string{"first", "second"} {
+		if err := DB.AddAuditLog(user.ID, "TEST", info); err != nil {
+			t.Fatalf("AddAuditLog: %v", err)
+		}
+	}
+	auditLogs, count, err := DB.GetAuditLogs(1, 10)
+	if err != nil || count != 2 || len(auditLogs) != 1 || auditLogs[0].Info != "second" || auditLogs[0].UserID != user.ID || auditLogs[0].Type != "TEST" {
+		t.Errorf("GetAuditLogs second page: %+v, %d, %v", auditLogs, count, err)
+	}
+
+	//Restored rows keep their IDs and times, and later rows are numbered after them
+	restoredTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	restoredUser := user
+	restoredUser.ID, restoredUser.Name, restoredUser.EMail, restoredUser.CreationTime = 50, "Restored", "restored@example.com", restoredTime
+	if err := DB.RestoreUser(restoredUser); err != nil {
+		t.Fatalf("RestoreUser: %v", err)
+	}
+	if err := DB.RestoreUser(restoredUser); err == nil {
+		t.Errorf("RestoreUser restored the same user twice")
+	}
+	if err := DB.ValidateUser("Restored", password); err != nil {
+		t.Errorf("ValidateUser with a restored password hash: %v", err)
+	}
+	if err := DB.ValidateSecurityQuestions("Restored", []byte("a"), []byte("b"), []byte("c")); err != nil {
+		t.Errorf("ValidateSecurityQuestions with restored answers: %v", err)
+	}
+	users, _, err = DB.GetUserBackups(1, 10)
+	if err != nil || len(users) != 1 || users[0].ID != 50 || users[0].CreationTime.Equal(restoredTime) == false {
+		t.Errorf("GetUserBackups of restored user: %+v, %v", users, err)
+	}
+
+	if err := DB.RestoreTag(interfaces.TagInformation{ID: 60, Name: "restored_tag", Description: "old", UploaderID: 50, UploadTime: restoredTime}); err != nil {
+		t.Fatalf("RestoreTag: %v", err)
+	}
+	if err := DB.RestoreTag(interfaces.TagInformation{ID: 61, Name: "restored_alias", UploaderID: 50, UploadTime: restoredTime, AliasedID: 60, IsAlias: true}); err != nil {
+		t.Fatalf("RestoreTag of alias: %v", err)
+	}
+	tag, err := DB.GetTag(61, false)
+	if err != nil || tag.Name != "restored_alias" || tag.IsAlias == false || tag.AliasedID != 60 || tag.UploaderID != 50 || tag.UploadTime.Equal(restoredTime) == false {
+		t.Errorf("GetTag of restored alias: %+v, %v", tag, err)
+	}
+	if newTagID := mustNewTag(t, DB, "after_restore"); newTagID <= 61 {
+		t.Errorf("NewTag after restoring tag 61 got ID %d", newTagID)
+	}
+
+	if err := DB.RestoreImage(interfaces.ImageInformation{ID: 70, Name: "restored.png", Location: "ab/cd/restored.png", Description: "described", UploaderID: 50, UploadTime: restoredTime, Rating: "safe", Source: "https://example.com"}); err != nil {
+		t.Fatalf("RestoreImage: %v", err)
+	}
+	image, err := DB.GetImage(70)
+	if err != nil || image.Name != "restored.png" || image.Location != "ab/cd/restored.png" || image.Description != "described" || image.UploaderID != 50 || image.Rating != "safe" || image.Source != "https://example.com" || image.UploadTime.Equal(restoredTime) == false {
+		t.Errorf("GetImage of restored image: %+v, %v", image, err)
+	}
+	newImageID := mustNewImage(t, DB, "after_restore")
+	if newImageID <= 70 {
+		t.Errorf("NewImage after restoring image 70 got ID %d", newImageID)
+	}
+
+	if err := DB.RestoreCollection(interfaces.CollectionInformation{ID: 80, Name: "Restored Collection", Description: "old", UploaderID: 50, UploadTime: restoredTime}); err != nil {
+		t.Fatalf("RestoreCollection: %v", err)
+	}
+	collection, err := DB.GetCollection(80)
+	if err != nil || collection.Name != "Restored Collection" || collection.UploaderID != 50 || collection.UploadTime.Equal(restoredTime) == false {
+		t.Errorf("GetCollection of restored collection: %+v, %v", collection, err)
+	}
+	if newCollectionID := mustNewCollection(t, DB, "After Restore"); newCollectionID <= 80 {
+		t.Errorf("NewCollection after restoring collection 80 got ID %d", newCollectionID)
+	}
+
+	if err := DB.RestoreAuditLog(interfaces.AuditLogInformation{UserID: 50, Type: "OLD", Info: "restored", LogTime: restoredTime}); err != nil {
+		t.Fatalf("RestoreAuditLog: %v", err)
+	}
+	auditLogs, count, err = DB.GetAuditLogs(2, 10)
+	if err != nil || count != 3 || len(auditLogs) != 1 || auditLogs[0].Info != "restored" || auditLogs[0].LogTime.Equal(restoredTime) == false {
+		t.Errorf("GetAuditLogs of restored entry: %+v, %d, %v", auditLogs, count, err)
+	}
+	if removed, err := DB.RemoveAuditLogs(24 * time.Hour); err != nil || removed != 1 {
+		t.Errorf("RemoveAuditLogs of the restored entry: %d, %v", removed, err)
+	}
+	if _, count, err := DB.GetAuditLogs(0, 10); err != nil || count != 2 {
+		t.Errorf("audit logs left after RemoveAuditLogs: %d, %v", count, err)
+	}
+
+	//Links keep their linker, time, and order
+	if err := DB.RestoreImageTags([]interfaces.ImageTagLink{{ImageID: 70, TagID: 60, LinkerID: 50, LinkTime: restoredTime}, {ImageID: 70, TagID: 61, LinkerID: 50, LinkTime: restoredTime}}); err != nil {
+		t.Fatalf("RestoreImageTags: %v", err)
+	}
+	if err := DB.RestoreImageTags([]interfaces.ImageTagLink{{ImageID: 70, TagID: 60, LinkerID: 50}}); err == nil {
+		t.Errorf("RestoreImageTags applied the same tag twice")
+	}
+	tagLinks, err := DB.GetImageTagLinks(70)
+	if err != nil || len(tagLinks) != 2 || tagLinks[0].TagID != 60 || tagLinks[1].TagID != 61 || tagLinks[0].LinkerID != 50 || tagLinks[1].LinkTime.Equal(restoredTime) == false {
+		t.Errorf("GetImageTagLinks of restored links: %+v, %v", tagLinks, err)
+	}
+	if err := DB.RestoreCollectionMembers([]interfaces.CollectionMemberLink{{CollectionID: 80, ImageID: 70, LinkerID: 50, LinkTime: restoredTime, OrderWeight: 3}, {CollectionID: 80, ImageID: newImageID, LinkerID: 50, LinkTime: restoredTime, OrderWeight: 1}}); err != nil {
+		t.Fatalf("RestoreCollectionMembers: %v", err)
+	}
+	memberLinks, err := DB.GetCollectionMemberLinks(80)
+	if err != nil || len(memberLinks) != 2 || memberLinks[0].ImageID != newImageID || memberLinks[0].OrderWeight != 1 || memberLinks[1].ImageID != 70 || memberLinks[1].OrderWeight != 3 || memberLinks[1].LinkerID != 50 || memberLinks[1].LinkTime.Equal(restoredTime) == false {
+		t.Errorf("GetCollectionMemberLinks of restored members: %+v, %v", memberLinks, err)
+	}
+	expectStrings(t, "collection tags after restoring members", collectionTagNames(t, DB, 80), "restored_alias", "restored_tag")
+	if err := DB.RestoreImageVote(interfaces.ImageVote{UserID: 50, ImageID: 70, Score: 3, CreationTime: restoredTime}); err != nil {
+		t.Fatalf("RestoreImageVote: %v", err)
+	}
+	if err := DB.RestoreImageVote(interfaces.ImageVote{UserID: 50, ImageID: 70, Score: 3}); err == nil {
+		t.Errorf("RestoreImageVote restored the same vote twice")
+	}
+	if err := DB.UpdateScoreOnImage(70); err != nil {
+		t.Fatalf("UpdateScoreOnImage: %v", err)
+	}
+	votes, err = DB.GetImageVotes(70)
+	if err != nil || len(votes) != 1 || votes[0].Score != 3 || votes[0].CreationTime.Equal(restoredTime) == false {
+		t.Errorf("GetImageVotes of restored vote: %+v, %v", votes, err)
+	}
+	if image, err := DB.GetImage(70); err != nil || image.ScoreTotal != 3 || image.ScoreVoters != 1 {
+		t.Errorf("score of image with restored vote: %+v, %v", image, err)
+	}
+}
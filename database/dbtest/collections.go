@@ -0,0 +1,207 @@
+package dbtest
+
+import (
+	"go-image-board/interfaces"
+	"testing"
+)
+
+func testCollectionOrdering(t *testing.T, DB interfaces.DBInterface) {
+	first := mustNewImage(t, DB, "first")
+	second := mustNewImage(t, DB, "second")
+	third := mustNewImage(t, DB, "third")
+	fourth := mustNewImage(t, DB, "fourth")
+
+	//Members are added in the order given, after any existing members
+	collection := mustNewCollection(t, DB, "Series", first, second, third)
+	if _, err := DB.NewCollection("SERIES", "", 0); err == nil {
+		t.Errorf("NewCollection accepted a duplicate name")
+	}
+	if err := DB.AddCollectionMember(collection, []uint64{fourth}, 0); err != nil {
+		t.Fatalf("AddCollectionMember: %v", err)
+	}
+	expectIDs(t, "members after adding", collectionOrder(t, DB, collection), first, second, third, fourth)
+	if err := DB.AddCollectionMember(collection, []uint64{second}, 0); err == nil {
+		t.Errorf("AddCollectionMember added an image twice")
+	}
+	collectionInfo, err := DB.GetCollection(collection)
+	if err != nil || collectionInfo.Name != "Series" || collectionInfo.Members != 4 {
+		t.Errorf("GetCollection: %+v, %v", collectionInfo, err)
+	}
+	collectionInfo, err = DB.GetCollectionByName("series")
+	if err != nil || collectionInfo.ID != collection {
+		t.Errorf("GetCollectionByName: %+v, %v", collectionInfo, err)
+	}
+
+	//Moving shifts the members in between, and orders past the end go last
+	if err := DB.UpdateCollectionMember(collection, fourth, 0); err != nil {
+		t.Fatalf("UpdateCollectionMember: %v", err)
+	}
+	expectIDs(t, "members after moving to the front", collectionOrder(t, DB, collection), fourth, first, second, third)
+	if err := DB.UpdateCollectionMember(collection, fourth, 99); err != nil {
+		t.Fatalf("UpdateCollectionMember: %v", err)
+	}
+	expectIDs(t, "members after moving past the end", collectionOrder(t, DB, collection), first, second, third, fourth)
+	if err := DB.UpdateCollectionMember(collection, first, 2); err != nil {
+		t.Fatalf("UpdateCollectionMember: %v", err)
+	}
+	expectIDs(t, "members after moving back", collectionOrder(t, DB, collection), second, third, first, fourth)
+	if err := DB.UpdateCollectionMember(collection, fourth, 1); err != nil {
+		t.Fatalf("UpdateCollectionMember: %v", err)
+	}
+	expectIDs(t, "members after moving forward", collectionOrder(t, DB, collection), second, fourth, third, first)
+
+	//Paging members
+	members, count, err := DB.GetCollectionMembers(collection, 1, 2)
+	if err != nil || count != 4 || len(members) != 2 || members[0].ID != fourth || members[1].ID != third {
+		t.Errorf("GetCollectionMembers paged: %+v, %d, %v", members, count, err)
+	}
+
+	//The preview is the first member
+	collections, count, err := DB.GetCollections(0, 10)
+	if err != nil || count != 1 || len(collections) != 1 || collections[0].Location != "second.png" || collections[0].Members != 4 {
+		t.Errorf("GetCollections: %+v, %d, %v", collections, count, err)
+	}
+
+	//Navigation from a member
+	withImage, err := DB.GetCollectionsWithImage(fourth)
+	if err != nil || len(withImage) != 1 {
+		t.Fatalf("GetCollectionsWithImage: %+v, %v", withImage, err)
+	}
+	if withImage[0].ID != collection || withImage[0].OrderInCollection != 1 || withImage[0].PreviousMemberID != second || withImage[0].NextMemberID != third || withImage[0].Members != 4 {
+		t.Errorf("GetCollectionsWithImage: %+v", withImage[0])
+	}
+	withImage, err = DB.GetCollectionsWithImage(second)
+	if err != nil || len(withImage) != 1 || withImage[0].PreviousMemberID != 0 || withImage[0].NextMemberID != fourth {
+		t.Errorf("GetCollectionsWithImage of the first member: %+v, %v", withImage, err)
+	}
+
+	//Removing closes the gap, and removing the last member removes the collection
+	if err := DB.RemoveCollectionMember(collection, fourth); err != nil {
+		t.Fatalf("RemoveCollectionMember: %v", err)
+	}
+	expectIDs(t, "members after removing", collectionOrder(t, DB, collection), second, third, first)
+	withImage, err = DB.GetCollectionsWithImage(fourth)
+	if err != nil || len(withImage) != 0 {
+		t.Errorf("GetCollectionsWithImage after removing: %+v, %v", withImage, err)
+	}
+	for _, imageID := range []uint64{second, first, third} {
+		if err := DB.RemoveCollectionMember(collection, imageID); err != nil {
+			t.Fatalf("RemoveCollectionMember: %v", err)
+		}
+	}
+	if _, err := DB.GetCollection(collection); err == nil {
+		t.Errorf("GetCollection found a collection with no members left")
+	}
+
+	//Deleting a collection leaves the images
+	other := mustNewCollection(t, DB, "Other", first, second)
+	if err := DB.DeleteCollection(other); err != nil {
+		t.Fatalf("DeleteCollection: %v", err)
+	}
+	if _, err := DB.GetCollection(other); err == nil {
+		t.Errorf("GetCollection found a deleted collection")
+	}
+	if _, err := DB.GetImage(first); err != nil {
+		t.Errorf("GetImage of a member of a deleted collection: %v", err)
+	}
+	withImage, err = DB.GetCollectionsWithImage(first)
+	if err != nil || len(withImage) != 0 {
+		t.Errorf("GetCollectionsWithImage after deleting the collection: %+v, %v", withImage, err)
+	}
+}
+
+func testCollectionTagSync(t *testing.T, DB interfaces.DBInterface) {
+	red := mustNewTag(t, DB, "red")
+	blue := mustNewTag(t, DB, "blue")
+	green := mustNewTag(t, DB, "green")
+	redImage := mustNewImage(t, DB, "red")
+	blueImage := mustNewImage(t, DB, "blue")
+	mustAddTag(t, DB, redImage, red)
+	mustAddTag(t, DB, blueImage, blue, red)
+
+	//A collection has every tag of its members
+	collection := mustNewCollection(t, DB, "Colours", redImage, blueImage)
+	expectStrings(t, "tags of new collection", collectionTagNames(t, DB, collection), "blue", "red")
+
+	//Tagging a member tags the collection
+	mustAddTag(t, DB, redImage, green)
+	expectStrings(t, "tags after tagging a member", collectionTagNames(t, DB, collection), "blue", "green", "red")
+
+	//Untagging removes the tag only once no member has it
+	if err := DB.RemoveTag(red, redImage); err != nil {
+		t.Fatalf("RemoveTag: %v", err)
+	}
+	expectStrings(t, "tags after untagging one of two", collectionTagNames(t, DB, collection), "blue", "green", "red")
+	if err := DB.RemoveTag(red, blueImage); err != nil {
+		t.Fatalf("RemoveTag: %v", err)
+	}
+	expectStrings(t, "tags after untagging the last", collectionTagNames(t, DB, collection), "blue", "green")
+
+	//Removing a member removes the tags only it had
+	if err := DB.RemoveCollectionMember(collection, blueImage); err != nil {
+		t.Fatalf("RemoveCollectionMember: %v", err)
+	}
+	expectStrings(t, "tags after removing a member", collectionTagNames(t, DB, collection), "green")
+
+	//Adding a member brings its tags
+	if err := DB.AddCollectionMember(collection, []uint64{blueImage}, 0); err != nil {
+		t.Fatalf("AddCollectionMember: %v", err)
+	}
+	expectStrings(t, "tags after adding a member", collectionTagNames(t, DB, collection), "blue", "green")
+
+	//Replacing a tag in bulk, as aliasing does, keeps the collection in step once fixed
+	if err := DB.ReplaceImageTags(blue, red, 0); err != nil {
+		t.Fatalf("ReplaceImageTags: %v", err)
+	}
+	if _, err := DB.FixCollectionTags(collection); err != nil {
+		t.Fatalf("FixCollectionTags: %v", err)
+	}
+	expectStrings(t, "tags after replacing and fixing", collectionTagNames(t, DB, collection), "green", "red")
+	changes, err := DB.FixCollectionTags(collection)
+	if err != nil || changes != 0 {
+		t.Errorf("FixCollectionTags on a collection in step: %d, %v", changes, err)
+	}
+
+	//Removing the image removes its tags from the collection
+	if err := DB.DeleteImage(blueImage); err != nil {
+		t.Fatalf("DeleteImage: %v", err)
+	}
+	expectStrings(t, "tags after deleting a member", collectionTagNames(t, DB, collection), "green")
+}
+
+func testCollectionSearch(t *testing.T, DB interfaces.DBInterface) {
+	red := mustNewTag(t, DB, "red")
+	blue := mustNewTag(t, DB, "blue")
+	alias := mustNewTag(t, DB, "crimson")
+	if err := DB.UpdateTag(alias, "crimson", "", red, true, 0); err != nil {
+		t.Fatalf("UpdateTag to alias: %v", err)
+	}
+	redImage := mustNewImage(t, DB, "red")
+	blueImage := mustNewImage(t, DB, "blue")
+	mustAddTag(t, DB, redImage, red)
+	mustAddTag(t, DB, blueImage, blue)
+	redCollection := mustNewCollection(t, DB, "Red Things", redImage)
+	blueCollection := mustNewCollection(t, DB, "Blue Things", blueImage)
+	bothCollection := mustNewCollection(t, DB, "All Colours", redImage, blueImage)
+
+	expectIDs(t, "search everything", searchCollectionIDs(t, DB, ""), bothCollection, blueCollection, redCollection)
+	expectIDs(t, "search red", searchCollectionIDs(t, DB, "red"), bothCollection, redCollection)
+	expectIDs(t, "search alias", searchCollectionIDs(t, DB, "crimson"), bothCollection, redCollection)
+	expectIDs(t, "search red and blue", searchCollectionIDs(t, DB, "red blue"), bothCollection)
+	expectIDs(t, "search not blue", searchCollectionIDs(t, DB, "-blue"), redCollection)
+	expectIDs(t, "search name", searchCollectionIDs(t, DB, "name:things"), blueCollection, redCollection)
+	expectIDs(t, "search negated name", searchCollectionIDs(t, DB, "-name:things"), bothCollection)
+	expectIDs(t, "search uploader", searchCollectionIDs(t, DB, "uploader:system"), bothCollection, blueCollection, redCollection)
+
+	//Image only metatags are not used in a collection context
+	for _, tag := range mustQueryTags(t, DB, "rating:safe", true) {
+		if tag.Exists {
+			t.Errorf("GetQueryTags in a collection context accepted %+v", tag)
+		}
+	}
+
+	collections, count, err := DB.SearchCollections(nil, 1, 1)
+	if err != nil || count != 3 || len(collections) != 1 || collections[0].ID != blueCollection || collections[0].Location != "blue.png" || collections[0].Members != 1 {
+		t.Errorf("SearchCollections paged: %+v, %d, %v", collections, count, err)
+	}
+}
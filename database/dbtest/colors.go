@@ -0,0 +1,124 @@
+package dbtest
+
+import (
+	"go-image-board/interfaces"
+	"reflect"
+	"testing"
+)
+
+func testImageColors(t *testing.T, DB interfaces.DBInterface) {
+	photo := mustNewImage(t, DB, "photo")
+	pending := mustNewImage(t, DB, "pending")
+
+	if colors, err := DB.GetImageColors(photo); err != nil || len(colors) != 0 {
+		t.Fatalf("GetImageColors before any were set: %+v, %v", colors, err)
+	}
+	if image, err := DB.GetImage(photo); err != nil || image.BlurHash != "" {
+		t.Fatalf("GetImage before a blurhash was set: %+v, %v", image, err)
+	}
+
+	//Given out of order to check they come back from most to least common
+	set := []interfaces.ImageColor{
+		{Red: 0xE9, Green: 0x16, Blue: 0x10, Share: 0.25},
+		{Red: 0x20, Green: 0x40, Blue: 0xF0, Share: 0.625},
+		{Red: 0x00, Green: 0xFF, Blue: 0x00, Share: 0.0625},
+	}
+	const blurHash = "LEHV6nWB2yk8pyo0adR*.7kCMdnj"
+	if err := DB.SetImageColors(photo, blurHash, set); err != nil {
+		t.Fatalf("SetImageColors: %v", err)
+	}
+	expected := []interfaces.ImageColor{set[1], set[0], set[2]}
+	colors, err := DB.GetImageColors(photo)
+	if err != nil || reflect.DeepEqual(colors, expected) == false {
+		t.Errorf("GetImageColors: %+v, %v, expected %+v", colors, err, expected)
+	}
+	if image, err := DB.GetImage(photo); err != nil || image.BlurHash != blurHash {
+		t.Errorf("GetImage BlurHash: %+v, %v", image, err)
+	}
+	if image, err := DB.GetImageByFileName("photo.png"); err != nil || image.BlurHash != blurHash {
+		t.Errorf("GetImageByFileName BlurHash: %+v, %v", image, err)
+	}
+
+	//Results pages draw placeholders, so searches and collections carry the blurhash too
+	images, _, err := DB.SearchImages(nil, 0, 10)
+	if err != nil || len(images) != 2 || images[0].ID != pending || images[0].BlurHash != "" || images[1].ID != photo || images[1].BlurHash != blurHash {
+		t.Errorf("SearchImages BlurHash: %+v, %v", images, err)
+	}
+	tagged := mustNewTag(t, DB, "tagged")
+	mustAddTag(t, DB, photo, tagged)
+	images, _, err = DB.SearchImages(mustQueryTags(t, DB, "tagged", false), 0, 10)
+	if err != nil || len(images) != 1 || images[0].BlurHash != blurHash {
+		t.Errorf("SearchImages with a tag BlurHash: %+v, %v", images, err)
+	}
+	collection := mustNewCollection(t, DB, "colourful", photo)
+	members, _, err := DB.GetCollectionMembers(collection, 0, 10)
+	if err != nil || len(members) != 1 || members[0].BlurHash != blurHash {
+		t.Errorf("GetCollectionMembers BlurHash: %+v, %v", members, err)
+	}
+
+	//Setting again replaces rather than adds
+	if err := DB.SetImageColors(photo, "00TI:j", set[:1]); err != nil {
+		t.Fatalf("SetImageColors replacing: %v", err)
+	}
+	colors, err = DB.GetImageColors(photo)
+	if err != nil || reflect.DeepEqual(colors, set[:1]) == false {
+		t.Errorf("GetImageColors after replacing: %+v, %v", colors, err)
+	}
+	if image, err := DB.GetImage(photo); err != nil || image.BlurHash != "00TI:j" {
+		t.Errorf("GetImage BlurHash after replacing: %+v, %v", image, err)
+	}
+
+	//Colours go with the image
+	if err := DB.DeleteImage(photo); err != nil {
+		t.Fatalf("DeleteImage: %v", err)
+	}
+	if colors, err := DB.GetImageColors(photo); err != nil || len(colors) != 0 {
+		t.Errorf("GetImageColors after DeleteImage: %+v, %v", colors, err)
+	}
+}
+
+func testColorSearch(t *testing.T, DB interfaces.DBInterface) {
+	plain := mustNewImage(t, DB, "plain")
+	sky := mustNewImage(t, DB, "sky")
+	sunset := mustNewImage(t, DB, "sunset")
+	navy := mustNewImage(t, DB, "navy")
+
+	for ID, colors := range map[uint64][]interfaces.ImageColor{
+		sky:    {{Red: 0x20, Green: 0x40, Blue: 0xF0, Share: 0.625}, {Red: 0xF0, Green: 0xF0, Blue: 0xF0, Share: 0.375}},
+		sunset: {{Red: 0xE9, Green: 0x16, Blue: 0x10, Share: 0.75}, {Red: 0x30, Green: 0x50, Blue: 0xD0, Share: 0.125}, {Red: 0xF0, Green: 0x8C, Blue: 0x1E, Share: 0.125}},
+		//Two shades of blue that only count as mostly blue together
+		navy: {{Red: 0x28, Green: 0x50, Blue: 0xC8, Share: 0.125}, {Red: 0x18, Green: 0x48, Blue: 0xE0, Share: 0.125}, {Red: 0x14, Green: 0x14, Blue: 0x14, Share: 0.75}},
+	} {
+		if err := DB.SetImageColors(ID, "", colors); err != nil {
+			t.Fatalf("SetImageColors: %v", err)
+		}
+	}
+
+	expectIDs(t, "color name", searchImageIDs(t, DB, "color:blue"), navy, sky)
+	expectIDs(t, "color hex", searchImageIDs(t, DB, "color:#2040f0"), navy, sky)
+	expectIDs(t, "color hex without #", searchImageIDs(t, DB, "color:2040F0"), navy, sky)
+	expectIDs(t, "color with a small tolerance", searchImageIDs(t, DB, "color:10-2040f0"), sky)
+	expectIDs(t, "color with a large tolerance", searchImageIDs(t, DB, "color:1000-red"), navy, sunset, sky)
+	expectIDs(t, "second colour", searchImageIDs(t, DB, "color:red"), sunset)
+	expectIDs(t, "negated color", searchImageIDs(t, DB, "-color:blue"), sunset, plain)
+	expectIDs(t, "negated white", searchImageIDs(t, DB, "-color:white"), navy, sunset, plain)
+
+	for _, query := range []string{"color:bleu", "color:#12345", "color:x-blue", "color:1-2-blue"} {
+		for _, tag := range mustQueryTags(t, DB, query, false) {
+			if tag.Exists {
+				t.Errorf("%q was accepted as %+v", query, tag)
+			}
+		}
+	}
+	//Collections have no colours
+	for _, tag := range mustQueryTags(t, DB, "color:blue", true) {
+		if tag.Exists {
+			t.Errorf("color was accepted for collections as %+v", tag)
+		}
+	}
+
+	prevNext, err := DB.GetPrevNexImages(mustQueryTags(t, DB, "-color:white", false), sunset)
+	if err != nil || len(prevNext) != 2 || prevNext[0].ID != navy || prevNext[1].ID != plain {
+		t.Errorf("GetPrevNexImages with color: %+v, %v", prevNext, err)
+	}
+}
@@ -0,0 +1,218 @@
+package dbtest
+
+import (
+	"fmt"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"go-image-board/plugins"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// NewDatabase returns an initialized and empty database, it is called once for every conformance test
+type NewDatabase func(t *testing.T) interfaces.DBInterface
+
+// RunConformance runs the behaviour every database plugin must share against databases created by newDB
+// Each test gets its own database, so tests may assume IDs start at 1
+func RunConformance(t *testing.T, newDB NewDatabase) {
+	if logging.LogInterface == nil {
+		quietLog := &plugins.STDLog{}
+		quietLog.Init(logging.LogLevelCritical, "", "")
+		logging.LogInterface = quietLog
+	}
+
+	tests := []struct {
+		Name string
+		Test func(t *testing.T, DB interfaces.DBInterface)
+	}{
+		{"Users", testUsers},
+		{"Tags", testTags},
+		{"Aliases", testAliases},
+		{"Search", testSearch},
+		{"MetaTags", testMetaTags},
+		{"Scores", testScores},
+		{"CollectionOrdering", testCollectionOrdering},
+		{"CollectionTagSync", testCollectionTagSync},
+		{"CollectionSearch", testCollectionSearch},
+		{"DeleteImage", testDeleteImage},
+		{"ImageMetadata", testImageMetadata},
+		{"MediaInfo", testMediaInfo},
+		{"ImageColors", testImageColors},
+		{"ColorSearch", testColorSearch},
+		{"Backup", testBackup},
+		{"Jobs", testJobs},
+		{"Transactions", testTransactions},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			test.Test(t, newDB(t))
+		})
+	}
+}
+
+// settleTimeout is how long to wait on work the SQL plugins push into the background
+var settleTimeout = 10 * time.Second
+
+// eventually polls condition until it passes, as some plugins update scores and aliases in the background
+func eventually(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(settleTimeout)
+	for condition() == false {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// mustNewImage adds an image uploaded by the system user, the location is derived from the name
+func mustNewImage(t *testing.T, DB interfaces.DBInterface, Name string) uint64 {
+	t.Helper()
+	ID, err := DB.NewImage(Name, Name+".png", 0, "")
+	if err != nil {
+		t.Fatalf("NewImage(%q): %v", Name, err)
+	}
+	return ID
+}
+
+func mustNewTag(t *testing.T, DB interfaces.DBInterface, Name string) uint64 {
+	t.Helper()
+	ID, err := DB.NewTag(Name, "", 0)
+	if err != nil {
+		t.Fatalf("NewTag(%q): %v", Name, err)
+	}
+	return ID
+}
+
+func mustAddTag(t *testing.T, DB interfaces.DBInterface, ImageID uint64, TagIDs ...uint64) {
+	t.Helper()
+	if err := DB.AddTag(TagIDs, ImageID, 0); err != nil {
+		t.Fatalf("AddTag(%v, %d): %v", TagIDs, ImageID, err)
+	}
+}
+
+func mustNewCollection(t *testing.T, DB interfaces.DBInterface, Name string, ImageIDs ...uint64) uint64 {
+	t.Helper()
+	ID, err := DB.NewCollection(Name, "", 0)
+	if err != nil {
+		t.Fatalf("NewCollection(%q): %v", Name, err)
+	}
+	if len(ImageIDs) > 0 {
+		if err := DB.AddCollectionMember(ID, ImageIDs, 0); err != nil {
+			t.Fatalf("AddCollectionMember(%d, %v): %v", ID, ImageIDs, err)
+		}
+	}
+	return ID
+}
+
+func mustQueryTags(t *testing.T, DB interfaces.DBInterface, Query string, CollectionContext bool) []interfaces.TagInformation {
+	t.Helper()
+	tags, err := DB.GetQueryTags(Query, CollectionContext)
+	if err != nil {
+		t.Fatalf("GetQueryTags(%q): %v", Query, err)
+	}
+	return tags
+}
+
+// searchImageIDs returns the IDs of every image matching a user query, in the order the plugin returned them
+func searchImageIDs(t *testing.T, DB interfaces.DBInterface, Query string) []uint64 {
+	t.Helper()
+	images, count, err := DB.SearchImages(mustQueryTags(t, DB, Query, false), 0, 100)
+	if err != nil {
+		t.Fatalf("SearchImages(%q): %v", Query, err)
+	}
+	if count != uint64(len(images)) {
+		t.Errorf("SearchImages(%q) counted %d results but returned %d", Query, count, len(images))
+	}
+	var ToReturn []uint64
+	for _, image := range images {
+		ToReturn = append(ToReturn, image.ID)
+	}
+	return ToReturn
+}
+
+// searchCollectionIDs returns the IDs of every collection matching a user query, in the order the plugin returned them
+func searchCollectionIDs(t *testing.T, DB interfaces.DBInterface, Query string) []uint64 {
+	t.Helper()
+	collections, count, err := DB.SearchCollections(mustQueryTags(t, DB, Query, true), 0, 100)
+	if err != nil {
+		t.Fatalf("SearchCollections(%q): %v", Query, err)
+	}
+	if count != uint64(len(collections)) {
+		t.Errorf("SearchCollections(%q) counted %d results but returned %d", Query, count, len(collections))
+	}
+	var ToReturn []uint64
+	for _, collection := range collections {
+		ToReturn = append(ToReturn, collection.ID)
+	}
+	return ToReturn
+}
+
+// tagNames returns the sorted names of a list of tags, for comparisons that do not care about order
+func tagNames(Tags []interfaces.TagInformation) []string {
+	var ToReturn []string
+	for _, tag := range Tags {
+		ToReturn = append(ToReturn, tag.Name)
+	}
+	sort.Strings(ToReturn)
+	return ToReturn
+}
+
+func imageTagNames(t *testing.T, DB interfaces.DBInterface, ImageID uint64) []string {
+	t.Helper()
+	tags, err := DB.GetImageTags(ImageID)
+	if err != nil {
+		t.Fatalf("GetImageTags(%d): %v", ImageID, err)
+	}
+	return tagNames(tags)
+}
+
+func collectionTagNames(t *testing.T, DB interfaces.DBInterface, CollectionID uint64) []string {
+	t.Helper()
+	tags, err := DB.GetCollectionTags(CollectionID)
+	if err != nil {
+		t.Fatalf("GetCollectionTags(%d): %v", CollectionID, err)
+	}
+	return tagNames(tags)
+}
+
+// collectionOrder returns a collection's member IDs in order, failing if the order weights are not 0 to n-1
+func collectionOrder(t *testing.T, DB interfaces.DBInterface, CollectionID uint64) []uint64 {
+	t.Helper()
+	members, count, err := DB.GetCollectionMembers(CollectionID, 0, 100)
+	if err != nil {
+		t.Fatalf("GetCollectionMembers(%d): %v", CollectionID, err)
+	}
+	if count != uint64(len(members)) {
+		t.Errorf("GetCollectionMembers(%d) counted %d members but returned %d", CollectionID, count, len(members))
+	}
+	var ToReturn []uint64
+	for index, member := range members {
+		if member.OrderInCollection != uint64(index) {
+			t.Errorf("member %d of collection %d has order %d, expected %d", member.ID, CollectionID, member.OrderInCollection, index)
+		}
+		ToReturn = append(ToReturn, member.ID)
+	}
+	return ToReturn
+}
+
+func idText(ID uint64) string {
+	return strconv.FormatUint(ID, 10)
+}
+
+func expectIDs(t *testing.T, What string, Got []uint64, Expected ...uint64) {
+	t.Helper()
+	if fmt.Sprint(Got) != fmt.Sprint(Expected) {
+		t.Errorf("%s: got %v, expected %v", What, Got, Expected)
+	}
+}
+
+func expectStrings(t *testing.T, What string, Got []string, Expected ...string) {
+	t.Helper()
+	if fmt.Sprintf("%q", Got) != fmt.Sprintf("%q", Expected) {
+		t.Errorf("%s: got %q, expected %q", What, Got, Expected)
+	}
+}
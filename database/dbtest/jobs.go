@@ -0,0 +1,114 @@
+package dbtest
+
+import (
+	"database/sql"
+	"go-image-board/interfaces"
+	"testing"
+	"time"
+)
+
+func testJobs(t *testing.T, DB interfaces.DBInterface) {
+	if _, err := DB.ClaimJob(time.Hour); err != sql.ErrNoRows {
+		t.Fatalf("ClaimJob of an empty queue: %v", err)
+	}
+	firstID, err := DB.AddJob("first", "payload", 3)
+	if err != nil {
+		t.Fatalf("AddJob: %v", err)
+	}
+	secondID, err := DB.AddJob("second", "", 1)
+	if err != nil {
+		t.Fatalf("AddJob: %v", err)
+	}
+
+	//Jobs are claimed oldest first, and only once
+	first, err := DB.ClaimJob(time.Hour)
+	if err != nil || first.ID != firstID || first.Type != "first" || first.Payload != "payload" || first.Status != interfaces.JobRunning || first.Attempts != 1 || first.MaxAttempts != 3 || first.CreationTime.IsZero() {
+		t.Fatalf("first ClaimJob: %+v, %v", first, err)
+	}
+	if second, err := DB.ClaimJob(time.Hour); err != nil || second.ID != secondID {
+		t.Fatalf("second ClaimJob: %+v, %v", second, err)
+	}
+	if job, err := DB.ClaimJob(time.Hour); err != sql.ErrNoRows {
+		t.Fatalf("ClaimJob claimed a running job: %+v, %v", job, err)
+	}
+
+	if err := DB.UpdateJobProgress(firstID, 5, 10); err != nil {
+		t.Fatalf("UpdateJobProgress: %v", err)
+	}
+	if job, err := DB.GetJob(firstID); err != nil || job.Progress != 5 || job.Total != 10 {
+		t.Errorf("GetJob after UpdateJobProgress: %+v, %v", job, err)
+	}
+
+	//A retried job waits out its delay
+	if err := DB.FinishJob(firstID, interfaces.JobQueued, "try again", time.Hour); err != nil {
+		t.Fatalf("FinishJob: %v", err)
+	}
+	if job, err := DB.ClaimJob(time.Hour); err != sql.ErrNoRows {
+		t.Fatalf("ClaimJob claimed a job before its retry delay: %+v, %v", job, err)
+	}
+	if err := DB.FinishJob(firstID, interfaces.JobQueued, "try again", 0); err != nil {
+		t.Fatalf("FinishJob: %v", err)
+	}
+	if job, err := DB.ClaimJob(time.Hour); err != nil || job.ID != firstID || job.Attempts != 2 || job.LastError != "try again" {
+		t.Fatalf("ClaimJob of a retried job: %+v, %v", job, err)
+	}
+
+	//A running job that stops updating is claimed again once its lease runs out
+	if job, err := DB.ClaimJob(-time.Minute); err != nil || job.ID != firstID || job.Attempts != 3 {
+		t.Fatalf("ClaimJob of a lost job: %+v, %v", job, err)
+	}
+
+	if err := DB.FinishJob(firstID, interfaces.JobDone, "", 0); err != nil {
+		t.Fatalf("FinishJob: %v", err)
+	}
+	if err := DB.FinishJob(secondID, interfaces.JobFailed, "broken", 0); err != nil {
+		t.Fatalf("FinishJob: %v", err)
+	}
+	if job, err := DB.ClaimJob(-time.Minute); err != sql.ErrNoRows {
+		t.Fatalf("ClaimJob claimed a finished job: %+v, %v", job, err)
+	}
+	if job, err := DB.GetJob(secondID); err != nil || job.Status != interfaces.JobFailed || job.LastError != "broken" {
+		t.Errorf("GetJob of a failed job: %+v, %v", job, err)
+	}
+	if _, err := DB.GetJob(secondID + 100); err == nil {
+		t.Errorf("GetJob of a missing job did not fail")
+	}
+
+	thirdID, err := DB.AddJob("third", "", 1)
+	if err != nil {
+		t.Fatalf("AddJob: %v", err)
+	}
+	if job, err := DB.GetLatestJob("second"); err != nil || job.ID != secondID {
+		t.Errorf("GetLatestJob: %+v, %v", job, err)
+	}
+	if _, err := DB.GetLatestJob("never"); err != sql.ErrNoRows {
+		t.Errorf("GetLatestJob of a type never queued: %v", err)
+	}
+	if job, err := DB.GetLatestJobWithPayload("first", "payload"); err != nil || job.ID != firstID {
+		t.Errorf("GetLatestJobWithPayload: %+v, %v", job, err)
+	}
+	if job, err := DB.GetLatestJobWithPayload("second", ""); err != nil || job.ID != secondID {
+		t.Errorf("GetLatestJobWithPayload with no payload: %+v, %v", job, err)
+	}
+	if _, err := DB.GetLatestJobWithPayload("first", "other"); err != sql.ErrNoRows {
+		t.Errorf("GetLatestJobWithPayload of a payload never queued: %v", err)
+	}
+	jobs, count, err := DB.GetJobs("", 0, 2)
+	if err != nil || count != 3 || len(jobs) != 2 || jobs[0].ID != thirdID || jobs[1].ID != secondID {
+		t.Errorf("GetJobs newest first: %+v, %d, %v", jobs, count, err)
+	}
+	jobs, count, err = DB.GetJobs(interfaces.JobDone, 0, 10)
+	if err != nil || count != 1 || len(jobs) != 1 || jobs[0].ID != firstID {
+		t.Errorf("GetJobs done: %+v, %d, %v", jobs, count, err)
+	}
+
+	if removed, err := DB.RemoveJobs(interfaces.JobDone, time.Hour); err != nil || removed != 0 {
+		t.Errorf("RemoveJobs removed recent jobs: %d, %v", removed, err)
+	}
+	if removed, err := DB.RemoveJobs(interfaces.JobDone, -time.Minute); err != nil || removed != 1 {
+		t.Errorf("RemoveJobs: %d, %v", removed, err)
+	}
+	if _, count, err := DB.GetJobs("", 0, 10); err != nil || count != 2 {
+		t.Errorf("jobs left after RemoveJobs: %d, %v", count, err)
+	}
+}
@@ -0,0 +1,87 @@
+package dbtest
+
+import (
+	"go-image-board/interfaces"
+	"testing"
+)
+
+func testMediaInfo(t *testing.T, DB interfaces.DBInterface) {
+	pending := mustNewImage(t, DB, "pending")
+	song := mustNewImage(t, DB, "song")
+	clip := mustNewImage(t, DB, "clip")
+	square := mustNewImage(t, DB, "square")
+	classic := mustNewImage(t, DB, "classic")
+	wide := mustNewImage(t, DB, "wide")
+
+	if image, err := DB.GetImage(pending); err != nil || image.Width != 0 || image.Height != 0 || image.FileSize != 0 || image.MIMEType != "" || image.Duration != 0 || image.Bitrate != 0 {
+		t.Fatalf("GetImage of an unprocessed image: %+v, %v", image, err)
+	}
+
+	for _, info := range []interfaces.ImageInformation{
+		{ID: song, FileSize: 3 << 20, MIMEType: "audio/mpeg", Duration: 20, Bitrate: 320000},
+		{ID: clip, Width: 1280, Height: 720, FileSize: 10 << 20, MIMEType: "video/mp4", Duration: 45.5, Bitrate: 1843000},
+		{ID: square, Width: 1000, Height: 1000, FileSize: 5<<20 + 1, MIMEType: "image/gif"},
+		{ID: classic, Width: 1366, Height: 768, FileSize: 300 << 10, MIMEType: "image/jpeg"},
+		{ID: wide, Width: 1920, Height: 1080, FileSize: 2 << 20, MIMEType: "image/png"},
+	} {
+		if err := DB.SetImageMediaInfo(info); err != nil {
+			t.Fatalf("SetImageMediaInfo(%d): %v", info.ID, err)
+		}
+	}
+	image, err := DB.GetImage(clip)
+	if err != nil || image.Width != 1280 || image.Height != 720 || image.FileSize != 10<<20 || image.MIMEType != "video/mp4" || image.Duration != 45.5 || image.Bitrate != 1843000 {
+		t.Errorf("GetImage after SetImageMediaInfo: %+v, %v", image, err)
+	}
+	image, err = DB.GetImageByFileName("wide.png")
+	if err != nil || image.Width != 1920 || image.Height != 1080 || image.FileSize != 2<<20 || image.MIMEType != "image/png" {
+		t.Errorf("GetImageByFileName after SetImageMediaInfo: %+v, %v", image, err)
+	}
+
+	expectIDs(t, "width", searchImageIDs(t, DB, "width:1920"), wide)
+	expectIDs(t, "width greater than", searchImageIDs(t, DB, "width:>1300"), wide, classic)
+	expectIDs(t, "height at most", searchImageIDs(t, DB, "height:<=720"), clip, song, pending)
+	expectIDs(t, "negated height", searchImageIDs(t, DB, "-height:0"), wide, classic, square, clip)
+
+	//Ratios are matched loosely so common resolutions that round differently still match
+	expectIDs(t, "ratio", searchImageIDs(t, DB, "ratio:16:9"), wide, classic, clip)
+	expectIDs(t, "ratio with a slash", searchImageIDs(t, DB, "ratio:16/9"), wide, classic, clip)
+	expectIDs(t, "decimal ratio", searchImageIDs(t, DB, "ratio:1"), square)
+	expectIDs(t, "ratio greater than", searchImageIDs(t, DB, "ratio:>1.5"), wide, classic, clip)
+	expectIDs(t, "negated ratio", searchImageIDs(t, DB, "-ratio:16:9"), square)
+
+	expectIDs(t, "filesize", searchImageIDs(t, DB, "filesize:2mb"), wide)
+	expectIDs(t, "filesize in bytes", searchImageIDs(t, DB, "filesize:2097152b"), wide)
+	expectIDs(t, "filesize less than", searchImageIDs(t, DB, "filesize:<1mb"), classic, pending)
+	expectIDs(t, "filesize at least", searchImageIDs(t, DB, "filesize:>=5mb"), square, clip)
+	expectIDs(t, "decimal filesize", searchImageIDs(t, DB, "filesize:>2.5mb type:audio"), song)
+
+	expectIDs(t, "duration", searchImageIDs(t, DB, "duration:>30"), clip)
+	expectIDs(t, "exact duration", searchImageIDs(t, DB, "duration:20"), song)
+
+	expectIDs(t, "type kind", searchImageIDs(t, DB, "type:video"), clip)
+	expectIDs(t, "type kind of images", searchImageIDs(t, DB, "type:image"), wide, classic, square)
+	expectIDs(t, "negated type kind", searchImageIDs(t, DB, "-type:image"), clip, song, pending)
+	expectIDs(t, "type extension", searchImageIDs(t, DB, "type:jpeg"), classic)
+	expectIDs(t, "type MIME", searchImageIDs(t, DB, "type:image/gif"), square)
+	expectIDs(t, "negated type MIME", searchImageIDs(t, DB, "-type:image/gif width:>1000"), wide, classic, clip)
+	expectIDs(t, "type with another metatag", searchImageIDs(t, DB, "type:png width:>1000"), wide)
+
+	for _, query := range []string{"width:wide", "filesize:big", "filesize:-1mb", "ratio:16:0", "ratio:wide", "duration:long", "type:document"} {
+		for _, tag := range mustQueryTags(t, DB, query, false) {
+			if tag.Exists {
+				t.Errorf("%q was accepted as %+v", query, tag)
+			}
+		}
+	}
+	//Collections have no dimensions
+	for _, tag := range mustQueryTags(t, DB, "width:1920", true) {
+		if tag.Exists {
+			t.Errorf("width was accepted for collections as %+v", tag)
+		}
+	}
+
+	prevNext, err := DB.GetPrevNexImages(mustQueryTags(t, DB, "ratio:16:9", false), classic)
+	if err != nil || len(prevNext) != 2 || prevNext[0].ID != wide || prevNext[1].ID != clip {
+		t.Errorf("GetPrevNexImages with ratio: %+v, %v", prevNext, err)
+	}
+}
@@ -0,0 +1,68 @@
+package dbtest
+
+import (
+	"database/sql"
+	"go-image-board/interfaces"
+	"testing"
+	"time"
+)
+
+func testImageMetadata(t *testing.T, DB interfaces.DBInterface) {
+	photo := mustNewImage(t, DB, "photo")
+	drawing := mustNewImage(t, DB, "drawing")
+	song := mustNewImage(t, DB, "song")
+	if _, err := DB.GetImageMetadata(photo); err != sql.ErrNoRows {
+		t.Fatalf("GetImageMetadata of an image without metadata: %v", err)
+	}
+
+	metadata := interfaces.ImageMetadata{
+		ImageID:      photo,
+		CaptureTime:  time.Date(2021, 6, 5, 14, 30, 15, 0, time.UTC),
+		CameraMake:   "Maker",
+		CameraModel:  "Model 1",
+		LensModel:    "50mm f/1.8",
+		ExposureTime: "1/250 s",
+		FNumber:      "f/2.8",
+		FocalLength:  "50 mm",
+		ISO:          400,
+		Orientation:  6,
+	}
+	if err := DB.SetImageMetadata(metadata); err != nil {
+		t.Fatalf("SetImageMetadata: %v", err)
+	}
+	stored, err := DB.GetImageMetadata(photo)
+	if err != nil || stored.CaptureTime.Equal(metadata.CaptureTime) == false {
+		t.Fatalf("GetImageMetadata: %+v, %v", stored, err)
+	}
+	stored.CaptureTime = metadata.CaptureTime
+	if stored != metadata {
+		t.Errorf("GetImageMetadata = %+v, want %+v", stored, metadata)
+	}
+
+	//Setting it again replaces everything, and a zero capture time stays zero
+	replacement := interfaces.ImageMetadata{ImageID: photo, CameraModel: "Model 2", Orientation: 1}
+	if err := DB.SetImageMetadata(replacement); err != nil {
+		t.Fatalf("SetImageMetadata replacing: %v", err)
+	}
+	if stored, err := DB.GetImageMetadata(photo); err != nil || stored != replacement {
+		t.Errorf("GetImageMetadata after replacing = %+v, %v, want %+v", stored, err, replacement)
+	}
+	//Audio keeps its tags in the same row, and text outside of ASCII comes back as it went in
+	tags := interfaces.ImageMetadata{ImageID: song, Title: "Ünïcode ♪", Artist: "Artist", Album: "Album", Genre: "Synthpop"}
+	if err := DB.SetImageMetadata(tags); err != nil {
+		t.Fatalf("SetImageMetadata of audio: %v", err)
+	}
+	if stored, err := DB.GetImageMetadata(song); err != nil || stored != tags {
+		t.Errorf("GetImageMetadata of audio = %+v, %v, want %+v", stored, err, tags)
+	}
+	if _, err := DB.GetImageMetadata(drawing); err != sql.ErrNoRows {
+		t.Errorf("GetImageMetadata of another image: %v", err)
+	}
+
+	if err := DB.DeleteImage(photo); err != nil {
+		t.Fatalf("DeleteImage: %v", err)
+	}
+	if _, err := DB.GetImageMetadata(photo); err != sql.ErrNoRows {
+		t.Errorf("GetImageMetadata found the metadata of a deleted image: %v", err)
+	}
+}
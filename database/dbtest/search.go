@@ -0,0 +1,256 @@
+package dbtest
+
+import (
+	"go-image-board/interfaces"
+	"testing"
+)
+
+func testSearch(t *testing.T, DB interfaces.DBInterface) {
+	red := mustNewTag(t, DB, "red")
+	blue := mustNewTag(t, DB, "blue")
+	mustNewTag(t, DB, "unused")
+	redImage := mustNewImage(t, DB, "red")
+	purpleImage := mustNewImage(t, DB, "purple")
+	blueImage := mustNewImage(t, DB, "blue")
+	plainImage := mustNewImage(t, DB, "plain")
+	mustAddTag(t, DB, redImage, red)
+	mustAddTag(t, DB, purpleImage, red, blue)
+	mustAddTag(t, DB, blueImage, blue)
+
+	//Newest first, every included tag must match, and no excluded tag may
+	expectIDs(t, "search everything", searchImageIDs(t, DB, ""), plainImage, blueImage, purpleImage, redImage)
+	expectIDs(t, "search red", searchImageIDs(t, DB, "RED"), purpleImage, redImage)
+	expectIDs(t, "search red and blue", searchImageIDs(t, DB, "red blue"), purpleImage)
+	expectIDs(t, "search red but not blue", searchImageIDs(t, DB, "red -blue"), redImage)
+	expectIDs(t, "search not red", searchImageIDs(t, DB, "-red"), plainImage, blueImage)
+	expectIDs(t, "search unused tag", searchImageIDs(t, DB, "unused"))
+
+	//Query parsing
+	tags := mustQueryTags(t, DB, "red -blue missing", false)
+	if len(tags) != 3 {
+		t.Fatalf("GetQueryTags returned %+v", tags)
+	}
+	for _, tag := range tags {
+		switch tag.Name {
+		case "red":
+			if tag.ID != red || tag.Exists == false || tag.Exclude {
+				t.Errorf("GetQueryTags parsed red as %+v", tag)
+			}
+		case "blue":
+			if tag.ID != blue || tag.Exists == false || tag.Exclude == false {
+				t.Errorf("GetQueryTags parsed -blue as %+v", tag)
+			}
+		case "missing":
+			if tag.Exists {
+				t.Errorf("GetQueryTags parsed missing as %+v", tag)
+			}
+		default:
+			t.Errorf("GetQueryTags returned unexpected tag %+v", tag)
+		}
+	}
+	expectStrings(t, "GetQueryTags with quotes", tagNames(mustQueryTags(t, DB, "\"Light Red\" blue", false)), "blue", "light_red")
+
+	//Paging keeps the total count
+	images, count, err := DB.SearchImages(nil, 1, 2)
+	if err != nil || count != 4 || len(images) != 2 || images[0].ID != blueImage || images[1].ID != purpleImage {
+		t.Errorf("SearchImages paged: %+v, %d, %v", images, count, err)
+	}
+	images, count, err = DB.SearchImages(nil, 10, 2)
+	if err != nil || count != 4 || len(images) != 0 {
+		t.Errorf("SearchImages past the end: %+v, %d, %v", images, count, err)
+	}
+
+	//Previous and next within a search, next (the newer image) comes first
+	_, err = DB.GetPrevNexImages(nil, 0)
+	if err == nil {
+		t.Errorf("GetPrevNexImages accepted a target of 0")
+	}
+	prevNext, err := DB.GetPrevNexImages(nil, purpleImage)
+	if err != nil || len(prevNext) != 2 || prevNext[0].ID != blueImage || prevNext[1].ID != redImage {
+		t.Errorf("GetPrevNexImages: %+v, %v", prevNext, err)
+	}
+	prevNext, err = DB.GetPrevNexImages(mustQueryTags(t, DB, "blue", false), blueImage)
+	if err != nil || len(prevNext) != 1 || prevNext[0].ID != purpleImage {
+		t.Errorf("GetPrevNexImages with tags: %+v, %v", prevNext, err)
+	}
+
+	//Random picks from the matches
+	for attempt := 0; attempt < 5; attempt++ {
+		image, count, err := DB.GetRandomImage(mustQueryTags(t, DB, "red", false))
+		if err != nil || count != 2 || (image.ID != redImage && image.ID != purpleImage) {
+			t.Errorf("GetRandomImage: %+v, %d, %v", image, count, err)
+		}
+	}
+	if _, _, err := DB.GetRandomImage(mustQueryTags(t, DB, "unused", false)); err == nil {
+		t.Errorf("GetRandomImage found an image with no matches")
+	}
+}
+
+func testMetaTags(t *testing.T, DB interfaces.DBInterface) {
+	if err := DB.CreateUser("Uploader", []byte("Password1"), "uploader@example.com", 0); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	uploaderID, err := DB.GetUserID("Uploader")
+	if err != nil {
+		t.Fatalf("GetUserID: %v", err)
+	}
+	red := mustNewTag(t, DB, "red")
+	blue := mustNewTag(t, DB, "blue")
+	sunset, err := DB.NewImage("Sunset Beach", "sunset.png", uploaderID, "")
+	if err != nil {
+		t.Fatalf("NewImage: %v", err)
+	}
+	city := mustNewImage(t, DB, "Night City")
+	party := mustNewImage(t, DB, "Beach Party")
+	mustAddTag(t, DB, sunset, red, blue)
+	mustAddTag(t, DB, city, red)
+	for _, rating := range []struct {
+		ID     uint64
+		Rating string
+	}{{sunset, "safe"}, {city, "explicit"}, {party, "safe"}} {
+		if err := DB.SetImageRating(rating.ID, rating.Rating); err != nil {
+			t.Fatalf("SetImageRating: %v", err)
+		}
+	}
+
+	expectIDs(t, "rating", searchImageIDs(t, DB, "rating:safe"), party, sunset)
+	expectIDs(t, "negated rating", searchImageIDs(t, DB, "-rating:safe"), city)
+	expectIDs(t, "rating with a tag", searchImageIDs(t, DB, "red rating:safe"), sunset)
+	expectIDs(t, "uploader", searchImageIDs(t, DB, "uploader:uploader"), sunset)
+	expectIDs(t, "negated uploader", searchImageIDs(t, DB, "-uploader:uploader"), party, city)
+	expectIDs(t, "name", searchImageIDs(t, DB, "name:beach"), party, sunset)
+	expectIDs(t, "negated name", searchImageIDs(t, DB, "-name:beach"), city)
+	expectIDs(t, "location", searchImageIDs(t, DB, "location:sunset"), sunset)
+
+	//Images without tags never match a tag count
+	expectIDs(t, "tagcount", searchImageIDs(t, DB, "tagcount:2"), sunset)
+	expectIDs(t, "tagcount at least", searchImageIDs(t, DB, "tagcount:>=1"), city, sunset)
+	expectIDs(t, "tagcount less than", searchImageIDs(t, DB, "tagcount:<2"), city)
+
+	mustNewCollection(t, DB, "Trip", city)
+	expectIDs(t, "in a collection", searchImageIDs(t, DB, "incollection:true"), city)
+	expectIDs(t, "not in a collection", searchImageIDs(t, DB, "incollection:n"), party, sunset)
+	expectIDs(t, "negated in a collection", searchImageIDs(t, DB, "-incollection:true"), party, sunset)
+
+	//Similar includes the image itself, at the default threshold of 26 differing bits
+	for _, hash := range []struct {
+		ID    uint64
+		hHash uint64
+		vHash uint64
+	}{{sunset, 0, 0}, {city, 0x3, 0x1}, {party, 0xFFFF, 0xFFFF}} {
+		if err := DB.SetImagedHash(hash.ID, hash.hHash, hash.vHash); err != nil {
+			t.Fatalf("SetImagedHash: %v", err)
+		}
+	}
+	hHash, vHash, err := DB.GetImagedHash(city)
+	if err != nil || hHash != 0x3 || vHash != 0x1 {
+		t.Errorf("GetImagedHash: %d, %d, %v", hHash, vHash, err)
+	}
+	expectIDs(t, "similar", searchImageIDs(t, DB, "similar:"+idText(sunset)), city, sunset)
+	expectIDs(t, "similar with threshold", searchImageIDs(t, DB, "similar:2-"+idText(sunset)), sunset)
+	expectIDs(t, "similar with wide threshold", searchImageIDs(t, DB, "similar:40-"+idText(sunset)), party, city, sunset)
+
+	//Scores
+	for _, vote := range []struct {
+		UserID  uint64
+		ImageID uint64
+		Score   int64
+	}{{1, sunset, 5}, {2, sunset, 3}, {1, city, 1}} {
+		if err := DB.UpdateUserVoteScore(vote.UserID, vote.ImageID, vote.Score); err != nil {
+			t.Fatalf("UpdateUserVoteScore: %v", err)
+		}
+	}
+	for _, imageID := range []uint64{sunset, city} {
+		if err := DB.UpdateScoreOnImage(imageID); err != nil {
+			t.Fatalf("UpdateScoreOnImage: %v", err)
+		}
+	}
+	eventually(t, "scores to update", func() bool {
+		image, err := DB.GetImage(sunset)
+		return err == nil && image.ScoreVoters == 2
+	})
+	expectIDs(t, "score", searchImageIDs(t, DB, "score:>=4"), sunset)
+	expectIDs(t, "averagescore", searchImageIDs(t, DB, "averagescore:1"), city)
+	expectIDs(t, "totalscore", searchImageIDs(t, DB, "totalscore:>1"), sunset)
+	expectIDs(t, "scorevoters", searchImageIDs(t, DB, "scorevoters:0"), party)
+}
+
+func testScores(t *testing.T, DB interfaces.DBInterface) {
+	image := mustNewImage(t, DB, "scored")
+	score, err := DB.GetUserVoteScore(1, image)
+	if err != nil || score != 0 {
+		t.Errorf("GetUserVoteScore before voting: %d, %v", score, err)
+	}
+
+	votes := []struct {
+		UserID  uint64
+		Score   int64
+		Total   int64
+		Average int64
+		Voters  int64
+	}{
+		{1, 4, 4, 4, 1},
+		{2, 1, 5, 3, 2}, //2.5 rounds away from zero
+		{1, -2, -1, -1, 2},
+	}
+	for _, vote := range votes {
+		if err := DB.UpdateUserVoteScore(vote.UserID, image, vote.Score); err != nil {
+			t.Fatalf("UpdateUserVoteScore: %v", err)
+		}
+		if err := DB.UpdateScoreOnImage(image); err != nil {
+			t.Fatalf("UpdateScoreOnImage: %v", err)
+		}
+		vote := vote
+		eventually(t, "image score to update", func() bool {
+			imageInfo, err := DB.GetImage(image)
+			return err == nil && imageInfo.ScoreTotal == vote.Total && imageInfo.ScoreAverage == vote.Average && imageInfo.ScoreVoters == vote.Voters
+		})
+		score, err := DB.GetUserVoteScore(vote.UserID, image)
+		if err != nil || score != vote.Score {
+			t.Errorf("GetUserVoteScore: %d, %v", score, err)
+		}
+	}
+}
+
+func testDeleteImage(t *testing.T, DB interfaces.DBInterface) {
+	red := mustNewTag(t, DB, "red")
+	blue := mustNewTag(t, DB, "blue")
+	first := mustNewImage(t, DB, "first")
+	second := mustNewImage(t, DB, "second")
+	third := mustNewImage(t, DB, "third")
+	mustAddTag(t, DB, first, red)
+	mustAddTag(t, DB, second, red, blue)
+	mustAddTag(t, DB, third, red)
+	collection := mustNewCollection(t, DB, "Set", first, second, third)
+	if err := DB.SetImagedHash(second, 1, 1); err != nil {
+		t.Fatalf("SetImagedHash: %v", err)
+	}
+	if err := DB.UpdateUserVoteScore(1, second, 5); err != nil {
+		t.Fatalf("UpdateUserVoteScore: %v", err)
+	}
+
+	if err := DB.DeleteImage(second); err != nil {
+		t.Fatalf("DeleteImage: %v", err)
+	}
+	if _, err := DB.GetImage(second); err == nil {
+		t.Errorf("GetImage found a deleted image")
+	}
+	if _, err := DB.GetImageByFileName("second.png"); err == nil {
+		t.Errorf("GetImageByFileName found a deleted image")
+	}
+	expectIDs(t, "search after delete", searchImageIDs(t, DB, "red"), third, first)
+	expectIDs(t, "members after delete", collectionOrder(t, DB, collection), first, third)
+	expectStrings(t, "collection tags after delete", collectionTagNames(t, DB, collection), "red")
+	tagInfo, err := DB.GetTag(blue, true)
+	if err != nil || tagInfo.UseCount != 0 {
+		t.Errorf("GetTag after delete: %+v, %v", tagInfo, err)
+	}
+	if _, _, err := DB.GetImagedHash(second); err == nil {
+		t.Errorf("GetImagedHash found the hash of a deleted image")
+	}
+
+	//The file name can be reused
+	if _, err := DB.NewImage("second again", "second.png", 0, ""); err != nil {
+		t.Errorf("NewImage reusing a deleted location: %v", err)
+	}
+}
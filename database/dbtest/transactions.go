@@ -0,0 +1,115 @@
+package dbtest
+
+import (
+	"errors"
+	"go-image-board/interfaces"
+	"testing"
+)
+
+// errRollback is returned from transactions that should be rolled back
+var errRollback = errors.New("roll back")
+
+func testTransactions(t *testing.T, DB interfaces.DBInterface) {
+	keptImage := mustNewImage(t, DB, "kept")
+	keptTag := mustNewTag(t, DB, "kept")
+	keptCollection, err := DB.NewCollection("kept", "", 0)
+	if err != nil {
+		t.Fatalf("NewCollection: %v", err)
+	}
+	if err := DB.AddCollectionMember(keptCollection, []uint64{keptImage}, 0); err != nil {
+		t.Fatalf("AddCollectionMember: %v", err)
+	}
+
+	//Everything done in a transaction that fails is undone, including deletes
+	var rolledBackImage uint64
+	err = DB.RunInTransaction(func(Transaction interfaces.DBInterface) error {
+		var err error
+		if rolledBackImage, err = Transaction.NewImage("rolledback", "rolledback.png", 0, ""); err != nil {
+			return err
+		}
+		tagID, err := Transaction.NewTag("rolledback", "", 0)
+		if err != nil {
+			return err
+		}
+		if err := Transaction.AddTag([]uint64{tagID, keptTag}, rolledBackImage, 0); err != nil {
+			return err
+		}
+		collectionID, err := Transaction.NewCollection("rolledback", "", 0)
+		if err != nil {
+			return err
+		}
+		if err := Transaction.AddCollectionMember(collectionID, []uint64{rolledBackImage}, 0); err != nil {
+			return err
+		}
+		if err := Transaction.DeleteCollection(keptCollection); err != nil {
+			return err
+		}
+		if err := Transaction.DeleteImage(keptImage); err != nil {
+			return err
+		}
+		//The transaction sees its own changes
+		if image, err := Transaction.GetImage(rolledBackImage); err != nil || image.Name != "rolledback" {
+			t.Errorf("GetImage inside transaction: %+v, %v", image, err)
+		}
+		return errRollback
+	})
+	if err != errRollback {
+		t.Fatalf("RunInTransaction should return Work's error, got %v", err)
+	}
+	if image, err := DB.GetImageByFileName("rolledback.png"); err == nil {
+		t.Errorf("image added in a rolled back transaction exists: %+v", image)
+	}
+	if tag, err := DB.GetTagByName("rolledback"); err == nil {
+		t.Errorf("tag added in a rolled back transaction exists: %+v", tag)
+	}
+	if collection, err := DB.GetCollectionByName("rolledback"); err == nil {
+		t.Errorf("collection added in a rolled back transaction exists: %+v", collection)
+	}
+	if _, err := DB.GetImage(keptImage); err != nil {
+		t.Errorf("image deleted in a rolled back transaction is gone: %v", err)
+	}
+	if members, _, err := DB.GetCollectionMembers(keptCollection, 0, 0); err != nil || len(members) != 1 {
+		t.Errorf("collection deleted in a rolled back transaction lost members: %+v, %v", members, err)
+	}
+
+	//A nested transaction joins the outer one, so failing it fails both
+	err = DB.RunInTransaction(func(Transaction interfaces.DBInterface) error {
+		if _, err := Transaction.NewTag("outer", "", 0); err != nil {
+			return err
+		}
+		return Transaction.RunInTransaction(func(Inner interfaces.DBInterface) error {
+			if _, err := Inner.NewTag("inner", "", 0); err != nil {
+				return err
+			}
+			return errRollback
+		})
+	})
+	if err != errRollback {
+		t.Fatalf("nested RunInTransaction should return Work's error, got %v", err)
+	}
+	if _, err := DB.GetTagByName("outer"); err == nil {
+		t.Errorf("tag added before a failed nested transaction exists")
+	}
+
+	//A transaction that succeeds is kept
+	var committedImage uint64
+	err = DB.RunInTransaction(func(Transaction interfaces.DBInterface) error {
+		var err error
+		if committedImage, err = Transaction.NewImage("committed", "committed.png", 0, ""); err != nil {
+			return err
+		}
+		if err := Transaction.AddTag([]uint64{keptTag}, committedImage, 0); err != nil {
+			return err
+		}
+		return Transaction.AddCollectionMember(keptCollection, []uint64{committedImage}, 0)
+	})
+	if err != nil {
+		t.Fatalf("RunInTransaction: %v", err)
+	}
+	if tags, err := DB.GetImageTags(committedImage); err != nil || len(tags) != 1 || tags[0].ID != keptTag {
+		t.Errorf("tags of an image added in a committed transaction: %+v, %v", tags, err)
+	}
+	if members, _, err := DB.GetCollectionMembers(keptCollection, 0, 0); err != nil || len(members) != 2 {
+		t.Errorf("members after a committed transaction: %+v, %v", members, err)
+	}
+}
@@ -0,0 +1,253 @@
+package tagquery
+
+import (
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/media"
+	"math"
+	"strconv"
+	"strings"
+)
+
+//RatioTolerance is how far apart two aspect ratios can be and still be equal, so 16:9 matches 1920x1080 and 1366x768
+const RatioTolerance = 0.01
+
+//colorTolerance is how far a dominant colour can be from a color tag's colour and still count, colorMinimumShare is how much of the image those colours must cover
+const (
+	colorTolerance    = 80
+	colorMinimumShare = 0.25
+	colorMaxTolerance = 442 //Black to white, larger tolerances are capped to this
+)
+
+//metaTagParser fills in the column name, description and value of a metatag, the tag is returned renamed even when its value could not be parsed
+type metaTagParser func(Tag interfaces.TagInformation) (interfaces.TagInformation, error)
+
+//imageMetaTags are the metatags on what is recorded about an image's file, which every database parses the same way
+var imageMetaTags = map[string]metaTagParser{
+	"width":    parseWidth,
+	"height":   parseHeight,
+	"filesize": parseFileSizeTag,
+	"duration": parseDuration,
+	"ratio":    parseRatioTag,
+	"type":     parseType,
+	"color":    parseColor,
+}
+
+//IsImageMetaTag returns whether Name is a metatag parsed by ParseImageMetaTag
+func IsImageMetaTag(Name string) bool {
+	_, exists := imageMetaTags[Name]
+	return exists
+}
+
+//ParseImageMetaTag parses a metatag on what is recorded about an image's file, leaving databases only to build their query from the result
+//On success the tag Exists, on failure it is still renamed to its column so it is reported the same way
+func ParseImageMetaTag(Tag interfaces.TagInformation) (interfaces.TagInformation, error) {
+	parser, exists := imageMetaTags[Tag.Name]
+	if exists == false {
+		return Tag, errors.New("MetaTag does not exist")
+	}
+	return parser(Tag)
+}
+
+//parseWholeNumber sets the tag's value to the whole number it was searched with. All comparators are valid
+func parseWholeNumber(Tag interfaces.TagInformation, Failure string) (interfaces.TagInformation, error) {
+	svalue, isString := Tag.MetaValue.(string)
+	if isString {
+		value, err := strconv.ParseInt(svalue, 10, 64)
+		if err == nil {
+			Tag.MetaValue = value
+		}
+	}
+	//Must be an int64
+	if _, isInt := Tag.MetaValue.(int64); isInt == false {
+		return Tag, errors.New(Failure)
+	}
+	Tag.Exists = true
+	return Tag, nil
+}
+
+func parseWidth(Tag interfaces.TagInformation) (interfaces.TagInformation, error) {
+	Tag.Name = "Width"
+	Tag.Description = "The width of the image in pixels"
+	return parseWholeNumber(Tag, "could not parse requested width, ensure it is a number")
+}
+
+func parseHeight(Tag interfaces.TagInformation) (interfaces.TagInformation, error) {
+	Tag.Name = "Height"
+	Tag.Description = "The height of the image in pixels"
+	return parseWholeNumber(Tag, "could not parse requested height, ensure it is a number")
+}
+
+func parseFileSizeTag(Tag interfaces.TagInformation) (interfaces.TagInformation, error) {
+	Tag.Name = "FileSize"
+	Tag.Description = "The size of the image's file"
+	ssize, isString := Tag.MetaValue.(string)
+	if isString {
+		size, err := ParseFileSize(ssize)
+		if err == nil {
+			Tag.MetaValue = size
+		}
+	}
+	//Must be an int64, all comparators valid
+	if _, isInt := Tag.MetaValue.(int64); isInt == false {
+		return Tag, errors.New("could not parse requested file size, ensure it is a number optionally followed by b, kb, mb, or gb")
+	}
+	Tag.Exists = true
+	return Tag, nil
+}
+
+func parseDuration(Tag interfaces.TagInformation) (interfaces.TagInformation, error) {
+	Tag.Name = "Duration"
+	Tag.Description = "The length of the video or audio in seconds"
+	sduration, isString := Tag.MetaValue.(string)
+	if isString {
+		duration, err := strconv.ParseFloat(sduration, 64)
+		if err == nil && duration >= 0 && math.IsInf(duration, 0) == false {
+			Tag.MetaValue = duration
+		}
+	}
+	//Must be a float64, all comparators valid
+	if _, isFloat := Tag.MetaValue.(float64); isFloat == false {
+		return Tag, errors.New("could not parse requested duration, ensure it is a number of seconds")
+	}
+	Tag.Exists = true
+	return Tag, nil
+}
+
+func parseRatioTag(Tag interfaces.TagInformation) (interfaces.TagInformation, error) {
+	Tag.Name = "Ratio"
+	Tag.Description = "The width of the image divided by its height"
+	Tag.IsComplexMeta = true
+	sratio, isString := Tag.MetaValue.(string)
+	if isString {
+		ratio, err := ParseRatio(sratio)
+		if err == nil {
+			Tag.MetaValue = ratio
+		}
+	}
+	//Must be a float64, all comparators valid, = matches ratios within RatioTolerance
+	if _, isFloat := Tag.MetaValue.(float64); isFloat == false {
+		return Tag, errors.New("could not parse requested ratio, use a form like 16:9 or 1.78")
+	}
+	Tag.Exists = true
+	return Tag, nil
+}
+
+//parseType matches a kind of file with LIKE on the start of its MIME type, or a file extension or MIME type with =
+func parseType(Tag interfaces.TagInformation) (interfaces.TagInformation, error) {
+	Tag.Name = "MIMEType"
+	Tag.Description = "The kind or format of the file"
+	stype, isString := Tag.MetaValue.(string)
+	if isString == false {
+		return Tag, errors.New("could not parse type tag")
+	}
+	stype = strings.ToLower(stype)
+	if stype == media.KindImage || stype == media.KindVideo || stype == media.KindAudio {
+		//MIME types start with the kind
+		Tag.MetaValue = stype + "/%"
+		Tag.Comparator = "LIKE"
+	} else if mediaType, found := media.ByName("." + stype); found {
+		//A file extension such as png
+		Tag.MetaValue = mediaType.MIME
+		Tag.Comparator = "="
+	} else if strings.Contains(stype, "/") {
+		//A MIME type such as image/png
+		Tag.MetaValue = stype
+		Tag.Comparator = "="
+	} else {
+		return Tag, errors.New("could not parse type tag, use image, video, audio, a file extension, or a MIME type")
+	}
+	Tag.Exists = true
+	return Tag, nil
+}
+
+//parseColor matches images mostly of a colour, searched as a name or hex value optionally after a tolerance, such as blue or 40-#3366ff
+func parseColor(Tag interfaces.TagInformation) (interfaces.TagInformation, error) {
+	Tag.Name = "Color"
+	Tag.Description = "Show images that are mostly the colour specified"
+	Tag.IsComplexMeta = true
+	Tag.Comparator = "=" //Only equal or not equal make sense for colours
+	stringValue, isString := Tag.MetaValue.(string)
+	if isString == false {
+		return Tag, errors.New("could not parse color tag")
+	}
+	//First handle tolerance if needed
+	Tolerance := uint64(colorTolerance)
+	stringComponents := strings.Split(stringValue, "-")
+	if len(stringComponents) == 2 {
+		newTolerance, err := strconv.ParseUint(stringComponents[0], 10, 64)
+		if err != nil {
+			return Tag, errors.New("error parsing tolerance for color tag")
+		}
+		stringValue = stringComponents[1]
+		Tolerance = newTolerance
+		if Tolerance > colorMaxTolerance {
+			Tolerance = colorMaxTolerance
+		}
+	} else if len(stringComponents) != 1 {
+		return Tag, errors.New("could not parse color tag")
+	}
+	//Then the colour itself
+	color, parsed := media.ParseColor(stringValue)
+	if parsed == false {
+		return Tag, errors.New("could not parse color tag, use a colour name such as blue or a hex value such as #3366ff")
+	}
+	Tag.Exists = true
+	Tag.MetaValue = interfaces.ImageColorSearch{Red: color.Red, Green: color.Green, Blue: color.Blue, Tolerance: Tolerance, MinimumShare: colorMinimumShare}
+	return Tag, nil
+}
+
+//ParseFileSize returns the bytes in a size such as 500, 200kb, or 1.5mb. Units are multiples of 1024
+func ParseFileSize(Size string) (int64, error) {
+	multiplier := float64(1)
+	switch {
+	case strings.HasSuffix(Size, "kb"):
+		multiplier = 1 << 10
+		Size = strings.TrimSuffix(Size, "kb")
+	case strings.HasSuffix(Size, "mb"):
+		multiplier = 1 << 20
+		Size = strings.TrimSuffix(Size, "mb")
+	case strings.HasSuffix(Size, "gb"):
+		multiplier = 1 << 30
+		Size = strings.TrimSuffix(Size, "gb")
+	case strings.HasSuffix(Size, "b"):
+		Size = strings.TrimSuffix(Size, "b")
+	}
+	value, err := strconv.ParseFloat(Size, 64)
+	if err != nil {
+		return 0, err
+	}
+	if value < 0 || value*multiplier >= math.MaxInt64 {
+		return 0, errors.New("file size out of range")
+	}
+	return int64(value * multiplier), nil
+}
+
+//ParseRatio returns the width divided by the height of a ratio such as 16:9, 16/9, or 1.78
+func ParseRatio(Ratio string) (float64, error) {
+	ratio := float64(0)
+	if separator := strings.IndexAny(Ratio, ":/"); separator != -1 {
+		width, err := strconv.ParseFloat(Ratio[:separator], 64)
+		if err != nil {
+			return 0, err
+		}
+		height, err := strconv.ParseFloat(Ratio[separator+1:], 64)
+		if err != nil {
+			return 0, err
+		}
+		if height == 0 {
+			return 0, errors.New("ratio height cannot be zero")
+		}
+		ratio = width / height
+	} else {
+		value, err := strconv.ParseFloat(Ratio, 64)
+		if err != nil {
+			return 0, err
+		}
+		ratio = value
+	}
+	if ratio <= 0 || math.IsInf(ratio, 0) || math.IsNaN(ratio) {
+		return 0, errors.New("ratio out of range")
+	}
+	return ratio, nil
+}
@@ -0,0 +1,57 @@
+package tagquery
+
+import (
+	"go-image-board/interfaces"
+	"testing"
+)
+
+func TestParseImageMetaTag(t *testing.T) {
+	tests := []struct {
+		Name       string
+		Value      string
+		Column     string
+		Expected   interface{}
+		Comparator string
+		Fails      bool
+	}{
+		{Name: "width", Value: "1920", Column: "Width", Expected: int64(1920), Comparator: ">="},
+		{Name: "height", Value: "tall", Column: "Height", Fails: true},
+		{Name: "filesize", Value: "1.5mb", Column: "FileSize", Expected: int64(3 << 19), Comparator: ">="},
+		{Name: "filesize", Value: "200kb", Column: "FileSize", Expected: int64(200 << 10), Comparator: ">="},
+		{Name: "filesize", Value: "-1", Column: "FileSize", Fails: true},
+		{Name: "duration", Value: "90.5", Column: "Duration", Expected: 90.5, Comparator: ">="},
+		{Name: "duration", Value: "inf", Column: "Duration", Fails: true},
+		{Name: "ratio", Value: "16:10", Column: "Ratio", Expected: 1.6, Comparator: ">="},
+		{Name: "ratio", Value: "4/0", Column: "Ratio", Fails: true},
+		{Name: "ratio", Value: "0", Column: "Ratio", Fails: true},
+		{Name: "type", Value: "Video", Column: "MIMEType", Expected: "video/%", Comparator: "LIKE"},
+		{Name: "type", Value: "png", Column: "MIMEType", Expected: "image/png", Comparator: "="},
+		{Name: "type", Value: "application/pdf", Column: "MIMEType", Expected: "application/pdf", Comparator: "="},
+		{Name: "type", Value: "document", Column: "MIMEType", Fails: true},
+		{Name: "color", Value: "#3366ff", Column: "Color", Expected: interfaces.ImageColorSearch{Red: 0x33, Green: 0x66, Blue: 0xff, Tolerance: colorTolerance, MinimumShare: colorMinimumShare}, Comparator: "="},
+		{Name: "color", Value: "1000-#000000", Column: "Color", Expected: interfaces.ImageColorSearch{Tolerance: colorMaxTolerance, MinimumShare: colorMinimumShare}, Comparator: "="},
+		{Name: "color", Value: "lots-#000000", Column: "Color", Fails: true},
+		{Name: "color", Value: "notacolour", Column: "Color", Fails: true},
+	}
+	for _, test := range tests {
+		tag, err := ParseImageMetaTag(interfaces.TagInformation{Name: test.Name, MetaValue: test.Value, Comparator: ">=", IsMeta: true})
+		if tag.Name != test.Column {
+			t.Errorf("%s:%s was named %q, expected %q", test.Name, test.Value, tag.Name, test.Column)
+		}
+		if test.Fails {
+			if err == nil || tag.Exists {
+				t.Errorf("%s:%s should not parse, got %+v", test.Name, test.Value, tag)
+			}
+			continue
+		}
+		if err != nil || tag.Exists == false || tag.MetaValue != test.Expected || tag.Comparator != test.Comparator {
+			t.Errorf("%s:%s parsed to %v %v (exists %v), %v, expected %v %v", test.Name, test.Value, tag.Comparator, tag.MetaValue, tag.Exists, err, test.Comparator, test.Expected)
+		}
+	}
+	if IsImageMetaTag("rating") || IsImageMetaTag("Width") {
+		t.Errorf("only the lower case names of image metatags should be handled")
+	}
+	if _, err := ParseImageMetaTag(interfaces.TagInformation{Name: "rating", MetaValue: "safe"}); err == nil {
+		t.Errorf("ParseImageMetaTag of a tag it does not handle did not fail")
+	}
+}
@@ -2,22 +2,29 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"flag"
 	"go-image-board/config"
 	"go-image-board/database"
 	"go-image-board/interfaces"
+	"go-image-board/jobs"
 	"go-image-board/logging"
+	"go-image-board/media"
 	"go-image-board/plugins"
+	"go-image-board/plugins/localstorageplugin"
 	"go-image-board/plugins/mariadbplugin"
+	"go-image-board/plugins/memoryplugin"
+	"go-image-board/plugins/postgresplugin"
+	"go-image-board/plugins/s3storageplugin"
+	"go-image-board/plugins/sqliteplugin"
 	"go-image-board/routers"
 	"go-image-board/routers/api"
 	"go-image-board/routers/templatecache"
-	"io/ioutil"
+	"go-image-board/storage"
 	"net/http"
 	"os"
-	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -29,16 +36,38 @@ import (
 
 func main() {
 	//Commands
-	generateThumbsOnly := flag.Bool("thumbsonly", false, "Regenerates all thumbnails. You should run this if you change your thumbnail size or enable ffmpeg.")
-	generatedHashesOnly := flag.Bool("dhashonly", false, "Regenerates all dhashes. You should run this if you change hash method, or after updating past 1.0.3.8")
-	missingOnly := flag.Bool("missingonly", false, "When used with dhashonly or thumbsonly, prevents deleting pre-existing entries.")
-	renameFilesOnly := flag.Bool("renameonly", false, "Renames all posts and corrects the names in the database. Use if changing naming convention of files.")
+	generateThumbsOnly := flag.Bool("thumbsonly", false, "Regenerates all thumbnails, sized ones and previews are removed and made again when next requested. You should run this if you change MaxThumbnailWidth or MaxThumbnailHeight or enable ffmpeg.")
+	generatedHashesOnly := flag.Bool("dhashonly", false, "Regenerates all dhashes, blurhashes, and colours. You should run this if you change hash method, or after updating past 1.0.3.8")
+	mediaInfoOnly := flag.Bool("mediainfoonly", false, "Records the dimensions, file size, MIME type, duration and bitrate of all images. You should run this after updating to a version that records them, or after enabling ffmpeg.")
+	missingOnly := flag.Bool("missingonly", false, "When used with dhashonly, thumbsonly or mediainfoonly, prevents deleting pre-existing entries.")
+	renameFilesOnly := flag.Bool("renameonly", false, "Renames all posts and corrects the names in the database. Use if changing naming convention of files. Extensions are corrected to match file content.")
 	removeOrphanFiles := flag.Bool("removeorphanfiles", false, "Removes images and thumbnails that do not have an associated database entry.")
+	migrateLayoutOnly := flag.Bool("migratelayout", false, "Moves all images and thumbnails to match StorageLayout and corrects their locations in the database. Use after changing StorageLayout.")
 	fixCollectionTags := flag.Bool("fixcollectiontags", false, "Validates and fixes tags applied to all collections")
+	verifyOnly := flag.Bool("verify", false, "Re-hashes every image, and reports images whose content does not match their name, images without files, files and thumbnails without images, missing or stale thumbnails, and missing dhashes.")
+	repairFiles := flag.Bool("repair", false, "When used with verify, regenerates thumbnails and dhashes, and moves corrupt and orphaned files to the quarantine directory of storage.")
+
+	//For bulk import
+	importDirectoryPath := flag.String("import", "", "Uploads every file under this directory as the user given by -username. Tags, source, rating, and collection are read from file.ext.txt or file.ext.json sidecars.")
+	importProgressPath := flag.String("importprogress", "."+string(filepath.Separator)+"configuration"+string(filepath.Separator)+"import-progress.tsv", "When used with import, the file progress is recorded in. Files recorded as imported or duplicate are skipped, so an interrupted import can be resumed.")
+	dryRun := flag.Bool("dryrun", false, "When used with import, reports what would be imported without changing anything.")
+	importFormat := flag.String("importformat", "", "When used with import, reads tags, ratings, sources, scores and pools from another board's export instead of sidecars. One of danbooru, gelbooru, or hydrus.")
+	importMetadataPath := flag.String("importmetadata", "", "When used with importformat danbooru or gelbooru, the file of exported posts.")
+	importPoolsPath := flag.String("importpools", "", "When used with importformat danbooru, an optional file of exported pools, which are imported as collections.")
+	importTagsPath := flag.String("importtags", "", "When used with importformat gelbooru, an optional file of exported tags, used to find each tag's category.")
+
+	//For export and restore
+	exportArchivePath := flag.String("export", "", "Writes every user, tag, image, collection, vote and audit log, with the image files and thumbnails, to one archive at this path.")
+	exportSecrets := flag.Bool("exportsecrets", false, "When used with export, includes password hashes and security questions. Keep such archives private.")
+	importArchivePath := flag.String("import-archive", "", "Restores an archive written by export into an empty board. If the archive has no passwords, users are given the one from -password.")
+
+	//For moving to another database
+	migrateTargetConfigPath := flag.String("migrate-to", "", "Copies every record from the configured database to the empty database described by this configuration file, keeping IDs, times, and links.")
 
 	//For account creation
+	allowMemoryDB := flag.Bool("allowmemorydb", false, "Allows DBPlugin memory, which loses everything when the board stops. Only for trying the board out or testing.")
 	newUserOnly := flag.Bool("createuser", false, "Creates a new user")
-	newUserName := flag.String("username", "", "Name of your new user")
+	newUserName := flag.String("username", "", "Name of your new user, or of the user to import as")
 	newUserPassword := flag.String("password", "", "Password for your new user")
 	newUserEmail := flag.String("email", "", "Email for your new user")
 	newUserPermissions := flag.Uint64("permissions", 4294967295, "Permissions to grant new user (Defaults to admin!)")
@@ -60,41 +89,25 @@ func main() {
 
 	//Init logging
 	logging.LogInterface.Init(config.Configuration.TargetLogLevel, config.Configuration.LoggingWhiteList, config.Configuration.LoggingBlackList)
+	registerJobHandlers()
+
+	//Init storage
+	switch config.Configuration.StoragePlugin {
+	case "s3":
+		storage.StorageInterface = &s3storageplugin.S3StoragePlugin{}
+	default:
+		storage.StorageInterface = &localstorageplugin.LocalStoragePlugin{}
+	}
+	if err := storage.StorageInterface.Init(); err != nil {
+		logging.WriteLog(logging.LogLevelCritical, "main/main", "0", logging.ResultFailure, []string{"Failed to initialize image storage", err.Error()})
+		return
+	}
 
 	if *generateThumbsOnly {
 		logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultInfo, []string{"Generate thumbnails flag detected. Server will not start and instead just generate thumbnails. This may take some time."})
-		//We need wait group so that we don't end the application before goroutines
-		var wg sync.WaitGroup
-		//list files
-		files, err := ioutil.ReadDir(config.Configuration.ImageDirectory)
-		if err != nil {
-			logging.WriteLog(logging.LogLevelError, "main/main", "0", logging.ResultFailure, []string{"failed to get files to generate new thumbnails", err.Error()})
-			return
-		}
-		//for each image
-		generatedThumbnails := uint64(0)
-		for _, file := range files {
-			if file.IsDir() {
-				continue
-			}
-			//Delete thumbnail
-			thumbNailPath := config.Configuration.ImageDirectory + string(filepath.Separator) + "thumbs" + string(filepath.Separator) + file.Name() + ".png"
-			if _, err := os.Stat(thumbNailPath); *missingOnly == false || (err != nil && os.IsNotExist(err)) {
-				os.Remove(thumbNailPath)
-				//Goroutine generate a new one
-				generatedThumbnails++
-				wg.Add(1) //This magic thing will prevent program from closing before goroutines finish
-				go func(fileName string) {
-					defer wg.Done()
-					routers.GenerateThumbnail(fileName)
-				}(file.Name())
-			}
-			if generatedThumbnails%config.Configuration.PageStride == 0 {
-				wg.Wait() //Throttle how fast we generate thumbnails
-			}
+		if err := jobs.Run(jobs.GenerateThumbnails, missingOnlyPayload(*missingOnly)); err != nil {
+			logging.WriteLog(logging.LogLevelError, "main/main", "0", logging.ResultFailure, []string{"Failed to generate thumbnails", err.Error()})
 		}
-		wg.Wait() //This will wait for all goroutines to finish
-		logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultSuccess, []string{"Finished generating " + strconv.FormatUint(generatedThumbnails, 10) + " new thumbnails."})
 		return //We do not want to start server if used in cli
 	}
 
@@ -107,12 +120,21 @@ func main() {
 	api.Throttle = api.ThrottleMap{}
 	api.Throttle.Init()
 
+	//The memory database loses every upload on restart, so it is never used by accident
+	if config.Configuration.DBPlugin == "memory" {
+		if *allowMemoryDB == false {
+			logging.WriteLog(logging.LogLevelCritical, "main/main", "0", logging.ResultFailure, []string{"DBPlugin memory loses every user, tag and image when the board stops. Choose sqlite, mariadb or postgres, or start with -allowmemorydb if this is only a test."})
+			return
+		}
+		logging.WriteLog(logging.LogLevelWarning, "main/main", "0", logging.ResultInfo, []string{"Using the memory database. NOTHING WILL BE SAVED, and every upload slows as the board grows. Do not use this for a real board."})
+	}
+
 	//If we can, start the database
-	if config.Configuration.DBName == "" || config.Configuration.DBPassword == "" || config.Configuration.DBUser == "" || config.Configuration.DBHost == "" {
-		logging.WriteLog(logging.LogLevelCritical, "main/main", "0", logging.ResultFailure, []string{"Missing database information. (Instance, User, Password?)"})
+	if missingDatabaseConfig() {
+		logging.WriteLog(logging.LogLevelCritical, "main/main", "0", logging.ResultFailure, []string{"Missing database information. (Plugin, Instance, User, Password, Path?)"})
 	} else {
 		//Initialize DB Connection
-		database.DBInterface = &mariadbplugin.MariaDBPlugin{}
+		database.DBInterface = newDatabasePlugin(config.Configuration.DBPlugin)
 		err = database.DBInterface.InitDatabase()
 		if err != nil {
 			logging.WriteLog(logging.LogLevelError, "main/main", "0", logging.ResultFailure, []string{"Failed to connect to database. Will keep trying. ", err.Error()})
@@ -150,128 +172,28 @@ func main() {
 	}
 	if *generatedHashesOnly {
 		logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultInfo, []string{"Generate dHashes flag detected. Server will not start and instead just generate dHashes. This will take some time."})
-		//We need wait group so that we don't end the application before goroutines
-		var wg sync.WaitGroup
-		//for each image in the database
-		page := uint64(0)
-		processedImages := uint64(0)
-		for true {
-			images, maxCount, err := database.DBInterface.SearchImages([]interfaces.TagInformation{}, page, config.Configuration.PageStride)
-			page += config.Configuration.PageStride
-			if err != nil {
-				logging.WriteLog(logging.LogLevelError, "main/main", "0", logging.ResultFailure, []string{"Error processing hashes.", err.Error()})
-				break
-			}
-			if len(images) <= 0 {
-				logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultInfo, []string{"Finished queing images"})
-				break
-			}
-			logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultInfo, []string{"Queing", strconv.FormatUint(page, 10), "of", strconv.FormatUint(maxCount, 10)})
-			for _, nextImage := range images {
-				var dhashExists error
-				if *missingOnly {
-					_, _, dhashExists = database.DBInterface.GetImagedHash(nextImage.ID)
-				}
-				if *missingOnly == false || dhashExists != nil {
-					processedImages++
-					wg.Add(1) //This magic thing will prevent program from closing before goroutines finish
-					go func(fileName string, imageID uint64) {
-						defer wg.Done()
-						routers.GeneratedHash(fileName, imageID)
-					}(nextImage.Location, nextImage.ID)
-				}
-			}
-			wg.Wait() //This will wait for all goroutines to finish
+		if err := jobs.Run(jobs.GeneratedHashes, missingOnlyPayload(*missingOnly)); err != nil {
+			logging.WriteLog(logging.LogLevelError, "main/main", "0", logging.ResultFailure, []string{"Failed to generate dHashes", err.Error()})
 		}
-		logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultInfo, []string{"Waiting for images to finish processing"})
-		wg.Wait() //This will wait for all goroutines to finish
-		logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultSuccess, []string{"Finished generating " + strconv.FormatUint(processedImages, 10) + " new dHashes."})
-
 		return //We do not want to start server if used in cli
 	}
-	if *removeOrphanFiles {
-		//Scan image directory
-		files, err := ioutil.ReadDir(config.Configuration.ImageDirectory)
-		if err != nil {
-			logging.WriteLog(logging.LogLevelCritical, "main/main", "0", logging.ResultFailure, []string{"Failed to get images from directory", err.Error()})
-			return
+	if *mediaInfoOnly {
+		logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultInfo, []string{"Media info flag detected. Server will not start and instead just record media info. This will take some time."})
+		if err := jobs.Run(jobs.MediaInfo, missingOnlyPayload(*missingOnly)); err != nil {
+			logging.WriteLog(logging.LogLevelError, "main/main", "0", logging.ResultFailure, []string{"Failed to record media info", err.Error()})
 		}
-		for _, file := range files {
-			if file.IsDir() {
-				continue
-			}
-			//Search database for matching image entry
-			_, err := database.DBInterface.GetImageByFileName(file.Name())
-			if err != nil && err == sql.ErrNoRows {
-				logging.WriteLog(logging.LogLevelWarning, "main/main", "0", logging.ResultInfo, []string{"Failed to get image from database, it will be deleted", file.Name()})
-				//If database entry does not exist, delete the image
-				err = os.Remove(path.Join(config.Configuration.ImageDirectory, file.Name()))
-				if err != nil {
-					logging.WriteLog(logging.LogLevelError, "main/main", "0", logging.ResultFailure, []string{"Failed to delete image", file.Name(), err.Error()})
-				}
-			} else if err != nil {
-				logging.WriteLog(logging.LogLevelError, "main/main", "0", logging.ResultFailure, []string{"Failed to get image from database due to an unexpected db error, it will be skipped", file.Name(), err.Error()})
-			}
-		}
-		//Rinse&repeat with the thumbnails
-		files, err = ioutil.ReadDir(path.Join(config.Configuration.ImageDirectory, "thumbs"))
-		if err != nil {
-			logging.WriteLog(logging.LogLevelCritical, "main/main", "0", logging.ResultFailure, []string{"Failed to get images from directory", err.Error()})
-			return
-		}
-		for _, file := range files {
-			if file.IsDir() {
-				continue
-			}
-			//Search database for matching image entry
-			imageName := file.Name()
-			if len(imageName) > 4 { //Strip .png to get original name
-				imageName = imageName[:len(imageName)-4]
-			}
-			_, err := database.DBInterface.GetImageByFileName(imageName)
-			if err != nil && err == sql.ErrNoRows {
-				logging.WriteLog(logging.LogLevelWarning, "main/main", "0", logging.ResultInfo, []string{"Failed to get image from database, it will be deleted", file.Name()})
-				//If database entry does not exist, delete the image
-				err = os.Remove(path.Join(path.Join(config.Configuration.ImageDirectory, "thumbs"), file.Name()))
-				if err != nil {
-					logging.WriteLog(logging.LogLevelError, "main/main", "0", logging.ResultFailure, []string{"Failed to delete thumbnail", file.Name(), err.Error()})
-				}
-			} else if err != nil {
-				logging.WriteLog(logging.LogLevelError, "main/main", "0", logging.ResultFailure, []string{"Failed to get image from database due to an unexpected db error, it will be skipped", file.Name(), err.Error()})
-			}
+		return //We do not want to start server if used in cli
+	}
+	if *removeOrphanFiles {
+		if err := jobs.Run(jobs.RemoveOrphanFiles, jobs.DeleteOrphans); err != nil {
+			logging.WriteLog(logging.LogLevelError, "main/main", "0", logging.ResultFailure, []string{"Failed to remove orphan files", err.Error()})
 		}
-
 		return //We do not want to start server if used in cli
 	}
 	if *fixCollectionTags {
-		//Loop through all collections
-		page := uint64(0)
-		processedCollections := uint64(0)
-		for true {
-			collections, maxCount, err := database.DBInterface.SearchCollections([]interfaces.TagInformation{}, page, config.Configuration.PageStride)
-			page += config.Configuration.PageStride
-			if err != nil {
-				logging.WriteLog(logging.LogLevelError, "main/main", "0", logging.ResultFailure, []string{"Error processing hashes.", err.Error()})
-				break
-			}
-			if len(collections) <= 0 {
-				logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultInfo, []string{"Finished queing collections"})
-				break
-			}
-			logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultInfo, []string{"Queing", strconv.FormatUint(page, 10), "of", strconv.FormatUint(maxCount, 10)})
-			for _, nextCollection := range collections {
-				//Fix missing tags
-				count, err := database.DBInterface.FixCollectionTags(nextCollection.ID)
-				if err != nil {
-					logging.WriteLog(logging.LogLevelError, "main/main", "0", logging.ResultInfo, []string{"Failed to fix collection images", err.Error()})
-				} else if err == nil && count > 0 {
-					logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultInfo, []string{"Fixed colllection tags", nextCollection.Name, "rows", strconv.FormatInt(count, 10)})
-				}
-				processedCollections++
-			}
+		if err := jobs.Run(jobs.FixCollectionTags, ""); err != nil {
+			logging.WriteLog(logging.LogLevelError, "main/main", "0", logging.ResultFailure, []string{"Failed to fix collection tags", err.Error()})
 		}
-		logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultInfo, []string{"Completed collection tag correction"})
-
 		return //We do not want to start server if used in cli
 	}
 	if *newUserOnly {
@@ -310,9 +232,43 @@ func main() {
 	if configConfirmed == true {
 		//Placing the rename function here, we need a validated connection to database for this to work
 		if *renameFilesOnly {
-			renameAllImages()
+			if err := jobs.Run(jobs.RenameImages, ""); err != nil {
+				logging.WriteLog(logging.LogLevelError, "main/main", "0", logging.ResultFailure, []string{"Failed to rename images", err.Error()})
+			}
 			return //We only wanted to rename
 		}
+		if *migrateLayoutOnly {
+			migrateImageLayout()
+			return //We only wanted to move files
+		}
+		if *verifyOnly {
+			verifyStorage(*repairFiles)
+			return //We only wanted to verify
+		}
+		if *migrateTargetConfigPath != "" {
+			migrateDatabase(*migrateTargetConfigPath)
+			return //We only wanted to migrate
+		}
+		if *exportArchivePath != "" {
+			exportArchive(*exportArchivePath, *exportSecrets)
+			return //We only wanted to export
+		}
+		if *importArchivePath != "" {
+			importArchive(*importArchivePath, *newUserPassword)
+			return //We only wanted to restore
+		}
+		//Imports queue thumbnails as jobs, and the server runs them
+		jobs.Start(config.Configuration.JobWorkers)
+		if *importDirectoryPath != "" && *importFormat != "" {
+			importBooruExport(*importFormat, *importDirectoryPath, *importMetadataPath, *importPoolsPath, *importTagsPath, *newUserName, *dryRun)
+			return //We only wanted to import
+		}
+		if *importDirectoryPath != "" {
+			importDirectory(*importDirectoryPath, *newUserName, *importProgressPath, *dryRun)
+			return //We only wanted to import
+		}
+		//Periodic maintenance is left to the server
+		scheduleMaintenance()
 		//Web routers
 		requestRouter.HandleFunc("/resources/{file}", routers.ResourceRouter).Methods("GET")
 		requestRouter.HandleFunc("/", routers.AccountRequiredMiddleWare(routers.RootRouter)).Methods("GET")
@@ -321,9 +277,16 @@ func main() {
 		requestRouter.HandleFunc("/collectionorder", routers.AccountRequiredMiddleWare(routers.CollectionImageOrderPostRouter)).Methods("POST")
 		requestRouter.HandleFunc("/collection", routers.AccountRequiredMiddleWare(routers.CollectionGetRouter)).Methods("GET")
 		requestRouter.HandleFunc("/collection", routers.AccountRequiredMiddleWare(routers.CollectionPostRouter)).Methods("POST")
+		requestRouter.HandleFunc("/collection/download", routers.AccountRequiredMiddleWare(routers.CollectionDownloadRouter)).Methods("GET")
 		requestRouter.HandleFunc("/collections", routers.AccountRequiredMiddleWare(routers.CollectionsRouter)).Methods("GET")
-		requestRouter.HandleFunc("/images/{file}", routers.AccountRequiredMiddleWare(routers.ResourceImageRouter)).Methods("GET")
-		requestRouter.HandleFunc("/thumbs/{file}", routers.AccountRequiredMiddleWare(routers.ThumbnailRouter)).Methods("GET")
+		requestRouter.HandleFunc("/images/{file:.+}", routers.AccountRequiredMiddleWare(routers.ResourceImageRouter)).Methods("GET")
+		//Sized thumbnails are matched first, so names of sharded images are not taken for sizes
+		if len(config.Configuration.ThumbnailSizes) > 0 {
+			requestRouter.HandleFunc("/thumbs/{size:"+thumbnailSizeRoutePattern()+"}/{file:.+}", routers.AccountRequiredMiddleWare(routers.SizedThumbnailRouter)).Methods("GET")
+		}
+		requestRouter.HandleFunc("/thumbs/{file:.+}", routers.AccountRequiredMiddleWare(routers.ThumbnailRouter)).Methods("GET")
+		requestRouter.HandleFunc("/previews/{file:.+}", routers.AccountRequiredMiddleWare(routers.PreviewRouter)).Methods("GET")
+		requestRouter.HandleFunc("/transcodes/{file:.+}", routers.AccountRequiredMiddleWare(routers.TranscodeRouter)).Methods("GET")
 		requestRouter.HandleFunc("/image", routers.AccountRequiredMiddleWare(routers.ImageGetRouter)).Methods("GET")
 		requestRouter.HandleFunc("/image", routers.AccountRequiredMiddleWare(routers.ImagePostRouter)).Methods("POST")
 		requestRouter.HandleFunc("/uploadImage", routers.AccountRequiredMiddleWare(routers.UploadFormRouter)).Methods("GET")
@@ -337,10 +300,13 @@ func main() {
 		requestRouter.HandleFunc("/mod", routers.AccountRequiredMiddleWare(routers.ModRouter)).Methods("GET")
 		requestRouter.HandleFunc("/mod/user", routers.AccountRequiredMiddleWare(routers.ModUserGetRouter)).Methods("GET")
 		requestRouter.HandleFunc("/mod/user", routers.AccountRequiredMiddleWare(routers.ModUserPostRouter)).Methods("POST")
+		requestRouter.HandleFunc("/mod/jobs", routers.AccountRequiredMiddleWare(routers.ModJobsGetRouter)).Methods("GET")
+		requestRouter.HandleFunc("/mod/jobs", routers.AccountRequiredMiddleWare(routers.ModJobsPostRouter)).Methods("POST")
 
 		//API routers
 		requestRouter.HandleFunc("/api/Collection/{CollectionID}", api.CollectionGetAPIRouter).Methods("GET")
 		requestRouter.HandleFunc("/api/Collection/{CollectionID}", api.CollectionDeleteAPIRouter).Methods("DELETE")
+		requestRouter.HandleFunc("/api/Collection/{CollectionID}/Download", api.CollectionDownloadAPIRouter).Methods("GET")
 		requestRouter.HandleFunc("/api/Collections", api.CollectionsGetAPIRouter).Methods("GET")
 		//
 		requestRouter.HandleFunc("/api/Tag/{TagID}", api.TagGetAPIRouter).Methods("GET")
@@ -359,9 +325,14 @@ func main() {
 		requestRouter.HandleFunc("/api/Logon", api.LogonAPIRouter).Methods("POST")
 		requestRouter.HandleFunc("/api/Logout", api.LogoutAPIRouter).Methods("POST")
 		requestRouter.HandleFunc("/api/Users", api.UsersAPIRouter).Methods("GET")
+		//
+		requestRouter.HandleFunc("/api/Job/{JobID}", api.JobGetAPIRouter).Methods("GET")
+		requestRouter.HandleFunc("/api/Jobs", api.JobsGetAPIRouter).Methods("GET")
+		requestRouter.HandleFunc("/api/Jobs", api.JobsPostAPIRouter).Methods("POST")
 		//Autocomplete helpers
 		requestRouter.HandleFunc("/api/TagName", api.TagNameAPIRouter).Methods("GET")
 		requestRouter.HandleFunc("/api/CollectionName", api.CollectionNameAPIRouter).Methods("GET")
+		requestRouter.HandleFunc("/api/TagSuggestions", api.TagSuggestionsAPIRouter).Methods("POST")
 		requestRouter.HandleFunc("/api", api.CSRFAPIRouter).Methods("GET")
 
 	} else {
@@ -396,10 +367,54 @@ func main() {
 	}
 }
 
+//newDatabasePlugin returns an uninitialized instance of the named database plugin, defaulting to mariadb
+func newDatabasePlugin(Plugin string) interfaces.DBInterface {
+	switch Plugin {
+	case "sqlite":
+		return &sqliteplugin.SQLitePlugin{}
+	case "postgres":
+		return &postgresplugin.PostgresPlugin{}
+	case "memory":
+		return &memoryplugin.MemoryPlugin{}
+	}
+	return &mariadbplugin.MariaDBPlugin{}
+}
+
+//missingDatabaseConfig returns true if the selected database plugin lacks the settings it needs to connect
+func missingDatabaseConfig() bool {
+	switch config.Configuration.DBPlugin {
+	case "sqlite":
+		return config.Configuration.DBPath == ""
+	case "memory":
+		return false
+	case "mariadb", "postgres":
+		return config.Configuration.DBName == "" || config.Configuration.DBPassword == "" || config.Configuration.DBUser == "" || config.Configuration.DBHost == ""
+	}
+	return true
+}
+
 func fixMissingConfigs() {
+	if config.Configuration.DBPlugin == "" {
+		config.Configuration.DBPlugin = "mariadb"
+	}
+	if config.Configuration.DBPlugin == "sqlite" && config.Configuration.DBPath == "" {
+		config.Configuration.DBPath = "." + string(filepath.Separator) + "configuration" + string(filepath.Separator) + "gib.db"
+	}
+	if config.Configuration.DBPlugin == "postgres" && config.Configuration.DBSSLMode == "" {
+		config.Configuration.DBSSLMode = "disable"
+	}
 	if config.Configuration.Address == "" {
 		config.Configuration.Address = ":8080"
 	}
+	if config.Configuration.AllowedMediaTypes == nil {
+		config.Configuration.AllowedMediaTypes = media.Names()
+	}
+	if config.Configuration.StoragePlugin == "" {
+		config.Configuration.StoragePlugin = "local"
+	}
+	if config.Configuration.StorageLayout == "" {
+		config.Configuration.StorageLayout = storage.LayoutFlat
+	}
 	if config.Configuration.ImageDirectory == "" {
 		config.Configuration.ImageDirectory = "." + string(filepath.Separator) + "images"
 	}
@@ -409,6 +424,12 @@ func fixMissingConfigs() {
 	if config.Configuration.MaxUploadBytes <= 0 {
 		config.Configuration.MaxUploadBytes = 100 << 20
 	}
+	if config.Configuration.MaxArchiveEntries <= 0 {
+		config.Configuration.MaxArchiveEntries = 1000
+	}
+	if config.Configuration.MaxArchiveBytes <= 0 {
+		config.Configuration.MaxArchiveBytes = 4 * config.Configuration.MaxUploadBytes
+	}
 	if config.Configuration.MaxHeaderBytes <= 0 {
 		config.Configuration.MaxHeaderBytes = 1 << 20
 	}
@@ -424,12 +445,103 @@ func fixMissingConfigs() {
 	if config.Configuration.MaxThumbnailHeight <= 0 {
 		config.Configuration.MaxThumbnailHeight = 258
 	}
+	if config.Configuration.ThumbnailSizes == nil {
+		config.Configuration.ThumbnailSizes = map[string]config.ThumbnailSize{
+			"small":  {Width: config.Configuration.MaxThumbnailWidth / 2, Height: config.Configuration.MaxThumbnailHeight / 2},
+			"medium": {Width: config.Configuration.MaxThumbnailWidth, Height: config.Configuration.MaxThumbnailHeight},
+			"large":  {Width: config.Configuration.MaxThumbnailWidth * 2, Height: config.Configuration.MaxThumbnailHeight * 2},
+		}
+	}
+	for name, size := range config.Configuration.ThumbnailSizes {
+		if thumbnailSizeNamePattern.MatchString(name) == false || size.Width == 0 || size.Height == 0 {
+			logging.WriteLog(logging.LogLevelError, "main/fixMissingConfigs", "0", logging.ResultFailure, []string{"Thumbnail size will be ignored, names must be at least three letters, numbers, dashes or underscores, and the width and height more than zero", name})
+			delete(config.Configuration.ThumbnailSizes, name)
+		}
+	}
+	if validThumbnailFormat(config.Configuration.ThumbnailFormat) == false {
+		if config.Configuration.ThumbnailFormat != "" {
+			logging.WriteLog(logging.LogLevelError, "main/fixMissingConfigs", "0", logging.ResultFailure, []string{"Unknown ThumbnailFormat, jpeg will be used", config.Configuration.ThumbnailFormat})
+		}
+		config.Configuration.ThumbnailFormat = "jpeg"
+	}
+	if validTranscodeFormat(config.Configuration.TranscodeFormat) == false {
+		if config.Configuration.TranscodeFormat != "" {
+			logging.WriteLog(logging.LogLevelError, "main/fixMissingConfigs", "0", logging.ResultFailure, []string{"Unknown TranscodeFormat, mp4 will be used", config.Configuration.TranscodeFormat})
+		}
+		config.Configuration.TranscodeFormat = "mp4"
+	}
+	if config.Configuration.ThumbnailQuality <= 0 || config.Configuration.ThumbnailQuality > 100 {
+		config.Configuration.ThumbnailQuality = 85
+	}
 	if config.Configuration.PageStride <= 0 {
 		config.Configuration.PageStride = 30
 	}
+	if config.Configuration.JobWorkers <= 0 {
+		config.Configuration.JobWorkers = 2
+	}
+	if config.Configuration.JobMaxAttempts <= 0 {
+		config.Configuration.JobMaxAttempts = 3
+	}
+	if config.Configuration.AuditRetention <= 0 {
+		config.Configuration.AuditRetention = 30 * 24 * time.Hour
+	}
+	//Negative intervals disable their task, so only unset ones are defaulted
+	if config.Configuration.AuditCleanupInterval == 0 {
+		config.Configuration.AuditCleanupInterval = 24 * time.Hour
+	}
+	if config.Configuration.OrphanScanInterval == 0 {
+		config.Configuration.OrphanScanInterval = 7 * 24 * time.Hour
+	}
+	if config.Configuration.ScoreRecalculationInterval == 0 {
+		config.Configuration.ScoreRecalculationInterval = 24 * time.Hour
+	}
+	if config.Configuration.CollectionTagRepairInterval == 0 {
+		config.Configuration.CollectionTagRepairInterval = 24 * time.Hour
+	}
 	config.CreateSessionStore()
 }
 
+//thumbnailSizeNamePattern matches names allowed in ThumbnailSizes, shorter names could be mistaken for the directories of a sharded layout
+var thumbnailSizeNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_\-]{3,}$`)
+
+//validThumbnailFormat returns whether Format is one of storage.ThumbnailFormats
+func validThumbnailFormat(Format string) bool {
+	for _, format := range storage.ThumbnailFormats {
+		if Format == format {
+			return true
+		}
+	}
+	return false
+}
+
+//validTranscodeFormat returns whether Format is one of media.TranscodeFormats
+func validTranscodeFormat(Format string) bool {
+	for _, format := range media.TranscodeFormats {
+		if Format == format {
+			return true
+		}
+	}
+	return false
+}
+
+//thumbnailSizeRoutePattern returns a pattern matching the name of any of ThumbnailSizes
+func thumbnailSizeRoutePattern() string {
+	var names []string
+	for name := range config.Configuration.ThumbnailSizes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, "|")
+}
+
+//missingOnlyPayload returns the job payload for the -missingonly flag
+func missingOnlyPayload(MissingOnly bool) string {
+	if MissingOnly {
+		return jobs.MissingOnly
+	}
+	return ""
+}
+
 func badConfigServerListenAndServe(serverEndedWG *sync.WaitGroup, server *http.Server) {
 	defer serverEndedWG.Done()
 	logging.WriteLog(logging.LogLevelInfo, "main/main", "0", logging.ResultInfo, []string{"Temp server now listening"})
@@ -252,4 +252,169 @@ Mousetrap.bind("right", function() {$("#mainImageSearchForm .nextInCollection").
 Mousetrap.bind("left", function() {$("#mainImageSearchForm .previousInCollection").click();})
 Mousetrap.bind("ctrl+right", function() {$(".CollectionList .nextInCollection").click();})
 Mousetrap.bind("ctrl+left", function() {$(".CollectionList .previousInCollection").click();})
-Mousetrap.bind("shift+q", function() {$("#SideMenu form[action=\"/collections\"] :input[name='SearchTerms']").select();$("#SideMenu form[action=\"/tags\"] :input[name='SearchTags']").select();})
\ No newline at end of file
+Mousetrap.bind("shift+q", function() {$("#SideMenu form[action=\"/collections\"] :input[name='SearchTerms']").select();$("#SideMenu form[action=\"/tags\"] :input[name='SearchTags']").select();})
+//Preview animations, thumbnails of GIFs and videos play a short animation while the mouse is over them
+$(document).on("mouseenter", "img[data-preview]", function() {
+    var thumbnail = this;
+    thumbnail.dataset.stillSrc = thumbnail.getAttribute("src");
+    thumbnail.dataset.stillSrcset = thumbnail.getAttribute("srcset") || "";
+    //If there is no preview keep showing the thumbnail, and stop asking for it
+    thumbnail.onerror = function() {
+        thumbnail.removeAttribute("data-preview");
+        RestoreThumbnail(thumbnail);
+    };
+    thumbnail.removeAttribute("srcset");
+    thumbnail.src = thumbnail.dataset.preview;
+});
+$(document).on("mouseleave", "img[data-preview]", function() {
+    RestoreThumbnail(this);
+});
+function RestoreThumbnail(thumbnail) {
+    if (thumbnail.dataset.stillSrc == undefined) {
+        return;
+    }
+    thumbnail.onerror = null;
+    thumbnail.src = thumbnail.dataset.stillSrc;
+    if (thumbnail.dataset.stillSrcset != "") {
+        thumbnail.setAttribute("srcset", thumbnail.dataset.stillSrcset);
+    }
+    delete thumbnail.dataset.stillSrc;
+    delete thumbnail.dataset.stillSrcset;
+}
+//SuggestAudioTags asks the server for tags read from the start of each audio file chosen in fileInput, and lists them in resultID, clicking one adds it to tagsID
+function SuggestAudioTags(fileInput, resultID, tagsID) {
+    var results = document.getElementById(resultID);
+    results.innerHTML = "";
+    var token = fileInput.form.elements["gorilla.csrf.Token"].value;
+    for (var I = 0; I < fileInput.files.length; I++) {
+        var file = fileInput.files[I];
+        if (file.type.startsWith("audio/") != true && /\.(mp3|ogg|wav)$/i.test(file.name) != true) {
+            continue;
+        }
+        var formData = new FormData();
+        formData.append("fileHead", file.slice(0, 1 << 20), file.name);
+        var xhttp = new XMLHttpRequest();
+        xhttp.onreadystatechange = function() {
+            if (this.readyState == 4 && this.status == 200) {
+                var Result = JSON.parse(this.responseText);
+                for (var J = 0; J < Result.Tags.length; J++) {
+                    AddTagSuggestion(results, Result.Tags[J], tagsID);
+                }
+            }
+        };
+        xhttp.open("POST", "/api/TagSuggestions", true);
+        xhttp.setRequestHeader("X-CSRF-Token", token);
+        xhttp.send(formData);
+    }
+}
+function AddTagSuggestion(results, tag, tagsID) {
+    for (var I = 0; I < results.children.length; I++) {
+        if (results.children[I].innerText == tag) {
+            return; //Already offered for another file
+        }
+    }
+    var link = document.createElement("a");
+    link.href = "#";
+    link.className = "tagSuggestion";
+    link.innerText = tag;
+    link.onclick = function() {
+        var tags = document.getElementById(tagsID);
+        if ((" " + tags.value + " ").indexOf(" " + tag + " ") == -1) {
+            tags.value = (tags.value.trim() + " " + tag).trim();
+        }
+        link.remove();
+        return false;
+    };
+    results.appendChild(link);
+}
+//Blurhash placeholders, thumbnails are drawn blurred from their data-blurhash until they have loaded
+$(function() {
+    $("img[data-blurhash]").each(function() {
+        var thumbnail = this;
+        if (thumbnail.complete && thumbnail.naturalWidth > 0) {
+            return; //Already loaded, likely from the cache
+        }
+        var placeholder = DecodeBlurHash(thumbnail.dataset.blurhash, 32, 32);
+        if (placeholder == null) {
+            return;
+        }
+        thumbnail.style.backgroundImage = "url(" + placeholder + ")";
+        thumbnail.style.backgroundSize = "100% 100%";
+        thumbnail.addEventListener("load", function() {
+            thumbnail.style.backgroundImage = "";
+        }, { once: true });
+    });
+});
+//DecodeBlurHash draws hash at width by height, returning it as a data URL, or null if it is not a valid blurhash
+function DecodeBlurHash(hash, width, height) {
+    var digits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~";
+    var decode83 = function(text) {
+        var value = 0;
+        for (var I = 0; I < text.length; I++) {
+            var digit = digits.indexOf(text[I]);
+            if (digit == -1) {
+                return NaN;
+            }
+            value = value * 83 + digit;
+        }
+        return value;
+    };
+    var toLinear = function(value) {
+        value = value / 255;
+        return value <= 0.04045 ? value / 12.92 : Math.pow((value + 0.055) / 1.055, 2.4);
+    };
+    var toSRGB = function(value) {
+        value = Math.max(0, Math.min(1, value));
+        return Math.round(value <= 0.0031308 ? value * 12.92 * 255 : (1.055 * Math.pow(value, 1 / 2.4) - 0.055) * 255);
+    };
+    if (hash == undefined || hash.length < 6) {
+        return null;
+    }
+    var sizeFlag = decode83(hash[0]);
+    var componentsX = sizeFlag % 9 + 1;
+    var componentsY = Math.floor(sizeFlag / 9) + 1;
+    if (hash.length != 4 + 2 * componentsX * componentsY) {
+        return null;
+    }
+    var maximum = (decode83(hash[1]) + 1) / 166;
+    var factors = [];
+    var dc = decode83(hash.substring(2, 6));
+    factors.push([toLinear(dc >> 16), toLinear((dc >> 8) & 255), toLinear(dc & 255)]);
+    for (var I = 1; I < componentsX * componentsY; I++) {
+        var ac = decode83(hash.substring(4 + I * 2, 6 + I * 2));
+        var channels = [Math.floor(ac / 361), Math.floor(ac / 19) % 19, ac % 19];
+        factors.push(channels.map(function(quantised) {
+            var value = (quantised - 9) / 9;
+            return Math.sign(value) * value * value * maximum;
+        }));
+    }
+    if (factors.some(function(factor) { return factor.some(isNaN); })) {
+        return null;
+    }
+    var canvas = document.createElement("canvas");
+    canvas.width = width;
+    canvas.height = height;
+    var context = canvas.getContext("2d");
+    var pixels = context.createImageData(width, height);
+    for (var y = 0; y < height; y++) {
+        for (var x = 0; x < width; x++) {
+            var color = [0, 0, 0];
+            for (var j = 0; j < componentsY; j++) {
+                for (var i = 0; i < componentsX; i++) {
+                    var basis = Math.cos(Math.PI * x * i / width) * Math.cos(Math.PI * y * j / height);
+                    var factor = factors[i + j * componentsX];
+                    color[0] += factor[0] * basis;
+                    color[1] += factor[1] * basis;
+                    color[2] += factor[2] * basis;
+                }
+            }
+            var offset = 4 * (x + y * width);
+            pixels.data[offset] = toSRGB(color[0]);
+            pixels.data[offset + 1] = toSRGB(color[1]);
+            pixels.data[offset + 2] = toSRGB(color[2]);
+            pixels.data[offset + 3] = 255;
+        }
+    }
+    context.putImageData(pixels, 0, 0);
+    return canvas.toDataURL();
+}
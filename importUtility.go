@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/jobs"
+	"go-image-board/logging"
+	"go-image-board/media"
+	"go-image-board/routers"
+	"go-image-board/storage"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+//Results recorded for each file in the import progress file
+const (
+	importStatusImported    = "imported"
+	importStatusDuplicate   = "duplicate"
+	importStatusFailed      = "failed"
+	importStatusUnsupported = "unsupported"
+)
+
+//importSidecar contains the upload details for a file, read from file.ext.txt and file.ext.json next to it
+//The JSON fields match those of the upload API, Tags may also be a list of tag names
+type importSidecar struct {
+	Tags       importTags
+	Source     string
+	Rating     string
+	Collection string
+}
+
+//importTags holds tags in the same format as the upload form, JSON sidecars may give either that string or a list of tag names
+type importTags string
+
+//UnmarshalJSON accepts either a string of tags, or a list of tag names which may contain spaces
+func (Tags *importTags) UnmarshalJSON(Data []byte) error {
+	var tagString string
+	if err := json.Unmarshal(Data, &tagString); err == nil {
+		*Tags = importTags(tagString)
+		return nil
+	}
+	var tagList []string
+	if err := json.Unmarshal(Data, &tagList); err != nil {
+		return errors.New("Tags must be a string or a list of strings")
+	}
+	for index, tag := range tagList {
+		tagList[index] = strings.Join(strings.Fields(tag), "_")
+	}
+	*Tags = importTags(strings.Join(tagList, " "))
+	return nil
+}
+
+//importResult is the outcome of importing a single file
+type importResult struct {
+	Status  string
+	ImageID uint64
+	Path    string
+	Detail  string
+}
+
+//importDirectory uploads every supported file under Directory as UserName, using the same path as the upload API
+//Each result is appended to ProgressPath, and files already imported or found as duplicates there are skipped, so an interrupted import can be run again
+//When DryRun is set nothing is uploaded or recorded, the files and sidecars are only checked
+func importDirectory(Directory string, UserName string, ProgressPath string, DryRun bool) {
+	if UserName == "" {
+		logging.WriteLog(logging.LogLevelError, "importUtility/importDirectory", "0", logging.ResultFailure, []string{"A username is required to import, use -username"})
+		return
+	}
+	userID, err := database.DBInterface.GetUserID(UserName)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "importUtility/importDirectory", "0", logging.ResultFailure, []string{"Failed to find user to import as", UserName, err.Error()})
+		return
+	}
+	userPermission, err := database.DBInterface.GetUserPermissionSet(UserName)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "importUtility/importDirectory", "0", logging.ResultFailure, []string{"Failed to get permissions of user to import as", UserName, err.Error()})
+		return
+	}
+	userInformation := interfaces.UserInformation{Name: UserName, ID: userID}
+	//Rating is set after upload, the uploader may only change it if they could from the image page
+	canRate := interfaces.UserPermission(userPermission).HasPermission(interfaces.ModifyImageTags) || config.Configuration.UsersControlOwnObjects
+
+	Directory, err = filepath.Abs(Directory)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "importUtility/importDirectory", "0", logging.ResultFailure, []string{"Failed to resolve import directory", err.Error()})
+		return
+	}
+	finishedFiles, err := readImportProgress(ProgressPath)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "importUtility/importDirectory", "0", logging.ResultFailure, []string{"Failed to read import progress", ProgressPath, err.Error()})
+		return
+	}
+	var progressFile *os.File
+	if DryRun == false {
+		progressFile, err = os.OpenFile(ProgressPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0660)
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "importUtility/importDirectory", "0", logging.ResultFailure, []string{"Failed to open import progress", ProgressPath, err.Error()})
+			return
+		}
+		defer progressFile.Close()
+	}
+	logging.WriteLog(logging.LogLevelInfo, "importUtility/importDirectory", "0", logging.ResultInfo, []string{"Importing", Directory, "as", UserName, "progress in", ProgressPath, "dry run", strconv.FormatBool(DryRun)})
+
+	resultCounts := make(map[string]uint64)
+	skippedFiles := uint64(0)
+	err = filepath.WalkDir(Directory, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			//Report and carry on past anything that cannot be read
+			logging.WriteLog(logging.LogLevelError, "importUtility/importDirectory", "0", logging.ResultFailure, []string{"Failed to read", filePath, err.Error()})
+			resultCounts[importStatusFailed]++
+			return nil
+		}
+		if entry.IsDir() || isImportSidecar(filePath) {
+			return nil
+		}
+		if finishedFiles[filePath] {
+			skippedFiles++
+			return nil
+		}
+		result := importFile(filePath, userInformation, canRate, DryRun)
+		resultCounts[result.Status]++
+		logImportResult(result, DryRun)
+		if progressFile != nil {
+			if err := writeImportProgress(progressFile, result); err != nil {
+				return err //Stop, as progress that cannot be recorded cannot be resumed
+			}
+		}
+		//Throttle how fast thumbnails are queued, as import does not wait on them
+		//Only files just imported queue thumbnails, so only they are counted
+		if result.Status == importStatusImported && resultCounts[importStatusImported]%config.Configuration.PageStride == 0 {
+			jobs.Wait()
+		}
+		return nil
+	})
+	jobs.Wait()
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "importUtility/importDirectory", "0", logging.ResultFailure, []string{"Import stopped", err.Error()})
+	}
+	importedLabel := "imported"
+	if DryRun {
+		importedLabel = "to import"
+	}
+	logging.WriteLog(logging.LogLevelInfo, "importUtility/importDirectory", "0", logging.ResultSuccess, []string{"Finished import.",
+		strconv.FormatUint(resultCounts[importStatusImported], 10), importedLabel + ",",
+		strconv.FormatUint(resultCounts[importStatusDuplicate], 10), "duplicates,",
+		strconv.FormatUint(resultCounts[importStatusFailed], 10), "failed,",
+		strconv.FormatUint(resultCounts[importStatusUnsupported], 10), "unsupported,",
+		strconv.FormatUint(skippedFiles, 10), "already done by a previous run."})
+}
+
+//importFile uploads a single file along with the details from its sidecars
+func importFile(FilePath string, UserInformation interfaces.UserInformation, CanRate bool, DryRun bool) importResult {
+	result := importResult{Path: FilePath}
+	sidecar, err := readImportSidecar(FilePath)
+	if err != nil {
+		result.Status = importStatusFailed
+		result.Detail = "Failed to read sidecar, " + err.Error()
+		return result
+	}
+	fileData, err := os.ReadFile(FilePath)
+	if err != nil {
+		result.Status = importStatusFailed
+		result.Detail = err.Error()
+		return result
+	}
+
+	//Files are recognized by their content, as uploads are
+	mediaType, content, _, err := routers.PrepareUpload(FilePath, bytes.NewReader(fileData))
+	if err != nil {
+		result.Status = importStatusUnsupported
+		return result
+	}
+
+	//Check for duplicates first, so they do not create empty collections or new tags
+	hashName, err := routers.GetNewImageName(mediaType.WithExtension(FilePath), content)
+	if err != nil {
+		result.Status = importStatusFailed
+		result.Detail = err.Error()
+		return result
+	}
+	exists, err := storage.Exists(storage.ImageLocation(hashName))
+	if err != nil {
+		result.Status = importStatusFailed
+		result.Detail = err.Error()
+		return result
+	}
+	if exists {
+		result.Status = importStatusDuplicate
+		if duplicateInfo, err := database.DBInterface.GetImageByFileName(storage.ImageLocation(hashName)); err == nil {
+			result.ImageID = duplicateInfo.ID
+		}
+		return result
+	}
+	if DryRun {
+		result.Status = importStatusImported
+		result.Detail = describeImportSidecar(sidecar)
+		return result
+	}
+
+	fileName := filepath.Base(FilePath)
+	lastID, duplicateIDs, err := routers.HandleImageUploadRequest(nil, UserInformation, sidecar.Collection, string(sidecar.Tags), []routers.UploadingFile{{Name: fileName, Data: fileData}}, sidecar.Source)
+	if duplicateID, isDuplicate := duplicateIDs[fileName]; isDuplicate {
+		result.Status = importStatusDuplicate
+		result.ImageID = duplicateID
+		return result
+	}
+	if lastID == 0 {
+		result.Status = importStatusFailed
+		if err != nil {
+			result.Detail = err.Error()
+		}
+		return result
+	}
+	result.Status = importStatusImported
+	result.ImageID = lastID
+	//The image is uploaded, anything else that went wrong is only noted
+	if err != nil {
+		result.Detail = err.Error()
+	}
+	if sidecar.Rating != "" {
+		if CanRate == false {
+			result.Detail += "Rating not set due to insufficient permissions. "
+		} else if err := database.DBInterface.SetImageRating(lastID, strings.ToLower(sidecar.Rating)); err != nil {
+			result.Detail += "Failed to set rating. "
+		}
+	}
+	return result
+}
+
+//isImportSidecar returns whether a file holds the details of another file, rather than being one to import
+func isImportSidecar(FilePath string) bool {
+	switch extension := strings.ToLower(filepath.Ext(FilePath)); extension {
+	case ".txt", ".json":
+		_, isMedia := media.ByName(FilePath[:len(FilePath)-len(extension)])
+		return isMedia
+	}
+	return false
+}
+
+//readImportSidecar reads the sidecars of a file, if it has any
+//A text sidecar holds tags as they would be typed into the upload form, along with optional lines starting source:, rating:, or collection:
+//When both exist, tags from each are used, and other details from the JSON sidecar win
+func readImportSidecar(FilePath string) (importSidecar, error) {
+	var ToReturn importSidecar
+	textFile, err := os.Open(FilePath + ".txt")
+	if err == nil {
+		var tags []string
+		scanner := bufio.NewScanner(textFile)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			key, value, hasKey := strings.Cut(line, ":")
+			value = strings.TrimSpace(value)
+			switch strings.ToLower(key) {
+			case "source":
+				ToReturn.Source = value
+			case "rating":
+				ToReturn.Rating = value
+			case "collection":
+				ToReturn.Collection = value
+			default:
+				hasKey = false
+			}
+			if hasKey == false && line != "" {
+				tags = append(tags, line)
+			}
+		}
+		textFile.Close()
+		if err := scanner.Err(); err != nil {
+			return ToReturn, err
+		}
+		ToReturn.Tags = importTags(strings.Join(tags, " "))
+	} else if errors.Is(err, fs.ErrNotExist) == false {
+		return ToReturn, err
+	}
+
+	jsonData, err := os.ReadFile(FilePath + ".json")
+	if err == nil {
+		var jsonSidecar importSidecar
+		if err := json.Unmarshal(jsonData, &jsonSidecar); err != nil {
+			return ToReturn, err
+		}
+		ToReturn.Tags = importTags(strings.TrimSpace(string(ToReturn.Tags) + " " + string(jsonSidecar.Tags)))
+		if jsonSidecar.Source != "" {
+			ToReturn.Source = jsonSidecar.Source
+		}
+		if jsonSidecar.Rating != "" {
+			ToReturn.Rating = jsonSidecar.Rating
+		}
+		if jsonSidecar.Collection != "" {
+			ToReturn.Collection = jsonSidecar.Collection
+		}
+	} else if errors.Is(err, fs.ErrNotExist) == false {
+		return ToReturn, err
+	}
+	return ToReturn, nil
+}
+
+//describeImportSidecar summarizes sidecar details for the dry run report
+func describeImportSidecar(Sidecar importSidecar) string {
+	var details []string
+	if Sidecar.Tags != "" {
+		details = append(details, "tags: "+string(Sidecar.Tags))
+	}
+	if Sidecar.Source != "" {
+		details = append(details, "source: "+Sidecar.Source)
+	}
+	if Sidecar.Rating != "" {
+		details = append(details, "rating: "+Sidecar.Rating)
+	}
+	if Sidecar.Collection != "" {
+		details = append(details, "collection: "+Sidecar.Collection)
+	}
+	return strings.Join(details, ", ")
+}
+
+//logImportResult reports the outcome of a file as it is imported
+func logImportResult(Result importResult, DryRun bool) {
+	imageID := strconv.FormatUint(Result.ImageID, 10)
+	switch Result.Status {
+	case importStatusImported:
+		if DryRun {
+			logging.WriteLog(logging.LogLevelInfo, "importUtility/importDirectory", "0", logging.ResultInfo, []string{"Would import", Result.Path, Result.Detail})
+		} else {
+			logging.WriteLog(logging.LogLevelInfo, "importUtility/importDirectory", "0", logging.ResultSuccess, []string{"Imported", Result.Path, "as", imageID, Result.Detail})
+		}
+	case importStatusDuplicate:
+		logging.WriteLog(logging.LogLevelWarning, "importUtility/importDirectory", "0", logging.ResultInfo, []string{"Duplicate", Result.Path, "already uploaded as", imageID})
+	case importStatusUnsupported:
+		logging.WriteLog(logging.LogLevelWarning, "importUtility/importDirectory", "0", logging.ResultInfo, []string{"Skipping unsupported file", Result.Path})
+	default:
+		logging.WriteLog(logging.LogLevelError, "importUtility/importDirectory", "0", logging.ResultFailure, []string{"Failed to import", Result.Path, Result.Detail})
+	}
+}
+
+//readImportProgress returns the files an earlier run imported or found to be duplicates, a missing progress file means nothing is done yet
+func readImportProgress(ProgressPath string) (map[string]bool, error) {
+	ToReturn := make(map[string]bool)
+	progressFile, err := os.Open(ProgressPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ToReturn, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer progressFile.Close()
+	scanner := bufio.NewScanner(progressFile)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 3 {
+			continue
+		}
+		if fields[0] == importStatusImported || fields[0] == importStatusDuplicate {
+			ToReturn[fields[2]] = true
+		}
+	}
+	return ToReturn, scanner.Err()
+}
+
+//writeImportProgress appends a result to the progress file as status, image id, path, and detail separated by tabs
+func writeImportProgress(ProgressFile *os.File, Result importResult) error {
+	detail := strings.Join(strings.Fields(Result.Detail), " ")
+	_, err := ProgressFile.WriteString(Result.Status + "\t" + strconv.FormatUint(Result.ImageID, 10) + "\t" + Result.Path + "\t" + detail + "\n")
+	return err
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/jobs"
+	"go-image-board/logging"
+	"go-image-board/media"
+	"go-image-board/plugins"
+	"go-image-board/plugins/localstorageplugin"
+	"go-image-board/plugins/memoryplugin"
+	"go-image-board/routers"
+	"go-image-board/storage"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+//setupImportTest prepares an empty memory database and image directory, and a user to import as
+func setupImportTest(t *testing.T) {
+	t.Helper()
+	quietLog := &plugins.STDLog{}
+	quietLog.Init(logging.LogLevelCritical, "", "")
+	logging.LogInterface = quietLog
+	config.Configuration.PageStride = 10
+	config.Configuration.AllowedMediaTypes = media.Names()
+	config.Configuration.MaxThumbnailWidth = 64
+	config.Configuration.MaxThumbnailHeight = 64
+	config.Configuration.ImageDirectory = t.TempDir()
+	storage.StorageInterface = &localstorageplugin.LocalStoragePlugin{}
+	if err := storage.StorageInterface.Init(); err != nil {
+		t.Fatalf("storage Init: %v", err)
+	}
+	database.DBInterface = &memoryplugin.MemoryPlugin{}
+	if err := database.DBInterface.InitDatabase(); err != nil {
+		t.Fatalf("InitDatabase: %v", err)
+	}
+	if err := database.DBInterface.CreateUser("importer", []byte("password"), "importer@localhost", 4294967295); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	registerJobHandlers()
+	jobs.Start(2)
+}
+
+//writeTestFile writes Content to Name under Directory, creating any directories needed
+func writeTestFile(t *testing.T, Directory string, Name string, Content []byte) {
+	t.Helper()
+	filePath := filepath.Join(Directory, filepath.FromSlash(Name))
+	if err := os.MkdirAll(filepath.Dir(filePath), 0770); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filePath, Content, 0660); err != nil {
+		t.Fatal(err)
+	}
+}
+
+//testPNG returns a small image, different for each Shade
+func testPNG(t *testing.T, Shade uint8) []byte {
+	t.Helper()
+	testImage := image.NewGray(image.Rect(0, 0, 4, 4))
+	for index := range testImage.Pix {
+		testImage.Pix[index] = Shade
+	}
+	testImage.Set(0, 0, color.White)
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, testImage); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+//readProgressStatuses returns the status recorded for each file, by path relative to Directory
+func readProgressStatuses(t *testing.T, ProgressPath string, Directory string) map[string]string {
+	t.Helper()
+	data, err := os.ReadFile(ProgressPath)
+	if err != nil {
+		t.Fatalf("reading progress: %v", err)
+	}
+	Directory, _ = filepath.Abs(Directory)
+	ToReturn := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.Split(line, "\t")
+		relativePath, _ := filepath.Rel(Directory, fields[2])
+		ToReturn[filepath.ToSlash(relativePath)] = fields[0]
+	}
+	return ToReturn
+}
+
+func imageTagNames(t *testing.T, ImageID uint64) string {
+	t.Helper()
+	tags, err := database.DBInterface.GetImageTags(ImageID)
+	if err != nil {
+		t.Fatalf("GetImageTags: %v", err)
+	}
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, " ")
+}
+
+func TestImportDirectory(t *testing.T) {
+	setupImportTest(t)
+	importPath := t.TempDir()
+	progressPath := filepath.Join(t.TempDir(), "progress.tsv")
+
+	writeTestFile(t, importPath, "a.png", testPNG(t, 10))
+	writeTestFile(t, importPath, "a.png.txt", []byte("red blue\nsource: https://example.com/a\nRating: Explicit\n"))
+	writeTestFile(t, importPath, "set/1.png", testPNG(t, 20))
+	writeTestFile(t, importPath, "set/1.png.json", []byte(`{"Tags": ["dark sky", "night"], "Collection": "Night Set"}`))
+	writeTestFile(t, importPath, "set/2.png", testPNG(t, 30))
+	writeTestFile(t, importPath, "set/2.png.json", []byte(`{"Tags": "night", "Collection": "Night Set", "Source": "scan"}`))
+	writeTestFile(t, importPath, "set/2.png.txt", []byte("moon\nsource: ignored\n"))
+	writeTestFile(t, importPath, "copy of a.png", testPNG(t, 10))
+	writeTestFile(t, importPath, "notes.doc", []byte("not an image"))
+	writeTestFile(t, importPath, "broken.png", testPNG(t, 40))
+	writeTestFile(t, importPath, "broken.png.json", []byte(`{"Tags": 5}`))
+
+	//A dry run changes nothing
+	importDirectory(importPath, "importer", progressPath, true)
+	if _, err := os.Stat(progressPath); err == nil {
+		t.Errorf("dry run wrote a progress file")
+	}
+	if _, count, err := database.DBInterface.SearchImages(nil, 0, 10); err != nil || count != 0 {
+		t.Errorf("dry run uploaded images: %d, %v", count, err)
+	}
+	if _, err := database.DBInterface.GetCollectionByName("Night Set"); err == nil {
+		t.Errorf("dry run created a collection")
+	}
+
+	importDirectory(importPath, "importer", progressPath, false)
+	statuses := readProgressStatuses(t, progressPath, importPath)
+	expected := map[string]string{
+		"a.png":         importStatusImported,
+		"broken.png":    importStatusFailed,
+		"copy of a.png": importStatusDuplicate,
+		"notes.doc":     importStatusUnsupported,
+		"set/1.png":     importStatusImported,
+		"set/2.png":     importStatusImported,
+	}
+	if fmt.Sprint(statuses) != fmt.Sprint(expected) {
+		t.Errorf("progress: got %v, expected %v", statuses, expected)
+	}
+
+	//Details from the text sidecar
+	imageA, err := database.DBInterface.GetImageByFileName(storage.ImageLocation(hashName(t, testPNG(t, 10))))
+	if err != nil {
+		t.Fatalf("GetImageByFileName of a.png: %v", err)
+	}
+	if imageA.Source != "https://example.com/a" || imageA.Rating != "explicit" {
+		t.Errorf("a.png details: source %q, rating %q", imageA.Source, imageA.Rating)
+	}
+	if tags := imageTagNames(t, imageA.ID); tags != "blue red" {
+		t.Errorf("a.png tags: %q", tags)
+	}
+	if _, err := storage.StorageInterface.Stat(storage.ThumbnailName(imageA.Location)); err != nil {
+		t.Errorf("thumbnail of a.png was not generated: %v", err)
+	}
+
+	//Details from JSON sidecars, in a collection ordered by file name
+	collection, err := database.DBInterface.GetCollectionByName("Night Set")
+	if err != nil {
+		t.Fatalf("GetCollectionByName: %v", err)
+	}
+	members, _, err := database.DBInterface.GetCollectionMembers(collection.ID, 0, 10)
+	if err != nil || len(members) != 2 {
+		t.Fatalf("GetCollectionMembers: %+v, %v", members, err)
+	}
+	if tags := imageTagNames(t, members[0].ID); tags != "dark_sky night" {
+		t.Errorf("set/1.png tags: %q", tags)
+	}
+	secondImage, err := database.DBInterface.GetImage(members[1].ID)
+	if err != nil {
+		t.Fatalf("GetImage: %v", err)
+	}
+	if tags := imageTagNames(t, secondImage.ID); tags != "moon night" || secondImage.Source != "scan" {
+		t.Errorf("set/2.png details: tags %q, source %q", tags, secondImage.Source)
+	}
+
+	//Running again only retries what did not finish
+	writeTestFile(t, importPath, "broken.png.json", []byte(`{"Tags": "fixed"}`))
+	importDirectory(importPath, "importer", progressPath, false)
+	statuses = readProgressStatuses(t, progressPath, importPath)
+	if statuses["broken.png"] != importStatusImported {
+		t.Errorf("broken.png after fixing sidecar: %q", statuses["broken.png"])
+	}
+	if _, count, err := database.DBInterface.SearchImages(nil, 0, 10); err != nil || count != 4 {
+		t.Errorf("images after resuming: %d, %v", count, err)
+	}
+	data, _ := os.ReadFile(progressPath)
+	if lines := strings.Count(string(data), "\n"); lines != 8 {
+		t.Errorf("resumed import recorded %d lines, expected the 6 from the first run plus broken.png and notes.doc again", lines)
+	}
+}
+
+//hashName returns the name an uploaded PNG with this content is given
+func hashName(t *testing.T, Data []byte) string {
+	t.Helper()
+	name, err := routers.GetNewImageName("upload.png", bytes.NewReader(Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
@@ -0,0 +1,133 @@
+package importers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//danbooruPost contains the fields of a Danbooru post that are imported
+type danbooruPost struct {
+	ID                 json.Number `json:"id"`
+	MD5                string      `json:"md5"`
+	FileExt            string      `json:"file_ext"`
+	FileURL            string      `json:"file_url"`
+	Rating             string      `json:"rating"`
+	Source             string      `json:"source"`
+	Score              int64       `json:"score"`
+	TagString          string      `json:"tag_string"`
+	TagStringGeneral   string      `json:"tag_string_general"`
+	TagStringArtist    string      `json:"tag_string_artist"`
+	TagStringCharacter string      `json:"tag_string_character"`
+	TagStringCopyright string      `json:"tag_string_copyright"`
+	TagStringMeta      string      `json:"tag_string_meta"`
+}
+
+//danbooruPool contains the fields of a Danbooru pool that are imported
+type danbooruPool struct {
+	ID          json.Number   `json:"id"`
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	PostIDs     []json.Number `json:"post_ids"`
+}
+
+//danbooruRatings converts Danbooru's rating letters to names
+var danbooruRatings = map[string]string{
+	"g": "general",
+	"s": "sensitive",
+	"q": "questionable",
+	"e": "explicit",
+}
+
+//ReadDanbooru reads posts saved from Danbooru's posts.json API, and optionally pools from pools.json
+//Either file may hold a JSON list, or one JSON object per line as in metadata dumps
+//Files are found in FileDirectory named by MD5 or post ID, or with the name in their file_url
+func ReadDanbooru(PostsPath string, PoolsPath string, FileDirectory string) (Export, error) {
+	var ToReturn Export
+	var posts []danbooruPost
+	if err := readJSONList(PostsPath, &posts); err != nil {
+		return ToReturn, err
+	}
+	for _, post := range posts {
+		if post.ID == "" {
+			return ToReturn, errors.New("Danbooru post without an id in " + PostsPath)
+		}
+		extension := strings.TrimPrefix(post.FileExt, ".")
+		newPost := Post{
+			ID:       post.ID.String(),
+			FilePath: findFile(FileDirectory, post.MD5+"."+extension, post.ID.String()+"."+extension, fileNameFromURL(post.FileURL)),
+			Rating:   danbooruRatings[post.Rating],
+			Source:   post.Source,
+			Score:    post.Score,
+		}
+		//Posts from the API split tags by category, but older exports only have tag_string
+		if post.TagStringGeneral != "" || post.TagStringArtist != "" || post.TagStringCharacter != "" || post.TagStringCopyright != "" || post.TagStringMeta != "" {
+			newPost.Tags = append(newPost.Tags, splitTags(post.TagStringGeneral, CategoryGeneral)...)
+			newPost.Tags = append(newPost.Tags, splitTags(post.TagStringArtist, CategoryArtist)...)
+			newPost.Tags = append(newPost.Tags, splitTags(post.TagStringCharacter, CategoryCharacter)...)
+			newPost.Tags = append(newPost.Tags, splitTags(post.TagStringCopyright, CategoryCopyright)...)
+			newPost.Tags = append(newPost.Tags, splitTags(post.TagStringMeta, CategoryMeta)...)
+		} else {
+			newPost.Tags = splitTags(post.TagString, CategoryGeneral)
+		}
+		ToReturn.Posts = append(ToReturn.Posts, newPost)
+	}
+
+	if PoolsPath == "" {
+		return ToReturn, nil
+	}
+	var pools []danbooruPool
+	if err := readJSONList(PoolsPath, &pools); err != nil {
+		return ToReturn, err
+	}
+	for _, pool := range pools {
+		newPool := Pool{
+			//Danbooru uses _ in place of spaces in pool names
+			Name:        strings.ReplaceAll(pool.Name, "_", " "),
+			Description: pool.Description,
+		}
+		if newPool.Name == "" {
+			newPool.Name = "Pool " + pool.ID.String()
+		}
+		for _, postID := range pool.PostIDs {
+			newPool.PostIDs = append(newPool.PostIDs, postID.String())
+		}
+		ToReturn.Pools = append(ToReturn.Pools, newPool)
+	}
+	return ToReturn, nil
+}
+
+//readJSONList decodes a file holding either a JSON list, or a series of JSON objects, into List
+func readJSONList[T any](FilePath string, List *[]T) error {
+	jsonFile, err := os.Open(FilePath)
+	if err != nil {
+		return err
+	}
+	defer jsonFile.Close()
+	decoder := json.NewDecoder(jsonFile)
+	decoder.UseNumber()
+	for line := 1; ; line++ {
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errors.New(FilePath + ": " + err.Error())
+		}
+		if strings.HasPrefix(strings.TrimSpace(string(value)), "[") {
+			var values []T
+			if err := json.Unmarshal(value, &values); err != nil {
+				return errors.New(FilePath + ": " + err.Error())
+			}
+			*List = append(*List, values...)
+		} else {
+			var single T
+			if err := json.Unmarshal(value, &single); err != nil {
+				return errors.New(FilePath + ", value " + strconv.Itoa(line) + ": " + err.Error())
+			}
+			*List = append(*List, single)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package importers
+
+import (
+	"encoding/xml"
+	"errors"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//gelbooruPost contains the fields of a Gelbooru post that are imported
+//Older versions of the API give these as attributes, newer ones as child elements
+type gelbooruPost struct {
+	ID            string `xml:"id,attr"`
+	MD5           string `xml:"md5,attr"`
+	FileURL       string `xml:"file_url,attr"`
+	Rating        string `xml:"rating,attr"`
+	Source        string `xml:"source,attr"`
+	Score         string `xml:"score,attr"`
+	Tags          string `xml:"tags,attr"`
+	IDElement     string `xml:"id"`
+	MD5Element    string `xml:"md5"`
+	ImageElement  string `xml:"image"`
+	URLElement    string `xml:"file_url"`
+	RatingElement string `xml:"rating"`
+	SourceElement string `xml:"source"`
+	ScoreElement  string `xml:"score"`
+	TagsElement   string `xml:"tags"`
+}
+
+//gelbooruTag contains the fields of a Gelbooru tag that are imported
+type gelbooruTag struct {
+	Name        string `xml:"name,attr"`
+	Type        string `xml:"type,attr"`
+	NameElement string `xml:"name"`
+	TypeElement string `xml:"type"`
+}
+
+//gelbooruRatings converts Gelbooru's rating letters to names, newer exports already use names
+var gelbooruRatings = map[string]string{
+	"s": "safe",
+	"q": "questionable",
+	"e": "explicit",
+}
+
+//gelbooruCategories converts Gelbooru's tag types to categories, both numbers and names are used depending on the version
+var gelbooruCategories = map[string]string{
+	"1":         CategoryArtist,
+	"artist":    CategoryArtist,
+	"3":         CategoryCopyright,
+	"copyright": CategoryCopyright,
+	"4":         CategoryCharacter,
+	"character": CategoryCharacter,
+	"5":         CategoryMeta,
+	"metadata":  CategoryMeta,
+}
+
+//firstOf returns the first of Values that is not empty
+func firstOf(Values ...string) string {
+	for _, value := range Values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+//ReadGelbooru reads posts saved from Gelbooru's XML API, and optionally the categories of tags from its tag XML API
+//Gelbooru posts do not carry tag categories, so without TagsPath every tag is general
+//Files are found in FileDirectory named by MD5 or with the name in their file_url
+func ReadGelbooru(PostsPath string, TagsPath string, FileDirectory string) (Export, error) {
+	var ToReturn Export
+	categories := make(map[string]string)
+	if TagsPath != "" {
+		var tagList struct {
+			Tags []gelbooruTag `xml:"tag"`
+		}
+		if err := readXML(TagsPath, &tagList); err != nil {
+			return ToReturn, err
+		}
+		for _, tag := range tagList.Tags {
+			categories[html.UnescapeString(firstOf(tag.Name, tag.NameElement))] = gelbooruCategories[strings.ToLower(firstOf(tag.Type, tag.TypeElement))]
+		}
+	}
+
+	var postList struct {
+		Posts []gelbooruPost `xml:"post"`
+	}
+	if err := readXML(PostsPath, &postList); err != nil {
+		return ToReturn, err
+	}
+	for _, post := range postList.Posts {
+		md5 := firstOf(post.MD5, post.MD5Element)
+		fileName := firstOf(fileNameFromURL(firstOf(post.FileURL, post.URLElement)), post.ImageElement)
+		newPost := Post{
+			ID:     firstOf(post.ID, post.IDElement),
+			Source: firstOf(post.Source, post.SourceElement),
+			Rating: strings.ToLower(firstOf(post.Rating, post.RatingElement)),
+		}
+		if rating, isLetter := gelbooruRatings[newPost.Rating]; isLetter {
+			newPost.Rating = rating
+		}
+		if md5 != "" {
+			newPost.FilePath = findFile(FileDirectory, md5+filepath.Ext(fileName), fileName)
+		} else {
+			newPost.FilePath = findFile(FileDirectory, fileName)
+		}
+		//Scores that are not numbers are left at 0
+		newPost.Score, _ = parseScore(firstOf(post.Score, post.ScoreElement))
+		//Gelbooru escapes some characters in tags a second time
+		for _, name := range strings.Fields(html.UnescapeString(firstOf(post.Tags, post.TagsElement))) {
+			newPost.Tags = append(newPost.Tags, Tag{Name: name, Category: categories[name]})
+		}
+		ToReturn.Posts = append(ToReturn.Posts, newPost)
+	}
+	return ToReturn, nil
+}
+
+//readXML decodes an XML file into Value
+func readXML(FilePath string, Value interface{}) error {
+	xmlFile, err := os.Open(FilePath)
+	if err != nil {
+		return err
+	}
+	defer xmlFile.Close()
+	if err := xml.NewDecoder(xmlFile).Decode(Value); err != nil {
+		return errors.New(FilePath + ": " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package importers
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//hydrusCategories converts the namespaces Hydrus's downloaders use to categories, the namespace is removed from those tags
+var hydrusCategories = map[string]string{
+	"creator":   CategoryArtist,
+	"artist":    CategoryArtist,
+	"character": CategoryCharacter,
+	"series":    CategoryCopyright,
+	"copyright": CategoryCopyright,
+	"meta":      CategoryMeta,
+}
+
+//ReadHydrus reads files exported from Hydrus along with their sidecars, from anywhere under Directory
+//Tags are read from file.ext.txt or file.ext.tags.txt, one per line, and the first URL in file.ext.urls.txt is used as the source
+//Namespaced tags are kept as namespace_tag, except for rating: which sets the rating and the namespaces in hydrusCategories
+//Hydrus has no post IDs, so each post's ID is its path relative to Directory
+func ReadHydrus(Directory string) (Export, error) {
+	var ToReturn Export
+	err := filepath.WalkDir(Directory, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || strings.HasSuffix(filePath, ".txt") {
+			return nil
+		}
+		newPost := Post{FilePath: filePath}
+		newPost.ID, _ = filepath.Rel(Directory, filePath)
+		newPost.ID = filepath.ToSlash(newPost.ID)
+		for _, sidecarPath := range []string{filePath + ".txt", filePath + ".tags.txt"} {
+			lines, err := readLines(sidecarPath)
+			if err != nil {
+				return err
+			}
+			for _, line := range lines {
+				namespace, value, isNamespaced := strings.Cut(line, ":")
+				namespace = strings.ToLower(namespace)
+				if isNamespaced == false || value == "" {
+					newPost.Tags = append(newPost.Tags, Tag{Name: line})
+				} else if namespace == "rating" {
+					newPost.Rating = strings.ToLower(value)
+				} else if category, isCategory := hydrusCategories[namespace]; isCategory {
+					newPost.Tags = append(newPost.Tags, Tag{Name: value, Category: category})
+				} else {
+					newPost.Tags = append(newPost.Tags, Tag{Name: namespace + "_" + value})
+				}
+			}
+		}
+		urls, err := readLines(filePath + ".urls.txt")
+		if err != nil {
+			return err
+		}
+		if len(urls) > 0 {
+			newPost.Source = urls[0]
+		}
+		ToReturn.Posts = append(ToReturn.Posts, newPost)
+		return nil
+	})
+	return ToReturn, err
+}
+
+//readLines returns the trimmed, non-empty lines of a file, or nothing if it does not exist
+func readLines(FilePath string) ([]string, error) {
+	textFile, err := os.Open(FilePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer textFile.Close()
+	var ToReturn []string
+	scanner := bufio.NewScanner(textFile)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			ToReturn = append(ToReturn, line)
+		}
+	}
+	return ToReturn, scanner.Err()
+}
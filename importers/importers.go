@@ -0,0 +1,98 @@
+package importers
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//Tag categories used by other boards, general tags have no category
+const (
+	CategoryGeneral   = ""
+	CategoryArtist    = "artist"
+	CategoryCharacter = "character"
+	CategoryCopyright = "copyright"
+	CategoryMeta      = "meta"
+)
+
+//Post is an image described by another board's export, along with where its file is
+type Post struct {
+	//ID is the post's ID on the original board, pools refer to posts by it
+	ID string
+	//FilePath is where the file is on disk, or empty if it was not found
+	FilePath string
+	Tags     []Tag
+	Rating   string
+	Source   string
+	//Score is the post's total score on the original board
+	Score int64
+}
+
+//Tag is a tag along with the category the original board filed it under
+type Tag struct {
+	Name     string
+	Category string
+}
+
+//Pool is an ordered set of posts, which is imported as a collection
+type Pool struct {
+	Name        string
+	Description string
+	PostIDs     []string
+}
+
+//Export is everything read from another board's export
+type Export struct {
+	Posts []Post
+	Pools []Pool
+}
+
+//regexTagName matches characters the database plugins replace when cleaning a tag name
+var regexTagName = regexp.MustCompile("[^a-z0-9_-]")
+
+//CleanTagName converts a tag name from another board to the form the database stores
+//This matches the cleanup the database plugins do when adding a tag, except that : is replaced too, as it would otherwise be read as a metatag
+func CleanTagName(Name string) string {
+	Name = strings.Join(strings.Fields(strings.ToLower(Name)), "_")
+	return regexTagName.ReplaceAllString(Name, "_")
+}
+
+//splitTags converts a space separated list of tags into Tags of one category
+func splitTags(TagString string, Category string) []Tag {
+	var ToReturn []Tag
+	for _, name := range strings.Fields(TagString) {
+		ToReturn = append(ToReturn, Tag{Name: name, Category: Category})
+	}
+	return ToReturn
+}
+
+//findFile returns the first of Names that exists in Directory, or an empty string if none do
+func findFile(Directory string, Names ...string) string {
+	for _, name := range Names {
+		if name == "" || name != filepath.Base(name) {
+			continue //Only look directly in Directory
+		}
+		filePath := filepath.Join(Directory, name)
+		if fileInfo, err := os.Stat(filePath); err == nil && fileInfo.IsDir() == false {
+			return filePath
+		}
+	}
+	return ""
+}
+
+//fileNameFromURL returns the last part of a file URL, such as abcdef.jpg from https://example.com/images/ab/cd/abcdef.jpg
+func fileNameFromURL(FileURL string) string {
+	FileURL, _, _ = strings.Cut(FileURL, "?")
+	if FileURL == "" {
+		return ""
+	}
+	return FileURL[strings.LastIndex(FileURL, "/")+1:]
+}
+
+//parseScore reads a score, which some exports give as a decimal
+func parseScore(Score string) (int64, error) {
+	value, err := strconv.ParseFloat(strings.TrimSpace(Score), 64)
+	return int64(value), err
+}
@@ -0,0 +1,98 @@
+package importers
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+//checkExport compares an export against the expected one, field by field so failures are readable
+func checkExport(t *testing.T, Got Export, Expected Export) {
+	t.Helper()
+	if len(Got.Posts) != len(Expected.Posts) {
+		t.Fatalf("got %d posts, expected %d: %+v", len(Got.Posts), len(Expected.Posts), Got.Posts)
+	}
+	for index := range Expected.Posts {
+		if got, expected := fmt.Sprintf("%+v", Got.Posts[index]), fmt.Sprintf("%+v", Expected.Posts[index]); got != expected {
+			t.Errorf("post %d:\ngot      %s\nexpected %s", index, got, expected)
+		}
+	}
+	if got, expected := fmt.Sprintf("%+v", Got.Pools), fmt.Sprintf("%+v", Expected.Pools); got != expected {
+		t.Errorf("pools:\ngot      %s\nexpected %s", got, expected)
+	}
+}
+
+func TestReadDanbooru(t *testing.T) {
+	directory := filepath.Join("testdata", "danbooru")
+	export, err := ReadDanbooru(filepath.Join(directory, "posts.json"), filepath.Join(directory, "pools.json"), directory)
+	if err != nil {
+		t.Fatalf("ReadDanbooru: %v", err)
+	}
+	checkExport(t, export, Export{
+		Posts: []Post{
+			{ID: "101", FilePath: filepath.Join(directory, "0cc175b9c0f1b6a831c399e269772661.png"), Rating: "sensitive", Source: "https://example.com/101", Score: 25, Tags: []Tag{
+				{"1girl", CategoryGeneral}, {"long_hair", CategoryGeneral}, {"artist_name", CategoryArtist}, {"hatsune_miku", CategoryCharacter}, {"vocaloid", CategoryCopyright}, {"highres", CategoryMeta},
+			}},
+			{ID: "102", FilePath: filepath.Join(directory, "102.png"), Rating: "explicit", Score: -15, Tags: []Tag{
+				{"long_hair", CategoryGeneral}, {"ok", CategoryGeneral}, {"solo", CategoryGeneral},
+			}},
+			{ID: "103", Rating: "general", Tags: []Tag{{"missing_file", CategoryGeneral}}},
+		},
+		Pools: []Pool{
+			{Name: "Miku Series", Description: "Posts in order", PostIDs: []string{"102", "101", "103"}},
+			{Name: "Pool 8"},
+		},
+	})
+}
+
+func TestReadGelbooru(t *testing.T) {
+	directory := filepath.Join("testdata", "gelbooru")
+	export, err := ReadGelbooru(filepath.Join(directory, "posts.xml"), filepath.Join(directory, "tags.xml"), directory)
+	if err != nil {
+		t.Fatalf("ReadGelbooru: %v", err)
+	}
+	checkExport(t, export, Export{Posts: []Post{
+		{ID: "5001", FilePath: filepath.Join(directory, "e1671797c52e15f763380b45e841ec32.jpg"), Rating: "questionable", Source: "https://example.com/5001", Score: 12, Tags: []Tag{
+			{"blue_sky", CategoryGeneral}, {"cloud", CategoryGeneral}, {"some_artist", CategoryArtist}, {"rock_'n_roll", CategoryGeneral},
+		}},
+	}})
+
+	//Newer exports use elements, and without the tag file every tag is general
+	export, err = ReadGelbooru(filepath.Join(directory, "posts-elements.xml"), "", directory)
+	if err != nil {
+		t.Fatalf("ReadGelbooru: %v", err)
+	}
+	checkExport(t, export, Export{Posts: []Post{
+		{ID: "5002", FilePath: filepath.Join(directory, "5002.png"), Rating: "general", Score: 3, Tags: []Tag{
+			{"cloud", CategoryGeneral}, {"some_artist", CategoryGeneral},
+		}},
+	}})
+}
+
+func TestReadHydrus(t *testing.T) {
+	directory := filepath.Join("testdata", "hydrus")
+	export, err := ReadHydrus(directory)
+	if err != nil {
+		t.Fatalf("ReadHydrus: %v", err)
+	}
+	checkExport(t, export, Export{Posts: []Post{
+		{ID: "art/cat.jpg", FilePath: filepath.Join(directory, "art", "cat.jpg"), Rating: "safe", Source: "https://example.com/cat", Tags: []Tag{
+			{"Some Artist", CategoryArtist}, {"Pets", CategoryCopyright}, {"blue sky", CategoryGeneral}, {"title_a cat", CategoryGeneral}, {"highres", CategoryMeta},
+		}},
+		{ID: "dog.png", FilePath: filepath.Join(directory, "dog.png")},
+	}})
+}
+
+func TestCleanTagName(t *testing.T) {
+	tests := map[string]string{
+		"Blue Sky":     "blue_sky",
+		"rock_'n_roll": "rock__n_roll",
+		"title:a cat":  "title_a_cat",
+		"  -_-  ":      "-_-",
+	}
+	for name, expected := range tests {
+		if got := CleanTagName(name); got != expected {
+			t.Errorf("CleanTagName(%q) = %q, expected %q", name, got, expected)
+		}
+	}
+}
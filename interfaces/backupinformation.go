@@ -0,0 +1,58 @@
+package interfaces
+
+import (
+	"time"
+)
+
+//UserBackup contains everything stored for a user that is needed to recreate them from a backup
+//PasswordHash and the security answers are hashes, they are left empty when a backup should not hold secrets
+type UserBackup struct {
+	ID               uint64
+	Name             string
+	EMail            string
+	CreationTime     time.Time
+	Disabled         bool
+	Permissions      uint64
+	SearchFilter     string
+	PasswordHash     string
+	SecQuestionOne   string
+	SecQuestionTwo   string
+	SecQuestionThree string
+	SecAnswerOne     string
+	SecAnswerTwo     string
+	SecAnswerThree   string
+}
+
+//ImageVote contains one user's vote on an image
+type ImageVote struct {
+	UserID       uint64
+	ImageID      uint64
+	Score        int64
+	CreationTime time.Time
+}
+
+//ImageTagLink contains a tag applied to an image, and who applied it when
+type ImageTagLink struct {
+	ImageID  uint64
+	TagID    uint64
+	LinkerID uint64
+	LinkTime time.Time
+}
+
+//CollectionMemberLink contains an image's place in a collection, and who added it when
+type CollectionMemberLink struct {
+	CollectionID uint64
+	ImageID      uint64
+	LinkerID     uint64
+	LinkTime     time.Time
+	OrderWeight  uint64
+}
+
+//AuditLogInformation contains a single entry of the audit log
+type AuditLogInformation struct {
+	ID      uint64
+	UserID  uint64
+	Type    string
+	Info    string
+	LogTime time.Time
+}
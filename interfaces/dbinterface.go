@@ -1,5 +1,9 @@
 package interfaces
 
+import (
+	"time"
+)
+
 //DBInterface is a generic interface to allow swappable databases
 type DBInterface interface {
 	////Account operations
@@ -47,6 +51,16 @@ type DBInterface interface {
 	SetImagedHash(ID uint64, hHash uint64, vHash uint64) error
 	//GetImagedHash changes a given image's dHash
 	GetImagedHash(ID uint64) (uint64, uint64, error)
+	//SetImageMetadata adds or replaces the metadata of the image Metadata.ImageID
+	SetImageMetadata(Metadata ImageMetadata) error
+	//GetImageMetadata returns the metadata of an image, sql.ErrNoRows if it has none
+	GetImageMetadata(ImageID uint64) (ImageMetadata, error)
+	//SetImageMediaInfo changes the width, height, file size, MIME type, duration, and bitrate of the image Image.ID
+	SetImageMediaInfo(Image ImageInformation) error
+	//SetImageColors replaces the BlurHash and dominant colours of an image
+	SetImageColors(ImageID uint64, BlurHash string, Colors []ImageColor) error
+	//GetImageColors returns the dominant colours of an image, from most to least common
+	GetImageColors(ImageID uint64) ([]ImageColor, error)
 	//GetUserFilter returns the raw string of the user's filter
 	GetUserFilter(UserID uint64) (string, error)
 	//SearchUsers performs a search for users (Returns a list of UserInfos, or error)
@@ -112,6 +126,8 @@ type DBInterface interface {
 	InitDatabase() error
 	//AddAuditLog adds a new audit log to the db
 	AddAuditLog(UserID uint64, Type string, Info string) error
+	//RemoveAuditLogs removes audit logs older than OlderThan, returns the count removed
+	RemoveAuditLogs(OlderThan time.Duration) (int64, error)
 
 	//Collections
 	//NewCollection adds a collection with the provided information, returns collection ID and/or error
@@ -142,4 +158,57 @@ type DBInterface interface {
 	GetCollectionTags(CollectionID uint64) ([]TagInformation, error)
 	//FixCollectionTags verifies and fixes collection tags, returns row count and error
 	FixCollectionTags(CollectionID uint64) (int64, error)
+
+	//Backup
+	//GetUserBackups returns everything stored for users other than the system user, ordered by ID (Returns a list of users, the count of all users, and or error)
+	GetUserBackups(PageStart uint64, PageStride uint64) ([]UserBackup, uint64, error)
+	//GetImageVotes returns every user's vote on an image
+	GetImageVotes(ImageID uint64) ([]ImageVote, error)
+	//GetAuditLogs returns audit log entries ordered by ID (Returns a list of entries, the count of all entries, and or error)
+	GetAuditLogs(PageStart uint64, PageStride uint64) ([]AuditLogInformation, uint64, error)
+	//GetImageTagLinks returns the tags applied to an image, with who applied them and when, ordered by TagID
+	GetImageTagLinks(ImageID uint64) ([]ImageTagLink, error)
+	//GetCollectionMemberLinks returns the images in a collection, with who added them and when, ordered by OrderWeight
+	GetCollectionMemberLinks(CollectionID uint64) ([]CollectionMemberLink, error)
+	//RestoreUser adds a user from a backup, keeping their ID, creation time, and hashes as they are
+	RestoreUser(User UserBackup) error
+	//RestoreImage adds an image from a backup, keeping its ID, uploader, upload time, name, description, rating, source, location, and media info
+	RestoreImage(Image ImageInformation) error
+	//RestoreTag adds a tag from a backup, keeping its ID, uploader, upload time, and alias as they are
+	RestoreTag(Tag TagInformation) error
+	//RestoreCollection adds a collection from a backup, keeping its ID, uploader, and upload time
+	RestoreCollection(Collection CollectionInformation) error
+	//RestoreAuditLog adds an audit log entry from a backup, keeping its time
+	RestoreAuditLog(Log AuditLogInformation) error
+	//RestoreImageTags applies tags from a backup, keeping their linker, time, and tag IDs even if they are now aliases
+	RestoreImageTags(Links []ImageTagLink) error
+	//RestoreCollectionMembers adds images to collections from a backup, keeping their linker, time, and OrderWeight
+	RestoreCollectionMembers(Links []CollectionMemberLink) error
+	//RestoreImageVote adds a vote from a backup, keeping its time. The image's score is not updated, call UpdateScoreOnImage after
+	RestoreImageVote(Vote ImageVote) error
+
+	//Jobs
+	//AddJob adds a job to the queue to be run as soon as a worker is free, returns the job ID and/or error
+	AddJob(Type string, Payload string, MaxAttempts uint64) (uint64, error)
+	//ClaimJob marks the oldest job that is ready as running and returns it, along with running jobs not updated within LeaseTime. Returns sql.ErrNoRows if none are ready
+	ClaimJob(LeaseTime time.Duration) (JobInformation, error)
+	//UpdateJobProgress records how far a running job is, and that it is still running
+	UpdateJobProgress(JobID uint64, Progress uint64, Total uint64) error
+	//FinishJob sets a job's status and error. A job set back to queued is not claimed again until RetryDelay has passed
+	FinishJob(JobID uint64, Status string, LastError string, RetryDelay time.Duration) error
+	//GetJob returns one job
+	GetJob(JobID uint64) (JobInformation, error)
+	//GetJobs returns jobs with a status, or all jobs when Status is "", newest first (Returns a list of jobs, the count of all matching jobs, and or error)
+	GetJobs(Status string, PageStart uint64, PageStride uint64) ([]JobInformation, uint64, error)
+	//GetLatestJob returns the newest job of a type, or sql.ErrNoRows if there has been none
+	GetLatestJob(Type string) (JobInformation, error)
+	//GetLatestJobWithPayload returns the newest job of a type with a payload, such as the job for one image, or sql.ErrNoRows if there has been none
+	GetLatestJobWithPayload(Type string, Payload string) (JobInformation, error)
+	//RemoveJobs removes jobs with a status that have not changed within OlderThan, returns the count removed
+	RemoveJobs(Status string, OlderThan time.Duration) (int64, error)
+
+	//Transactions
+	//RunInTransaction runs Work against a DBInterface bound to one transaction, committing if Work returns nil and rolling back otherwise
+	//Work must only use Transaction, other callers may be held off until it returns. Calling RunInTransaction on Transaction joins the same transaction
+	RunInTransaction(Work func(Transaction DBInterface) error) error
 }
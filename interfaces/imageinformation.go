@@ -1,6 +1,7 @@
 package interfaces
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -20,9 +21,34 @@ type ImageInformation struct {
 	UsersVotedScore int64
 	Source          string
 	SourceIsURL     bool
+	//Media info, zero until the image has been processed
+	Width    uint64
+	Height   uint64
+	FileSize uint64 //In bytes
+	MIMEType string
+	Duration float64 //In seconds, for video and audio
+	Bitrate  uint64  //In bits per second, for video and audio
+	//BlurHash is a blurred stand-in for the image while its thumbnail loads, empty until the image has been processed
+	BlurHash string
 	//Special for collections
 	OrderInCollection uint64                  //Should be used in overview of a single collection
 	MemberCollections []CollectionInformation //Should be used in view of single image (For navigation of collections it's a member of)
+	Metadata          *ImageMetadata          //Should be used in view of single image, nil if the image has none
+	Colors            []ImageColor            //Should be used in view of single image, the image's dominant colours from most to least common
+}
+
+//ImageColor is one of the dominant colours of an image
+type ImageColor struct {
+	Red   uint8
+	Green uint8
+	Blue  uint8
+	//Share is the fraction of the image, from 0 to 1, closest to this colour
+	Share float64
+}
+
+//Hex returns the colour as it is written in CSS, such as #4a90d9
+func (Color ImageColor) Hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", Color.Red, Color.Green, Color.Blue)
 }
 
 //ImagedHash conveniently contains the vertical and horizontal dHashes of an image
@@ -31,3 +57,33 @@ type ImagedHash struct {
 	ImagevHash          uint64
 	SimilarityThreshold uint64
 }
+
+//ImageColorSearch is what a color metatag looks for, images whose dominant colours within Tolerance of the colour cover at least MinimumShare of them
+type ImageColorSearch struct {
+	Red          uint8
+	Green        uint8
+	Blue         uint8
+	Tolerance    uint64 //Distance between colours, counting each channel from 0 to 255
+	MinimumShare float64
+}
+
+//ImageMetadata contains details read from an image's EXIF, or the tags of audio, when it was uploaded
+type ImageMetadata struct {
+	ImageID uint64
+	//CaptureTime is when the picture was taken, as the camera's clock showed it. Zero if not recorded
+	CaptureTime  time.Time
+	CameraMake   string
+	CameraModel  string
+	LensModel    string
+	ExposureTime string
+	FNumber      string
+	FocalLength  string
+	ISO          uint64
+	//Orientation is the EXIF orientation, from 1 to 8, or 0 if not recorded
+	Orientation uint64
+	//Read from the ID3 tags, Vorbis comments, or INFO list of audio
+	Title  string
+	Artist string
+	Album  string
+	Genre  string
+}
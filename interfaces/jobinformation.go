@@ -0,0 +1,38 @@
+package interfaces
+
+import (
+	"time"
+)
+
+//Job statuses
+const (
+	//JobQueued jobs are waiting for a worker, or for RunAfter if they are being retried
+	JobQueued = "queued"
+	//JobRunning jobs have been claimed by a worker
+	JobRunning = "running"
+	//JobDone jobs finished without error
+	JobDone = "done"
+	//JobFailed jobs ran out of attempts, LastError holds why
+	JobFailed = "failed"
+)
+
+//JobInformation contains one job from the background job queue
+type JobInformation struct {
+	ID uint64
+	//Type selects the handler that runs the job
+	Type string
+	//Payload is handed to the handler, its meaning depends on Type
+	Payload     string
+	Status      string
+	Attempts    uint64
+	MaxAttempts uint64
+	//Progress and Total are set by the handler of long running jobs, Total is 0 when unknown
+	Progress     uint64
+	Total        uint64
+	LastError    string
+	CreationTime time.Time
+	//UpdateTime is when the job last changed, running jobs that stop updating are assumed lost and run again
+	UpdateTime time.Time
+	//RunAfter is when a queued job may next be claimed
+	RunAfter time.Time
+}
@@ -47,6 +47,8 @@ const (
 	//APIWriteAccess grants a user access to the API for making changes. The user is still limited by their other permissions however.
 	//Read access is generally given to authenticated users.
 	APIWriteAccess UserPermission = 32768
+	//RunMaintenance Allows a user to view background jobs, and start maintenance jobs such as regenerating thumbnails
+	RunMaintenance UserPermission = 65536
 	//Add more permissions here as needed in future. Keep using powers of 2 for this to work.
 	//Max number will be 18446744073709551615, after 64 possible permission assignments.
 )
@@ -0,0 +1,35 @@
+package interfaces
+
+import (
+	"io"
+	"time"
+)
+
+//StorageInterface provides a generic pattern for where images and thumbnails are kept
+//Names are relative paths separated by /, such as "abc.png" or "thumbs/abc.png.png"
+//Functions that do not find the requested file return an error matching fs.ErrNotExist
+type StorageInterface interface {
+	//Init prepares the storage backend, creating any directories or buckets needed
+	Init() error
+	//Save stores Content under Name, replacing any file already there
+	Save(Name string, Content io.Reader) error
+	//Open returns the contents of a file, the caller must close it
+	Open(Name string) (io.ReadSeekCloser, error)
+	//Stat returns information about a file
+	Stat(Name string) (StorageFileInfo, error)
+	//Remove deletes a file
+	Remove(Name string) error
+	//Rename moves a file to a new name, replacing any file already there
+	Rename(OldName string, NewName string) error
+	//List returns the names, including Directory, of files directly inside Directory, use "" for the top level
+	List(Directory string) ([]string, error)
+	//ListDirectories returns the names, including Directory, of directories directly inside Directory, use "" for the top level
+	ListDirectories(Directory string) ([]string, error)
+}
+
+//StorageFileInfo contains information about a stored file
+type StorageFileInfo struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
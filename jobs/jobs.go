@@ -0,0 +1,289 @@
+package jobs
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//Job types
+const (
+	//ProcessImage generates the thumbnail, dHash, and media info of the image whose ID is the payload
+	ProcessImage = "process-image"
+	//TranscodeVideo transcodes the video whose ID is the payload to TranscodeFormat, for browsers that cannot play it as it is
+	TranscodeVideo = "transcode-video"
+	//TranscodeVideos queues TranscodeVideo for every video browsers cannot play, or only those not transcoded yet if the payload is MissingOnly
+	TranscodeVideos = "transcodes"
+	//GenerateThumbnails regenerates every thumbnail, or only missing ones if the payload is MissingOnly
+	GenerateThumbnails = "thumbnails"
+	//GeneratedHashes regenerates every dHash, blurhash, and set of dominant colours, or only missing ones if the payload is MissingOnly
+	GeneratedHashes = "dhashes"
+	//MediaInfo records the dimensions, file size, MIME type, duration, and bitrate of every image, or only images without them if the payload is MissingOnly
+	MediaInfo = "media-info"
+	//RenameImages renames every image to match the naming convention
+	RenameImages = "rename-images"
+	//FixCollectionTags validates and fixes the tags applied to every collection
+	FixCollectionTags = "fix-collection-tags"
+	//RemoveAuditLogs removes audit logs older than the configured AuditRetention
+	RemoveAuditLogs = "audit-cleanup"
+	//RemoveOrphanFiles quarantines images and thumbnails without an image in the database, or deletes them if the payload is DeleteOrphans
+	RemoveOrphanFiles = "orphan-files"
+	//RecalculateScores recalculates the score of every image from its votes
+	RecalculateScores = "scores"
+)
+
+//MissingOnly is the payload that limits GenerateThumbnails, GeneratedHashes, MediaInfo, and TranscodeVideos to what is missing
+const MissingOnly = "missingonly"
+
+//DeleteOrphans is the payload that makes RemoveOrphanFiles delete files rather than quarantine them
+const DeleteOrphans = "delete"
+
+//ProgressFunc records how far a job is. Total may be 0 if it is not known
+type ProgressFunc func(Done uint64, Total uint64)
+
+//Handler runs one job. Returned errors are retried until the job runs out of attempts
+type Handler func(Job interfaces.JobInformation, Progress ProgressFunc) error
+
+//RetryDelay is how long a job waits after its first failure, doubling with every attempt after
+var RetryDelay = time.Minute
+
+//LeaseTime is how long a running job may go without updating before it is assumed lost, such as by a restart, and run again
+var LeaseTime = 10 * time.Minute
+
+//PollInterval is how often idle workers check for jobs added by other processes, or waiting on a retry
+var PollInterval = 5 * time.Second
+
+//Retention is how long finished jobs are kept for the job list
+var Retention = 7 * 24 * time.Hour
+
+var handlers = make(map[string]Handler)
+var handlersLock sync.RWMutex
+
+//workers holds the state of the worker pool started by Start
+var workers struct {
+	sync.Mutex
+	started bool
+	wake    chan struct{}
+	//pending are the jobs added by this process that have not been attempted yet, for Wait
+	//Jobs are removed once attempted, or by Wait if another process attempts them
+	pending map[uint64]bool
+}
+
+//Register sets the handler for a job type, jobs of a type without a handler fail when claimed
+func Register(Type string, JobHandler Handler) {
+	handlersLock.Lock()
+	defer handlersLock.Unlock()
+	handlers[Type] = JobHandler
+}
+
+//HasHandler returns whether a job type has a handler, so requests to run unknown types can be refused
+func HasHandler(Type string) bool {
+	handlersLock.RLock()
+	defer handlersLock.RUnlock()
+	_, exists := handlers[Type]
+	return exists
+}
+
+//Enqueue adds a job to the queue, it is run by the first free worker of any process using this database
+func Enqueue(Type string, Payload string) (uint64, error) {
+	maxAttempts := config.Configuration.JobMaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = 1
+	}
+	JobID, err := database.DBInterface.AddJob(Type, Payload, maxAttempts)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "jobs/Enqueue", "0", logging.ResultFailure, []string{"Failed to queue job", Type, Payload, err.Error()})
+		return 0, err
+	}
+	workers.Lock()
+	if workers.pending == nil {
+		workers.pending = make(map[uint64]bool)
+	}
+	workers.pending[JobID] = true
+	workers.Unlock()
+	wakeWorker()
+	return JobID, nil
+}
+
+//Start starts Count workers and the scheduler, further calls do nothing
+func Start(Count uint64) {
+	workers.Lock()
+	defer workers.Unlock()
+	if workers.started {
+		return
+	}
+	if Count == 0 {
+		Count = 1
+	}
+	workers.started = true
+	workers.wake = make(chan struct{}, 1)
+	for worker := uint64(0); worker < Count; worker++ {
+		go work()
+	}
+	go cleanup()
+	go runSchedule()
+	logging.WriteLog(logging.LogLevelInfo, "jobs/Start", "0", logging.ResultInfo, []string{"Started", strconv.FormatUint(Count, 10), "job workers"})
+}
+
+//Wait blocks until every job this process queued has been attempted at least once, for command line tools that would otherwise exit first
+//Jobs waiting on a retry are left to the server's workers
+func Wait() {
+	for true {
+		workers.Lock()
+		var pending []uint64
+		for JobID := range workers.pending {
+			pending = append(pending, JobID)
+		}
+		workers.Unlock()
+		if len(pending) == 0 {
+			return
+		}
+		for _, JobID := range pending {
+			job, err := database.DBInterface.GetJob(JobID)
+			if err == nil && (job.Status == interfaces.JobRunning || (job.Status == interfaces.JobQueued && job.Attempts == 0)) {
+				continue
+			}
+			workers.Lock()
+			delete(workers.pending, JobID)
+			workers.Unlock()
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+//Run runs a job immediately in this goroutine without queueing it, logging its progress, for the command line
+func Run(Type string, Payload string) error {
+	handlersLock.RLock()
+	JobHandler, exists := handlers[Type]
+	handlersLock.RUnlock()
+	if exists == false {
+		return errors.New("no handler for job type " + Type)
+	}
+	return JobHandler(interfaces.JobInformation{Type: Type, Payload: Payload, Status: interfaces.JobRunning, Attempts: 1, MaxAttempts: 1}, func(Done uint64, Total uint64) {
+		logging.WriteLog(logging.LogLevelInfo, "jobs/Run", "0", logging.ResultInfo, []string{Type, "processed", strconv.FormatUint(Done, 10), "of", strconv.FormatUint(Total, 10)})
+	})
+}
+
+//wakeWorker tells an idle worker there may be a job, without blocking if none are idle
+func wakeWorker() {
+	workers.Lock()
+	wake := workers.wake
+	workers.Unlock()
+	if wake == nil {
+		return
+	}
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+//work claims and runs jobs until the process exits
+func work() {
+	for true {
+		job, err := database.DBInterface.ClaimJob(LeaseTime)
+		if err != nil {
+			if err != sql.ErrNoRows {
+				logging.WriteLog(logging.LogLevelError, "jobs/work", "0", logging.ResultFailure, []string{"Failed to claim job", err.Error()})
+			}
+			select {
+			case <-workers.wake:
+			case <-time.After(PollInterval):
+			}
+			continue
+		}
+		//There may be more, so pass the wake on to another idle worker
+		wakeWorker()
+		runJob(job)
+	}
+}
+
+//runJob runs a claimed job and records the outcome
+func runJob(Job interfaces.JobInformation) {
+	defer func() {
+		workers.Lock()
+		delete(workers.pending, Job.ID)
+		workers.Unlock()
+	}()
+	handlersLock.RLock()
+	JobHandler, exists := handlers[Job.Type]
+	handlersLock.RUnlock()
+	var err error
+	if exists == false {
+		err = errors.New("no handler for job type " + Job.Type)
+	} else {
+		err = runHandler(JobHandler, Job)
+	}
+	if err == nil {
+		database.DBInterface.FinishJob(Job.ID, interfaces.JobDone, "", 0)
+		return
+	}
+	if Job.Attempts < Job.MaxAttempts {
+		retryDelay := RetryDelay << (Job.Attempts - 1)
+		logging.WriteLog(logging.LogLevelWarning, "jobs/runJob", "0", logging.ResultFailure, []string{"Job failed, it will be retried in", retryDelay.String(), strconv.FormatUint(Job.ID, 10), Job.Type, Job.Payload, err.Error()})
+		database.DBInterface.FinishJob(Job.ID, interfaces.JobQueued, err.Error(), retryDelay)
+		return
+	}
+	logging.WriteLog(logging.LogLevelError, "jobs/runJob", "0", logging.ResultFailure, []string{"Job failed", strconv.FormatUint(Job.ID, 10), Job.Type, Job.Payload, err.Error()})
+	database.DBInterface.FinishJob(Job.ID, interfaces.JobFailed, err.Error(), 0)
+}
+
+//runHandler runs a job's handler, turning a panic into an error so one bad job does not take down the worker
+//The job's lease is renewed every half LeaseTime until the handler returns, so handlers that rarely report progress are not claimed again while still running
+func runHandler(JobHandler Handler, Job interfaces.JobInformation) (err error) {
+	var progressLock sync.Mutex
+	done, total := Job.Progress, Job.Total
+	stopRenewing := make(chan struct{})
+	renewingStopped := make(chan struct{})
+	go func() {
+		defer close(renewingStopped)
+		ticker := time.NewTicker(LeaseTime / 2)
+		defer ticker.Stop()
+		for true {
+			select {
+			case <-stopRenewing:
+				return
+			case <-ticker.C:
+				progressLock.Lock()
+				lastDone, lastTotal := done, total
+				progressLock.Unlock()
+				if err := database.DBInterface.UpdateJobProgress(Job.ID, lastDone, lastTotal); err != nil {
+					logging.WriteLog(logging.LogLevelWarning, "jobs/runHandler", "0", logging.ResultFailure, []string{"Failed to renew job lease", strconv.FormatUint(Job.ID, 10), Job.Type, err.Error()})
+				}
+			}
+		}
+	}()
+	defer func() {
+		//Stopped before the job is finished, so a late renewal cannot land after it
+		close(stopRenewing)
+		<-renewingStopped
+		if recovered := recover(); recovered != nil {
+			err = errors.New("job panicked")
+			logging.WriteLog(logging.LogLevelError, "jobs/runHandler", "0", logging.ResultFailure, []string{"Job panicked", strconv.FormatUint(Job.ID, 10), Job.Type})
+		}
+	}()
+	return JobHandler(Job, func(Done uint64, Total uint64) {
+		progressLock.Lock()
+		done, total = Done, Total
+		progressLock.Unlock()
+		database.DBInterface.UpdateJobProgress(Job.ID, Done, Total)
+	})
+}
+
+//cleanup removes finished jobs older than Retention once a day
+func cleanup() {
+	for true {
+		if removed, err := database.DBInterface.RemoveJobs(interfaces.JobDone, Retention); err != nil {
+			logging.WriteLog(logging.LogLevelError, "jobs/cleanup", "0", logging.ResultFailure, []string{"Failed to remove old jobs", err.Error()})
+		} else if removed > 0 {
+			logging.WriteLog(logging.LogLevelInfo, "jobs/cleanup", "0", logging.ResultInfo, []string{"Removed", strconv.FormatInt(removed, 10), "old jobs"})
+		}
+		time.Sleep(24 * time.Hour)
+	}
+}
@@ -0,0 +1,136 @@
+package jobs
+
+import (
+	"errors"
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"go-image-board/plugins"
+	"go-image-board/plugins/memoryplugin"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQueue(t *testing.T) {
+	quietLog := &plugins.STDLog{}
+	quietLog.Init(logging.LogLevelCritical, "", "")
+	logging.LogInterface = quietLog
+	database.DBInterface = &memoryplugin.MemoryPlugin{}
+	if err := database.DBInterface.InitDatabase(); err != nil {
+		t.Fatalf("InitDatabase: %v", err)
+	}
+	config.Configuration.JobMaxAttempts = 3
+	RetryDelay = time.Millisecond
+	PollInterval = 10 * time.Millisecond
+
+	var flakyCalls int32
+	Register("test-flaky", func(Job interfaces.JobInformation, Progress ProgressFunc) error {
+		Progress(1, 2)
+		if atomic.AddInt32(&flakyCalls, 1) < 2 {
+			return errors.New("not yet")
+		}
+		Progress(2, 2)
+		return nil
+	})
+	Register("test-broken", func(Job interfaces.JobInformation, Progress ProgressFunc) error {
+		return errors.New("broken " + Job.Payload)
+	})
+	Register("test-panics", func(Job interfaces.JobInformation, Progress ProgressFunc) error {
+		panic("oops")
+	})
+	Start(2)
+
+	flakyID, _ := Enqueue("test-flaky", "")
+	brokenID, _ := Enqueue("test-broken", "payload")
+	panicID, _ := Enqueue("test-panics", "")
+	unknownID, _ := Enqueue("test-unknown", "")
+
+	//Wait only covers the first attempt, so poll until retries have run out
+	Wait()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		queued, _, _ := database.DBInterface.GetJobs(interfaces.JobQueued, 0, 0)
+		running, _, _ := database.DBInterface.GetJobs(interfaces.JobRunning, 0, 0)
+		if len(queued) == 0 && len(running) == 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if job, _ := database.DBInterface.GetJob(flakyID); job.Status != interfaces.JobDone || job.Attempts != 2 || job.Progress != 2 || job.Total != 2 {
+		t.Errorf("flaky job should succeed on its second attempt: %+v", job)
+	}
+	if job, _ := database.DBInterface.GetJob(brokenID); job.Status != interfaces.JobFailed || job.Attempts != 3 || job.LastError != "broken payload" {
+		t.Errorf("broken job should fail after every attempt: %+v", job)
+	}
+	if job, _ := database.DBInterface.GetJob(panicID); job.Status != interfaces.JobFailed {
+		t.Errorf("panicking job should fail without stopping workers: %+v", job)
+	}
+	if job, _ := database.DBInterface.GetJob(unknownID); job.Status != interfaces.JobFailed {
+		t.Errorf("job without a handler should fail: %+v", job)
+	}
+
+	if err := Run("test-broken", "now"); err == nil || err.Error() != "broken now" {
+		t.Errorf("Run should return the handler's error, got %v", err)
+	}
+	if HasHandler("test-unknown") {
+		t.Errorf("HasHandler reports a type that was never registered")
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	database.DBInterface = &memoryplugin.MemoryPlugin{}
+	if err := database.DBInterface.InitDatabase(); err != nil {
+		t.Fatalf("InitDatabase: %v", err)
+	}
+	Schedule("test-scheduled", "payload", time.Hour)
+	Schedule("test-disabled", "", -1)
+
+	checkSchedule()
+	first, err := database.DBInterface.GetLatestJob("test-scheduled")
+	if err != nil || first.Payload != "payload" {
+		t.Fatalf("scheduled job was not queued: %+v, %v", first, err)
+	}
+	if _, err := database.DBInterface.GetLatestJob("test-disabled"); err == nil {
+		t.Errorf("disabled job was queued")
+	}
+
+	//Not queued again while unfinished, nor once finished until the interval has passed
+	checkSchedule()
+	database.DBInterface.FinishJob(first.ID, interfaces.JobDone, "", 0)
+	checkSchedule()
+	if latest, _ := database.DBInterface.GetLatestJob("test-scheduled"); latest.ID != first.ID {
+		t.Errorf("scheduled job was queued again before it was due: %+v", latest)
+	}
+}
+
+func TestLeaseRenewal(t *testing.T) {
+	database.DBInterface = &memoryplugin.MemoryPlugin{}
+	if err := database.DBInterface.InitDatabase(); err != nil {
+		t.Fatalf("InitDatabase: %v", err)
+	}
+	originalLease := LeaseTime
+	LeaseTime = 100 * time.Millisecond
+	PollInterval = 10 * time.Millisecond
+	defer func() { LeaseTime = originalLease }()
+
+	//Runs for several leases without reporting progress, as a long transcode might
+	var slowCalls int32
+	Register("test-slow", func(Job interfaces.JobInformation, Progress ProgressFunc) error {
+		atomic.AddInt32(&slowCalls, 1)
+		time.Sleep(6 * LeaseTime)
+		return nil
+	})
+	Start(2)
+
+	slowID, _ := Enqueue("test-slow", "")
+	Wait()
+	if calls := atomic.LoadInt32(&slowCalls); calls != 1 {
+		t.Errorf("slow job was claimed again while running, it ran %d times", calls)
+	}
+	if job, _ := database.DBInterface.GetJob(slowID); job.Status != interfaces.JobDone || job.Attempts != 1 {
+		t.Errorf("slow job should finish on its first attempt: %+v", job)
+	}
+}
@@ -0,0 +1,63 @@
+package jobs
+
+import (
+	"database/sql"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"sync"
+	"time"
+)
+
+//ScheduleCheckInterval is how often the scheduler checks whether a scheduled job is due
+var ScheduleCheckInterval = time.Minute
+
+//scheduledJob is a job the scheduler queues every Interval
+type scheduledJob struct {
+	Type     string
+	Payload  string
+	Interval time.Duration
+}
+
+var schedule struct {
+	sync.Mutex
+	jobs []scheduledJob
+}
+
+//Schedule queues a job every Interval once Start is called. Intervals are counted from when the last job of that type was queued, so restarting does not put jobs off
+//An Interval of 0 or less disables the job
+func Schedule(Type string, Payload string, Interval time.Duration) {
+	if Interval <= 0 {
+		logging.WriteLog(logging.LogLevelInfo, "jobs/Schedule", "0", logging.ResultInfo, []string{"Scheduled job is disabled", Type})
+		return
+	}
+	schedule.Lock()
+	defer schedule.Unlock()
+	schedule.jobs = append(schedule.jobs, scheduledJob{Type: Type, Payload: Payload, Interval: Interval})
+}
+
+//runSchedule queues scheduled jobs as they come due until the process exits
+func runSchedule() {
+	for true {
+		checkSchedule()
+		time.Sleep(ScheduleCheckInterval)
+	}
+}
+
+//checkSchedule queues each scheduled job whose last run was queued at least its Interval ago, and has finished
+func checkSchedule() {
+	schedule.Lock()
+	scheduledJobs := append([]scheduledJob(nil), schedule.jobs...)
+	schedule.Unlock()
+	for _, scheduled := range scheduledJobs {
+		latest, err := database.DBInterface.GetLatestJob(scheduled.Type)
+		if err != nil && err != sql.ErrNoRows {
+			logging.WriteLog(logging.LogLevelError, "jobs/checkSchedule", "0", logging.ResultFailure, []string{"Failed to get last run of scheduled job", scheduled.Type, err.Error()})
+			continue
+		}
+		if err == nil && (latest.Status == interfaces.JobQueued || latest.Status == interfaces.JobRunning || time.Since(latest.CreationTime) < scheduled.Interval) {
+			continue //Not due, or still going
+		}
+		Enqueue(scheduled.Type, scheduled.Payload)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/logging"
+	"go-image-board/storage"
+	"strconv"
+)
+
+//migrateImageLayout moves every image and thumbnail to where the configured StorageLayout expects it, and updates the database to match
+func migrateImageLayout() {
+	_, maxCount, err := database.DBInterface.SearchImages(nil, 0, config.Configuration.PageStride)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "layoutUtility/migrateImageLayout", "0", logging.ResultFailure, []string{"Failed to query for images", err.Error()})
+		return
+	}
+	logging.WriteLog(logging.LogLevelInfo, "layoutUtility/migrateImageLayout", "0", logging.ResultInfo, []string{"Images to process", strconv.FormatUint(maxCount, 10), "Layout", config.Configuration.StorageLayout})
+	movedImages := uint64(0)
+	//Loop through the images one page at a time
+	for count := uint64(0); count < maxCount; count += config.Configuration.PageStride {
+		logging.WriteLog(logging.LogLevelInfo, "layoutUtility/migrateImageLayout", "0", logging.ResultInfo, []string{"Processing at", strconv.FormatUint(count, 10)})
+		images, _, err := database.DBInterface.SearchImages(nil, count, config.Configuration.PageStride)
+		if err != nil {
+			logging.WriteLog(logging.LogLevelCritical, "layoutUtility/migrateImageLayout", "0", logging.ResultFailure, []string{"Failed to query for images", err.Error()})
+			return
+		}
+		//Loop through the images in this page
+		for _, imageInfo := range images {
+			newLocation := storage.ImageLocation(imageInfo.Location)
+			if newLocation == imageInfo.Location {
+				continue //Already in place
+			}
+			if err := moveImage(imageInfo, newLocation); err != nil {
+				return //On error cancel out to keep db and image in sync
+			}
+			movedImages++
+			logging.WriteLog(logging.LogLevelDebug, "layoutUtility/migrateImageLayout", "0", logging.ResultInfo, []string{"Moved", imageInfo.Location, newLocation})
+		}
+	}
+	logging.WriteLog(logging.LogLevelInfo, "layoutUtility/migrateImageLayout", "0", logging.ResultSuccess, []string{"Finished moving " + strconv.FormatUint(movedImages, 10) + " images."})
+}
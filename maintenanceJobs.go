@@ -0,0 +1,341 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/jobs"
+	"go-image-board/logging"
+	"go-image-board/routers"
+	"go-image-board/storage"
+	"io/fs"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//registerJobHandlers sets the handlers for every job type, so jobs can be run from the command line, queued by uploads, or started from /mod/jobs
+func registerJobHandlers() {
+	jobs.Register(jobs.ProcessImage, routers.ProcessImageJob)
+	jobs.Register(jobs.GenerateThumbnails, generateThumbnailsJob)
+	jobs.Register(jobs.GeneratedHashes, generatedHashesJob)
+	jobs.Register(jobs.MediaInfo, mediaInfoJob)
+	jobs.Register(jobs.TranscodeVideo, routers.TranscodeVideoJob)
+	jobs.Register(jobs.TranscodeVideos, transcodeVideosJob)
+	jobs.Register(jobs.RenameImages, func(Job interfaces.JobInformation, Progress jobs.ProgressFunc) error {
+		return renameAllImages(Progress)
+	})
+	jobs.Register(jobs.FixCollectionTags, fixCollectionTagsJob)
+	jobs.Register(jobs.RemoveAuditLogs, removeAuditLogsJob)
+	jobs.Register(jobs.RemoveOrphanFiles, removeOrphanFilesJob)
+	jobs.Register(jobs.RecalculateScores, recalculateScoresJob)
+}
+
+//scheduleMaintenance has the server run maintenance at the intervals set in the configuration
+func scheduleMaintenance() {
+	jobs.Schedule(jobs.RemoveAuditLogs, "", config.Configuration.AuditCleanupInterval)
+	jobs.Schedule(jobs.RemoveOrphanFiles, "", config.Configuration.OrphanScanInterval)
+	jobs.Schedule(jobs.RecalculateScores, "", config.Configuration.ScoreRecalculationInterval)
+	jobs.Schedule(jobs.FixCollectionTags, "", config.Configuration.CollectionTagRepairInterval)
+}
+
+//orphanGracePeriod is how new a file may be and still be left alone by a scheduled orphan scan, as uploads save files before adding them to the database
+const orphanGracePeriod = time.Hour
+
+//generateThumbnailsJob regenerates the thumbnail of every file in storage, or only missing ones if the payload is jobs.MissingOnly
+func generateThumbnailsJob(Job interfaces.JobInformation, Progress jobs.ProgressFunc) error {
+	missingOnly := Job.Payload == jobs.MissingOnly
+	//We need wait group so that we don't finish before goroutines
+	var wg sync.WaitGroup
+	//list files
+	files, err := storage.ListImages()
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "maintenanceJobs/generateThumbnailsJob", "0", logging.ResultFailure, []string{"failed to get files to generate new thumbnails", err.Error()})
+		return err
+	}
+	//for each image
+	generatedThumbnails := uint64(0)
+	for index, file := range files {
+		//Delete thumbnail
+		thumbnailName := storage.ThumbnailName(file)
+		if _, err := storage.StorageInterface.Stat(thumbnailName); missingOnly == false || (err != nil && errors.Is(err, fs.ErrNotExist)) {
+			storage.StorageInterface.Remove(thumbnailName)
+			if missingOnly == false {
+				//Sized thumbnails and previews are made again when next requested
+				storage.RemoveSizedThumbnails(file)
+			}
+			//Goroutine generate a new one
+			generatedThumbnails++
+			wg.Add(1)
+			go func(fileName string) {
+				defer wg.Done()
+				routers.GenerateThumbnail(fileName)
+			}(file)
+		}
+		if generatedThumbnails%config.Configuration.PageStride == 0 {
+			wg.Wait() //Throttle how fast we generate thumbnails
+		}
+		if uint64(index+1)%config.Configuration.PageStride == 0 {
+			Progress(uint64(index+1), uint64(len(files)))
+		}
+	}
+	wg.Wait() //This will wait for all goroutines to finish
+	Progress(uint64(len(files)), uint64(len(files)))
+	logging.WriteLog(logging.LogLevelInfo, "maintenanceJobs/generateThumbnailsJob", "0", logging.ResultSuccess, []string{"Finished generating " + strconv.FormatUint(generatedThumbnails, 10) + " new thumbnails."})
+	return nil
+}
+
+//generatedHashesJob regenerates the dHash, blurhash, and dominant colours of every image, or only of images missing either if the payload is jobs.MissingOnly
+func generatedHashesJob(Job interfaces.JobInformation, Progress jobs.ProgressFunc) error {
+	missingOnly := Job.Payload == jobs.MissingOnly
+	//We need wait group so that we don't finish before goroutines
+	var wg sync.WaitGroup
+	//for each image in the database
+	page := uint64(0)
+	processedImages := uint64(0)
+	for true {
+		images, maxCount, err := database.DBInterface.SearchImages([]interfaces.TagInformation{}, page, config.Configuration.PageStride)
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "maintenanceJobs/generatedHashesJob", "0", logging.ResultFailure, []string{"Error processing hashes.", err.Error()})
+			return err
+		}
+		if len(images) <= 0 {
+			break
+		}
+		for _, nextImage := range images {
+			var dhashExists error
+			if missingOnly {
+				_, _, dhashExists = database.DBInterface.GetImagedHash(nextImage.ID)
+			}
+			//Images hashed before colours were recorded have a dHash but no blurhash
+			if missingOnly == false || dhashExists != nil || nextImage.BlurHash == "" {
+				processedImages++
+				wg.Add(1)
+				go func(fileName string, imageID uint64) {
+					defer wg.Done()
+					routers.GeneratedHash(fileName, imageID)
+				}(nextImage.Location, nextImage.ID)
+			}
+		}
+		wg.Wait() //Throttle to a page at a time
+		page += uint64(len(images))
+		Progress(page, maxCount)
+	}
+	logging.WriteLog(logging.LogLevelInfo, "maintenanceJobs/generatedHashesJob", "0", logging.ResultSuccess, []string{"Finished generating " + strconv.FormatUint(processedImages, 10) + " new dHashes."})
+	return nil
+}
+
+//mediaInfoJob records the dimensions, file size, MIME type, duration, and bitrate of every image, or only images without them if the payload is jobs.MissingOnly
+func mediaInfoJob(Job interfaces.JobInformation, Progress jobs.ProgressFunc) error {
+	missingOnly := Job.Payload == jobs.MissingOnly
+	//We need wait group so that we don't finish before goroutines
+	var wg sync.WaitGroup
+	//for each image in the database
+	page := uint64(0)
+	processedImages := uint64(0)
+	for true {
+		images, maxCount, err := database.DBInterface.SearchImages([]interfaces.TagInformation{}, page, config.Configuration.PageStride)
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "maintenanceJobs/mediaInfoJob", "0", logging.ResultFailure, []string{"Error processing media info.", err.Error()})
+			return err
+		}
+		if len(images) <= 0 {
+			break
+		}
+		for _, nextImage := range images {
+			if missingOnly {
+				//Search results do not include media info, and every processed image has a MIME type
+				imageInfo, err := database.DBInterface.GetImage(nextImage.ID)
+				if err != nil || imageInfo.MIMEType != "" {
+					continue
+				}
+			}
+			processedImages++
+			wg.Add(1)
+			go func(fileName string, imageID uint64) {
+				defer wg.Done()
+				if err := routers.GenerateMediaInfo(fileName, imageID); err != nil {
+					logging.WriteLog(logging.LogLevelWarning, "maintenanceJobs/mediaInfoJob", "0", logging.ResultFailure, []string{"Failed to get media info", fileName, err.Error()})
+				}
+			}(nextImage.Location, nextImage.ID)
+		}
+		wg.Wait() //Throttle to a page at a time
+		page += uint64(len(images))
+		Progress(page, maxCount)
+	}
+	logging.WriteLog(logging.LogLevelInfo, "maintenanceJobs/mediaInfoJob", "0", logging.ResultSuccess, []string{"Finished recording media info of " + strconv.FormatUint(processedImages, 10) + " images."})
+	return nil
+}
+
+//transcodeVideosJob queues a transcode of every video browsers cannot play, or only of those not transcoded yet if the payload is jobs.MissingOnly
+//Each video is transcoded by its own job, so their progress and failures show against the video
+func transcodeVideosJob(Job interfaces.JobInformation, Progress jobs.ProgressFunc) error {
+	missingOnly := Job.Payload == jobs.MissingOnly
+	page := uint64(0)
+	queuedVideos := uint64(0)
+	for true {
+		images, maxCount, err := database.DBInterface.SearchImages([]interfaces.TagInformation{}, page, config.Configuration.PageStride)
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "maintenanceJobs/transcodeVideosJob", "0", logging.ResultFailure, []string{"Error queueing transcodes.", err.Error()})
+			return err
+		}
+		if len(images) <= 0 {
+			break
+		}
+		for _, nextImage := range images {
+			if routers.CanTranscode(nextImage.Location) == false {
+				continue
+			}
+			if missingOnly {
+				if exists, _ := storage.Exists(storage.TranscodeName(nextImage.Location, config.Configuration.TranscodeFormat)); exists {
+					continue
+				}
+				//Do not queue a second transcode of a video that is already waiting for one
+				latest, err := database.DBInterface.GetLatestJobWithPayload(jobs.TranscodeVideo, strconv.FormatUint(nextImage.ID, 10))
+				if err == nil && (latest.Status == interfaces.JobQueued || latest.Status == interfaces.JobRunning) {
+					continue
+				}
+			}
+			if _, err := jobs.Enqueue(jobs.TranscodeVideo, strconv.FormatUint(nextImage.ID, 10)); err != nil {
+				return err
+			}
+			queuedVideos++
+		}
+		page += uint64(len(images))
+		Progress(page, maxCount)
+	}
+	logging.WriteLog(logging.LogLevelInfo, "maintenanceJobs/transcodeVideosJob", "0", logging.ResultSuccess, []string{"Queued transcoding of " + strconv.FormatUint(queuedVideos, 10) + " videos."})
+	return nil
+}
+
+//fixCollectionTagsJob validates and fixes the tags applied to every collection
+func fixCollectionTagsJob(Job interfaces.JobInformation, Progress jobs.ProgressFunc) error {
+	//Loop through all collections
+	page := uint64(0)
+	for true {
+		collections, maxCount, err := database.DBInterface.SearchCollections([]interfaces.TagInformation{}, page, config.Configuration.PageStride)
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "maintenanceJobs/fixCollectionTagsJob", "0", logging.ResultFailure, []string{"Error querying collections.", err.Error()})
+			return err
+		}
+		if len(collections) <= 0 {
+			break
+		}
+		for _, nextCollection := range collections {
+			//Fix missing tags
+			count, err := database.DBInterface.FixCollectionTags(nextCollection.ID)
+			if err != nil {
+				logging.WriteLog(logging.LogLevelError, "maintenanceJobs/fixCollectionTagsJob", "0", logging.ResultInfo, []string{"Failed to fix collection images", err.Error()})
+			} else if count > 0 {
+				logging.WriteLog(logging.LogLevelInfo, "maintenanceJobs/fixCollectionTagsJob", "0", logging.ResultInfo, []string{"Fixed colllection tags", nextCollection.Name, "rows", strconv.FormatInt(count, 10)})
+			}
+		}
+		page += uint64(len(collections))
+		Progress(page, maxCount)
+	}
+	logging.WriteLog(logging.LogLevelInfo, "maintenanceJobs/fixCollectionTagsJob", "0", logging.ResultInfo, []string{"Completed collection tag correction"})
+	return nil
+}
+
+//removeAuditLogsJob removes audit logs older than AuditRetention
+func removeAuditLogsJob(Job interfaces.JobInformation, Progress jobs.ProgressFunc) error {
+	removed, err := database.DBInterface.RemoveAuditLogs(config.Configuration.AuditRetention)
+	if err != nil {
+		return err
+	}
+	logging.WriteLog(logging.LogLevelInfo, "maintenanceJobs/removeAuditLogsJob", "0", logging.ResultSuccess, []string{"Removed", strconv.FormatInt(removed, 10), "old audit logs"})
+	return nil
+}
+
+//removeOrphanFilesJob quarantines images and thumbnails that do not have an associated database entry, or deletes them if the payload is jobs.DeleteOrphans
+//Thumbnails for sizes that are no longer configured are always deleted
+//Quarantine is used when scheduled, so files new enough that their upload may not be finished are skipped
+func removeOrphanFilesJob(Job interfaces.JobInformation, Progress jobs.ProgressFunc) error {
+	deleteOrphans := Job.Payload == jobs.DeleteOrphans
+	//Scan image storage
+	files, err := storage.ListImages()
+	if err != nil {
+		logging.WriteLog(logging.LogLevelCritical, "maintenanceJobs/removeOrphanFilesJob", "0", logging.ResultFailure, []string{"Failed to get images from storage", err.Error()})
+		return err
+	}
+	thumbnails, err := storage.ListThumbnails()
+	if err != nil {
+		logging.WriteLog(logging.LogLevelCritical, "maintenanceJobs/removeOrphanFilesJob", "0", logging.ResultFailure, []string{"Failed to get thumbnails from storage", err.Error()})
+		return err
+	}
+	total := uint64(len(files) + len(thumbnails))
+	removedFiles := uint64(0)
+	for index, file := range append(files, thumbnails...) {
+		if uint64(index+1)%config.Configuration.PageStride == 0 {
+			Progress(uint64(index+1), total)
+		}
+		imageName := file
+		if index >= len(files) {
+			var ok bool
+			if imageName, ok = storage.ImageNameFromThumbnail(file); ok == false {
+				continue //Not a thumbnail, leave it alone
+			}
+			if storage.IsOutdatedThumbnail(file) {
+				//Left from a size or format that has since changed, they are not kept as they can be made again
+				if err := storage.StorageInterface.Remove(file); err != nil {
+					logging.WriteLog(logging.LogLevelError, "maintenanceJobs/removeOrphanFilesJob", "0", logging.ResultFailure, []string{"Failed to remove outdated thumbnail", file, err.Error()})
+					continue
+				}
+				removedFiles++
+				continue
+			}
+		}
+		//Search database for matching image entry
+		_, err := database.DBInterface.GetImageByFileName(imageName)
+		if err != nil && err != sql.ErrNoRows {
+			logging.WriteLog(logging.LogLevelError, "maintenanceJobs/removeOrphanFilesJob", "0", logging.ResultFailure, []string{"Failed to get image from database due to an unexpected db error, it will be skipped", file, err.Error()})
+			continue
+		} else if err == nil {
+			continue
+		}
+		if deleteOrphans {
+			logging.WriteLog(logging.LogLevelWarning, "maintenanceJobs/removeOrphanFilesJob", "0", logging.ResultInfo, []string{"Failed to get image from database, it will be deleted", file})
+			err = storage.StorageInterface.Remove(file)
+		} else {
+			fileStat, statErr := storage.StorageInterface.Stat(file)
+			if statErr != nil || time.Since(fileStat.ModTime) < orphanGracePeriod {
+				continue //Gone already, or may still be uploading
+			}
+			logging.WriteLog(logging.LogLevelWarning, "maintenanceJobs/removeOrphanFilesJob", "0", logging.ResultInfo, []string{"Failed to get image from database, it will be quarantined", file})
+			err = storage.StorageInterface.Rename(file, storage.QuarantineName(file))
+		}
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "maintenanceJobs/removeOrphanFilesJob", "0", logging.ResultFailure, []string{"Failed to remove file", file, err.Error()})
+			continue
+		}
+		removedFiles++
+	}
+	Progress(total, total)
+	logging.WriteLog(logging.LogLevelInfo, "maintenanceJobs/removeOrphanFilesJob", "0", logging.ResultSuccess, []string{"Removed", strconv.FormatUint(removedFiles, 10), "orphan files"})
+	return nil
+}
+
+//recalculateScoresJob recalculates the score of every image from its votes
+func recalculateScoresJob(Job interfaces.JobInformation, Progress jobs.ProgressFunc) error {
+	page := uint64(0)
+	for true {
+		images, maxCount, err := database.DBInterface.SearchImages([]interfaces.TagInformation{}, page, config.Configuration.PageStride)
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "maintenanceJobs/recalculateScoresJob", "0", logging.ResultFailure, []string{"Error querying images.", err.Error()})
+			return err
+		}
+		if len(images) <= 0 {
+			break
+		}
+		for _, nextImage := range images {
+			if err := database.DBInterface.UpdateScoreOnImage(nextImage.ID); err != nil {
+				logging.WriteLog(logging.LogLevelError, "maintenanceJobs/recalculateScoresJob", "0", logging.ResultFailure, []string{"Failed to update score", strconv.FormatUint(nextImage.ID, 10), err.Error()})
+			}
+		}
+		page += uint64(len(images))
+		Progress(page, maxCount)
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/jobs"
+	"go-image-board/routers"
+	"go-image-board/storage"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRemoveOrphanFilesJob(t *testing.T) {
+	setupImportTest(t)
+	importPath := t.TempDir()
+	writeTestFile(t, importPath, "kept.png", testPNG(t, 10))
+	importDirectory(importPath, "importer", filepath.Join(t.TempDir(), "progress.tsv"), false)
+
+	oldOrphan := hashName(t, testPNG(t, 20))
+	newOrphan := hashName(t, testPNG(t, 30))
+	writeTestFile(t, config.Configuration.ImageDirectory, oldOrphan, testPNG(t, 20))
+	writeTestFile(t, config.Configuration.ImageDirectory, newOrphan, testPNG(t, 30))
+	longAgo := time.Now().Add(-2 * orphanGracePeriod)
+	if err := os.Chtimes(filepath.Join(config.Configuration.ImageDirectory, oldOrphan), longAgo, longAgo); err != nil {
+		t.Fatal(err)
+	}
+	noProgress := func(Done uint64, Total uint64) {}
+
+	//Scheduled scans quarantine, and leave files that may still be uploading
+	if err := removeOrphanFilesJob(interfaces.JobInformation{}, noProgress); err != nil {
+		t.Fatalf("removeOrphanFilesJob: %v", err)
+	}
+	if exists, _ := storage.Exists(storage.QuarantineName(oldOrphan)); exists == false {
+		t.Errorf("old orphan was not quarantined")
+	}
+	if exists, _ := storage.Exists(newOrphan); exists == false {
+		t.Errorf("new orphan was moved while it may still be uploading")
+	}
+	if files, _ := storage.ListImages(); len(files) != 2 {
+		t.Errorf("files left after scan: %v", files)
+	}
+
+	//Thumbnails for a size that has changed are deleted, even when scheduled
+	config.Configuration.ThumbnailSizes = map[string]config.ThumbnailSize{"small": {Width: 2, Height: 2}}
+	config.Configuration.ThumbnailFormat = "webp-lossless"
+	defer func() {
+		config.Configuration.ThumbnailSizes = nil
+		config.Configuration.ThumbnailFormat = ""
+	}()
+	kept := hashName(t, testPNG(t, 10))
+	current := storage.SizedThumbnailName(kept, config.ThumbnailSize{Width: 2, Height: 2}, "webp-lossless")
+	outdated := storage.SizedThumbnailName(kept, config.ThumbnailSize{Width: 3, Height: 3}, "webp-lossless")
+	writeTestFile(t, config.Configuration.ImageDirectory, current, testPNG(t, 40))
+	writeTestFile(t, config.Configuration.ImageDirectory, outdated, testPNG(t, 40))
+	if err := removeOrphanFilesJob(interfaces.JobInformation{}, noProgress); err != nil {
+		t.Fatalf("removeOrphanFilesJob: %v", err)
+	}
+	if exists, _ := storage.Exists(current); exists == false {
+		t.Errorf("thumbnail for a configured size was removed")
+	}
+	if exists, _ := storage.Exists(outdated); exists {
+		t.Errorf("thumbnail for a size that is no longer configured was kept")
+	}
+
+	//-removeorphanfiles deletes regardless of age
+	if err := removeOrphanFilesJob(interfaces.JobInformation{Payload: jobs.DeleteOrphans}, noProgress); err != nil {
+		t.Fatalf("removeOrphanFilesJob: %v", err)
+	}
+	if files, _ := storage.ListImages(); len(files) != 1 {
+		t.Errorf("files left after deleting orphans: %v", files)
+	}
+}
+
+func TestGenerateSizedThumbnail(t *testing.T) {
+	setupImportTest(t)
+	importPath := t.TempDir()
+	writeTestFile(t, importPath, "image.png", testPNG(t, 10))
+	importDirectory(importPath, "importer", filepath.Join(t.TempDir(), "progress.tsv"), false)
+	name := hashName(t, testPNG(t, 10))
+	size := config.ThumbnailSize{Width: 3, Height: 2}
+	config.Configuration.ThumbnailSizes = map[string]config.ThumbnailSize{"tiny": size}
+	config.Configuration.ThumbnailQuality = 85
+	defer func() {
+		config.Configuration.ThumbnailSizes = nil
+		config.Configuration.ThumbnailFormat = ""
+	}()
+
+	for _, format := range storage.ThumbnailFormats {
+		config.Configuration.ThumbnailFormat = format
+		if err := routers.GenerateSizedThumbnail(name, size); err != nil {
+			t.Fatalf("GenerateSizedThumbnail as %s: %v", format, err)
+		}
+		file, err := storage.StorageInterface.Open(storage.SizedThumbnailName(name, size, format))
+		if err != nil {
+			t.Fatalf("opening %s thumbnail: %v", format, err)
+		}
+		thumbnail, decodedFormat, err := image.DecodeConfig(file)
+		file.Close()
+		//The 4x4 test image is shrunk to fit, keeping its shape
+		if err != nil || decodedFormat != storage.ThumbnailExtension(format) || thumbnail.Width != 2 || thumbnail.Height != 2 {
+			t.Errorf("%s thumbnail decoded as %s %dx%d, %v", format, decodedFormat, thumbnail.Width, thumbnail.Height, err)
+		}
+	}
+
+	//Regenerating every thumbnail removes sized ones, they are made again when requested
+	if err := generateThumbnailsJob(interfaces.JobInformation{}, func(Done uint64, Total uint64) {}); err != nil {
+		t.Fatalf("generateThumbnailsJob: %v", err)
+	}
+	if exists, _ := storage.Exists(storage.SizedThumbnailName(name, size, config.Configuration.ThumbnailFormat)); exists {
+		t.Errorf("sized thumbnail was kept when regenerating thumbnails")
+	}
+	if exists, _ := storage.Exists(storage.ThumbnailName(name)); exists == false {
+		t.Errorf("thumbnail was not regenerated")
+	}
+}
+
+func TestGeneratePreview(t *testing.T) {
+	setupImportTest(t)
+	animation := &gif.GIF{}
+	for _, shade := range []uint8{0, 100, 200} {
+		frame := image.NewPaletted(image.Rect(0, 0, 4, 4), color.Palette{color.Gray{shade}, color.White})
+		animation.Image = append(animation.Image, frame)
+		animation.Delay = append(animation.Delay, 10)
+	}
+	var animationData bytes.Buffer
+	if err := gif.EncodeAll(&animationData, animation); err != nil {
+		t.Fatal(err)
+	}
+	name, err := routers.GetNewImageName("animation.gif", bytes.NewReader(animationData.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, config.Configuration.ImageDirectory, name, animationData.Bytes())
+
+	if routers.CanGeneratePreview(name) == false || routers.CanGeneratePreview(hashName(t, testPNG(t, 10))) {
+		t.Fatalf("only animated images should have previews without FFMPEG")
+	}
+	if err := routers.GeneratePreview(name); err != nil {
+		t.Fatalf("GeneratePreview: %v", err)
+	}
+	file, err := storage.StorageInterface.Open(storage.PreviewName(name))
+	if err != nil {
+		t.Fatalf("opening preview: %v", err)
+	}
+	preview, err := io.ReadAll(file)
+	file.Close()
+	if err != nil || string(preview[0:4]) != "RIFF" || string(preview[8:12]) != "WEBP" || bytes.Count(preview, []byte("ANMF")) != 3 {
+		t.Errorf("preview is not an animated WebP of three frames: %q, %v", preview, err)
+	}
+
+	//Regenerating every thumbnail removes previews, they are made again when requested
+	if err := generateThumbnailsJob(interfaces.JobInformation{}, func(Done uint64, Total uint64) {}); err != nil {
+		t.Fatalf("generateThumbnailsJob: %v", err)
+	}
+	if exists, _ := storage.Exists(storage.PreviewName(name)); exists {
+		t.Errorf("preview was kept when regenerating thumbnails")
+	}
+}
+
+func TestTranscodeVideosJob(t *testing.T) {
+	setupImportTest(t)
+	config.Configuration.UseFFMPEG = true
+	config.Configuration.TranscodeFormat = "mp4"
+	defer func() {
+		config.Configuration.UseFFMPEG = false
+		config.Configuration.TranscodeFormat = ""
+	}()
+	var videos []uint64
+	for _, name := range []string{"clip.avi", "transcoded.mpg", "playable.mp4", "still.png"} {
+		imageID, err := database.DBInterface.NewImage(name, name, 1, "")
+		if err != nil {
+			t.Fatalf("NewImage: %v", err)
+		}
+		videos = append(videos, imageID)
+	}
+	writeTestFile(t, config.Configuration.ImageDirectory, storage.TranscodeName("transcoded.mpg", "mp4"), []byte("transcoded"))
+	if routers.CanTranscode("clip.avi") == false || routers.CanTranscode("playable.mp4") || routers.CanTranscode("still.png") {
+		t.Fatalf("only video browsers cannot play should be transcoded")
+	}
+
+	//Only videos browsers cannot play, and that have not been transcoded, get a job of their own
+	if err := transcodeVideosJob(interfaces.JobInformation{Payload: jobs.MissingOnly}, func(Done uint64, Total uint64) {}); err != nil {
+		t.Fatalf("transcodeVideosJob: %v", err)
+	}
+	queued, _, err := database.DBInterface.GetJobs("", 0, 100)
+	if err != nil {
+		t.Fatalf("GetJobs: %v", err)
+	}
+	var payloads []string
+	for _, job := range queued {
+		if job.Type == jobs.TranscodeVideo {
+			payloads = append(payloads, job.Payload)
+		}
+	}
+	if len(payloads) != 1 || payloads[0] != strconv.FormatUint(videos[0], 10) {
+		t.Errorf("transcodes queued for %v, expected only %d", payloads, videos[0])
+	}
+
+	//Transcodes go with the video
+	storage.RemoveThumbnails("transcoded.mpg")
+	if exists, _ := storage.Exists(storage.TranscodeName("transcoded.mpg", "mp4")); exists {
+		t.Errorf("transcode was kept when the thumbnails of its video were removed")
+	}
+}
+
+func TestWaveformThumbnail(t *testing.T) {
+	setupImportTest(t)
+	//One second of a rising tone at 8kHz, in 16 bit mono
+	samples := make([]byte, 16000)
+	for index := 0; index < 8000; index++ {
+		binary.LittleEndian.PutUint16(samples[index*2:], uint16(int16(math.Sin(float64(index)/10)*float64(index)*4)))
+	}
+	wav := []byte("RIFF\x00\x00\x00\x00WAVEfmt \x10\x00\x00\x00\x01\x00\x01\x00\x40\x1f\x00\x00\x80\x3e\x00\x00\x02\x00\x10\x00data")
+	wav = binary.LittleEndian.AppendUint32(wav, uint32(len(samples)))
+	wav = append(wav, samples...)
+	binary.LittleEndian.PutUint32(wav[4:], uint32(len(wav)-8))
+	name, err := routers.GetNewImageName("tone.wav", bytes.NewReader(wav))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, config.Configuration.ImageDirectory, name, wav)
+
+	//WAV files are read without FFMPEG
+	if routers.CanGenerateThumbnail(name) == false {
+		t.Fatalf("no thumbnail can be generated for WAV files")
+	}
+	if err := routers.GenerateThumbnail(name); err != nil {
+		t.Fatalf("GenerateThumbnail: %v", err)
+	}
+	file, err := storage.StorageInterface.Open(storage.ThumbnailName(name))
+	if err != nil {
+		t.Fatalf("opening thumbnail: %v", err)
+	}
+	thumbnail, _, err := image.DecodeConfig(file)
+	file.Close()
+	//Waveforms are as wide as thumbnails may be, and half as high
+	if err != nil || thumbnail.Width != 64 || thumbnail.Height != 32 {
+		t.Errorf("waveform thumbnail is %dx%d, %v", thumbnail.Width, thumbnail.Height, err)
+	}
+
+	imageID, err := database.DBInterface.NewImage("tone", name, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := routers.GenerateMediaInfo(name, imageID); err != nil {
+		t.Fatalf("GenerateMediaInfo: %v", err)
+	}
+	if info, err := database.DBInterface.GetImage(imageID); err != nil || info.Duration != 1 || info.Bitrate != 128000 || info.MIMEType != "audio/wav" {
+		t.Errorf("media info of a WAV file: %+v, %v", info, err)
+	}
+}
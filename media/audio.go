@@ -0,0 +1,389 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"go-image-board/interfaces"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+//id3Genres are the genres ID3v1 numbers, which ID3v2 tags may also use in place of a name
+var id3Genres = []string{
+	"Blues", "Classic Rock", "Country", "Dance", "Disco", "Funk", "Grunge", "Hip-Hop", "Jazz", "Metal",
+	"New Age", "Oldies", "Other", "Pop", "R&B", "Rap", "Reggae", "Rock", "Techno", "Industrial",
+	"Alternative", "Ska", "Death Metal", "Pranks", "Soundtrack", "Euro-Techno", "Ambient", "Trip-Hop", "Vocal", "Jazz+Funk",
+	"Fusion", "Trance", "Classical", "Instrumental", "Acid", "House", "Game", "Sound Clip", "Gospel", "Noise",
+	"Alternative Rock", "Bass", "Soul", "Punk", "Space", "Meditative", "Instrumental Pop", "Instrumental Rock", "Ethnic", "Gothic",
+	"Darkwave", "Techno-Industrial", "Electronic", "Pop-Folk", "Eurodance", "Dream", "Southern Rock", "Comedy", "Cult", "Gangsta",
+	"Top 40", "Christian Rap", "Pop/Funk", "Jungle", "Native American", "Cabaret", "New Wave", "Psychedelic", "Rave", "Showtunes",
+	"Trailer", "Lo-Fi", "Tribal", "Acid Punk", "Acid Jazz", "Polka", "Retro", "Musical", "Rock & Roll", "Hard Rock",
+}
+
+//readAudioTags fills in the title, artist, album, and genre of Metadata from the tags of an audio file, and returns whether any were found
+//MP3 files use ID3, Ogg files Vorbis comments, and WAV files their INFO list or an ID3 chunk. Files cut short are read as far as they go
+func readAudioTags(Data []byte, MediaType Type, Metadata *interfaces.ImageMetadata) bool {
+	switch MediaType.Name {
+	case "mp3":
+		readID3v2(Data, Metadata)
+		//ID3v1 is at the end of the file, and only fills in what ID3v2 did not have
+		if len(Data) >= 128 && string(Data[len(Data)-128:len(Data)-125]) == "TAG" {
+			readID3v1(Data[len(Data)-128:], Metadata)
+		}
+	case "ogg":
+		readVorbisComments(Data, Metadata)
+	case "wav":
+		riffChunks(Data, "WAVE", func(FourCC string, Data []byte) {
+			switch {
+			case FourCC == "LIST" && len(Data) >= 4 && string(Data[:4]) == "INFO":
+				readWAVInfo(Data[4:], Metadata)
+			case FourCC == "id3 " || FourCC == "ID3 ":
+				readID3v2(Data, Metadata)
+			}
+		})
+	}
+	return Metadata.Title != "" || Metadata.Artist != "" || Metadata.Album != "" || Metadata.Genre != ""
+}
+
+//AudioTagSuggestions returns the artist, album, and genre of Metadata written as tag names, for uploaders to pick from
+//Characters tag names cannot hold become underscores, the way the database would store them
+func AudioTagSuggestions(Metadata interfaces.ImageMetadata) []string {
+	var suggestions []string
+	for _, value := range []string{Metadata.Artist, Metadata.Album, Metadata.Genre} {
+		name := strings.Trim(strings.Map(func(Character rune) rune {
+			if (Character >= 'a' && Character <= 'z') || (Character >= '0' && Character <= '9') || Character == '-' {
+				return Character
+			}
+			return '_'
+		}, strings.ToLower(strings.Join(strings.Fields(value), "_"))), "_")
+		if name == "" {
+			continue
+		}
+		duplicate := false
+		for _, suggestion := range suggestions {
+			duplicate = duplicate || suggestion == name
+		}
+		if duplicate == false {
+			suggestions = append(suggestions, name)
+		}
+	}
+	return suggestions
+}
+
+//setAudioTag sets Field to Value if it is not already set, cleaned up and cut to fit the database
+func setAudioTag(Field *string, Value string) {
+	Value = strings.TrimSpace(strings.Trim(Value, "\x00"))
+	if *Field != "" || Value == "" {
+		return
+	}
+	*Field = truncateText(Value)
+}
+
+//truncateText cuts Value to maxMetadataText bytes without splitting a character
+func truncateText(Value string) string {
+	if len(Value) <= maxMetadataText {
+		return Value
+	}
+	Value = Value[:maxMetadataText]
+	for len(Value) > 0 && utf8.ValidString(Value) == false {
+		Value = Value[:len(Value)-1]
+	}
+	return Value
+}
+
+//ID3
+
+//readID3v2 reads the text frames of an ID3v2.2, 2.3, or 2.4 tag at the start of Data
+func readID3v2(Data []byte, Metadata *interfaces.ImageMetadata) {
+	if len(Data) < 10 || string(Data[:3]) != "ID3" || Data[3] < 2 || Data[3] > 4 {
+		return
+	}
+	version, flags := Data[3], Data[5]
+	size := synchsafe(Data[6:10])
+	body := Data[10:]
+	if size < len(body) {
+		body = body[:size]
+	}
+	//Unsynchronisation hides bytes that look like MPEG frame headers, version 4 marks it on each frame instead
+	if flags&0x80 != 0 && version < 4 {
+		body = removeUnsynchronisation(body)
+	}
+	if flags&0x40 != 0 && version > 2 && len(body) >= 4 {
+		//Version 3 does not count the size itself in the extended header's size, version 4 does
+		extendedSize := int(binary.BigEndian.Uint32(body[:4])) + 4
+		if version == 4 {
+			extendedSize = synchsafe(body[:4])
+		}
+		if extendedSize > len(body) {
+			return
+		}
+		body = body[extendedSize:]
+	}
+
+	idLength, headerLength := 4, 10
+	if version == 2 {
+		idLength, headerLength = 3, 6
+	}
+	for len(body) >= headerLength && body[0] != 0 {
+		id := string(body[:idLength])
+		var frameSize int
+		var frameFlags byte
+		switch version {
+		case 2:
+			frameSize = int(body[3])<<16 | int(body[4])<<8 | int(body[5])
+		case 3:
+			frameSize = int(binary.BigEndian.Uint32(body[4:8]))
+			//Compressed or encrypted
+			if body[9]&0xC0 != 0 {
+				frameFlags = 0xFF
+			}
+		default:
+			frameSize = synchsafe(body[4:8])
+			frameFlags = body[9]
+		}
+		if frameSize < 0 || headerLength+frameSize > len(body) {
+			frameSize = len(body) - headerLength //The file was cut short, so read what there is
+		}
+		frame := body[headerLength : headerLength+frameSize]
+		body = body[headerLength+frameSize:]
+		if frameFlags == 0xFF || frameFlags&0x0C != 0 {
+			continue //Compressed and encrypted frames are not read
+		}
+		if frameFlags&0x02 != 0 {
+			frame = removeUnsynchronisation(frame)
+		}
+		if frameFlags&0x01 != 0 {
+			if len(frame) < 4 {
+				continue
+			}
+			frame = frame[4:] //Data length indicator
+		}
+		switch id {
+		case "TIT2", "TT2":
+			setAudioTag(&Metadata.Title, id3Text(frame))
+		case "TPE1", "TP1":
+			setAudioTag(&Metadata.Artist, id3Text(frame))
+		case "TALB", "TAL":
+			setAudioTag(&Metadata.Album, id3Text(frame))
+		case "TCON", "TCO":
+			setAudioTag(&Metadata.Genre, id3Genre(id3Text(frame)))
+		}
+	}
+}
+
+//readID3v1 reads the fixed size tag ID3v1 keeps in the last 128 bytes of a file
+func readID3v1(Tag []byte, Metadata *interfaces.ImageMetadata) {
+	setAudioTag(&Metadata.Title, latin1(bytes.TrimRight(Tag[3:33], "\x00 ")))
+	setAudioTag(&Metadata.Artist, latin1(bytes.TrimRight(Tag[33:63], "\x00 ")))
+	setAudioTag(&Metadata.Album, latin1(bytes.TrimRight(Tag[63:93], "\x00 ")))
+	if int(Tag[127]) < len(id3Genres) {
+		setAudioTag(&Metadata.Genre, id3Genres[Tag[127]])
+	}
+}
+
+//synchsafe decodes the sizes ID3 writes with seven bits in each byte
+func synchsafe(Data []byte) int {
+	return int(Data[0]&0x7F)<<21 | int(Data[1]&0x7F)<<14 | int(Data[2]&0x7F)<<7 | int(Data[3]&0x7F)
+}
+
+//removeUnsynchronisation removes the zero ID3 writes after every 0xFF byte
+func removeUnsynchronisation(Data []byte) []byte {
+	return bytes.ReplaceAll(Data, []byte{0xFF, 0x00}, []byte{0xFF})
+}
+
+//id3Text returns the first string of a text frame, which starts with a byte giving its encoding
+func id3Text(Frame []byte) string {
+	if len(Frame) < 1 {
+		return ""
+	}
+	text := Frame[1:]
+	switch Frame[0] {
+	case 0:
+		if end := bytes.IndexByte(text, 0); end >= 0 {
+			text = text[:end]
+		}
+		return latin1(text)
+	case 1, 2:
+		bigEndian := Frame[0] == 2
+		if len(text) >= 2 && text[0] == 0xFF && text[1] == 0xFE {
+			text, bigEndian = text[2:], false
+		} else if len(text) >= 2 && text[0] == 0xFE && text[1] == 0xFF {
+			text, bigEndian = text[2:], true
+		}
+		var units []uint16
+		for index := 0; index+1 < len(text); index += 2 {
+			unit := binary.LittleEndian.Uint16(text[index:])
+			if bigEndian {
+				unit = binary.BigEndian.Uint16(text[index:])
+			}
+			if unit == 0 {
+				break
+			}
+			units = append(units, unit)
+		}
+		return string(utf16.Decode(units))
+	case 3:
+		if end := bytes.IndexByte(text, 0); end >= 0 {
+			text = text[:end]
+		}
+		return strings.ToValidUTF8(string(text), "")
+	}
+	return ""
+}
+
+//id3Genre turns genres given by number, such as 17 or (17), into their names, keeping any name that follows
+func id3Genre(Genre string) string {
+	number := Genre
+	if strings.HasPrefix(Genre, "(") {
+		end := strings.Index(Genre, ")")
+		if end < 0 {
+			return Genre
+		}
+		if refinement := strings.TrimSpace(Genre[end+1:]); refinement != "" {
+			return refinement
+		}
+		number = Genre[1:end]
+	}
+	if index, err := strconv.Atoi(number); err == nil {
+		if index >= 0 && index < len(id3Genres) {
+			return id3Genres[index]
+		}
+		return ""
+	}
+	return Genre
+}
+
+//latin1 converts ISO-8859-1 text, which older tags use, to UTF-8
+func latin1(Data []byte) string {
+	runes := make([]rune, len(Data))
+	for index, character := range Data {
+		runes[index] = rune(character)
+	}
+	return string(runes)
+}
+
+//Ogg
+
+//readVorbisComments reads the comment header of the first stream of an Ogg file, which holds its tags for both Vorbis and Opus
+func readVorbisComments(Data []byte, Metadata *interfaces.ImageMetadata) {
+	//Packets can span pages, so they are put back together from the segments of each page
+	var packet []byte
+	packets := 0
+	var serial uint32
+	for position := 0; position+27 <= len(Data) && string(Data[position:position+4]) == "OggS"; {
+		pageSerial := binary.LittleEndian.Uint32(Data[position+14:])
+		segmentCount := int(Data[position+26])
+		if position == 0 {
+			serial = pageSerial
+		}
+		segments := Data[position+27:]
+		if segmentCount > len(segments) {
+			return
+		}
+		segments, pageData := segments[:segmentCount], segments[segmentCount:]
+		for _, length := range segments {
+			if int(length) > len(pageData) {
+				length = byte(len(pageData)) //The file was cut short
+			}
+			if pageSerial == serial {
+				packet = append(packet, pageData[:length]...)
+			}
+			pageData = pageData[length:]
+			if length < 255 && pageSerial == serial {
+				packets++
+				//The comments are always the second packet
+				if packets == 2 {
+					parseVorbisComments(packet, Metadata)
+					return
+				}
+				packet = nil
+			}
+		}
+		position = len(Data) - len(pageData)
+	}
+	if packets == 1 {
+		parseVorbisComments(packet, Metadata) //Read what there is of a comment packet cut short
+	}
+}
+
+//parseVorbisComments reads the KEY=value comments of a Vorbis or Opus comment header
+func parseVorbisComments(Packet []byte, Metadata *interfaces.ImageMetadata) {
+	switch {
+	case bytes.HasPrefix(Packet, []byte("\x03vorbis")):
+		Packet = Packet[7:]
+	case bytes.HasPrefix(Packet, []byte("OpusTags")):
+		Packet = Packet[8:]
+	default:
+		return
+	}
+	if len(Packet) < 4 {
+		return
+	}
+	vendorLength := int(binary.LittleEndian.Uint32(Packet))
+	if vendorLength < 0 || 8+vendorLength > len(Packet) {
+		return
+	}
+	Packet = Packet[4+vendorLength:]
+	count := int(binary.LittleEndian.Uint32(Packet))
+	Packet = Packet[4:]
+	for index := 0; index < count && len(Packet) >= 4; index++ {
+		length := int(binary.LittleEndian.Uint32(Packet))
+		if length < 0 || 4+length > len(Packet) {
+			return
+		}
+		comment := strings.ToValidUTF8(string(Packet[4:4+length]), "")
+		Packet = Packet[4+length:]
+		key, value, found := strings.Cut(comment, "=")
+		if found == false {
+			continue
+		}
+		switch strings.ToUpper(key) {
+		case "TITLE":
+			setAudioTag(&Metadata.Title, value)
+		case "ARTIST":
+			setAudioTag(&Metadata.Artist, value)
+		case "ALBUM":
+			setAudioTag(&Metadata.Album, value)
+		case "GENRE":
+			setAudioTag(&Metadata.Genre, value)
+		}
+	}
+}
+
+//WAV
+
+//readWAVInfo reads the tags of the INFO list of a WAV file
+func readWAVInfo(List []byte, Metadata *interfaces.ImageMetadata) {
+	for len(List) >= 8 {
+		id := string(List[:4])
+		length := int(binary.LittleEndian.Uint32(List[4:8]))
+		if length < 0 || 8+length > len(List) {
+			return
+		}
+		value := List[8 : 8+length]
+		if end := bytes.IndexByte(value, 0); end >= 0 {
+			value = value[:end]
+		}
+		//INFO text has no set encoding, UTF-8 is most common now and older files are usually Latin-1
+		text := string(value)
+		if utf8.Valid(value) == false {
+			text = latin1(value)
+		}
+		switch id {
+		case "INAM":
+			setAudioTag(&Metadata.Title, text)
+		case "IART":
+			setAudioTag(&Metadata.Artist, text)
+		case "IPRD":
+			setAudioTag(&Metadata.Album, text)
+		case "IGNR":
+			setAudioTag(&Metadata.Genre, text)
+		}
+		if 8+length+length%2 > len(List) {
+			return
+		}
+		List = List[8+length+length%2:]
+	}
+}
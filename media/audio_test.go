@@ -0,0 +1,143 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"go-image-board/interfaces"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+//id3Frame returns an ID3v2.3 or 2.4 frame
+func id3Frame(Version byte, ID string, Data []byte) []byte {
+	frame := []byte(ID)
+	size := make([]byte, 4)
+	if Version == 4 {
+		size = []byte{byte(len(Data) >> 21 & 0x7F), byte(len(Data) >> 14 & 0x7F), byte(len(Data) >> 7 & 0x7F), byte(len(Data) & 0x7F)}
+	} else {
+		binary.BigEndian.PutUint32(size, uint32(len(Data)))
+	}
+	frame = append(frame, size...)
+	frame = append(frame, 0, 0)
+	return append(frame, Data...)
+}
+
+//id3Tag returns an ID3v2 tag holding Frames, followed by some padding
+func id3Tag(Version byte, Frames ...[]byte) []byte {
+	body := append(bytes.Join(Frames, nil), make([]byte, 20)...)
+	return append([]byte{'I', 'D', '3', Version, 0, 0, byte(len(body) >> 21 & 0x7F), byte(len(body) >> 14 & 0x7F), byte(len(body) >> 7 & 0x7F), byte(len(body) & 0x7F)}, body...)
+}
+
+//utf16Text returns a text frame's data in UTF-16 with a byte order mark
+func utf16Text(Text string) []byte {
+	data := []byte{1, 0xFF, 0xFE}
+	for _, unit := range utf16.Encode([]rune(Text)) {
+		data = append(data, byte(unit), byte(unit>>8))
+	}
+	return append(data, 0, 0)
+}
+
+//oggPage returns an Ogg page of the stream Serial holding Segments
+func oggPage(Serial uint32, Data []byte, Segments []byte) []byte {
+	page := []byte("OggS\x00\x00")
+	page = append(page, make([]byte, 8)...) //Granule position
+	page = binary.LittleEndian.AppendUint32(page, Serial)
+	page = append(page, make([]byte, 8)...) //Sequence number and checksum, which are not checked
+	page = append(page, byte(len(Segments)))
+	page = append(page, Segments...)
+	return append(page, Data...)
+}
+
+//vorbisComments returns a Vorbis comment header
+func vorbisComments(Comments ...string) []byte {
+	packet := []byte("\x03vorbis")
+	packet = binary.LittleEndian.AppendUint32(packet, 6)
+	packet = append(packet, "vendor"...)
+	packet = binary.LittleEndian.AppendUint32(packet, uint32(len(Comments)))
+	for _, comment := range Comments {
+		packet = binary.LittleEndian.AppendUint32(packet, uint32(len(comment)))
+		packet = append(packet, comment...)
+	}
+	return packet
+}
+
+func TestReadAudioTags(t *testing.T) {
+	mp3, _ := ByName("song.mp3")
+	ogg, _ := ByName("song.ogg")
+	wav, _ := ByName("song.wav")
+
+	id3v1 := make([]byte, 128)
+	copy(id3v1, "TAG")
+	copy(id3v1[3:], "Old Title")
+	copy(id3v1[33:], "Old Artist")
+	copy(id3v1[63:], "Old Album")
+	id3v1[127] = 17
+
+	//The comment packet is split over two pages, with a page of another stream between them
+	comments := vorbisComments("TITLE=Ogg Title", "artist=Ogg Artist", "DESCRIPTION="+strings.Repeat("long ", 40), "ALBUM=Ogg Album", "GENRE=Jazz")
+	identification := []byte("\x01vorbis identification")
+	oggFile := oggPage(7, append(identification, comments[:255]...), []byte{byte(len(identification)), 255})
+	oggFile = append(oggFile, oggPage(8, []byte("other"), []byte{5})...)
+	oggFile = append(oggFile, oggPage(7, comments[255:], []byte{byte(len(comments) - 255)})...)
+
+	info := []byte("INFO")
+	for _, field := range []struct{ ID, Value string }{{"INAM", "Wav Title"}, {"IART", "Caf\xe9"}, {"IGNR", "Ambient\x00"}} {
+		info = append(info, field.ID...)
+		info = binary.LittleEndian.AppendUint32(info, uint32(len(field.Value)))
+		info = append(info, field.Value...)
+		if len(field.Value)%2 == 1 {
+			info = append(info, 0)
+		}
+	}
+	wavFile := []byte("RIFF\x00\x00\x00\x00WAVEdata\x04\x00\x00\x00\x00\x00\x00\x00LIST")
+	wavFile = binary.LittleEndian.AppendUint32(wavFile, uint32(len(info)))
+	wavFile = append(wavFile, info...)
+	binary.LittleEndian.PutUint32(wavFile[4:], uint32(len(wavFile)-8))
+
+	tests := []struct {
+		What      string
+		Data      []byte
+		MediaType Type
+		Want      interfaces.ImageMetadata
+	}{
+		{"ID3v2.3", append(id3Tag(3,
+			id3Frame(3, "TIT2", utf16Text("Title ♪")),
+			id3Frame(3, "TPE1", []byte("\x00Artist\x00")),
+			id3Frame(3, "APIC", make([]byte, 300)),
+			id3Frame(3, "TALB", []byte("\x03Album")),
+			id3Frame(3, "TCON", []byte("\x00(17)")),
+		), 0xFF, 0xFB, 0x90, 0x00), mp3, interfaces.ImageMetadata{Title: "Title ♪", Artist: "Artist", Album: "Album", Genre: "Rock"}},
+		{"ID3v2.4 with ID3v1", append(append(id3Tag(4,
+			id3Frame(4, "TIT2", []byte("\x03Ünïcode\x00Second")),
+			id3Frame(4, "TCON", []byte("\x03(13)Synthpop")),
+		), make([]byte, 200)...), id3v1...), mp3, interfaces.ImageMetadata{Title: "Ünïcode", Artist: "Old Artist", Album: "Old Album", Genre: "Synthpop"}},
+		{"ID3v2.2", id3Tag(2, append([]byte("TT2\x00\x00\x06\x00Short"), []byte("TP1\x00\x00\x04\x00Two")...)), mp3, interfaces.ImageMetadata{Title: "Short", Artist: "Two"}},
+		{"cut short", id3Tag(3, id3Frame(3, "TIT2", []byte("\x00A long title")))[:24], mp3, interfaces.ImageMetadata{Title: "A l"}},
+		{"Vorbis", oggFile, ogg, interfaces.ImageMetadata{Title: "Ogg Title", Artist: "Ogg Artist", Album: "Ogg Album", Genre: "Jazz"}},
+		{"WAV", wavFile, wav, interfaces.ImageMetadata{Title: "Wav Title", Artist: "Café", Genre: "Ambient"}},
+	}
+	for _, test := range tests {
+		metadata, found := ReadMetadata(test.Data, test.MediaType)
+		if found == false || metadata != test.Want {
+			t.Errorf("%s: ReadMetadata = %+v, %v, want %+v", test.What, metadata, found, test.Want)
+		}
+	}
+
+	if metadata, found := ReadMetadata([]byte{0xFF, 0xFB, 0x90, 0x00}, mp3); found {
+		t.Errorf("ReadMetadata found tags in an MP3 without any: %+v", metadata)
+	}
+	if metadata, found := ReadMetadata(id3Tag(3, id3Frame(3, "TIT2", append([]byte{3}, bytes.Repeat([]byte("ab"), 200)...))), mp3); found == false || len(metadata.Title) != maxMetadataText {
+		t.Errorf("a long title was not cut to fit: %d bytes", len(metadata.Title))
+	}
+}
+
+func TestAudioTagSuggestions(t *testing.T) {
+	suggestions := AudioTagSuggestions(interfaces.ImageMetadata{Title: "Not Suggested", Artist: " The  Band ", Album: "Café: Live!", Genre: "the band"})
+	if strings.Join(suggestions, " ") != "the_band caf___live" {
+		t.Errorf("AudioTagSuggestions = %q", suggestions)
+	}
+	if suggestions := AudioTagSuggestions(interfaces.ImageMetadata{Artist: "ARTIST", Album: "♪", Genre: "artist"}); len(suggestions) != 1 || suggestions[0] != "artist" {
+		t.Errorf("AudioTagSuggestions kept an empty or repeated name: %q", suggestions)
+	}
+}
@@ -0,0 +1,115 @@
+package media
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"math"
+)
+
+//blurHashCharacters are the digits of the base 83 numbers a BlurHash is written in
+const blurHashCharacters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+//EncodeBlurHash returns the BlurHash of Image, keeping XComponents by YComponents waves of detail, from 1 to 9 each
+//See https://github.com/woltapp/blurhash for the format. Image should already be small, as every pixel is read for every component
+func EncodeBlurHash(Image image.Image, XComponents int, YComponents int) (string, error) {
+	if XComponents < 1 || XComponents > 9 || YComponents < 1 || YComponents > 9 {
+		return "", errors.New("BlurHash components must be from 1 to 9")
+	}
+	bounds := Image.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 1 || height < 1 {
+		return "", errors.New("cannot hash an empty image")
+	}
+
+	//Convert every pixel to linear light once, rather than once for each component
+	linear := make([][3]float64, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			pixel := color.NRGBAModel.Convert(Image.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			linear[y*width+x] = [3]float64{sRGBToLinear(pixel.R), sRGBToLinear(pixel.G), sRGBToLinear(pixel.B)}
+		}
+	}
+	factors := make([][3]float64, 0, XComponents*YComponents)
+	for j := 0; j < YComponents; j++ {
+		for i := 0; i < XComponents; i++ {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+			var factor [3]float64
+			for y := 0; y < height; y++ {
+				for x := 0; x < width; x++ {
+					basis := normalisation * math.Cos(math.Pi*float64(i*x)/float64(width)) * math.Cos(math.Pi*float64(j*y)/float64(height))
+					for channel := range factor {
+						factor[channel] += basis * linear[y*width+x][channel]
+					}
+				}
+			}
+			for channel := range factor {
+				factor[channel] /= float64(width * height)
+			}
+			factors = append(factors, factor)
+		}
+	}
+
+	hash := encodeBase83((XComponents-1)+(YComponents-1)*9, 1)
+	maximum := 1.0
+	if len(factors) > 1 {
+		//The detail is scaled to its largest value, which is written first
+		largest := 0.0
+		for _, factor := range factors[1:] {
+			for _, value := range factor {
+				largest = math.Max(largest, math.Abs(value))
+			}
+		}
+		quantised := int(math.Max(0, math.Min(82, math.Floor(largest*166-0.5))))
+		maximum = float64(quantised+1) / 166
+		hash += encodeBase83(quantised, 1)
+	} else {
+		hash += encodeBase83(0, 1)
+	}
+	dc := factors[0]
+	hash += encodeBase83(linearToSRGB(dc[0])<<16|linearToSRGB(dc[1])<<8|linearToSRGB(dc[2]), 4)
+	for _, factor := range factors[1:] {
+		quantised := [3]int{}
+		for channel, value := range factor {
+			quantised[channel] = int(math.Max(0, math.Min(18, math.Floor(signedPow(value/maximum, 0.5)*9+9.5))))
+		}
+		hash += encodeBase83(quantised[0]*19*19+quantised[1]*19+quantised[2], 2)
+	}
+	return hash, nil
+}
+
+//encodeBase83 writes Value as Length base 83 digits
+func encodeBase83(Value int, Length int) string {
+	digits := make([]byte, Length)
+	for index := Length - 1; index >= 0; index-- {
+		digits[index] = blurHashCharacters[Value%83]
+		Value /= 83
+	}
+	return string(digits)
+}
+
+//sRGBToLinear converts a colour channel from how it is stored to how bright it is
+func sRGBToLinear(Value uint8) float64 {
+	scaled := float64(Value) / 255
+	if scaled <= 0.04045 {
+		return scaled / 12.92
+	}
+	return math.Pow((scaled+0.055)/1.055, 2.4)
+}
+
+//linearToSRGB converts a brightness back to how a colour channel is stored
+func linearToSRGB(Value float64) int {
+	Value = math.Max(0, math.Min(1, Value))
+	if Value <= 0.0031308 {
+		return int(Value*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(Value, 1/2.4)-0.055)*255 + 0.5)
+}
+
+//signedPow raises the size of Value to Exponent, keeping its sign
+func signedPow(Value float64, Exponent float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(Value), Exponent), Value)
+}
@@ -0,0 +1,42 @@
+package media
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"testing"
+)
+
+func TestEncodeBlurHash(t *testing.T) {
+	solid := image.NewRGBA(image.Rect(0, 0, 8, 6))
+	draw.Draw(solid, solid.Bounds(), image.NewUniform(color.RGBA{255, 0, 0, 255}), image.Point{}, draw.Src)
+	//The size comes first, then the scale of the detail, the average colour, and two digits for each other component
+	hash, err := EncodeBlurHash(solid, 4, 3)
+	if err != nil || len(hash) != 28 || hash[0] != 'L' || hash[2:6] != "TI:j" {
+		t.Errorf("EncodeBlurHash of solid red = %q, %v", hash, err)
+	}
+	if hash, err := EncodeBlurHash(solid, 1, 1); err != nil || hash != "00TI:j" {
+		t.Errorf("EncodeBlurHash with one component = %q, %v", hash, err)
+	}
+
+	//White on the left and black on the right gives a grey average and a strong first horizontal component
+	halves := image.NewGray(image.Rect(0, 0, 8, 6))
+	draw.Draw(halves, image.Rect(0, 0, 4, 6), image.White, image.Point{}, draw.Src)
+	hash, err = EncodeBlurHash(halves, 2, 1)
+	if err != nil || len(hash) != 8 || hash[0] != '1' {
+		t.Fatalf("EncodeBlurHash of halves = %q, %v", hash, err)
+	}
+	//The component is positive in every channel, the top of the 19 levels
+	ac := strings.IndexByte(blurHashCharacters, hash[6])*83 + strings.IndexByte(blurHashCharacters, hash[7])
+	if ac/361 != ac/19%19 || ac/19%19 != ac%19 || ac%19 <= 9 {
+		t.Errorf("first component of halves decoded as %d, %d, %d", ac/361, ac/19%19, ac%19)
+	}
+
+	if _, err := EncodeBlurHash(solid, 0, 3); err == nil {
+		t.Errorf("EncodeBlurHash accepted no components")
+	}
+	if _, err := EncodeBlurHash(image.NewRGBA(image.Rect(0, 0, 0, 0)), 4, 3); err == nil {
+		t.Errorf("EncodeBlurHash accepted an empty image")
+	}
+}
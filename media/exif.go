@@ -0,0 +1,602 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"go-image-board/interfaces"
+	"hash/crc32"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+//ErrMalformed is returned by StripMetadata when a file's structure cannot be followed, so metadata cannot be found reliably
+var ErrMalformed = errors.New("file structure could not be read")
+
+//exifHeader starts the EXIF segment of JPEG files, and sometimes the EXIF chunk of WebP files
+const exifHeader = "Exif\x00\x00"
+
+//maxMetadataText is the longest text kept from metadata, matching the columns it is stored in
+const maxMetadataText = 255
+
+//EXIF tags read into ImageMetadata
+const (
+	tagMake             = 0x010F
+	tagModel            = 0x0110
+	tagOrientation      = 0x0112
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagGPSIFD           = 0x8825
+	tagExposureTime     = 0x829A
+	tagFNumber          = 0x829D
+	tagISO              = 0x8827
+	tagDateTimeOriginal = 0x9003
+	tagFocalLength      = 0x920A
+	tagLensModel        = 0xA434
+)
+
+//sensitiveTags identify people, places, or devices. They are blanked in TIFF files, which cannot have their metadata simply left out
+var sensitiveTags = map[uint16]bool{
+	0x010D: true, //DocumentName
+	0x013B: true, //Artist
+	0x013C: true, //HostComputer
+	0x02BC: true, //XMP
+	0x83BB: true, //IPTC
+	0x8298: true, //Copyright
+	0x927C: true, //MakerNote
+	0x9286: true, //UserComment
+	0x9C9B: true, //XPTitle
+	0x9C9C: true, //XPComment
+	0x9C9D: true, //XPAuthor
+	0x9C9E: true, //XPKeywords
+	0x9C9F: true, //XPSubject
+	0xA420: true, //ImageUniqueID
+	0xA430: true, //CameraOwnerName
+	0xA431: true, //BodySerialNumber
+	0xA435: true, //LensSerialNumber
+}
+
+//HasMetadata returns whether files of this type can hold EXIF metadata or audio tags that ReadMetadata understands
+func (MediaType Type) HasMetadata() bool {
+	switch MediaType.Name {
+	case "jpg", "png", "webp", "tiff", "mp3", "ogg", "wav":
+		return true
+	}
+	return false
+}
+
+//ReadMetadata returns the useful details from a file's EXIF, such as the camera and when the picture was taken, or the title and artist from the tags of audio, and whether any were found
+//ImageID is left for the caller to fill in
+func ReadMetadata(Data []byte, MediaType Type) (interfaces.ImageMetadata, bool) {
+	var exif []byte
+	switch MediaType.Name {
+	case "mp3", "ogg", "wav":
+		var metadata interfaces.ImageMetadata
+		found := readAudioTags(Data, MediaType, &metadata)
+		return metadata, found
+	case "jpg":
+		exif, _ = jpegEXIF(Data)
+	case "png":
+		exif, _ = pngEXIF(Data)
+	case "webp":
+		exif, _ = webpEXIF(Data)
+	case "tiff":
+		exif = Data
+	}
+	var metadata interfaces.ImageMetadata
+	reader, ifd0, ok := newTIFFReader(exif)
+	if ok == false {
+		return metadata, false
+	}
+	entries := reader.entries(ifd0)
+	metadata.CameraMake = reader.text(entries[tagMake])
+	metadata.CameraModel = reader.text(entries[tagModel])
+	metadata.Orientation = reader.integer(entries[tagOrientation])
+	if metadata.Orientation > 8 {
+		metadata.Orientation = 0
+	}
+	captureTime := reader.text(entries[tagDateTime])
+	if pointer, exists := entries[tagExifIFD]; exists {
+		exifEntries := reader.entries(uint32(reader.integer(pointer)))
+		if original := reader.text(exifEntries[tagDateTimeOriginal]); original != "" {
+			captureTime = original
+		}
+		metadata.LensModel = reader.text(exifEntries[tagLensModel])
+		metadata.ISO = reader.integer(exifEntries[tagISO])
+		if numerator, denominator, ok := reader.rational(exifEntries[tagExposureTime]); ok {
+			if numerator < denominator && numerator != 0 {
+				metadata.ExposureTime = "1/" + strconv.FormatFloat(math.Round(float64(denominator)/float64(numerator)), 'f', -1, 64) + " s"
+			} else {
+				metadata.ExposureTime = strconv.FormatFloat(float64(numerator)/float64(denominator), 'f', -1, 64) + " s"
+			}
+		}
+		if numerator, denominator, ok := reader.rational(exifEntries[tagFNumber]); ok {
+			metadata.FNumber = "f/" + strconv.FormatFloat(math.Round(float64(numerator)/float64(denominator)*10)/10, 'f', -1, 64)
+		}
+		if numerator, denominator, ok := reader.rational(exifEntries[tagFocalLength]); ok {
+			metadata.FocalLength = strconv.FormatFloat(math.Round(float64(numerator)/float64(denominator)*10)/10, 'f', -1, 64) + " mm"
+		}
+	}
+	//EXIF times have no zone, they are kept as the camera's clock showed them
+	if parsed, err := time.Parse("2006:01:02 15:04:05", captureTime); err == nil {
+		metadata.CaptureTime = parsed
+	}
+	found := metadata.CameraMake != "" || metadata.CameraModel != "" || metadata.LensModel != "" || metadata.Orientation != 0 ||
+		metadata.ISO != 0 || metadata.ExposureTime != "" || metadata.FNumber != "" || metadata.FocalLength != "" || metadata.CaptureTime.IsZero() == false
+	return metadata, found
+}
+
+//StripMetadata returns a file without the metadata that could identify where it was taken or by whom, such as GPS coordinates and serial numbers
+//JPEG, PNG, and WebP files lose all EXIF, XMP, and text metadata, except orientation so they are still shown the right way up
+//TIFF files keep their structure, with GPS and identifying tags blanked. Other types are returned unchanged
+func StripMetadata(Data []byte, MediaType Type) ([]byte, error) {
+	switch MediaType.Name {
+	case "jpg":
+		return stripJPEG(Data)
+	case "png":
+		return stripPNG(Data)
+	case "webp":
+		return stripWebP(Data)
+	case "tiff":
+		return stripTIFF(Data)
+	}
+	return Data, nil
+}
+
+//orientationOf returns the EXIF orientation of an EXIF block, or 0 if it has none
+func orientationOf(EXIF []byte) uint64 {
+	reader, ifd0, ok := newTIFFReader(EXIF)
+	if ok == false {
+		return 0
+	}
+	orientation := reader.integer(reader.entries(ifd0)[tagOrientation])
+	if orientation > 8 {
+		return 0
+	}
+	return orientation
+}
+
+//orientationEXIF returns an EXIF block holding only an orientation
+func orientationEXIF(Orientation uint64) []byte {
+	block := []byte("MM\x00\x2A\x00\x00\x00\x08")
+	block = binary.BigEndian.AppendUint16(block, 1)
+	block = binary.BigEndian.AppendUint16(block, tagOrientation)
+	block = binary.BigEndian.AppendUint16(block, 3)
+	block = binary.BigEndian.AppendUint32(block, 1)
+	block = binary.BigEndian.AppendUint16(block, uint16(Orientation))
+	block = append(block, 0, 0)
+	return binary.BigEndian.AppendUint32(block, 0)
+}
+
+//JPEG
+
+//jpegSegment is a marker segment before the image data of a JPEG file
+type jpegSegment struct {
+	Marker byte
+	//Data is the whole segment, including marker and length
+	Data []byte
+}
+
+//jpegSegments splits a JPEG file into the segments before the first scan, and the rest of the file from the first scan
+func jpegSegments(Data []byte) ([]jpegSegment, []byte, error) {
+	if len(Data) < 4 || Data[0] != 0xFF || Data[1] != 0xD8 {
+		return nil, nil, ErrMalformed
+	}
+	var segments []jpegSegment
+	position := 2
+	for position+4 <= len(Data) {
+		if Data[position] != 0xFF {
+			return nil, nil, ErrMalformed
+		}
+		marker := Data[position+1]
+		//Fill bytes may come before a marker
+		if marker == 0xFF {
+			position++
+			continue
+		}
+		if marker == 0xDA {
+			return segments, Data[position:], nil
+		}
+		length := int(binary.BigEndian.Uint16(Data[position+2:]))
+		if length < 2 || position+2+length > len(Data) {
+			return nil, nil, ErrMalformed
+		}
+		segments = append(segments, jpegSegment{Marker: marker, Data: Data[position : position+2+length]})
+		position += 2 + length
+	}
+	return nil, nil, ErrMalformed
+}
+
+//jpegEXIF returns the EXIF block of a JPEG file
+func jpegEXIF(Data []byte) ([]byte, bool) {
+	segments, _, err := jpegSegments(Data)
+	if err != nil {
+		return nil, false
+	}
+	for _, segment := range segments {
+		if segment.Marker == 0xE1 && bytes.HasPrefix(segment.Data[4:], []byte(exifHeader)) {
+			return segment.Data[4+len(exifHeader):], true
+		}
+	}
+	return nil, false
+}
+
+//jpegImageEnd returns where the image that starts with Scan ends, so anything appended after it, such as more pictures with their own metadata, is left out
+//Segments between scans, such as the tables of progressive images, are skipped by their length, as their contents could look like the end of the image
+func jpegImageEnd(Scan []byte) int {
+	position := 0
+	for position+4 <= len(Scan) {
+		//Scan is at a marker segment, skip it
+		length := int(binary.BigEndian.Uint16(Scan[position+2:]))
+		position += 2 + length
+		//Entropy coded data follows a scan header, where 0xFF is always followed by 0 or a restart marker
+		for position+1 < len(Scan) {
+			if Scan[position] == 0xFF && Scan[position+1] != 0 && (Scan[position+1] < 0xD0 || Scan[position+1] > 0xD7) && Scan[position+1] != 0xFF {
+				break
+			}
+			position++
+		}
+		if position+1 >= len(Scan) {
+			return len(Scan)
+		}
+		if Scan[position+1] == 0xD9 {
+			return position + 2
+		}
+	}
+	return len(Scan)
+}
+
+//stripJPEG keeps only the segments needed to show the image, which are everything other than application segments except JFIF, ICC profiles, and Adobe color information, and comments
+func stripJPEG(Data []byte) ([]byte, error) {
+	segments, scan, err := jpegSegments(Data)
+	if err != nil {
+		return nil, err
+	}
+	exif, _ := jpegEXIF(Data)
+	orientation := orientationOf(exif)
+	var output bytes.Buffer
+	output.Write([]byte{0xFF, 0xD8})
+	writeOrientation := func() {
+		if orientation > 1 {
+			block := append([]byte(exifHeader), orientationEXIF(orientation)...)
+			output.Write([]byte{0xFF, 0xE1})
+			output.Write(binary.BigEndian.AppendUint16(nil, uint16(len(block)+2)))
+			output.Write(block)
+			orientation = 0
+		}
+	}
+	for _, segment := range segments {
+		keep := true
+		switch {
+		case segment.Marker == 0xE0:
+			keep = bytes.HasPrefix(segment.Data[4:], []byte("JFIF\x00")) || bytes.HasPrefix(segment.Data[4:], []byte("JFXX\x00"))
+		case segment.Marker == 0xE2:
+			keep = bytes.HasPrefix(segment.Data[4:], []byte("ICC_PROFILE\x00"))
+		case segment.Marker == 0xEE:
+			keep = bytes.HasPrefix(segment.Data[4:], []byte("Adobe"))
+		case segment.Marker >= 0xE1 && segment.Marker <= 0xEF, segment.Marker == 0xFE:
+			keep = false
+		}
+		if keep == false {
+			continue
+		}
+		//Orientation goes after JFIF, which should be first
+		if segment.Marker != 0xE0 {
+			writeOrientation()
+		}
+		output.Write(segment.Data)
+	}
+	writeOrientation()
+	output.Write(scan[:jpegImageEnd(scan)])
+	return output.Bytes(), nil
+}
+
+//PNG
+
+//pngSignature starts every PNG file
+const pngSignature = "\x89PNG\r\n\x1A\n"
+
+//pngChunks calls Chunk with the type and data of each chunk of a PNG file
+func pngChunks(Data []byte, Chunk func(Type string, Data []byte)) error {
+	if bytes.HasPrefix(Data, []byte(pngSignature)) == false {
+		return ErrMalformed
+	}
+	position := len(pngSignature)
+	for position < len(Data) {
+		if position+12 > len(Data) {
+			return ErrMalformed
+		}
+		length := int(binary.BigEndian.Uint32(Data[position:]))
+		if length < 0 || position+12+length > len(Data) {
+			return ErrMalformed
+		}
+		chunkType := string(Data[position+4 : position+8])
+		Chunk(chunkType, Data[position+8:position+8+length])
+		position += 12 + length
+		if chunkType == "IEND" {
+			break
+		}
+	}
+	return nil
+}
+
+//pngEXIF returns the EXIF block of a PNG file
+func pngEXIF(Data []byte) ([]byte, bool) {
+	var exif []byte
+	pngChunks(Data, func(Type string, Data []byte) {
+		//Some programs write the JPEG EXIF header here too
+		if Type == "eXIf" && exif == nil {
+			exif = bytes.TrimPrefix(Data, []byte(exifHeader))
+		}
+	})
+	return exif, exif != nil
+}
+
+//stripPNG leaves out EXIF, text, and time chunks, and anything after the end of the image
+func stripPNG(Data []byte) ([]byte, error) {
+	var output bytes.Buffer
+	output.WriteString(pngSignature)
+	writeChunk := func(Type string, Data []byte) {
+		output.Write(binary.BigEndian.AppendUint32(nil, uint32(len(Data))))
+		checksum := crc32.NewIEEE()
+		checksum.Write([]byte(Type))
+		checksum.Write(Data)
+		output.WriteString(Type)
+		output.Write(Data)
+		output.Write(binary.BigEndian.AppendUint32(nil, checksum.Sum32()))
+	}
+	err := pngChunks(Data, func(Type string, Data []byte) {
+		switch Type {
+		case "eXIf":
+			if orientation := orientationOf(bytes.TrimPrefix(Data, []byte(exifHeader))); orientation > 1 {
+				writeChunk(Type, orientationEXIF(orientation))
+			}
+		case "tEXt", "zTXt", "iTXt", "tIME":
+		default:
+			writeChunk(Type, Data)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output.Bytes(), nil
+}
+
+//WebP
+
+//webpChunks calls Chunk with the FourCC and data of each chunk of a WebP file
+func webpChunks(Data []byte, Chunk func(FourCC string, Data []byte)) error {
+	return riffChunks(Data, "WEBP", Chunk)
+}
+
+//riffChunks calls Chunk with the FourCC and data of each top level chunk of a RIFF container holding Format
+func riffChunks(Data []byte, Format string, Chunk func(FourCC string, Data []byte)) error {
+	if len(Data) < 12 || string(Data[:4]) != "RIFF" || string(Data[8:12]) != Format {
+		return ErrMalformed
+	}
+	position := 12
+	end := 8 + int(binary.LittleEndian.Uint32(Data[4:]))
+	if end > len(Data) {
+		end = len(Data)
+	}
+	for position < end {
+		if position+8 > end {
+			return ErrMalformed
+		}
+		length := int(binary.LittleEndian.Uint32(Data[position+4:]))
+		if length < 0 || position+8+length > end {
+			return ErrMalformed
+		}
+		Chunk(string(Data[position:position+4]), Data[position+8:position+8+length])
+		position += 8 + length + length%2
+	}
+	return nil
+}
+
+//webpEXIF returns the EXIF block of a WebP file
+func webpEXIF(Data []byte) ([]byte, bool) {
+	var exif []byte
+	webpChunks(Data, func(FourCC string, Data []byte) {
+		if FourCC == "EXIF" && exif == nil {
+			exif = bytes.TrimPrefix(Data, []byte(exifHeader))
+		}
+	})
+	return exif, exif != nil
+}
+
+//stripWebP replaces the EXIF chunk with one holding only orientation, leaves out the XMP chunk, and updates the flags in the VP8X chunk that say they are there
+func stripWebP(Data []byte) ([]byte, error) {
+	exif, _ := webpEXIF(Data)
+	orientation := orientationOf(exif)
+	var chunks bytes.Buffer
+	err := webpChunks(Data, func(FourCC string, Data []byte) {
+		switch FourCC {
+		case "EXIF":
+			if orientation <= 1 {
+				return
+			}
+			Data = orientationEXIF(orientation)
+		case "XMP ":
+			return
+		case "VP8X":
+			if len(Data) > 0 {
+				//0x04 marks XMP and 0x08 EXIF
+				flags := Data[0] &^ 0x0C
+				if orientation > 1 {
+					flags |= 0x08
+				}
+				Data = append([]byte{flags}, Data[1:]...)
+			}
+		}
+		chunks.WriteString(FourCC)
+		chunks.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(Data))))
+		chunks.Write(Data)
+		if len(Data)%2 == 1 {
+			chunks.WriteByte(0)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	output := []byte("RIFF")
+	output = binary.LittleEndian.AppendUint32(output, uint32(4+chunks.Len()))
+	output = append(output, "WEBP"...)
+	return append(output, chunks.Bytes()...), nil
+}
+
+//TIFF
+
+//tiffEntry is an entry of a TIFF image file directory
+type tiffEntry struct {
+	Type  uint16
+	Count uint32
+	//Value is where the value is in the file, and Size how long it is
+	Value int
+	Size  int
+}
+
+//tiffReader reads the image file directories of a TIFF file or EXIF block
+type tiffReader struct {
+	Data  []byte
+	Order binary.ByteOrder
+}
+
+//tiffTypeSizes is the size of one value of each TIFF field type
+var tiffTypeSizes = map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8, 13: 4}
+
+//newTIFFReader checks the header of a TIFF file, returning a reader and the offset of the first directory
+func newTIFFReader(Data []byte) (*tiffReader, uint32, bool) {
+	if len(Data) < 8 {
+		return nil, 0, false
+	}
+	reader := &tiffReader{Data: Data}
+	switch string(Data[:4]) {
+	case "II*\x00":
+		reader.Order = binary.LittleEndian
+	case "MM\x00*":
+		reader.Order = binary.BigEndian
+	default:
+		return nil, 0, false
+	}
+	return reader, reader.Order.Uint32(Data[4:]), true
+}
+
+//entries returns the entries of the directory at Offset by tag, skipping any that point outside the file
+func (Reader *tiffReader) entries(Offset uint32) map[uint16]tiffEntry {
+	entries := make(map[uint16]tiffEntry)
+	if Offset < 8 || uint64(Offset)+2 > uint64(len(Reader.Data)) {
+		return entries
+	}
+	count := int(Reader.Order.Uint16(Reader.Data[Offset:]))
+	for index := 0; index < count; index++ {
+		position := int(Offset) + 2 + index*12
+		if position+12 > len(Reader.Data) {
+			break
+		}
+		entry := tiffEntry{Type: Reader.Order.Uint16(Reader.Data[position+2:]), Count: Reader.Order.Uint32(Reader.Data[position+4:])}
+		typeSize, known := tiffTypeSizes[entry.Type]
+		if known == false || uint64(entry.Count)*uint64(typeSize) > uint64(len(Reader.Data)) {
+			continue
+		}
+		entry.Size = int(entry.Count) * typeSize
+		entry.Value = position + 8
+		if entry.Size > 4 {
+			entry.Value = int(Reader.Order.Uint32(Reader.Data[position+8:]))
+		}
+		if entry.Value < 0 || entry.Value+entry.Size > len(Reader.Data) {
+			continue
+		}
+		entries[Reader.Order.Uint16(Reader.Data[position:])] = entry
+	}
+	return entries
+}
+
+//text returns an ASCII value, trimmed and made valid UTF-8
+func (Reader *tiffReader) text(Entry tiffEntry) string {
+	if Entry.Type != 2 || Entry.Size == 0 {
+		return ""
+	}
+	value := strings.TrimSpace(strings.TrimRight(string(Reader.Data[Entry.Value:Entry.Value+Entry.Size]), "\x00"))
+	if index := strings.IndexByte(value, 0); index >= 0 {
+		value = value[:index]
+	}
+	value = strings.ToValidUTF8(value, "")
+	for len(value) > maxMetadataText {
+		_, size := utf8.DecodeLastRuneInString(value)
+		value = value[:len(value)-size]
+	}
+	return value
+}
+
+//integer returns the first value of a BYTE, SHORT, or LONG entry, or 0
+func (Reader *tiffReader) integer(Entry tiffEntry) uint64 {
+	if Entry.Size == 0 {
+		return 0
+	}
+	switch Entry.Type {
+	case 1:
+		return uint64(Reader.Data[Entry.Value])
+	case 3:
+		return uint64(Reader.Order.Uint16(Reader.Data[Entry.Value:]))
+	case 4, 13:
+		return uint64(Reader.Order.Uint32(Reader.Data[Entry.Value:]))
+	}
+	return 0
+}
+
+//rational returns the first value of a RATIONAL entry, if it has one with a denominator
+func (Reader *tiffReader) rational(Entry tiffEntry) (uint32, uint32, bool) {
+	if Entry.Type != 5 || Entry.Size < 8 {
+		return 0, 0, false
+	}
+	numerator, denominator := Reader.Order.Uint32(Reader.Data[Entry.Value:]), Reader.Order.Uint32(Reader.Data[Entry.Value+4:])
+	return numerator, denominator, denominator != 0
+}
+
+//stripTIFF blanks the values of identifying tags and of every GPS tag in each image and its EXIF directory
+//TIFF files refer to everything by offset, so blanking in place is much safer than removing anything
+func stripTIFF(Data []byte) ([]byte, error) {
+	output := append([]byte(nil), Data...)
+	reader, offset, ok := newTIFFReader(output)
+	if ok == false {
+		return nil, ErrMalformed
+	}
+	blank := func(Entry tiffEntry) {
+		for index := Entry.Value; index < Entry.Value+Entry.Size; index++ {
+			output[index] = 0
+		}
+	}
+	blankDirectory := func(Offset uint32, All bool) {
+		for tag, entry := range reader.entries(Offset) {
+			if All || sensitiveTags[tag] {
+				blank(entry)
+			}
+		}
+	}
+	visited := make(map[uint32]bool)
+	for offset != 0 && visited[offset] == false && len(visited) < 1024 {
+		visited[offset] = true
+		entries := reader.entries(offset)
+		if pointer, exists := entries[tagGPSIFD]; exists {
+			blankDirectory(uint32(reader.integer(pointer)), true)
+		}
+		if pointer, exists := entries[tagExifIFD]; exists {
+			blankDirectory(uint32(reader.integer(pointer)), false)
+		}
+		blankDirectory(offset, false)
+		//The offset of the next directory follows the entries
+		count := uint64(0)
+		if uint64(offset)+2 <= uint64(len(output)) {
+			count = uint64(reader.Order.Uint16(output[offset:]))
+		}
+		next := uint64(offset) + 2 + count*12
+		if next+4 > uint64(len(output)) {
+			break
+		}
+		offset = reader.Order.Uint32(output[next:])
+	}
+	return output, nil
+}
@@ -0,0 +1,258 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+	"time"
+)
+
+//testEntry is a TIFF directory entry for testEXIF
+type testEntry struct {
+	Tag   uint16
+	Type  uint16
+	Count uint32
+	Value []byte
+}
+
+func testText(Tag uint16, Value string) testEntry {
+	return testEntry{Tag, 2, uint32(len(Value) + 1), append([]byte(Value), 0)}
+}
+
+func testShort(Order binary.AppendByteOrder, Tag uint16, Value uint16) testEntry {
+	return testEntry{Tag, 3, 1, Order.AppendUint16(nil, Value)}
+}
+
+func testRational(Order binary.AppendByteOrder, Tag uint16, Numerator uint32, Denominator uint32) testEntry {
+	return testEntry{Tag, 5, 1, Order.AppendUint32(Order.AppendUint32(nil, Numerator), Denominator)}
+}
+
+//testEXIF returns an EXIF block with a camera, lens, capture time, orientation 6, a serial number, and a location
+func testEXIF(Order binary.AppendByteOrder) []byte {
+	ifd0 := []testEntry{testText(tagMake, "Maker"), testText(tagModel, "Model 1"), testShort(Order, tagOrientation, 6), testText(0x013B, "Photographer Name")}
+	exifIFD := []testEntry{
+		testRational(Order, tagExposureTime, 1, 250),
+		testRational(Order, tagFNumber, 28, 10),
+		testShort(Order, tagISO, 400),
+		testText(tagDateTimeOriginal, "2021:06:05 14:30:15"),
+		testRational(Order, tagFocalLength, 50, 1),
+		testText(0xA431, "Serial 12345"),
+		testText(tagLensModel, "50mm f/1.8"),
+	}
+	gpsIFD := []testEntry{testText(1, "N"), {2, 5, 3, bytes.Repeat(Order.AppendUint32(Order.AppendUint32(nil, 51), 1), 3)}}
+
+	//Directories follow the header, with pointers to the EXIF and GPS directories in the first, and then the values too long to fit in an entry
+	directorySize := func(Entries []testEntry) uint32 { return uint32(2 + 12*len(Entries) + 4) }
+	exifOffset := 8 + directorySize(ifd0) + 12*2
+	gpsOffset := exifOffset + directorySize(exifIFD)
+	dataOffset := gpsOffset + directorySize(gpsIFD)
+	ifd0 = append(ifd0, testEntry{tagExifIFD, 4, 1, Order.AppendUint32(nil, exifOffset)}, testEntry{tagGPSIFD, 4, 1, Order.AppendUint32(nil, gpsOffset)})
+
+	var header string
+	if Order == binary.BigEndian {
+		header = "MM\x00*"
+	} else {
+		header = "II*\x00"
+	}
+	block := Order.AppendUint32([]byte(header), 8)
+	var values []byte
+	for _, directory := range [][]testEntry{ifd0, exifIFD, gpsIFD} {
+		block = Order.AppendUint16(block, uint16(len(directory)))
+		for _, entry := range directory {
+			block = Order.AppendUint16(block, entry.Tag)
+			block = Order.AppendUint16(block, entry.Type)
+			block = Order.AppendUint32(block, entry.Count)
+			if len(entry.Value) <= 4 {
+				block = append(block, entry.Value...)
+				block = append(block, make([]byte, 4-len(entry.Value))...)
+			} else {
+				block = Order.AppendUint32(block, dataOffset+uint32(len(values)))
+				values = append(values, entry.Value...)
+			}
+		}
+		block = Order.AppendUint32(block, 0)
+	}
+	return append(block, values...)
+}
+
+//checkMetadata checks the details from testEXIF were read
+func checkMetadata(t *testing.T, What string, Data []byte, MediaType Type) {
+	t.Helper()
+	metadata, found := ReadMetadata(Data, MediaType)
+	if found == false || metadata.CameraMake != "Maker" || metadata.CameraModel != "Model 1" || metadata.LensModel != "50mm f/1.8" || metadata.Orientation != 6 ||
+		metadata.ISO != 400 || metadata.ExposureTime != "1/250 s" || metadata.FNumber != "f/2.8" || metadata.FocalLength != "50 mm" ||
+		metadata.CaptureTime.Equal(time.Date(2021, 6, 5, 14, 30, 15, 0, time.UTC)) == false {
+		t.Errorf("ReadMetadata of %s = %+v, %v", What, metadata, found)
+	}
+}
+
+//checkStripped checks only the orientation is left of testEXIF, along with none of the other metadata
+func checkStripped(t *testing.T, What string, Data []byte, MediaType Type) {
+	t.Helper()
+	for _, removed := range []string{"Maker", "Photographer", "Serial", "2021:06:05", "xmpmeta", "comment"} {
+		if bytes.Contains(Data, []byte(removed)) {
+			t.Errorf("stripped %s still contains %q", What, removed)
+		}
+	}
+	if metadata, _ := ReadMetadata(Data, MediaType); metadata.Orientation != 6 || metadata.CameraMake != "" || metadata.CaptureTime.IsZero() == false {
+		t.Errorf("ReadMetadata of stripped %s = %+v, want only orientation 6", What, metadata)
+	}
+	again, err := StripMetadata(Data, MediaType)
+	if err != nil || bytes.Equal(again, Data) == false {
+		t.Errorf("stripping %s twice changed it: %v", What, err)
+	}
+}
+
+func testImage() image.Image {
+	picture := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	for index := range picture.Pix {
+		picture.Pix[index] = byte(index)
+	}
+	return picture
+}
+
+func TestStripJPEG(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, testImage(), nil); err != nil {
+		t.Fatal(err)
+	}
+	segment := func(Marker byte, Data string) []byte {
+		return append(binary.BigEndian.AppendUint16([]byte{0xFF, Marker}, uint16(len(Data)+2)), Data...)
+	}
+	original := []byte{0xFF, 0xD8}
+	original = append(original, segment(0xE0, "JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00")...)
+	original = append(original, segment(0xE1, exifHeader+string(testEXIF(binary.BigEndian)))...)
+	original = append(original, segment(0xE1, "http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta/>")...)
+	original = append(original, segment(0xFE, "a comment")...)
+	original = append(original, encoded.Bytes()[2:]...)
+	//Some cameras add a preview with its own metadata after the image
+	original = append(original, []byte("\xFF\xD8\xFF\xE1Maker")...)
+	checkMetadata(t, "JPEG", original, Type{Name: "jpg"})
+
+	stripped, err := StripMetadata(original, Type{Name: "jpg"})
+	if err != nil {
+		t.Fatalf("StripMetadata: %v", err)
+	}
+	checkStripped(t, "JPEG", stripped, Type{Name: "jpg"})
+	if bytes.Contains(stripped, []byte("JFIF")) == false {
+		t.Errorf("stripped JPEG lost its JFIF segment")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("stripped JPEG could not be decoded: %v", err)
+	}
+
+	if _, err := StripMetadata([]byte("\xFF\xD8\xFF\xE1\xFF\xFF"), Type{Name: "jpg"}); err != ErrMalformed {
+		t.Errorf("StripMetadata of a truncated JPEG: %v", err)
+	}
+}
+
+func TestStripPNG(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	chunk := func(Type string, Data []byte) []byte {
+		output := binary.BigEndian.AppendUint32(nil, uint32(len(Data)))
+		output = append(append(output, Type...), Data...)
+		return binary.BigEndian.AppendUint32(output, crc32.ChecksumIEEE(append([]byte(Type), Data...)))
+	}
+	//The signature and IHDR chunk come first
+	headerLength := len(pngSignature) + 12 + 13
+	original := append([]byte(nil), encoded.Bytes()[:headerLength]...)
+	original = append(original, chunk("eXIf", testEXIF(binary.LittleEndian))...)
+	original = append(original, chunk("tEXt", []byte("Comment\x00a comment"))...)
+	original = append(original, chunk("iTXt", []byte("XML:com.adobe.xmp\x00\x00\x00\x00\x00<x:xmpmeta/>"))...)
+	original = append(original, encoded.Bytes()[headerLength:]...)
+	checkMetadata(t, "PNG", original, Type{Name: "png"})
+
+	stripped, err := StripMetadata(original, Type{Name: "png"})
+	if err != nil {
+		t.Fatalf("StripMetadata: %v", err)
+	}
+	checkStripped(t, "PNG", stripped, Type{Name: "png"})
+	if _, err := png.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("stripped PNG could not be decoded: %v", err)
+	}
+}
+
+func TestStripWebP(t *testing.T) {
+	chunk := func(FourCC string, Data []byte) []byte {
+		output := append(binary.LittleEndian.AppendUint32([]byte(FourCC), uint32(len(Data))), Data...)
+		if len(Data)%2 == 1 {
+			output = append(output, 0)
+		}
+		return output
+	}
+	var chunks []byte
+	chunks = append(chunks, chunk("VP8X", []byte{0x0C, 0, 0, 0, 15, 0, 0, 7, 0, 0})...)
+	chunks = append(chunks, chunk("VP8L", []byte("image data"))...)
+	chunks = append(chunks, chunk("EXIF", append([]byte(exifHeader), testEXIF(binary.BigEndian)...))...)
+	chunks = append(chunks, chunk("XMP ", []byte("<x:xmpmeta/>"))...)
+	original := append(binary.LittleEndian.AppendUint32([]byte("RIFF"), uint32(4+len(chunks))), "WEBP"...)
+	original = append(original, chunks...)
+	checkMetadata(t, "WebP", original, Type{Name: "webp"})
+
+	stripped, err := StripMetadata(original, Type{Name: "webp"})
+	if err != nil {
+		t.Fatalf("StripMetadata: %v", err)
+	}
+	checkStripped(t, "WebP", stripped, Type{Name: "webp"})
+	if size := binary.LittleEndian.Uint32(stripped[4:]); int(size) != len(stripped)-8 {
+		t.Errorf("stripped WebP has size %d, want %d", size, len(stripped)-8)
+	}
+	if flags := stripped[20]; flags != 0x08 {
+		t.Errorf("stripped WebP has VP8X flags %#x, want only EXIF", flags)
+	}
+	if bytes.Contains(stripped, []byte("image data")) == false {
+		t.Errorf("stripped WebP lost its image")
+	}
+}
+
+func TestStripTIFF(t *testing.T) {
+	original := testEXIF(binary.LittleEndian)
+	if mediaType, found := Detect(original); found == false || mediaType.Name != "tiff" {
+		t.Fatalf("test TIFF not recognized: %q", mediaType.Name)
+	}
+	checkMetadata(t, "TIFF", original, Type{Name: "tiff"})
+
+	stripped, err := StripMetadata(original, Type{Name: "tiff"})
+	if err != nil {
+		t.Fatalf("StripMetadata: %v", err)
+	}
+	if len(stripped) != len(original) {
+		t.Errorf("stripped TIFF is %d bytes, want %d", len(stripped), len(original))
+	}
+	for _, removed := range []string{"Photographer", "Serial"} {
+		if bytes.Contains(stripped, []byte(removed)) {
+			t.Errorf("stripped TIFF still contains %q", removed)
+		}
+	}
+	//Unlike other types, TIFF files keep their camera details
+	checkMetadata(t, "stripped TIFF", stripped, Type{Name: "tiff"})
+	reader, ifd0, _ := newTIFFReader(stripped)
+	gpsEntries := reader.entries(uint32(reader.integer(reader.entries(ifd0)[tagGPSIFD])))
+	if numerator, _, _ := reader.rational(gpsEntries[2]); numerator != 0 || reader.text(gpsEntries[1]) != "" {
+		t.Errorf("stripped TIFF still has a location")
+	}
+}
+
+func TestReadMetadataWithout(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	if metadata, found := ReadMetadata(encoded.Bytes(), Type{Name: "png"}); found {
+		t.Errorf("ReadMetadata of a PNG without EXIF = %+v", metadata)
+	}
+	stripped, err := StripMetadata(encoded.Bytes(), Type{Name: "png"})
+	if err != nil || bytes.Equal(stripped, encoded.Bytes()) == false {
+		t.Errorf("StripMetadata changed a PNG without metadata: %v", err)
+	}
+	if _, found := ReadMetadata([]byte("MM\x00*\xFF\xFF\xFF\xFF"), Type{Name: "tiff"}); found {
+		t.Errorf("ReadMetadata found metadata in a directory outside the file")
+	}
+}
@@ -0,0 +1,105 @@
+package media
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//ffmpegDuration matches the length ffmpeg -i prints for a file, it prints N/A when it does not know
+var ffmpegDuration = regexp.MustCompile(`Duration: (\d+):(\d{2}):(\d{2}(?:\.\d+)?)`)
+
+//ffmpegBitrate matches the overall bitrate ffmpeg -i prints for a file after its duration
+var ffmpegBitrate = regexp.MustCompile(`Duration: .*, bitrate: (\d+) kb/s`)
+
+//ffmpegSize matches a stream's frame size, the separators keep codec tags such as 0x31637661 from matching
+var ffmpegSize = regexp.MustCompile(`(?:^|[ ,])(\d+)x(\d+)(?:[ ,]|$)`)
+
+//ffmpegRotation matches the side data of a stream that players turn before showing
+var ffmpegRotation = regexp.MustCompile(`rotation of (-?\d+(?:\.\d+)?) degrees`)
+
+//FFMPEGInfo holds what ParseFFMPEGInfo finds in what ffmpeg -i prints about a file
+type FFMPEGInfo struct {
+	//Width and Height come from the first video stream, and are zero for audio
+	Width  uint64
+	Height uint64
+	//Duration is in seconds
+	Duration float64
+	//Bitrate is in bits per second, over every stream
+	Bitrate uint64
+}
+
+//ParseFFMPEGInfo returns the dimensions, duration, and bitrate from what ffmpeg -i prints about a file
+func ParseFFMPEGInfo(Output string) FFMPEGInfo {
+	var info FFMPEGInfo
+	if match := ffmpegDuration.FindStringSubmatch(Output); match != nil {
+		hours, _ := strconv.ParseFloat(match[1], 64)
+		minutes, _ := strconv.ParseFloat(match[2], 64)
+		seconds, _ := strconv.ParseFloat(match[3], 64)
+		info.Duration = hours*3600 + minutes*60 + seconds
+	}
+	if match := ffmpegBitrate.FindStringSubmatch(Output); match != nil {
+		kilobits, _ := strconv.ParseUint(match[1], 10, 64)
+		info.Bitrate = kilobits * 1000
+	}
+	inVideo := false
+	for _, line := range strings.Split(Output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "Stream #") {
+			if inVideo {
+				break //Only the first video stream is used
+			}
+			if strings.Contains(line, ": Video: ") == false {
+				continue
+			}
+			match := ffmpegSize.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+			info.Width, _ = strconv.ParseUint(match[1], 10, 64)
+			info.Height, _ = strconv.ParseUint(match[2], 10, 64)
+			inVideo = true
+		} else if inVideo {
+			//Side data of the video stream is printed on the lines after it
+			if match := ffmpegRotation.FindStringSubmatch(line); match != nil {
+				rotation, _ := strconv.ParseFloat(match[1], 64)
+				if math.Mod(math.Abs(math.Round(rotation)), 180) == 90 {
+					info.Width, info.Height = info.Height, info.Width
+				}
+			}
+		}
+	}
+	return info
+}
+
+//TranscodeFormats are the formats video browsers cannot play may be transcoded to, MP4 holding H.264 or WebM holding VP9
+var TranscodeFormats = []string{"mp4", "webm"}
+
+//TranscodeArgs returns the arguments for ffmpeg to transcode the video at InputPath to Format at OutputPath, printing its progress to standard output
+func TranscodeArgs(Format string, InputPath string, OutputPath string) []string {
+	args := []string{"-nostdin", "-y", "-loglevel", "error", "-progress", "pipe:1", "-i", InputPath, "-map", "0:v:0", "-map", "0:a:0?", "-sn"}
+	//Most encoders need even dimensions for yuv420p, which players expect
+	args = append(args, "-vf", "scale=trunc(iw/2)*2:trunc(ih/2)*2", "-pix_fmt", "yuv420p")
+	if Format == "webm" {
+		args = append(args, "-c:v", "libvpx-vp9", "-crf", "32", "-b:v", "0", "-row-mt", "1", "-c:a", "libopus", "-b:a", "128k")
+	} else {
+		//faststart moves the index to the front, so playback can begin before the whole file is downloaded
+		args = append(args, "-c:v", "libx264", "-preset", "veryfast", "-crf", "23", "-c:a", "aac", "-b:a", "128k", "-movflags", "+faststart")
+	}
+	return append(args, "-f", Format, OutputPath)
+}
+
+//ParseFFMPEGProgress returns how many seconds of output have been written from one line of what ffmpeg -progress prints, and whether the line held them
+func ParseFFMPEGProgress(Line string) (float64, bool) {
+	key, value, found := strings.Cut(strings.TrimSpace(Line), "=")
+	//out_time_ms is in microseconds as well, older versions only print that
+	if found == false || (key != "out_time_us" && key != "out_time_ms") {
+		return 0, false
+	}
+	microseconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || microseconds < 0 {
+		return 0, false
+	}
+	return float64(microseconds) / 1000000, true
+}
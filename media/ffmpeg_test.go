@@ -0,0 +1,86 @@
+package media
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFFMPEGInfo(t *testing.T) {
+	tests := []struct {
+		What     string
+		Output   string
+		Width    uint64
+		Height   uint64
+		Duration float64
+		Bitrate  uint64
+	}{
+		{"video", `Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'video.mp4':
+  Metadata:
+    major_brand     : isom
+  Duration: 00:01:02.50, start: 0.000000, bitrate: 3041 kb/s
+  Stream #0:0[0x1](und): Video: h264 (High) (avc1 / 0x31637661), yuv420p(tv, bt709, progressive), 1920x1080 [SAR 1:1 DAR 16:9], 2905 kb/s, 30 fps, 30 tbr, 15360 tbn (default)
+  Stream #0:1[0x2](und): Audio: aac (LC) (mp4a / 0x6134706D), 48000 Hz, stereo, fltp, 128 kb/s (default)
+At least one output file must be specified
+`, 1920, 1080, 62.5, 3041000},
+		{"rotated video", `Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'video.mov':
+  Duration: 01:00:00.00, start: 0.000000, bitrate: 9000 kb/s
+  Stream #0:0(und): Video: hevc (Main) (hvc1 / 0x31637668), yuv420p(tv), 1280x720, 8000 kb/s, 29.97 fps (default)
+    Side data:
+      displaymatrix: rotation of -90.00 degrees
+  Stream #0:1(und): Audio: aac (LC) (mp4a / 0x6134706D), 44100 Hz, mono, fltp, 96 kb/s (default)
+    Side data:
+      displaymatrix: rotation of 180.00 degrees
+`, 720, 1280, 3600, 9000000},
+		{"cover art is not the first video", `Input #0, mp3, from 'song.mp3':
+  Duration: 00:03:20.04, start: 0.025057, bitrate: 320 kb/s
+  Stream #0:0: Audio: mp3 (mp3float), 44100 Hz, stereo, fltp, 320 kb/s
+  Stream #0:1: Video: png, rgba(pc), 600x600, 90k tbr, 90k tbn (attached pic)
+`, 600, 600, 200.04, 320000},
+		{"audio", `Input #0, wav, from 'sound.wav':
+  Duration: 00:00:04.25, bitrate: 1411 kb/s
+  Stream #0:0: Audio: pcm_s16le ([1][0][0][0] / 0x0001), 44100 Hz, 2 channels, s16, 1411 kb/s
+`, 0, 0, 4.25, 1411000},
+		{"unknown duration", `Input #0, mpeg, from 'stream.mpg':
+  Duration: N/A, start: 0.500000, bitrate: N/A
+  Stream #0:0[0x1e0]: Video: mpeg1video, yuv420p(tv), 352x240 [SAR 1:1 DAR 22:15], 104857 kb/s, 29.97 fps
+`, 352, 240, 0, 0},
+		{"not media", "video.mp4: Invalid data found when processing input\n", 0, 0, 0, 0},
+	}
+	for _, test := range tests {
+		info := ParseFFMPEGInfo(test.Output)
+		if info.Width != test.Width || info.Height != test.Height || info.Duration != test.Duration || info.Bitrate != test.Bitrate {
+			t.Errorf("%s: ParseFFMPEGInfo = %+v, want %dx%d %v %d", test.What, info, test.Width, test.Height, test.Duration, test.Bitrate)
+		}
+	}
+}
+
+func TestParseFFMPEGProgress(t *testing.T) {
+	tests := []struct {
+		Line    string
+		Seconds float64
+		Found   bool
+	}{
+		{"out_time_us=12500000", 12.5, true},
+		{"out_time_ms=3000000\n", 3, true},
+		{"out_time_us=N/A", 0, false},
+		{"out_time=00:00:12.500000", 0, false},
+		{"frame=300", 0, false},
+		{"progress=end", 0, false},
+	}
+	for _, test := range tests {
+		seconds, found := ParseFFMPEGProgress(test.Line)
+		if seconds != test.Seconds || found != test.Found {
+			t.Errorf("ParseFFMPEGProgress(%q) = %v, %v, want %v, %v", test.Line, seconds, found, test.Seconds, test.Found)
+		}
+	}
+}
+
+func TestTranscodeArgs(t *testing.T) {
+	for format, codec := range map[string]string{"mp4": "libx264", "webm": "libvpx-vp9"} {
+		args := TranscodeArgs(format, "in.avi", "out."+format)
+		joined := strings.Join(args, " ")
+		if strings.Contains(joined, "-i in.avi ") == false || strings.HasSuffix(joined, " -f "+format+" out."+format) == false || strings.Contains(joined, " -c:v "+codec+" ") == false {
+			t.Errorf("TranscodeArgs(%q) = %q", format, joined)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package media
+
+import (
+	"image"
+	"image/draw"
+	"image/gif"
+	"time"
+
+	"github.com/nfnt/resize"
+)
+
+//gifMinDelay is the shortest delay browsers honour, frames asking for less are shown for gifDefaultDelay instead
+const gifMinDelay = 20 * time.Millisecond
+const gifDefaultDelay = 100 * time.Millisecond
+
+//GIFFrames returns the frames of Animation as they appear on screen, scaled to fit MaxWidth by MaxHeight, and how long each is shown
+//Animations with more than MaxFrames frames are cut down to MaxFrames evenly spaced ones, each shown until the next
+func GIFFrames(Animation *gif.GIF, MaxFrames int, MaxWidth uint, MaxHeight uint) ([]image.Image, []time.Duration) {
+	if len(Animation.Image) == 0 || MaxFrames < 1 {
+		return nil, nil
+	}
+	bounds := image.Rect(0, 0, Animation.Config.Width, Animation.Config.Height)
+	if bounds.Empty() {
+		for _, frame := range Animation.Image {
+			bounds = bounds.Union(frame.Bounds())
+		}
+	}
+	count := len(Animation.Image)
+	if MaxFrames > count {
+		MaxFrames = count
+	}
+	frames := make([]image.Image, MaxFrames)
+	durations := make([]time.Duration, MaxFrames)
+	canvas := image.NewRGBA(bounds)
+	for index, frame := range Animation.Image {
+		disposal := byte(gif.DisposalNone)
+		if index < len(Animation.Disposal) {
+			disposal = Animation.Disposal[index]
+		}
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(bounds)
+			draw.Draw(previous, bounds, canvas, bounds.Min, draw.Src)
+		}
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+
+		//Every frame counts towards the time of the slot it falls in, but only the first is shown
+		slot := index * MaxFrames / count
+		if frames[slot] == nil {
+			//Thumbnail returns images that already fit as they are, so the canvas is copied before it is drawn on again
+			shown := image.NewRGBA(bounds)
+			draw.Draw(shown, bounds, canvas, bounds.Min, draw.Src)
+			frames[slot] = resize.Thumbnail(MaxWidth, MaxHeight, shown, resize.Lanczos3)
+		}
+		delay := gifDefaultDelay
+		if index < len(Animation.Delay) && time.Duration(Animation.Delay[index])*10*time.Millisecond >= gifMinDelay {
+			delay = time.Duration(Animation.Delay[index]) * 10 * time.Millisecond
+		}
+		durations[slot] += delay
+
+		switch disposal {
+		case gif.DisposalBackground:
+			//Browsers clear to transparent rather than the background colour
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return frames, durations
+}
@@ -0,0 +1,62 @@
+package media
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+	"time"
+)
+
+func TestGIFFrames(t *testing.T) {
+	palette := color.Palette{color.Transparent, color.NRGBA{255, 0, 0, 255}, color.NRGBA{0, 0, 255, 255}}
+	//A red square the size of the canvas, then a blue pixel drawn over it in each corner in turn. The first corner asks for no delay, which browsers stretch
+	animation := &gif.GIF{Config: image.Config{Width: 4, Height: 4, ColorModel: palette}}
+	background := image.NewPaletted(image.Rect(0, 0, 4, 4), palette)
+	for index := range background.Pix {
+		background.Pix[index] = 1
+	}
+	animation.Image = append(animation.Image, background)
+	animation.Delay = append(animation.Delay, 50)
+	animation.Disposal = append(animation.Disposal, gif.DisposalNone)
+	for index, corner := range []image.Point{{0, 0}, {3, 0}, {3, 3}, {0, 3}} {
+		dot := image.NewPaletted(image.Rectangle{corner, corner.Add(image.Point{1, 1})}, palette)
+		dot.Pix[0] = 2
+		animation.Image = append(animation.Image, dot)
+		animation.Delay = append(animation.Delay, index*5)
+		animation.Disposal = append(animation.Disposal, gif.DisposalPrevious)
+	}
+
+	blue := color.RGBA{0, 0, 255, 255}
+	red := color.RGBA{255, 0, 0, 255}
+	frames, durations := GIFFrames(animation, 10, 10, 10)
+	if len(frames) != 5 || len(durations) != 5 {
+		t.Fatalf("GIFFrames returned %d frames and %d durations, want 5", len(frames), len(durations))
+	}
+	wantDurations := []time.Duration{500, 100, 50, 100, 150}
+	for index, frame := range frames {
+		if durations[index] != wantDurations[index]*time.Millisecond {
+			t.Errorf("frame %d is shown for %v, want %v", index, durations[index], wantDurations[index]*time.Millisecond)
+		}
+		for y := 0; y < 4; y++ {
+			for x := 0; x < 4; x++ {
+				want := red
+				if index > 0 && image.Pt(x, y) == animation.Image[index].Bounds().Min {
+					want = blue
+				}
+				if got := frame.At(x, y); got != want {
+					t.Errorf("frame %d: pixel %d,%d is %v, want %v", index, x, y, got, want)
+				}
+			}
+		}
+	}
+
+	//Frames are merged into the ones before them, and scaled down
+	frames, durations = GIFFrames(animation, 2, 2, 2)
+	if len(frames) != 2 || frames[0].Bounds().Dx() != 2 || frames[0].Bounds().Dy() != 2 {
+		t.Fatalf("GIFFrames with a limit returned %d frames", len(frames))
+	}
+	if durations[0] != 650*time.Millisecond || durations[1] != 250*time.Millisecond {
+		t.Errorf("merged frames are shown for %v", durations)
+	}
+}
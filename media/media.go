@@ -0,0 +1,219 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"go-image-board/config"
+	"io"
+	"path"
+	"strings"
+)
+
+//KindImage is shown with an img element
+const KindImage = "image"
+
+//KindVideo is shown with a video element
+const KindVideo = "video"
+
+//KindAudio is shown with an audio element
+const KindAudio = "audio"
+
+//sniffLength is how much of a file is read to recognize it. SVG can start with a long XML prolog, so this is more than the magic bytes need
+const sniffLength = 1024
+
+//ErrUnrecognized is returned by Sniff when content does not match any known type
+var ErrUnrecognized = errors.New("file type not recognized")
+
+//Type is a format the board can store
+type Type struct {
+	//Name identifies the type in AllowedMediaTypes, it is the stored extension without the dot
+	Name string
+	//MIME is sent to browsers when embedding files of this type
+	MIME string
+	//Extension is what files of this type are stored with
+	Extension string
+	//Aliases are other extensions files of this type may have been stored with
+	Aliases []string
+	//Kind is one of KindImage, KindVideo, or KindAudio
+	Kind string
+	//Decodable types can be decoded by the image package, so thumbnails and dHashes can be made without FFMPEG
+	Decodable bool
+	//Animated types may move, so they are marked with a play icon like video
+	Animated bool
+	//Playable video can be played by browsers as it is, other video is transcoded for them when FFMPEG is used
+	Playable bool
+	//matches returns whether the start of a file is of this type
+	matches func(Header []byte) bool
+}
+
+//types holds every format that can be uploaded
+var types = []Type{
+	{Name: "jpg", MIME: "image/jpeg", Extension: ".jpg", Aliases: []string{".jpeg", ".jfif"}, Kind: KindImage, Decodable: true, matches: prefix("\xFF\xD8\xFF")},
+	{Name: "png", MIME: "image/png", Extension: ".png", Kind: KindImage, Decodable: true, matches: prefix("\x89PNG\r\n\x1A\n")},
+	{Name: "gif", MIME: "image/gif", Extension: ".gif", Kind: KindImage, Decodable: true, Animated: true, matches: prefix("GIF87a", "GIF89a")},
+	{Name: "bmp", MIME: "image/bmp", Extension: ".bmp", Kind: KindImage, Decodable: true, matches: isBMP},
+	{Name: "webp", MIME: "image/webp", Extension: ".webp", Kind: KindImage, Decodable: true, matches: riff("WEBP")},
+	{Name: "tiff", MIME: "image/tiff", Extension: ".tiff", Aliases: []string{".tif"}, Kind: KindImage, Decodable: true, matches: prefix("II*\x00", "MM\x00*")},
+	{Name: "svg", MIME: "image/svg+xml", Extension: ".svg", Kind: KindImage, matches: isSVG},
+	{Name: "mp4", MIME: "video/mp4", Extension: ".mp4", Kind: KindVideo, Playable: true, matches: isMP4},
+	{Name: "mov", MIME: "video/quicktime", Extension: ".mov", Kind: KindVideo, matches: isQuickTime},
+	{Name: "webm", MIME: "video/webm", Extension: ".webm", Kind: KindVideo, Playable: true, matches: isWebM},
+	{Name: "avi", MIME: "video/avi", Extension: ".avi", Kind: KindVideo, matches: riff("AVI ")},
+	{Name: "mpg", MIME: "video/mpeg", Extension: ".mpg", Aliases: []string{".mpeg"}, Kind: KindVideo, matches: prefix("\x00\x00\x01\xBA", "\x00\x00\x01\xB3")},
+	{Name: "mp3", MIME: "audio/mpeg", Extension: ".mp3", Kind: KindAudio, matches: isMP3},
+	{Name: "ogg", MIME: "audio/ogg", Extension: ".ogg", Kind: KindAudio, matches: prefix("OggS")},
+	{Name: "wav", MIME: "audio/wav", Extension: ".wav", Kind: KindAudio, matches: riff("WAVE")},
+}
+
+//Names returns the Name of every type, in the order they are checked
+func Names() []string {
+	var names []string
+	for _, mediaType := range types {
+		names = append(names, mediaType.Name)
+	}
+	return names
+}
+
+//Detect returns the type whose magic bytes Header starts with
+func Detect(Header []byte) (Type, bool) {
+	for _, mediaType := range types {
+		if mediaType.matches(Header) {
+			return mediaType, true
+		}
+	}
+	return Type{}, false
+}
+
+//Sniff reads the start of Stream to recognize it, then seeks back to the start. Returns ErrUnrecognized for unknown content
+func Sniff(Stream io.ReadSeeker) (Type, error) {
+	header := make([]byte, sniffLength)
+	read, err := io.ReadFull(Stream, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return Type{}, err
+	}
+	if _, err := Stream.Seek(0, io.SeekStart); err != nil {
+		return Type{}, err
+	}
+	mediaType, found := Detect(header[:read])
+	if found == false {
+		return Type{}, ErrUnrecognized
+	}
+	return mediaType, nil
+}
+
+//ByName returns the type of a stored file from its extension, which was set from its content when uploaded
+func ByName(Name string) (Type, bool) {
+	extension := strings.ToLower(path.Ext(Name))
+	for _, mediaType := range types {
+		if extension == mediaType.Extension {
+			return mediaType, true
+		}
+		for _, alias := range mediaType.Aliases {
+			if extension == alias {
+				return mediaType, true
+			}
+		}
+	}
+	return Type{}, false
+}
+
+//IsAllowed returns whether AllowedMediaTypes permits uploading this type
+func IsAllowed(MediaType Type) bool {
+	for _, name := range config.Configuration.AllowedMediaTypes {
+		if strings.EqualFold(strings.TrimPrefix(name, "."), MediaType.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+//WithExtension returns Name with its extension replaced by the type's
+func (MediaType Type) WithExtension(Name string) string {
+	return strings.TrimSuffix(Name, path.Ext(Name)) + MediaType.Extension
+}
+
+//Magic byte matchers
+
+//prefix matches content starting with any of Prefixes
+func prefix(Prefixes ...string) func(Header []byte) bool {
+	return func(Header []byte) bool {
+		for _, start := range Prefixes {
+			if bytes.HasPrefix(Header, []byte(start)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+//riff matches RIFF containers holding Format, such as WAVE or AVI
+func riff(Format string) func(Header []byte) bool {
+	return func(Header []byte) bool {
+		return len(Header) >= 12 && string(Header[:4]) == "RIFF" && string(Header[8:12]) == Format
+	}
+}
+
+//isBMP checks the info header has a known size as well, since BM alone is too likely to start a text file
+func isBMP(Header []byte) bool {
+	if len(Header) < 18 || string(Header[:2]) != "BM" {
+		return false
+	}
+	switch binary.LittleEndian.Uint32(Header[14:18]) {
+	case 12, 40, 52, 56, 64, 108, 124:
+		return true
+	}
+	return false
+}
+
+//isSVG looks for an svg element at the start of an XML document
+func isSVG(Header []byte) bool {
+	text := bytes.TrimLeft(bytes.TrimPrefix(Header, []byte("\xEF\xBB\xBF")), " \t\r\n")
+	if bytes.HasPrefix(text, []byte("<")) == false {
+		return false
+	}
+	return bytes.Contains(bytes.ToLower(text), []byte("<svg"))
+}
+
+//mp4Brands are the ftyp brands of MP4 video. HEIF images and others share the container, so only these are accepted
+var mp4Brands = []string{"isom", "iso2", "iso3", "iso4", "iso5", "iso6", "mp41", "mp42", "avc1", "dash", "mmp4", "M4V ", "M4VH", "M4VP", "f4v ", "XAVC"}
+
+//isMP4 matches ISO base media files with a video brand
+func isMP4(Header []byte) bool {
+	if len(Header) < 12 || string(Header[4:8]) != "ftyp" {
+		return false
+	}
+	for _, brand := range mp4Brands {
+		if string(Header[8:12]) == brand {
+			return true
+		}
+	}
+	return false
+}
+
+//isQuickTime matches QuickTime files, which either have the qt brand or, when older, start straight away with an atom
+func isQuickTime(Header []byte) bool {
+	if len(Header) < 12 {
+		return false
+	}
+	switch string(Header[4:8]) {
+	case "ftyp":
+		return string(Header[8:12]) == "qt  "
+	case "moov", "mdat", "wide", "free", "skip", "pnot":
+		return true
+	}
+	return false
+}
+
+//isWebM matches Matroska files with the webm DocType
+func isWebM(Header []byte) bool {
+	return bytes.HasPrefix(Header, []byte("\x1A\x45\xDF\xA3")) && bytes.Contains(Header, []byte("webm"))
+}
+
+//isMP3 matches an ID3 tag, or an MPEG audio frame header with a valid layer, bitrate, and sample rate
+func isMP3(Header []byte) bool {
+	if bytes.HasPrefix(Header, []byte("ID3")) {
+		return true
+	}
+	return len(Header) >= 3 && Header[0] == 0xFF && Header[1]&0xE0 == 0xE0 && Header[1]&0x06 != 0 && Header[2]&0xF0 != 0xF0 && Header[2]&0x0C != 0x0C
+}
@@ -0,0 +1,90 @@
+package media
+
+import (
+	"bytes"
+	"go-image-board/config"
+	"io"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	bmpHeader := append([]byte("BM"), make([]byte, 16)...)
+	bmpHeader[14] = 40
+	tests := []struct {
+		Header string
+		Name   string
+	}{
+		{"\xFF\xD8\xFF\xE0\x00\x10JFIF", "jpg"},
+		{"\x89PNG\r\n\x1A\n\x00\x00\x00\x0DIHDR", "png"},
+		{"GIF89a\x01\x00", "gif"},
+		{string(bmpHeader), "bmp"},
+		{"RIFF\x00\x00\x00\x00WEBPVP8 ", "webp"},
+		{"II*\x00\x08\x00\x00\x00", "tiff"},
+		{"\xEF\xBB\xBF\n<?xml version=\"1.0\"?>\n<!-- drawn -->\n<SVG xmlns=\"http://www.w3.org/2000/svg\">", "svg"},
+		{"\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00", "mp4"},
+		{"\x00\x00\x00\x14ftypqt  \x00\x00\x00\x00", "mov"},
+		{"\x00\x00\x00\x08wide\x00\x00\x00\x00", "mov"},
+		{"\x1A\x45\xDF\xA3\x9F\x42\x86\x81\x01\x42\x82\x84webm", "webm"},
+		{"RIFF\x00\x00\x00\x00AVI LIST", "avi"},
+		{"\x00\x00\x01\xBA\x44\x00", "mpg"},
+		{"ID3\x04\x00\x00", "mp3"},
+		{"\xFF\xFB\x90\x00", "mp3"},
+		{"OggS\x00\x02", "ogg"},
+		{"RIFF\x00\x00\x00\x00WAVEfmt ", "wav"},
+	}
+	for _, test := range tests {
+		mediaType, found := Detect([]byte(test.Header))
+		if found == false || mediaType.Name != test.Name {
+			t.Errorf("Detect(%q) = %q, %v, want %q", test.Header, mediaType.Name, found, test.Name)
+		}
+	}
+
+	for _, header := range []string{
+		"MZ\x90\x00\x03\x00\x00\x00",               //Windows executable
+		"BM is how this text starts",               //Not a real bitmap header
+		"\x00\x00\x00\x18ftypheic\x00\x00\x00\x00", //HEIF images share the MP4 container
+		"\x1A\x45\xDF\xA3\x9F\x42\x86\x81\x01\x42\x82\x88matroska",
+		"\xFF\xFE<\x00h\x00t\x00", //UTF-16 text
+		"<html><body></body></html>",
+		"",
+	} {
+		if mediaType, found := Detect([]byte(header)); found {
+			t.Errorf("Detect(%q) recognized %q", header, mediaType.Name)
+		}
+	}
+}
+
+func TestSniff(t *testing.T) {
+	stream := bytes.NewReader([]byte("GIF87a and the rest of the image"))
+	mediaType, err := Sniff(stream)
+	if err != nil || mediaType.Name != "gif" {
+		t.Fatalf("Sniff: %q, %v", mediaType.Name, err)
+	}
+	if rest, _ := io.ReadAll(stream); string(rest) != "GIF87a and the rest of the image" {
+		t.Errorf("Sniff did not leave the stream at the start, read %q", rest)
+	}
+	if _, err := Sniff(bytes.NewReader([]byte("MZ"))); err != ErrUnrecognized {
+		t.Errorf("Sniff of an executable: %v", err)
+	}
+}
+
+func TestNames(t *testing.T) {
+	mediaType, found := ByName("folder/picture.JPEG")
+	if found == false || mediaType.Name != "jpg" {
+		t.Errorf("ByName of an alias: %q, %v", mediaType.Name, found)
+	}
+	if _, found := ByName("program.exe"); found {
+		t.Errorf("ByName recognized an executable")
+	}
+	if name := mediaType.WithExtension("folder/picture.png"); name != "folder/picture.jpg" {
+		t.Errorf("WithExtension = %q", name)
+	}
+
+	config.Configuration.AllowedMediaTypes = []string{"PNG", ".jpg"}
+	if IsAllowed(mediaType) == false {
+		t.Errorf("jpg should be allowed")
+	}
+	if webm, _ := ByName("clip.webm"); IsAllowed(webm) {
+		t.Errorf("webm should not be allowed")
+	}
+}
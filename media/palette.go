@@ -0,0 +1,121 @@
+package media
+
+import (
+	"encoding/hex"
+	"go-image-board/interfaces"
+	"image"
+	"image/color"
+	"sort"
+	"strings"
+)
+
+//paletteMergeDistance is how close, in RGB, two colours may be before they are counted as one
+const paletteMergeDistance = 48
+
+//DominantColors returns up to Count of the colours that cover most of Image, from most to least common
+//Pixels are grouped into bins of similar colour, then the largest bins are merged with any close enough to them. Image should already be small
+//Pixels that are mostly transparent are not counted
+func DominantColors(Image image.Image, Count int) []interfaces.ImageColor {
+	type bin struct {
+		key, red, green, blue, pixels int
+	}
+	bins := map[int]*bin{}
+	total := 0
+	bounds := Image.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			pixel := color.NRGBAModel.Convert(Image.At(x, y)).(color.NRGBA)
+			if pixel.A < 128 {
+				continue
+			}
+			//Sixteen levels of each channel
+			key := int(pixel.R>>4)<<8 | int(pixel.G>>4)<<4 | int(pixel.B>>4)
+			if bins[key] == nil {
+				bins[key] = &bin{key: key}
+			}
+			bins[key].red += int(pixel.R)
+			bins[key].green += int(pixel.G)
+			bins[key].blue += int(pixel.B)
+			bins[key].pixels++
+			total++
+		}
+	}
+	if total == 0 || Count < 1 {
+		return nil
+	}
+	sorted := make([]*bin, 0, len(bins))
+	for _, next := range bins {
+		sorted = append(sorted, next)
+	}
+	//Ties are broken by colour so the same image always gives the same palette
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].pixels != sorted[j].pixels {
+			return sorted[i].pixels > sorted[j].pixels
+		}
+		return sorted[i].key < sorted[j].key
+	})
+
+	var merged []*bin
+	for _, next := range sorted {
+		found := false
+		for _, existing := range merged {
+			red := next.red/next.pixels - existing.red/existing.pixels
+			green := next.green/next.pixels - existing.green/existing.pixels
+			blue := next.blue/next.pixels - existing.blue/existing.pixels
+			if red*red+green*green+blue*blue <= paletteMergeDistance*paletteMergeDistance {
+				existing.red += next.red
+				existing.green += next.green
+				existing.blue += next.blue
+				existing.pixels += next.pixels
+				found = true
+				break
+			}
+		}
+		if found == false && len(merged) < Count {
+			copied := *next
+			merged = append(merged, &copied)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].pixels > merged[j].pixels })
+
+	colors := make([]interfaces.ImageColor, 0, len(merged))
+	for _, next := range merged {
+		colors = append(colors, interfaces.ImageColor{
+			Red:   uint8(next.red / next.pixels),
+			Green: uint8(next.green / next.pixels),
+			Blue:  uint8(next.blue / next.pixels),
+			Share: float64(next.pixels) / float64(total),
+		})
+	}
+	return colors
+}
+
+//namedColors are the colours that can be searched for by name, each near the middle of what the name covers
+var namedColors = map[string]interfaces.ImageColor{
+	"red":    {Red: 220, Green: 40, Blue: 40},
+	"orange": {Red: 240, Green: 140, Blue: 30},
+	"yellow": {Red: 240, Green: 220, Blue: 40},
+	"green":  {Red: 50, Green: 160, Blue: 60},
+	"cyan":   {Red: 40, Green: 200, Blue: 210},
+	"blue":   {Red: 40, Green: 80, Blue: 200},
+	"purple": {Red: 130, Green: 60, Blue: 170},
+	"pink":   {Red: 240, Green: 130, Blue: 180},
+	"brown":  {Red: 130, Green: 80, Blue: 40},
+	"black":  {Red: 20, Green: 20, Blue: 20},
+	"white":  {Red: 240, Green: 240, Blue: 240},
+	"grey":   {Red: 128, Green: 128, Blue: 128},
+	"gray":   {Red: 128, Green: 128, Blue: 128},
+}
+
+//ParseColor returns the colour named by Value, either a name such as blue or a hex value such as #3366ff, with or without the #
+func ParseColor(Value string) (interfaces.ImageColor, bool) {
+	Value = strings.ToLower(Value)
+	if named, found := namedColors[Value]; found {
+		return named, true
+	}
+	channels, err := hex.DecodeString(strings.TrimPrefix(Value, "#"))
+	if err != nil || len(channels) != 3 {
+		return interfaces.ImageColor{}, false
+	}
+	return interfaces.ImageColor{Red: channels[0], Green: channels[1], Blue: channels[2]}, true
+}
@@ -0,0 +1,66 @@
+package media
+
+import (
+	"go-image-board/interfaces"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestDominantColors(t *testing.T) {
+	//Half blue, a quarter in two close shades of red, and the rest transparent or a little green
+	picture := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	draw.Draw(picture, image.Rect(0, 0, 10, 5), image.NewUniform(color.NRGBA{0x20, 0x40, 0xF0, 0xFF}), image.Point{}, draw.Src)
+	draw.Draw(picture, image.Rect(0, 5, 5, 10), image.NewUniform(color.NRGBA{0xF0, 0x10, 0x10, 0xFF}), image.Point{}, draw.Src)
+	draw.Draw(picture, image.Rect(0, 5, 2, 10), image.NewUniform(color.NRGBA{0xE0, 0x20, 0x10, 0xFF}), image.Point{}, draw.Src)
+	draw.Draw(picture, image.Rect(5, 5, 10, 9), image.NewUniform(color.NRGBA{0xFF, 0xFF, 0xFF, 0x10}), image.Point{}, draw.Src)
+	draw.Draw(picture, image.Rect(5, 9, 10, 10), image.NewUniform(color.NRGBA{0x00, 0xFF, 0x00, 0xFF}), image.Point{}, draw.Src)
+
+	colors := DominantColors(picture, 5)
+	want := []interfaces.ImageColor{
+		{Red: 0x20, Green: 0x40, Blue: 0xF0, Share: 50.0 / 80},
+		{Red: 0xE9, Green: 0x16, Blue: 0x10, Share: 25.0 / 80},
+		{Red: 0x00, Green: 0xFF, Blue: 0x00, Share: 5.0 / 80},
+	}
+	if len(colors) != len(want) {
+		t.Fatalf("DominantColors = %+v, want %+v", colors, want)
+	}
+	for index := range want {
+		if colors[index] != want[index] {
+			t.Errorf("colour %d = %+v, want %+v", index, colors[index], want[index])
+		}
+	}
+	if colors[0].Hex() != "#2040f0" {
+		t.Errorf("Hex = %q", colors[0].Hex())
+	}
+
+	//Leftover pixels still count towards the shares, but are not listed
+	if colors := DominantColors(picture, 1); len(colors) != 1 || colors[0].Share != 50.0/80 {
+		t.Errorf("DominantColors of one colour = %+v", colors)
+	}
+	if colors := DominantColors(image.NewNRGBA(image.Rect(0, 0, 4, 4)), 5); colors != nil {
+		t.Errorf("DominantColors of a transparent image = %+v", colors)
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	for _, test := range []struct {
+		Value    string
+		Expected interfaces.ImageColor
+	}{
+		{"#3366ff", interfaces.ImageColor{Red: 0x33, Green: 0x66, Blue: 0xFF}},
+		{"3366FF", interfaces.ImageColor{Red: 0x33, Green: 0x66, Blue: 0xFF}},
+		{"Blue", namedColors["blue"]},
+		{"gray", namedColors["grey"]},
+	} {
+		if color, found := ParseColor(test.Value); found == false || color != test.Expected {
+			t.Errorf("ParseColor(%q) = %+v, %v, want %+v", test.Value, color, found, test.Expected)
+		}
+	}
+	for _, value := range []string{"", "#", "#36f", "#3366ffaa", "#zz66ff", "chartreuse"} {
+		if color, found := ParseColor(value); found {
+			t.Errorf("ParseColor(%q) accepted as %+v", value, color)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package media
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+const svgNamespace = "http://www.w3.org/2000/svg"
+const xlinkNamespace = "http://www.w3.org/1999/xlink"
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+//ErrNotSVG is returned by SanitizeSVG when the document is not well formed or is not an svg element
+var ErrNotSVG = errors.New("not an SVG document")
+
+//svgElements are the elements kept by SanitizeSVG. Anything else, such as script, foreignObject, and animation which can change links, is removed along with its children
+var svgElements = map[string]bool{
+	"svg": true, "g": true, "defs": true, "title": true, "desc": true, "symbol": true, "use": true, "switch": true, "style": true, "image": true,
+	"path": true, "rect": true, "circle": true, "ellipse": true, "line": true, "polyline": true, "polygon": true,
+	"text": true, "tspan": true, "textPath": true,
+	"linearGradient": true, "radialGradient": true, "stop": true, "pattern": true, "clipPath": true, "mask": true, "marker": true,
+	"filter": true, "feBlend": true, "feColorMatrix": true, "feComponentTransfer": true, "feComposite": true, "feConvolveMatrix": true,
+	"feDiffuseLighting": true, "feDisplacementMap": true, "feDistantLight": true, "feDropShadow": true, "feFlood": true,
+	"feFuncA": true, "feFuncB": true, "feFuncG": true, "feFuncR": true, "feGaussianBlur": true, "feMerge": true, "feMergeNode": true,
+	"feMorphology": true, "feOffset": true, "fePointLight": true, "feSpecularLighting": true, "feSpotLight": true, "feTile": true, "feTurbulence": true,
+}
+
+//embeddedImagePrefixes are the only links an image element may have, so it cannot load anything from elsewhere
+var embeddedImagePrefixes = []string{"data:image/png;", "data:image/jpeg;", "data:image/gif;", "data:image/webp;"}
+
+//textEscaper escapes character data. Unlike xml.EscapeText it leaves line breaks alone, so style sheets stay readable
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+//SanitizeSVG rewrites an SVG document with only elements and attributes that cannot run script or load anything from another file or site
+//Event handlers, links other than to the document itself, foreignObject, comments, and processing instructions are all removed
+//Sanitizing a document already returned by SanitizeSVG returns the same bytes
+func SanitizeSVG(Data []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(Data))
+	decoder.Strict = true
+	var output bytes.Buffer
+	//skipDepth counts how far into a removed element the decoder is
+	skipDepth := 0
+	var open []string
+	finished := false
+	//Style sheets are checked whole, as CDATA sections could otherwise split what is refused over several tokens
+	var styleSheet strings.Builder
+	for true {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, ErrNotSVG
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			if len(open) == 0 && skipDepth == 0 && (finished || token.Name.Local != "svg" || (token.Name.Space != svgNamespace && token.Name.Space != "")) {
+				return nil, ErrNotSVG
+			}
+			//Links are kept as groups, so what they hold is still shown
+			elementName := token.Name.Local
+			if elementName == "a" {
+				elementName = "g"
+			}
+			if skipDepth > 0 || svgElementAllowed(xml.Name{Space: token.Name.Space, Local: elementName}) == false || (len(open) > 0 && open[len(open)-1] == "style") {
+				skipDepth++
+				continue
+			}
+			output.WriteString("<" + elementName)
+			if len(open) == 0 {
+				output.WriteString(` xmlns="` + svgNamespace + `" xmlns:xlink="` + xlinkNamespace + `"`)
+			}
+			for _, attribute := range token.Attr {
+				name, allowed := svgAttributeName(attribute.Name)
+				if allowed == false || svgAttributeAllowed(elementName, attribute.Name.Local, attribute.Value) == false {
+					continue
+				}
+				output.WriteString(" " + name + `="`)
+				xml.EscapeText(&output, []byte(attribute.Value))
+				output.WriteString(`"`)
+			}
+			output.WriteString(">")
+			open = append(open, elementName)
+		case xml.EndElement:
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+			if open[len(open)-1] == "style" {
+				//Unsafe style sheets are dropped entirely
+				if cssIsSafe(styleSheet.String()) {
+					output.WriteString(textEscaper.Replace(styleSheet.String()))
+				}
+				styleSheet.Reset()
+			}
+			output.WriteString("</" + open[len(open)-1] + ">")
+			open = open[:len(open)-1]
+			finished = len(open) == 0
+		case xml.CharData:
+			if skipDepth > 0 || len(open) == 0 {
+				continue
+			}
+			if open[len(open)-1] == "style" {
+				styleSheet.Write(token)
+				continue
+			}
+			output.WriteString(textEscaper.Replace(string(token)))
+		}
+		//Comments, processing instructions such as xml-stylesheet, and directives such as DOCTYPE are all dropped
+	}
+	if output.Len() == 0 || len(open) != 0 {
+		return nil, ErrNotSVG
+	}
+	return output.Bytes(), nil
+}
+
+//svgElementAllowed returns whether an element is kept, it must be an SVG element in the allow list
+func svgElementAllowed(Name xml.Name) bool {
+	if Name.Space != svgNamespace && Name.Space != "" {
+		return false
+	}
+	return svgElements[Name.Local]
+}
+
+//svgAttributeName returns how an attribute is written, and whether its namespace is one that is kept
+//Namespace declarations are not kept, as the root element declares the only ones needed
+func svgAttributeName(Name xml.Name) (string, bool) {
+	switch Name.Space {
+	case "":
+		return Name.Local, Name.Local != "xmlns"
+	case xlinkNamespace:
+		return "xlink:" + Name.Local, true
+	case xmlNamespace:
+		return "xml:" + Name.Local, Name.Local == "space" || Name.Local == "lang"
+	}
+	return "", false
+}
+
+//svgAttributeAllowed returns whether an attribute is safe, by its name without namespace and its value
+func svgAttributeAllowed(Element string, Name string, Value string) bool {
+	lowerName := strings.ToLower(Name)
+	//Event handlers run script
+	if strings.HasPrefix(lowerName, "on") {
+		return false
+	}
+	compactValue := strings.ToLower(strings.Join(strings.Fields(Value), ""))
+	if strings.Contains(compactValue, "javascript:") {
+		return false
+	}
+	if lowerName == "href" {
+		if Element == "image" {
+			for _, allowedPrefix := range embeddedImagePrefixes {
+				if strings.HasPrefix(compactValue, allowedPrefix) {
+					return true
+				}
+			}
+			return false
+		}
+		return strings.HasPrefix(compactValue, "#")
+	}
+	if lowerName == "style" {
+		return cssIsSafe(Value)
+	}
+	return cssURLsAreLocal(compactValue)
+}
+
+//cssIsSafe returns whether a style sheet or style attribute only refers to the document itself
+//Escapes are refused outright, as they could be used to hide anything else
+func cssIsSafe(Style string) bool {
+	compactStyle := strings.ToLower(strings.Join(strings.Fields(Style), ""))
+	if strings.Contains(compactStyle, "\\") {
+		return false
+	}
+	for _, refused := range []string{"@import", "expression(", "javascript:", "behavior:", "-moz-binding"} {
+		if strings.Contains(compactStyle, refused) {
+			return false
+		}
+	}
+	return cssURLsAreLocal(compactStyle)
+}
+
+//cssURLsAreLocal returns whether every url() in a lower case value without white space points to an element of the document
+func cssURLsAreLocal(Value string) bool {
+	for index := strings.Index(Value, "url("); index >= 0; index = strings.Index(Value, "url(") {
+		Value = strings.TrimLeft(Value[index+len("url("):], `"'`)
+		if strings.HasPrefix(Value, "#") == false {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,171 @@
+package media
+
+import (
+	"image/color"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeSVG(t *testing.T) {
+	document := `<?xml version="1.0" encoding="UTF-8"?>
+<?xml-stylesheet href="https://example.com/sheet.css"?>
+<!DOCTYPE svg>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape" width="10" height="10" onload="alert(1)" inkscape:version="1.0">
+<!-- a comment -->
+<script>alert(2)</script>
+<style>.kept { fill: url(#gradient) }</style>
+<style>@imp<![CDATA[ort "https://example.com/sheet.css";]]></style>
+<defs><linearGradient id="gradient"><stop offset="0" stop-color="red"/></linearGradient></defs>
+<g OnClick="alert(3)" style="fill: url(https://example.com/track.png)">
+<rect class="kept" x="1" y="1" width="8" height="8" fill="url( 'https://example.com/a.svg#b' )"/>
+<use xlink:href="https://example.com/sprite.svg#icon"/>
+<use href="#gradient"/>
+<a href="javascript:alert(4)"><circle cx="5" cy="5" r="2"/></a>
+<image href="https://example.com/tracker.png"/>
+<image href="data:image/png;base64,AAAA"/>
+<image href="data:image/svg+xml;base64,AAAA"/>
+<foreignObject><div xmlns="http://www.w3.org/1999/xhtml">html</div></foreignObject>
+<set attributeName="href" to="javascript:alert(5)"/>
+<text x="1" y="5">a &amp; b &lt; c</text>
+</g>
+</svg>`
+	sanitized, err := SanitizeSVG([]byte(document))
+	if err != nil {
+		t.Fatalf("SanitizeSVG: %v", err)
+	}
+	result := string(sanitized)
+	for _, removed := range []string{"alert", "script", "https://", "inkscape", "comment", "foreignObject", "html", "<set", "@import", "svg+xml", "<?", "<!"} {
+		if strings.Contains(result, removed) {
+			t.Errorf("sanitized document still contains %q:\n%s", removed, result)
+		}
+	}
+	for _, kept := range []string{`<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="10" height="10">`,
+		`.kept { fill: url(#gradient) }`, `<use href="#gradient">`, `<g><circle cx="5" cy="5" r="2"></circle></g>`,
+		`<image href="data:image/png;base64,AAAA">`, `a &amp; b &lt; c`, `<stop offset="0" stop-color="red">`} {
+		if strings.Contains(result, kept) == false {
+			t.Errorf("sanitized document is missing %q:\n%s", kept, result)
+		}
+	}
+	if mediaType, found := Detect(sanitized); found == false || mediaType.Name != "svg" {
+		t.Errorf("sanitized document is not recognized as SVG")
+	}
+
+	//Uploads are hashed after sanitizing, so doing it again must not change them
+	again, err := SanitizeSVG(sanitized)
+	if err != nil || string(again) != result {
+		t.Errorf("sanitizing twice changed the document: %v\n%s\n%s", err, result, again)
+	}
+
+	for _, invalid := range []string{
+		`<html><svg></svg></html>`,
+		`<svg xmlns="http://www.w3.org/2000/svg"><rect>`,
+		`<svg xmlns="http://www.w3.org/2000/svg"></svg><svg xmlns="http://www.w3.org/2000/svg"></svg>`,
+		`<!DOCTYPE svg [<!ENTITY lol "lol">]><svg xmlns="http://www.w3.org/2000/svg">&lol;</svg>`,
+	} {
+		if _, err := SanitizeSVG([]byte(invalid)); err != ErrNotSVG {
+			t.Errorf("SanitizeSVG(%q) = %v, want ErrNotSVG", invalid, err)
+		}
+	}
+}
+
+func TestRasterizeSVG(t *testing.T) {
+	document := `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 200 100" width="400">
+<style>.blue { fill: #0000ff }</style>
+<rect width="100" height="100" fill="red"/>
+<path class="blue" d="M100 0h100v50H100z"/>
+<path d="M150,75 m-20,0 a20,20 0 1,0 40,0 a20,20 0 1,0 -40,0" fill="none" stroke="rgb(0, 128, 0)" stroke-width="4"/>
+<g transform="translate(100 50)" display="none"><rect width="100" height="50"/></g>
+</svg>`
+	output, err := RasterizeSVG([]byte(document), 100, 100)
+	if err != nil {
+		t.Fatalf("RasterizeSVG: %v", err)
+	}
+	if output.Bounds().Dx() != 100 || output.Bounds().Dy() != 50 {
+		t.Fatalf("RasterizeSVG size %v, want 100x50", output.Bounds())
+	}
+	for _, check := range []struct {
+		X, Y  int
+		Color color.RGBA
+	}{
+		{25, 25, color.RGBA{255, 0, 0, 255}},
+		{75, 10, color.RGBA{0, 0, 255, 255}},
+		{65, 37, color.RGBA{0, 128, 0, 255}},
+		{75, 37, color.RGBA{}},
+		{90, 45, color.RGBA{}},
+	} {
+		if pixel := output.RGBAAt(check.X, check.Y); pixel != check.Color {
+			t.Errorf("pixel at %d, %d is %v, want %v", check.X, check.Y, pixel, check.Color)
+		}
+	}
+
+	//use elements referring to each other are limited, rather than drawing forever
+	var recursive strings.Builder
+	recursive.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><rect id="l0" width="1" height="1"/>`)
+	for level := 1; level < 30; level++ {
+		recursive.WriteString(`<g id="l` + string(rune('a'+level)) + `">`)
+		for copies := 0; copies < 10; copies++ {
+			if level == 1 {
+				recursive.WriteString(`<use href="#l0"/>`)
+			} else {
+				recursive.WriteString(`<use href="#l` + string(rune('a'+level-1)) + `"/>`)
+			}
+		}
+		recursive.WriteString(`</g>`)
+	}
+	recursive.WriteString(`</svg>`)
+	if _, err := RasterizeSVG([]byte(recursive.String()), 10, 10); err != nil {
+		t.Errorf("RasterizeSVG of nested use elements: %v", err)
+	}
+}
+
+func TestRasterizeSVGPathLimit(t *testing.T) {
+	//pathDocument returns a document with one stroked path of Points points, drawn Copies times
+	pathDocument := func(Points int, Copies int) string {
+		var document strings.Builder
+		document.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 1000 1000"><path id="p" fill="none" stroke="black" d="M0 0`)
+		for index := 1; index < Points; index++ {
+			document.WriteString(" L" + strconv.Itoa(index*37%1000) + " " + strconv.Itoa(index*91%1000))
+		}
+		document.WriteString(`"/>`)
+		for copies := 1; copies < Copies; copies++ {
+			document.WriteString(`<use href="#p"/>`)
+		}
+		document.WriteString(`</svg>`)
+		return document.String()
+	}
+	if _, err := RasterizeSVG([]byte(pathDocument(svgMaxPathPoints/2, 1)), 64, 64); err != nil {
+		t.Errorf("RasterizeSVG of a path within the limit: %v", err)
+	}
+	if _, err := RasterizeSVG([]byte(pathDocument(400000, 1)), 512, 512); err != ErrSVGTooComplex {
+		t.Errorf("RasterizeSVG of an oversized path: %v, expected ErrSVGTooComplex", err)
+	}
+	//Points drawn again by use elements count towards the limit too
+	if _, err := RasterizeSVG([]byte(pathDocument(svgMaxPathPoints/2, 3)), 64, 64); err != ErrSVGTooComplex {
+		t.Errorf("RasterizeSVG of a path drawn three times: %v, expected ErrSVGTooComplex", err)
+	}
+	polyline := `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><polyline points="` + strings.Repeat("1 2 3 4 ", svgMaxPathPoints) + `"/></svg>`
+	if _, err := RasterizeSVG([]byte(polyline), 64, 64); err != ErrSVGTooComplex {
+		t.Errorf("RasterizeSVG of an oversized polyline: %v, expected ErrSVGTooComplex", err)
+	}
+}
+
+func TestSVGSize(t *testing.T) {
+	for _, test := range []struct {
+		Document      string
+		Width, Height float64
+	}{
+		{`<svg xmlns="http://www.w3.org/2000/svg" width="640" height="480"/>`, 640, 480},
+		{`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 200 100" width="400"/>`, 400, 200},
+		{`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 16 9"/>`, 16, 9},
+		{`<svg xmlns="http://www.w3.org/2000/svg"/>`, 300, 150},
+	} {
+		width, height, err := SVGSize([]byte(test.Document))
+		if err != nil || width != test.Width || height != test.Height {
+			t.Errorf("SVGSize(%q) = %v, %v, %v, want %v, %v", test.Document, width, height, err, test.Width, test.Height)
+		}
+	}
+	if _, _, err := SVGSize([]byte("not a document")); err == nil {
+		t.Errorf("SVGSize accepted something that is not SVG")
+	}
+}
@@ -0,0 +1,1072 @@
+package media
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/vector"
+)
+
+//The SVG renderer draws shapes and paths with solid fills and strokes, which is enough for a thumbnail of most drawings
+//Gradients are drawn with their first stop, and text, embedded images, filters, masks, and clipping are not drawn
+
+//svgMaxElements limits how much is drawn, as use elements can refer to each other to draw far more than the document holds
+const svgMaxElements = 20000
+
+//svgMaxUseDepth limits how deeply use elements may refer to other use elements
+const svgMaxUseDepth = 8
+
+//svgMaxPathPoints limits the points of paths and polylines in a document, counting each time a use element draws them again, as drawing takes longer the more points there are
+const svgMaxPathPoints = 50000
+
+//ErrSVGTooComplex is returned by RasterizeSVG when a document's paths have more than svgMaxPathPoints points
+var ErrSVGTooComplex = errors.New("SVG document has too many points to draw")
+
+//svgCurveSegments is how many lines each curve is drawn with
+const svgCurveSegments = 16
+
+//svgNode is an element of a parsed SVG document
+type svgNode struct {
+	Name       string
+	Attributes map[string]string
+	Children   []*svgNode
+	Text       string
+}
+
+//svgPaint is a fill or stroke
+type svgPaint struct {
+	None  bool
+	Color color.NRGBA
+}
+
+//svgStyle holds the properties that children inherit
+type svgStyle struct {
+	Fill          svgPaint
+	Stroke        svgPaint
+	StrokeWidth   float64
+	FillOpacity   float64
+	StrokeOpacity float64
+	Opacity       float64
+	Color         color.NRGBA
+	Hidden        bool
+}
+
+//svgMatrix is an affine transform, mapping x, y to A*x + C*y + E, B*x + D*y + F
+type svgMatrix struct {
+	A, B, C, D, E, F float64
+}
+
+//svgPoint is a point in the output image or in the user space of an element
+type svgPoint struct {
+	X, Y float64
+}
+
+//svgSubpath is one run of connected points of a shape
+type svgSubpath struct {
+	Points []svgPoint
+	Closed bool
+}
+
+//svgCSSRule is a rule from a style element, with a single simple selector
+type svgCSSRule struct {
+	Selector     string
+	Declarations map[string]string
+}
+
+//svgRenderer holds the state of drawing one document
+type svgRenderer struct {
+	IDs       map[string]*svgNode
+	Rules     []svgCSSRule
+	Output    *image.RGBA
+	Raster    *vector.Rasterizer
+	ViewWidth float64
+	Drawn     int
+	//PathPoints counts the points of paths and polylines drawn so far, and Err is set once there are too many
+	PathPoints int
+	Err        error
+}
+
+//RasterizeSVG draws an SVG document as large as fits within MaxWidth by MaxHeight, keeping its proportions
+func RasterizeSVG(Data []byte, MaxWidth uint, MaxHeight uint) (*image.RGBA, error) {
+	root, err := parseSVG(Data)
+	if err != nil {
+		return nil, err
+	}
+	renderer := &svgRenderer{IDs: make(map[string]*svgNode)}
+	renderer.index(root)
+
+	documentWidth, documentHeight, viewBox := documentSize(root)
+	scale := math.Min(float64(MaxWidth)/documentWidth, float64(MaxHeight)/documentHeight)
+	outputWidth := int(math.Max(1, math.Round(documentWidth*scale)))
+	outputHeight := int(math.Max(1, math.Round(documentHeight*scale)))
+
+	//Fit the viewBox in the middle of the output
+	viewScale := math.Min(float64(outputWidth)/viewBox[2], float64(outputHeight)/viewBox[3])
+	base := svgMatrix{A: viewScale, D: viewScale,
+		E: (float64(outputWidth)-viewBox[2]*viewScale)/2 - viewBox[0]*viewScale,
+		F: (float64(outputHeight)-viewBox[3]*viewScale)/2 - viewBox[1]*viewScale}
+
+	renderer.Output = image.NewRGBA(image.Rect(0, 0, outputWidth, outputHeight))
+	renderer.Raster = vector.NewRasterizer(outputWidth, outputHeight)
+	renderer.ViewWidth = viewBox[2]
+	style := svgStyle{Fill: svgPaint{Color: color.NRGBA{A: 255}}, Stroke: svgPaint{None: true}, StrokeWidth: 1, FillOpacity: 1, StrokeOpacity: 1, Opacity: 1, Color: color.NRGBA{A: 255}}
+	style = renderer.inherit(style, renderer.properties(root))
+	if style.Hidden == false {
+		renderer.drawChildren(root, base, style, 0)
+	}
+	if renderer.Err != nil {
+		return nil, renderer.Err
+	}
+	return renderer.Output, nil
+}
+
+//SVGSize returns the width and height of an SVG document, which RasterizeSVG scales to fit
+func SVGSize(Data []byte) (float64, float64, error) {
+	root, err := parseSVG(Data)
+	if err != nil {
+		return 0, 0, err
+	}
+	documentWidth, documentHeight, _ := documentSize(root)
+	return documentWidth, documentHeight, nil
+}
+
+//documentSize returns the size of a document and the viewBox to draw into it
+//The size comes from width and height, falling back to the viewBox, then to the default size of replaced elements
+func documentSize(Root *svgNode) (float64, float64, []float64) {
+	viewBox := parseNumbers(Root.Attributes["viewBox"])
+	documentWidth := parseLength(Root.Attributes["width"], 0)
+	documentHeight := parseLength(Root.Attributes["height"], 0)
+	if len(viewBox) == 4 && viewBox[2] > 0 && viewBox[3] > 0 {
+		if documentWidth <= 0 && documentHeight <= 0 {
+			documentWidth, documentHeight = viewBox[2], viewBox[3]
+		} else if documentWidth <= 0 {
+			documentWidth = documentHeight * viewBox[2] / viewBox[3]
+		} else if documentHeight <= 0 {
+			documentHeight = documentWidth * viewBox[3] / viewBox[2]
+		}
+	} else {
+		if documentWidth <= 0 {
+			documentWidth = 300
+		}
+		if documentHeight <= 0 {
+			documentHeight = 150
+		}
+		viewBox = []float64{0, 0, documentWidth, documentHeight}
+	}
+	return documentWidth, documentHeight, viewBox
+}
+
+//parseSVG reads a document into a tree of elements, by their names without namespace
+func parseSVG(Data []byte) (*svgNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(Data))
+	decoder.Strict = true
+	var root *svgNode
+	var open []*svgNode
+	for true {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, ErrNotSVG
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			node := &svgNode{Name: token.Name.Local, Attributes: make(map[string]string)}
+			for _, attribute := range token.Attr {
+				node.Attributes[attribute.Name.Local] = attribute.Value
+			}
+			if len(open) > 0 {
+				parent := open[len(open)-1]
+				parent.Children = append(parent.Children, node)
+			} else if root == nil {
+				root = node
+			}
+			open = append(open, node)
+		case xml.EndElement:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		case xml.CharData:
+			if len(open) > 0 {
+				open[len(open)-1].Text += string(token)
+			}
+		}
+	}
+	if root == nil || root.Name != "svg" {
+		return nil, ErrNotSVG
+	}
+	return root, nil
+}
+
+//index records elements by ID and reads style sheets
+func (Renderer *svgRenderer) index(Node *svgNode) {
+	if id := Node.Attributes["id"]; id != "" {
+		if _, exists := Renderer.IDs[id]; exists == false {
+			Renderer.IDs[id] = Node
+		}
+	}
+	if Node.Name == "style" {
+		Renderer.Rules = append(Renderer.Rules, parseStyleSheet(Node.Text)...)
+	}
+	for _, child := range Node.Children {
+		Renderer.index(child)
+	}
+}
+
+//drawChildren draws every child of an element
+func (Renderer *svgRenderer) drawChildren(Node *svgNode, Transform svgMatrix, Style svgStyle, UseDepth int) {
+	for _, child := range Node.Children {
+		Renderer.draw(child, Transform, Style, UseDepth)
+	}
+}
+
+//draw draws an element and its children
+func (Renderer *svgRenderer) draw(Node *svgNode, Transform svgMatrix, Style svgStyle, UseDepth int) {
+	if Renderer.Drawn >= svgMaxElements || Renderer.Err != nil {
+		return
+	}
+	Renderer.Drawn++
+	properties := Renderer.properties(Node)
+	style := Renderer.inherit(Style, properties)
+	if style.Hidden {
+		return
+	}
+	transform := Transform.multiply(parseTransform(Node.Attributes["transform"]))
+	switch Node.Name {
+	case "g", "svg":
+		if Node.Name == "svg" {
+			transform = transform.multiply(svgMatrix{A: 1, D: 1, E: parseLength(Node.Attributes["x"], Renderer.ViewWidth), F: parseLength(Node.Attributes["y"], Renderer.ViewWidth)})
+		}
+		Renderer.drawChildren(Node, transform, style, UseDepth)
+	case "switch":
+		//Conditions are not checked, so only the first child is drawn
+		if len(Node.Children) > 0 {
+			Renderer.draw(Node.Children[0], transform, style, UseDepth)
+		}
+	case "use":
+		target := Renderer.IDs[strings.TrimPrefix(strings.TrimSpace(Node.Attributes["href"]), "#")]
+		if target == nil || UseDepth >= svgMaxUseDepth {
+			return
+		}
+		transform = transform.multiply(svgMatrix{A: 1, D: 1, E: parseLength(Node.Attributes["x"], Renderer.ViewWidth), F: parseLength(Node.Attributes["y"], Renderer.ViewWidth)})
+		if target.Name == "symbol" {
+			Renderer.drawChildren(target, transform, style, UseDepth+1)
+		} else {
+			Renderer.draw(target, transform, style, UseDepth+1)
+		}
+	default:
+		subpaths, fillable := Renderer.shape(Node)
+		if len(subpaths) == 0 {
+			return
+		}
+		for index := range subpaths {
+			for pointIndex, point := range subpaths[index].Points {
+				subpaths[index].Points[pointIndex] = transform.apply(point)
+			}
+		}
+		if fillable && style.Fill.None == false {
+			Renderer.fill(subpaths, withOpacity(style.Fill.Color, style.FillOpacity*style.Opacity))
+		}
+		if style.Stroke.None == false && style.StrokeWidth > 0 {
+			width := style.StrokeWidth * math.Sqrt(math.Abs(transform.A*transform.D-transform.B*transform.C))
+			Renderer.stroke(subpaths, width, withOpacity(style.Stroke.Color, style.StrokeOpacity*style.Opacity))
+		}
+	}
+}
+
+//properties returns the presentation properties of an element, from its attributes, then style sheets, then its style attribute
+func (Renderer *svgRenderer) properties(Node *svgNode) map[string]string {
+	properties := make(map[string]string)
+	for _, name := range []string{"fill", "stroke", "stroke-width", "fill-opacity", "stroke-opacity", "opacity", "color", "display", "visibility"} {
+		if value, exists := Node.Attributes[name]; exists {
+			properties[name] = value
+		}
+	}
+	classes := strings.Fields(Node.Attributes["class"])
+	//Apply less specific selectors first, so more specific ones win
+	for _, pass := range []func(string) bool{
+		func(Selector string) bool { return Selector == Node.Name || Selector == "*" },
+		func(Selector string) bool {
+			for _, class := range classes {
+				if Selector == "."+class || Selector == Node.Name+"."+class {
+					return true
+				}
+			}
+			return false
+		},
+		func(Selector string) bool {
+			return Node.Attributes["id"] != "" && Selector == "#"+Node.Attributes["id"]
+		},
+	} {
+		for _, rule := range Renderer.Rules {
+			if pass(rule.Selector) {
+				for name, value := range rule.Declarations {
+					properties[name] = value
+				}
+			}
+		}
+	}
+	for name, value := range parseDeclarations(Node.Attributes["style"]) {
+		properties[name] = value
+	}
+	return properties
+}
+
+//inherit returns the style of an element from the style of its parent and its own properties
+func (Renderer *svgRenderer) inherit(Parent svgStyle, Properties map[string]string) svgStyle {
+	style := Parent
+	if value, exists := Properties["color"]; exists {
+		if parsed, ok := parseColor(value, Parent.Color); ok {
+			style.Color = parsed
+		}
+	}
+	if value, exists := Properties["fill"]; exists {
+		if paint, ok := Renderer.parsePaint(value, style.Color); ok {
+			style.Fill = paint
+		}
+	}
+	if value, exists := Properties["stroke"]; exists {
+		if paint, ok := Renderer.parsePaint(value, style.Color); ok {
+			style.Stroke = paint
+		}
+	}
+	if value, exists := Properties["stroke-width"]; exists {
+		style.StrokeWidth = parseLength(value, Renderer.ViewWidth)
+	}
+	if value, exists := Properties["fill-opacity"]; exists {
+		style.FillOpacity = parseOpacity(value)
+	}
+	if value, exists := Properties["stroke-opacity"]; exists {
+		style.StrokeOpacity = parseOpacity(value)
+	}
+	//Opacity applies to a group as a whole, this approximates it by applying it to each shape
+	if value, exists := Properties["opacity"]; exists {
+		style.Opacity *= parseOpacity(value)
+	}
+	if strings.TrimSpace(Properties["display"]) == "none" || strings.TrimSpace(Properties["visibility"]) == "hidden" {
+		style.Hidden = true
+	}
+	return style
+}
+
+//shape returns the outline of a shape element in its user space, and whether it can be filled
+func (Renderer *svgRenderer) shape(Node *svgNode) ([]svgSubpath, bool) {
+	length := func(Name string) float64 {
+		return parseLength(Node.Attributes[Name], Renderer.ViewWidth)
+	}
+	switch Node.Name {
+	case "path":
+		subpaths, err := parsePathData(Node.Attributes["d"], svgMaxPathPoints-Renderer.PathPoints)
+		if err != nil {
+			Renderer.Err = err
+			return nil, false
+		}
+		for _, subpath := range subpaths {
+			Renderer.PathPoints += len(subpath.Points)
+		}
+		return subpaths, true
+	case "rect":
+		x, y, width, height := length("x"), length("y"), length("width"), length("height")
+		if width <= 0 || height <= 0 {
+			return nil, false
+		}
+		rx, ry := length("rx"), length("ry")
+		if _, exists := Node.Attributes["rx"]; exists == false {
+			rx = ry
+		}
+		if _, exists := Node.Attributes["ry"]; exists == false {
+			ry = rx
+		}
+		rx, ry = math.Min(math.Max(rx, 0), width/2), math.Min(math.Max(ry, 0), height/2)
+		if rx == 0 || ry == 0 {
+			return []svgSubpath{{Points: []svgPoint{{x, y}, {x + width, y}, {x + width, y + height}, {x, y + height}}, Closed: true}}, true
+		}
+		var points []svgPoint
+		for corner, center := range []svgPoint{{x + width - rx, y + ry}, {x + width - rx, y + height - ry}, {x + rx, y + height - ry}, {x + rx, y + ry}} {
+			startAngle := float64(corner-1) * math.Pi / 2
+			for step := 0; step <= svgCurveSegments/2; step++ {
+				angle := startAngle + float64(step)*math.Pi/float64(svgCurveSegments)
+				points = append(points, svgPoint{center.X + rx*math.Cos(angle), center.Y + ry*math.Sin(angle)})
+			}
+		}
+		return []svgSubpath{{Points: points, Closed: true}}, true
+	case "circle", "ellipse":
+		rx, ry := length("r"), length("r")
+		if Node.Name == "ellipse" {
+			rx, ry = length("rx"), length("ry")
+		}
+		if rx <= 0 || ry <= 0 {
+			return nil, false
+		}
+		centerX, centerY := length("cx"), length("cy")
+		var points []svgPoint
+		for step := 0; step < svgCurveSegments*4; step++ {
+			angle := float64(step) * 2 * math.Pi / float64(svgCurveSegments*4)
+			points = append(points, svgPoint{centerX + rx*math.Cos(angle), centerY + ry*math.Sin(angle)})
+		}
+		return []svgSubpath{{Points: points, Closed: true}}, true
+	case "line":
+		return []svgSubpath{{Points: []svgPoint{{length("x1"), length("y1")}, {length("x2"), length("y2")}}}}, false
+	case "polyline", "polygon":
+		numbers := parseNumbers(Node.Attributes["points"])
+		var points []svgPoint
+		for index := 0; index+1 < len(numbers); index += 2 {
+			points = append(points, svgPoint{numbers[index], numbers[index+1]})
+		}
+		if len(points) == 0 {
+			return nil, false
+		}
+		Renderer.PathPoints += len(points)
+		if Renderer.PathPoints > svgMaxPathPoints {
+			Renderer.Err = ErrSVGTooComplex
+			return nil, false
+		}
+		return []svgSubpath{{Points: points, Closed: Node.Name == "polygon"}}, true
+	}
+	//Everything else, such as definitions, text, and images, is not drawn
+	return nil, false
+}
+
+//fill draws the inside of subpaths already in output space
+func (Renderer *svgRenderer) fill(Subpaths []svgSubpath, Color color.NRGBA) {
+	if Color.A == 0 {
+		return
+	}
+	Renderer.Raster.Reset(Renderer.Output.Bounds().Dx(), Renderer.Output.Bounds().Dy())
+	for _, subpath := range Subpaths {
+		if len(subpath.Points) < 2 {
+			continue
+		}
+		Renderer.Raster.MoveTo(float32(subpath.Points[0].X), float32(subpath.Points[0].Y))
+		for _, point := range subpath.Points[1:] {
+			Renderer.Raster.LineTo(float32(point.X), float32(point.Y))
+		}
+		Renderer.Raster.ClosePath()
+	}
+	Renderer.Raster.Draw(Renderer.Output, Renderer.Output.Bounds(), image.NewUniform(Color), image.Point{})
+}
+
+//stroke draws lines of Width along subpaths already in output space
+//Each segment is drawn as a rectangle with a round join at each point, all wound the same way so overlaps do not cancel out
+func (Renderer *svgRenderer) stroke(Subpaths []svgSubpath, Width float64, Color color.NRGBA) {
+	if Color.A == 0 {
+		return
+	}
+	halfWidth := Width / 2
+	Renderer.Raster.Reset(Renderer.Output.Bounds().Dx(), Renderer.Output.Bounds().Dy())
+	for _, subpath := range Subpaths {
+		points := subpath.Points
+		if len(points) < 2 {
+			continue
+		}
+		if subpath.Closed {
+			points = append(points[:len(points):len(points)], points[0])
+		}
+		for index := 0; index+1 < len(points); index++ {
+			start, end := points[index], points[index+1]
+			length := math.Hypot(end.X-start.X, end.Y-start.Y)
+			if length == 0 {
+				continue
+			}
+			normalX, normalY := -(end.Y-start.Y)/length*halfWidth, (end.X-start.X)/length*halfWidth
+			Renderer.Raster.MoveTo(float32(start.X+normalX), float32(start.Y+normalY))
+			Renderer.Raster.LineTo(float32(end.X+normalX), float32(end.Y+normalY))
+			Renderer.Raster.LineTo(float32(end.X-normalX), float32(end.Y-normalY))
+			Renderer.Raster.LineTo(float32(start.X-normalX), float32(start.Y-normalY))
+			Renderer.Raster.ClosePath()
+		}
+		for _, point := range points {
+			Renderer.Raster.MoveTo(float32(point.X+halfWidth), float32(point.Y))
+			for step := 1; step < 8; step++ {
+				angle := -float64(step) * math.Pi / 4
+				Renderer.Raster.LineTo(float32(point.X+halfWidth*math.Cos(angle)), float32(point.Y+halfWidth*math.Sin(angle)))
+			}
+			Renderer.Raster.ClosePath()
+		}
+	}
+	Renderer.Raster.Draw(Renderer.Output, Renderer.Output.Bounds(), image.NewUniform(Color), image.Point{})
+}
+
+//parsePaint reads a fill or stroke. Gradients and patterns are drawn with the color of their first stop
+func (Renderer *svgRenderer) parsePaint(Value string, CurrentColor color.NRGBA) (svgPaint, bool) {
+	Value = strings.TrimSpace(Value)
+	if Value == "none" {
+		return svgPaint{None: true}, true
+	}
+	if strings.HasPrefix(Value, "url(") {
+		end := strings.Index(Value, ")")
+		if end < 0 {
+			return svgPaint{}, false
+		}
+		id := strings.TrimPrefix(strings.Trim(strings.TrimSpace(Value[len("url("):end]), `"'`), "#")
+		if stopColor, found := Renderer.firstStop(Renderer.IDs[id], 0); found {
+			return svgPaint{Color: stopColor}, true
+		}
+		//A fallback color may follow the reference
+		if fallback := strings.TrimSpace(Value[end+1:]); fallback != "" {
+			return Renderer.parsePaint(fallback, CurrentColor)
+		}
+		return svgPaint{None: true}, true
+	}
+	parsed, ok := parseColor(Value, CurrentColor)
+	return svgPaint{Color: parsed}, ok
+}
+
+//firstStop returns the color of the first stop of a gradient, following gradients that take their stops from another
+func (Renderer *svgRenderer) firstStop(Gradient *svgNode, Depth int) (color.NRGBA, bool) {
+	if Gradient == nil || Depth > svgMaxUseDepth {
+		return color.NRGBA{}, false
+	}
+	for _, child := range Gradient.Children {
+		if child.Name != "stop" {
+			continue
+		}
+		properties := Renderer.properties(child)
+		stopColor := color.NRGBA{A: 255}
+		if value, exists := properties["stop-color"]; exists {
+			stopColor, _ = parseColor(value, stopColor)
+		} else if value, exists := child.Attributes["stop-color"]; exists {
+			stopColor, _ = parseColor(value, stopColor)
+		}
+		opacity := 1.0
+		if value, exists := child.Attributes["stop-opacity"]; exists {
+			opacity = parseOpacity(value)
+		}
+		return withOpacity(stopColor, opacity), true
+	}
+	return Renderer.firstStop(Renderer.IDs[strings.TrimPrefix(strings.TrimSpace(Gradient.Attributes["href"]), "#")], Depth+1)
+}
+
+//svgColorNames are the named colors most often found in drawings
+var svgColorNames = map[string]color.NRGBA{
+	"black": {0, 0, 0, 255}, "white": {255, 255, 255, 255}, "red": {255, 0, 0, 255}, "green": {0, 128, 0, 255},
+	"blue": {0, 0, 255, 255}, "yellow": {255, 255, 0, 255}, "cyan": {0, 255, 255, 255}, "aqua": {0, 255, 255, 255},
+	"magenta": {255, 0, 255, 255}, "fuchsia": {255, 0, 255, 255}, "gray": {128, 128, 128, 255}, "grey": {128, 128, 128, 255},
+	"silver": {192, 192, 192, 255}, "maroon": {128, 0, 0, 255}, "olive": {128, 128, 0, 255}, "lime": {0, 255, 0, 255},
+	"teal": {0, 128, 128, 255}, "navy": {0, 0, 128, 255}, "purple": {128, 0, 128, 255}, "orange": {255, 165, 0, 255},
+	"brown": {165, 42, 42, 255}, "pink": {255, 192, 203, 255}, "gold": {255, 215, 0, 255}, "darkgray": {169, 169, 169, 255},
+	"darkgrey": {169, 169, 169, 255}, "lightgray": {211, 211, 211, 255}, "lightgrey": {211, 211, 211, 255},
+	"darkred": {139, 0, 0, 255}, "darkgreen": {0, 100, 0, 255}, "darkblue": {0, 0, 139, 255}, "transparent": {0, 0, 0, 0},
+}
+
+//parseColor reads a color in hex, rgb(), rgba(), or by name
+func parseColor(Value string, CurrentColor color.NRGBA) (color.NRGBA, bool) {
+	Value = strings.ToLower(strings.TrimSpace(Value))
+	if Value == "currentcolor" {
+		return CurrentColor, true
+	}
+	if named, exists := svgColorNames[Value]; exists {
+		return named, true
+	}
+	if strings.HasPrefix(Value, "#") {
+		hex := Value[1:]
+		if len(hex) == 3 || len(hex) == 4 {
+			var expanded strings.Builder
+			for _, digit := range hex {
+				expanded.WriteString(string(digit) + string(digit))
+			}
+			hex = expanded.String()
+		}
+		if len(hex) == 6 {
+			hex += "ff"
+		}
+		parsed, err := strconv.ParseUint(hex, 16, 32)
+		if len(hex) != 8 || err != nil {
+			return color.NRGBA{}, false
+		}
+		return color.NRGBA{R: uint8(parsed >> 24), G: uint8(parsed >> 16), B: uint8(parsed >> 8), A: uint8(parsed)}, true
+	}
+	if strings.HasPrefix(Value, "rgb") {
+		start, end := strings.Index(Value, "("), strings.Index(Value, ")")
+		if start < 0 || end < start {
+			return color.NRGBA{}, false
+		}
+		components := strings.FieldsFunc(Value[start+1:end], func(Character rune) bool { return Character == ',' || Character == ' ' || Character == '/' })
+		if len(components) < 3 {
+			return color.NRGBA{}, false
+		}
+		var channels [4]uint8
+		channels[3] = 255
+		for index, component := range components {
+			if index > 3 {
+				break
+			}
+			scale := 1.0
+			if index == 3 {
+				scale = 255
+			}
+			if strings.HasSuffix(component, "%") {
+				component = strings.TrimSuffix(component, "%")
+				scale = 2.55
+			}
+			number, err := strconv.ParseFloat(component, 64)
+			if err != nil {
+				return color.NRGBA{}, false
+			}
+			channels[index] = uint8(math.Max(0, math.Min(255, math.Round(number*scale))))
+		}
+		return color.NRGBA{R: channels[0], G: channels[1], B: channels[2], A: channels[3]}, true
+	}
+	return color.NRGBA{}, false
+}
+
+//withOpacity multiplies the alpha of a color by Opacity
+func withOpacity(Color color.NRGBA, Opacity float64) color.NRGBA {
+	Color.A = uint8(math.Round(float64(Color.A) * math.Max(0, math.Min(1, Opacity))))
+	return Color
+}
+
+//parseOpacity reads an opacity as a number or percentage, returning 1 for anything else
+func parseOpacity(Value string) float64 {
+	Value = strings.TrimSpace(Value)
+	scale := 1.0
+	if strings.HasSuffix(Value, "%") {
+		Value = strings.TrimSuffix(Value, "%")
+		scale = 0.01
+	}
+	number, err := strconv.ParseFloat(Value, 64)
+	if err != nil {
+		return 1
+	}
+	return math.Max(0, math.Min(1, number*scale))
+}
+
+//svgUnits converts lengths in other units to user units
+var svgUnits = map[string]float64{"px": 1, "pt": 4.0 / 3, "pc": 16, "mm": 96 / 25.4, "cm": 96 / 2.54, "in": 96, "em": 16, "ex": 8}
+
+//parseLength reads a length in user units. Percentages are of Reference
+func parseLength(Value string, Reference float64) float64 {
+	Value = strings.TrimSpace(Value)
+	scale := 1.0
+	if strings.HasSuffix(Value, "%") {
+		Value = strings.TrimSuffix(Value, "%")
+		scale = Reference / 100
+	} else if len(Value) > 2 {
+		if unitScale, exists := svgUnits[Value[len(Value)-2:]]; exists {
+			Value = Value[:len(Value)-2]
+			scale = unitScale
+		}
+	}
+	number, err := strconv.ParseFloat(Value, 64)
+	if err != nil {
+		return 0
+	}
+	return number * scale
+}
+
+//parseNumbers reads a list of numbers separated by white space or commas
+func parseNumbers(Value string) []float64 {
+	scanner := svgNumberScanner{Text: Value}
+	var numbers []float64
+	for true {
+		number, ok := scanner.number()
+		if ok == false {
+			return numbers
+		}
+		numbers = append(numbers, number)
+	}
+	return numbers
+}
+
+//parseTransform reads a transform attribute
+func parseTransform(Value string) svgMatrix {
+	result := svgMatrix{A: 1, D: 1}
+	for true {
+		start, end := strings.Index(Value, "("), strings.Index(Value, ")")
+		if start < 0 || end < start {
+			return result
+		}
+		name := strings.TrimSpace(strings.Trim(strings.TrimSpace(Value[:start]), ","))
+		arguments := parseNumbers(Value[start+1 : end])
+		Value = Value[end+1:]
+		argument := func(Index int, Default float64) float64 {
+			if Index < len(arguments) {
+				return arguments[Index]
+			}
+			return Default
+		}
+		var step svgMatrix
+		switch name {
+		case "matrix":
+			if len(arguments) != 6 {
+				continue
+			}
+			step = svgMatrix{arguments[0], arguments[1], arguments[2], arguments[3], arguments[4], arguments[5]}
+		case "translate":
+			step = svgMatrix{A: 1, D: 1, E: argument(0, 0), F: argument(1, 0)}
+		case "scale":
+			step = svgMatrix{A: argument(0, 1), D: argument(1, argument(0, 1))}
+		case "rotate":
+			angle := argument(0, 0) * math.Pi / 180
+			centerX, centerY := argument(1, 0), argument(2, 0)
+			step = svgMatrix{A: 1, D: 1, E: centerX, F: centerY}.
+				multiply(svgMatrix{A: math.Cos(angle), B: math.Sin(angle), C: -math.Sin(angle), D: math.Cos(angle)}).
+				multiply(svgMatrix{A: 1, D: 1, E: -centerX, F: -centerY})
+		case "skewX":
+			step = svgMatrix{A: 1, C: math.Tan(argument(0, 0) * math.Pi / 180), D: 1}
+		case "skewY":
+			step = svgMatrix{A: 1, B: math.Tan(argument(0, 0) * math.Pi / 180), D: 1}
+		default:
+			continue
+		}
+		result = result.multiply(step)
+	}
+	return result
+}
+
+//multiply returns the transform that applies Inner, then Matrix
+func (Matrix svgMatrix) multiply(Inner svgMatrix) svgMatrix {
+	return svgMatrix{
+		A: Matrix.A*Inner.A + Matrix.C*Inner.B,
+		B: Matrix.B*Inner.A + Matrix.D*Inner.B,
+		C: Matrix.A*Inner.C + Matrix.C*Inner.D,
+		D: Matrix.B*Inner.C + Matrix.D*Inner.D,
+		E: Matrix.A*Inner.E + Matrix.C*Inner.F + Matrix.E,
+		F: Matrix.B*Inner.E + Matrix.D*Inner.F + Matrix.F,
+	}
+}
+
+//apply transforms a point
+func (Matrix svgMatrix) apply(Point svgPoint) svgPoint {
+	return svgPoint{Matrix.A*Point.X + Matrix.C*Point.Y + Matrix.E, Matrix.B*Point.X + Matrix.D*Point.Y + Matrix.F}
+}
+
+//parseStyleSheet reads the rules of a style element. Only single selectors of an element, class, or ID are understood, others are skipped
+func parseStyleSheet(Sheet string) []svgCSSRule {
+	var rules []svgCSSRule
+	for _, block := range strings.Split(Sheet, "}") {
+		parts := strings.SplitN(block, "{", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		declarations := parseDeclarations(parts[1])
+		for _, selector := range strings.Split(parts[0], ",") {
+			selector = strings.TrimSpace(selector)
+			if selector == "" || strings.ContainsAny(selector, " >+~:[") {
+				continue
+			}
+			rules = append(rules, svgCSSRule{Selector: selector, Declarations: declarations})
+		}
+	}
+	return rules
+}
+
+//parseDeclarations reads CSS declarations such as those in a style attribute
+func parseDeclarations(Style string) map[string]string {
+	declarations := make(map[string]string)
+	for _, declaration := range strings.Split(Style, ";") {
+		parts := strings.SplitN(declaration, ":", 2)
+		if len(parts) == 2 {
+			declarations[strings.ToLower(strings.TrimSpace(parts[0]))] = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(parts[1]), "!important"))
+		}
+	}
+	return declarations
+}
+
+//svgNumberScanner reads numbers from path data, where separators are optional wherever a number could not continue
+type svgNumberScanner struct {
+	Text     string
+	Position int
+}
+
+//skipSeparators moves past white space and up to one comma
+func (Scanner *svgNumberScanner) skipSeparators() {
+	comma := false
+	for Scanner.Position < len(Scanner.Text) {
+		character := Scanner.Text[Scanner.Position]
+		if character == ',' && comma == false {
+			comma = true
+		} else if character != ' ' && character != '\t' && character != '\r' && character != '\n' {
+			return
+		}
+		Scanner.Position++
+	}
+}
+
+//number reads the next number, returning false if there is not one
+func (Scanner *svgNumberScanner) number() (float64, bool) {
+	Scanner.skipSeparators()
+	start := Scanner.Position
+	position := start
+	if position < len(Scanner.Text) && (Scanner.Text[position] == '+' || Scanner.Text[position] == '-') {
+		position++
+	}
+	digits, dot := 0, false
+	for position < len(Scanner.Text) {
+		character := Scanner.Text[position]
+		if character >= '0' && character <= '9' {
+			digits++
+		} else if character == '.' && dot == false {
+			dot = true
+		} else {
+			break
+		}
+		position++
+	}
+	if digits == 0 {
+		return 0, false
+	}
+	if position < len(Scanner.Text) && (Scanner.Text[position] == 'e' || Scanner.Text[position] == 'E') {
+		exponent := position + 1
+		if exponent < len(Scanner.Text) && (Scanner.Text[exponent] == '+' || Scanner.Text[exponent] == '-') {
+			exponent++
+		}
+		if exponent < len(Scanner.Text) && Scanner.Text[exponent] >= '0' && Scanner.Text[exponent] <= '9' {
+			for exponent < len(Scanner.Text) && Scanner.Text[exponent] >= '0' && Scanner.Text[exponent] <= '9' {
+				exponent++
+			}
+			position = exponent
+		}
+	}
+	number, err := strconv.ParseFloat(Scanner.Text[start:position], 64)
+	if err != nil {
+		return 0, false
+	}
+	Scanner.Position = position
+	return number, true
+}
+
+//flag reads an arc flag, which may be written without a separator before the next number
+func (Scanner *svgNumberScanner) flag() (bool, bool) {
+	Scanner.skipSeparators()
+	if Scanner.Position < len(Scanner.Text) && (Scanner.Text[Scanner.Position] == '0' || Scanner.Text[Scanner.Position] == '1') {
+		Scanner.Position++
+		return Scanner.Text[Scanner.Position-1] == '1', true
+	}
+	return false, false
+}
+
+//parsePathData reads the d attribute of a path into subpaths, stopping at the first error as browsers do
+//Returns ErrSVGTooComplex if the subpaths would have more than MaxPoints points
+func parsePathData(Data string, MaxPoints int) ([]svgSubpath, error) {
+	scanner := svgNumberScanner{Text: Data}
+	var subpaths []svgSubpath
+	var current svgPoint
+	var start svgPoint
+	//control is the last control point, used by the smooth curve commands
+	var control svgPoint
+	var command, lastCommand byte
+	//Drawing after closing a subpath starts a new one from the same point
+	startSubpath := true
+	points := 0
+	addPoint := func(Point svgPoint) {
+		if startSubpath {
+			subpaths = append(subpaths, svgSubpath{Points: []svgPoint{current}})
+			startSubpath = false
+			points++
+		}
+		points++
+		subpaths[len(subpaths)-1].Points = append(subpaths[len(subpaths)-1].Points, Point)
+		current = Point
+	}
+	for true {
+		//Checked once per command, as the points of one command are few
+		if points > MaxPoints {
+			return nil, ErrSVGTooComplex
+		}
+		scanner.skipSeparators()
+		if scanner.Position >= len(Data) {
+			return subpaths, nil
+		}
+		if character := Data[scanner.Position]; strings.IndexByte("MmLlHhVvCcSsQqTtAaZz", character) >= 0 {
+			command = character
+			scanner.Position++
+		} else if command == 0 {
+			return subpaths, nil
+		}
+		relative := command >= 'a'
+		offset := svgPoint{}
+		if relative {
+			offset = current
+		}
+		numbers := func(Count int) ([]float64, bool) {
+			values := make([]float64, Count)
+			for index := range values {
+				value, ok := scanner.number()
+				if ok == false {
+					return nil, false
+				}
+				values[index] = value
+			}
+			return values, true
+		}
+		point := func(X float64, Y float64) svgPoint {
+			return svgPoint{X + offset.X, Y + offset.Y}
+		}
+		switch command {
+		case 'M', 'm':
+			values, ok := numbers(2)
+			if ok == false {
+				return subpaths, nil
+			}
+			current = point(values[0], values[1])
+			start = current
+			startSubpath = true
+			//Further pairs are lines
+			if relative {
+				command = 'l'
+			} else {
+				command = 'L'
+			}
+		case 'L', 'l':
+			values, ok := numbers(2)
+			if ok == false {
+				return subpaths, nil
+			}
+			addPoint(point(values[0], values[1]))
+		case 'H', 'h':
+			values, ok := numbers(1)
+			if ok == false {
+				return subpaths, nil
+			}
+			addPoint(svgPoint{values[0] + offset.X, current.Y})
+		case 'V', 'v':
+			values, ok := numbers(1)
+			if ok == false {
+				return subpaths, nil
+			}
+			addPoint(svgPoint{current.X, values[0] + offset.Y})
+		case 'C', 'c', 'S', 's':
+			var first, second, end svgPoint
+			if command == 'C' || command == 'c' {
+				values, ok := numbers(6)
+				if ok == false {
+					return subpaths, nil
+				}
+				first, second, end = point(values[0], values[1]), point(values[2], values[3]), point(values[4], values[5])
+			} else {
+				values, ok := numbers(4)
+				if ok == false {
+					return subpaths, nil
+				}
+				first = current
+				if strings.IndexByte("CcSs", lastCommand) >= 0 {
+					first = svgPoint{2*current.X - control.X, 2*current.Y - control.Y}
+				}
+				second, end = point(values[0], values[1]), point(values[2], values[3])
+			}
+			from := current
+			for step := 1; step <= svgCurveSegments; step++ {
+				t := float64(step) / svgCurveSegments
+				u := 1 - t
+				addPoint(svgPoint{
+					u*u*u*from.X + 3*u*u*t*first.X + 3*u*t*t*second.X + t*t*t*end.X,
+					u*u*u*from.Y + 3*u*u*t*first.Y + 3*u*t*t*second.Y + t*t*t*end.Y,
+				})
+			}
+			control = second
+		case 'Q', 'q', 'T', 't':
+			var middle, end svgPoint
+			if command == 'Q' || command == 'q' {
+				values, ok := numbers(4)
+				if ok == false {
+					return subpaths, nil
+				}
+				middle, end = point(values[0], values[1]), point(values[2], values[3])
+			} else {
+				values, ok := numbers(2)
+				if ok == false {
+					return subpaths, nil
+				}
+				middle = current
+				if strings.IndexByte("QqTt", lastCommand) >= 0 {
+					middle = svgPoint{2*current.X - control.X, 2*current.Y - control.Y}
+				}
+				end = point(values[0], values[1])
+			}
+			from := current
+			for step := 1; step <= svgCurveSegments; step++ {
+				t := float64(step) / svgCurveSegments
+				u := 1 - t
+				addPoint(svgPoint{u*u*from.X + 2*u*t*middle.X + t*t*end.X, u*u*from.Y + 2*u*t*middle.Y + t*t*end.Y})
+			}
+			control = middle
+		case 'A', 'a':
+			radii, ok := numbers(3)
+			if ok == false {
+				return subpaths, nil
+			}
+			largeArc, ok := scanner.flag()
+			if ok == false {
+				return subpaths, nil
+			}
+			sweep, ok := scanner.flag()
+			if ok == false {
+				return subpaths, nil
+			}
+			values, ok := numbers(2)
+			if ok == false {
+				return subpaths, nil
+			}
+			for _, arcPoint := range arcPoints(current, radii[0], radii[1], radii[2], largeArc, sweep, point(values[0], values[1])) {
+				addPoint(arcPoint)
+			}
+		case 'Z', 'z':
+			if len(subpaths) > 0 && startSubpath == false {
+				subpaths[len(subpaths)-1].Closed = true
+			}
+			current = start
+			startSubpath = true
+			lastCommand = command
+			command = 0
+			continue
+		}
+		lastCommand = command
+	}
+	return subpaths, nil
+}
+
+//arcPoints returns points along an elliptical arc from From to To, not including From, converting from the endpoint form SVG uses to a center and angles
+func arcPoints(From svgPoint, RadiusX float64, RadiusY float64, Rotation float64, LargeArc bool, Sweep bool, To svgPoint) []svgPoint {
+	RadiusX, RadiusY = math.Abs(RadiusX), math.Abs(RadiusY)
+	if RadiusX == 0 || RadiusY == 0 || From == To {
+		return []svgPoint{To}
+	}
+	angle := Rotation * math.Pi / 180
+	cosAngle, sinAngle := math.Cos(angle), math.Sin(angle)
+	halfX, halfY := (From.X-To.X)/2, (From.Y-To.Y)/2
+	primeX := cosAngle*halfX + sinAngle*halfY
+	primeY := -sinAngle*halfX + cosAngle*halfY
+	//Radii too small to reach are scaled up until they do
+	if lambda := primeX*primeX/(RadiusX*RadiusX) + primeY*primeY/(RadiusY*RadiusY); lambda > 1 {
+		RadiusX *= math.Sqrt(lambda)
+		RadiusY *= math.Sqrt(lambda)
+	}
+	numerator := RadiusX*RadiusX*RadiusY*RadiusY - RadiusX*RadiusX*primeY*primeY - RadiusY*RadiusY*primeX*primeX
+	denominator := RadiusX*RadiusX*primeY*primeY + RadiusY*RadiusY*primeX*primeX
+	coefficient := math.Sqrt(math.Max(0, numerator/denominator))
+	if LargeArc == Sweep {
+		coefficient = -coefficient
+	}
+	centerPrimeX := coefficient * RadiusX * primeY / RadiusY
+	centerPrimeY := -coefficient * RadiusY * primeX / RadiusX
+	centerX := cosAngle*centerPrimeX - sinAngle*centerPrimeY + (From.X+To.X)/2
+	centerY := sinAngle*centerPrimeX + cosAngle*centerPrimeY + (From.Y+To.Y)/2
+	startAngle := math.Atan2((primeY-centerPrimeY)/RadiusY, (primeX-centerPrimeX)/RadiusX)
+	endAngle := math.Atan2((-primeY-centerPrimeY)/RadiusY, (-primeX-centerPrimeX)/RadiusX)
+	sweepAngle := endAngle - startAngle
+	if Sweep && sweepAngle < 0 {
+		sweepAngle += 2 * math.Pi
+	} else if Sweep == false && sweepAngle > 0 {
+		sweepAngle -= 2 * math.Pi
+	}
+	steps := int(math.Ceil(math.Abs(sweepAngle) / (math.Pi / 2) * svgCurveSegments / 2))
+	if steps < 1 {
+		steps = 1
+	}
+	points := make([]svgPoint, 0, steps)
+	for step := 1; step <= steps; step++ {
+		theta := startAngle + sweepAngle*float64(step)/float64(steps)
+		x, y := RadiusX*math.Cos(theta), RadiusY*math.Sin(theta)
+		points = append(points, svgPoint{cosAngle*x - sinAngle*y + centerX, sinAngle*x + cosAngle*y + centerY})
+	}
+	points[len(points)-1] = To
+	return points
+}
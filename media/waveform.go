@@ -0,0 +1,200 @@
+package media
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"math"
+)
+
+//waveformBucketsPerSecond is how finely the loudest points of audio are kept while it is read, before they are fitted to the picture
+const waveformBucketsPerSecond = 100
+
+//waveformColor is what waveforms are drawn in, readable on both the light and dark theme
+var waveformColor = color.NRGBA{0x4A, 0x90, 0xD9, 0xFF}
+
+//Waveform collects the quietest and loudest sample of each part of some audio as it is read, so long files do not need to be held in memory
+type Waveform struct {
+	bucketSize int
+	filled     int
+	low        []int16
+	high       []int16
+}
+
+//NewWaveform returns an empty Waveform for audio with SampleRate samples each second
+func NewWaveform(SampleRate int) *Waveform {
+	bucketSize := SampleRate / waveformBucketsPerSecond
+	if bucketSize < 1 {
+		bucketSize = 1
+	}
+	return &Waveform{bucketSize: bucketSize}
+}
+
+//Add records the next sample of the audio
+func (Wave *Waveform) Add(Sample int16) {
+	if Wave.filled == 0 {
+		Wave.low = append(Wave.low, Sample)
+		Wave.high = append(Wave.high, Sample)
+	}
+	last := len(Wave.low) - 1
+	if Sample < Wave.low[last] {
+		Wave.low[last] = Sample
+	}
+	if Sample > Wave.high[last] {
+		Wave.high[last] = Sample
+	}
+	Wave.filled++
+	if Wave.filled == Wave.bucketSize {
+		Wave.filled = 0
+	}
+}
+
+//Draw returns a picture of the waveform Width by Height pixels, on a transparent background, with a line along the middle where it is silent
+func (Wave *Waveform) Draw(Width uint, Height uint) (*image.NRGBA, error) {
+	if len(Wave.low) == 0 {
+		return nil, errors.New("no audio to draw")
+	}
+	if Width < 1 || Height < 1 {
+		return nil, errors.New("waveforms must be at least one pixel wide and high")
+	}
+	picture := image.NewNRGBA(image.Rect(0, 0, int(Width), int(Height)))
+	middle := float64(Height-1) / 2
+	for x := 0; x < int(Width); x++ {
+		//Each column covers an even share of the buckets, at least one
+		first := x * len(Wave.low) / int(Width)
+		last := (x + 1) * len(Wave.low) / int(Width)
+		if last <= first {
+			last = first + 1
+		}
+		low, high := Wave.low[first], Wave.high[first]
+		for bucket := first + 1; bucket < last; bucket++ {
+			if Wave.low[bucket] < low {
+				low = Wave.low[bucket]
+			}
+			if Wave.high[bucket] > high {
+				high = Wave.high[bucket]
+			}
+		}
+		//Higher samples are drawn nearer the top
+		top := int(math.Floor(middle - float64(high)/32768*middle))
+		bottom := int(math.Ceil(middle - float64(low)/32768*middle))
+		for y := top; y <= bottom; y++ {
+			picture.SetNRGBA(x, y, waveformColor)
+		}
+	}
+	return picture, nil
+}
+
+//WAVFormat describes how the samples of a WAV file are stored
+type WAVFormat struct {
+	//Encoding is 1 for whole numbers and 3 for floating point
+	Encoding      uint16
+	Channels      int
+	SampleRate    int
+	BitsPerSample int
+	//DataSize is the length in bytes of the samples
+	DataSize int64
+}
+
+//Duration returns the length of the audio in seconds
+func (Format WAVFormat) Duration() float64 {
+	return float64(Format.DataSize) / float64(Format.blockSize()) / float64(Format.SampleRate)
+}
+
+//Bitrate returns the bits of samples in each second of audio
+func (Format WAVFormat) Bitrate() uint64 {
+	return uint64(Format.SampleRate * Format.blockSize() * 8)
+}
+
+//blockSize returns the bytes in one sample of every channel
+func (Format WAVFormat) blockSize() int {
+	return Format.Channels * ((Format.BitsPerSample + 7) / 8)
+}
+
+//ReadWAVHeader reads Stream up to the start of its samples, which must be PCM or floating point
+func ReadWAVHeader(Stream io.Reader) (WAVFormat, error) {
+	var format WAVFormat
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(Stream, header); err != nil || string(header[:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return format, ErrMalformed
+	}
+	foundFormat := false
+	for true {
+		if _, err := io.ReadFull(Stream, header[:8]); err != nil {
+			return format, ErrMalformed
+		}
+		length := int64(binary.LittleEndian.Uint32(header[4:8]))
+		switch string(header[:4]) {
+		case "fmt ":
+			if length < 16 || length > 1024 {
+				return format, ErrMalformed
+			}
+			chunk := make([]byte, length+length%2)
+			if _, err := io.ReadFull(Stream, chunk); err != nil {
+				return format, ErrMalformed
+			}
+			format.Encoding = binary.LittleEndian.Uint16(chunk[0:2])
+			format.Channels = int(binary.LittleEndian.Uint16(chunk[2:4]))
+			format.SampleRate = int(binary.LittleEndian.Uint32(chunk[4:8]))
+			format.BitsPerSample = int(binary.LittleEndian.Uint16(chunk[14:16]))
+			//Extensible files give the real encoding at the start of their sub format
+			if format.Encoding == 0xFFFE && length >= 26 {
+				format.Encoding = binary.LittleEndian.Uint16(chunk[24:26])
+			}
+			foundFormat = true
+		case "data":
+			if foundFormat == false {
+				return format, ErrMalformed
+			}
+			if format.Channels < 1 || format.SampleRate < 1 || (format.Encoding != 1 && format.Encoding != 3) ||
+				(format.Encoding == 1 && format.BitsPerSample != 8 && format.BitsPerSample != 16 && format.BitsPerSample != 24 && format.BitsPerSample != 32) ||
+				(format.Encoding == 3 && format.BitsPerSample != 32 && format.BitsPerSample != 64) {
+				return format, errors.New("WAV samples are in an unsupported format")
+			}
+			format.DataSize = length
+			return format, nil
+		default:
+			if _, err := io.CopyN(io.Discard, Stream, length+length%2); err != nil {
+				return format, ErrMalformed
+			}
+		}
+	}
+	return format, ErrMalformed
+}
+
+//ReadWAVSamples adds the samples that follow the header of a WAV file to Wave, with the channels mixed together
+func ReadWAVSamples(Stream io.Reader, Format WAVFormat, Wave *Waveform) error {
+	bytesPerSample := (Format.BitsPerSample + 7) / 8
+	block := make([]byte, Format.blockSize())
+	reader := bufio.NewReader(io.LimitReader(Stream, Format.DataSize))
+	for true {
+		if _, err := io.ReadFull(reader, block); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil //Files cut short are drawn as far as they go
+		} else if err != nil {
+			return err
+		}
+		var mixed float64
+		for channel := 0; channel < Format.Channels; channel++ {
+			sample := block[channel*bytesPerSample : (channel+1)*bytesPerSample]
+			var value float64
+			switch {
+			case Format.Encoding == 3 && bytesPerSample == 4:
+				value = float64(math.Float32frombits(binary.LittleEndian.Uint32(sample)))
+			case Format.Encoding == 3:
+				value = math.Float64frombits(binary.LittleEndian.Uint64(sample))
+			case bytesPerSample == 1:
+				value = (float64(sample[0]) - 128) / 128 //8 bit samples are unsigned
+			default:
+				//Only the two most significant bytes are needed
+				value = float64(int16(binary.LittleEndian.Uint16(sample[bytesPerSample-2:]))) / 32768
+			}
+			mixed += value
+		}
+		mixed = mixed / float64(Format.Channels) * 32768
+		Wave.Add(int16(math.Max(math.Min(mixed, math.MaxInt16), math.MinInt16)))
+	}
+	return nil
+}
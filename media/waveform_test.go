@@ -0,0 +1,106 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+//testWAV returns a WAV file holding Samples, which are written with BitsPerSample bits each
+func testWAV(Channels int, SampleRate int, BitsPerSample int, Samples []int16) []byte {
+	var data []byte
+	for _, sample := range Samples {
+		switch BitsPerSample {
+		case 8:
+			data = append(data, byte(sample>>8)+128)
+		case 24:
+			data = append(data, 0, byte(sample), byte(sample>>8))
+		default:
+			data = binary.LittleEndian.AppendUint16(data, uint16(sample))
+		}
+	}
+	file := []byte("RIFF\x00\x00\x00\x00WAVEfmt \x10\x00\x00\x00")
+	file = binary.LittleEndian.AppendUint16(file, 1)
+	file = binary.LittleEndian.AppendUint16(file, uint16(Channels))
+	file = binary.LittleEndian.AppendUint32(file, uint32(SampleRate))
+	file = binary.LittleEndian.AppendUint32(file, uint32(SampleRate*Channels*BitsPerSample/8))
+	file = binary.LittleEndian.AppendUint16(file, uint16(Channels*BitsPerSample/8))
+	file = binary.LittleEndian.AppendUint16(file, uint16(BitsPerSample))
+	//Chunks that are not understood are skipped
+	file = append(file, "junk\x03\x00\x00\x00abc\x00data"...)
+	file = binary.LittleEndian.AppendUint32(file, uint32(len(data)))
+	file = append(file, data...)
+	binary.LittleEndian.PutUint32(file[4:], uint32(len(file)-8))
+	return file
+}
+
+func TestWAVWaveform(t *testing.T) {
+	//One second of stereo, silent on the left for the first half, then loud on both for the second
+	var samples []int16
+	for index := 0; index < 8000; index++ {
+		right := int16(math.Sin(float64(index)/5) * 16000)
+		left := int16(0)
+		if index >= 4000 {
+			left = right
+		}
+		samples = append(samples, left, right)
+	}
+	for _, bits := range []int{8, 16, 24} {
+		file := testWAV(2, 8000, bits, samples)
+		stream := bytes.NewReader(file)
+		format, err := ReadWAVHeader(stream)
+		if err != nil {
+			t.Fatalf("%d bit: ReadWAVHeader: %v", bits, err)
+		}
+		if format.Duration() != 1 || format.Bitrate() != uint64(8000*2*bits) {
+			t.Errorf("%d bit: %v seconds at %d bits per second", bits, format.Duration(), format.Bitrate())
+		}
+		wave := NewWaveform(format.SampleRate)
+		if err := ReadWAVSamples(stream, format, wave); err != nil {
+			t.Fatalf("%d bit: ReadWAVSamples: %v", bits, err)
+		}
+		picture, err := wave.Draw(100, 41)
+		if err != nil {
+			t.Fatalf("%d bit: Draw: %v", bits, err)
+		}
+		//Columns are filled from the middle out as far as the audio reaches, quieter where the channels were mixed with silence
+		height := func(X int) int {
+			filled := 0
+			for y := 0; y < 41; y++ {
+				if picture.NRGBAAt(X, y).A != 0 {
+					filled++
+				}
+			}
+			return filled
+		}
+		if picture.NRGBAAt(10, 20).A == 0 || picture.NRGBAAt(90, 20).A == 0 || picture.NRGBAAt(10, 0).A != 0 {
+			t.Errorf("%d bit: waveform is not centred", bits)
+		}
+		if quiet, loud := height(10), height(90); quiet < 8 || quiet > 14 || loud < 18 || loud > 24 {
+			t.Errorf("%d bit: waveform is %d pixels high where quiet and %d where loud", bits, quiet, loud)
+		}
+	}
+
+	//Files cut short are drawn as far as they go
+	file := testWAV(1, 8000, 16, make([]int16, 800))
+	stream := bytes.NewReader(file[:len(file)-100])
+	format, err := ReadWAVHeader(stream)
+	if err != nil {
+		t.Fatalf("ReadWAVHeader of a short file: %v", err)
+	}
+	wave := NewWaveform(format.SampleRate)
+	if err := ReadWAVSamples(stream, format, wave); err != nil {
+		t.Errorf("ReadWAVSamples of a short file: %v", err)
+	}
+	if picture, err := wave.Draw(10, 5); err != nil || picture.NRGBAAt(9, 2).A == 0 || picture.NRGBAAt(9, 1).A != 0 {
+		t.Errorf("silence is not drawn as a line: %v", err)
+	}
+
+	if _, err := ReadWAVHeader(bytes.NewReader([]byte("RIFF\x04\x00\x00\x00WAVEdata\x00\x00\x00\x00"))); err == nil {
+		t.Errorf("ReadWAVHeader accepted samples without a format")
+	}
+	if _, err := NewWaveform(8000).Draw(10, 10); err == nil {
+		t.Errorf("Draw drew a waveform with no audio")
+	}
+}
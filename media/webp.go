@@ -0,0 +1,561 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"sort"
+	"time"
+)
+
+//webpTileBits is the log2 size of the square tiles that each pick their own predictor
+const webpTileBits = 4
+
+//webpMinRun is the shortest run of repeated pixels written as a copy rather than one at a time
+const webpMinRun = 3
+
+//webpMaxCopy is the longest copy a single length code can give
+const webpMaxCopy = 4096
+
+//webpPredictorTransform and webpSubtractGreenTransform identify transforms in a VP8L stream
+const (
+	webpPredictorTransform     = 0
+	webpSubtractGreenTransform = 2
+)
+
+//webpPredictors are the predictor modes tried for each tile. Modes that use the top right pixel are left out, as it wraps around at the right edge
+var webpPredictors = []uint32{1, 2, 7, 11, 12}
+
+//webpCodeLengthOrder is the order the lengths of the code length code are written in
+var webpCodeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+//EncodeWebP writes Image to Writer as a lossless WebP
+//Only the predictor and subtract green transforms and copies of repeated pixels are used, which is quick and suits thumbnails
+func EncodeWebP(Writer io.Writer, Image image.Image) error {
+	data, _, err := webpLossless(Image)
+	if err != nil {
+		return err
+	}
+	return writeWebPFile(Writer, webpChunk("VP8L", data))
+}
+
+//EncodeAnimatedWebP writes Frames to Writer as a lossless WebP that loops forever, each frame is shown for the matching entry of Durations
+//Frames are drawn from the top left of a canvas as large as the largest of them
+func EncodeAnimatedWebP(Writer io.Writer, Frames []image.Image, Durations []time.Duration) error {
+	if len(Frames) == 0 || len(Frames) != len(Durations) {
+		return errors.New("an animation needs at least one frame, and a duration for each")
+	}
+	var frameChunks []byte
+	width, height, opaque := 0, 0, true
+	for index, frame := range Frames {
+		data, frameOpaque, err := webpLossless(frame)
+		if err != nil {
+			return err
+		}
+		opaque = opaque && frameOpaque
+		frameWidth, frameHeight := frame.Bounds().Dx(), frame.Bounds().Dy()
+		if frameWidth > width {
+			width = frameWidth
+		}
+		if frameHeight > height {
+			height = frameHeight
+		}
+		duration := Durations[index].Milliseconds()
+		if duration < 0 {
+			duration = 0
+		} else if duration > 0xffffff {
+			duration = 0xffffff
+		}
+		//The offset is left at zero
+		header := make([]byte, 16)
+		putUint24(header[6:9], uint32(frameWidth-1))
+		putUint24(header[9:12], uint32(frameHeight-1))
+		putUint24(header[12:15], uint32(duration))
+		header[15] = 0x02 //Replace what is under the frame rather than blending with it
+		frameChunks = append(frameChunks, webpChunk("ANMF", append(header, webpChunk("VP8L", data)...))...)
+	}
+	extended := make([]byte, 10)
+	extended[0] = 0x02 //Animated
+	if opaque == false {
+		extended[0] |= 0x10
+	}
+	putUint24(extended[4:7], uint32(width-1))
+	putUint24(extended[7:10], uint32(height-1))
+	//A transparent background, and a loop count of zero to loop forever
+	animation := make([]byte, 6)
+	chunks := append(webpChunk("VP8X", extended), webpChunk("ANIM", animation)...)
+	return writeWebPFile(Writer, append(chunks, frameChunks...))
+}
+
+//writeWebPFile writes the RIFF header of a WebP file followed by Chunks
+func writeWebPFile(Writer io.Writer, Chunks []byte) error {
+	header := make([]byte, 12)
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(4+len(Chunks)))
+	copy(header[8:12], "WEBP")
+	if _, err := Writer.Write(header); err != nil {
+		return err
+	}
+	_, err := Writer.Write(Chunks)
+	return err
+}
+
+//webpChunk returns Data as a RIFF chunk, which is padded to an even length
+func webpChunk(FourCC string, Data []byte) []byte {
+	chunk := make([]byte, 8, 8+len(Data)+1)
+	copy(chunk[0:4], FourCC)
+	binary.LittleEndian.PutUint32(chunk[4:8], uint32(len(Data)))
+	chunk = append(chunk, Data...)
+	if len(Data)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+func putUint24(Data []byte, Value uint32) {
+	Data[0], Data[1], Data[2] = byte(Value), byte(Value>>8), byte(Value>>16)
+}
+
+//webpLossless returns Image as a VP8L stream, and whether it is fully opaque
+func webpLossless(Image image.Image) ([]byte, bool, error) {
+	bounds := Image.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 1 || height < 1 || width > 1<<14 || height > 1<<14 {
+		return nil, false, errors.New("WebP images must be from 1 to 16384 pixels wide and high")
+	}
+	//VP8L works on ARGB pixels without premultiplied alpha
+	pixels := make([]uint32, width*height)
+	opaque := true
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			pixel := color.NRGBAModel.Convert(Image.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			pixels[y*width+x] = uint32(pixel.A)<<24 | uint32(pixel.R)<<16 | uint32(pixel.G)<<8 | uint32(pixel.B)
+			if pixel.A != 0xff {
+				opaque = false
+			}
+		}
+	}
+
+	var bits webpBitWriter
+	bits.write(0x2f, 8)
+	bits.write(uint32(width-1), 14)
+	bits.write(uint32(height-1), 14)
+	if opaque {
+		bits.write(0, 1)
+	} else {
+		bits.write(1, 1)
+	}
+	bits.write(0, 3) //Version
+	//Decoders undo transforms in the opposite order they are written
+	bits.write(1, 1)
+	bits.write(webpSubtractGreenTransform, 2)
+	for index, pixel := range pixels {
+		green := pixel >> 8 & 0xff
+		pixels[index] = pixel&0xff00ff00 | (pixel>>16-green)&0xff<<16 | (pixel-green)&0xff
+	}
+	bits.write(1, 1)
+	bits.write(webpPredictorTransform, 2)
+	bits.write(webpTileBits-2, 3)
+	modes, residuals := webpPredict(pixels, width, height)
+	bits.writeImage(modes, false)
+	bits.write(0, 1) //No more transforms
+	bits.writeImage(residuals, true)
+	return bits.bytes(), opaque, nil
+}
+
+//webpPredict returns the predictor mode chosen for each tile, and the difference between each pixel and its prediction
+func webpPredict(Pixels []uint32, Width int, Height int) ([]uint32, []uint32) {
+	tileSize := 1 << webpTileBits
+	tilesWide := (Width + tileSize - 1) >> webpTileBits
+	tilesHigh := (Height + tileSize - 1) >> webpTileBits
+	modes := make([]uint32, tilesWide*tilesHigh)
+	residuals := make([]uint32, len(Pixels))
+	for tileY := 0; tileY < tilesHigh; tileY++ {
+		for tileX := 0; tileX < tilesWide; tileX++ {
+			bestMode, bestCost := webpPredictors[0], -1
+			for _, mode := range webpPredictors {
+				cost := 0
+				for y := tileY * tileSize; y < Height && y < (tileY+1)*tileSize; y++ {
+					for x := tileX * tileSize; x < Width && x < (tileX+1)*tileSize; x++ {
+						residual := webpSubtract(Pixels[y*Width+x], webpPrediction(Pixels, Width, x, y, mode))
+						for shift := uint(0); shift < 32; shift += 8 {
+							if channel := int8(residual >> shift); channel < 0 {
+								cost -= int(channel)
+							} else {
+								cost += int(channel)
+							}
+						}
+					}
+				}
+				if bestCost < 0 || cost < bestCost {
+					bestMode, bestCost = mode, cost
+				}
+			}
+			//The mode is read from the green channel
+			modes[tileY*tilesWide+tileX] = 0xff000000 | bestMode<<8
+			for y := tileY * tileSize; y < Height && y < (tileY+1)*tileSize; y++ {
+				for x := tileX * tileSize; x < Width && x < (tileX+1)*tileSize; x++ {
+					residuals[y*Width+x] = webpSubtract(Pixels[y*Width+x], webpPrediction(Pixels, Width, x, y, bestMode))
+				}
+			}
+		}
+	}
+	return modes, residuals
+}
+
+//webpPrediction returns what Mode predicts the pixel at X, Y to be. The top row and left column always use the pixel beside them
+func webpPrediction(Pixels []uint32, Width int, X int, Y int, Mode uint32) uint32 {
+	switch {
+	case X == 0 && Y == 0:
+		return 0xff000000
+	case Y == 0:
+		return Pixels[X-1]
+	case X == 0:
+		return Pixels[(Y-1)*Width]
+	}
+	left, top, topLeft := Pixels[Y*Width+X-1], Pixels[(Y-1)*Width+X], Pixels[(Y-1)*Width+X-1]
+	switch Mode {
+	case 1:
+		return left
+	case 2:
+		return top
+	case 7:
+		return (left^top)&0xfefefefe>>1 + left&top
+	case 11:
+		//Select whichever of left and top is closer to the gradient estimate
+		leftDistance, topDistance := 0, 0
+		for shift := uint(0); shift < 32; shift += 8 {
+			leftDistance += webpAbs(int(top>>shift&0xff) - int(topLeft>>shift&0xff))
+			topDistance += webpAbs(int(left>>shift&0xff) - int(topLeft>>shift&0xff))
+		}
+		if leftDistance < topDistance {
+			return left
+		}
+		return top
+	default:
+		//Clamped gradient, left + top - top left
+		prediction := uint32(0)
+		for shift := uint(0); shift < 32; shift += 8 {
+			channel := int(left>>shift&0xff) + int(top>>shift&0xff) - int(topLeft>>shift&0xff)
+			if channel < 0 {
+				channel = 0
+			} else if channel > 0xff {
+				channel = 0xff
+			}
+			prediction |= uint32(channel) << shift
+		}
+		return prediction
+	}
+}
+
+//webpSubtract subtracts each channel of B from A, wrapping around
+func webpSubtract(A uint32, B uint32) uint32 {
+	difference := uint32(0)
+	for shift := uint(0); shift < 32; shift += 8 {
+		difference |= (A>>shift - B>>shift) & 0xff << shift
+	}
+	return difference
+}
+
+func webpAbs(Value int) int {
+	if Value < 0 {
+		return -Value
+	}
+	return Value
+}
+
+//webpPrefix splits a copy length or distance into its prefix code and the extra bits that follow it
+func webpPrefix(Value uint32) (uint32, uint32, uint) {
+	Value--
+	if Value < 4 {
+		return Value, 0, 0
+	}
+	highBit := uint(31)
+	for Value>>highBit == 0 {
+		highBit--
+	}
+	extraBits := highBit - 1
+	return uint32(2*highBit) + Value>>extraBits&1, Value & (1<<extraBits - 1), extraBits
+}
+
+//webpBitWriter collects a VP8L stream, which is packed from the lowest bit of each byte
+type webpBitWriter struct {
+	data  []byte
+	bits  uint64
+	nBits uint
+}
+
+func (Writer *webpBitWriter) write(Value uint32, Count uint) {
+	Writer.bits |= uint64(Value) << Writer.nBits
+	Writer.nBits += Count
+	for Writer.nBits >= 8 {
+		Writer.data = append(Writer.data, byte(Writer.bits))
+		Writer.bits >>= 8
+		Writer.nBits -= 8
+	}
+}
+
+func (Writer *webpBitWriter) bytes() []byte {
+	if Writer.nBits > 0 {
+		Writer.data = append(Writer.data, byte(Writer.bits))
+		Writer.bits, Writer.nBits = 0, 0
+	}
+	return Writer.data
+}
+
+//webpToken is a pixel, or a copy of the pixel before it when Length is not zero
+type webpToken struct {
+	Pixel  uint32
+	Length uint32
+}
+
+//writeImage entropy codes Pixels with one set of prefix codes. Main must be set for the image itself, rather than for a transform's tiles
+func (Writer *webpBitWriter) writeImage(Pixels []uint32, Main bool) {
+	Writer.write(0, 1) //No color cache
+	if Main {
+		Writer.write(0, 1) //No meta prefix codes
+	}
+	var tokens []webpToken
+	var green [256 + 24]uint32
+	var red, blue, alpha [256]uint32
+	var distance [40]uint32
+	for index := 0; index < len(Pixels); {
+		run := 0
+		for index > 0 && index+run < len(Pixels) && run < webpMaxCopy && Pixels[index+run] == Pixels[index-1] {
+			run++
+		}
+		if run >= webpMinRun {
+			lengthCode, _, _ := webpPrefix(uint32(run))
+			green[256+lengthCode]++
+			//Distance code 2 is the pixel to the left, its prefix code is 1
+			distance[1]++
+			tokens = append(tokens, webpToken{Length: uint32(run)})
+			index += run
+			continue
+		}
+		pixel := Pixels[index]
+		green[pixel>>8&0xff]++
+		red[pixel>>16&0xff]++
+		blue[pixel&0xff]++
+		alpha[pixel>>24]++
+		tokens = append(tokens, webpToken{Pixel: pixel})
+		index++
+	}
+
+	greenCode := Writer.writePrefixCode(green[:])
+	redCode := Writer.writePrefixCode(red[:])
+	blueCode := Writer.writePrefixCode(blue[:])
+	alphaCode := Writer.writePrefixCode(alpha[:])
+	distanceCode := Writer.writePrefixCode(distance[:])
+	for _, token := range tokens {
+		if token.Length > 0 {
+			lengthCode, extra, extraBits := webpPrefix(token.Length)
+			greenCode.writeSymbol(Writer, 256+lengthCode)
+			Writer.write(extra, extraBits)
+			distanceCode.writeSymbol(Writer, 1)
+			continue
+		}
+		greenCode.writeSymbol(Writer, token.Pixel>>8&0xff)
+		redCode.writeSymbol(Writer, token.Pixel>>16&0xff)
+		blueCode.writeSymbol(Writer, token.Pixel&0xff)
+		alphaCode.writeSymbol(Writer, token.Pixel>>24)
+	}
+}
+
+//webpPrefixCode is a Huffman code, a symbol with a length of zero takes no bits when it is the only one
+type webpPrefixCode struct {
+	lengths []uint8
+	codes   []uint32
+}
+
+func (Code webpPrefixCode) writeSymbol(Writer *webpBitWriter, Symbol uint32) {
+	Writer.write(Code.codes[Symbol], uint(Code.lengths[Symbol]))
+}
+
+//writePrefixCode writes a Huffman code for symbols seen Counts times, and returns it for writing those symbols
+func (Writer *webpBitWriter) writePrefixCode(Counts []uint32) webpPrefixCode {
+	var symbols []uint32
+	for symbol, count := range Counts {
+		if count > 0 {
+			symbols = append(symbols, uint32(symbol))
+		}
+	}
+	code := webpPrefixCode{lengths: make([]uint8, len(Counts)), codes: make([]uint32, len(Counts))}
+	//One or two symbols below 256 fit a simple code
+	if len(symbols) <= 2 && (len(symbols) == 0 || symbols[len(symbols)-1] < 256) {
+		if len(symbols) == 0 {
+			symbols = []uint32{0} //The code is never used, but must still be given
+		}
+		Writer.write(1, 1)
+		Writer.write(uint32(len(symbols)-1), 1)
+		if symbols[0] < 2 {
+			Writer.write(0, 1)
+			Writer.write(symbols[0], 1)
+		} else {
+			Writer.write(1, 1)
+			Writer.write(symbols[0], 8)
+		}
+		if len(symbols) == 2 {
+			Writer.write(symbols[1], 8)
+			code.lengths[symbols[0]], code.lengths[symbols[1]] = 1, 1
+			code.codes[symbols[1]] = 1
+		}
+		return code
+	}
+	code.lengths = webpCodeLengths(Counts, 15)
+	code.codes = webpCodes(code.lengths)
+	Writer.writeCodeLengths(code.lengths)
+	return code
+}
+
+//writeCodeLengths writes the lengths of a normal Huffman code, themselves Huffman coded
+func (Writer *webpBitWriter) writeCodeLengths(Lengths []uint8) {
+	Writer.write(0, 1) //Not a simple code
+	//Runs of zeros are shortened with 17 and 18, other lengths are written as they are
+	var tokens, extras []uint32
+	for index := 0; index < len(Lengths); {
+		if Lengths[index] != 0 {
+			tokens = append(tokens, uint32(Lengths[index]))
+			extras = append(extras, 0)
+			index++
+			continue
+		}
+		run := 1
+		for index+run < len(Lengths) && Lengths[index+run] == 0 && run < 138 {
+			run++
+		}
+		switch {
+		case run >= 11:
+			tokens = append(tokens, 18)
+			extras = append(extras, uint32(run-11))
+		case run >= 3:
+			tokens = append(tokens, 17)
+			extras = append(extras, uint32(run-3))
+		default:
+			run = 1
+			tokens = append(tokens, 0)
+			extras = append(extras, 0)
+		}
+		index += run
+	}
+	var counts [19]uint32
+	for _, token := range tokens {
+		counts[token]++
+	}
+	lengthLengths := webpCodeLengths(counts[:], 7)
+	lengthCodes := webpCodes(lengthLengths)
+	//Trailing unused lengths of the code length code can be left off
+	written := len(webpCodeLengthOrder)
+	for written > 4 && lengthLengths[webpCodeLengthOrder[written-1]] == 0 {
+		written--
+	}
+	Writer.write(uint32(written-4), 4)
+	for _, symbol := range webpCodeLengthOrder[:written] {
+		Writer.write(uint32(lengthLengths[symbol]), 3)
+	}
+	Writer.write(0, 1) //Every length is written, rather than giving how many
+	for index, token := range tokens {
+		Writer.write(lengthCodes[token], uint(lengthLengths[token]))
+		switch token {
+		case 17:
+			Writer.write(extras[index], 3)
+		case 18:
+			Writer.write(extras[index], 7)
+		}
+	}
+}
+
+//webpCodeLengths returns Huffman code lengths no longer than MaxLength for symbols seen Counts times
+//There are always at least two symbols, as decoders reject codes that do not use every bit pattern
+func webpCodeLengths(Counts []uint32, MaxLength uint8) []uint8 {
+	counts := append([]uint32(nil), Counts...)
+	used := 0
+	for _, count := range counts {
+		if count > 0 {
+			used++
+		}
+	}
+	for symbol := 0; used < 2; symbol++ {
+		if counts[symbol] == 0 {
+			counts[symbol] = 1
+			used++
+		}
+	}
+	for true {
+		lengths := webpHuffmanLengths(counts)
+		longest := uint8(0)
+		for _, length := range lengths {
+			if length > longest {
+				longest = length
+			}
+		}
+		if longest <= MaxLength {
+			return lengths
+		}
+		//Evening out the counts makes the tree shallower, until every symbol has the same length
+		for symbol, count := range counts {
+			if count > 0 {
+				counts[symbol] = (count + 1) / 2
+			}
+		}
+	}
+	return nil
+}
+
+//webpHuffmanLengths returns the depth of each symbol in a Huffman tree built from Counts
+func webpHuffmanLengths(Counts []uint32) []uint8 {
+	var weights []uint64
+	var parents, leaves, active []int
+	for symbol, count := range Counts {
+		if count > 0 {
+			active = append(active, len(weights))
+			leaves = append(leaves, symbol)
+			weights = append(weights, uint64(count))
+			parents = append(parents, -1)
+		}
+	}
+	for len(active) > 1 {
+		//Join the two lightest trees
+		sort.SliceStable(active, func(i, j int) bool { return weights[active[i]] < weights[active[j]] })
+		parent := len(weights)
+		weights = append(weights, weights[active[0]]+weights[active[1]])
+		parents = append(parents, -1)
+		parents[active[0]], parents[active[1]] = parent, parent
+		active = append(active[2:], parent)
+	}
+	lengths := make([]uint8, len(Counts))
+	for leaf, symbol := range leaves {
+		for node := leaf; parents[node] != -1; node = parents[node] {
+			lengths[symbol]++
+		}
+	}
+	return lengths
+}
+
+//webpCodes returns the canonical Huffman codes for Lengths, bit reversed as they are read from their first bit
+func webpCodes(Lengths []uint8) []uint32 {
+	var lengthCounts, next [16]uint32
+	for _, length := range Lengths {
+		if length > 0 {
+			lengthCounts[length]++
+		}
+	}
+	code := uint32(0)
+	for length := 1; length < len(next); length++ {
+		code = (code + lengthCounts[length-1]) << 1
+		next[length] = code
+	}
+	codes := make([]uint32, len(Lengths))
+	for symbol, length := range Lengths {
+		if length == 0 {
+			continue
+		}
+		for bit := uint8(0); bit < length; bit++ {
+			codes[symbol] |= (next[length] >> bit & 1) << (length - 1 - bit)
+		}
+		next[length]++
+	}
+	return codes
+}
@@ -0,0 +1,163 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/image/vp8l"
+	"golang.org/x/image/webp"
+)
+
+func TestEncodeWebP(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	tests := []struct {
+		What   string
+		Width  int
+		Height int
+		Pixel  func(X int, Y int) color.NRGBA
+	}{
+		{"single pixel", 1, 1, func(X int, Y int) color.NRGBA { return color.NRGBA{10, 20, 30, 255} }},
+		{"flat", 300, 200, func(X int, Y int) color.NRGBA { return color.NRGBA{200, 100, 50, 255} }},
+		{"gradient", 402, 258, func(X int, Y int) color.NRGBA { return color.NRGBA{uint8(X), uint8(Y), uint8(X + Y), 255} }},
+		{"transparent", 37, 19, func(X int, Y int) color.NRGBA {
+			if (X+Y)%3 == 0 {
+				return color.NRGBA{1, 2, 3, 0}
+			}
+			return color.NRGBA{uint8(X * 7), 128, uint8(Y * 13), uint8(X * Y)}
+		}},
+		{"noise", 64, 48, func(X int, Y int) color.NRGBA {
+			return color.NRGBA{uint8(random.Intn(256)), uint8(random.Intn(256)), uint8(random.Intn(256)), uint8(random.Intn(256))}
+		}},
+		{"stripes", 5000, 3, func(X int, Y int) color.NRGBA { return color.NRGBA{uint8(Y * 100), 0, 0, 255} }},
+	}
+	for _, test := range tests {
+		original := image.NewNRGBA(image.Rect(0, 0, test.Width, test.Height))
+		for y := 0; y < test.Height; y++ {
+			for x := 0; x < test.Width; x++ {
+				original.SetNRGBA(x, y, test.Pixel(x, y))
+			}
+		}
+		var encoded bytes.Buffer
+		if err := EncodeWebP(&encoded, original); err != nil {
+			t.Errorf("%s: EncodeWebP: %v", test.What, err)
+			continue
+		}
+		decoded, err := webp.Decode(bytes.NewReader(encoded.Bytes()))
+		if err != nil {
+			t.Errorf("%s: decoding: %v", test.What, err)
+			continue
+		}
+		if decoded.Bounds() != original.Bounds() {
+			t.Errorf("%s: decoded as %v, want %v", test.What, decoded.Bounds(), original.Bounds())
+			continue
+		}
+	Compare:
+		for y := 0; y < test.Height; y++ {
+			for x := 0; x < test.Width; x++ {
+				if got := color.NRGBAModel.Convert(decoded.At(x, y)); got != original.NRGBAAt(x, y) {
+					t.Errorf("%s: pixel %d,%d decoded as %v, want %v", test.What, x, y, got, original.NRGBAAt(x, y))
+					break Compare
+				}
+			}
+		}
+	}
+
+	//Repeated pixels are copied rather than written out
+	flat := image.NewNRGBA(image.Rect(0, 0, 402, 258))
+	for index := range flat.Pix {
+		flat.Pix[index] = 0xff
+	}
+	var encoded bytes.Buffer
+	if err := EncodeWebP(&encoded, flat); err != nil {
+		t.Fatalf("EncodeWebP: %v", err)
+	}
+	if encoded.Len() > 100 {
+		t.Errorf("a plain white image took %d bytes", encoded.Len())
+	}
+
+	if err := EncodeWebP(&encoded, image.NewNRGBA(image.Rect(0, 0, 1<<14+1, 1))); err == nil {
+		t.Errorf("EncodeWebP accepted an image too wide for WebP")
+	}
+}
+
+func TestEncodeAnimatedWebP(t *testing.T) {
+	var frames []image.Image
+	var durations []time.Duration
+	for index := 0; index < 3; index++ {
+		frame := image.NewNRGBA(image.Rect(0, 0, 5+index, 3))
+		for y := 0; y < 3; y++ {
+			for x := 0; x < 5+index; x++ {
+				frame.SetNRGBA(x, y, color.NRGBA{uint8(index * 80), uint8(x * 20), uint8(y * 40), 255})
+			}
+		}
+		frames = append(frames, frame)
+		durations = append(durations, time.Duration(index+1)*100*time.Millisecond)
+	}
+	frames[1].(*image.NRGBA).SetNRGBA(0, 0, color.NRGBA{1, 2, 3, 4})
+	var encoded bytes.Buffer
+	if err := EncodeAnimatedWebP(&encoded, frames, durations); err != nil {
+		t.Fatalf("EncodeAnimatedWebP: %v", err)
+	}
+	data := encoded.Bytes()
+	if string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" || int(binary.LittleEndian.Uint32(data[4:8])) != len(data)-8 {
+		t.Fatalf("bad RIFF header %q", data[:12])
+	}
+
+	var chunks []string
+	frame := 0
+	for offset := 12; offset < len(data); {
+		fourCC, size := string(data[offset:offset+4]), int(binary.LittleEndian.Uint32(data[offset+4:offset+8]))
+		chunk := data[offset+8 : offset+8+size]
+		chunks = append(chunks, fourCC)
+		switch fourCC {
+		case "VP8X":
+			if chunk[0] != 0x12 || readUint24(chunk[4:7]) != 6 || readUint24(chunk[7:10]) != 2 {
+				t.Errorf("VP8X chunk %v, want an animation with alpha on a 7x3 canvas", chunk)
+			}
+		case "ANMF":
+			width, height := readUint24(chunk[6:9])+1, readUint24(chunk[9:12])+1
+			if width != 5+frame || height != 3 || readUint24(chunk[12:15]) != (frame+1)*100 {
+				t.Errorf("frame %d is %dx%d for %dms", frame, width, height, readUint24(chunk[12:15]))
+			}
+			if string(chunk[16:20]) != "VP8L" {
+				t.Errorf("frame %d holds a %q chunk", frame, chunk[16:20])
+				break
+			}
+			decoded, err := vp8l.Decode(bytes.NewReader(chunk[24:]))
+			if err != nil {
+				t.Errorf("frame %d: decoding: %v", frame, err)
+				break
+			}
+			if decoded.Bounds() != frames[frame].Bounds() {
+				t.Errorf("frame %d decoded as %v", frame, decoded.Bounds())
+				break
+			}
+			for y := 0; y < 3; y++ {
+				for x := 0; x < width; x++ {
+					if got, want := color.NRGBAModel.Convert(decoded.At(x, y)), frames[frame].At(x, y); got != want {
+						t.Errorf("frame %d: pixel %d,%d decoded as %v, want %v", frame, x, y, got, want)
+					}
+				}
+			}
+			frame++
+		}
+		offset += 8 + size + size%2
+	}
+	if strings.Join(chunks, " ") != "VP8X ANIM ANMF ANMF ANMF" {
+		t.Errorf("chunks are %v", chunks)
+	}
+
+	if err := EncodeAnimatedWebP(&encoded, frames, durations[:2]); err == nil {
+		t.Errorf("EncodeAnimatedWebP accepted frames without durations")
+	}
+}
+
+func readUint24(Data []byte) int {
+	return int(Data[0]) | int(Data[1])<<8 | int(Data[2])<<16
+}
@@ -0,0 +1,170 @@
+package media
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"unicode"
+)
+
+//ZipLimits bounds what ReadZip will unpack, so a small upload cannot expand to fill memory
+type ZipLimits struct {
+	//MaxEntries is the most files an archive may hold, not counting directories
+	MaxEntries int
+	//MaxBytes is the most the files of an archive may add up to once unpacked
+	MaxBytes int64
+	//MaxRatio is how many times larger than its compressed size a file may be, for files over ratioFloor
+	MaxRatio int64
+}
+
+//ratioFloor is the size below which files are not held to MaxRatio, as small files of text or blank images can compress very well
+const ratioFloor = 1 << 20
+
+//ZipEntry is one file unpacked from an archive
+type ZipEntry struct {
+	//Name is the path of the file within the archive, using forward slashes
+	Name string
+	Data []byte
+}
+
+//ErrZipTooLarge is returned by ReadZip when an archive breaks its limits
+var ErrZipTooLarge = errors.New("archive is too large once unpacked")
+
+//ErrZipUnsafePath is returned by ReadZip when an archive holds a path that would leave the directory it is unpacked in
+var ErrZipUnsafePath = errors.New("archive holds an unsafe path")
+
+//IsZip returns whether Header is the start of a zip archive, which includes cbz comic books
+func IsZip(Header []byte) bool {
+	return bytes.HasPrefix(Header, []byte("PK\x03\x04")) || bytes.HasPrefix(Header, []byte("PK\x05\x06"))
+}
+
+//ReadZip unpacks the files of the zip archive in Archive, skipping directories and the hidden files and metadata archivers add
+//Every file is checked against Limits as it is read, as the sizes an archive claims may be false
+func ReadZip(Archive io.ReaderAt, Size int64, Limits ZipLimits) ([]ZipEntry, error) {
+	reader, err := zip.NewReader(Archive, Size)
+	if err == zip.ErrInsecurePath {
+		return nil, ErrZipUnsafePath
+	} else if err != nil {
+		return nil, err
+	}
+	var ToReturn []ZipEntry
+	remaining := Limits.MaxBytes
+	for _, file := range reader.File {
+		if isUnsafeZipPath(file.Name) {
+			return nil, ErrZipUnsafePath
+		}
+		if file.FileInfo().IsDir() || isZipClutter(file.Name) {
+			continue
+		}
+		if len(ToReturn) >= Limits.MaxEntries {
+			return nil, ErrZipTooLarge
+		}
+		//Claimed sizes are checked first so obvious bombs are refused before anything is unpacked
+		if file.UncompressedSize64 > uint64(remaining) {
+			return nil, ErrZipTooLarge
+		}
+		data, err := readZipFile(file, remaining)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > ratioFloor && int64(len(data)) > Limits.MaxRatio*int64(file.CompressedSize64) {
+			return nil, ErrZipTooLarge
+		}
+		remaining -= int64(len(data))
+		ToReturn = append(ToReturn, ZipEntry{Name: file.Name, Data: data})
+	}
+	return ToReturn, nil
+}
+
+//readZipFile returns the content of one file of an archive, or ErrZipTooLarge if it is more than Remaining bytes
+func readZipFile(File *zip.File, Remaining int64) ([]byte, error) {
+	fileReader, err := File.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer fileReader.Close()
+	data, err := io.ReadAll(io.LimitReader(fileReader, Remaining+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > Remaining {
+		return nil, ErrZipTooLarge
+	}
+	return data, nil
+}
+
+//isUnsafeZipPath returns whether Name is absolute or climbs out of the archive, such as ../../etc/passwd
+func isUnsafeZipPath(Name string) bool {
+	Name = strings.ReplaceAll(Name, "\\", "/")
+	if strings.HasPrefix(Name, "/") || (len(Name) >= 2 && Name[1] == ':') {
+		return true
+	}
+	for _, part := range strings.Split(Name, "/") {
+		if part == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+//isZipClutter returns whether Name is a hidden file, or metadata added by an archiver, comic book tool, or collection download rather than a page
+func isZipClutter(Name string) bool {
+	for _, part := range strings.Split(Name, "/") {
+		if strings.HasPrefix(part, ".") || part == "__MACOSX" {
+			return true
+		}
+	}
+	switch strings.ToLower(path.Base(Name)) {
+	case "thumbs.db", "desktop.ini", "comicinfo.xml", "collection.json":
+		return true
+	}
+	return false
+}
+
+//NaturalLess returns whether A sorts before B with runs of digits compared by value and letters compared without case, so page2 comes before page10
+func NaturalLess(A string, B string) bool {
+	a, b := []rune(A), []rune(B)
+	for len(a) > 0 && len(b) > 0 {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			aDigits, bDigits := leadingDigits(a), leadingDigits(b)
+			aValue := strings.TrimLeft(string(a[:aDigits]), "0")
+			bValue := strings.TrimLeft(string(b[:bDigits]), "0")
+			//Without leading zeros, the longer run is the larger number
+			if len(aValue) != len(bValue) {
+				return len(aValue) < len(bValue)
+			}
+			if aValue != bValue {
+				return aValue < bValue
+			}
+			a, b = a[aDigits:], b[bDigits:]
+			continue
+		}
+		aLower, bLower := unicode.ToLower(a[0]), unicode.ToLower(b[0])
+		if aLower != bLower {
+			return aLower < bLower
+		}
+		a, b = a[1:], b[1:]
+	}
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	//Names that only differ by case or leading zeros still need an order
+	return A < B
+}
+
+//isDigit returns whether Character is 0 to 9, other scripts' digits are compared like letters
+func isDigit(Character rune) bool {
+	return Character >= '0' && Character <= '9'
+}
+
+//leadingDigits returns how many digits Value starts with
+func leadingDigits(Value []rune) int {
+	count := 0
+	for count < len(Value) && isDigit(Value[count]) {
+		count++
+	}
+	return count
+}
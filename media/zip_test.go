@@ -0,0 +1,91 @@
+package media
+
+import (
+	"archive/zip"
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+)
+
+//testZip returns an archive holding Files, in the order given
+func testZip(t *testing.T, Files ...ZipEntry) *bytes.Reader {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for _, file := range Files {
+		fileWriter, err := writer.Create(file.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fileWriter.Write(file.Data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buffer.Bytes())
+}
+
+var testZipLimits = ZipLimits{MaxEntries: 3, MaxBytes: 4 << 20, MaxRatio: 100}
+
+func TestReadZip(t *testing.T) {
+	archive := testZip(t,
+		ZipEntry{Name: "comic/", Data: nil},
+		ZipEntry{Name: "comic/page2.png", Data: []byte("two")},
+		ZipEntry{Name: "comic/page10.png", Data: []byte("ten")},
+		ZipEntry{Name: "__MACOSX/comic/._page2.png", Data: []byte("resource fork")},
+		ZipEntry{Name: "comic/.DS_Store", Data: []byte("finder")},
+		ZipEntry{Name: "ComicInfo.xml", Data: []byte("<ComicInfo/>")},
+	)
+	if IsZip(make([]byte, 4)) {
+		t.Errorf("IsZip accepted zeros")
+	}
+	header := make([]byte, 4)
+	archive.ReadAt(header, 0)
+	if IsZip(header) == false {
+		t.Errorf("IsZip did not accept an archive")
+	}
+	entries, err := ReadZip(archive, archive.Size(), testZipLimits)
+	if err != nil || len(entries) != 2 || entries[0].Name != "comic/page2.png" || string(entries[0].Data) != "two" || entries[1].Name != "comic/page10.png" {
+		t.Errorf("ReadZip = %+v, %v", entries, err)
+	}
+}
+
+func TestReadZipLimits(t *testing.T) {
+	tests := []struct {
+		What  string
+		Files []ZipEntry
+		Err   error
+	}{
+		{"climbing path", []ZipEntry{{Name: "../../evil.png", Data: []byte("evil")}}, ErrZipUnsafePath},
+		{"backslash path", []ZipEntry{{Name: "pages\\..\\..\\evil.png", Data: []byte("evil")}}, ErrZipUnsafePath},
+		{"absolute path", []ZipEntry{{Name: "/etc/evil.png", Data: []byte("evil")}}, ErrZipUnsafePath},
+		{"too many files", []ZipEntry{{Name: "1.png"}, {Name: "2.png"}, {Name: "3.png"}, {Name: "4.png"}}, ErrZipTooLarge},
+		{"too large", []ZipEntry{{Name: "1.png", Data: bytes.Repeat([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9}, 300000)}, {Name: "2.png", Data: bytes.Repeat([]byte{9, 8, 7, 6, 5, 4, 3, 2, 1}, 300000)}}, ErrZipTooLarge},
+		{"bomb", []ZipEntry{{Name: "zeros.png", Data: make([]byte, 3<<20)}}, ErrZipTooLarge},
+	}
+	for _, test := range tests {
+		archive := testZip(t, test.Files...)
+		if entries, err := ReadZip(archive, archive.Size(), testZipLimits); err != test.Err {
+			t.Errorf("%s: ReadZip = %d entries, %v, expected %v", test.What, len(entries), err, test.Err)
+		}
+	}
+	//Small files that compress well are fine
+	archive := testZip(t, ZipEntry{Name: "blank.svg", Data: []byte(strings.Repeat(" ", 100000))})
+	if entries, err := ReadZip(archive, archive.Size(), testZipLimits); err != nil || len(entries) != 1 {
+		t.Errorf("ReadZip of a small file that compresses well = %+v, %v", entries, err)
+	}
+}
+
+func TestNaturalLess(t *testing.T) {
+	names := []string{"page10.png", "Page2.png", "page1.png", "page02b.png", "chapter 2/01.png", "chapter 10/01.png", "page.png", "page1.png.png"}
+	sort.SliceStable(names, func(i, j int) bool {
+		return NaturalLess(names[i], names[j])
+	})
+	expected := []string{"chapter 2/01.png", "chapter 10/01.png", "page.png", "page1.png", "page1.png.png", "Page2.png", "page02b.png", "page10.png"}
+	if strings.Join(names, "|") != strings.Join(expected, "|") {
+		t.Errorf("NaturalLess sorted %q, expected %q", names, expected)
+	}
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"os"
+	"strconv"
+)
+
+//boardCounts holds how many of each kind of record a database has, to verify a migration
+type boardCounts struct {
+	Users             uint64
+	Tags              uint64
+	Images            uint64
+	ImagedHashes      uint64
+	ImageMetadata     uint64
+	ImageColors       uint64
+	ImageTags         uint64
+	Votes             uint64
+	Collections       uint64
+	CollectionMembers uint64
+	AuditLogs         uint64
+}
+
+//String lists the counts for logging
+func (Counts boardCounts) String() string {
+	return "users: " + strconv.FormatUint(Counts.Users, 10) +
+		" tags: " + strconv.FormatUint(Counts.Tags, 10) +
+		" images: " + strconv.FormatUint(Counts.Images, 10) +
+		" dhashes: " + strconv.FormatUint(Counts.ImagedHashes, 10) +
+		" image metadata: " + strconv.FormatUint(Counts.ImageMetadata, 10) +
+		" image colors: " + strconv.FormatUint(Counts.ImageColors, 10) +
+		" image tags: " + strconv.FormatUint(Counts.ImageTags, 10) +
+		" votes: " + strconv.FormatUint(Counts.Votes, 10) +
+		" collections: " + strconv.FormatUint(Counts.Collections, 10) +
+		" collection members: " + strconv.FormatUint(Counts.CollectionMembers, 10) +
+		" audit logs: " + strconv.FormatUint(Counts.AuditLogs, 10)
+}
+
+//migrateDatabase copies everything from the running database to the empty one described by the configuration file at TargetConfigPath
+//IDs, times, linkers, collection order, dHashes, blurhashes, colours, and image metadata are kept, and the record counts of both are compared once done
+//Image files are not touched, as images keep their locations
+func migrateDatabase(TargetConfigPath string) {
+	logging.WriteLog(logging.LogLevelInfo, "migrateUtility/migrateDatabase", "0", logging.ResultInfo, []string{"Migrating database to the one configured in", TargetConfigPath})
+	target, err := openMigrationTarget(TargetConfigPath)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "migrateUtility/migrateDatabase", "0", logging.ResultFailure, []string{"Failed to open target database", err.Error()})
+		return
+	}
+	counts, err := copyDatabase(database.DBInterface, target)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "migrateUtility/migrateDatabase", "0", logging.ResultFailure, []string{"Migration failed, the target database should be recreated before trying again", err.Error()})
+		return
+	}
+	logging.WriteLog(logging.LogLevelInfo, "migrateUtility/migrateDatabase", "0", logging.ResultSuccess, []string{"Finished migration, counts match.", counts.String()})
+}
+
+//openMigrationTarget connects to the database described by the configuration file at Path
+//Plugins read their connection settings from config.Configuration as they initialize, so the target's are swapped in until then
+func openMigrationTarget(Path string) (interfaces.DBInterface, error) {
+	file, err := os.Open(Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var targetSettings config.ConfigurationSettings
+	if err := json.NewDecoder(file).Decode(&targetSettings); err != nil {
+		return nil, errors.New("failed to read target configuration, " + err.Error())
+	}
+	if targetSettings.DBPlugin == "" {
+		targetSettings.DBPlugin = "mariadb"
+	}
+	if targetSettings.DBPlugin == config.Configuration.DBPlugin && targetSettings.DBPath == config.Configuration.DBPath && targetSettings.DBHost == config.Configuration.DBHost && targetSettings.DBPort == config.Configuration.DBPort && targetSettings.DBName == config.Configuration.DBName && targetSettings.DBPlugin != "memory" {
+		return nil, errors.New("the target is the running database")
+	}
+
+	runningSettings := config.Configuration
+	defer func() { config.Configuration = runningSettings }()
+	config.Configuration.DBPlugin = targetSettings.DBPlugin
+	config.Configuration.DBPath = targetSettings.DBPath
+	config.Configuration.DBName = targetSettings.DBName
+	config.Configuration.DBUser = targetSettings.DBUser
+	config.Configuration.DBPassword = targetSettings.DBPassword
+	config.Configuration.DBPort = targetSettings.DBPort
+	config.Configuration.DBHost = targetSettings.DBHost
+	config.Configuration.DBSSLMode = targetSettings.DBSSLMode
+	if missingDatabaseConfig() {
+		return nil, errors.New("target configuration is missing database information. (Plugin, Instance, User, Password, Path?)")
+	}
+	target := newDatabasePlugin(config.Configuration.DBPlugin)
+	if err := target.InitDatabase(); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+//copyDatabase copies every record from Source into the empty Target, and returns the counts once they are verified to match
+func copyDatabase(Source interfaces.DBInterface, Target interfaces.DBInterface) (boardCounts, error) {
+	sourceCounts, err := countBoard(Source)
+	if err != nil {
+		return sourceCounts, err
+	}
+	if err := checkBoardEmpty(Target); err != nil {
+		return sourceCounts, err
+	}
+
+	for count, maxCount := uint64(0), uint64(1); count < maxCount; count += config.Configuration.PageStride {
+		var users []interfaces.UserBackup
+		users, maxCount, err = Source.GetUserBackups(count, config.Configuration.PageStride)
+		if err != nil {
+			return sourceCounts, err
+		}
+		for _, user := range users {
+			if err := Target.RestoreUser(user); err != nil {
+				return sourceCounts, err
+			}
+		}
+	}
+
+	tags, err := Source.GetAllTags()
+	if err != nil {
+		return sourceCounts, err
+	}
+	for _, tag := range tags {
+		//GetAllTags does not include the uploader or alias
+		tagInfo, err := Source.GetTag(tag.ID, false)
+		if err != nil {
+			return sourceCounts, err
+		}
+		if err := Target.RestoreTag(tagInfo); err != nil {
+			return sourceCounts, err
+		}
+	}
+
+	err = forEachImage(Source, func(imageInfo interfaces.ImageInformation) error {
+		//Search results do not include the description
+		imageInfo, err := Source.GetImage(imageInfo.ID)
+		if err != nil {
+			return err
+		}
+		if err := Target.RestoreImage(imageInfo); err != nil {
+			return err
+		}
+		//Images without a dHash or metadata have no row, anything else is a failure to read and must not be skipped
+		if hHash, vHash, err := Source.GetImagedHash(imageInfo.ID); err == nil {
+			if err := Target.SetImagedHash(imageInfo.ID, hHash, vHash); err != nil {
+				return err
+			}
+		} else if err != sql.ErrNoRows {
+			return err
+		}
+		if metadata, err := Source.GetImageMetadata(imageInfo.ID); err == nil {
+			if err := Target.SetImageMetadata(metadata); err != nil {
+				return err
+			}
+		} else if err != sql.ErrNoRows {
+			return err
+		}
+		colors, err := Source.GetImageColors(imageInfo.ID)
+		if err != nil {
+			return err
+		}
+		if len(colors) > 0 {
+			if err := Target.SetImageColors(imageInfo.ID, imageInfo.BlurHash, colors); err != nil {
+				return err
+			}
+		}
+		links, err := Source.GetImageTagLinks(imageInfo.ID)
+		if err != nil {
+			return err
+		}
+		if err := Target.RestoreImageTags(links); err != nil {
+			return err
+		}
+		votes, err := Source.GetImageVotes(imageInfo.ID)
+		if err != nil {
+			return err
+		}
+		for _, vote := range votes {
+			if err := Target.RestoreImageVote(vote); err != nil {
+				return err
+			}
+		}
+		if len(votes) > 0 {
+			return Target.UpdateScoreOnImage(imageInfo.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return sourceCounts, err
+	}
+
+	//Collection tags are added by the target as members are restored
+	err = forEachCollection(Source, func(collectionInfo interfaces.CollectionInformation) error {
+		//Collection lists do not include the uploader
+		collectionInfo, err := Source.GetCollection(collectionInfo.ID)
+		if err != nil {
+			return err
+		}
+		if err := Target.RestoreCollection(collectionInfo); err != nil {
+			return err
+		}
+		links, err := Source.GetCollectionMemberLinks(collectionInfo.ID)
+		if err != nil {
+			return err
+		}
+		return Target.RestoreCollectionMembers(links)
+	})
+	if err != nil {
+		return sourceCounts, err
+	}
+
+	for count, maxCount := uint64(0), uint64(1); count < maxCount; count += config.Configuration.PageStride {
+		var auditLogs []interfaces.AuditLogInformation
+		auditLogs, maxCount, err = Source.GetAuditLogs(count, config.Configuration.PageStride)
+		if err != nil {
+			return sourceCounts, err
+		}
+		for _, auditLog := range auditLogs {
+			if err := Target.RestoreAuditLog(auditLog); err != nil {
+				return sourceCounts, err
+			}
+		}
+	}
+
+	targetCounts, err := countBoard(Target)
+	if err != nil {
+		return sourceCounts, err
+	}
+	if targetCounts != sourceCounts {
+		return sourceCounts, errors.New("counts do not match, source has " + sourceCounts.String() + ", target has " + targetCounts.String())
+	}
+	return sourceCounts, nil
+}
+
+//countBoard counts the records in DB
+func countBoard(DB interfaces.DBInterface) (boardCounts, error) {
+	var ToReturn boardCounts
+	var err error
+	if _, ToReturn.Users, err = DB.GetUserBackups(0, 1); err != nil {
+		return ToReturn, err
+	}
+	tags, err := DB.GetAllTags()
+	if err != nil {
+		return ToReturn, err
+	}
+	ToReturn.Tags = uint64(len(tags))
+	if _, ToReturn.AuditLogs, err = DB.GetAuditLogs(0, 1); err != nil {
+		return ToReturn, err
+	}
+	err = forEachImage(DB, func(imageInfo interfaces.ImageInformation) error {
+		ToReturn.Images++
+		if _, _, err := DB.GetImagedHash(imageInfo.ID); err == nil {
+			ToReturn.ImagedHashes++
+		} else if err != sql.ErrNoRows {
+			return err
+		}
+		if _, err := DB.GetImageMetadata(imageInfo.ID); err == nil {
+			ToReturn.ImageMetadata++
+		} else if err != sql.ErrNoRows {
+			return err
+		}
+		colors, err := DB.GetImageColors(imageInfo.ID)
+		if err != nil {
+			return err
+		}
+		ToReturn.ImageColors += uint64(len(colors))
+		links, err := DB.GetImageTagLinks(imageInfo.ID)
+		if err != nil {
+			return err
+		}
+		ToReturn.ImageTags += uint64(len(links))
+		votes, err := DB.GetImageVotes(imageInfo.ID)
+		ToReturn.Votes += uint64(len(votes))
+		return err
+	})
+	if err != nil {
+		return ToReturn, err
+	}
+	err = forEachCollection(DB, func(collectionInfo interfaces.CollectionInformation) error {
+		ToReturn.Collections++
+		links, err := DB.GetCollectionMemberLinks(collectionInfo.ID)
+		ToReturn.CollectionMembers += uint64(len(links))
+		return err
+	})
+	return ToReturn, err
+}
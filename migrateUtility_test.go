@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/jobs"
+	"go-image-board/plugins/memoryplugin"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMigrateDatabase(t *testing.T) {
+	setupImportTest(t)
+	importPath := t.TempDir()
+	writeTestFile(t, importPath, "a.png", testPNG(t, 10))
+	writeTestFile(t, importPath, "a.png.txt", []byte("red blue\nsource: https://example.com/a\nrating: safe\n"))
+	writeTestFile(t, importPath, "set/1.png", testPNG(t, 20))
+	writeTestFile(t, importPath, "set/1.png.txt", []byte("night\ncollection: Night Set\n"))
+	writeTestFile(t, importPath, "set/2.png", testPNG(t, 30))
+	writeTestFile(t, importPath, "set/2.png.txt", []byte("night moon\ncollection: Night Set\n"))
+	importDirectory(importPath, "importer", filepath.Join(t.TempDir(), "progress.tsv"), false)
+
+	source := database.DBInterface
+	userID, err := source.GetUserID("importer")
+	if err != nil {
+		t.Fatalf("GetUserID: %v", err)
+	}
+	first, err := source.GetImageByFileName(hashName(t, testPNG(t, 10)))
+	if err != nil {
+		t.Fatalf("GetImageByFileName: %v", err)
+	}
+	if err := source.UpdateUserVoteScore(userID, first.ID, 4); err != nil {
+		t.Fatalf("UpdateUserVoteScore: %v", err)
+	}
+	if err := source.SetImageMetadata(interfaces.ImageMetadata{ImageID: first.ID, CameraModel: "Model 1", Orientation: 6}); err != nil {
+		t.Fatalf("SetImageMetadata: %v", err)
+	}
+	collection, err := source.GetCollectionByName("Night Set")
+	if err != nil {
+		t.Fatalf("GetCollectionByName: %v", err)
+	}
+	sourceMembers, err := source.GetCollectionMemberLinks(collection.ID)
+	if err != nil || len(sourceMembers) != 2 {
+		t.Fatalf("GetCollectionMemberLinks: %+v, %v", sourceMembers, err)
+	}
+	//Reversing the order checks OrderWeight is copied rather than recreated
+	if err := source.UpdateCollectionMember(collection.ID, sourceMembers[1].ImageID, 0); err != nil {
+		t.Fatalf("UpdateCollectionMember: %v", err)
+	}
+	sourceMembers, _ = source.GetCollectionMemberLinks(collection.ID)
+	sourceTags, err := source.GetImageTagLinks(first.ID)
+	if err != nil || len(sourceTags) != 2 {
+		t.Fatalf("GetImageTagLinks: %+v, %v", sourceTags, err)
+	}
+	sourceHHash, sourceVHash, err := source.GetImagedHash(first.ID)
+	if err != nil {
+		t.Fatalf("GetImagedHash: %v", err)
+	}
+	sourceColors, err := source.GetImageColors(first.ID)
+	if err != nil || len(sourceColors) == 0 || first.BlurHash == "" {
+		t.Fatalf("GetImageColors: %+v, %q, %v", sourceColors, first.BlurHash, err)
+	}
+
+	targetConfigPath := filepath.Join(t.TempDir(), "target.json")
+	targetSettings, err := json.Marshal(config.ConfigurationSettings{DBPlugin: "sqlite", DBPath: filepath.Join(t.TempDir(), "target.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(targetConfigPath, targetSettings, 0660); err != nil {
+		t.Fatal(err)
+	}
+	migrateDatabase(targetConfigPath)
+	if database.DBInterface != source || config.Configuration.DBPlugin != "" {
+		t.Fatalf("migration changed the running database or its configuration")
+	}
+
+	target, err := openMigrationTarget(targetConfigPath)
+	if err != nil {
+		t.Fatalf("openMigrationTarget: %v", err)
+	}
+	sourceCounts, err := countBoard(source)
+	if err != nil {
+		t.Fatalf("countBoard of source: %v", err)
+	}
+	targetCounts, err := countBoard(target)
+	if err != nil || targetCounts != sourceCounts || targetCounts.Images != 3 || targetCounts.CollectionMembers != 2 {
+		t.Fatalf("counts after migration: source %s, target %s, %v", sourceCounts, targetCounts, err)
+	}
+
+	migrated, err := target.GetImage(first.ID)
+	if err != nil {
+		t.Fatalf("GetImage of migrated image: %v", err)
+	}
+	if migrated.Location != first.Location || migrated.Source != first.Source || migrated.UploadTime.Unix() != first.UploadTime.Unix() || migrated.ScoreTotal != 4 {
+		t.Errorf("migrated image %+v, source %+v", migrated, first)
+	}
+	if hHash, vHash, err := target.GetImagedHash(first.ID); err != nil || hHash != sourceHHash || vHash != sourceVHash {
+		t.Errorf("migrated dHash: %d %d, %v", hHash, vHash, err)
+	}
+	if colors, err := target.GetImageColors(first.ID); err != nil || reflect.DeepEqual(colors, sourceColors) == false || migrated.BlurHash != first.BlurHash {
+		t.Errorf("migrated colors: %+v, %q, %v", colors, migrated.BlurHash, err)
+	}
+	if metadata, err := target.GetImageMetadata(first.ID); err != nil || metadata.CameraModel != "Model 1" || metadata.Orientation != 6 {
+		t.Errorf("migrated metadata: %+v, %v", metadata, err)
+	}
+	targetTags, err := target.GetImageTagLinks(first.ID)
+	if err != nil || len(targetTags) != len(sourceTags) {
+		t.Fatalf("migrated image tags: %+v, %v", targetTags, err)
+	}
+	for index, link := range targetTags {
+		if link.TagID != sourceTags[index].TagID || link.LinkerID != userID || link.LinkTime.Unix() != sourceTags[index].LinkTime.Unix() {
+			t.Errorf("migrated image tag %+v, source %+v", link, sourceTags[index])
+		}
+	}
+	targetMembers, err := target.GetCollectionMemberLinks(collection.ID)
+	if err != nil || len(targetMembers) != len(sourceMembers) {
+		t.Fatalf("migrated collection members: %+v, %v", targetMembers, err)
+	}
+	for index, link := range targetMembers {
+		if link.ImageID != sourceMembers[index].ImageID || link.OrderWeight != sourceMembers[index].OrderWeight || link.LinkerID != userID {
+			t.Errorf("migrated collection member %+v, source %+v", link, sourceMembers[index])
+		}
+	}
+	if err := target.ValidateUser("importer", []byte("password")); err != nil {
+		t.Errorf("ValidateUser after migration: %v", err)
+	}
+
+	//A second migration into the now full target is refused
+	migrateDatabase(targetConfigPath)
+	if counts, err := countBoard(target); err != nil || counts != sourceCounts {
+		t.Errorf("second migration changed the target: %s, %v", counts, err)
+	}
+}
+
+//failingMetadataDB is a database whose image metadata cannot be read, as if the connection dropped
+type failingMetadataDB struct {
+	interfaces.DBInterface
+}
+
+func (DBConnection failingMetadataDB) GetImageMetadata(ImageID uint64) (interfaces.ImageMetadata, error) {
+	return interfaces.ImageMetadata{}, errors.New("connection lost")
+}
+
+func TestMigrateDatabaseReadFailure(t *testing.T) {
+	setupImportTest(t)
+	t.Cleanup(jobs.Wait)
+	importPath := t.TempDir()
+	writeTestFile(t, importPath, "a.png", testPNG(t, 10))
+	importDirectory(importPath, "importer", filepath.Join(t.TempDir(), "progress.tsv"), false)
+
+	//Images without metadata are copied, as having none is not an error
+	target := &memoryplugin.MemoryPlugin{}
+	if err := target.InitDatabase(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := copyDatabase(database.DBInterface, target); err != nil {
+		t.Fatalf("copyDatabase: %v", err)
+	}
+
+	//Failing to read metadata stops the migration rather than leaving it out
+	target = &memoryplugin.MemoryPlugin{}
+	if err := target.InitDatabase(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := copyDatabase(failingMetadataDB{database.DBInterface}, target); err == nil || err.Error() != "connection lost" {
+		t.Errorf("copyDatabase with unreadable metadata: %v", err)
+	}
+	if _, err := countBoard(failingMetadataDB{database.DBInterface}); err == nil {
+		t.Errorf("countBoard with unreadable metadata did not fail")
+	}
+}
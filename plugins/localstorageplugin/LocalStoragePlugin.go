@@ -0,0 +1,145 @@
+package localstorageplugin
+
+import (
+	"go-image-board/config"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+//LocalStoragePlugin keeps files in a directory on the local filesystem
+type LocalStoragePlugin struct {
+	//Root is the directory files are kept in, set from ImageDirectory by Init
+	Root string
+}
+
+//Init prepares the image directory and thumbnail directory for use
+func (Storage *LocalStoragePlugin) Init() error {
+	Storage.Root = config.Configuration.ImageDirectory
+	if err := os.MkdirAll(filepath.Join(Storage.Root, "thumbs"), 0770); err != nil {
+		logging.WriteLog(logging.LogLevelError, "LocalStoragePlugin/Init", "0", logging.ResultFailure, []string{"Failed to create image directory", Storage.Root, err.Error()})
+		return err
+	}
+	return nil
+}
+
+//filePath converts a storage name into a path under Root, names are not allowed to leave Root
+func (Storage *LocalStoragePlugin) filePath(Name string) string {
+	return filepath.Join(Storage.Root, filepath.FromSlash(path.Clean("/"+Name)))
+}
+
+//Save stores Content under Name, replacing any file already there
+func (Storage *LocalStoragePlugin) Save(Name string, Content io.Reader) error {
+	filePath := Storage.filePath(Name)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0770); err != nil {
+		return err
+	}
+	//Write to a temporary file first, so a failed or partial write never replaces a good file
+	tempFile, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tempFile, Content); err != nil {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		os.Remove(tempFile.Name())
+		return err
+	}
+	if err := os.Chmod(tempFile.Name(), 0660); err != nil {
+		os.Remove(tempFile.Name())
+		return err
+	}
+	if err := os.Rename(tempFile.Name(), filePath); err != nil {
+		os.Remove(tempFile.Name())
+		return err
+	}
+	return nil
+}
+
+//Open returns the contents of a file, the caller must close it
+func (Storage *LocalStoragePlugin) Open(Name string) (io.ReadSeekCloser, error) {
+	return os.Open(Storage.filePath(Name))
+}
+
+//Stat returns information about a file
+func (Storage *LocalStoragePlugin) Stat(Name string) (interfaces.StorageFileInfo, error) {
+	fileInfo, err := os.Stat(Storage.filePath(Name))
+	if err != nil {
+		return interfaces.StorageFileInfo{}, err
+	}
+	if fileInfo.IsDir() {
+		return interfaces.StorageFileInfo{}, &os.PathError{Op: "stat", Path: Name, Err: os.ErrNotExist}
+	}
+	return interfaces.StorageFileInfo{Name: Name, Size: fileInfo.Size(), ModTime: fileInfo.ModTime()}, nil
+}
+
+//Remove deletes a file
+func (Storage *LocalStoragePlugin) Remove(Name string) error {
+	if err := os.Remove(Storage.filePath(Name)); err != nil {
+		return err
+	}
+	Storage.pruneDirectories(Name)
+	return nil
+}
+
+//Rename moves a file to a new name, replacing any file already there
+func (Storage *LocalStoragePlugin) Rename(OldName string, NewName string) error {
+	newPath := Storage.filePath(NewName)
+	if err := os.MkdirAll(filepath.Dir(newPath), 0770); err != nil {
+		return err
+	}
+	if err := os.Rename(Storage.filePath(OldName), newPath); err != nil {
+		return err
+	}
+	Storage.pruneDirectories(OldName)
+	return nil
+}
+
+//pruneDirectories removes the directories above Name that are left empty, so moving away from a sharded layout does not leave them behind
+func (Storage *LocalStoragePlugin) pruneDirectories(Name string) {
+	for directory := path.Dir(path.Clean("/" + Name)); directory != "/" && directory != "/thumbs"; directory = path.Dir(directory) {
+		//Removing a directory that still has files fails, which ends the walk
+		if os.Remove(Storage.filePath(directory)) != nil {
+			return
+		}
+	}
+}
+
+//List returns the names, including Directory, of files directly inside Directory, use "" for the top level
+func (Storage *LocalStoragePlugin) List(Directory string) ([]string, error) {
+	entries, err := os.ReadDir(Storage.filePath(Directory))
+	if err != nil {
+		return nil, err
+	}
+	var ToReturn []string
+	for _, entry := range entries {
+		//Skip folders, and any temporary file from a save in progress
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".upload-") {
+			continue
+		}
+		ToReturn = append(ToReturn, path.Join(Directory, entry.Name()))
+	}
+	return ToReturn, nil
+}
+
+//ListDirectories returns the names, including Directory, of directories directly inside Directory, use "" for the top level
+func (Storage *LocalStoragePlugin) ListDirectories(Directory string) ([]string, error) {
+	entries, err := os.ReadDir(Storage.filePath(Directory))
+	if err != nil {
+		return nil, err
+	}
+	var ToReturn []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			ToReturn = append(ToReturn, path.Join(Directory, entry.Name()))
+		}
+	}
+	return ToReturn, nil
+}
@@ -0,0 +1,19 @@
+package localstorageplugin
+
+import (
+	"go-image-board/config"
+	"go-image-board/interfaces"
+	"go-image-board/storage/storagetest"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.RunConformance(t, func(t *testing.T) interfaces.StorageInterface {
+		config.Configuration.ImageDirectory = t.TempDir()
+		Storage := &LocalStoragePlugin{}
+		if err := Storage.Init(); err != nil {
+			t.Fatalf("Init: %v", err)
+		}
+		return Storage
+	})
+}
@@ -16,7 +16,7 @@ import (
 func (DBConnection *MariaDBPlugin) CreateUser(userName string, password []byte, email string, permissions uint64) error {
 	//Validate User does not exist
 	var userCount int
-	row := DBConnection.DBHandle.QueryRow("SELECT COUNT(*) AS UserCount FROM Users WHERE Name = ? OR EMail = ?", userName, email)
+	row := DBConnection.handle().QueryRow("SELECT COUNT(*) AS UserCount FROM Users WHERE Name = ? OR EMail = ?", userName, email)
 	if err := row.Scan(&userCount); err != nil {
 		return err
 	}
@@ -30,7 +30,7 @@ func (DBConnection *MariaDBPlugin) CreateUser(userName string, password []byte,
 	if err != nil {
 		return errors.New("Error with user password")
 	}
-	_, err = DBConnection.DBHandle.Exec("INSERT INTO Users (Name, EMail, PasswordHash, Permissions) VALUES (?, ?, ?, ?);", userName, email, string(hash), permissions)
+	_, err = DBConnection.handle().Exec("INSERT INTO Users (Name, EMail, PasswordHash, Permissions) VALUES (?, ?, ?, ?);", userName, email, string(hash), permissions)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/CreateUser", userName, logging.ResultFailure, []string{"Failed to create new user", err.Error()})
 	}
@@ -42,7 +42,7 @@ func (DBConnection *MariaDBPlugin) CreateUser(userName string, password []byte,
 func (DBConnection *MariaDBPlugin) ValidateUser(userName string, password []byte) error {
 	var userPassword string
 	var userDisabled bool
-	row := DBConnection.DBHandle.QueryRow("SELECT PasswordHash, Disabled FROM Users WHERE Name = ?", userName)
+	row := DBConnection.handle().QueryRow("SELECT PasswordHash, Disabled FROM Users WHERE Name = ?", userName)
 	err := row.Scan(&userPassword, &userDisabled)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/ValidateUser", userName, logging.ResultFailure, []string{"Username and Password not correct", userName, err.Error()})
@@ -63,7 +63,7 @@ func (DBConnection *MariaDBPlugin) ValidateUser(userName string, password []byte
 //GetUserID returns a user's DBID for association with other db elements
 func (DBConnection *MariaDBPlugin) GetUserID(userName string) (uint64, error) {
 	var userID uint64
-	row := DBConnection.DBHandle.QueryRow("SELECT ID FROM Users WHERE Name = ?", userName)
+	row := DBConnection.handle().QueryRow("SELECT ID FROM Users WHERE Name = ?", userName)
 	err := row.Scan(&userID)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/GetUserID", userName, logging.ResultFailure, []string{"Username does not exist", userName})
@@ -75,7 +75,7 @@ func (DBConnection *MariaDBPlugin) GetUserID(userName string) (uint64, error) {
 //GetUserPermissionSet returns a UserPermission object representing a user's intended access
 func (DBConnection *MariaDBPlugin) GetUserPermissionSet(userName string) (interfaces.UserPermission, error) {
 	var userPermission uint64
-	row := DBConnection.DBHandle.QueryRow("SELECT Permissions FROM Users WHERE Name = ?", userName)
+	row := DBConnection.handle().QueryRow("SELECT Permissions FROM Users WHERE Name = ?", userName)
 	err := row.Scan(&userPermission)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/GetUserID", userName, logging.ResultFailure, []string{"Username does not exist", userName})
@@ -86,19 +86,19 @@ func (DBConnection *MariaDBPlugin) GetUserPermissionSet(userName string) (interf
 
 //SetUserPermissionSet sets a user's permission in the database
 func (DBConnection *MariaDBPlugin) SetUserPermissionSet(userID uint64, permissions uint64) error {
-	_, err := DBConnection.DBHandle.Exec("UPDATE Users SET Permissions=? WHERE ID=?", permissions, userID)
+	_, err := DBConnection.handle().Exec("UPDATE Users SET Permissions=? WHERE ID=?", permissions, userID)
 	return err
 }
 
 //SetUserDisableState disables or enables a user account
 func (DBConnection *MariaDBPlugin) SetUserDisableState(userID uint64, isDisabled bool) error {
-	_, err := DBConnection.DBHandle.Exec("UPDATE Users SET Disabled=? WHERE ID=?", isDisabled, userID)
+	_, err := DBConnection.handle().Exec("UPDATE Users SET Disabled=? WHERE ID=?", isDisabled, userID)
 	return err
 }
 
 //SetUserQueryTags sets a user's global filter
 func (DBConnection *MariaDBPlugin) SetUserQueryTags(UserID uint64, Filter string) error {
-	_, err := DBConnection.DBHandle.Exec("UPDATE Users SET SearchFilter=? WHERE ID=?", Filter, UserID)
+	_, err := DBConnection.handle().Exec("UPDATE Users SET SearchFilter=? WHERE ID=?", Filter, UserID)
 	return err
 }
 
@@ -126,13 +126,13 @@ func (DBConnection *MariaDBPlugin) SetUserPassword(userName string, password []b
 		return err
 	}
 
-	_, err = DBConnection.DBHandle.Exec("UPDATE Users SET PasswordHash=? WHERE Name = ?", string(newPasswordHash), userName)
+	_, err = DBConnection.handle().Exec("UPDATE Users SET PasswordHash=? WHERE Name = ?", string(newPasswordHash), userName)
 	return err
 }
 
 //RemoveUser Removes a user from the database (nil on success)
 func (DBConnection *MariaDBPlugin) RemoveUser(userName string) error {
-	_, err := DBConnection.DBHandle.Exec("DELETE FROM Users WHERE Name = ?", userName)
+	_, err := DBConnection.handle().Exec("DELETE FROM Users WHERE Name = ?", userName)
 	if err == nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/RemoveUser", userName, logging.ResultSuccess, []string{"User removed", userName})
 	} else {
@@ -171,7 +171,7 @@ func (DBConnection *MariaDBPlugin) ValidateProposedUsername(UserName string) err
 //GetUserFilter returns the raw string of the user's filter
 func (DBConnection *MariaDBPlugin) GetUserFilter(UserID uint64) (string, error) {
 	var userFilter string
-	err := DBConnection.DBHandle.QueryRow("SELECT SearchFilter FROM Users WHERE ID = ?", UserID).Scan(&userFilter)
+	err := DBConnection.handle().QueryRow("SELECT SearchFilter FROM Users WHERE ID = ?", UserID).Scan(&userFilter)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/GetUserQueryTags", "0", logging.ResultFailure, []string{"Failed to get user filter", err.Error()})
 	}
@@ -197,7 +197,7 @@ func (DBConnection *MariaDBPlugin) SearchUsers(searchString string, PageStart ui
 	//Query Count
 	//Run the count query (Count query does not use start/stride, so run this before we add those)
 	var MaxResults uint64
-	err := DBConnection.DBHandle.QueryRow(sqlCountQuery, queryArray...).Scan(&MaxResults)
+	err := DBConnection.handle().QueryRow(sqlCountQuery, queryArray...).Scan(&MaxResults)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/SearchUsers", "0", logging.ResultFailure, []string{"Error running search query", sqlCountQuery, err.Error()})
 		return nil, 0, err
@@ -210,7 +210,7 @@ func (DBConnection *MariaDBPlugin) SearchUsers(searchString string, PageStart ui
 	}
 
 	//First Query the main information
-	rows, err := DBConnection.DBHandle.Query(sqlQuery, queryArray...)
+	rows, err := DBConnection.handle().Query(sqlQuery, queryArray...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -251,7 +251,7 @@ func (DBConnection *MariaDBPlugin) GetUser(UserID uint64) (interfaces.UserInform
 	var CreationTime time.Time
 	var Disabled bool
 	var Permissions uint64
-	err := DBConnection.DBHandle.QueryRow(sqlQuery, queryArray...).Scan(&Name, &NCreationTime, &Disabled, &Permissions)
+	err := DBConnection.handle().QueryRow(sqlQuery, queryArray...).Scan(&Name, &NCreationTime, &Disabled, &Permissions)
 	if err != nil {
 		return interfaces.UserInformation{}, err
 	}
@@ -22,7 +22,7 @@ func (DBConnection *MariaDBPlugin) SetSecurityQuestions(userName string, questio
 	//Grab pre-existing first quesion, if needed
 	var secQuestionOne sql.NullString
 	var secAnswerOne sql.NullString
-	err := DBConnection.DBHandle.QueryRow("SELECT SecQuestionOne, SecAnswerOne FROM Users WHERE Name = ?", userName).Scan(&secQuestionOne, &secAnswerOne)
+	err := DBConnection.handle().QueryRow("SELECT SecQuestionOne, SecAnswerOne FROM Users WHERE Name = ?", userName).Scan(&secQuestionOne, &secAnswerOne)
 	//If question one is set
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/SetSecurityQuestions", userName, logging.ResultFailure, []string{"Security questions failed to update. Challenge could not be loaded SQL Error.", userName, err.Error()})
@@ -37,7 +37,7 @@ func (DBConnection *MariaDBPlugin) SetSecurityQuestions(userName string, questio
 		}
 	}
 
-	_, err = DBConnection.DBHandle.Exec("UPDATE Users SET SecQuestionOne=?, SecQuestionTwo=?, SecQuestionThree=?, SecAnswerOne=?, SecAnswerTwo=?, SecAnswerThree=? WHERE Name = ? AND Disabled = FALSE", questionOne, questionTwo, questionThree, string(answerOneHash), string(answerTwoHash), string(answerThreeHash), userName)
+	_, err = DBConnection.handle().Exec("UPDATE Users SET SecQuestionOne=?, SecQuestionTwo=?, SecQuestionThree=?, SecAnswerOne=?, SecAnswerTwo=?, SecAnswerThree=? WHERE Name = ? AND Disabled = FALSE", questionOne, questionTwo, questionThree, string(answerOneHash), string(answerTwoHash), string(answerThreeHash), userName)
 	if err == nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/SetSecurityQuestions", userName, logging.ResultSuccess, []string{"Security questions updated!", userName})
 	} else {
@@ -70,7 +70,7 @@ func (DBConnection *MariaDBPlugin) ValidateSecurityQuestions(userName string, an
 	var secAnswerTwo sql.NullString
 	var secAnswerThree sql.NullString
 
-	row := DBConnection.DBHandle.QueryRow("SELECT SecAnswerOne, SecAnswerTwo, SecAnswerThree FROM Users WHERE Name = ?", userName)
+	row := DBConnection.handle().QueryRow("SELECT SecAnswerOne, SecAnswerTwo, SecAnswerThree FROM Users WHERE Name = ?", userName)
 	err = row.Scan(&secAnswerOne, &secAnswerTwo, &secAnswerThree)
 	if err != nil {
 		return err
@@ -103,7 +103,7 @@ func (DBConnection *MariaDBPlugin) GetSecurityQuestions(userName string) (string
 	var secQuestionOne sql.NullString
 	var secQuestionTwo sql.NullString
 	var secQuestionThree sql.NullString
-	row := DBConnection.DBHandle.QueryRow("SELECT SecQuestionOne, SecQuestionTwo, SecQuestionThree FROM Users WHERE Name = ?", userName)
+	row := DBConnection.handle().QueryRow("SELECT SecQuestionOne, SecQuestionTwo, SecQuestionThree FROM Users WHERE Name = ?", userName)
 	err := row.Scan(&secQuestionOne, &secQuestionTwo, &secQuestionThree)
 	if err != nil {
 		return "", "", "", err
@@ -14,7 +14,7 @@ func (DBConnection *MariaDBPlugin) ValidateToken(userName string, tokenID string
 	var validTokenID sql.NullString
 	var validTokenIP sql.NullString
 	var userDisabled bool
-	row := DBConnection.DBHandle.QueryRow("SELECT TokenID, IP, Disabled FROM Users WHERE Name = ?", userName)
+	row := DBConnection.handle().QueryRow("SELECT TokenID, IP, Disabled FROM Users WHERE Name = ?", userName)
 	err := row.Scan(&validTokenID, &validTokenIP, &userDisabled)
 	if userDisabled {
 		return errors.New("Account disabled")
@@ -50,7 +50,7 @@ func (DBConnection *MariaDBPlugin) ValidateToken(userName string, tokenID string
 //GenerateToken Generate a cookie token (string token, or error)
 func (DBConnection *MariaDBPlugin) GenerateToken(userName string, ip string) (string, error) {
 	newToken := uuid.NewV4()
-	_, err := DBConnection.DBHandle.Exec("UPDATE Users SET TokenID=?, IP=? WHERE Name = ?", newToken.String(), ip, userName)
+	_, err := DBConnection.handle().Exec("UPDATE Users SET TokenID=?, IP=? WHERE Name = ?", newToken.String(), ip, userName)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/GenerateToken", userName, logging.ResultFailure, []string{"Failed to save token", userName, ip, err.Error()})
 		return "", errors.New("failed to generate a token, check if user exists")
@@ -60,7 +60,7 @@ func (DBConnection *MariaDBPlugin) GenerateToken(userName string, ip string) (st
 
 //RevokeToken Revokes a token (nil on success)
 func (DBConnection *MariaDBPlugin) RevokeToken(userName string) error {
-	_, err := DBConnection.DBHandle.Exec("UPDATE Users SET TokenID=NULL, IP=NULL WHERE Name = ?", userName)
+	_, err := DBConnection.handle().Exec("UPDATE Users SET TokenID=NULL, IP=NULL WHERE Name = ?", userName)
 	if err == nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/RevokeToken", userName, logging.ResultSuccess, []string{"Token revoked!", userName})
 	} else {
@@ -3,6 +3,7 @@ package mariadbplugin
 import (
 	"go-image-board/logging"
 	"strconv"
+	"time"
 )
 
 //AddAuditLog adds an audit event into the audit table
@@ -15,6 +16,16 @@ func (DBConnection *MariaDBPlugin) AddAuditLog(UserID uint64, Type string, Info
 		//return errors.New("either the type, or the info is too long for the audit log table")
 	}
 
-	_, err := DBConnection.DBHandle.Exec("INSERT INTO AuditLogs (UserID, Type, Info) VALUES (?, ?, ?);", UserID, Type, Info)
+	_, err := DBConnection.handle().Exec("INSERT INTO AuditLogs (UserID, Type, Info) VALUES (?, ?, ?);", UserID, Type, Info)
 	return err
 }
+
+//RemoveAuditLogs removes audit logs older than OlderThan, returns the count removed
+func (DBConnection *MariaDBPlugin) RemoveAuditLogs(OlderThan time.Duration) (int64, error) {
+	resultInfo, err := DBConnection.handle().Exec("DELETE FROM AuditLogs WHERE LogTime < DATE_SUB(CURRENT_TIMESTAMP, INTERVAL ? SECOND);", int64(OlderThan.Seconds()))
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/RemoveAuditLogs", "0", logging.ResultFailure, []string{"Failed to remove old audit logs", err.Error()})
+		return 0, err
+	}
+	return resultInfo.RowsAffected()
+}
@@ -20,7 +20,7 @@ func (DBConnection *MariaDBPlugin) NewCollection(Name string, Description string
 		return 0, errors.New("name or description outside size range")
 	}
 
-	resultInfo, err := DBConnection.DBHandle.Exec("INSERT INTO Collections (Name, Description, UploaderID) VALUES (?, ?, ?);", Name, Description, UploaderID)
+	resultInfo, err := DBConnection.handle().Exec("INSERT INTO Collections (Name, Description, UploaderID) VALUES (?, ?, ?);", Name, Description, UploaderID)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/NewCollection", strconv.FormatUint(UploaderID, 10), logging.ResultFailure, []string{"Failed to add collection", err.Error()})
 		return 0, err
@@ -33,14 +33,14 @@ func (DBConnection *MariaDBPlugin) NewCollection(Name string, Description string
 //DeleteCollection removes a collection
 func (DBConnection *MariaDBPlugin) DeleteCollection(CollectionID uint64) error {
 	//Ensure not in use
-	_, err := DBConnection.DBHandle.Exec("DELETE FROM CollectionMembers WHERE CollectionID=?;", CollectionID)
+	_, err := DBConnection.handle().Exec("DELETE FROM CollectionMembers WHERE CollectionID=?;", CollectionID)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/DeleteCollection", "0", logging.ResultFailure, []string{"Colleciton to delete is still in use and members could not be removed", strconv.FormatUint(CollectionID, 10)})
 		return errors.New("could not remove members from collection before deleting collection")
 	}
 
 	//Delete
-	_, err = DBConnection.DBHandle.Exec("DELETE FROM Collections WHERE ID=?;", CollectionID)
+	_, err = DBConnection.handle().Exec("DELETE FROM Collections WHERE ID=?;", CollectionID)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/DeleteCollection", "0", logging.ResultFailure, []string{"Failed to delete collection", err.Error(), strconv.FormatUint(CollectionID, 10)})
 	} else {
@@ -57,7 +57,7 @@ func (DBConnection *MariaDBPlugin) UpdateCollection(CollectionID uint64, Name st
 		return errors.New("name or description outside of right sizes")
 	}
 
-	_, err := DBConnection.DBHandle.Exec("UPDATE Collections SET Name = ?, Description=? WHERE ID=?;", Name, Description, CollectionID)
+	_, err := DBConnection.handle().Exec("UPDATE Collections SET Name = ?, Description=? WHERE ID=?;", Name, Description, CollectionID)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/UpdateCollection", "0", logging.ResultFailure, []string{"Failed to update collection", err.Error()})
 		return err
@@ -92,14 +92,14 @@ func (DBConnection *MariaDBPlugin) GetCollections(PageStart uint64, PageStride u
 	//Get Count query
 	var MaxResults uint64
 	//Run the count query (Count query does not use start/stride)
-	err := DBConnection.DBHandle.QueryRow(sqlCountQuery).Scan(&MaxResults)
+	err := DBConnection.handle().QueryRow(sqlCountQuery).Scan(&MaxResults)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/GetCollections", "0", logging.ResultFailure, []string{"Error running count query", sqlCountQuery, err.Error()})
 		return nil, 0, err
 	}
 
 	//Pass the sql query to DB
-	rows, err := DBConnection.DBHandle.Query(sqlQuery, PageStride, PageStart)
+	rows, err := DBConnection.handle().Query(sqlQuery, PageStride, PageStart)
 	if err != nil {
 		return nil, MaxResults, err
 	}
@@ -138,12 +138,12 @@ func (DBConnection *MariaDBPlugin) GetCollection(ID uint64) (interfaces.Collecti
 	var UploaderID uint64
 	var NUploadTime mysql.NullTime
 	var UploadTime time.Time
-	if err := DBConnection.DBHandle.QueryRow(sqlQuery, ID).Scan(&Name, &Description, &UploaderID, &NUploadTime); err != nil {
+	if err := DBConnection.handle().QueryRow(sqlQuery, ID).Scan(&Name, &Description, &UploaderID, &NUploadTime); err != nil {
 		return interfaces.CollectionInformation{}, err
 	}
 
 	var MemberCount uint64
-	if err := DBConnection.DBHandle.QueryRow("SELECT COUNT(*) FROM CollectionMembers WHERE CollectionID=?", ID).Scan(&MemberCount); err != nil {
+	if err := DBConnection.handle().QueryRow("SELECT COUNT(*) FROM CollectionMembers WHERE CollectionID=?", ID).Scan(&MemberCount); err != nil {
 		return interfaces.CollectionInformation{}, err
 	}
 
@@ -170,12 +170,12 @@ func (DBConnection *MariaDBPlugin) GetCollectionByName(Name string) (interfaces.
 	var UploaderID uint64
 	var NUploadTime mysql.NullTime
 	var UploadTime time.Time
-	if err := DBConnection.DBHandle.QueryRow(sqlQuery, Name).Scan(&CollectionID, &Name, &Description, &UploaderID, &NUploadTime); err != nil {
+	if err := DBConnection.handle().QueryRow(sqlQuery, Name).Scan(&CollectionID, &Name, &Description, &UploaderID, &NUploadTime); err != nil {
 		return interfaces.CollectionInformation{}, err
 	}
 
 	var MemberCount uint64
-	if err := DBConnection.DBHandle.QueryRow("SELECT COUNT(*) FROM CollectionMembers WHERE CollectionID=?", CollectionID).Scan(&MemberCount); err != nil {
+	if err := DBConnection.handle().QueryRow("SELECT COUNT(*) FROM CollectionMembers WHERE CollectionID=?", CollectionID).Scan(&MemberCount); err != nil {
 		return interfaces.CollectionInformation{}, err
 	}
 
@@ -202,7 +202,7 @@ func (DBConnection *MariaDBPlugin) AddCollectionMember(CollectionID uint64, Imag
 	//Get last order
 	lastOrder := uint64(0)
 	memberCount := uint64(0)
-	if err := DBConnection.DBHandle.QueryRow("SELECT IFNULL(MAX(OrderWeight),0) AS LastWeight, COUNT(*) AS MemberCount FROM CollectionMembers WHERE CollectionID = ?", CollectionID).Scan(&lastOrder, &memberCount); err != nil {
+	if err := DBConnection.handle().QueryRow("SELECT IFNULL(MAX(OrderWeight),0) AS LastWeight, COUNT(*) AS MemberCount FROM CollectionMembers WHERE CollectionID = ?", CollectionID).Scan(&lastOrder, &memberCount); err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/AddCollectionMember", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Could not get count of members in collection", strconv.FormatUint(CollectionID, 10)})
 		return errors.New("could not get count of members in collection")
 	}
@@ -227,7 +227,7 @@ func (DBConnection *MariaDBPlugin) AddCollectionMember(CollectionID uint64, Imag
 
 	//Add image
 	sqlQuery := "INSERT INTO CollectionMembers (CollectionID, ImageID, LinkerID, OrderWeight) VALUES" + values
-	if _, err := DBConnection.DBHandle.Exec(sqlQuery, queryArray...); err != nil {
+	if _, err := DBConnection.handle().Exec(sqlQuery, queryArray...); err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/AddCollectionMember", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Image not added to collection", strconv.FormatUint(CollectionID, 10), idString, err.Error()})
 		return err
 	}
@@ -239,12 +239,12 @@ func (DBConnection *MariaDBPlugin) AddCollectionMember(CollectionID uint64, Imag
 func (DBConnection *MariaDBPlugin) RemoveCollectionMember(CollectionID uint64, ImageID uint64) error {
 	//Get Order
 	var Order uint64
-	if err := DBConnection.DBHandle.QueryRow("SELECT OrderWeight FROM CollectionMembers WHERE ImageID=? AND CollectionID=?", ImageID, CollectionID).Scan(&Order); err != nil {
+	if err := DBConnection.handle().QueryRow("SELECT OrderWeight FROM CollectionMembers WHERE ImageID=? AND CollectionID=?", ImageID, CollectionID).Scan(&Order); err != nil {
 		return err
 	}
 
 	var Members uint64
-	if err := DBConnection.DBHandle.QueryRow("SELECT Count(*) FROM CollectionMembers WHERE CollectionID=?", CollectionID).Scan(&Members); err != nil {
+	if err := DBConnection.handle().QueryRow("SELECT Count(*) FROM CollectionMembers WHERE CollectionID=?", CollectionID).Scan(&Members); err != nil {
 		return err
 	}
 
@@ -254,14 +254,14 @@ func (DBConnection *MariaDBPlugin) RemoveCollectionMember(CollectionID uint64, I
 	}
 
 	//Delete Image
-	if _, err := DBConnection.DBHandle.Exec("DELETE FROM CollectionMembers WHERE CollectionID =? AND ImageID = ?;", CollectionID, ImageID); err != nil {
+	if _, err := DBConnection.handle().Exec("DELETE FROM CollectionMembers WHERE CollectionID =? AND ImageID = ?;", CollectionID, ImageID); err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/RemoveCollectionMember", "0", logging.ResultFailure, []string{"Image not removed from collection", strconv.FormatUint(CollectionID, 10), strconv.FormatUint(ImageID, 10), err.Error()})
 		return err
 	}
 	logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/RemoveCollectionMember", "0", logging.ResultSuccess, []string{"Image removed from collection", strconv.FormatUint(CollectionID, 10), strconv.FormatUint(ImageID, 10)})
 
 	//Decrement Order
-	if _, err := DBConnection.DBHandle.Exec("UPDATE CollectionMembers SET OrderWeight = OrderWeight - 1 WHERE OrderWeight > ? AND CollectionID=?;", Order, CollectionID); err != nil {
+	if _, err := DBConnection.handle().Exec("UPDATE CollectionMembers SET OrderWeight = OrderWeight - 1 WHERE OrderWeight > ? AND CollectionID=?;", Order, CollectionID); err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/RemoveCollectionMember", "0", logging.ResultFailure, []string{"Could not update Order after member removed from collection", strconv.FormatUint(CollectionID, 10), strconv.FormatUint(ImageID, 10), err.Error()})
 		return err
 	}
@@ -273,13 +273,13 @@ func (DBConnection *MariaDBPlugin) RemoveCollectionMember(CollectionID uint64, I
 func (DBConnection *MariaDBPlugin) UpdateCollectionMember(CollectionID uint64, ImageID uint64, Order uint64) error {
 	//Get Current Order
 	var BeforeOrder uint64
-	if err := DBConnection.DBHandle.QueryRow("SELECT OrderWeight FROM CollectionMembers WHERE ImageID=? AND CollectionID=?", ImageID, CollectionID).Scan(&BeforeOrder); err != nil {
+	if err := DBConnection.handle().QueryRow("SELECT OrderWeight FROM CollectionMembers WHERE ImageID=? AND CollectionID=?", ImageID, CollectionID).Scan(&BeforeOrder); err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/UpdateCollectionMember", "0", logging.ResultFailure, []string{"Could not get previous order to update collectionmember", strconv.FormatUint(CollectionID, 10), strconv.FormatUint(ImageID, 10), err.Error()})
 		return err
 	}
 
 	var MemberCount uint64
-	if err := DBConnection.DBHandle.QueryRow("SELECT COUNT(*) FROM CollectionMembers WHERE CollectionID=?", CollectionID).Scan(&MemberCount); err != nil {
+	if err := DBConnection.handle().QueryRow("SELECT COUNT(*) FROM CollectionMembers WHERE CollectionID=?", CollectionID).Scan(&MemberCount); err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/UpdateCollectionMember", "0", logging.ResultFailure, []string{"Could not validate order", strconv.FormatUint(CollectionID, 10), strconv.FormatUint(ImageID, 10), err.Error()})
 		return err
 	}
@@ -290,19 +290,19 @@ func (DBConnection *MariaDBPlugin) UpdateCollectionMember(CollectionID uint64, I
 	}
 
 	//Set order for image
-	if _, err := DBConnection.DBHandle.Exec("UPDATE CollectionMembers SET OrderWeight = ? WHERE ImageID=? AND CollectionID=?;", Order, ImageID, CollectionID); err != nil {
+	if _, err := DBConnection.handle().Exec("UPDATE CollectionMembers SET OrderWeight = ? WHERE ImageID=? AND CollectionID=?;", Order, ImageID, CollectionID); err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/UpdateCollectionMember", "0", logging.ResultFailure, []string{"Could not set Order of member in collection", strconv.FormatUint(CollectionID, 10), strconv.FormatUint(ImageID, 10), err.Error()})
 		return err
 	}
 
 	//Decrement Order
-	if _, err := DBConnection.DBHandle.Exec("UPDATE CollectionMembers SET OrderWeight = OrderWeight - 1 WHERE OrderWeight >= ? AND CollectionID=? AND ImageID<>?;", BeforeOrder, CollectionID, ImageID); err != nil {
+	if _, err := DBConnection.handle().Exec("UPDATE CollectionMembers SET OrderWeight = OrderWeight - 1 WHERE OrderWeight >= ? AND CollectionID=? AND ImageID<>?;", BeforeOrder, CollectionID, ImageID); err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/UpdateCollectionMember", "0", logging.ResultFailure, []string{"Could not decrement Order of members in collection", strconv.FormatUint(CollectionID, 10), strconv.FormatUint(ImageID, 10), err.Error()})
 		return err
 	}
 
 	//Increment Order
-	if _, err := DBConnection.DBHandle.Exec("UPDATE CollectionMembers SET OrderWeight = OrderWeight + 1 WHERE OrderWeight >= ? AND CollectionID=? AND ImageID<>?;", Order, CollectionID, ImageID); err != nil {
+	if _, err := DBConnection.handle().Exec("UPDATE CollectionMembers SET OrderWeight = OrderWeight + 1 WHERE OrderWeight >= ? AND CollectionID=? AND ImageID<>?;", Order, CollectionID, ImageID); err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/UpdateCollectionMember", "0", logging.ResultFailure, []string{"Could not increment Order of members in collection", strconv.FormatUint(CollectionID, 10), strconv.FormatUint(ImageID, 10), err.Error()})
 		return err
 	}
@@ -317,7 +317,7 @@ func (DBConnection *MariaDBPlugin) GetCollectionMembers(CollectionID uint64, Pag
 	queryArray = append(queryArray, CollectionID)
 
 	//Queries
-	sqlQuery := `SELECT ImageID, Name, Location, OrderWeight
+	sqlQuery := `SELECT ImageID, Name, Location, BlurHash, OrderWeight
 	FROM Images
 	INNER JOIN CollectionMembers ON Images.ID=CollectionMembers.ImageID
 	WHERE CollectionMembers.CollectionID=?
@@ -341,14 +341,14 @@ func (DBConnection *MariaDBPlugin) GetCollectionMembers(CollectionID uint64, Pag
 	var MaxResults uint64
 
 	//Run the count query (Count query does not use start/stride)
-	err := DBConnection.DBHandle.QueryRow(sqlCountQuery, CollectionID).Scan(&MaxResults)
+	err := DBConnection.handle().QueryRow(sqlCountQuery, CollectionID).Scan(&MaxResults)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/GetCollectionMembers", "0", logging.ResultFailure, []string{"Error running count query", sqlCountQuery, err.Error()})
 		return nil, 0, err
 	}
 
 	//Now for the real query
-	rows, err := DBConnection.DBHandle.Query(sqlQuery, queryArray...)
+	rows, err := DBConnection.handle().Query(sqlQuery, queryArray...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -357,16 +357,17 @@ func (DBConnection *MariaDBPlugin) GetCollectionMembers(CollectionID uint64, Pag
 	var ImageID uint64
 	var Name string
 	var Location string
+	var BlurHash string
 	var Order uint64
 	//For each row
 	for rows.Next() {
 		//Parse out the data
-		err := rows.Scan(&ImageID, &Name, &Location, &Order)
+		err := rows.Scan(&ImageID, &Name, &Location, &BlurHash, &Order)
 		if err != nil {
 			return nil, 0, err
 		}
 		//Add this result to ToReturn
-		ToReturn = append(ToReturn, interfaces.ImageInformation{Name: Name, ID: ImageID, Location: Location, OrderInCollection: Order})
+		ToReturn = append(ToReturn, interfaces.ImageInformation{Name: Name, ID: ImageID, Location: Location, BlurHash: BlurHash, OrderInCollection: Order})
 	}
 	return ToReturn, MaxResults, nil
 }
@@ -402,7 +403,7 @@ func (DBConnection *MariaDBPlugin) GetCollectionsWithImage(ImageID uint64) ([]in
 	WHERE CollectionMembers.ImageID=?`
 
 	//First Query the main information
-	rows, err := DBConnection.DBHandle.Query(sqlQuery, ImageID, ImageID, ImageID)
+	rows, err := DBConnection.handle().Query(sqlQuery, ImageID, ImageID, ImageID)
 	if err != nil {
 		return nil, err
 	}
@@ -434,7 +435,7 @@ func (DBConnection *MariaDBPlugin) GetCollectionTags(CollectionID uint64) ([]int
 	var ToReturn []interfaces.TagInformation
 	sqlQuery := "SELECT Tags.ID, Tags.Name, Tags.Description FROM CollectionTags INNER JOIN Tags ON Tags.ID = CollectionTags.TagID WHERE CollectionID=?"
 	//Pass the sql query to DB
-	rows, err := DBConnection.DBHandle.Query(sqlQuery, CollectionID)
+	rows, err := DBConnection.handle().Query(sqlQuery, CollectionID)
 	if err != nil {
 		return nil, err
 	}
@@ -463,7 +464,7 @@ func (DBConnection *MariaDBPlugin) GetCollectionTags(CollectionID uint64) ([]int
 
 //FixCollectionTags  verifies and fixes collection tags, returns row count and error
 func (DBConnection *MariaDBPlugin) FixCollectionTags(CollectionID uint64) (int64, error) {
-	results, err := DBConnection.DBHandle.Exec("CALL LinkCollTags(?)", CollectionID)
+	results, err := DBConnection.handle().Exec("CALL LinkCollTags(?)", CollectionID)
 
 	if err != nil {
 		return 0, err
@@ -139,7 +139,7 @@ func (DBConnection *MariaDBPlugin) SearchCollections(Tags []interfaces.TagInform
 	}
 
 	//Run the count query (Count query does not use start/stride, so run this before we add those)
-	err := DBConnection.DBHandle.QueryRow(sqlCountQuery, queryArray...).Scan(&MaxResults)
+	err := DBConnection.handle().QueryRow(sqlCountQuery, queryArray...).Scan(&MaxResults)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/SearchCollections", "0", logging.ResultFailure, []string{"Error running search query", sqlCountQuery, err.Error()})
 		return nil, 0, err
@@ -150,7 +150,7 @@ func (DBConnection *MariaDBPlugin) SearchCollections(Tags []interfaces.TagInform
 	queryArray = append(queryArray, PageStart)
 
 	//Now we have query and args, run the query
-	rows, err := DBConnection.DBHandle.Query(sqlQuery, queryArray...)
+	rows, err := DBConnection.handle().Query(sqlQuery, queryArray...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -14,7 +14,7 @@ import (
 
 //NewImage adds an image with the provided information
 func (DBConnection *MariaDBPlugin) NewImage(ImageName string, ImageFileName string, OwnerID uint64, Source string) (uint64, error) {
-	resultInfo, err := DBConnection.DBHandle.Exec("INSERT INTO Images (Name, Location, UploaderID, Source) VALUES (?, ?, ?, ?);", ImageName, ImageFileName, OwnerID, Source)
+	resultInfo, err := DBConnection.handle().Exec("INSERT INTO Images (Name, Location, UploaderID, Source) VALUES (?, ?, ?, ?);", ImageName, ImageFileName, OwnerID, Source)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/NewImage", strconv.FormatUint(OwnerID, 10), logging.ResultFailure, []string{"Failed to add image", err.Error()})
 		return 0, err
@@ -39,14 +39,14 @@ func (DBConnection *MariaDBPlugin) DeleteImage(ImageID uint64) error {
 	}
 
 	//First delete ImageTags
-	_, err = DBConnection.DBHandle.Exec("DELETE FROM ImageTags WHERE ImageID=?;", ImageID)
+	_, err = DBConnection.handle().Exec("DELETE FROM ImageTags WHERE ImageID=?;", ImageID)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/DeleteImage", "0", logging.ResultFailure, []string{"Failed to delete image", err.Error(), strconv.FormatUint(ImageID, 10)})
 		return err
 	}
 	logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/DeleteImage", "0", logging.ResultSuccess, []string{"Image tags deleted", strconv.FormatUint(ImageID, 10)})
 	//Second delete Image from table
-	_, err = DBConnection.DBHandle.Exec("DELETE FROM Images WHERE ID=?;", ImageID)
+	_, err = DBConnection.handle().Exec("DELETE FROM Images WHERE ID=?;", ImageID)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/DeleteImage", "0", logging.ResultFailure, []string{"Failed to delete image", err.Error(), strconv.FormatUint(ImageID, 10)})
 	} else {
@@ -117,7 +117,7 @@ func (DBConnection *MariaDBPlugin) UpdateImage(ImageID uint64, ImageName interfa
 		return nil //No change requested
 	}
 	sqlQuery = "UPDATE Images SET " + sqlQuery + "WHERE ID = ?"
-	_, err = DBConnection.DBHandle.Exec(sqlQuery, queryArray...)
+	_, err = DBConnection.handle().Exec(sqlQuery, queryArray...)
 	return err
 }
 
@@ -125,7 +125,7 @@ func (DBConnection *MariaDBPlugin) UpdateImage(ImageID uint64, ImageName interfa
 func (DBConnection *MariaDBPlugin) GetImage(ID uint64) (interfaces.ImageInformation, error) {
 	ToReturn := interfaces.ImageInformation{ID: ID}
 	var UploadTime mysql.NullTime
-	err := DBConnection.DBHandle.QueryRow("Select Images.Name, IFNULL(Images.Description,'') AS Description, Images.Location, Images.UploaderID, Images.UploadTime, Images.Rating, Users.Name, Images.ScoreAverage, Images.ScoreTotal, Images.ScoreVoters, Images.Source FROM Images LEFT OUTER JOIN Users ON Images.UploaderID = Users.ID WHERE Images.ID=?", ID).Scan(&ToReturn.Name, &ToReturn.Description, &ToReturn.Location, &ToReturn.UploaderID, &UploadTime, &ToReturn.Rating, &ToReturn.UploaderName, &ToReturn.ScoreAverage, &ToReturn.ScoreTotal, &ToReturn.ScoreVoters, &ToReturn.Source)
+	err := DBConnection.handle().QueryRow("Select Images.Name, IFNULL(Images.Description,'') AS Description, Images.Location, Images.UploaderID, Images.UploadTime, Images.Rating, Users.Name, Images.ScoreAverage, Images.ScoreTotal, Images.ScoreVoters, Images.Source, Images.Width, Images.Height, Images.FileSize, Images.MIMEType, Images.Duration, Images.Bitrate, Images.BlurHash FROM Images LEFT OUTER JOIN Users ON Images.UploaderID = Users.ID WHERE Images.ID=?", ID).Scan(&ToReturn.Name, &ToReturn.Description, &ToReturn.Location, &ToReturn.UploaderID, &UploadTime, &ToReturn.Rating, &ToReturn.UploaderName, &ToReturn.ScoreAverage, &ToReturn.ScoreTotal, &ToReturn.ScoreVoters, &ToReturn.Source, &ToReturn.Width, &ToReturn.Height, &ToReturn.FileSize, &ToReturn.MIMEType, &ToReturn.Duration, &ToReturn.Bitrate, &ToReturn.BlurHash)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/ImageFunctions/GetImage", "0", logging.ResultFailure, []string{"Failed to get image info from database", err.Error()})
 		return ToReturn, err
@@ -140,7 +140,7 @@ func (DBConnection *MariaDBPlugin) GetImage(ID uint64) (interfaces.ImageInformat
 func (DBConnection *MariaDBPlugin) GetImageByFileName(imageName string) (interfaces.ImageInformation, error) {
 	ToReturn := interfaces.ImageInformation{Location: imageName}
 	var UploadTime mysql.NullTime
-	err := DBConnection.DBHandle.QueryRow("Select Images.Name, IFNULL(Images.Description,'') AS Description, Images.ID, Images.UploaderID, Images.UploadTime, Images.Rating, Users.Name, Images.ScoreAverage, Images.ScoreTotal, Images.ScoreVoters, Images.Source FROM Images LEFT OUTER JOIN Users ON Images.UploaderID = Users.ID WHERE Images.Location=?", imageName).Scan(&ToReturn.Name, &ToReturn.Description, &ToReturn.ID, &ToReturn.UploaderID, &UploadTime, &ToReturn.Rating, &ToReturn.UploaderName, &ToReturn.ScoreAverage, &ToReturn.ScoreTotal, &ToReturn.ScoreVoters, &ToReturn.Source)
+	err := DBConnection.handle().QueryRow("Select Images.Name, IFNULL(Images.Description,'') AS Description, Images.ID, Images.UploaderID, Images.UploadTime, Images.Rating, Users.Name, Images.ScoreAverage, Images.ScoreTotal, Images.ScoreVoters, Images.Source, Images.Width, Images.Height, Images.FileSize, Images.MIMEType, Images.Duration, Images.Bitrate, Images.BlurHash FROM Images LEFT OUTER JOIN Users ON Images.UploaderID = Users.ID WHERE Images.Location=?", imageName).Scan(&ToReturn.Name, &ToReturn.Description, &ToReturn.ID, &ToReturn.UploaderID, &UploadTime, &ToReturn.Rating, &ToReturn.UploaderName, &ToReturn.ScoreAverage, &ToReturn.ScoreTotal, &ToReturn.ScoreVoters, &ToReturn.Source, &ToReturn.Width, &ToReturn.Height, &ToReturn.FileSize, &ToReturn.MIMEType, &ToReturn.Duration, &ToReturn.Bitrate, &ToReturn.BlurHash)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/ImageFunctions/GetImageByFileName", "0", logging.ResultFailure, []string{"Failed to get image info from database", err.Error()})
 		return ToReturn, err
@@ -153,7 +153,7 @@ func (DBConnection *MariaDBPlugin) GetImageByFileName(imageName string) (interfa
 
 //SetImageRating changes a given image's rating in the database
 func (DBConnection *MariaDBPlugin) SetImageRating(ID uint64, Rating string) error {
-	_, err := DBConnection.DBHandle.Exec("UPDATE Images SET Rating = ? WHERE ID = ?;", Rating, ID)
+	_, err := DBConnection.handle().Exec("UPDATE Images SET Rating = ? WHERE ID = ?;", Rating, ID)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/ImageFunctions/SetImageRating", "0", logging.ResultFailure, []string{"Failed to set image rating", err.Error()})
 		return err
@@ -163,7 +163,7 @@ func (DBConnection *MariaDBPlugin) SetImageRating(ID uint64, Rating string) erro
 
 //SetImageSource changes a given image's source in the database
 func (DBConnection *MariaDBPlugin) SetImageSource(ID uint64, Source string) error {
-	_, err := DBConnection.DBHandle.Exec("UPDATE Images SET Source = ? WHERE ID = ?;", Source, ID)
+	_, err := DBConnection.handle().Exec("UPDATE Images SET Source = ? WHERE ID = ?;", Source, ID)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/ImageFunctions/SetImageSource", "0", logging.ResultFailure, []string{"Failed to set image source", err.Error()})
 		return err
@@ -173,7 +173,7 @@ func (DBConnection *MariaDBPlugin) SetImageSource(ID uint64, Source string) erro
 
 //SetImagedHash changes a given image's dHash in the database
 func (DBConnection *MariaDBPlugin) SetImagedHash(ID uint64, hHash uint64, vHash uint64) error {
-	_, err := DBConnection.DBHandle.Exec("INSERT INTO ImagedHashes (ImageID, hHash, vHash) VALUES (?,?,?) ON DUPLICATE KEY UPDATE hHash = VALUES(hHash), vHash = VALUES(vHash);", ID, hHash, vHash)
+	_, err := DBConnection.handle().Exec("INSERT INTO ImagedHashes (ImageID, hHash, vHash) VALUES (?,?,?) ON DUPLICATE KEY UPDATE hHash = VALUES(hHash), vHash = VALUES(vHash);", ID, hHash, vHash)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/ImageFunctions/SetImagedHash", "0", logging.ResultFailure, []string{"Failed to set image dHashes", err.Error()})
 		return err
@@ -184,13 +184,93 @@ func (DBConnection *MariaDBPlugin) SetImagedHash(ID uint64, hHash uint64, vHash
 //GetImagedHash changes a given image's dHash in the database
 func (DBConnection *MariaDBPlugin) GetImagedHash(ID uint64) (uint64, uint64, error) {
 	var hHash, vHash uint64
-	err := DBConnection.DBHandle.QueryRow("SELECT hHash, vHash from ImagedHashes WHERE ImageID = ?", ID).Scan(&hHash, &vHash)
+	err := DBConnection.handle().QueryRow("SELECT hHash, vHash from ImagedHashes WHERE ImageID = ?", ID).Scan(&hHash, &vHash)
 	if err != nil {
 		return hHash, vHash, err
 	}
 	return hHash, vHash, nil
 }
 
+//SetImageMetadata adds or replaces the metadata of the image Metadata.ImageID
+func (DBConnection *MariaDBPlugin) SetImageMetadata(Metadata interfaces.ImageMetadata) error {
+	//A zero capture time means it was not recorded
+	var captureTime interface{}
+	if Metadata.CaptureTime.IsZero() == false {
+		captureTime = Metadata.CaptureTime.UTC()
+	}
+	_, err := DBConnection.handle().Exec("INSERT INTO ImageMetadata (ImageID, CaptureTime, CameraMake, CameraModel, LensModel, ExposureTime, FNumber, FocalLength, ISO, Orientation, Title, Artist, Album, Genre) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?) ON DUPLICATE KEY UPDATE CaptureTime = VALUES(CaptureTime), CameraMake = VALUES(CameraMake), CameraModel = VALUES(CameraModel), LensModel = VALUES(LensModel), ExposureTime = VALUES(ExposureTime), FNumber = VALUES(FNumber), FocalLength = VALUES(FocalLength), ISO = VALUES(ISO), Orientation = VALUES(Orientation), Title = VALUES(Title), Artist = VALUES(Artist), Album = VALUES(Album), Genre = VALUES(Genre);",
+		Metadata.ImageID, captureTime, Metadata.CameraMake, Metadata.CameraModel, Metadata.LensModel, Metadata.ExposureTime, Metadata.FNumber, Metadata.FocalLength, Metadata.ISO, Metadata.Orientation, Metadata.Title, Metadata.Artist, Metadata.Album, Metadata.Genre)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/ImageFunctions/SetImageMetadata", "0", logging.ResultFailure, []string{"Failed to set image metadata", err.Error()})
+		return err
+	}
+	return nil
+}
+
+//GetImageMetadata returns the metadata of an image, sql.ErrNoRows if it has none
+func (DBConnection *MariaDBPlugin) GetImageMetadata(ImageID uint64) (interfaces.ImageMetadata, error) {
+	ToReturn := interfaces.ImageMetadata{ImageID: ImageID}
+	var CaptureTime mysql.NullTime
+	err := DBConnection.handle().QueryRow("SELECT CaptureTime, CameraMake, CameraModel, LensModel, ExposureTime, FNumber, FocalLength, ISO, Orientation, Title, Artist, Album, Genre FROM ImageMetadata WHERE ImageID = ?", ImageID).Scan(&CaptureTime, &ToReturn.CameraMake, &ToReturn.CameraModel, &ToReturn.LensModel, &ToReturn.ExposureTime, &ToReturn.FNumber, &ToReturn.FocalLength, &ToReturn.ISO, &ToReturn.Orientation, &ToReturn.Title, &ToReturn.Artist, &ToReturn.Album, &ToReturn.Genre)
+	if err != nil {
+		return ToReturn, err
+	}
+	if CaptureTime.Valid {
+		ToReturn.CaptureTime = CaptureTime.Time
+	}
+	return ToReturn, nil
+}
+
+//SetImageColors replaces the BlurHash and dominant colours of a given image
+func (DBConnection *MariaDBPlugin) SetImageColors(ImageID uint64, BlurHash string, Colors []interfaces.ImageColor) error {
+	return DBConnection.RunInTransaction(func(Transaction interfaces.DBInterface) error {
+		handle := Transaction.(*MariaDBPlugin).handle()
+		if _, err := handle.Exec("UPDATE Images SET BlurHash = ? WHERE ID = ?;", BlurHash, ImageID); err != nil {
+			logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/ImageFunctions/SetImageColors", "0", logging.ResultFailure, []string{"Failed to set image blurhash", err.Error()})
+			return err
+		}
+		if _, err := handle.Exec("DELETE FROM ImageColors WHERE ImageID = ?;", ImageID); err != nil {
+			logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/ImageFunctions/SetImageColors", "0", logging.ResultFailure, []string{"Failed to clear image colors", err.Error()})
+			return err
+		}
+		for _, Color := range Colors {
+			if _, err := handle.Exec("INSERT INTO ImageColors (ImageID, Red, Green, Blue, Share) VALUES (?,?,?,?,?);", ImageID, Color.Red, Color.Green, Color.Blue, Color.Share); err != nil {
+				logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/ImageFunctions/SetImageColors", "0", logging.ResultFailure, []string{"Failed to add image color", err.Error()})
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+//GetImageColors returns the dominant colours of a given image, from most to least common
+func (DBConnection *MariaDBPlugin) GetImageColors(ImageID uint64) ([]interfaces.ImageColor, error) {
+	rows, err := DBConnection.handle().Query("SELECT Red, Green, Blue, Share FROM ImageColors WHERE ImageID = ? ORDER BY Share DESC, ID;", ImageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ToReturn []interfaces.ImageColor
+	for rows.Next() {
+		var Color interfaces.ImageColor
+		if err := rows.Scan(&Color.Red, &Color.Green, &Color.Blue, &Color.Share); err != nil {
+			return nil, err
+		}
+		ToReturn = append(ToReturn, Color)
+	}
+	return ToReturn, rows.Err()
+}
+
+//SetImageMediaInfo changes the width, height, file size, MIME type, duration, and bitrate of the image Image.ID
+func (DBConnection *MariaDBPlugin) SetImageMediaInfo(Image interfaces.ImageInformation) error {
+	_, err := DBConnection.handle().Exec("UPDATE Images SET Width = ?, Height = ?, FileSize = ?, MIMEType = ?, Duration = ?, Bitrate = ? WHERE ID = ?;", Image.Width, Image.Height, Image.FileSize, Image.MIMEType, Image.Duration, Image.Bitrate, Image.ID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/ImageFunctions/SetImageMediaInfo", "0", logging.ResultFailure, []string{"Failed to set image media info", err.Error()})
+		return err
+	}
+	return nil
+}
+
 /*
 //Our select query, if inclusive
 SELECT ImageID, Name, Location FROM (
@@ -3,6 +3,7 @@ package mariadbplugin
 import (
 	"database/sql"
 	"errors"
+	"go-image-board/database/tagquery"
 	"go-image-board/interfaces"
 	"go-image-board/logging"
 	"math/rand"
@@ -37,14 +38,14 @@ func (DBConnection *MariaDBPlugin) SearchImages(Tags []interfaces.TagInformation
 	//Construct SQL Query
 
 	//This is the start of the query we want
-	sqlQuery := `SELECT ID, Name, Location `
+	sqlQuery := `SELECT ID, Name, Location, BlurHash `
 	sqlCountQuery := `SELECT COUNT(*) `
 	if len(IncludeTags) == 0 {
 		sqlQuery = sqlQuery + `FROM Images `
 		sqlCountQuery = sqlCountQuery + `FROM Images `
 	} else {
 		sqlQuery = sqlQuery + `FROM (
-			SELECT ImageID as ID, Name, Location, COUNT(*) as MatchingTags
+			SELECT ImageID as ID, Name, Location, BlurHash, COUNT(*) as MatchingTags
 			FROM ImageTags 
 			INNER JOIN Images ON ImageTags.ImageID=Images.ID `
 		sqlCountQuery = sqlCountQuery + `FROM ( 
@@ -114,6 +115,39 @@ func (DBConnection *MariaDBPlugin) SearchImages(Tags []interfaces.TagInformation
 				metaTagQuery += "Images.ID IN (SELECT ImageID FROM ImagedHashes WHERE (BIT_COUNT(hHash ^ " + strconv.FormatUint(tagImagedHashValue.ImagehHash, 10) + ")+BIT_COUNT(vHash ^ " + strconv.FormatUint(tagImagedHashValue.ImagevHash, 10) + ")) " + comparator + " " + strconv.FormatUint(tagImagedHashValue.SimilarityThreshold, 10) + ") "
 				sqlWhereClause = sqlWhereClause + metaTagQuery
 				continue //Skip over rest of code for this tag
+			} else if tag.Name == "Color" { //Special Exception for Color
+				tagColorValue, isTagValued := tag.MetaValue.(interfaces.ImageColorSearch)
+				if isTagValued == false {
+					return ToReturn, 0, errors.New("Failed get value of " + tag.Name)
+				}
+				colorComparator := " IN "
+				if comparator != "=" {
+					colorComparator = " NOT IN "
+				}
+				//Images whose dominant colours within tolerance add up to enough of the image
+				red := strconv.FormatUint(uint64(tagColorValue.Red), 10)
+				green := strconv.FormatUint(uint64(tagColorValue.Green), 10)
+				blue := strconv.FormatUint(uint64(tagColorValue.Blue), 10)
+				metaTagQuery += "Images.ID" + colorComparator + "(SELECT ImageID FROM ImageColors WHERE (Red - " + red + ") * (Red - " + red + ") + (Green - " + green + ") * (Green - " + green + ") + (Blue - " + blue + ") * (Blue - " + blue + ") <= " + strconv.FormatUint(tagColorValue.Tolerance*tagColorValue.Tolerance, 10) + " GROUP BY ImageID HAVING SUM(Share) >= " + strconv.FormatFloat(tagColorValue.MinimumShare, 'f', -1, 64) + ") "
+				sqlWhereClause = sqlWhereClause + metaTagQuery
+				continue //Skip over rest of code for this tag
+			} else if tag.Name == "Ratio" { //Special Exception for Ratio
+				tagFloatValue, isTagValued := tag.MetaValue.(float64)
+				if isTagValued == false {
+					return ToReturn, 0, errors.New("Failed get value of " + tag.Name)
+				}
+				//Images without a height divide by NULL, so never match
+				ratioQuery := "(Images.Width / NULLIF(Images.Height, 0))"
+				ratioValue := strconv.FormatFloat(tagFloatValue, 'f', -1, 64)
+				if comparator == "=" {
+					metaTagQuery += "ABS(" + ratioQuery + " - " + ratioValue + ") < " + strconv.FormatFloat(tagquery.RatioTolerance, 'f', -1, 64) + " "
+				} else if comparator == "!=" {
+					metaTagQuery += "ABS(" + ratioQuery + " - " + ratioValue + ") >= " + strconv.FormatFloat(tagquery.RatioTolerance, 'f', -1, 64) + " "
+				} else {
+					metaTagQuery += ratioQuery + " " + comparator + " " + ratioValue + " "
+				}
+				sqlWhereClause = sqlWhereClause + metaTagQuery
+				continue //Skip over rest of code for this tag
 			}
 
 			metaTagQuery = metaTagQuery + "Images." + tag.Name + " "
@@ -154,7 +188,7 @@ func (DBConnection *MariaDBPlugin) SearchImages(Tags []interfaces.TagInformation
 	//Add values for metatags
 	for _, tag := range MetaTags {
 		//Handle Complex Tags Here
-		if tag.Name == "InCollection" || tag.Name == "TagCount" || tag.Name == "Similar" { //Special Exception for cert MetaTags
+		if tag.Name == "InCollection" || tag.Name == "TagCount" || tag.Name == "Similar" || tag.Name == "Ratio" || tag.Name == "Color" { //Special Exception for cert MetaTags
 			continue
 		}
 		//Otherwise use default
@@ -167,7 +201,7 @@ func (DBConnection *MariaDBPlugin) SearchImages(Tags []interfaces.TagInformation
 	}
 
 	//Run the count query (Count query does not use start/stride, so run this before we add those)
-	err := DBConnection.DBHandle.QueryRow(sqlCountQuery, queryArray...).Scan(&MaxResults)
+	err := DBConnection.handle().QueryRow(sqlCountQuery, queryArray...).Scan(&MaxResults)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/SearchImages", "0", logging.ResultFailure, []string{"Error running search query", sqlCountQuery, err.Error()})
 		return nil, 0, err
@@ -178,7 +212,7 @@ func (DBConnection *MariaDBPlugin) SearchImages(Tags []interfaces.TagInformation
 	queryArray = append(queryArray, PageStart)
 
 	//Now we have query and args, run the query
-	rows, err := DBConnection.DBHandle.Query(sqlQuery, queryArray...)
+	rows, err := DBConnection.handle().Query(sqlQuery, queryArray...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -187,15 +221,16 @@ func (DBConnection *MariaDBPlugin) SearchImages(Tags []interfaces.TagInformation
 	var ImageID uint64
 	var Name string
 	var Location string
+	var BlurHash string
 	//For each row
 	for rows.Next() {
 		//Parse out the data
-		err := rows.Scan(&ImageID, &Name, &Location)
+		err := rows.Scan(&ImageID, &Name, &Location, &BlurHash)
 		if err != nil {
 			return nil, 0, err
 		}
 		//Add this result to ToReturn
-		ToReturn = append(ToReturn, interfaces.ImageInformation{Name: Name, ID: ImageID, Location: Location})
+		ToReturn = append(ToReturn, interfaces.ImageInformation{Name: Name, ID: ImageID, Location: Location, BlurHash: BlurHash})
 	}
 	return ToReturn, MaxResults, nil
 }
@@ -335,6 +370,39 @@ func (DBConnection *MariaDBPlugin) getPrevNexImage(Tags []interfaces.TagInformat
 				metaTagQuery += "Images.ID IN (SELECT ImageID FROM ImagedHashes WHERE (BIT_COUNT(hHash ^ " + strconv.FormatUint(tagImagedHashValue.ImagehHash, 10) + ")+BIT_COUNT(vHash ^ " + strconv.FormatUint(tagImagedHashValue.ImagevHash, 10) + ")) " + comparator + " " + strconv.FormatUint(tagImagedHashValue.SimilarityThreshold, 10) + ") "
 				sqlWhereClause = sqlWhereClause + metaTagQuery
 				continue //Skip over rest of code for this tag
+			} else if tag.Name == "Color" { //Special Exception for Color
+				tagColorValue, isTagValued := tag.MetaValue.(interfaces.ImageColorSearch)
+				if isTagValued == false {
+					return ToReturn, errors.New("Failed get value of " + tag.Name)
+				}
+				colorComparator := " IN "
+				if comparator != "=" {
+					colorComparator = " NOT IN "
+				}
+				//Images whose dominant colours within tolerance add up to enough of the image
+				red := strconv.FormatUint(uint64(tagColorValue.Red), 10)
+				green := strconv.FormatUint(uint64(tagColorValue.Green), 10)
+				blue := strconv.FormatUint(uint64(tagColorValue.Blue), 10)
+				metaTagQuery += "Images.ID" + colorComparator + "(SELECT ImageID FROM ImageColors WHERE (Red - " + red + ") * (Red - " + red + ") + (Green - " + green + ") * (Green - " + green + ") + (Blue - " + blue + ") * (Blue - " + blue + ") <= " + strconv.FormatUint(tagColorValue.Tolerance*tagColorValue.Tolerance, 10) + " GROUP BY ImageID HAVING SUM(Share) >= " + strconv.FormatFloat(tagColorValue.MinimumShare, 'f', -1, 64) + ") "
+				sqlWhereClause = sqlWhereClause + metaTagQuery
+				continue //Skip over rest of code for this tag
+			} else if tag.Name == "Ratio" { //Special Exception for Ratio
+				tagFloatValue, isTagValued := tag.MetaValue.(float64)
+				if isTagValued == false {
+					return ToReturn, errors.New("Failed get value of " + tag.Name)
+				}
+				//Images without a height divide by NULL, so never match
+				ratioQuery := "(Images.Width / NULLIF(Images.Height, 0))"
+				ratioValue := strconv.FormatFloat(tagFloatValue, 'f', -1, 64)
+				if comparator == "=" {
+					metaTagQuery += "ABS(" + ratioQuery + " - " + ratioValue + ") < " + strconv.FormatFloat(tagquery.RatioTolerance, 'f', -1, 64) + " "
+				} else if comparator == "!=" {
+					metaTagQuery += "ABS(" + ratioQuery + " - " + ratioValue + ") >= " + strconv.FormatFloat(tagquery.RatioTolerance, 'f', -1, 64) + " "
+				} else {
+					metaTagQuery += ratioQuery + " " + comparator + " " + ratioValue + " "
+				}
+				sqlWhereClause = sqlWhereClause + metaTagQuery
+				continue //Skip over rest of code for this tag
 			}
 
 			metaTagQuery = metaTagQuery + "Images." + tag.Name + " "
@@ -389,7 +457,7 @@ func (DBConnection *MariaDBPlugin) getPrevNexImage(Tags []interfaces.TagInformat
 	//Add values for metatags
 	for _, tag := range MetaTags {
 		//Handle Complex Tags Here
-		if tag.Name == "InCollection" || tag.Name == "TagCount" || tag.Name == "Similar" { //Special Exception for cert MetaTags
+		if tag.Name == "InCollection" || tag.Name == "TagCount" || tag.Name == "Similar" || tag.Name == "Ratio" || tag.Name == "Color" { //Special Exception for cert MetaTags
 			continue
 		}
 		//Otherwise use default
@@ -405,7 +473,7 @@ func (DBConnection *MariaDBPlugin) getPrevNexImage(Tags []interfaces.TagInformat
 	}
 
 	//Run the count query (Count query does not use start/stride, so run this before we add those)
-	/*err := DBConnection.DBHandle.QueryRow(sqlCountQuery, queryArray...).Scan(&MaxResults) //Uneeded
+	/*err := DBConnection.handle().QueryRow(sqlCountQuery, queryArray...).Scan(&MaxResults) //Uneeded
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError,"MariaDBPlugin/SearchImages", "0", logging.ResultFailure, []string{"Error running search query", sqlCountQuery, err.Error()})
 		return nil, 0, err
@@ -421,7 +489,7 @@ func (DBConnection *MariaDBPlugin) getPrevNexImage(Tags []interfaces.TagInformat
 	var Location string
 
 	//Now we have query and args, run the query
-	err := DBConnection.DBHandle.QueryRow(sqlQuery, queryArray...).Scan(&ImageID, &Name, &Location)
+	err := DBConnection.handle().QueryRow(sqlQuery, queryArray...).Scan(&ImageID, &Name, &Location)
 	if err != nil {
 		return ToReturn, err
 	}
@@ -488,7 +556,7 @@ func (DBConnection *MariaDBPlugin) getPrevNextImageWithoutTags(TargetID uint64,
 	var Location string
 
 	//Now we have query and args, run the query
-	err := DBConnection.DBHandle.QueryRow(sqlQuery, TargetID).Scan(&ImageID, &Name, &Location)
+	err := DBConnection.handle().QueryRow(sqlQuery, TargetID).Scan(&ImageID, &Name, &Location)
 	if err != nil {
 		return ToReturn, err
 	}
@@ -16,7 +16,7 @@ func (DBConnection *MariaDBPlugin) GetImageTags(ImageID uint64) ([]interfaces.Ta
 
 	sqlQuery := "SELECT Tags.ID, Tags.Name, Tags.Description FROM ImageTags INNER JOIN Tags ON Tags.ID = ImageTags.TagID WHERE ImageID=?"
 	//Pass the sql query to DB
-	rows, err := DBConnection.DBHandle.Query(sqlQuery, ImageID)
+	rows, err := DBConnection.handle().Query(sqlQuery, ImageID)
 	if err != nil {
 		return nil, err
 	}
@@ -45,7 +45,7 @@ func (DBConnection *MariaDBPlugin) GetImageTags(ImageID uint64) ([]interfaces.Ta
 
 //RemoveTag remove a tag association
 func (DBConnection *MariaDBPlugin) RemoveTag(TagID uint64, ImageID uint64) error {
-	if _, err := DBConnection.DBHandle.Exec("DELETE FROM ImageTags WHERE TagID=? AND ImageID=?;", TagID, ImageID); err != nil {
+	if _, err := DBConnection.handle().Exec("DELETE FROM ImageTags WHERE TagID=? AND ImageID=?;", TagID, ImageID); err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/RemoveTag", "0", logging.ResultFailure, []string{"Tag to remove was not on image", strconv.FormatUint(TagID, 10), strconv.FormatUint(ImageID, 10), err.Error()})
 		return err
 	}
@@ -81,13 +81,13 @@ func (DBConnection *MariaDBPlugin) ReplaceImageTags(OldTagID uint64, NewTagID ui
 	(
 		SELECT ImageID from ImageTags WHERE TagID=?
 	);`
-	_, err := DBConnection.DBHandle.Exec(query, NewTagID, LinkerID, OldTagID, NewTagID)
+	_, err := DBConnection.handle().Exec(query, NewTagID, LinkerID, OldTagID, NewTagID)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/ReplaceImageTags", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Failed to update imagetags", err.Error()})
 		return err
 	}
 	//Remove any instances of old tag, first query replaces the old tag on all images, but does not allow duplicates. This query will remove the old tag that would have been replaced if it would not have lead to a duplicate.
-	_, err = DBConnection.DBHandle.Exec("DELETE FROM ImageTags WHERE TagID=?;", OldTagID)
+	_, err = DBConnection.handle().Exec("DELETE FROM ImageTags WHERE TagID=?;", OldTagID)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/ReplaceImageTags", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Failed to remove old instances of tag", err.Error()})
 		return err
@@ -114,7 +114,7 @@ func (DBConnection *MariaDBPlugin) BulkAddTag(TagID uint64, OldTagID uint64, Lin
 		OldTagID = oldTagInfo.AliasedID
 	}
 
-	if _, err := DBConnection.DBHandle.Exec("INSERT INTO ImageTags (TagID, ImageID, LinkerID) SELECT ?, ImageID, ? FROM ImageTags WHERE TagID=? AND ImageID NOT IN (SELECT ImageID FROM ImageTags WHERE TagID=?);", TagID, LinkerID, OldTagID, TagID); err != nil {
+	if _, err := DBConnection.handle().Exec("INSERT INTO ImageTags (TagID, ImageID, LinkerID) SELECT ?, ImageID, ? FROM ImageTags WHERE TagID=? AND ImageID NOT IN (SELECT ImageID FROM ImageTags WHERE TagID=?);", TagID, LinkerID, OldTagID, TagID); err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/BulkAddTag", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Tag not added to image", strconv.FormatUint(OldTagID, 10), strconv.FormatUint(TagID, 10), err.Error()})
 		return err
 	}
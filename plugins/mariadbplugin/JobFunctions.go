@@ -0,0 +1,149 @@
+package mariadbplugin
+
+import (
+	"database/sql"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"strconv"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+//jobColumns are the columns scanJob expects, in order
+const jobColumns = "ID, Type, Payload, Status, Attempts, MaxAttempts, Progress, Total, LastError, CreationTime, UpdateTime, RunAfter"
+
+//scanJob reads a row of jobColumns
+func scanJob(Row interface{ Scan(...interface{}) error }) (interfaces.JobInformation, error) {
+	var job interfaces.JobInformation
+	var CreationTime, UpdateTime, RunAfter mysql.NullTime
+	if err := Row.Scan(&job.ID, &job.Type, &job.Payload, &job.Status, &job.Attempts, &job.MaxAttempts, &job.Progress, &job.Total, &job.LastError, &CreationTime, &UpdateTime, &RunAfter); err != nil {
+		return job, err
+	}
+	if CreationTime.Valid {
+		job.CreationTime = CreationTime.Time
+	}
+	if UpdateTime.Valid {
+		job.UpdateTime = UpdateTime.Time
+	}
+	if RunAfter.Valid {
+		job.RunAfter = RunAfter.Time
+	}
+	return job, nil
+}
+
+//AddJob adds a job to the queue to be run as soon as a worker is free, returns the job ID and/or error
+func (DBConnection *MariaDBPlugin) AddJob(Type string, Payload string, MaxAttempts uint64) (uint64, error) {
+	resultInfo, err := DBConnection.handle().Exec("INSERT INTO Jobs (Type, Payload, MaxAttempts) VALUES (?, ?, ?);", Type, Payload, MaxAttempts)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/AddJob", "0", logging.ResultFailure, []string{"Failed to add job", Type, Payload, err.Error()})
+		return 0, err
+	}
+	lastID, err := resultInfo.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(lastID), nil
+}
+
+//ClaimJob marks the oldest job that is ready as running and returns it, along with running jobs not updated within LeaseTime. Returns sql.ErrNoRows if none are ready
+func (DBConnection *MariaDBPlugin) ClaimJob(LeaseTime time.Duration) (interfaces.JobInformation, error) {
+	for true {
+		var JobID, Attempts uint64
+		err := DBConnection.handle().QueryRow("SELECT ID, Attempts FROM Jobs WHERE (Status='queued' AND RunAfter <= CURRENT_TIMESTAMP) OR (Status='running' AND UpdateTime < DATE_SUB(CURRENT_TIMESTAMP, INTERVAL ? SECOND)) ORDER BY ID LIMIT 1;", int64(LeaseTime.Seconds())).Scan(&JobID, &Attempts)
+		if err != nil {
+			if err != sql.ErrNoRows {
+				logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/ClaimJob", "0", logging.ResultFailure, []string{"Failed to claim job", err.Error()})
+			}
+			return interfaces.JobInformation{}, err
+		}
+		//Every claim increments Attempts, so this only matches if no other worker claimed the job first
+		resultInfo, err := DBConnection.handle().Exec("UPDATE Jobs SET Status='running', Attempts=Attempts+1, UpdateTime=CURRENT_TIMESTAMP WHERE ID=? AND Attempts=?;", JobID, Attempts)
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/ClaimJob", "0", logging.ResultFailure, []string{"Failed to claim job", err.Error()})
+			return interfaces.JobInformation{}, err
+		}
+		if claimed, err := resultInfo.RowsAffected(); err == nil && claimed == 1 {
+			return DBConnection.GetJob(JobID)
+		}
+	}
+	return interfaces.JobInformation{}, sql.ErrNoRows
+}
+
+//UpdateJobProgress records how far a running job is, and that it is still running
+func (DBConnection *MariaDBPlugin) UpdateJobProgress(JobID uint64, Progress uint64, Total uint64) error {
+	_, err := DBConnection.handle().Exec("UPDATE Jobs SET Progress=?, Total=?, UpdateTime=CURRENT_TIMESTAMP WHERE ID=?;", Progress, Total, JobID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/UpdateJobProgress", "0", logging.ResultFailure, []string{"Failed to update job progress", strconv.FormatUint(JobID, 10), err.Error()})
+	}
+	return err
+}
+
+//FinishJob sets a job's status and error. A job set back to queued is not claimed again until RetryDelay has passed
+func (DBConnection *MariaDBPlugin) FinishJob(JobID uint64, Status string, LastError string, RetryDelay time.Duration) error {
+	_, err := DBConnection.handle().Exec("UPDATE Jobs SET Status=?, LastError=?, UpdateTime=CURRENT_TIMESTAMP, RunAfter=DATE_ADD(CURRENT_TIMESTAMP, INTERVAL ? SECOND) WHERE ID=?;", Status, LastError, int64(RetryDelay.Seconds()), JobID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/FinishJob", "0", logging.ResultFailure, []string{"Failed to finish job", strconv.FormatUint(JobID, 10), Status, err.Error()})
+	}
+	return err
+}
+
+//GetJob returns one job
+func (DBConnection *MariaDBPlugin) GetJob(JobID uint64) (interfaces.JobInformation, error) {
+	return scanJob(DBConnection.handle().QueryRow("SELECT "+jobColumns+" FROM Jobs WHERE ID=?;", JobID))
+}
+
+//GetLatestJob returns the newest job of a type, or sql.ErrNoRows if there has been none
+func (DBConnection *MariaDBPlugin) GetLatestJob(Type string) (interfaces.JobInformation, error) {
+	return scanJob(DBConnection.handle().QueryRow("SELECT "+jobColumns+" FROM Jobs WHERE Type=? ORDER BY ID DESC LIMIT 1;", Type))
+}
+
+//GetLatestJobWithPayload returns the newest job of a type with a payload, such as the job for one image, or sql.ErrNoRows if there has been none
+func (DBConnection *MariaDBPlugin) GetLatestJobWithPayload(Type string, Payload string) (interfaces.JobInformation, error) {
+	return scanJob(DBConnection.handle().QueryRow("SELECT "+jobColumns+" FROM Jobs WHERE Type=? AND Payload=? ORDER BY ID DESC LIMIT 1;", Type, Payload))
+}
+
+//GetJobs returns jobs with a status, or all jobs when Status is "", newest first (Returns a list of jobs, the count of all matching jobs, and or error)
+func (DBConnection *MariaDBPlugin) GetJobs(Status string, PageStart uint64, PageStride uint64) ([]interfaces.JobInformation, uint64, error) {
+	whereQuery := ""
+	queryArray := []interface{}{}
+	if Status != "" {
+		whereQuery = " WHERE Status=?"
+		queryArray = append(queryArray, Status)
+	}
+	var MaxResults uint64
+	if err := DBConnection.handle().QueryRow("SELECT COUNT(*) FROM Jobs"+whereQuery, queryArray...).Scan(&MaxResults); err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/GetJobs", "0", logging.ResultFailure, []string{"Failed to count jobs", err.Error()})
+		return nil, 0, err
+	}
+	sqlQuery := "SELECT " + jobColumns + " FROM Jobs" + whereQuery + " ORDER BY ID DESC"
+	if PageStride > 0 {
+		sqlQuery += " LIMIT ? OFFSET ?;"
+		queryArray = append(queryArray, PageStride, PageStart)
+	}
+	rows, err := DBConnection.handle().Query(sqlQuery, queryArray...)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/GetJobs", "0", logging.ResultFailure, []string{"Failed to query jobs", err.Error()})
+		return nil, 0, err
+	}
+	defer rows.Close()
+	var ToReturn []interfaces.JobInformation
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		ToReturn = append(ToReturn, job)
+	}
+	return ToReturn, MaxResults, rows.Err()
+}
+
+//RemoveJobs removes jobs with a status that have not changed within OlderThan, returns the count removed
+func (DBConnection *MariaDBPlugin) RemoveJobs(Status string, OlderThan time.Duration) (int64, error) {
+	resultInfo, err := DBConnection.handle().Exec("DELETE FROM Jobs WHERE Status=? AND UpdateTime <= DATE_SUB(CURRENT_TIMESTAMP, INTERVAL ? SECOND);", Status, int64(OlderThan.Seconds()))
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/RemoveJobs", "0", logging.ResultFailure, []string{"Failed to remove jobs", Status, err.Error()})
+		return 0, err
+	}
+	return resultInfo.RowsAffected()
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"go-image-board/config"
+	"go-image-board/interfaces"
 	"go-image-board/logging"
 	"strconv"
 
@@ -15,7 +16,7 @@ import (
 )
 
 //TODO: Increment this whenever we alter the DB Schema, ensure you attempt to add update code below
-var currentDBVersion int64 = 13
+var currentDBVersion int64 = 19
 
 //TODO: Increment this when we alter the db schema and don't add update code to compensate
 var minSupportedDBVersion int64 // 0 by default
@@ -23,6 +24,53 @@ var minSupportedDBVersion int64 // 0 by default
 //MariaDBPlugin acts as plugin between gib and a Maria/MySQL DB
 type MariaDBPlugin struct {
 	DBHandle *sql.DB
+	//tx is set on the copy handed to RunInTransaction's Work, so its queries run inside the transaction
+	tx *sql.Tx
+}
+
+//sqlHandle is satisfied by both sql.DB and sql.Tx, so the same queries run inside and outside a transaction
+type sqlHandle interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+//handle returns the transaction when there is one, or the database otherwise
+func (DBConnection *MariaDBPlugin) handle() sqlHandle {
+	if DBConnection.tx != nil {
+		return DBConnection.tx
+	}
+	return DBConnection.DBHandle
+}
+
+//background runs Work in a goroutine, or straight away inside a transaction as it cannot be used once committed
+func (DBConnection *MariaDBPlugin) background(Work func()) {
+	if DBConnection.tx != nil {
+		Work()
+		return
+	}
+	go Work()
+}
+
+//RunInTransaction runs Work against a copy of the plugin bound to one transaction, committing if Work returns nil and rolling back otherwise
+func (DBConnection *MariaDBPlugin) RunInTransaction(Work func(Transaction interfaces.DBInterface) error) error {
+	if DBConnection.tx != nil {
+		return Work(DBConnection) //Already in one
+	}
+	tx, err := DBConnection.DBHandle.Begin()
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/RunInTransaction", "0", logging.ResultFailure, []string{"Failed to begin transaction", err.Error()})
+		return err
+	}
+	defer tx.Rollback() //Does nothing once committed, but covers Work panicking
+	if err := Work(&MariaDBPlugin{DBHandle: DBConnection.DBHandle, tx: tx}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/RunInTransaction", "0", logging.ResultFailure, []string{"Failed to commit transaction", err.Error()})
+		return err
+	}
+	return nil
 }
 
 //InitDatabase connects to a database, and if needed, creates and or updates tables
@@ -45,17 +93,6 @@ func (DBConnection *MariaDBPlugin) InitDatabase() error {
 						return err
 					}
 				}
-				//Validate Events
-				var EventsEnabled string
-				row := DBConnection.DBHandle.QueryRow("SELECT @@global.event_scheduler")
-				err := row.Scan(&EventsEnabled)
-				if err != nil {
-					logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to get event scheduler setting", err.Error()})
-				} else {
-					if EventsEnabled != "ON" {
-						logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Event scheduler is set to", EventsEnabled, "this may prevent automatic maitenance tasks from running"})
-					}
-				}
 			} else {
 				logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to get database version, assuming not installed. Will attempt to perform install.", err.Error()})
 				//Assume no database installed. Perform fresh install
@@ -100,7 +137,7 @@ func (DBConnection *MariaDBPlugin) performFreshDBInstall() error {
 		return err
 	}
 	//Images
-	_, err = DBConnection.DBHandle.Exec("CREATE TABLE Images (ID BIGINT UNSIGNED NOT NULL AUTO_INCREMENT UNIQUE, UploaderID BIGINT UNSIGNED NOT NULL, Name VARCHAR(255) NOT NULL, Rating VARCHAR(255) DEFAULT 'unrated', ScoreTotal BIGINT NOT NULL DEFAULT 0, ScoreAverage BIGINT NOT NULL DEFAULT 0, ScoreVoters BIGINT NOT NULL DEFAULT 0, Location VARCHAR(255) UNIQUE NOT NULL, Source VARCHAR(2000) NOT NULL DEFAULT '', UploadTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, Description TEXT NOT NULL DEFAULT '', INDEX(UploaderID), INDEX(Rating), INDEX(UploadTime), INDEX(ScoreAverage));")
+	_, err = DBConnection.DBHandle.Exec("CREATE TABLE Images (ID BIGINT UNSIGNED NOT NULL AUTO_INCREMENT UNIQUE, UploaderID BIGINT UNSIGNED NOT NULL, Name VARCHAR(255) NOT NULL, Rating VARCHAR(255) DEFAULT 'unrated', ScoreTotal BIGINT NOT NULL DEFAULT 0, ScoreAverage BIGINT NOT NULL DEFAULT 0, ScoreVoters BIGINT NOT NULL DEFAULT 0, Location VARCHAR(255) UNIQUE NOT NULL, Source VARCHAR(2000) NOT NULL DEFAULT '', UploadTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, Description TEXT NOT NULL DEFAULT '', Width BIGINT UNSIGNED NOT NULL DEFAULT 0, Height BIGINT UNSIGNED NOT NULL DEFAULT 0, FileSize BIGINT UNSIGNED NOT NULL DEFAULT 0, MIMEType VARCHAR(255) NOT NULL DEFAULT '', Duration DOUBLE NOT NULL DEFAULT 0, Bitrate BIGINT UNSIGNED NOT NULL DEFAULT 0, BlurHash VARCHAR(64) NOT NULL DEFAULT '', INDEX(UploaderID), INDEX(Rating), INDEX(UploadTime), INDEX(ScoreAverage));")
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/performFreshDBInstall", "0", logging.ResultFailure, []string{"Failed to install database", err.Error()})
 		return err
@@ -115,6 +152,21 @@ func (DBConnection *MariaDBPlugin) performFreshDBInstall() error {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/performFreshDBInstall", "0", logging.ResultFailure, []string{"Failed to install database", err.Error()})
 		return err
 	}
+	_, err = DBConnection.DBHandle.Exec(imageMetadataTable)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/performFreshDBInstall", "0", logging.ResultFailure, []string{"Failed to install database", err.Error()})
+		return err
+	}
+	_, err = DBConnection.DBHandle.Exec(imageMetadataAudioColumns)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/performFreshDBInstall", "0", logging.ResultFailure, []string{"Failed to install database", err.Error()})
+		return err
+	}
+	_, err = DBConnection.DBHandle.Exec(imageColorsTable)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/performFreshDBInstall", "0", logging.ResultFailure, []string{"Failed to install database", err.Error()})
+		return err
+	}
 	_, err = DBConnection.DBHandle.Exec("CREATE TABLE ImageUserScores (ID BIGINT UNSIGNED NOT NULL AUTO_INCREMENT UNIQUE, UserID BIGINT UNSIGNED NOT NULL, ImageID BIGINT UNSIGNED NOT NULL, Score BIGINT NOT NULL, CreationTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, UNIQUE INDEX ImageUserPair (UserID,ImageID));")
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/performFreshDBInstall", "0", logging.ResultFailure, []string{"Failed to install database", err.Error()})
@@ -148,7 +200,13 @@ func (DBConnection *MariaDBPlugin) performFreshDBInstall() error {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/performFreshDBInstall", "0", logging.ResultFailure, []string{"Failed to install database", err.Error()})
 		return err
 	}
-	//Stored Procedures, Triggers, Events
+	//Background jobs
+	_, err = DBConnection.DBHandle.Exec(jobsTable)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/performFreshDBInstall", "0", logging.ResultFailure, []string{"Failed to install database", err.Error()})
+		return err
+	}
+	//Stored Procedures, Triggers
 	sqlQuery := `CREATE PROCEDURE LinkCollTags(IN collID BIGINT UNSIGNED)
 	BEGIN
 	-- Insert missing tags
@@ -208,15 +266,6 @@ func (DBConnection *MariaDBPlugin) performFreshDBInstall() error {
 		return err
 	}
 
-	sqlQuery = `CREATE EVENT auditCleanup 
-	ON SCHEDULE EVERY 1 DAY 
-	DO 
-	DELETE FROM AuditLogs WHERE LogTime < DATE_SUB(current_timestamp(), INTERVAL 30 DAY);`
-	if _, err := DBConnection.DBHandle.Exec(sqlQuery); err != nil {
-		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/performFreshDBInstall", "0", logging.ResultFailure, []string{"Failed to install database", err.Error()})
-		return err
-	}
-
 	sqlQuery = `CREATE TRIGGER onCollectionDelete BEFORE DELETE ON Collections
 	FOR EACH ROW BEGIN
 		DELETE FROM CollectionMembers WHERE CollectionID=OLD.ID;
@@ -265,14 +314,7 @@ func (DBConnection *MariaDBPlugin) performFreshDBInstall() error {
 		return err
 	}
 
-	sqlQuery = `CREATE TRIGGER onImageDelete BEFORE DELETE ON Images
-	FOR EACH ROW BEGIN
-		DELETE FROM ImageTags WHERE ImageID=OLD.ID;
-		DELETE FROM ImageUserScores WHERE ImageID=OLD.ID;
-		DELETE FROM CollectionMembers WHERE ImageID=OLD.ID;
-		DELETE FROM ImagedHashes WHERE ImageID=OLD.ID;
-	END`
-	if _, err := DBConnection.DBHandle.Exec(sqlQuery); err != nil {
+	if _, err := DBConnection.DBHandle.Exec(imageDeleteTrigger); err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/performFreshDBInstall", "0", logging.ResultFailure, []string{"Failed to install database", err.Error()})
 		return err
 	}
@@ -298,6 +340,30 @@ func (DBConnection *MariaDBPlugin) performFreshDBInstall() error {
 	return nil
 }
 
+//imageMetadataTable is shared by the fresh install and the upgrade to version 16
+//CaptureTime is a DATETIME as cameras record local time without a time zone
+const imageMetadataTable = "CREATE TABLE ImageMetadata (ImageID BIGINT UNSIGNED NOT NULL, CaptureTime DATETIME NULL, CameraMake VARCHAR(255) NOT NULL DEFAULT '', CameraModel VARCHAR(255) NOT NULL DEFAULT '', LensModel VARCHAR(255) NOT NULL DEFAULT '', ExposureTime VARCHAR(40) NOT NULL DEFAULT '', FNumber VARCHAR(40) NOT NULL DEFAULT '', FocalLength VARCHAR(40) NOT NULL DEFAULT '', ISO BIGINT UNSIGNED NOT NULL DEFAULT 0, Orientation BIGINT UNSIGNED NOT NULL DEFAULT 0, PRIMARY KEY(ImageID), CONSTRAINT fk_ImageMetadataImageID FOREIGN KEY (ImageID) REFERENCES Images(ID));"
+
+//imageMetadataAudioColumns is added by both the fresh install and the upgrade to version 18, so the upgrade to version 16 still creates the table it always has
+const imageMetadataAudioColumns = "ALTER TABLE ImageMetadata ADD COLUMN Title VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN Artist VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN Album VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN Genre VARCHAR(255) NOT NULL DEFAULT '';"
+
+//imageColorsTable is shared by the fresh install and the upgrade to version 19
+const imageColorsTable = "CREATE TABLE ImageColors (ID BIGINT UNSIGNED NOT NULL AUTO_INCREMENT UNIQUE, ImageID BIGINT UNSIGNED NOT NULL, Red BIGINT NOT NULL, Green BIGINT NOT NULL, Blue BIGINT NOT NULL, Share DOUBLE NOT NULL, INDEX(ImageID), CONSTRAINT fk_ImageColorsImageID FOREIGN KEY (ImageID) REFERENCES Images(ID));"
+
+//imageDeleteTrigger is shared by the fresh install and the upgrades to version 16 and 19, which added ImageMetadata and ImageColors to it
+const imageDeleteTrigger = `CREATE TRIGGER onImageDelete BEFORE DELETE ON Images
+	FOR EACH ROW BEGIN
+		DELETE FROM ImageTags WHERE ImageID=OLD.ID;
+		DELETE FROM ImageUserScores WHERE ImageID=OLD.ID;
+		DELETE FROM CollectionMembers WHERE ImageID=OLD.ID;
+		DELETE FROM ImagedHashes WHERE ImageID=OLD.ID;
+		DELETE FROM ImageMetadata WHERE ImageID=OLD.ID;
+		DELETE FROM ImageColors WHERE ImageID=OLD.ID;
+	END`
+
+//jobsTable is shared by the fresh install and the upgrade to version 14
+const jobsTable = "CREATE TABLE Jobs (ID BIGINT UNSIGNED NOT NULL AUTO_INCREMENT UNIQUE, Type VARCHAR(40) NOT NULL, Payload VARCHAR(2000) NOT NULL DEFAULT '', Status VARCHAR(20) NOT NULL DEFAULT 'queued', Attempts BIGINT UNSIGNED NOT NULL DEFAULT 0, MaxAttempts BIGINT UNSIGNED NOT NULL DEFAULT 1, Progress BIGINT UNSIGNED NOT NULL DEFAULT 0, Total BIGINT UNSIGNED NOT NULL DEFAULT 0, LastError VARCHAR(2000) NOT NULL DEFAULT '', CreationTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, UpdateTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, RunAfter TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, INDEX(Status));"
+
 //TODO: Add update code here
 func (DBConnection *MariaDBPlugin) upgradeDatabase(version int64) (int64, error) {
 	//Update version 0 -> 1
@@ -814,5 +880,92 @@ func (DBConnection *MariaDBPlugin) upgradeDatabase(version int64) (int64, error)
 		version = 13
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/InitDatabase", "0", logging.ResultInfo, []string{"Database schema updated to version", strconv.FormatInt(version, 10)})
 	}
+	//Update version 13->14
+	if version == 13 {
+		if _, err := DBConnection.DBHandle.Exec(jobsTable); err != nil {
+			logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+			return version, err
+		}
+		if _, err := DBConnection.DBHandle.Exec("UPDATE DBVersion SET version = 14;"); err != nil {
+			logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+			return version, err
+		}
+		version = 14
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/InitDatabase", "0", logging.ResultInfo, []string{"Database schema updated to version", strconv.FormatInt(version, 10)})
+	}
+	//Update version 14->15
+	if version == 14 {
+		//Audit logs are now removed by the scheduler, which does not depend on event_scheduler being on
+		if _, err := DBConnection.DBHandle.Exec("DROP EVENT IF EXISTS auditCleanup;"); err != nil {
+			logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+			return version, err
+		}
+		if _, err := DBConnection.DBHandle.Exec("UPDATE DBVersion SET version = 15;"); err != nil {
+			logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+			return version, err
+		}
+		version = 15
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/InitDatabase", "0", logging.ResultInfo, []string{"Database schema updated to version", strconv.FormatInt(version, 10)})
+	}
+	//Update version 15->16
+	if version == 15 {
+		for _, sqlQuery := range []string{imageMetadataTable, "DROP TRIGGER onImageDelete;", imageDeleteTrigger, "UPDATE DBVersion SET version = 16;"} {
+			if _, err := DBConnection.DBHandle.Exec(sqlQuery); err != nil {
+				logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+				return version, err
+			}
+		}
+		version = 16
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/InitDatabase", "0", logging.ResultInfo, []string{"Database schema updated to version", strconv.FormatInt(version, 10)})
+	}
+	//Update version 16->17
+	if version == 16 {
+		//Existing images are left at zero until the media-info job fills them in
+		for _, sqlQuery := range []string{
+			"ALTER TABLE Images ADD COLUMN Width BIGINT UNSIGNED NOT NULL DEFAULT 0, ADD COLUMN Height BIGINT UNSIGNED NOT NULL DEFAULT 0, ADD COLUMN FileSize BIGINT UNSIGNED NOT NULL DEFAULT 0, ADD COLUMN MIMEType VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN Duration DOUBLE NOT NULL DEFAULT 0;",
+			"UPDATE DBVersion SET version = 17;",
+		} {
+			if _, err := DBConnection.DBHandle.Exec(sqlQuery); err != nil {
+				logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+				return version, err
+			}
+		}
+		version = 17
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/InitDatabase", "0", logging.ResultInfo, []string{"Database schema updated to version", strconv.FormatInt(version, 10)})
+	}
+	//Update version 17->18
+	if version == 17 {
+		//Existing audio keeps no tags, they are only read when a file is uploaded
+		for _, sqlQuery := range []string{
+			"ALTER TABLE Images ADD COLUMN Bitrate BIGINT UNSIGNED NOT NULL DEFAULT 0;",
+			imageMetadataAudioColumns,
+			"UPDATE DBVersion SET version = 18;",
+		} {
+			if _, err := DBConnection.DBHandle.Exec(sqlQuery); err != nil {
+				logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+				return version, err
+			}
+		}
+		version = 18
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/InitDatabase", "0", logging.ResultInfo, []string{"Database schema updated to version", strconv.FormatInt(version, 10)})
+	}
+	//Update version 18->19
+	if version == 18 {
+		//Existing images get their colours when the hash job next runs over them
+		for _, sqlQuery := range []string{
+			"ALTER TABLE Images ADD COLUMN BlurHash VARCHAR(64) NOT NULL DEFAULT '';",
+			imageColorsTable,
+			"DROP TRIGGER onImageDelete;",
+			imageDeleteTrigger,
+			"UPDATE DBVersion SET version = 19;",
+		} {
+			if _, err := DBConnection.DBHandle.Exec(sqlQuery); err != nil {
+				logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+				return version, err
+			}
+		}
+		version = 19
+		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/InitDatabase", "0", logging.ResultInfo, []string{"Database schema updated to version", strconv.FormatInt(version, 10)})
+	}
 	return version, nil
 }
@@ -0,0 +1,52 @@
+package mariadbplugin
+
+import (
+	"database/sql"
+	"go-image-board/config"
+	"go-image-board/database/dbtest"
+	"go-image-board/interfaces"
+	"os"
+	"testing"
+)
+
+//TestConformance runs against the server in GIB_TEST_MARIADB_HOST, the database in GIB_TEST_MARIADB_NAME is dropped and recreated for every test
+//The user needs rights to create databases, along with the TRIGGER and ROUTINE rights the plugin needs to install
+func TestConformance(t *testing.T) {
+	if os.Getenv("GIB_TEST_MARIADB_HOST") == "" {
+		t.Skip("GIB_TEST_MARIADB_HOST not set, skipping MariaDB conformance tests")
+	}
+	config.Configuration.DBHost = os.Getenv("GIB_TEST_MARIADB_HOST")
+	config.Configuration.DBPort = testSetting("GIB_TEST_MARIADB_PORT", "3306")
+	config.Configuration.DBUser = testSetting("GIB_TEST_MARIADB_USER", "root")
+	config.Configuration.DBPassword = os.Getenv("GIB_TEST_MARIADB_PASSWORD")
+	config.Configuration.DBName = testSetting("GIB_TEST_MARIADB_NAME", "gib_test")
+
+	dbtest.RunConformance(t, func(t *testing.T) interfaces.DBInterface {
+		server, err := sql.Open("mysql", config.Configuration.DBUser+":"+config.Configuration.DBPassword+"@tcp("+config.Configuration.DBHost+":"+config.Configuration.DBPort+")/")
+		if err != nil {
+			t.Fatalf("Failed to connect to server: %v", err)
+		}
+		defer server.Close()
+		if _, err := server.Exec("DROP DATABASE IF EXISTS `" + config.Configuration.DBName + "`;"); err != nil {
+			t.Fatalf("Failed to drop test database: %v", err)
+		}
+		if _, err := server.Exec("CREATE DATABASE `" + config.Configuration.DBName + "`;"); err != nil {
+			t.Fatalf("Failed to create test database: %v", err)
+		}
+
+		DBConnection := &MariaDBPlugin{}
+		if err := DBConnection.InitDatabase(); err != nil {
+			t.Fatalf("InitDatabase: %v", err)
+		}
+		t.Cleanup(func() { DBConnection.DBHandle.Close() })
+		return DBConnection
+	})
+}
+
+//testSetting returns an environment variable, or Default when it is not set
+func testSetting(Name string, Default string) string {
+	if value := os.Getenv(Name); value != "" {
+		return value
+	}
+	return Default
+}
@@ -13,7 +13,7 @@ func (DBConnection *MariaDBPlugin) UpdateUserVoteScore(UserID uint64, ImageID ui
 	//Check if user voted before
 	sqlQuery := "SELECT COUNT(*) FROM ImageUserScores WHERE UserID=? AND ImageID=?;"
 	count := 0
-	err := DBConnection.DBHandle.QueryRow(sqlQuery, UserID, ImageID).Scan(&count)
+	err := DBConnection.handle().QueryRow(sqlQuery, UserID, ImageID).Scan(&count)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/UpdateUserVoteScore", strconv.FormatUint(UserID, 10), logging.ResultFailure, []string{"Failed to verify score existance", err.Error()})
 		return err
@@ -25,13 +25,13 @@ func (DBConnection *MariaDBPlugin) UpdateUserVoteScore(UserID uint64, ImageID ui
 		//Create if not
 		sqlQuery = "INSERT INTO ImageUserScores (Score, UserID, ImageID) VALUES (?, ?, ?);"
 	}
-	_, err = DBConnection.DBHandle.Exec(sqlQuery, Score, UserID, ImageID)
+	_, err = DBConnection.handle().Exec(sqlQuery, Score, UserID, ImageID)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/UpdateUserVoteScore", strconv.FormatUint(UserID, 10), logging.ResultFailure, []string{"Failed to update/add score", err.Error()})
 		return err
 	}
 	logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/UpdateUserVoteScore", strconv.FormatUint(UserID, 10), logging.ResultSuccess, []string{"Score added/updated"})
-	go DBConnection.UpdateScoreOnImage(ImageID)
+	DBConnection.background(func() { DBConnection.UpdateScoreOnImage(ImageID) })
 	return nil
 }
 
@@ -39,13 +39,13 @@ func (DBConnection *MariaDBPlugin) UpdateUserVoteScore(UserID uint64, ImageID ui
 func (DBConnection *MariaDBPlugin) UpdateScoreOnImage(ImageID uint64) error {
 	sqlQuery := "SELECT COUNT(Score), SUM(Score), AVG(Score) FROM ImageUserScores WHERE ImageID=?;"
 	var count, sum, average float64
-	err := DBConnection.DBHandle.QueryRow(sqlQuery, ImageID).Scan(&count, &sum, &average)
+	err := DBConnection.handle().QueryRow(sqlQuery, ImageID).Scan(&count, &sum, &average)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/UpdateScoreOnImage", "0", logging.ResultFailure, []string{"Failed to pull score metrics", err.Error()})
 		return err
 	}
 	sqlQuery = "UPDATE Images SET ScoreTotal = ?, ScoreAverage = ?, ScoreVoters = ? WHERE ID=?;"
-	_, err = DBConnection.DBHandle.Exec(sqlQuery, sum, average, count, ImageID)
+	_, err = DBConnection.handle().Exec(sqlQuery, sum, average, count, ImageID)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/UpdateScoreOnImage", "0", logging.ResultFailure, []string{"Failed to update score for image", err.Error()})
 		return err
@@ -58,7 +58,7 @@ func (DBConnection *MariaDBPlugin) GetUserVoteScore(UserID uint64, ImageID uint6
 	//Check if user voted before
 	sqlQuery := "SELECT Score FROM ImageUserScores WHERE UserID=? AND ImageID=?;"
 	var score int64
-	err := DBConnection.DBHandle.QueryRow(sqlQuery, UserID, ImageID).Scan(&score)
+	err := DBConnection.handle().QueryRow(sqlQuery, UserID, ImageID).Scan(&score)
 	if err != nil {
 		if err != sql.ErrNoRows {
 			logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/UpdateUserVoteScore", strconv.FormatUint(UserID, 10), logging.ResultFailure, []string{"Failed to verify score existance", err.Error()})
@@ -16,15 +16,15 @@ import (
 //Tag Operations
 var regexTagName = regexp.MustCompile("[^a-zA-Z0-9_-]") //Used to cleanup tag names
 var regexWhiteSpace = regexp.MustCompile("\\s{2,}")     //Matches 2 or more consecutive whitespace
-var regexTagValue = regexp.MustCompile("[^a-zA-Z0-9_\\-\\.]")
+var regexTagValue = regexp.MustCompile("[^a-zA-Z0-9_\\-\\.:/#]")
 
 func prepareTagName(Name string) string {
 	//Lowercase Name -> Trimmed front and end of whitespace -> any inner whitespace reduced and underscored
 	Name = regexWhiteSpace.ReplaceAllString(strings.TrimSpace(strings.ToLower(Name)), "_") //Replace all whitespace with _
 	//Case of metatag
-	if strings.Count(Name, ":") == 1 {
-		//Assume a metatag
-		NameValue := strings.Split(Name, ":")
+	if strings.Contains(Name, ":") {
+		//Assume a metatag, the value may contain more colons such as ratio:16:9
+		NameValue := strings.SplitN(Name, ":", 2)
 		value, comparator := getTagComparator(NameValue[1]) //Strip comparator, so it does not get replaced by a _
 		Name = regexTagName.ReplaceAllString(NameValue[0], "_") + ":" + comparator + regexTagValue.ReplaceAllString(value, "_")
 	} else {
@@ -44,7 +44,7 @@ func (DBConnection *MariaDBPlugin) NewTag(Name string, Description string, Uploa
 		return 0, errors.New("name or description outside of right sizes")
 	}
 
-	resultInfo, err := DBConnection.DBHandle.Exec("INSERT INTO Tags (Name, Description, UploaderID) VALUES (?, ?, ?);", Name, Description, UploaderID)
+	resultInfo, err := DBConnection.handle().Exec("INSERT INTO Tags (Name, Description, UploaderID) VALUES (?, ?, ?);", Name, Description, UploaderID)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/NewTag", strconv.FormatUint(UploaderID, 10), logging.ResultFailure, []string{"Failed to add tag", err.Error()})
 		return 0, err
@@ -59,7 +59,7 @@ func (DBConnection *MariaDBPlugin) NewTag(Name string, Description string, Uploa
 func (DBConnection *MariaDBPlugin) DeleteTag(TagID uint64) error {
 	//Ensure not in use
 	var useCount int
-	if err := DBConnection.DBHandle.QueryRow("SELECT COUNT(*) AS UseCount FROM ImageTags WHERE TagID = ?", TagID).Scan(&useCount); err != nil {
+	if err := DBConnection.handle().QueryRow("SELECT COUNT(*) AS UseCount FROM ImageTags WHERE TagID = ?", TagID).Scan(&useCount); err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/DeleteTag", "0", logging.ResultFailure, []string{"Failed to get tag use information", err.Error()})
 		return errors.New("failed to check tag to delete usage")
 	}
@@ -70,7 +70,7 @@ func (DBConnection *MariaDBPlugin) DeleteTag(TagID uint64) error {
 	}
 
 	//Delete
-	_, err := DBConnection.DBHandle.Exec("DELETE FROM Tags WHERE ID=?;", TagID)
+	_, err := DBConnection.handle().Exec("DELETE FROM Tags WHERE ID=?;", TagID)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/DeleteTag", "0", logging.ResultFailure, []string{"Failed to delete tag", err.Error(), strconv.FormatUint(TagID, 10)})
 	} else {
@@ -112,7 +112,7 @@ func (DBConnection *MariaDBPlugin) AddTag(TagIDs []uint64, ImageID uint64, Linke
 	values = values[:len(values)-1] + " ON DUPLICATE KEY UPDATE LinkerID=?;" //Strip last comma, add end
 	queryArray = append(queryArray, LinkerID)                                //For duplicate key update
 	sqlQuery := "INSERT INTO ImageTags (TagID, ImageID, LinkerID) VALUES" + values
-	if _, err := DBConnection.DBHandle.Exec(sqlQuery, queryArray...); err != nil {
+	if _, err := DBConnection.handle().Exec(sqlQuery, queryArray...); err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/AddTag", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Tags not added to image", strconv.FormatUint(ImageID, 10), sqlQuery, err.Error()})
 		return err
 	}
@@ -126,7 +126,7 @@ func (DBConnection *MariaDBPlugin) GetAllTags() ([]interfaces.TagInformation, er
 
 	sqlQuery := "SELECT ID, Name, Description, IsAlias FROM Tags ORDER BY Name"
 	//Pass the sql query to DB
-	rows, err := DBConnection.DBHandle.Query(sqlQuery)
+	rows, err := DBConnection.handle().Query(sqlQuery)
 	if err != nil {
 		return nil, err
 	}
@@ -167,7 +167,7 @@ func (DBConnection *MariaDBPlugin) GetTag(ID uint64, IncludeCount bool) (interfa
 	var AliasedID uint64
 	var IsAlias bool
 	var TagCount uint64
-	err := DBConnection.DBHandle.QueryRow(sqlQuery, ID).Scan(&Name, &Description, &UploaderID, &NUploadTime, &AliasedID, &IsAlias)
+	err := DBConnection.handle().QueryRow(sqlQuery, ID).Scan(&Name, &Description, &UploaderID, &NUploadTime, &AliasedID, &IsAlias)
 	if err != nil {
 		return interfaces.TagInformation{ID: ID, Exists: false}, err
 	}
@@ -183,7 +183,7 @@ func (DBConnection *MariaDBPlugin) GetTag(ID uint64, IncludeCount bool) (interfa
 
 	if IncludeCount {
 		sqlQuery := "SELECT COUNT(*) as TagCount FROM ImageTags WHERE TagID=?"
-		err := DBConnection.DBHandle.QueryRow(sqlQuery, ID).Scan(&TagCount)
+		err := DBConnection.handle().QueryRow(sqlQuery, ID).Scan(&TagCount)
 		if err != nil {
 			return interfaces.TagInformation{ID: ID, Exists: false}, err
 		}
@@ -204,7 +204,7 @@ func (DBConnection *MariaDBPlugin) GetTagByName(Name string) (interfaces.TagInfo
 	var UploadTime time.Time
 	var AliasedID uint64
 	var IsAlias bool
-	err := DBConnection.DBHandle.QueryRow(sqlQuery, Name).Scan(&TagID, &Description, &UploaderID, &NUploadTime, &AliasedID, &IsAlias)
+	err := DBConnection.handle().QueryRow(sqlQuery, Name).Scan(&TagID, &Description, &UploaderID, &NUploadTime, &AliasedID, &IsAlias)
 	if err != nil {
 		return interfaces.TagInformation{Name: Name, Exists: false}, err
 	}
@@ -238,7 +238,7 @@ func (DBConnection *MariaDBPlugin) UpdateTag(TagID uint64, Name string, Descript
 		}
 	}
 
-	_, err := DBConnection.DBHandle.Exec("UPDATE Tags SET Name = ?, Description=?, AliasedID=?, IsAlias=? WHERE ID=?;", Name, Description, AliasedID, IsAlias, TagID)
+	_, err := DBConnection.handle().Exec("UPDATE Tags SET Name = ?, Description=?, AliasedID=?, IsAlias=? WHERE ID=?;", Name, Description, AliasedID, IsAlias, TagID)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/UpdateTag", strconv.FormatUint(RequestorID, 10), logging.ResultFailure, []string{"Failed to update tag", err.Error()})
 		return err
@@ -246,7 +246,7 @@ func (DBConnection *MariaDBPlugin) UpdateTag(TagID uint64, Name string, Descript
 	logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/UpdateTag", strconv.FormatUint(RequestorID, 10), logging.ResultSuccess, []string{"Image added"})
 
 	if IsAlias {
-		go DBConnection.ReplaceImageTags(TagID, AliasedID, RequestorID)
+		DBConnection.background(func() { DBConnection.ReplaceImageTags(TagID, AliasedID, RequestorID) })
 	}
 
 	return nil
@@ -290,7 +290,7 @@ func (DBConnection *MariaDBPlugin) SearchTags(name string, PageStart uint64, Pag
 	//Query Count
 	//Run the count query (Count query does not use start/stride, so run this before we add those)
 	var MaxResults uint64
-	err := DBConnection.DBHandle.QueryRow(sqlCountQuery, queryArray...).Scan(&MaxResults)
+	err := DBConnection.handle().QueryRow(sqlCountQuery, queryArray...).Scan(&MaxResults)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/SearchTags", "0", logging.ResultFailure, []string{"Error running count query", sqlCountQuery, err.Error()})
 		return nil, 0, err
@@ -301,7 +301,7 @@ func (DBConnection *MariaDBPlugin) SearchTags(name string, PageStart uint64, Pag
 	queryArray = append(queryArray, PageStart)
 
 	//Pass the sql query to DB
-	rows, err := DBConnection.DBHandle.Query(sqlQuery, queryArray...)
+	rows, err := DBConnection.handle().Query(sqlQuery, queryArray...)
 	if err != nil {
 		return nil, 0, err
 	}
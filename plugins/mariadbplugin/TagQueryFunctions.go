@@ -3,6 +3,7 @@ package mariadbplugin
 import (
 	"database/sql"
 	"errors"
+	"go-image-board/database/tagquery"
 	"go-image-board/interfaces"
 	"go-image-board/logging"
 	"strconv"
@@ -15,7 +16,7 @@ import (
 //GetUserFilterTags returns a slice of tags based on a user's custom filter
 func (DBConnection *MariaDBPlugin) GetUserFilterTags(UserID uint64, CollectionContext bool) ([]interfaces.TagInformation, error) {
 	var userFilter string
-	err := DBConnection.DBHandle.QueryRow("SELECT SearchFilter FROM Users WHERE ID = ?", UserID).Scan(&userFilter)
+	err := DBConnection.handle().QueryRow("SELECT SearchFilter FROM Users WHERE ID = ?", UserID).Scan(&userFilter)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "MariaDBPlugin/GetUserQueryTags", strconv.FormatUint(UserID, 10), logging.ResultFailure, []string{"Failed to get user filter", err.Error()})
 		return nil, err
@@ -201,12 +202,13 @@ func (DBConnection *MariaDBPlugin) getTagsInfo(Tags []string, Exclude bool, Coll
 	var NonMetaTags []string //Tags will be set to this and used later on in code
 	for _, value := range Tags {
 		if strings.Contains(value, ":") {
-			MetaValue, Comparator := getTagComparator(strings.Split(value, ":")[1])
+			NameValue := strings.SplitN(value, ":", 2)
+			MetaValue, Comparator := getTagComparator(NameValue[1])
 			if Comparator == "" {
 				Comparator = "="
 			}
 			ToAdd := interfaces.TagInformation{
-				Name:       strings.Split(value, ":")[0],
+				Name:       NameValue[0],
 				MetaValue:  MetaValue,
 				Comparator: Comparator,
 				Exclude:    Exclude,
@@ -235,7 +237,7 @@ func (DBConnection *MariaDBPlugin) getTagsInfo(Tags []string, Exclude bool, Coll
 		queryArray = append(queryArray, tag)
 	}
 	//Pass the sql query to DB
-	rows, err := DBConnection.DBHandle.Query(sqlQuery, queryArray...)
+	rows, err := DBConnection.handle().Query(sqlQuery, queryArray...)
 	defer rows.Close()
 	if err != nil {
 		return nil, err
@@ -302,7 +304,7 @@ func (DBConnection *MariaDBPlugin) getTagsInfo(Tags []string, Exclude bool, Coll
 			queryArray = append(queryArray, ID)
 		}
 		//Pass the sql query to DB
-		idrows, err := DBConnection.DBHandle.Query(sqlQuery, queryArray...)
+		idrows, err := DBConnection.handle().Query(sqlQuery, queryArray...)
 		defer idrows.Close()
 		if err != nil {
 			return nil, err
@@ -511,6 +513,12 @@ func (DBConnection *MariaDBPlugin) parseMetaTags(MetaTags []interfaces.TagInform
 			} else {
 				ErrorList = append(ErrorList, errors.New("could not parse similar tag"))
 			}
+		case CollectionContext == false && tagquery.IsImageMetaTag(ToAdd.Name):
+			var err error
+			ToAdd, err = tagquery.ParseImageMetaTag(ToAdd)
+			if err != nil {
+				ErrorList = append(ErrorList, err)
+			}
 		case ToAdd.Name == "name":
 			ToAdd.Name = "Name"
 			ToAdd.Description = "Name of the item"
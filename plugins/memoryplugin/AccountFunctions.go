@@ -0,0 +1,247 @@
+package memoryplugin
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+//CreateUser is used to create and add a user to the AuthN database (return nil on success)
+func (DBConnection *MemoryPlugin) CreateUser(userName string, password []byte, email string, permissions uint64) error {
+	if err := DBConnection.ValidatePasswordStrength(string(password)); err != nil {
+		return err
+	}
+	hash, err := getPasswordHash(password)
+	if err != nil {
+		return errors.New("Error with user password")
+	}
+
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	//Validate User does not exist
+	for _, user := range DBConnection.users {
+		if strings.EqualFold(user.Name, userName) || strings.EqualFold(user.EMail, email) {
+			return errors.New("Username or email already taken")
+		}
+	}
+	DBConnection.lastUserID++
+	DBConnection.users[DBConnection.lastUserID] = &memoryUser{ID: DBConnection.lastUserID, Name: userName, EMail: email, PasswordHash: string(hash), Permissions: permissions, CreationTime: time.Now()}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/CreateUser", userName, logging.ResultSuccess, []string{"New user added to database", userName})
+	return nil
+}
+
+//ValidateUser Validate a user's password (return nil if valid)
+func (DBConnection *MemoryPlugin) ValidateUser(userName string, password []byte) error {
+	DBConnection.lock.RLock()
+	user := DBConnection.getUserByName(userName)
+	if user == nil {
+		DBConnection.lock.RUnlock()
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ValidateUser", userName, logging.ResultFailure, []string{"Username and Password not correct", userName, sql.ErrNoRows.Error()})
+		return sql.ErrNoRows
+	}
+	userPassword := user.PasswordHash
+	userDisabled := user.Disabled
+	DBConnection.lock.RUnlock()
+
+	if userDisabled {
+		return errors.New("Account disabled")
+	}
+	result := bcrypt.CompareHashAndPassword([]byte(userPassword), password)
+	if result == nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ValidateUser", userName, logging.ResultSuccess, []string{"Username and Password Correct", userName})
+	} else {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ValidateUser", userName, logging.ResultFailure, []string{"Password incorrect", userName})
+	}
+	return result
+}
+
+//GetUserID returns a user's DBID for association with other db elements
+func (DBConnection *MemoryPlugin) GetUserID(userName string) (uint64, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	user := DBConnection.getUserByName(userName)
+	if user == nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/GetUserID", userName, logging.ResultFailure, []string{"Username does not exist", userName})
+		return 0, sql.ErrNoRows
+	}
+	return user.ID, nil
+}
+
+//GetUserPermissionSet returns a UserPermission object representing a user's intended access
+func (DBConnection *MemoryPlugin) GetUserPermissionSet(userName string) (interfaces.UserPermission, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	user := DBConnection.getUserByName(userName)
+	if user == nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/GetUserID", userName, logging.ResultFailure, []string{"Username does not exist", userName})
+		return 0, sql.ErrNoRows
+	}
+	return interfaces.UserPermission(user.Permissions), nil
+}
+
+//SetUserPermissionSet sets a user's permission in the database
+func (DBConnection *MemoryPlugin) SetUserPermissionSet(userID uint64, permissions uint64) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	if user, exists := DBConnection.users[userID]; exists {
+		user.Permissions = permissions
+	}
+	return nil
+}
+
+//SetUserDisableState disables or enables a user account
+func (DBConnection *MemoryPlugin) SetUserDisableState(userID uint64, isDisabled bool) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	if user, exists := DBConnection.users[userID]; exists {
+		user.Disabled = isDisabled
+	}
+	return nil
+}
+
+//SetUserQueryTags sets a user's global filter
+func (DBConnection *MemoryPlugin) SetUserQueryTags(UserID uint64, Filter string) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	if user, exists := DBConnection.users[UserID]; exists {
+		user.SearchFilter = Filter
+	}
+	return nil
+}
+
+//SetUserPassword Update a user's password, validation of user provided by either old password, or security answers. (nil on success)
+func (DBConnection *MemoryPlugin) SetUserPassword(userName string, password []byte, newPassword []byte, answerOne []byte, answerTwo []byte, answerThree []byte) error {
+	//Validate authentication method
+	if password == nil {
+		if err := DBConnection.ValidateSecurityQuestions(userName, answerOne, answerTwo, answerThree); err != nil {
+			//Need to use security question method
+			return err
+		}
+	} else if err := DBConnection.ValidateUser(userName, password); err != nil {
+		//Otherwise, utilize classic password
+		return err
+	}
+
+	//At this point, we have passed the authentication (either security question or old password) now we need to change the password
+	//Validate password meets strength requirements
+	if err := DBConnection.ValidatePasswordStrength(string(newPassword)); err != nil {
+		return err
+	}
+	//Hash it
+	newPasswordHash, err := getPasswordHash(newPassword)
+	if err != nil {
+		return err
+	}
+
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	if user := DBConnection.getUserByName(userName); user != nil {
+		user.PasswordHash = string(newPasswordHash)
+	}
+	return nil
+}
+
+//RemoveUser Removes a user from the database (nil on success)
+func (DBConnection *MemoryPlugin) RemoveUser(userName string) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	if user := DBConnection.getUserByName(userName); user != nil {
+		delete(DBConnection.users, user.ID)
+	}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/RemoveUser", userName, logging.ResultSuccess, []string{"User removed", userName})
+	return nil
+}
+
+//ValidatePasswordStrength validates whether a user's password passes complexity requirements
+func (DBConnection *MemoryPlugin) ValidatePasswordStrength(password string) error {
+	match, err := regexp.MatchString("^[a-zA-Z\\d\\!\\@\\#\\$\\%\\^\\&\\*\\(\\)\\-\\_\\=\\+]{3,60}$", string(password))
+	if match == false {
+		return errors.New("Password using invalid characters. alphanumeric and !@#$%^&*()_+=- between 3 and 60 characters")
+	}
+	return err
+}
+
+//Support Functions
+//getPasswordHash Gets bcrypt hash from password
+//The minimum cost is used, as nothing held by this plugin outlives the process
+func getPasswordHash(password []byte) ([]byte, error) {
+	return bcrypt.GenerateFromPassword(password, bcrypt.MinCost)
+}
+
+//getUserByName returns the user with a matching name, compared case insensitively like the SQL plugins, or nil. Callers must hold the lock
+func (DBConnection *MemoryPlugin) getUserByName(userName string) *memoryUser {
+	for _, user := range DBConnection.users {
+		if strings.EqualFold(user.Name, userName) {
+			return user
+		}
+	}
+	return nil
+}
+
+//ValidateProposedUsername returns whether a username is in a valid format
+func (DBConnection *MemoryPlugin) ValidateProposedUsername(UserName string) error {
+	match, err := regexp.MatchString("^[a-zA-Z\\d]{3,20}$", UserName)
+	if match == false {
+		return errors.New("username using invalid characters. alphanumeric only between 3 and 20 characters")
+	}
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+//GetUserFilter returns the raw string of the user's filter
+func (DBConnection *MemoryPlugin) GetUserFilter(UserID uint64) (string, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	user, exists := DBConnection.users[UserID]
+	if exists == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/GetUserQueryTags", "0", logging.ResultFailure, []string{"Failed to get user filter", sql.ErrNoRows.Error()})
+		return "", nil
+	}
+	return user.SearchFilter, nil
+}
+
+//SearchUsers performs a search for users (Returns a list of UserInfos, or error)
+func (DBConnection *MemoryPlugin) SearchUsers(searchString string, PageStart uint64, PageStride uint64) ([]interfaces.UserInformation, uint64, error) {
+	var ToReturn []interfaces.UserInformation
+	searchString = strings.TrimSpace(searchString)
+	searchString = strings.Replace(searchString, "%", "", -1)
+	searchMatcher := likeMatcher("%" + searchString + "%")
+
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	for _, user := range DBConnection.users {
+		if searchMatcher.MatchString(user.Name) {
+			ToReturn = append(ToReturn, interfaces.UserInformation{ID: user.ID, Name: user.Name, CreationTime: user.CreationTime, Disabled: user.Disabled, Permissions: interfaces.UserPermission(user.Permissions)})
+		}
+	}
+	sort.SliceStable(ToReturn, func(i, j int) bool {
+		return strings.ToLower(ToReturn[i].Name) < strings.ToLower(ToReturn[j].Name)
+	})
+
+	MaxResults := uint64(len(ToReturn))
+	if PageStride > 0 {
+		start, end := pageBounds(len(ToReturn), PageStart, PageStride)
+		ToReturn = ToReturn[start:end]
+	}
+	return ToReturn, MaxResults, nil
+}
+
+//GetUser returns a UserInformation object for the user with the specified ID
+func (DBConnection *MemoryPlugin) GetUser(UserID uint64) (interfaces.UserInformation, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	user, exists := DBConnection.users[UserID]
+	if exists == false {
+		return interfaces.UserInformation{}, sql.ErrNoRows
+	}
+	return interfaces.UserInformation{ID: UserID, Name: user.Name, CreationTime: user.CreationTime, Disabled: user.Disabled, Permissions: interfaces.UserPermission(user.Permissions)}, nil
+}
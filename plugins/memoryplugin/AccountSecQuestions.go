@@ -0,0 +1,113 @@
+package memoryplugin
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/logging"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+//SetSecurityQuestions changes a user's security questions (nil if success)
+func (DBConnection *MemoryPlugin) SetSecurityQuestions(userName string, questionOne string, questionTwo string, questionThree string, answerOne []byte, answerTwo []byte, answerThree []byte, challengeAnswer []byte) error {
+	answerOneHash, errA := getPasswordHash(answerOne)
+	answerTwoHash, errB := getPasswordHash(answerTwo)
+	answerThreeHash, errC := getPasswordHash(answerThree)
+
+	if errA != nil || errB != nil || errC != nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/RevokeToken", userName, logging.ResultFailure, []string{"Failed to hash security question answers", userName})
+		return errors.New("Failed to set answers")
+	}
+
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	user := DBConnection.getUserByName(userName)
+	if user == nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/SetSecurityQuestions", userName, logging.ResultFailure, []string{"Security questions failed to update. Challenge could not be loaded SQL Error.", userName, sql.ErrNoRows.Error()})
+		return errors.New("sql error occured attempt to load old question")
+	}
+	//If question one is set
+	if user.SecQuestionOne != "" {
+		//Challenge needed/Require that the user entered in the answer to q1
+		if bcrypt.CompareHashAndPassword([]byte(user.SecAnswerOne), challengeAnswer) != nil {
+			//Challenge failed/If we fail, log it, and quit without setting questions
+			logging.WriteLog(logging.LogLevelError, "MemoryPlugin/SetSecurityQuestions", userName, logging.ResultFailure, []string{"Security questions failed to update. Challenge answer incorrect or SQL error.", userName})
+			return errors.New("provided answer did not pass challenge")
+		}
+	}
+
+	//Matches the SQL plugins, which only update enabled accounts
+	if user.Disabled == false {
+		user.SecQuestionOne = questionOne
+		user.SecQuestionTwo = questionTwo
+		user.SecQuestionThree = questionThree
+		user.SecAnswerOne = string(answerOneHash)
+		user.SecAnswerTwo = string(answerTwoHash)
+		user.SecAnswerThree = string(answerThreeHash)
+	}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/SetSecurityQuestions", userName, logging.ResultSuccess, []string{"Security questions updated!", userName})
+	return nil
+}
+
+//ValidateSecurityQuestions Validates answers against a user's security questions (nil on success)
+func (DBConnection *MemoryPlugin) ValidateSecurityQuestions(userName string, answerOne []byte, answerTwo []byte, answerThree []byte) error {
+	//Ensure answers have values
+	if answerOne == nil || answerTwo == nil || answerThree == nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ValidateSecurityQuestions", userName, logging.ResultFailure, []string{"No answers?", userName})
+		return errors.New("Security Question validation failed, provide answers")
+	}
+
+	//Ensure Questions Exist
+	secQuestionOne, secQuestionTwo, secQuestionThree, err := DBConnection.GetSecurityQuestions(userName)
+	if err != nil || secQuestionOne == "" || secQuestionTwo == "" || secQuestionThree == "" {
+
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ValidateSecurityQuestions", userName, logging.ResultFailure, []string{"User does not exist?", err.Error(), userName})
+			return err
+		}
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ValidateSecurityQuestions", userName, logging.ResultFailure, []string{"Questions do not exist for user", userName})
+		return errors.New("Questions do not exist for user")
+	}
+
+	DBConnection.lock.RLock()
+	user := DBConnection.getUserByName(userName)
+	if user == nil {
+		DBConnection.lock.RUnlock()
+		return sql.ErrNoRows
+	}
+	secAnswerOne := user.SecAnswerOne
+	secAnswerTwo := user.SecAnswerTwo
+	secAnswerThree := user.SecAnswerThree
+	DBConnection.lock.RUnlock()
+
+	if bcrypt.CompareHashAndPassword([]byte(secAnswerOne), answerOne) != nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ValidateSecurityQuestions", userName, logging.ResultFailure, []string{"Answer 1 incorrect", userName})
+		return errors.New("Security Question validation failed")
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(secAnswerTwo), answerTwo) != nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ValidateSecurityQuestions", userName, logging.ResultFailure, []string{"Answer 2 incorrect", userName})
+		return errors.New("Security Question validation failed")
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(secAnswerThree), answerThree) != nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ValidateSecurityQuestions", userName, logging.ResultFailure, []string{"Answer 3 incorrect", userName})
+		return errors.New("Security Question validation failed")
+	}
+
+	return nil
+}
+
+//GetSecurityQuestions returns the three questions, first, second, third, and an error if an issue occured
+func (DBConnection *MemoryPlugin) GetSecurityQuestions(userName string) (string, string, string, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	user := DBConnection.getUserByName(userName)
+	if user == nil {
+		return "", "", "", sql.ErrNoRows
+	}
+	if user.SecQuestionOne != "" && user.SecQuestionTwo != "" && user.SecQuestionThree != "" {
+		return user.SecQuestionOne, user.SecQuestionTwo, user.SecQuestionThree, nil
+	}
+	return "", "", "", errors.New("one or more questions nil")
+}
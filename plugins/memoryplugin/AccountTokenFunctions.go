@@ -0,0 +1,73 @@
+package memoryplugin
+
+import (
+	"bytes"
+	"errors"
+	"go-image-board/logging"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+//ValidateToken Validate a cookie token (true if valid cookie, false otherwise, error for reason or nil)
+func (DBConnection *MemoryPlugin) ValidateToken(userName string, tokenID string, ip string) error {
+	DBConnection.lock.RLock()
+	user := DBConnection.getUserByName(userName)
+	if user == nil {
+		DBConnection.lock.RUnlock()
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ValidateToken", userName, logging.ResultFailure, []string{"Token Invalid", userName, tokenID, ip})
+		return errors.New("Token invalid")
+	}
+	validTokenID := user.TokenID
+	validTokenIP := user.IP
+	userDisabled := user.Disabled
+	DBConnection.lock.RUnlock()
+
+	if userDisabled {
+		return errors.New("Account disabled")
+	}
+
+	UUIDBytes := uuid.FromStringOrNil(tokenID)
+	if uuid.Equal(UUIDBytes, uuid.UUID{}) == true {
+		//Token provided is blank
+		return errors.New("Token provided is blank")
+	}
+
+	if validTokenIP != ip {
+		//Token is registered for a different IP
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ValidateToken", userName, logging.ResultFailure, []string{"Token for a different IP", userName, tokenID, ip})
+		return errors.New("Token invalid")
+	}
+
+	if bytes.Equal(UUIDBytes.Bytes(), uuid.FromStringOrNil(validTokenID).Bytes()) == false {
+		//Tokens do not match
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ValidateToken", userName, logging.ResultFailure, []string{"Tokens don't match", userName, tokenID, ip})
+		return errors.New("Token invalid")
+	}
+
+	return nil
+}
+
+//GenerateToken Generate a cookie token (string token, or error)
+func (DBConnection *MemoryPlugin) GenerateToken(userName string, ip string) (string, error) {
+	newToken := uuid.NewV4()
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	//The SQL plugins silently update nothing for a missing user, so do the same here
+	if user := DBConnection.getUserByName(userName); user != nil {
+		user.TokenID = newToken.String()
+		user.IP = ip
+	}
+	return newToken.String(), nil
+}
+
+//RevokeToken Revokes a token (nil on success)
+func (DBConnection *MemoryPlugin) RevokeToken(userName string) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	if user := DBConnection.getUserByName(userName); user != nil {
+		user.TokenID = ""
+		user.IP = ""
+	}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/RevokeToken", userName, logging.ResultSuccess, []string{"Token revoked!", userName})
+	return nil
+}
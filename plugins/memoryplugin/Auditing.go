@@ -0,0 +1,43 @@
+package memoryplugin
+
+import (
+	"go-image-board/logging"
+	"strconv"
+	"time"
+)
+
+//AddAuditLog adds an audit event into the audit table
+func (DBConnection *MemoryPlugin) AddAuditLog(UserID uint64, Type string, Info string) error {
+	if len(Type) > 40 || len(Info) > 10240 {
+
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/AddAuditLog", strconv.FormatUint(UserID, 10), logging.ResultFailure, []string{"either the type, or the info is too long for the audit log table", Type, Info})
+		if len(Info) > 10240 {
+			Info = Info[:10240]
+		}
+		if len(Type) > 40 {
+			Type = Type[:40]
+		}
+	}
+
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	DBConnection.auditLogs = append(DBConnection.auditLogs, memoryAuditLog{UserID: UserID, Type: Type, Info: Info, LogTime: time.Now()})
+	return nil
+}
+
+//RemoveAuditLogs removes audit logs older than OlderThan, returns the count removed
+//As IDs are positions, the IDs of the remaining entries shift down
+func (DBConnection *MemoryPlugin) RemoveAuditLogs(OlderThan time.Duration) (int64, error) {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	cutoff := time.Now().Add(-OlderThan)
+	var kept []memoryAuditLog
+	for _, auditLog := range DBConnection.auditLogs {
+		if auditLog.LogTime.Before(cutoff) == false {
+			kept = append(kept, auditLog)
+		}
+	}
+	removed := int64(len(DBConnection.auditLogs) - len(kept))
+	DBConnection.auditLogs = kept
+	return removed, nil
+}
@@ -0,0 +1,244 @@
+package memoryplugin
+
+import (
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Backup operations
+
+//backupTime returns the time from a backup, or the current time if the backup has none, as the SQL plugins default it
+func backupTime(Time time.Time) time.Time {
+	if Time.IsZero() {
+		return time.Now()
+	}
+	return Time
+}
+
+//GetUserBackups returns everything stored for users other than the system user, ordered by ID (Returns a list of users, the count of all users, and or error)
+func (DBConnection *MemoryPlugin) GetUserBackups(PageStart uint64, PageStride uint64) ([]interfaces.UserBackup, uint64, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	var ToReturn []interfaces.UserBackup
+	for _, user := range DBConnection.users {
+		if user.ID == 0 {
+			continue
+		}
+		ToReturn = append(ToReturn, interfaces.UserBackup{ID: user.ID, Name: user.Name, EMail: user.EMail, CreationTime: user.CreationTime, Disabled: user.Disabled, Permissions: user.Permissions, SearchFilter: user.SearchFilter,
+			PasswordHash: user.PasswordHash, SecQuestionOne: user.SecQuestionOne, SecQuestionTwo: user.SecQuestionTwo, SecQuestionThree: user.SecQuestionThree, SecAnswerOne: user.SecAnswerOne, SecAnswerTwo: user.SecAnswerTwo, SecAnswerThree: user.SecAnswerThree})
+	}
+	sort.Slice(ToReturn, func(i, j int) bool {
+		return ToReturn[i].ID < ToReturn[j].ID
+	})
+	start, end := pageBounds(len(ToReturn), PageStart, PageStride)
+	return ToReturn[start:end], uint64(len(ToReturn)), nil
+}
+
+//GetImageVotes returns every user's vote on an image
+func (DBConnection *MemoryPlugin) GetImageVotes(ImageID uint64) ([]interfaces.ImageVote, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	var ToReturn []interfaces.ImageVote
+	for scoreKey, score := range DBConnection.imageUserScores {
+		if scoreKey.ImageID == ImageID {
+			ToReturn = append(ToReturn, interfaces.ImageVote{UserID: scoreKey.UserID, ImageID: ImageID, Score: score.Score, CreationTime: score.CreationTime})
+		}
+	}
+	sort.Slice(ToReturn, func(i, j int) bool {
+		return ToReturn[i].UserID < ToReturn[j].UserID
+	})
+	return ToReturn, nil
+}
+
+//GetAuditLogs returns audit log entries ordered by ID (Returns a list of entries, the count of all entries, and or error)
+//Entries are kept in the order they were added, so an entry's ID is its position
+func (DBConnection *MemoryPlugin) GetAuditLogs(PageStart uint64, PageStride uint64) ([]interfaces.AuditLogInformation, uint64, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	start, end := pageBounds(len(DBConnection.auditLogs), PageStart, PageStride)
+	var ToReturn []interfaces.AuditLogInformation
+	for index := start; index < end; index++ {
+		auditLog := DBConnection.auditLogs[index]
+		ToReturn = append(ToReturn, interfaces.AuditLogInformation{ID: uint64(index) + 1, UserID: auditLog.UserID, Type: auditLog.Type, Info: auditLog.Info, LogTime: auditLog.LogTime})
+	}
+	return ToReturn, uint64(len(DBConnection.auditLogs)), nil
+}
+
+//GetImageTagLinks returns the tags applied to an image, with who applied them and when, ordered by TagID
+func (DBConnection *MemoryPlugin) GetImageTagLinks(ImageID uint64) ([]interfaces.ImageTagLink, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	var ToReturn []interfaces.ImageTagLink
+	for tagKey, link := range DBConnection.imageTags {
+		if tagKey.OwnerID == ImageID {
+			ToReturn = append(ToReturn, interfaces.ImageTagLink{ImageID: ImageID, TagID: tagKey.TagID, LinkerID: link.LinkerID, LinkTime: link.LinkTime})
+		}
+	}
+	sort.Slice(ToReturn, func(i, j int) bool {
+		return ToReturn[i].TagID < ToReturn[j].TagID
+	})
+	return ToReturn, nil
+}
+
+//GetCollectionMemberLinks returns the images in a collection, with who added them and when, ordered by OrderWeight
+func (DBConnection *MemoryPlugin) GetCollectionMemberLinks(CollectionID uint64) ([]interfaces.CollectionMemberLink, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	var ToReturn []interfaces.CollectionMemberLink
+	for memberKey, member := range DBConnection.collectionMembers {
+		if memberKey.CollectionID == CollectionID {
+			ToReturn = append(ToReturn, interfaces.CollectionMemberLink{CollectionID: CollectionID, ImageID: memberKey.ImageID, LinkerID: member.LinkerID, LinkTime: member.LinkTime, OrderWeight: member.OrderWeight})
+		}
+	}
+	sort.Slice(ToReturn, func(i, j int) bool {
+		if ToReturn[i].OrderWeight == ToReturn[j].OrderWeight {
+			return ToReturn[i].ImageID < ToReturn[j].ImageID
+		}
+		return ToReturn[i].OrderWeight < ToReturn[j].OrderWeight
+	})
+	return ToReturn, nil
+}
+
+//RestoreUser adds a user from a backup, keeping their ID, creation time, and hashes as they are
+func (DBConnection *MemoryPlugin) RestoreUser(User interfaces.UserBackup) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	if _, exists := DBConnection.users[User.ID]; exists {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/RestoreUser", strconv.FormatUint(User.ID, 10), logging.ResultFailure, []string{"Failed to restore user", User.Name, "ID already in use"})
+		return errors.New("a user with that ID already exists")
+	}
+	for _, user := range DBConnection.users {
+		if strings.EqualFold(user.Name, User.Name) || strings.EqualFold(user.EMail, User.EMail) {
+			logging.WriteLog(logging.LogLevelError, "MemoryPlugin/RestoreUser", strconv.FormatUint(User.ID, 10), logging.ResultFailure, []string{"Failed to restore user", User.Name, "name or email already in use"})
+			return errors.New("Username or email already taken")
+		}
+	}
+	DBConnection.users[User.ID] = &memoryUser{ID: User.ID, Name: User.Name, EMail: User.EMail, PasswordHash: User.PasswordHash, SecQuestionOne: User.SecQuestionOne, SecQuestionTwo: User.SecQuestionTwo, SecQuestionThree: User.SecQuestionThree,
+		SecAnswerOne: User.SecAnswerOne, SecAnswerTwo: User.SecAnswerTwo, SecAnswerThree: User.SecAnswerThree, CreationTime: backupTime(User.CreationTime), Disabled: User.Disabled, Permissions: User.Permissions, SearchFilter: User.SearchFilter}
+	if User.ID > DBConnection.lastUserID {
+		DBConnection.lastUserID = User.ID
+	}
+	return nil
+}
+
+//RestoreImage adds an image from a backup, keeping its ID, uploader, upload time, name, description, rating, source, location, media info, and blurhash
+func (DBConnection *MemoryPlugin) RestoreImage(Image interfaces.ImageInformation) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	if _, exists := DBConnection.images[Image.ID]; exists || DBConnection.getImageByLocation(Image.Location) != nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/RestoreImage", strconv.FormatUint(Image.UploaderID, 10), logging.ResultFailure, []string{"Failed to restore image", strconv.FormatUint(Image.ID, 10), "ID or location already in use"})
+		return errors.New("an image with that ID or location already exists")
+	}
+	if Image.Rating == "" {
+		Image.Rating = "unrated"
+	}
+	DBConnection.images[Image.ID] = &memoryImage{ID: Image.ID, UploaderID: Image.UploaderID, Name: Image.Name, Description: Image.Description, Rating: Image.Rating, Location: Image.Location, Source: Image.Source, UploadTime: backupTime(Image.UploadTime), Width: Image.Width, Height: Image.Height, FileSize: Image.FileSize, MIMEType: Image.MIMEType, Duration: Image.Duration, Bitrate: Image.Bitrate, BlurHash: Image.BlurHash}
+	if Image.ID > DBConnection.lastImageID {
+		DBConnection.lastImageID = Image.ID
+	}
+	return nil
+}
+
+//RestoreTag adds a tag from a backup, keeping its ID, uploader, upload time, and alias as they are
+func (DBConnection *MemoryPlugin) RestoreTag(Tag interfaces.TagInformation) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	if _, exists := DBConnection.tags[Tag.ID]; exists || DBConnection.getTagByName(Tag.Name) != nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/RestoreTag", strconv.FormatUint(Tag.UploaderID, 10), logging.ResultFailure, []string{"Failed to restore tag", Tag.Name, "ID or name already in use"})
+		return errors.New("a tag with that ID or name already exists")
+	}
+	DBConnection.tags[Tag.ID] = &memoryTag{ID: Tag.ID, Name: Tag.Name, Description: Tag.Description, UploaderID: Tag.UploaderID, UploadTime: backupTime(Tag.UploadTime), AliasedID: Tag.AliasedID, IsAlias: Tag.IsAlias}
+	if Tag.ID > DBConnection.lastTagID {
+		DBConnection.lastTagID = Tag.ID
+	}
+	return nil
+}
+
+//RestoreCollection adds a collection from a backup, keeping its ID, uploader, and upload time
+func (DBConnection *MemoryPlugin) RestoreCollection(Collection interfaces.CollectionInformation) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	if _, exists := DBConnection.collections[Collection.ID]; exists || DBConnection.getCollectionByName(Collection.Name) != nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/RestoreCollection", strconv.FormatUint(Collection.UploaderID, 10), logging.ResultFailure, []string{"Failed to restore collection", Collection.Name, "ID or name already in use"})
+		return errors.New("a collection with that ID or name already exists")
+	}
+	DBConnection.collections[Collection.ID] = &memoryCollection{ID: Collection.ID, Name: Collection.Name, Description: Collection.Description, UploaderID: Collection.UploaderID, UploadTime: backupTime(Collection.UploadTime)}
+	if Collection.ID > DBConnection.lastCollectionID {
+		DBConnection.lastCollectionID = Collection.ID
+	}
+	return nil
+}
+
+//RestoreAuditLog adds an audit log entry from a backup, keeping its time
+func (DBConnection *MemoryPlugin) RestoreAuditLog(Log interfaces.AuditLogInformation) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	DBConnection.auditLogs = append(DBConnection.auditLogs, memoryAuditLog{UserID: Log.UserID, Type: Log.Type, Info: Log.Info, LogTime: backupTime(Log.LogTime)})
+	return nil
+}
+
+//RestoreImageTags applies tags from a backup, keeping their linker, time, and tag IDs even if they are now aliases
+func (DBConnection *MemoryPlugin) RestoreImageTags(Links []interfaces.ImageTagLink) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	//Validate every link first, as a failed SQL insert adds nothing
+	for index, link := range Links {
+		_, imageExists := DBConnection.images[link.ImageID]
+		_, tagExists := DBConnection.tags[link.TagID]
+		_, linked := DBConnection.imageTags[tagPair{TagID: link.TagID, OwnerID: link.ImageID}]
+		for _, previous := range Links[:index] {
+			linked = linked || (previous.TagID == link.TagID && previous.ImageID == link.ImageID)
+		}
+		if imageExists == false || tagExists == false || linked {
+			logging.WriteLog(logging.LogLevelError, "MemoryPlugin/RestoreImageTags", strconv.FormatUint(link.LinkerID, 10), logging.ResultFailure, []string{"Failed to restore image tag", strconv.FormatUint(link.ImageID, 10), strconv.FormatUint(link.TagID, 10), "missing image or tag, or already linked"})
+			return errors.New("image or tag does not exist, or the tag is already applied")
+		}
+	}
+	for _, link := range Links {
+		DBConnection.insertImageTag(link.TagID, link.ImageID, link.LinkerID)
+		DBConnection.imageTags[tagPair{TagID: link.TagID, OwnerID: link.ImageID}].LinkTime = backupTime(link.LinkTime)
+	}
+	return nil
+}
+
+//RestoreCollectionMembers adds images to collections from a backup, keeping their linker, time, and OrderWeight
+func (DBConnection *MemoryPlugin) RestoreCollectionMembers(Links []interfaces.CollectionMemberLink) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	//Validate every link first, as a failed SQL insert adds nothing
+	for index, link := range Links {
+		_, imageExists := DBConnection.images[link.ImageID]
+		_, collectionExists := DBConnection.collections[link.CollectionID]
+		_, linked := DBConnection.collectionMembers[collectionImagePair{CollectionID: link.CollectionID, ImageID: link.ImageID}]
+		for _, previous := range Links[:index] {
+			linked = linked || (previous.CollectionID == link.CollectionID && previous.ImageID == link.ImageID)
+		}
+		if imageExists == false || collectionExists == false || linked {
+			logging.WriteLog(logging.LogLevelError, "MemoryPlugin/RestoreCollectionMembers", strconv.FormatUint(link.LinkerID, 10), logging.ResultFailure, []string{"Failed to restore collection member", strconv.FormatUint(link.CollectionID, 10), strconv.FormatUint(link.ImageID, 10), "missing image or collection, or already a member"})
+			return errors.New("image or collection does not exist, or the image is already a member")
+		}
+	}
+	for _, link := range Links {
+		DBConnection.insertCollectionMember(link.CollectionID, link.ImageID, link.LinkerID, link.OrderWeight)
+		DBConnection.collectionMembers[collectionImagePair{CollectionID: link.CollectionID, ImageID: link.ImageID}].LinkTime = backupTime(link.LinkTime)
+	}
+	return nil
+}
+
+//RestoreImageVote adds a vote from a backup, keeping its time. The image's score is not updated, call UpdateScoreOnImage after
+func (DBConnection *MemoryPlugin) RestoreImageVote(Vote interfaces.ImageVote) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	key := userImagePair{UserID: Vote.UserID, ImageID: Vote.ImageID}
+	if _, exists := DBConnection.imageUserScores[key]; exists {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/RestoreImageVote", strconv.FormatUint(Vote.UserID, 10), logging.ResultFailure, []string{"Failed to restore vote", strconv.FormatUint(Vote.ImageID, 10), "already voted"})
+		return errors.New("a vote by that user on that image already exists")
+	}
+	DBConnection.imageUserScores[key] = &memoryScore{Score: Vote.Score, CreationTime: backupTime(Vote.CreationTime)}
+	return nil
+}
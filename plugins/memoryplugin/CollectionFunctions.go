@@ -0,0 +1,354 @@
+package memoryplugin
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//--Collections
+
+//NewCollection adds a collection with the provided information
+func (DBConnection *MemoryPlugin) NewCollection(Name string, Description string, UploaderID uint64) (uint64, error) {
+	if len(Name) < 3 || len(Name) > 255 || len(Description) > 255 {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/NewCollection", strconv.FormatUint(UploaderID, 10), logging.ResultFailure, []string{"Failed to add collection due to name/description size", Name, Description})
+		return 0, errors.New("name or description outside size range")
+	}
+
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	//Name is unique
+	if DBConnection.getCollectionByName(Name) != nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/NewCollection", strconv.FormatUint(UploaderID, 10), logging.ResultFailure, []string{"Failed to add collection", "name already in use"})
+		return 0, errors.New("a collection with that name already exists")
+	}
+	DBConnection.lastCollectionID++
+	DBConnection.collections[DBConnection.lastCollectionID] = &memoryCollection{ID: DBConnection.lastCollectionID, Name: Name, Description: Description, UploaderID: UploaderID, UploadTime: time.Now()}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/NewCollection", strconv.FormatUint(UploaderID, 10), logging.ResultSuccess, []string{"Collection added"})
+	return DBConnection.lastCollectionID, nil
+}
+
+//DeleteCollection removes a collection
+func (DBConnection *MemoryPlugin) DeleteCollection(CollectionID uint64) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	DBConnection.deleteCollection(CollectionID)
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/DeleteCollection", "0", logging.ResultSuccess, []string{"Collection deleted", strconv.FormatUint(CollectionID, 10)})
+	return nil
+}
+
+//UpdateCollection updates a pre-existing collection
+func (DBConnection *MemoryPlugin) UpdateCollection(CollectionID uint64, Name string, Description string) error {
+	//Cleanup name
+	if len(Name) < 3 || len(Name) > 255 || len(Description) > 255 {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/UpdateCollection", "0", logging.ResultFailure, []string{"Failed to update collection due to size of name/description", Name, Description})
+		return errors.New("name or description outside of right sizes")
+	}
+
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	if existing := DBConnection.getCollectionByName(Name); existing != nil && existing.ID != CollectionID {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/UpdateCollection", "0", logging.ResultFailure, []string{"Failed to update collection", "name already in use"})
+		return errors.New("a collection with that name already exists")
+	}
+	if collection, exists := DBConnection.collections[CollectionID]; exists {
+		collection.Name = Name
+		collection.Description = Description
+	}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/UpdateCollection", "0", logging.ResultSuccess, []string{"Collection updated"})
+	return nil
+}
+
+//GetCollections returns a list of all collections, but only the ID, Name, Description
+func (DBConnection *MemoryPlugin) GetCollections(PageStart uint64, PageStride uint64) ([]interfaces.CollectionInformation, uint64, error) {
+	var ToReturn []interfaces.CollectionInformation
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	for _, collection := range DBConnection.collections {
+		ToReturn = append(ToReturn, interfaces.CollectionInformation{Name: collection.Name, ID: collection.ID, Description: collection.Description, Location: DBConnection.collectionPreview(collection.ID), Members: DBConnection.collectionMemberCount(collection.ID)})
+	}
+	sort.Slice(ToReturn, func(i, j int) bool {
+		return strings.ToLower(ToReturn[i].Name) < strings.ToLower(ToReturn[j].Name)
+	})
+	MaxResults := uint64(len(ToReturn))
+	start, end := pageBounds(len(ToReturn), PageStart, PageStride)
+	return ToReturn[start:end], MaxResults, nil
+}
+
+//GetCollection returns detailed information on one collection
+func (DBConnection *MemoryPlugin) GetCollection(ID uint64) (interfaces.CollectionInformation, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	collection, exists := DBConnection.collections[ID]
+	if exists == false {
+		return interfaces.CollectionInformation{}, sql.ErrNoRows
+	}
+	return interfaces.CollectionInformation{Name: collection.Name, ID: ID, Description: collection.Description, UploaderID: collection.UploaderID, UploadTime: collection.UploadTime, Members: DBConnection.collectionMemberCount(ID)}, nil
+}
+
+//GetCollectionByName returns detailed information on one collection
+func (DBConnection *MemoryPlugin) GetCollectionByName(Name string) (interfaces.CollectionInformation, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	collection := DBConnection.getCollectionByName(Name)
+	if collection == nil {
+		return interfaces.CollectionInformation{}, sql.ErrNoRows
+	}
+	return interfaces.CollectionInformation{Name: collection.Name, ID: collection.ID, Description: collection.Description, UploaderID: collection.UploaderID, UploadTime: collection.UploadTime, Members: DBConnection.collectionMemberCount(collection.ID)}, nil
+}
+
+//--Collection Members
+
+//AddCollectionMember adds an image to a collection
+func (DBConnection *MemoryPlugin) AddCollectionMember(CollectionID uint64, ImageIDs []uint64, LinkerID uint64) error {
+	if len(ImageIDs) == 0 {
+		return errors.New("ImageIDs required")
+	}
+	idString := ""
+	for i := 0; i < len(ImageIDs); i++ {
+		idString += strconv.FormatUint(ImageIDs[i], 10) + ", "
+	}
+
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	//Validate the whole insert first, as a failed SQL insert adds nothing
+	if _, exists := DBConnection.collections[CollectionID]; exists == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/AddCollectionMember", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Image not added to collection", strconv.FormatUint(CollectionID, 10), idString, "collection does not exist"})
+		return errors.New("collection does not exist")
+	}
+	for i := 0; i < len(ImageIDs); i++ {
+		if _, exists := DBConnection.images[ImageIDs[i]]; exists == false {
+			logging.WriteLog(logging.LogLevelError, "MemoryPlugin/AddCollectionMember", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Image not added to collection", strconv.FormatUint(CollectionID, 10), idString, "image does not exist"})
+			return errors.New("image does not exist")
+		}
+		if _, exists := DBConnection.collectionMembers[collectionImagePair{CollectionID: CollectionID, ImageID: ImageIDs[i]}]; exists {
+			logging.WriteLog(logging.LogLevelError, "MemoryPlugin/AddCollectionMember", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Image not added to collection", strconv.FormatUint(CollectionID, 10), idString, "image already in collection"})
+			return errors.New("image already in collection")
+		}
+		for j := 0; j < i; j++ {
+			if ImageIDs[i] == ImageIDs[j] {
+				return errors.New("image already in collection")
+			}
+		}
+	}
+
+	//Get last order
+	lastOrder := uint64(0)
+	memberCount := uint64(0)
+	for memberKey, member := range DBConnection.collectionMembers {
+		if memberKey.CollectionID == CollectionID {
+			memberCount++
+			if member.OrderWeight > lastOrder {
+				lastOrder = member.OrderWeight
+			}
+		}
+	}
+	//If we are not an empty collection, increment the number
+	//Otherwise first image will have 0 as it's weight
+	if memberCount != 0 {
+		lastOrder++
+	}
+	for i := 0; i < len(ImageIDs); i++ {
+		DBConnection.insertCollectionMember(CollectionID, ImageIDs[i], LinkerID, lastOrder)
+		lastOrder++
+	}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/AddCollectionMember", strconv.FormatUint(LinkerID, 10), logging.ResultSuccess, []string{"Image added to collection", strconv.FormatUint(CollectionID, 10), idString})
+	return nil
+}
+
+//RemoveCollectionMember removes an image from collection
+func (DBConnection *MemoryPlugin) RemoveCollectionMember(CollectionID uint64, ImageID uint64) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	return DBConnection.removeCollectionMember(CollectionID, ImageID)
+}
+
+//removeCollectionMember performs RemoveCollectionMember. Callers must hold the lock
+func (DBConnection *MemoryPlugin) removeCollectionMember(CollectionID uint64, ImageID uint64) error {
+	//Get Order
+	member, exists := DBConnection.collectionMembers[collectionImagePair{CollectionID: CollectionID, ImageID: ImageID}]
+	if exists == false {
+		return sql.ErrNoRows
+	}
+	Order := member.OrderWeight
+
+	//If last member of collection, just delete it instead
+	if DBConnection.collectionMemberCount(CollectionID) <= 1 {
+		DBConnection.deleteCollection(CollectionID)
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/DeleteCollection", "0", logging.ResultSuccess, []string{"Collection deleted", strconv.FormatUint(CollectionID, 10)})
+		return nil
+	}
+
+	//Delete Image
+	DBConnection.deleteCollectionMember(CollectionID, ImageID)
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/RemoveCollectionMember", "0", logging.ResultSuccess, []string{"Image removed from collection", strconv.FormatUint(CollectionID, 10), strconv.FormatUint(ImageID, 10)})
+
+	//Decrement Order
+	for memberKey, member := range DBConnection.collectionMembers {
+		if memberKey.CollectionID == CollectionID && member.OrderWeight > Order {
+			member.OrderWeight--
+		}
+	}
+	return nil
+}
+
+//UpdateCollectionMember updates an image's properties in a collection
+func (DBConnection *MemoryPlugin) UpdateCollectionMember(CollectionID uint64, ImageID uint64, Order uint64) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	//Get Current Order
+	target, exists := DBConnection.collectionMembers[collectionImagePair{CollectionID: CollectionID, ImageID: ImageID}]
+	if exists == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/UpdateCollectionMember", "0", logging.ResultFailure, []string{"Could not get previous order to update collectionmember", strconv.FormatUint(CollectionID, 10), strconv.FormatUint(ImageID, 10), sql.ErrNoRows.Error()})
+		return sql.ErrNoRows
+	}
+	BeforeOrder := target.OrderWeight
+
+	//Ensure that we do not try and set this image to say, the 20th position when we have 3 images. Don't error, just silently set order to last image.
+	MemberCount := DBConnection.collectionMemberCount(CollectionID)
+	if MemberCount <= Order {
+		Order = MemberCount - 1 //-1 because we are ordering from 0. If we have 20 images, the last spot is actually 19
+	}
+
+	//Set order for image
+	target.OrderWeight = Order
+
+	//Decrement Order
+	for memberKey, member := range DBConnection.collectionMembers {
+		if memberKey.CollectionID == CollectionID && memberKey.ImageID != ImageID && member.OrderWeight >= BeforeOrder {
+			member.OrderWeight--
+		}
+	}
+
+	//Increment Order
+	for memberKey, member := range DBConnection.collectionMembers {
+		if memberKey.CollectionID == CollectionID && memberKey.ImageID != ImageID && member.OrderWeight >= Order {
+			member.OrderWeight++
+		}
+	}
+	return nil
+}
+
+//GetCollectionMembers gets a list of images in a collection (Returns a list of imageIDs, or error)
+func (DBConnection *MemoryPlugin) GetCollectionMembers(CollectionID uint64, PageStart uint64, PageStride uint64) ([]interfaces.ImageInformation, uint64, error) {
+	var ToReturn []interfaces.ImageInformation
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	for memberKey, member := range DBConnection.collectionMembers {
+		if memberKey.CollectionID != CollectionID {
+			continue
+		}
+		if image, exists := DBConnection.images[memberKey.ImageID]; exists {
+			ToReturn = append(ToReturn, interfaces.ImageInformation{Name: image.Name, ID: image.ID, Location: image.Location, BlurHash: image.BlurHash, OrderInCollection: member.OrderWeight})
+		}
+	}
+	sort.Slice(ToReturn, func(i, j int) bool {
+		if ToReturn[i].OrderInCollection != ToReturn[j].OrderInCollection {
+			return ToReturn[i].OrderInCollection < ToReturn[j].OrderInCollection
+		}
+		return ToReturn[i].ID < ToReturn[j].ID
+	})
+
+	MaxResults := uint64(len(ToReturn))
+	//If we limited the search
+	if PageStride > 0 {
+		start, end := pageBounds(len(ToReturn), PageStart, PageStride)
+		ToReturn = ToReturn[start:end]
+	}
+	return ToReturn, MaxResults, nil
+}
+
+//GetCollectionsWithImage returns a slice of collections with a specific image
+func (DBConnection *MemoryPlugin) GetCollectionsWithImage(ImageID uint64) ([]interfaces.CollectionInformation, error) {
+	var ToReturn []interfaces.CollectionInformation
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	for memberKey, target := range DBConnection.collectionMembers {
+		if memberKey.ImageID != ImageID {
+			continue
+		}
+		collection, exists := DBConnection.collections[memberKey.CollectionID]
+		if exists == false {
+			continue
+		}
+		ToAdd := interfaces.CollectionInformation{Name: collection.Name, Description: collection.Description, ID: collection.ID, OrderInCollection: target.OrderWeight, Members: DBConnection.collectionMemberCount(collection.ID)}
+		//Find the closest members either side of this image
+		var beforeOrder, afterOrder uint64
+		for otherKey, other := range DBConnection.collectionMembers {
+			if otherKey.CollectionID != collection.ID {
+				continue
+			}
+			if other.OrderWeight < target.OrderWeight && (ToAdd.PreviousMemberID == 0 || other.OrderWeight > beforeOrder) {
+				ToAdd.PreviousMemberID = otherKey.ImageID
+				beforeOrder = other.OrderWeight
+			}
+			if other.OrderWeight > target.OrderWeight && (ToAdd.NextMemberID == 0 || other.OrderWeight < afterOrder) {
+				ToAdd.NextMemberID = otherKey.ImageID
+				afterOrder = other.OrderWeight
+			}
+		}
+		ToReturn = append(ToReturn, ToAdd)
+	}
+	sort.Slice(ToReturn, func(i, j int) bool {
+		return ToReturn[i].ID < ToReturn[j].ID
+	})
+	return ToReturn, nil
+}
+
+//GetCollectionTags returns a list of TagInformation for all tags that apply to the given collection
+func (DBConnection *MemoryPlugin) GetCollectionTags(CollectionID uint64) ([]interfaces.TagInformation, error) {
+	var ToReturn []interfaces.TagInformation
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	for collectionKey := range DBConnection.collectionTags {
+		if collectionKey.OwnerID != CollectionID {
+			continue
+		}
+		if tag, exists := DBConnection.tags[collectionKey.TagID]; exists {
+			ToReturn = append(ToReturn, interfaces.TagInformation{Name: tag.Name, ID: tag.ID, Description: tag.Description, Exists: true, Exclude: false})
+		}
+	}
+	sort.Slice(ToReturn, func(i, j int) bool {
+		return ToReturn[i].ID < ToReturn[j].ID
+	})
+	return ToReturn, nil
+}
+
+//FixCollectionTags  verifies and fixes collection tags, returns row count and error
+func (DBConnection *MemoryPlugin) FixCollectionTags(CollectionID uint64) (int64, error) {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	//Performs the work of the LinkCollTags procedure
+	var added int64
+	for memberKey := range DBConnection.collectionMembers {
+		if memberKey.CollectionID != CollectionID {
+			continue
+		}
+		for tagKey, imageTag := range DBConnection.imageTags {
+			if tagKey.OwnerID != memberKey.ImageID {
+				continue
+			}
+			collectionKey := tagPair{TagID: tagKey.TagID, OwnerID: CollectionID}
+			if _, exists := DBConnection.collectionTags[collectionKey]; exists == false {
+				DBConnection.collectionTags[collectionKey] = &memoryLink{LinkerID: imageTag.LinkerID, LinkTime: time.Now()}
+				added++
+			}
+		}
+	}
+	return added + DBConnection.remSurplusCollectionImageTags(CollectionID), nil
+}
+
+//getCollectionByName returns the collection with a matching name, or nil. Callers must hold the lock
+func (DBConnection *MemoryPlugin) getCollectionByName(Name string) *memoryCollection {
+	for _, collection := range DBConnection.collections {
+		if strings.EqualFold(collection.Name, Name) {
+			return collection
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package memoryplugin
+
+import (
+	"errors"
+	"go-image-board/interfaces"
+	"sort"
+)
+
+//SearchCollections performs a search for collections (Returns a list of CollectionInformation a result count and an error/nil)
+//If you edit this function, consider SearchImages for a similar change
+func (DBConnection *MemoryPlugin) SearchCollections(Tags []interfaces.TagInformation, PageStart uint64, PageStride uint64) ([]interfaces.CollectionInformation, uint64, error) {
+	//Cleanup input for use in code below
+	//Specifically we separate the include, the exclude and metatags into their own lists
+	Query := newImageQuery(Tags)
+
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	var matches []*memoryCollection
+	for _, collection := range DBConnection.collections {
+		if len(Query.IncludeTags) > 0 && DBConnection.collectionTagMatches(collection.ID, Query.IncludeTags) != len(Query.IncludeTags) {
+			continue
+		}
+		if len(Query.ExcludeTags) > 0 && DBConnection.collectionTagMatches(collection.ID, Query.ExcludeTags) > 0 {
+			continue
+		}
+		matched := true
+		for _, tag := range Query.MetaTags {
+			comparator := tag.Comparator
+			if tag.Exclude {
+				comparator = getInvertedComparator(comparator)
+			}
+			if comparator == "" {
+				return nil, 0, errors.New("Failed to invert query to negate on " + tag.Name)
+			}
+			var metaMatch bool
+			var err error
+			switch tag.Name {
+			case "Name":
+				metaMatch, err = compareMetaValue(collection.Name, tag.MetaValue, comparator)
+			case "UploaderID":
+				metaMatch, err = compareMetaValue(int64(collection.UploaderID), tag.MetaValue, comparator)
+			default:
+				err = errors.New("unknown column " + tag.Name)
+			}
+			if err != nil {
+				return nil, 0, err
+			}
+			if metaMatch == false {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			matches = append(matches, collection)
+		}
+	}
+	//Newest first
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].ID > matches[j].ID
+	})
+
+	var ToReturn []interfaces.CollectionInformation
+	start, end := pageBounds(len(matches), PageStart, PageStride)
+	for _, collection := range matches[start:end] {
+		ToReturn = append(ToReturn, interfaces.CollectionInformation{Name: collection.Name, ID: collection.ID, Location: DBConnection.collectionPreview(collection.ID), Members: DBConnection.collectionMemberCount(collection.ID)})
+	}
+	return ToReturn, uint64(len(matches)), nil
+}
+
+//collectionTagMatches returns how many of the provided tags a collection has, each tag counted once. Callers must hold the lock
+func (DBConnection *MemoryPlugin) collectionTagMatches(CollectionID uint64, TagIDs []uint64) int {
+	count := 0
+	counted := make(map[uint64]bool)
+	for _, TagID := range TagIDs {
+		if counted[TagID] {
+			continue
+		}
+		counted[TagID] = true
+		if _, exists := DBConnection.collectionTags[tagPair{TagID: TagID, OwnerID: CollectionID}]; exists {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,256 @@
+package memoryplugin
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//Image operations
+
+//NewImage adds an image with the provided information
+func (DBConnection *MemoryPlugin) NewImage(ImageName string, ImageFileName string, OwnerID uint64, Source string) (uint64, error) {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	//Location is unique
+	if DBConnection.getImageByLocation(ImageFileName) != nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/NewImage", strconv.FormatUint(OwnerID, 10), logging.ResultFailure, []string{"Failed to add image", "location already in use"})
+		return 0, errors.New("an image with that location already exists")
+	}
+	DBConnection.lastImageID++
+	DBConnection.images[DBConnection.lastImageID] = &memoryImage{ID: DBConnection.lastImageID, UploaderID: OwnerID, Name: ImageName, Rating: "unrated", Location: ImageFileName, Source: Source, UploadTime: time.Now()}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/NewImage", strconv.FormatUint(OwnerID, 10), logging.ResultSuccess, []string{"Image added"})
+	return DBConnection.lastImageID, nil
+}
+
+//DeleteImage removes an image from the db
+func (DBConnection *MemoryPlugin) DeleteImage(ImageID uint64) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	//First, remove image from any associated collections
+	for memberKey := range DBConnection.collectionMembers {
+		if memberKey.ImageID == ImageID {
+			if err := DBConnection.removeCollectionMember(memberKey.CollectionID, ImageID); err != nil {
+				logging.WriteLog(logging.LogLevelWarning, "MemoryPlugin/DeleteImage", "0", logging.ResultFailure, []string{"Failed to remove image from collection", err.Error(), strconv.FormatUint(ImageID, 10)})
+			}
+		}
+	}
+
+	//First delete ImageTags
+	for tagKey := range DBConnection.imageTags {
+		if tagKey.OwnerID == ImageID {
+			DBConnection.deleteImageTag(tagKey.TagID, ImageID)
+		}
+	}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/DeleteImage", "0", logging.ResultSuccess, []string{"Image tags deleted", strconv.FormatUint(ImageID, 10)})
+	//Second delete Image from table, along with what the onImageDelete trigger would remove
+	for scoreKey := range DBConnection.imageUserScores {
+		if scoreKey.ImageID == ImageID {
+			delete(DBConnection.imageUserScores, scoreKey)
+		}
+	}
+	delete(DBConnection.imagedHashes, ImageID)
+	delete(DBConnection.imageMetadata, ImageID)
+	delete(DBConnection.imageColors, ImageID)
+	delete(DBConnection.images, ImageID)
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/DeleteImage", "0", logging.ResultSuccess, []string{"Image deleted", strconv.FormatUint(ImageID, 10)})
+	return nil
+}
+
+//UpdateImage updates properties of an image
+func (DBConnection *MemoryPlugin) UpdateImage(ImageID uint64, ImageName interface{}, ImageDescription interface{}, OwnerID interface{}, Rating interface{}, Source interface{}, Location interface{}) error {
+	if _, correctValue := OwnerID.(uint64); OwnerID != nil && correctValue == false {
+		return errors.New("OwnerID, when provided, must be of uint64 type")
+	}
+
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	//See if image exists
+	image, exists := DBConnection.images[ImageID]
+	if exists == false {
+		return sql.ErrNoRows
+	}
+
+	if Location != nil {
+		newLocation := fmt.Sprintf("%v", Location)
+		if existing := DBConnection.getImageByLocation(newLocation); existing != nil && existing.ID != ImageID {
+			return errors.New("an image with that location already exists")
+		}
+		image.Location = newLocation
+	}
+	if ImageName != nil {
+		image.Name = fmt.Sprintf("%v", ImageName)
+	}
+	if ImageDescription != nil {
+		image.Description = fmt.Sprintf("%v", ImageDescription)
+	}
+	if unwrappedOwnerID, correctValue := OwnerID.(uint64); OwnerID != nil && correctValue {
+		image.UploaderID = unwrappedOwnerID
+	}
+	if Rating != nil {
+		image.Rating = fmt.Sprintf("%v", Rating)
+	}
+	if Source != nil {
+		image.Source = fmt.Sprintf("%v", Source)
+	}
+	return nil
+}
+
+//GetImage returns information on a single image (Returns an ImageInformation, or error)
+func (DBConnection *MemoryPlugin) GetImage(ID uint64) (interfaces.ImageInformation, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	image, exists := DBConnection.images[ID]
+	if exists == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ImageFunctions/GetImage", "0", logging.ResultFailure, []string{"Failed to get image info from database", sql.ErrNoRows.Error()})
+		return interfaces.ImageInformation{ID: ID}, sql.ErrNoRows
+	}
+	return DBConnection.imageInformation(image), nil
+}
+
+//GetImageByFileName returns an ImageInformation object given a ImageName
+func (DBConnection *MemoryPlugin) GetImageByFileName(imageName string) (interfaces.ImageInformation, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	image := DBConnection.getImageByLocation(imageName)
+	if image == nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ImageFunctions/GetImageByFileName", "0", logging.ResultFailure, []string{"Failed to get image info from database", sql.ErrNoRows.Error()})
+		return interfaces.ImageInformation{Location: imageName}, sql.ErrNoRows
+	}
+	return DBConnection.imageInformation(image), nil
+}
+
+//SetImageRating changes a given image's rating in the database
+func (DBConnection *MemoryPlugin) SetImageRating(ID uint64, Rating string) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	if image, exists := DBConnection.images[ID]; exists {
+		image.Rating = Rating
+	}
+	return nil
+}
+
+//SetImageSource changes a given image's source in the database
+func (DBConnection *MemoryPlugin) SetImageSource(ID uint64, Source string) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	if image, exists := DBConnection.images[ID]; exists {
+		image.Source = Source
+	}
+	return nil
+}
+
+//SetImagedHash changes a given image's dHash in the database
+func (DBConnection *MemoryPlugin) SetImagedHash(ID uint64, hHash uint64, vHash uint64) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	//ImagedHashes references Images
+	if _, exists := DBConnection.images[ID]; exists == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ImageFunctions/SetImagedHash", "0", logging.ResultFailure, []string{"Failed to set image dHashes", "image does not exist"})
+		return errors.New("image does not exist")
+	}
+	DBConnection.imagedHashes[ID] = memoryHash{hHash: hHash, vHash: vHash}
+	return nil
+}
+
+//GetImagedHash changes a given image's dHash in the database
+func (DBConnection *MemoryPlugin) GetImagedHash(ID uint64) (uint64, uint64, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	hash, exists := DBConnection.imagedHashes[ID]
+	if exists == false {
+		return 0, 0, sql.ErrNoRows
+	}
+	return hash.hHash, hash.vHash, nil
+}
+
+//SetImageMetadata adds or replaces the metadata of the image Metadata.ImageID
+func (DBConnection *MemoryPlugin) SetImageMetadata(Metadata interfaces.ImageMetadata) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	//ImageMetadata references Images
+	if _, exists := DBConnection.images[Metadata.ImageID]; exists == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ImageFunctions/SetImageMetadata", "0", logging.ResultFailure, []string{"Failed to set image metadata", "image does not exist"})
+		return errors.New("image does not exist")
+	}
+	DBConnection.imageMetadata[Metadata.ImageID] = Metadata
+	return nil
+}
+
+//GetImageMetadata returns the metadata of an image, sql.ErrNoRows if it has none
+func (DBConnection *MemoryPlugin) GetImageMetadata(ImageID uint64) (interfaces.ImageMetadata, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	metadata, exists := DBConnection.imageMetadata[ImageID]
+	if exists == false {
+		return interfaces.ImageMetadata{ImageID: ImageID}, sql.ErrNoRows
+	}
+	return metadata, nil
+}
+
+//SetImageColors replaces the BlurHash and dominant colours of a given image
+func (DBConnection *MemoryPlugin) SetImageColors(ImageID uint64, BlurHash string, Colors []interfaces.ImageColor) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	image, exists := DBConnection.images[ImageID]
+	//ImageColors references Images
+	if exists == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/ImageFunctions/SetImageColors", "0", logging.ResultFailure, []string{"Failed to set image colors", "image does not exist"})
+		return errors.New("image does not exist")
+	}
+	image.BlurHash = BlurHash
+	if len(Colors) == 0 {
+		delete(DBConnection.imageColors, ImageID)
+		return nil
+	}
+	colors := append([]interfaces.ImageColor(nil), Colors...)
+	sort.SliceStable(colors, func(i, j int) bool { return colors[i].Share > colors[j].Share })
+	DBConnection.imageColors[ImageID] = colors
+	return nil
+}
+
+//GetImageColors returns the dominant colours of a given image, from most to least common
+func (DBConnection *MemoryPlugin) GetImageColors(ImageID uint64) ([]interfaces.ImageColor, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	return append([]interfaces.ImageColor(nil), DBConnection.imageColors[ImageID]...), nil
+}
+
+//SetImageMediaInfo changes the width, height, file size, MIME type, duration, and bitrate of the image Image.ID
+func (DBConnection *MemoryPlugin) SetImageMediaInfo(Image interfaces.ImageInformation) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	if image, exists := DBConnection.images[Image.ID]; exists {
+		image.Width = Image.Width
+		image.Height = Image.Height
+		image.FileSize = Image.FileSize
+		image.MIMEType = Image.MIMEType
+		image.Duration = Image.Duration
+		image.Bitrate = Image.Bitrate
+	}
+	return nil
+}
+
+//getImageByLocation returns the image stored at a location, or nil. Callers must hold the lock
+func (DBConnection *MemoryPlugin) getImageByLocation(Location string) *memoryImage {
+	for _, image := range DBConnection.images {
+		if image.Location == Location {
+			return image
+		}
+	}
+	return nil
+}
+
+//imageInformation converts an image row into the ImageInformation GetImage returns. Callers must hold the lock
+func (DBConnection *MemoryPlugin) imageInformation(image *memoryImage) interfaces.ImageInformation {
+	ToReturn := interfaces.ImageInformation{ID: image.ID, Name: image.Name, Description: image.Description, Location: image.Location, UploaderID: image.UploaderID, UploadTime: image.UploadTime, Rating: image.Rating, ScoreAverage: image.ScoreAverage, ScoreTotal: image.ScoreTotal, ScoreVoters: image.ScoreVoters, Source: image.Source, Width: image.Width, Height: image.Height, FileSize: image.FileSize, MIMEType: image.MIMEType, Duration: image.Duration, Bitrate: image.Bitrate, BlurHash: image.BlurHash}
+	if uploader, exists := DBConnection.users[image.UploaderID]; exists {
+		ToReturn.UploaderName = uploader.Name
+	}
+	return ToReturn
+}
@@ -0,0 +1,405 @@
+package memoryplugin
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/database/tagquery"
+	"go-image-board/interfaces"
+	"math"
+	"math/bits"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//imageQuery is a search split into the pieces the SQL plugins build their WHERE clause from
+type imageQuery struct {
+	IncludeTags []uint64
+	ExcludeTags []uint64
+	MetaTags    []interfaces.TagInformation
+}
+
+//newImageQuery separates the include, the exclude and metatags into their own lists, as the SQL plugins do
+func newImageQuery(Tags []interfaces.TagInformation) imageQuery {
+	var ToReturn imageQuery
+	for _, tag := range Tags {
+		if tag.Exists && tag.IsAlias == false && tag.IsMeta == false {
+			if tag.Exclude {
+				ToReturn.ExcludeTags = append(ToReturn.ExcludeTags, tag.ID)
+			} else {
+				ToReturn.IncludeTags = append(ToReturn.IncludeTags, tag.ID)
+			}
+		} else if tag.Exists && tag.IsMeta {
+			ToReturn.MetaTags = append(ToReturn.MetaTags, tag)
+		}
+	}
+	return ToReturn
+}
+
+//SearchImages performs a search for images (Returns a list of ImageInformations a result count and an error/nil)
+//If you edit this function, consider SearchCollections and GetPrevNexImages for a similar change
+func (DBConnection *MemoryPlugin) SearchImages(Tags []interfaces.TagInformation, PageStart uint64, PageStride uint64) ([]interfaces.ImageInformation, uint64, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	matches, err := DBConnection.searchImages(newImageQuery(Tags))
+	if err != nil {
+		return nil, 0, err
+	}
+	//Newest first
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].ID > matches[j].ID
+	})
+
+	var ToReturn []interfaces.ImageInformation
+	start, end := pageBounds(len(matches), PageStart, PageStride)
+	for _, image := range matches[start:end] {
+		ToReturn = append(ToReturn, interfaces.ImageInformation{Name: image.Name, ID: image.ID, Location: image.Location, BlurHash: image.BlurHash})
+	}
+	return ToReturn, uint64(len(matches)), nil
+}
+
+//GetPrevNexImages performs a search for images (Returns a list of ImageInformations (Up to 2) and an error/nil)
+func (DBConnection *MemoryPlugin) GetPrevNexImages(Tags []interfaces.TagInformation, TargetID uint64) ([]interfaces.ImageInformation, error) {
+	if TargetID == 0 {
+		return nil, errors.New("invalid targetid")
+	}
+
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	matches, err := DBConnection.searchImages(newImageQuery(Tags))
+	if err != nil {
+		return nil, err
+	}
+
+	//Next is the lowest ID above the target, previous the highest ID below it
+	var next, previous *memoryImage
+	for _, image := range matches {
+		if image.ID > TargetID && (next == nil || image.ID < next.ID) {
+			next = image
+		}
+		if image.ID < TargetID && (previous == nil || image.ID > previous.ID) {
+			previous = image
+		}
+	}
+
+	var ToReturn []interfaces.ImageInformation
+	if next != nil {
+		ToReturn = append(ToReturn, interfaces.ImageInformation{Name: next.Name, ID: next.ID, Location: next.Location})
+	}
+	if previous != nil {
+		ToReturn = append(ToReturn, interfaces.ImageInformation{Name: previous.Name, ID: previous.ID, Location: previous.Location})
+	}
+	return ToReturn, nil
+}
+
+//GetRandomImage returns a random image (Returns a ImageInformation and an error/nil)
+func (DBConnection *MemoryPlugin) GetRandomImage(Tags []interfaces.TagInformation) (interfaces.ImageInformation, uint64, error) {
+	imageInfo, resultCount, err := DBConnection.SearchImages(Tags, 0, 1)
+
+	if err == nil {
+		if resultCount <= 0 {
+			return interfaces.ImageInformation{}, 0, errors.New("no images found with provided tags")
+		}
+		if resultCount == 1 {
+			return imageInfo[0], resultCount, nil //Shortcut for one result
+		}
+
+		rando := rand.Float64()
+		randoID := uint64(rando * float64(resultCount))
+		imageInfo, _, err = DBConnection.SearchImages(Tags, randoID, 1)
+		if err == nil && len(imageInfo) > 0 {
+			return imageInfo[0], resultCount, nil
+		}
+		if err == nil {
+			err = sql.ErrNoRows //Images were removed between the two searches
+		}
+		return interfaces.ImageInformation{}, resultCount, err
+	}
+	return interfaces.ImageInformation{}, resultCount, err
+}
+
+//searchImages returns every image that matches a query, in no particular order. Callers must hold the lock
+func (DBConnection *MemoryPlugin) searchImages(Query imageQuery) ([]*memoryImage, error) {
+	var ToReturn []*memoryImage
+	for _, image := range DBConnection.images {
+		//Like the SQL plugins, every included tag must match, so repeated tags match nothing
+		if len(Query.IncludeTags) > 0 && DBConnection.imageTagMatches(image.ID, Query.IncludeTags) != len(Query.IncludeTags) {
+			continue
+		}
+		if len(Query.ExcludeTags) > 0 && DBConnection.imageTagMatches(image.ID, Query.ExcludeTags) > 0 {
+			continue
+		}
+		matched := true
+		for _, tag := range Query.MetaTags {
+			metaMatch, err := DBConnection.imageMetaMatches(image, tag)
+			if err != nil {
+				return nil, err
+			}
+			if metaMatch == false {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			ToReturn = append(ToReturn, image)
+		}
+	}
+	return ToReturn, nil
+}
+
+//imageTagMatches returns how many of the provided tags an image has, each tag counted once. Callers must hold the lock
+func (DBConnection *MemoryPlugin) imageTagMatches(ImageID uint64, TagIDs []uint64) int {
+	count := 0
+	counted := make(map[uint64]bool)
+	for _, TagID := range TagIDs {
+		if counted[TagID] {
+			continue
+		}
+		counted[TagID] = true
+		if _, exists := DBConnection.imageTags[tagPair{TagID: TagID, OwnerID: ImageID}]; exists {
+			count++
+		}
+	}
+	return count
+}
+
+//imageMetaMatches returns whether an image passes a metatag. Callers must hold the lock
+func (DBConnection *MemoryPlugin) imageMetaMatches(image *memoryImage, tag interfaces.TagInformation) (bool, error) {
+	//Handle Comparator transforms
+	comparator := tag.Comparator
+	if tag.Exclude {
+		comparator = getInvertedComparator(comparator)
+	}
+	if comparator == "" {
+		return false, errors.New("Failed to invert query to negate on " + tag.Name)
+	}
+
+	//Handle Complex Tags Here
+	switch tag.Name {
+	case "InCollection": //Special Exception for InCollection
+		tagBoolValue, isTagValued := tag.MetaValue.(bool)
+		if isTagValued == false {
+			return false, errors.New("Failed get value of " + tag.Name)
+		}
+		inCollection := false
+		for memberKey := range DBConnection.collectionMembers {
+			if memberKey.ImageID == image.ID {
+				inCollection = true
+				break
+			}
+		}
+		if (comparator == "=" && tagBoolValue == true) || (comparator == "!=" && tagBoolValue == false) {
+			return inCollection, nil
+		}
+		return inCollection == false, nil
+	case "TagCount": //Special Exception for TagCount
+		tagStringValue, isTagValued := tag.MetaValue.(string)
+		if isTagValued == false {
+			return false, errors.New("Failed get value of " + tag.Name)
+		}
+		countValue, err := strconv.ParseInt(tagStringValue, 10, 64)
+		if err != nil {
+			return false, err
+		}
+		var tagCount int64
+		for tagKey := range DBConnection.imageTags {
+			if tagKey.OwnerID == image.ID {
+				tagCount++
+			}
+		}
+		//Images without any tags are not in the SQL plugins' count table, so never match
+		if tagCount == 0 {
+			return false, nil
+		}
+		return compareInt64(tagCount, countValue, comparator)
+	case "Similar": //Special Exception for Similar
+		tagImagedHashValue, isTagValued := tag.MetaValue.(interfaces.ImagedHash)
+		if isTagValued == false {
+			return false, errors.New("Failed get value of " + tag.Name)
+		}
+		hash, exists := DBConnection.imagedHashes[image.ID]
+		if exists == false {
+			return false, nil
+		}
+		distance := bits.OnesCount64(hash.hHash^tagImagedHashValue.ImagehHash) + bits.OnesCount64(hash.vHash^tagImagedHashValue.ImagevHash)
+		return compareInt64(int64(distance), int64(tagImagedHashValue.SimilarityThreshold), comparator)
+	case "Ratio": //Special Exception for Ratio
+		tagFloatValue, isTagValued := tag.MetaValue.(float64)
+		if isTagValued == false {
+			return false, errors.New("Failed get value of " + tag.Name)
+		}
+		//The SQL plugins divide by NULL for images without a height, so these never match
+		if image.Height == 0 {
+			return false, nil
+		}
+		ratio := float64(image.Width) / float64(image.Height)
+		switch comparator {
+		case "=":
+			return math.Abs(ratio-tagFloatValue) < tagquery.RatioTolerance, nil
+		case "!=":
+			return math.Abs(ratio-tagFloatValue) >= tagquery.RatioTolerance, nil
+		}
+		return compareFloat64(ratio, tagFloatValue, comparator)
+	case "Color": //Special Exception for Color
+		tagColorValue, isTagValued := tag.MetaValue.(interfaces.ImageColorSearch)
+		if isTagValued == false {
+			return false, errors.New("Failed get value of " + tag.Name)
+		}
+		//Add up the share of dominant colours within tolerance, as the SQL plugins do
+		var share float64
+		for _, color := range DBConnection.imageColors[image.ID] {
+			red := int64(color.Red) - int64(tagColorValue.Red)
+			green := int64(color.Green) - int64(tagColorValue.Green)
+			blue := int64(color.Blue) - int64(tagColorValue.Blue)
+			if uint64(red*red+green*green+blue*blue) <= tagColorValue.Tolerance*tagColorValue.Tolerance {
+				share += color.Share
+			}
+		}
+		if comparator == "=" {
+			return share >= tagColorValue.MinimumShare, nil
+		}
+		return share < tagColorValue.MinimumShare, nil
+	case "UploaderID":
+		return compareMetaValue(int64(image.UploaderID), tag.MetaValue, comparator)
+	case "ScoreAverage":
+		return compareMetaValue(image.ScoreAverage, tag.MetaValue, comparator)
+	case "ScoreTotal":
+		return compareMetaValue(image.ScoreTotal, tag.MetaValue, comparator)
+	case "ScoreVoters":
+		return compareMetaValue(image.ScoreVoters, tag.MetaValue, comparator)
+	case "Rating":
+		return compareMetaValue(image.Rating, tag.MetaValue, comparator)
+	case "Width":
+		return compareMetaValue(int64(image.Width), tag.MetaValue, comparator)
+	case "Height":
+		return compareMetaValue(int64(image.Height), tag.MetaValue, comparator)
+	case "FileSize":
+		return compareMetaValue(int64(image.FileSize), tag.MetaValue, comparator)
+	case "Duration":
+		return compareMetaValue(image.Duration, tag.MetaValue, comparator)
+	case "MIMEType":
+		return compareMetaValue(image.MIMEType, tag.MetaValue, comparator)
+	case "Name":
+		return compareMetaValue(image.Name, tag.MetaValue, comparator)
+	case "Location":
+		return compareMetaValue(image.Location, tag.MetaValue, comparator)
+	}
+	return false, errors.New("unknown column " + tag.Name)
+}
+
+//compareMetaValue compares a column against a metatag's value using the metatag's comparator
+//Text is compared case insensitively, as MariaDB's default collation does
+func compareMetaValue(Column interface{}, MetaValue interface{}, Comparator string) (bool, error) {
+	switch columnValue := Column.(type) {
+	case int64:
+		switch metaValue := MetaValue.(type) {
+		case int64:
+			return compareInt64(columnValue, metaValue, Comparator)
+		case uint64:
+			return compareInt64(columnValue, int64(metaValue), Comparator)
+		case string:
+			parsedValue, err := strconv.ParseInt(metaValue, 10, 64)
+			if err != nil {
+				return false, err
+			}
+			return compareInt64(columnValue, parsedValue, Comparator)
+		}
+	case float64:
+		metaValue, isFloat := MetaValue.(float64)
+		if isFloat == false {
+			break
+		}
+		return compareFloat64(columnValue, metaValue, Comparator)
+	case string:
+		metaValue, isString := MetaValue.(string)
+		if isString == false {
+			break
+		}
+		switch Comparator {
+		case "LIKE":
+			return likeMatcher(metaValue).MatchString(columnValue), nil
+		case "NOT LIKE":
+			return likeMatcher(metaValue).MatchString(columnValue) == false, nil
+		}
+		return compareInt64(int64(strings.Compare(strings.ToLower(columnValue), strings.ToLower(metaValue))), 0, Comparator)
+	}
+	return false, errors.New("could not compare metatag value")
+}
+
+//compareInt64 applies a SQL comparator to two numbers
+func compareInt64(Left int64, Right int64, Comparator string) (bool, error) {
+	switch Comparator {
+	case "=":
+		return Left == Right, nil
+	case "!=":
+		return Left != Right, nil
+	case ">":
+		return Left > Right, nil
+	case "<":
+		return Left < Right, nil
+	case ">=":
+		return Left >= Right, nil
+	case "<=":
+		return Left <= Right, nil
+	}
+	return false, errors.New("unsupported comparator " + Comparator)
+}
+
+//compareFloat64 applies a SQL comparator to two decimal numbers
+func compareFloat64(Left float64, Right float64, Comparator string) (bool, error) {
+	switch Comparator {
+	case "=":
+		return Left == Right, nil
+	case "!=":
+		return Left != Right, nil
+	case ">":
+		return Left > Right, nil
+	case "<":
+		return Left < Right, nil
+	case ">=":
+		return Left >= Right, nil
+	case "<=":
+		return Left <= Right, nil
+	}
+	return false, errors.New("unsupported comparator " + Comparator)
+}
+
+//likeMatcher converts a SQL LIKE pattern, using \ as the escape character, into a case insensitive regular expression
+func likeMatcher(Pattern string) *regexp.Regexp {
+	expression := "(?is)^"
+	escaped := false
+	for _, character := range Pattern {
+		switch {
+		case escaped:
+			expression += regexp.QuoteMeta(string(character))
+			escaped = false
+		case character == '\\':
+			escaped = true
+		case character == '%':
+			expression += ".*"
+		case character == '_':
+			expression += "."
+		default:
+			expression += regexp.QuoteMeta(string(character))
+		}
+	}
+	return regexp.MustCompile(expression + "$")
+}
+
+//pageBounds returns the slice bounds for a LIMIT PageStride OFFSET PageStart over Length results
+//A PageStride of 0 returns everything, as the SQL plugins leave out the LIMIT
+func pageBounds(Length int, PageStart uint64, PageStride uint64) (int, int) {
+	if PageStride == 0 {
+		return 0, Length
+	}
+	if PageStart >= uint64(Length) {
+		return Length, Length
+	}
+	start := int(PageStart)
+	if PageStride >= uint64(Length-start) {
+		return start, Length
+	}
+	return start, start + int(PageStride)
+}
@@ -0,0 +1,191 @@
+package memoryplugin
+
+import (
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"sort"
+	"strconv"
+)
+
+//GetImageTags returns a list of TagInformation for all tags that apply to the given image
+func (DBConnection *MemoryPlugin) GetImageTags(ImageID uint64) ([]interfaces.TagInformation, error) {
+	var ToReturn []interfaces.TagInformation
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	for tagKey := range DBConnection.imageTags {
+		if tagKey.OwnerID != ImageID {
+			continue
+		}
+		if tag, exists := DBConnection.tags[tagKey.TagID]; exists {
+			ToReturn = append(ToReturn, interfaces.TagInformation{Name: tag.Name, ID: tag.ID, Description: tag.Description, Exists: true, Exclude: false})
+		}
+	}
+	sort.Slice(ToReturn, func(i, j int) bool {
+		return ToReturn[i].ID < ToReturn[j].ID
+	})
+	return ToReturn, nil
+}
+
+//RemoveTag remove a tag association
+func (DBConnection *MemoryPlugin) RemoveTag(TagID uint64, ImageID uint64) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	DBConnection.deleteImageTag(TagID, ImageID)
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/RemoveTag", "0", logging.ResultSuccess, []string{"Tag removed", strconv.FormatUint(TagID, 10), strconv.FormatUint(ImageID, 10)})
+	return nil
+}
+
+//tagsContainID is a helper function to check if a TagInformation slice contains a specified ID
+func tagsContainID(ID uint64, Tags []interfaces.TagInformation) bool {
+	for _, Tag := range Tags {
+		if Tag.ID == ID {
+			return true
+		}
+	}
+	return false
+}
+
+//tagsContainName is a helper function to check if a TagInformation slice contains a specified Name
+func tagsContainName(Name string, Tags []interfaces.TagInformation) bool {
+	for _, Tag := range Tags {
+		if Tag.Name == Name {
+			return true
+		}
+	}
+	return false
+}
+
+//ReplaceImageTags replaces all instances of ImageTags that have the specified tag with the new tag
+func (DBConnection *MemoryPlugin) ReplaceImageTags(OldTagID uint64, NewTagID uint64, LinkerID uint64) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	//Like the SQL UPDATE, moving a link to the new tag does not fire the insert trigger
+	for tagKey, imageTag := range DBConnection.imageTags {
+		if tagKey.TagID != OldTagID {
+			continue
+		}
+		newKey := tagPair{TagID: NewTagID, OwnerID: tagKey.OwnerID}
+		if _, exists := DBConnection.imageTags[newKey]; exists == false {
+			delete(DBConnection.imageTags, tagKey)
+			DBConnection.imageTags[newKey] = &memoryLink{LinkerID: LinkerID, LinkTime: imageTag.LinkTime}
+		}
+	}
+	//Remove any instances of old tag that would have lead to a duplicate
+	for tagKey := range DBConnection.imageTags {
+		if tagKey.TagID == OldTagID {
+			DBConnection.deleteImageTag(OldTagID, tagKey.OwnerID)
+		}
+	}
+	return nil
+}
+
+//BulkAddTag adds an association of a tag to image into the association table that already have another tag
+func (DBConnection *MemoryPlugin) BulkAddTag(TagID uint64, OldTagID uint64, LinkerID uint64) error {
+	//Prevent adding alias
+	tagInfo, err := DBConnection.GetTag(TagID, false)
+	oldTagInfo, err2 := DBConnection.GetTag(OldTagID, false)
+	if err != nil || err2 != nil {
+		return errors.New("Failed to validate tags")
+	}
+
+	//If this is an alias, then add aliasedid instead
+	if tagInfo.IsAlias {
+		TagID = tagInfo.AliasedID
+	}
+
+	//Similiarly convert oldTag if it is an alias
+	if oldTagInfo.IsAlias {
+		OldTagID = oldTagInfo.AliasedID
+	}
+
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	var imageIDs []uint64
+	for tagKey := range DBConnection.imageTags {
+		if tagKey.TagID != OldTagID {
+			continue
+		}
+		if _, exists := DBConnection.imageTags[tagPair{TagID: TagID, OwnerID: tagKey.OwnerID}]; exists == false {
+			imageIDs = append(imageIDs, tagKey.OwnerID)
+		}
+	}
+	for _, imageID := range imageIDs {
+		DBConnection.insertImageTag(TagID, imageID, LinkerID)
+	}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/BulkAddTag", strconv.FormatUint(LinkerID, 10), logging.ResultSuccess, []string{"Tags added", strconv.FormatUint(OldTagID, 10), strconv.FormatUint(TagID, 10)})
+	return nil
+}
+
+//sliceContains is a helper function that returns whether a slice contains a specifc string
+func sliceContains(slice []string, item string) bool {
+	for _, sliceItem := range slice {
+		if sliceItem == item {
+			return true
+		}
+	}
+	return false
+}
+
+//inverts a tags comparator
+func getInvertedComparator(comparator string) string {
+	if comparator == "=" {
+		return "!="
+	}
+	if comparator == ">" {
+		return "<="
+	}
+	if comparator == "<" {
+		return ">="
+	}
+	if comparator == ">=" {
+		return "<"
+	}
+	if comparator == "<=" {
+		return ">"
+	}
+	if comparator == "LIKE" {
+		return "NOT LIKE"
+	}
+	return ""
+}
+
+//AddTag adds an association of a tag to image into the association table
+func (DBConnection *MemoryPlugin) AddTag(TagIDs []uint64, ImageID uint64, LinkerID uint64) error {
+	if len(TagIDs) == 0 {
+		return errors.New("No tags provided")
+	}
+	//Validate tags, if some are alias, add alias instead, if a tag does not exist, error out
+	var validatedTagIDs []uint64
+	for i := 0; i < len(TagIDs); i++ {
+		TagID := TagIDs[i]
+		tagInfo, err := DBConnection.GetTag(TagID, false)
+		if err != nil {
+			return errors.New("Failed to validate tag " + strconv.FormatUint(TagID, 10))
+		}
+		//If this is an alias, then add aliasedid instead
+		if tagInfo.IsAlias {
+			validatedTagIDs = append(validatedTagIDs, tagInfo.AliasedID)
+		} else {
+			validatedTagIDs = append(validatedTagIDs, TagID)
+		}
+	}
+
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	//ImageTags references Images
+	if _, exists := DBConnection.images[ImageID]; exists == false {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/AddTag", strconv.FormatUint(LinkerID, 10), logging.ResultFailure, []string{"Tags not added to image", strconv.FormatUint(ImageID, 10), "image does not exist"})
+		return errors.New("image does not exist")
+	}
+	for _, TagID := range validatedTagIDs {
+		if existing, exists := DBConnection.imageTags[tagPair{TagID: TagID, OwnerID: ImageID}]; exists {
+			//Already linked, matches the SQL upsert which only changes the linker
+			existing.LinkerID = LinkerID
+			continue
+		}
+		DBConnection.insertImageTag(TagID, ImageID, LinkerID)
+	}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/AddTag", strconv.FormatUint(LinkerID, 10), logging.ResultSuccess, []string{"Tags added", strconv.FormatUint(ImageID, 10)})
+	return nil
+}
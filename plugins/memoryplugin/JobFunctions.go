@@ -0,0 +1,150 @@
+package memoryplugin
+
+import (
+	"database/sql"
+	"go-image-board/interfaces"
+	"sort"
+	"time"
+)
+
+//AddJob adds a job to the queue to be run as soon as a worker is free, returns the job ID and/or error
+func (DBConnection *MemoryPlugin) AddJob(Type string, Payload string, MaxAttempts uint64) (uint64, error) {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	DBConnection.lastJobID++
+	now := time.Now()
+	DBConnection.jobs[DBConnection.lastJobID] = &interfaces.JobInformation{
+		ID:           DBConnection.lastJobID,
+		Type:         Type,
+		Payload:      Payload,
+		Status:       interfaces.JobQueued,
+		MaxAttempts:  MaxAttempts,
+		CreationTime: now,
+		UpdateTime:   now,
+		RunAfter:     now,
+	}
+	return DBConnection.lastJobID, nil
+}
+
+//ClaimJob marks the oldest job that is ready as running and returns it, along with running jobs not updated within LeaseTime. Returns sql.ErrNoRows if none are ready
+func (DBConnection *MemoryPlugin) ClaimJob(LeaseTime time.Duration) (interfaces.JobInformation, error) {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	now := time.Now()
+	var claimed *interfaces.JobInformation
+	for _, job := range DBConnection.jobs {
+		ready := (job.Status == interfaces.JobQueued && job.RunAfter.After(now) == false) || (job.Status == interfaces.JobRunning && job.UpdateTime.Before(now.Add(-LeaseTime)))
+		if ready && (claimed == nil || job.ID < claimed.ID) {
+			claimed = job
+		}
+	}
+	if claimed == nil {
+		return interfaces.JobInformation{}, sql.ErrNoRows
+	}
+	claimed.Status = interfaces.JobRunning
+	claimed.Attempts++
+	claimed.UpdateTime = now
+	return *claimed, nil
+}
+
+//UpdateJobProgress records how far a running job is, and that it is still running
+func (DBConnection *MemoryPlugin) UpdateJobProgress(JobID uint64, Progress uint64, Total uint64) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	job, exists := DBConnection.jobs[JobID]
+	if exists == false {
+		return sql.ErrNoRows
+	}
+	job.Progress = Progress
+	job.Total = Total
+	job.UpdateTime = time.Now()
+	return nil
+}
+
+//FinishJob sets a job's status and error. A job set back to queued is not claimed again until RetryDelay has passed
+func (DBConnection *MemoryPlugin) FinishJob(JobID uint64, Status string, LastError string, RetryDelay time.Duration) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	job, exists := DBConnection.jobs[JobID]
+	if exists == false {
+		return sql.ErrNoRows
+	}
+	job.Status = Status
+	job.LastError = LastError
+	job.UpdateTime = time.Now()
+	job.RunAfter = job.UpdateTime.Add(RetryDelay)
+	return nil
+}
+
+//GetJob returns one job
+func (DBConnection *MemoryPlugin) GetJob(JobID uint64) (interfaces.JobInformation, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	job, exists := DBConnection.jobs[JobID]
+	if exists == false {
+		return interfaces.JobInformation{}, sql.ErrNoRows
+	}
+	return *job, nil
+}
+
+//GetLatestJob returns the newest job of a type, or sql.ErrNoRows if there has been none
+func (DBConnection *MemoryPlugin) GetLatestJob(Type string) (interfaces.JobInformation, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	var latest *interfaces.JobInformation
+	for _, job := range DBConnection.jobs {
+		if job.Type == Type && (latest == nil || job.ID > latest.ID) {
+			latest = job
+		}
+	}
+	if latest == nil {
+		return interfaces.JobInformation{}, sql.ErrNoRows
+	}
+	return *latest, nil
+}
+
+//GetLatestJobWithPayload returns the newest job of a type with a payload, such as the job for one image, or sql.ErrNoRows if there has been none
+func (DBConnection *MemoryPlugin) GetLatestJobWithPayload(Type string, Payload string) (interfaces.JobInformation, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	var latest *interfaces.JobInformation
+	for _, job := range DBConnection.jobs {
+		if job.Type == Type && job.Payload == Payload && (latest == nil || job.ID > latest.ID) {
+			latest = job
+		}
+	}
+	if latest == nil {
+		return interfaces.JobInformation{}, sql.ErrNoRows
+	}
+	return *latest, nil
+}
+
+//GetJobs returns jobs with a status, or all jobs when Status is "", newest first (Returns a list of jobs, the count of all matching jobs, and or error)
+func (DBConnection *MemoryPlugin) GetJobs(Status string, PageStart uint64, PageStride uint64) ([]interfaces.JobInformation, uint64, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	var matches []interfaces.JobInformation
+	for _, job := range DBConnection.jobs {
+		if Status == "" || job.Status == Status {
+			matches = append(matches, *job)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].ID > matches[j].ID })
+	start, end := pageBounds(len(matches), PageStart, PageStride)
+	return matches[start:end], uint64(len(matches)), nil
+}
+
+//RemoveJobs removes jobs with a status that have not changed within OlderThan, returns the count removed
+func (DBConnection *MemoryPlugin) RemoveJobs(Status string, OlderThan time.Duration) (int64, error) {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	var removed int64
+	cutoff := time.Now().Add(-OlderThan)
+	for ID, job := range DBConnection.jobs {
+		if job.Status == Status && job.UpdateTime.After(cutoff) == false {
+			delete(DBConnection.jobs, ID)
+			removed++
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,398 @@
+package memoryplugin
+
+import (
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+//MemoryPlugin acts as a database held entirely in memory. Nothing is persisted, so it is intended for tests and throwaway instances.
+//Behaviour, including what the MariaDB triggers and procedures do, is mirrored so it can stand in for a real database.
+type MemoryPlugin struct {
+	lock sync.RWMutex
+	memoryTables
+	//inTransaction is set on the copy handed to RunInTransaction's Work
+	inTransaction bool
+}
+
+//memoryTables holds every table, so a transaction can work on a copy of them
+type memoryTables struct {
+	users             map[uint64]*memoryUser
+	tags              map[uint64]*memoryTag
+	images            map[uint64]*memoryImage
+	imageTags         map[tagPair]*memoryLink
+	imagedHashes      map[uint64]memoryHash
+	imageMetadata     map[uint64]interfaces.ImageMetadata
+	imageColors       map[uint64][]interfaces.ImageColor
+	imageUserScores   map[userImagePair]*memoryScore
+	collections       map[uint64]*memoryCollection
+	collectionMembers map[collectionImagePair]*memoryMember
+	collectionTags    map[tagPair]*memoryLink
+	auditLogs         []memoryAuditLog
+	jobs              map[uint64]*interfaces.JobInformation
+
+	lastUserID       uint64
+	lastTagID        uint64
+	lastImageID      uint64
+	lastCollectionID uint64
+	lastJobID        uint64
+}
+
+//memoryUser mirrors a row of the Users table
+type memoryUser struct {
+	ID               uint64
+	Name             string
+	EMail            string
+	PasswordHash     string
+	TokenID          string
+	IP               string
+	SecQuestionOne   string
+	SecQuestionTwo   string
+	SecQuestionThree string
+	SecAnswerOne     string
+	SecAnswerTwo     string
+	SecAnswerThree   string
+	CreationTime     time.Time
+	Disabled         bool
+	Permissions      uint64
+	SearchFilter     string
+}
+
+//memoryTag mirrors a row of the Tags table
+type memoryTag struct {
+	ID          uint64
+	Name        string
+	Description string
+	UploaderID  uint64
+	UploadTime  time.Time
+	AliasedID   uint64
+	IsAlias     bool
+}
+
+//memoryImage mirrors a row of the Images table
+type memoryImage struct {
+	ID           uint64
+	UploaderID   uint64
+	Name         string
+	Rating       string
+	ScoreTotal   int64
+	ScoreAverage int64
+	ScoreVoters  int64
+	Location     string
+	Source       string
+	UploadTime   time.Time
+	Description  string
+	Width        uint64
+	Height       uint64
+	FileSize     uint64
+	MIMEType     string
+	Duration     float64
+	Bitrate      uint64
+	BlurHash     string
+}
+
+//memoryCollection mirrors a row of the Collections table
+type memoryCollection struct {
+	ID          uint64
+	Name        string
+	Description string
+	UploaderID  uint64
+	UploadTime  time.Time
+}
+
+//memoryHash mirrors a row of the ImagedHashes table
+type memoryHash struct {
+	hHash uint64
+	vHash uint64
+}
+
+//memoryLink mirrors a row of the ImageTags or CollectionTags tables
+type memoryLink struct {
+	LinkerID uint64
+	LinkTime time.Time
+}
+
+//memoryMember mirrors a row of the CollectionMembers table
+type memoryMember struct {
+	LinkerID    uint64
+	LinkTime    time.Time
+	OrderWeight uint64
+}
+
+//memoryScore mirrors a row of the ImageUserScores table
+type memoryScore struct {
+	Score        int64
+	CreationTime time.Time
+}
+
+//memoryAuditLog mirrors a row of the AuditLogs table
+type memoryAuditLog struct {
+	UserID  uint64
+	Type    string
+	Info    string
+	LogTime time.Time
+}
+
+//tagPair keys ImageTags (OwnerID is the ImageID) and CollectionTags (OwnerID is the CollectionID)
+type tagPair struct {
+	TagID   uint64
+	OwnerID uint64
+}
+
+//userImagePair keys ImageUserScores
+type userImagePair struct {
+	UserID  uint64
+	ImageID uint64
+}
+
+//collectionImagePair keys CollectionMembers
+type collectionImagePair struct {
+	CollectionID uint64
+	ImageID      uint64
+}
+
+//InitDatabase prepares the empty tables, calling it again wipes all data
+func (DBConnection *MemoryPlugin) InitDatabase() error {
+	rand.Seed(time.Now().UnixNano())
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+
+	DBConnection.users = make(map[uint64]*memoryUser)
+	DBConnection.tags = make(map[uint64]*memoryTag)
+	DBConnection.images = make(map[uint64]*memoryImage)
+	DBConnection.imageTags = make(map[tagPair]*memoryLink)
+	DBConnection.imagedHashes = make(map[uint64]memoryHash)
+	DBConnection.imageMetadata = make(map[uint64]interfaces.ImageMetadata)
+	DBConnection.imageColors = make(map[uint64][]interfaces.ImageColor)
+	DBConnection.imageUserScores = make(map[userImagePair]*memoryScore)
+	DBConnection.collections = make(map[uint64]*memoryCollection)
+	DBConnection.collectionMembers = make(map[collectionImagePair]*memoryMember)
+	DBConnection.collectionTags = make(map[tagPair]*memoryLink)
+	DBConnection.auditLogs = nil
+	DBConnection.jobs = make(map[uint64]*interfaces.JobInformation)
+	DBConnection.lastUserID = 0
+	DBConnection.lastTagID = 0
+	DBConnection.lastImageID = 0
+	DBConnection.lastCollectionID = 0
+	DBConnection.lastJobID = 0
+
+	//Reserve system for auditing
+	DBConnection.users[0] = &memoryUser{ID: 0, Name: "SYSTEM", CreationTime: time.Now(), Disabled: true}
+
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/InitDatabase", "0", logging.ResultInfo, []string{"In-memory database initialized, no data will be persisted"})
+	return nil
+}
+
+//RunInTransaction runs Work against a copy of the tables, which replace the originals only if Work returns nil. Everyone else is held off until then
+func (DBConnection *MemoryPlugin) RunInTransaction(Work func(Transaction interfaces.DBInterface) error) error {
+	if DBConnection.inTransaction {
+		return Work(DBConnection) //Already in one
+	}
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	transaction := &MemoryPlugin{memoryTables: DBConnection.memoryTables.copy(), inTransaction: true}
+	if err := Work(transaction); err != nil {
+		return err
+	}
+	DBConnection.memoryTables = transaction.memoryTables
+	return nil
+}
+
+//copy returns a deep copy of the tables, rows are copied too as they are changed in place
+func (Tables memoryTables) copy() memoryTables {
+	Copy := Tables
+	Copy.users = make(map[uint64]*memoryUser, len(Tables.users))
+	for key, row := range Tables.users {
+		rowCopy := *row
+		Copy.users[key] = &rowCopy
+	}
+	Copy.tags = make(map[uint64]*memoryTag, len(Tables.tags))
+	for key, row := range Tables.tags {
+		rowCopy := *row
+		Copy.tags[key] = &rowCopy
+	}
+	Copy.images = make(map[uint64]*memoryImage, len(Tables.images))
+	for key, row := range Tables.images {
+		rowCopy := *row
+		Copy.images[key] = &rowCopy
+	}
+	Copy.imageTags = make(map[tagPair]*memoryLink, len(Tables.imageTags))
+	for key, row := range Tables.imageTags {
+		rowCopy := *row
+		Copy.imageTags[key] = &rowCopy
+	}
+	Copy.imagedHashes = make(map[uint64]memoryHash, len(Tables.imagedHashes))
+	for key, row := range Tables.imagedHashes {
+		Copy.imagedHashes[key] = row
+	}
+	Copy.imageMetadata = make(map[uint64]interfaces.ImageMetadata, len(Tables.imageMetadata))
+	for key, row := range Tables.imageMetadata {
+		Copy.imageMetadata[key] = row
+	}
+	//Colours are replaced as a whole rather than changed in place, so the slices can be shared
+	Copy.imageColors = make(map[uint64][]interfaces.ImageColor, len(Tables.imageColors))
+	for key, row := range Tables.imageColors {
+		Copy.imageColors[key] = row
+	}
+	Copy.imageUserScores = make(map[userImagePair]*memoryScore, len(Tables.imageUserScores))
+	for key, row := range Tables.imageUserScores {
+		rowCopy := *row
+		Copy.imageUserScores[key] = &rowCopy
+	}
+	Copy.collections = make(map[uint64]*memoryCollection, len(Tables.collections))
+	for key, row := range Tables.collections {
+		rowCopy := *row
+		Copy.collections[key] = &rowCopy
+	}
+	Copy.collectionMembers = make(map[collectionImagePair]*memoryMember, len(Tables.collectionMembers))
+	for key, row := range Tables.collectionMembers {
+		rowCopy := *row
+		Copy.collectionMembers[key] = &rowCopy
+	}
+	Copy.collectionTags = make(map[tagPair]*memoryLink, len(Tables.collectionTags))
+	for key, row := range Tables.collectionTags {
+		rowCopy := *row
+		Copy.collectionTags[key] = &rowCopy
+	}
+	Copy.auditLogs = append([]memoryAuditLog(nil), Tables.auditLogs...)
+	Copy.jobs = make(map[uint64]*interfaces.JobInformation, len(Tables.jobs))
+	for key, row := range Tables.jobs {
+		rowCopy := *row
+		Copy.jobs[key] = &rowCopy
+	}
+	return Copy
+}
+
+//Support Functions, callers must hold the lock
+
+//addMissingCollectionImageTags replaces the MariaDB procedure of the same name, adding an image's tags to every collection it is in
+func (DBConnection *MemoryPlugin) addMissingCollectionImageTags(ImageID uint64) int64 {
+	var added int64
+	for memberKey := range DBConnection.collectionMembers {
+		if memberKey.ImageID != ImageID {
+			continue
+		}
+		for tagKey, imageTag := range DBConnection.imageTags {
+			if tagKey.OwnerID != ImageID {
+				continue
+			}
+			collectionKey := tagPair{TagID: tagKey.TagID, OwnerID: memberKey.CollectionID}
+			if _, exists := DBConnection.collectionTags[collectionKey]; exists == false {
+				DBConnection.collectionTags[collectionKey] = &memoryLink{LinkerID: imageTag.LinkerID, LinkTime: time.Now()}
+				added++
+			}
+		}
+	}
+	return added
+}
+
+//remSurplusCollectionImageTags replaces the MariaDB procedure of the same name, removing collection tags no member image still has
+func (DBConnection *MemoryPlugin) remSurplusCollectionImageTags(CollectionID uint64) int64 {
+	var removed int64
+	for collectionKey := range DBConnection.collectionTags {
+		if collectionKey.OwnerID != CollectionID {
+			continue
+		}
+		if DBConnection.collectionHasImageTag(CollectionID, collectionKey.TagID) == false {
+			delete(DBConnection.collectionTags, collectionKey)
+			removed++
+		}
+	}
+	return removed
+}
+
+//collectionHasImageTag returns whether any member of a collection has the provided tag
+func (DBConnection *MemoryPlugin) collectionHasImageTag(CollectionID uint64, TagID uint64) bool {
+	for memberKey := range DBConnection.collectionMembers {
+		if memberKey.CollectionID != CollectionID {
+			continue
+		}
+		if _, exists := DBConnection.imageTags[tagPair{TagID: TagID, OwnerID: memberKey.ImageID}]; exists {
+			return true
+		}
+	}
+	return false
+}
+
+//insertImageTag adds an ImageTags row and performs the work of the onImageTagInsert trigger
+func (DBConnection *MemoryPlugin) insertImageTag(TagID uint64, ImageID uint64, LinkerID uint64) {
+	DBConnection.imageTags[tagPair{TagID: TagID, OwnerID: ImageID}] = &memoryLink{LinkerID: LinkerID, LinkTime: time.Now()}
+	DBConnection.addMissingCollectionImageTags(ImageID)
+}
+
+//deleteImageTag removes an ImageTags row and performs the work of the onImageTagDelete trigger
+func (DBConnection *MemoryPlugin) deleteImageTag(TagID uint64, ImageID uint64) bool {
+	key := tagPair{TagID: TagID, OwnerID: ImageID}
+	if _, exists := DBConnection.imageTags[key]; exists == false {
+		return false
+	}
+	delete(DBConnection.imageTags, key)
+	for memberKey := range DBConnection.collectionMembers {
+		if memberKey.ImageID == ImageID {
+			DBConnection.remSurplusCollectionImageTags(memberKey.CollectionID)
+		}
+	}
+	return true
+}
+
+//insertCollectionMember adds a CollectionMembers row and performs the work of the onCollectionMemberAdd trigger
+func (DBConnection *MemoryPlugin) insertCollectionMember(CollectionID uint64, ImageID uint64, LinkerID uint64, OrderWeight uint64) {
+	DBConnection.collectionMembers[collectionImagePair{CollectionID: CollectionID, ImageID: ImageID}] = &memoryMember{LinkerID: LinkerID, LinkTime: time.Now(), OrderWeight: OrderWeight}
+	DBConnection.addMissingCollectionImageTags(ImageID)
+}
+
+//deleteCollectionMember removes a CollectionMembers row and performs the work of the onCollectionMemberDelete trigger
+func (DBConnection *MemoryPlugin) deleteCollectionMember(CollectionID uint64, ImageID uint64) {
+	delete(DBConnection.collectionMembers, collectionImagePair{CollectionID: CollectionID, ImageID: ImageID})
+	DBConnection.remSurplusCollectionImageTags(CollectionID)
+}
+
+//collectionMemberCount returns the number of images in a collection
+func (DBConnection *MemoryPlugin) collectionMemberCount(CollectionID uint64) uint64 {
+	var count uint64
+	for memberKey := range DBConnection.collectionMembers {
+		if memberKey.CollectionID == CollectionID {
+			count++
+		}
+	}
+	return count
+}
+
+//collectionPreview returns the location of the first image in a collection, or "" if it has none
+func (DBConnection *MemoryPlugin) collectionPreview(CollectionID uint64) string {
+	location := ""
+	var lowestOrder uint64
+	found := false
+	for memberKey, member := range DBConnection.collectionMembers {
+		if memberKey.CollectionID != CollectionID {
+			continue
+		}
+		image, exists := DBConnection.images[memberKey.ImageID]
+		if exists == false {
+			continue
+		}
+		if found == false || member.OrderWeight < lowestOrder || (member.OrderWeight == lowestOrder && image.Location < location) {
+			location = image.Location
+			lowestOrder = member.OrderWeight
+			found = true
+		}
+	}
+	return location
+}
+
+//deleteCollection removes a collection and performs the work of the onCollectionDelete trigger
+func (DBConnection *MemoryPlugin) deleteCollection(CollectionID uint64) {
+	for memberKey := range DBConnection.collectionMembers {
+		if memberKey.CollectionID == CollectionID {
+			DBConnection.deleteCollectionMember(CollectionID, memberKey.ImageID)
+		}
+	}
+	for collectionKey := range DBConnection.collectionTags {
+		if collectionKey.OwnerID == CollectionID {
+			delete(DBConnection.collectionTags, collectionKey)
+		}
+	}
+	delete(DBConnection.collections, CollectionID)
+}
@@ -0,0 +1,17 @@
+package memoryplugin
+
+import (
+	"go-image-board/database/dbtest"
+	"go-image-board/interfaces"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	dbtest.RunConformance(t, func(t *testing.T) interfaces.DBInterface {
+		DBConnection := &MemoryPlugin{}
+		if err := DBConnection.InitDatabase(); err != nil {
+			t.Fatalf("InitDatabase: %v", err)
+		}
+		return DBConnection
+	})
+}
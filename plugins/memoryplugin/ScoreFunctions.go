@@ -0,0 +1,61 @@
+package memoryplugin
+
+import (
+	"go-image-board/logging"
+	"math"
+	"strconv"
+	"time"
+)
+
+//Score operations
+
+//UpdateUserVoteScore Either creates or changes a user's vote on an image
+func (DBConnection *MemoryPlugin) UpdateUserVoteScore(UserID uint64, ImageID uint64, Score int64) error {
+	DBConnection.lock.Lock()
+	if existing, exists := DBConnection.imageUserScores[userImagePair{UserID: UserID, ImageID: ImageID}]; exists {
+		//Like the SQL plugins, changing a vote keeps its CreationTime
+		existing.Score = Score
+	} else {
+		DBConnection.imageUserScores[userImagePair{UserID: UserID, ImageID: ImageID}] = &memoryScore{Score: Score, CreationTime: time.Now()}
+	}
+	DBConnection.lock.Unlock()
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/UpdateUserVoteScore", strconv.FormatUint(UserID, 10), logging.ResultSuccess, []string{"Score added/updated"})
+	//The SQL plugins do this in the background, it is cheap enough here to keep results deterministic
+	return DBConnection.UpdateScoreOnImage(ImageID)
+}
+
+//UpdateScoreOnImage update ScoreTotal, ScoreAverage, and ScoreVoters on an image
+func (DBConnection *MemoryPlugin) UpdateScoreOnImage(ImageID uint64) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	var count, sum int64
+	for key, score := range DBConnection.imageUserScores {
+		if key.ImageID == ImageID {
+			count++
+			sum += score.Score
+		}
+	}
+	image, exists := DBConnection.images[ImageID]
+	if exists == false {
+		return nil //Matches an UPDATE that found no rows
+	}
+	image.ScoreTotal = sum
+	image.ScoreVoters = count
+	image.ScoreAverage = 0
+	if count > 0 {
+		image.ScoreAverage = int64(math.Round(float64(sum) / float64(count)))
+	}
+	return nil
+}
+
+//GetUserVoteScore Returns a user's vote on an image
+func (DBConnection *MemoryPlugin) GetUserVoteScore(UserID uint64, ImageID uint64) (int64, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	score, exists := DBConnection.imageUserScores[userImagePair{UserID: UserID, ImageID: ImageID}]
+	if exists == false {
+		//Like the SQL plugins, no vote is a score of 0
+		return 0, nil
+	}
+	return score.Score, nil
+}
@@ -0,0 +1,231 @@
+package memoryplugin
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Tag Operations
+var regexTagName = regexp.MustCompile("[^a-zA-Z0-9_-]") //Used to cleanup tag names
+var regexWhiteSpace = regexp.MustCompile("\\s{2,}")     //Matches 2 or more consecutive whitespace
+var regexTagValue = regexp.MustCompile("[^a-zA-Z0-9_\\-\\.:/#]")
+
+func prepareTagName(Name string) string {
+	//Lowercase Name -> Trimmed front and end of whitespace -> any inner whitespace reduced and underscored
+	Name = regexWhiteSpace.ReplaceAllString(strings.TrimSpace(strings.ToLower(Name)), "_") //Replace all whitespace with _
+	//Case of metatag
+	if strings.Contains(Name, ":") {
+		//Assume a metatag, the value may contain more colons such as ratio:16:9
+		NameValue := strings.SplitN(Name, ":", 2)
+		value, comparator := getTagComparator(NameValue[1]) //Strip comparator, so it does not get replaced by a _
+		Name = regexTagName.ReplaceAllString(NameValue[0], "_") + ":" + comparator + regexTagValue.ReplaceAllString(value, "_")
+	} else {
+		//Then any special characters replaced with _
+		Name = regexTagName.ReplaceAllString(Name, "_")
+	}
+	return Name
+}
+
+//NewTag adds a tag with the provided information
+func (DBConnection *MemoryPlugin) NewTag(Name string, Description string, UploaderID uint64) (uint64, error) {
+	//Cleanup name
+	Name = prepareTagName(Name)
+
+	if len(Name) < 3 || len(Name) > 255 || len(Description) > 255 {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/NewTag", strconv.FormatUint(UploaderID, 10), logging.ResultFailure, []string{"Failed to add tag dues to size of name/description", Name, Description})
+		return 0, errors.New("name or description outside of right sizes")
+	}
+
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	//Name is unique
+	if DBConnection.getTagByName(Name) != nil {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/NewTag", strconv.FormatUint(UploaderID, 10), logging.ResultFailure, []string{"Failed to add tag", "name already in use"})
+		return 0, errors.New("a tag with that name already exists")
+	}
+	DBConnection.lastTagID++
+	DBConnection.tags[DBConnection.lastTagID] = &memoryTag{ID: DBConnection.lastTagID, Name: Name, Description: Description, UploaderID: UploaderID, UploadTime: time.Now()}
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/NewTag", strconv.FormatUint(UploaderID, 10), logging.ResultSuccess, []string{"Tag added", strconv.FormatUint(DBConnection.lastTagID, 10)})
+
+	return DBConnection.lastTagID, nil
+}
+
+//DeleteTag removes a tag
+func (DBConnection *MemoryPlugin) DeleteTag(TagID uint64) error {
+	DBConnection.lock.Lock()
+	defer DBConnection.lock.Unlock()
+	//Ensure not in use
+	useCount := DBConnection.tagUseCount(TagID)
+	if useCount > 0 {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/DeleteTag", "0", logging.ResultFailure, []string{"Tag to delete is still in use", strconv.FormatUint(TagID, 10), "in use", strconv.FormatUint(useCount, 10)})
+		return errors.New("tag to delete is still in use")
+	}
+
+	//Delete, along with what the onTagDelete trigger would remove
+	for collectionKey := range DBConnection.collectionTags {
+		if collectionKey.TagID == TagID {
+			delete(DBConnection.collectionTags, collectionKey)
+		}
+	}
+	delete(DBConnection.tags, TagID)
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/DeleteTag", "0", logging.ResultSuccess, []string{"Tag deleted", strconv.FormatUint(TagID, 10)})
+	return nil
+}
+
+//GetAllTags returns a list of all tags, but only the ID, Name, Description, and IsAlias
+func (DBConnection *MemoryPlugin) GetAllTags() ([]interfaces.TagInformation, error) {
+	var ToReturn []interfaces.TagInformation
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	for _, tag := range DBConnection.tags {
+		ToReturn = append(ToReturn, interfaces.TagInformation{Name: tag.Name, ID: tag.ID, Description: tag.Description, Exists: true, Exclude: false, IsAlias: tag.IsAlias})
+	}
+	sort.Slice(ToReturn, func(i, j int) bool {
+		return ToReturn[i].Name < ToReturn[j].Name
+	})
+	return ToReturn, nil
+}
+
+//GetTag returns detailed information on one tag
+func (DBConnection *MemoryPlugin) GetTag(ID uint64, IncludeCount bool) (interfaces.TagInformation, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	tag, exists := DBConnection.tags[ID]
+	if exists == false {
+		return interfaces.TagInformation{ID: ID, Exists: false}, sql.ErrNoRows
+	}
+	ToReturn := tagInformation(tag, false)
+	if IncludeCount {
+		ToReturn.UseCount = DBConnection.tagUseCount(ID)
+	}
+	return ToReturn, nil
+}
+
+//GetTagByName returns detailed information on one tag as queried by name
+func (DBConnection *MemoryPlugin) GetTagByName(Name string) (interfaces.TagInformation, error) {
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	tag := DBConnection.getTagByName(Name)
+	if tag == nil {
+		return interfaces.TagInformation{Name: Name, Exists: false}, sql.ErrNoRows
+	}
+	return tagInformation(tag, false), nil
+}
+
+//UpdateTag updates a pre-existing tag
+func (DBConnection *MemoryPlugin) UpdateTag(TagID uint64, Name string, Description string, AliasedID uint64, IsAlias bool, RequestorID uint64) error {
+	//Cleanup name
+	Name = prepareTagName(Name)
+	if len(Name) < 3 || len(Name) > 255 || len(Description) > 255 {
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/UpdateTag", strconv.FormatUint(RequestorID, 10), logging.ResultFailure, []string{"Failed to update tag dues to size", Name, Description})
+		return errors.New("name or description outside of right sizes")
+	}
+
+	if IsAlias {
+		//Prevent adding alias
+		tagInfo, err := DBConnection.GetTag(AliasedID, false)
+		if err != nil || tagInfo.IsAlias {
+			return errors.New("Tag to alias could not be found, or is an alias itself")
+		}
+	}
+
+	DBConnection.lock.Lock()
+	if existing := DBConnection.getTagByName(Name); existing != nil && existing.ID != TagID {
+		DBConnection.lock.Unlock()
+		logging.WriteLog(logging.LogLevelError, "MemoryPlugin/UpdateTag", strconv.FormatUint(RequestorID, 10), logging.ResultFailure, []string{"Failed to update tag", "name already in use"})
+		return errors.New("a tag with that name already exists")
+	}
+	if tag, exists := DBConnection.tags[TagID]; exists {
+		tag.Name = Name
+		tag.Description = Description
+		tag.AliasedID = AliasedID
+		tag.IsAlias = IsAlias
+	}
+	DBConnection.lock.Unlock()
+	logging.WriteLog(logging.LogLevelError, "MemoryPlugin/UpdateTag", strconv.FormatUint(RequestorID, 10), logging.ResultSuccess, []string{"Image added"})
+
+	if IsAlias {
+		//The SQL plugins do this in the background, it is cheap enough here to keep results deterministic
+		return DBConnection.ReplaceImageTags(TagID, AliasedID, RequestorID)
+	}
+
+	return nil
+}
+
+//SearchTags returns a list of tags like the provided name, but only the ID, Name, Description, and IsAlias
+func (DBConnection *MemoryPlugin) SearchTags(name string, PageStart uint64, PageStride uint64, WildcardForwardOnly bool, SortByUsage bool) ([]interfaces.TagInformation, uint64, error) {
+	var ToReturn []interfaces.TagInformation
+
+	//Cleanup Query and alter if we were provided a name
+	name = strings.TrimSpace(name)
+	name = strings.Replace(name, "%", "", -1)
+	if WildcardForwardOnly {
+		name = name + "%"
+	} else {
+		name = "%" + name + "%"
+	}
+	nameMatcher := likeMatcher(name)
+
+	DBConnection.lock.RLock()
+	defer DBConnection.lock.RUnlock()
+	var MaxResults uint64
+	usage := make(map[uint64]uint64)
+	for _, tag := range DBConnection.tags {
+		if nameMatcher.MatchString(tag.Name) == false {
+			continue
+		}
+		//Count query does not join on usage, so unused tags are still counted
+		MaxResults++
+		if SortByUsage {
+			usage[tag.ID] = DBConnection.tagUseCount(tag.ID)
+			if usage[tag.ID] == 0 {
+				continue
+			}
+		}
+		ToReturn = append(ToReturn, interfaces.TagInformation{Name: tag.Name, ID: tag.ID, Description: tag.Description, Exists: true, Exclude: false, IsAlias: tag.IsAlias})
+	}
+
+	//Add the sorting
+	sort.Slice(ToReturn, func(i, j int) bool {
+		if SortByUsage && usage[ToReturn[i].ID] != usage[ToReturn[j].ID] {
+			return usage[ToReturn[i].ID] > usage[ToReturn[j].ID]
+		}
+		return ToReturn[i].Name < ToReturn[j].Name
+	})
+
+	start, end := pageBounds(len(ToReturn), PageStart, PageStride)
+	return ToReturn[start:end], MaxResults, nil
+}
+
+//getTagByName returns the tag with a matching name, or nil. Callers must hold the lock
+func (DBConnection *MemoryPlugin) getTagByName(Name string) *memoryTag {
+	for _, tag := range DBConnection.tags {
+		if strings.EqualFold(tag.Name, Name) {
+			return tag
+		}
+	}
+	return nil
+}
+
+//tagUseCount returns how many images have a tag. Callers must hold the lock
+func (DBConnection *MemoryPlugin) tagUseCount(TagID uint64) uint64 {
+	var count uint64
+	for tagKey := range DBConnection.imageTags {
+		if tagKey.TagID == TagID {
+			count++
+		}
+	}
+	return count
+}
+
+//tagInformation converts a tag row into a TagInformation
+func tagInformation(tag *memoryTag, Exclude bool) interfaces.TagInformation {
+	return interfaces.TagInformation{Name: tag.Name, ID: tag.ID, Description: tag.Description, Exists: true, Exclude: Exclude, UploaderID: tag.UploaderID, UploadTime: tag.UploadTime, AliasedID: tag.AliasedID, IsAlias: tag.IsAlias}
+}
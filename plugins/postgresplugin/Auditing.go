@@ -0,0 +1,35 @@
+package postgresplugin
+
+import (
+	"go-image-board/logging"
+	"strconv"
+	"time"
+)
+
+//AddAuditLog adds an audit event into the audit table
+func (DBConnection *PostgresPlugin) AddAuditLog(UserID uint64, Type string, Info string) error {
+	if len(Type) > 40 || len(Info) > 10240 {
+
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/AddAuditLog", strconv.FormatUint(UserID, 10), logging.ResultFailure, []string{"either the type, or the info is too long for the audit log table", Type, Info})
+		if len(Info) > 10240 {
+			Info = Info[:10240]
+		}
+		if len(Type) > 40 {
+			Type = Type[:40]
+		}
+		//return errors.New("either the type, or the info is too long for the audit log table")
+	}
+
+	_, err := DBConnection.handle().Exec("INSERT INTO AuditLogs (UserID, Type, Info) VALUES (?, ?, ?);", UserID, Type, Info)
+	return err
+}
+
+//RemoveAuditLogs removes audit logs older than OlderThan, returns the count removed
+func (DBConnection *PostgresPlugin) RemoveAuditLogs(OlderThan time.Duration) (int64, error) {
+	resultInfo, err := DBConnection.handle().Exec("DELETE FROM AuditLogs WHERE LogTime < CURRENT_TIMESTAMP - CAST(? AS INTERVAL);", secondsInterval(OlderThan))
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/RemoveAuditLogs", "0", logging.ResultFailure, []string{"Failed to remove old audit logs", err.Error()})
+		return 0, err
+	}
+	return resultInfo.RowsAffected()
+}
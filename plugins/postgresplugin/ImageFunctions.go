@@ -0,0 +1,315 @@
+package postgresplugin
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"strconv"
+)
+
+//Image operations
+
+//NewImage adds an image with the provided information
+func (DBConnection *PostgresPlugin) NewImage(ImageName string, ImageFileName string, OwnerID uint64, Source string) (uint64, error) {
+	var id int64
+	err := DBConnection.handle().QueryRow("INSERT INTO Images (Name, Location, UploaderID, Source) VALUES (?, ?, ?, ?) RETURNING ID;", ImageName, ImageFileName, OwnerID, Source).Scan(&id)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/NewImage", strconv.FormatUint(OwnerID, 10), logging.ResultFailure, []string{"Failed to add image", err.Error()})
+		return 0, err
+	}
+	logging.WriteLog(logging.LogLevelError, "PostgresPlugin/NewImage", strconv.FormatUint(OwnerID, 10), logging.ResultSuccess, []string{"Image added"})
+	return uint64(id), err
+}
+
+//DeleteImage removes an image from the db
+func (DBConnection *PostgresPlugin) DeleteImage(ImageID uint64) error {
+	//First, remove image from any associated collections
+	collectionInfo, err := DBConnection.GetCollectionsWithImage(ImageID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/DeleteImage", "0", logging.ResultFailure, []string{"Failed to get collection data to delete image", err.Error(), strconv.FormatUint(ImageID, 10)})
+		return err
+	}
+
+	for I := 0; I < len(collectionInfo); I++ {
+		if err := DBConnection.RemoveCollectionMember(collectionInfo[I].ID, ImageID); err != nil {
+			logging.WriteLog(logging.LogLevelWarning, "PostgresPlugin/DeleteImage", "0", logging.ResultFailure, []string{"Failed to remove image from collection", err.Error(), strconv.FormatUint(ImageID, 10)})
+		}
+	}
+
+	//First delete ImageTags
+	_, err = DBConnection.handle().Exec("DELETE FROM ImageTags WHERE ImageID=?;", ImageID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/DeleteImage", "0", logging.ResultFailure, []string{"Failed to delete image", err.Error(), strconv.FormatUint(ImageID, 10)})
+		return err
+	}
+	logging.WriteLog(logging.LogLevelError, "PostgresPlugin/DeleteImage", "0", logging.ResultSuccess, []string{"Image tags deleted", strconv.FormatUint(ImageID, 10)})
+	//Second delete Image from table
+	_, err = DBConnection.handle().Exec("DELETE FROM Images WHERE ID=?;", ImageID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/DeleteImage", "0", logging.ResultFailure, []string{"Failed to delete image", err.Error(), strconv.FormatUint(ImageID, 10)})
+	} else {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/DeleteImage", "0", logging.ResultSuccess, []string{"Image deleted", strconv.FormatUint(ImageID, 10)})
+	}
+	return err
+}
+
+//UpdateImage updates properties of an image
+func (DBConnection *PostgresPlugin) UpdateImage(ImageID uint64, ImageName interface{}, ImageDescription interface{}, OwnerID interface{}, Rating interface{}, Source interface{}, Location interface{}) error {
+	if _, correctValue := OwnerID.(uint64); OwnerID != nil && correctValue == false {
+		return errors.New("OwnerID, when provided, must be of uint64 type")
+	}
+
+	//See if image exists
+	_, err := DBConnection.GetImage(ImageID)
+	if err != nil {
+		return err
+	}
+
+	queryArray := []interface{}{}
+	sqlQuery := ""
+
+	if ImageName != nil {
+		queryArray = append(queryArray, fmt.Sprintf("%v", ImageName))
+		if sqlQuery != "" {
+			sqlQuery += ", "
+		}
+		sqlQuery += "Name = ? "
+	}
+	if ImageDescription != nil {
+		queryArray = append(queryArray, fmt.Sprintf("%v", ImageDescription))
+		if sqlQuery != "" {
+			sqlQuery += ", "
+		}
+		sqlQuery += "Description = ? "
+	}
+	if unwrappedOwnerID, correctValue := OwnerID.(uint64); OwnerID != nil && correctValue {
+		queryArray = append(queryArray, unwrappedOwnerID)
+		if sqlQuery != "" {
+			sqlQuery += ", "
+		}
+		sqlQuery += "UploaderID = ? "
+	}
+	if Rating != nil {
+		queryArray = append(queryArray, fmt.Sprintf("%v", Rating))
+		if sqlQuery != "" {
+			sqlQuery += ", "
+		}
+		sqlQuery += "Rating = ? "
+	}
+	if Source != nil {
+		queryArray = append(queryArray, fmt.Sprintf("%v", Source))
+		if sqlQuery != "" {
+			sqlQuery += ", "
+		}
+		sqlQuery += "Source = ? "
+	}
+	if Location != nil {
+		queryArray = append(queryArray, fmt.Sprintf("%v", Location))
+		if sqlQuery != "" {
+			sqlQuery += ", "
+		}
+		sqlQuery += "Location = ? "
+	}
+	queryArray = append(queryArray, ImageID)
+	if sqlQuery == "" {
+		return nil //No change requested
+	}
+	sqlQuery = "UPDATE Images SET " + sqlQuery + "WHERE ID = ?"
+	_, err = DBConnection.handle().Exec(sqlQuery, queryArray...)
+	return err
+}
+
+//GetImage returns information on a single image (Returns an ImageInformation, or error)
+func (DBConnection *PostgresPlugin) GetImage(ID uint64) (interfaces.ImageInformation, error) {
+	ToReturn := interfaces.ImageInformation{ID: ID}
+	var UploadTime sql.NullTime
+	err := DBConnection.handle().QueryRow("Select Images.Name, COALESCE(Images.Description,'') AS Description, Images.Location, Images.UploaderID, Images.UploadTime, Images.Rating, Users.Name, Images.ScoreAverage, Images.ScoreTotal, Images.ScoreVoters, Images.Source, Images.Width, Images.Height, Images.FileSize, Images.MIMEType, Images.Duration, Images.Bitrate, Images.BlurHash FROM Images LEFT OUTER JOIN Users ON Images.UploaderID = Users.ID WHERE Images.ID=?", ID).Scan(&ToReturn.Name, &ToReturn.Description, &ToReturn.Location, &ToReturn.UploaderID, &UploadTime, &ToReturn.Rating, &ToReturn.UploaderName, &ToReturn.ScoreAverage, &ToReturn.ScoreTotal, &ToReturn.ScoreVoters, &ToReturn.Source, &ToReturn.Width, &ToReturn.Height, &ToReturn.FileSize, &ToReturn.MIMEType, &ToReturn.Duration, &ToReturn.Bitrate, &ToReturn.BlurHash)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/ImageFunctions/GetImage", "0", logging.ResultFailure, []string{"Failed to get image info from database", err.Error()})
+		return ToReturn, err
+	}
+	if UploadTime.Valid {
+		ToReturn.UploadTime = UploadTime.Time
+	}
+	return ToReturn, nil
+}
+
+//GetImageByFileName returns an ImageInformation object given a ImageName
+func (DBConnection *PostgresPlugin) GetImageByFileName(imageName string) (interfaces.ImageInformation, error) {
+	ToReturn := interfaces.ImageInformation{Location: imageName}
+	var UploadTime sql.NullTime
+	err := DBConnection.handle().QueryRow("Select Images.Name, COALESCE(Images.Description,'') AS Description, Images.ID, Images.UploaderID, Images.UploadTime, Images.Rating, Users.Name, Images.ScoreAverage, Images.ScoreTotal, Images.ScoreVoters, Images.Source, Images.Width, Images.Height, Images.FileSize, Images.MIMEType, Images.Duration, Images.Bitrate, Images.BlurHash FROM Images LEFT OUTER JOIN Users ON Images.UploaderID = Users.ID WHERE Images.Location=?", imageName).Scan(&ToReturn.Name, &ToReturn.Description, &ToReturn.ID, &ToReturn.UploaderID, &UploadTime, &ToReturn.Rating, &ToReturn.UploaderName, &ToReturn.ScoreAverage, &ToReturn.ScoreTotal, &ToReturn.ScoreVoters, &ToReturn.Source, &ToReturn.Width, &ToReturn.Height, &ToReturn.FileSize, &ToReturn.MIMEType, &ToReturn.Duration, &ToReturn.Bitrate, &ToReturn.BlurHash)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/ImageFunctions/GetImageByFileName", "0", logging.ResultFailure, []string{"Failed to get image info from database", err.Error()})
+		return ToReturn, err
+	}
+	if UploadTime.Valid {
+		ToReturn.UploadTime = UploadTime.Time
+	}
+	return ToReturn, nil
+}
+
+//SetImageRating changes a given image's rating in the database
+func (DBConnection *PostgresPlugin) SetImageRating(ID uint64, Rating string) error {
+	_, err := DBConnection.handle().Exec("UPDATE Images SET Rating = ? WHERE ID = ?;", Rating, ID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/ImageFunctions/SetImageRating", "0", logging.ResultFailure, []string{"Failed to set image rating", err.Error()})
+		return err
+	}
+	return nil
+}
+
+//SetImageSource changes a given image's source in the database
+func (DBConnection *PostgresPlugin) SetImageSource(ID uint64, Source string) error {
+	_, err := DBConnection.handle().Exec("UPDATE Images SET Source = ? WHERE ID = ?;", Source, ID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/ImageFunctions/SetImageSource", "0", logging.ResultFailure, []string{"Failed to set image source", err.Error()})
+		return err
+	}
+	return nil
+}
+
+//SetImagedHash changes a given image's dHash in the database
+func (DBConnection *PostgresPlugin) SetImagedHash(ID uint64, hHash uint64, vHash uint64) error {
+	//Postgres has no unsigned integers, so hashes are stored as their int64 bit pattern
+	_, err := DBConnection.handle().Exec("INSERT INTO ImagedHashes (ImageID, hHash, vHash) VALUES (?,?,?) ON CONFLICT(ImageID) DO UPDATE SET hHash = excluded.hHash, vHash = excluded.vHash;", ID, int64(hHash), int64(vHash))
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/ImageFunctions/SetImagedHash", "0", logging.ResultFailure, []string{"Failed to set image dHashes", err.Error()})
+		return err
+	}
+	return nil
+}
+
+//GetImagedHash changes a given image's dHash in the database
+func (DBConnection *PostgresPlugin) GetImagedHash(ID uint64) (uint64, uint64, error) {
+	var hHash, vHash int64
+	err := DBConnection.handle().QueryRow("SELECT hHash, vHash from ImagedHashes WHERE ImageID = ?", ID).Scan(&hHash, &vHash)
+	if err != nil {
+		return uint64(hHash), uint64(vHash), err
+	}
+	return uint64(hHash), uint64(vHash), nil
+}
+
+//SetImageMetadata adds or replaces the metadata of the image Metadata.ImageID
+func (DBConnection *PostgresPlugin) SetImageMetadata(Metadata interfaces.ImageMetadata) error {
+	//A zero capture time means it was not recorded
+	var captureTime interface{}
+	if Metadata.CaptureTime.IsZero() == false {
+		captureTime = Metadata.CaptureTime.UTC()
+	}
+	_, err := DBConnection.handle().Exec("INSERT INTO ImageMetadata (ImageID, CaptureTime, CameraMake, CameraModel, LensModel, ExposureTime, FNumber, FocalLength, ISO, Orientation, Title, Artist, Album, Genre) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?) ON CONFLICT(ImageID) DO UPDATE SET CaptureTime = excluded.CaptureTime, CameraMake = excluded.CameraMake, CameraModel = excluded.CameraModel, LensModel = excluded.LensModel, ExposureTime = excluded.ExposureTime, FNumber = excluded.FNumber, FocalLength = excluded.FocalLength, ISO = excluded.ISO, Orientation = excluded.Orientation, Title = excluded.Title, Artist = excluded.Artist, Album = excluded.Album, Genre = excluded.Genre;",
+		Metadata.ImageID, captureTime, Metadata.CameraMake, Metadata.CameraModel, Metadata.LensModel, Metadata.ExposureTime, Metadata.FNumber, Metadata.FocalLength, Metadata.ISO, Metadata.Orientation, Metadata.Title, Metadata.Artist, Metadata.Album, Metadata.Genre)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/ImageFunctions/SetImageMetadata", "0", logging.ResultFailure, []string{"Failed to set image metadata", err.Error()})
+		return err
+	}
+	return nil
+}
+
+//GetImageMetadata returns the metadata of an image, sql.ErrNoRows if it has none
+func (DBConnection *PostgresPlugin) GetImageMetadata(ImageID uint64) (interfaces.ImageMetadata, error) {
+	ToReturn := interfaces.ImageMetadata{ImageID: ImageID}
+	var CaptureTime sql.NullTime
+	err := DBConnection.handle().QueryRow("SELECT CaptureTime, CameraMake, CameraModel, LensModel, ExposureTime, FNumber, FocalLength, ISO, Orientation, Title, Artist, Album, Genre FROM ImageMetadata WHERE ImageID = ?", ImageID).Scan(&CaptureTime, &ToReturn.CameraMake, &ToReturn.CameraModel, &ToReturn.LensModel, &ToReturn.ExposureTime, &ToReturn.FNumber, &ToReturn.FocalLength, &ToReturn.ISO, &ToReturn.Orientation, &ToReturn.Title, &ToReturn.Artist, &ToReturn.Album, &ToReturn.Genre)
+	if err != nil {
+		return ToReturn, err
+	}
+	if CaptureTime.Valid {
+		ToReturn.CaptureTime = CaptureTime.Time
+	}
+	return ToReturn, nil
+}
+
+//SetImageColors replaces the BlurHash and dominant colours of a given image
+func (DBConnection *PostgresPlugin) SetImageColors(ImageID uint64, BlurHash string, Colors []interfaces.ImageColor) error {
+	return DBConnection.RunInTransaction(func(Transaction interfaces.DBInterface) error {
+		handle := Transaction.(*PostgresPlugin).handle()
+		if _, err := handle.Exec("UPDATE Images SET BlurHash = ? WHERE ID = ?;", BlurHash, ImageID); err != nil {
+			logging.WriteLog(logging.LogLevelError, "PostgresPlugin/ImageFunctions/SetImageColors", "0", logging.ResultFailure, []string{"Failed to set image blurhash", err.Error()})
+			return err
+		}
+		if _, err := handle.Exec("DELETE FROM ImageColors WHERE ImageID = ?;", ImageID); err != nil {
+			logging.WriteLog(logging.LogLevelError, "PostgresPlugin/ImageFunctions/SetImageColors", "0", logging.ResultFailure, []string{"Failed to clear image colors", err.Error()})
+			return err
+		}
+		for _, Color := range Colors {
+			if _, err := handle.Exec("INSERT INTO ImageColors (ImageID, Red, Green, Blue, Share) VALUES (?,?,?,?,?);", ImageID, Color.Red, Color.Green, Color.Blue, Color.Share); err != nil {
+				logging.WriteLog(logging.LogLevelError, "PostgresPlugin/ImageFunctions/SetImageColors", "0", logging.ResultFailure, []string{"Failed to add image color", err.Error()})
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+//GetImageColors returns the dominant colours of a given image, from most to least common
+func (DBConnection *PostgresPlugin) GetImageColors(ImageID uint64) ([]interfaces.ImageColor, error) {
+	rows, err := DBConnection.handle().Query("SELECT Red, Green, Blue, Share FROM ImageColors WHERE ImageID = ? ORDER BY Share DESC, ID;", ImageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ToReturn []interfaces.ImageColor
+	for rows.Next() {
+		var Color interfaces.ImageColor
+		if err := rows.Scan(&Color.Red, &Color.Green, &Color.Blue, &Color.Share); err != nil {
+			return nil, err
+		}
+		ToReturn = append(ToReturn, Color)
+	}
+	return ToReturn, rows.Err()
+}
+
+//SetImageMediaInfo changes the width, height, file size, MIME type, duration, and bitrate of the image Image.ID
+func (DBConnection *PostgresPlugin) SetImageMediaInfo(Image interfaces.ImageInformation) error {
+	_, err := DBConnection.handle().Exec("UPDATE Images SET Width = ?, Height = ?, FileSize = ?, MIMEType = ?, Duration = ?, Bitrate = ? WHERE ID = ?;", Image.Width, Image.Height, Image.FileSize, Image.MIMEType, Image.Duration, Image.Bitrate, Image.ID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/ImageFunctions/SetImageMediaInfo", "0", logging.ResultFailure, []string{"Failed to set image media info", err.Error()})
+		return err
+	}
+	return nil
+}
+
+/*
+//Our select query, if inclusive
+SELECT ImageID, Name, Location FROM (
+	SELECT ImageID, Name, Location, Count(*) as MatchingTags
+	FROM ImageTags
+	INNER JOIN Images ON ImageTags.ImageID=Images.ID
+	[WHERE ][TagID IN (1, 2, 3)]
+		[AND ][ImageID NOT IN (
+								SELECT DISTINCT ImageID FROM ImageTags WHERE TagID IN (4)
+							)]
+	GROUP BY ImageID
+) InnerStatement
+WHERE MatchingTags = 3
+ORDER BY ImageID DESC LIMIT 30 OFFSET 0;
+
+//Our Count Query
+SELECT COUNT(ImageID) FROM (
+	SELECT ImageID, Name, Location, Count(*) as MatchingTags
+	FROM ImageTags
+	INNER JOIN Images ON ImageTags.ImageID=Images.ID
+	WHERE TagID IN (1, 2, 3)
+		AND ImageID NOT IN (
+								SELECT DISTINCT ImageID FROM ImageTags WHERE TagID IN (4)
+							)
+	GROUP BY ImageID
+) InnerStatement
+WHERE MatchingTags = 3
+*/
+
+/*
+//Our select query, if blank or exlusive
+SELECT ImageID, Name, Location FROM Images [WHERE ][ImageID NOT IN (
+		SELECT DISTINCT ImageID FROM ImageTags WHERE TagID IN (4, 5, 6)
+	)]
+ORDER BY ImageID DESC LIMIT 30 OFFSET 0;
+
+//Our Count Query
+SELECT COUNT(*) FROM Images [WHERE ][ImageID NOT IN (
+		SELECT DISTINCT ImageID FROM ImageTags WHERE TagID IN (4, 5, 6)
+	)]
+*/
@@ -0,0 +1,518 @@
+package postgresplugin
+
+import (
+	"database/sql"
+	"errors"
+	"go-image-board/config"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"math/rand"
+	"time"
+
+	//Postgres driver for database/sql
+	_ "github.com/lib/pq"
+)
+
+//TODO: Increment this whenever we alter the DB Schema, ensure you attempt to add update code below
+var currentDBVersion int64 = 6
+
+//TODO: Increment this when we alter the db schema and don't add update code to compensate
+var minSupportedDBVersion int64 // 0 by default
+
+//PostgresPlugin acts as plugin between gib and a PostgreSQL DB
+type PostgresPlugin struct {
+	DBHandle *PostgresHandle
+	//tx is set on the copy handed to RunInTransaction's Work, so its queries run inside the transaction
+	tx *postgresTx
+}
+
+//sqlHandle is satisfied by both PostgresHandle and postgresTx, so the same queries run inside and outside a transaction
+type sqlHandle interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+//handle returns the transaction when there is one, or the database otherwise
+func (DBConnection *PostgresPlugin) handle() sqlHandle {
+	if DBConnection.tx != nil {
+		return DBConnection.tx
+	}
+	return DBConnection.DBHandle
+}
+
+//background runs Work in a goroutine, or straight away inside a transaction as it cannot be used once committed
+func (DBConnection *PostgresPlugin) background(Work func()) {
+	if DBConnection.tx != nil {
+		Work()
+		return
+	}
+	go Work()
+}
+
+//RunInTransaction runs Work against a copy of the plugin bound to one transaction, committing if Work returns nil and rolling back otherwise
+func (DBConnection *PostgresPlugin) RunInTransaction(Work func(Transaction interfaces.DBInterface) error) error {
+	if DBConnection.tx != nil {
+		return Work(DBConnection) //Already in one
+	}
+	tx, err := DBConnection.DBHandle.Begin()
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/RunInTransaction", "0", logging.ResultFailure, []string{"Failed to begin transaction", err.Error()})
+		return err
+	}
+	defer tx.Rollback() //Does nothing once committed, but covers Work panicking
+	if err := Work(&PostgresPlugin{DBHandle: DBConnection.DBHandle, tx: &postgresTx{tx}}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/RunInTransaction", "0", logging.ResultFailure, []string{"Failed to commit transaction", err.Error()})
+		return err
+	}
+	return nil
+}
+
+//PostgresHandle wraps a sql.DB so queries can be written with MariaDB style ? placeholders
+type PostgresHandle struct {
+	*sql.DB
+}
+
+//Exec rebinds the query's placeholders and executes it
+func (Handle *PostgresHandle) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return Handle.DB.Exec(rebind(query), args...)
+}
+
+//Query rebinds the query's placeholders and runs it
+func (Handle *PostgresHandle) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return Handle.DB.Query(rebind(query), args...)
+}
+
+//QueryRow rebinds the query's placeholders and runs it
+func (Handle *PostgresHandle) QueryRow(query string, args ...interface{}) *sql.Row {
+	return Handle.DB.QueryRow(rebind(query), args...)
+}
+
+//postgresTx wraps a sql.Tx so queries inside a transaction can use the same ? placeholders
+type postgresTx struct {
+	*sql.Tx
+}
+
+//Exec rebinds the query's placeholders and executes it
+func (Handle *postgresTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return Handle.Tx.Exec(rebind(query), args...)
+}
+
+//Query rebinds the query's placeholders and runs it
+func (Handle *postgresTx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return Handle.Tx.Query(rebind(query), args...)
+}
+
+//QueryRow rebinds the query's placeholders and runs it
+func (Handle *postgresTx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return Handle.Tx.QueryRow(rebind(query), args...)
+}
+
+//rebind converts ? placeholders into Postgres' numbered $n placeholders, ignoring any inside string literals
+func rebind(query string) string {
+	var builder strings.Builder
+	builder.Grow(len(query) + 16)
+	inLiteral := false
+	placeholder := 0
+	for _, character := range query {
+		switch {
+		case character == '\'':
+			inLiteral = !inLiteral
+			builder.WriteRune(character)
+		case character == '?' && inLiteral == false:
+			placeholder++
+			builder.WriteString("$" + strconv.Itoa(placeholder))
+		default:
+			builder.WriteRune(character)
+		}
+	}
+	return builder.String()
+}
+
+//InitDatabase connects to a database, and if needed, creates and or updates tables
+func (DBConnection *PostgresPlugin) InitDatabase() error {
+	rand.Seed(time.Now().UnixNano())
+	//https://pkg.go.dev/github.com/lib/pq#hdr-Connection_String_Parameters
+	connectionURL := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(config.Configuration.DBUser, config.Configuration.DBPassword),
+		Host:     config.Configuration.DBHost + ":" + config.Configuration.DBPort,
+		Path:     "/" + config.Configuration.DBName,
+		RawQuery: "sslmode=" + url.QueryEscape(config.Configuration.DBSSLMode),
+	}
+	handle, err := sql.Open("postgres", connectionURL.String())
+	DBConnection.DBHandle = &PostgresHandle{handle}
+	if err == nil {
+		err = DBConnection.DBHandle.Ping() //Ping actually validates we can query database
+		if err == nil {
+			version, err := DBConnection.getDatabaseVersion()
+			if err == nil {
+				logging.WriteLog(logging.LogLevelError, "PostgresPlugin/InitDatabase", "0", logging.ResultInfo, []string{"DBVersion is " + strconv.FormatInt(version, 10)})
+				if version < minSupportedDBVersion {
+					return errors.New("database version is not supported and no update code was found to bring database up to current version")
+				} else if version < currentDBVersion {
+					version, err = DBConnection.upgradeDatabase(version)
+					if err != nil {
+						return err
+					}
+				}
+			} else {
+				logging.WriteLog(logging.LogLevelError, "PostgresPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to get database version, assuming not installed. Will attempt to perform install.", err.Error()})
+				//Assume no database installed. Perform fresh install
+				if err := DBConnection.performFreshDBInstall(); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	return err
+}
+
+func (DBConnection *PostgresPlugin) getDatabaseVersion() (int64, error) {
+	var version int64
+	row := DBConnection.DBHandle.QueryRow("SELECT version FROM DBVersion")
+	err := row.Scan(&version)
+	return version, err
+}
+
+//performFreshDBInstall Installs the necessary tables for the application. This assumes that the database has not been created before
+func (DBConnection *PostgresPlugin) performFreshDBInstall() error {
+	//Names use citext so lookups behave like MariaDB's case insensitive collation
+	installQueries := []string{
+		"CREATE EXTENSION IF NOT EXISTS citext;",
+		//DBVersion
+		"CREATE TABLE DBVersion (version BIGINT NOT NULL);",
+		//Images and tags
+		"CREATE TABLE Tags (ID BIGSERIAL PRIMARY KEY, Name CITEXT NOT NULL UNIQUE CHECK (length(Name) <= 255), Description VARCHAR(255), UploaderID BIGINT NOT NULL, UploadTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, AliasedID BIGINT NOT NULL DEFAULT 0, IsAlias BOOL NOT NULL DEFAULT FALSE);",
+		//Users
+		"CREATE TABLE Users (ID BIGSERIAL PRIMARY KEY, Name CITEXT NOT NULL UNIQUE CHECK (length(Name) <= 40), EMail CITEXT NOT NULL UNIQUE CHECK (length(EMail) <= 255), PasswordHash VARCHAR(255) NOT NULL, TokenID VARCHAR(255), IP VARCHAR(50), SecQuestionOne VARCHAR(50), SecQuestionTwo VARCHAR(50), SecQuestionThree VARCHAR(50), SecAnswerOne VARCHAR(255), SecAnswerTwo VARCHAR(255), SecAnswerThree VARCHAR(255), CreationTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, Disabled BOOL NOT NULL DEFAULT FALSE, Permissions BIGINT NOT NULL DEFAULT 0, SearchFilter VARCHAR(255) NOT NULL DEFAULT '');",
+		//Images
+		"CREATE TABLE Images (ID BIGSERIAL PRIMARY KEY, UploaderID BIGINT NOT NULL, Name CITEXT NOT NULL CHECK (length(Name) <= 255), Rating CITEXT DEFAULT 'unrated' CHECK (length(Rating) <= 255), ScoreTotal BIGINT NOT NULL DEFAULT 0, ScoreAverage BIGINT NOT NULL DEFAULT 0, ScoreVoters BIGINT NOT NULL DEFAULT 0, Location VARCHAR(255) UNIQUE NOT NULL, Source VARCHAR(2000) NOT NULL DEFAULT '', UploadTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, Description TEXT NOT NULL DEFAULT '', Width BIGINT NOT NULL DEFAULT 0, Height BIGINT NOT NULL DEFAULT 0, FileSize BIGINT NOT NULL DEFAULT 0, MIMEType VARCHAR(255) NOT NULL DEFAULT '', Duration DOUBLE PRECISION NOT NULL DEFAULT 0, Bitrate BIGINT NOT NULL DEFAULT 0, BlurHash VARCHAR(64) NOT NULL DEFAULT '');",
+		"CREATE INDEX ImagesUploaderID ON Images(UploaderID);",
+		"CREATE INDEX ImagesRating ON Images(Rating);",
+		"CREATE INDEX ImagesUploadTime ON Images(UploadTime);",
+		"CREATE INDEX ImagesScoreAverage ON Images(ScoreAverage);",
+		"CREATE TABLE ImageTags (ID BIGSERIAL PRIMARY KEY, ImageID BIGINT NOT NULL, TagID BIGINT NOT NULL, LinkerID BIGINT NOT NULL, LinkTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, CONSTRAINT ImageTagPair UNIQUE (TagID,ImageID), CONSTRAINT fk_ImageTagsImageID FOREIGN KEY (ImageID) REFERENCES Images(ID), CONSTRAINT fk_ImageTagsTagID FOREIGN KEY (TagID) REFERENCES Tags(ID));",
+		"CREATE INDEX ImageTagsImageID ON ImageTags(ImageID);",
+		"CREATE INDEX ImageTagsLinkerID ON ImageTags(LinkerID);",
+		"CREATE TABLE ImagedHashes (ID BIGSERIAL PRIMARY KEY, ImageID BIGINT NOT NULL UNIQUE, vHash BIGINT NOT NULL, hHash BIGINT NOT NULL, CONSTRAINT fk_ImagedHashesImageID FOREIGN KEY (ImageID) REFERENCES Images(ID));",
+		"CREATE INDEX ImagedHashesvHash ON ImagedHashes(vHash);",
+		"CREATE INDEX ImagedHasheshHash ON ImagedHashes(hHash);",
+		imageMetadataTable,
+		imageMetadataAudioColumns,
+		imageColorsTable,
+		"CREATE INDEX ImageColorsImageID ON ImageColors(ImageID);",
+		"CREATE TABLE ImageUserScores (ID BIGSERIAL PRIMARY KEY, UserID BIGINT NOT NULL, ImageID BIGINT NOT NULL, Score BIGINT NOT NULL, CreationTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, CONSTRAINT ImageUserPair UNIQUE (UserID,ImageID));",
+		//Reserve system for auditing
+		"INSERT INTO Users (ID, Name, EMail, PasswordHash, Disabled) VALUES (0, 'SYSTEM', '', '', TRUE);",
+		//Auditing
+		"CREATE TABLE AuditLogs (ID BIGSERIAL PRIMARY KEY, UserID BIGINT NOT NULL, Type VARCHAR(40), Info VARCHAR(10240) NOT NULL DEFAULT '', LogTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL);",
+		"CREATE INDEX AuditLogsLogTime ON AuditLogs(LogTime);",
+		//Collections
+		"CREATE TABLE Collections (ID BIGSERIAL PRIMARY KEY, Name CITEXT NOT NULL UNIQUE CHECK (length(Name) <= 255), Description VARCHAR(255), UploaderID BIGINT NOT NULL, UploadTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL);",
+		"CREATE TABLE CollectionMembers (ID BIGSERIAL PRIMARY KEY, ImageID BIGINT NOT NULL, CollectionID BIGINT NOT NULL, LinkerID BIGINT NOT NULL, LinkTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, OrderWeight BIGINT NOT NULL, CONSTRAINT ImageCollectionPair UNIQUE (CollectionID,ImageID), CONSTRAINT fk_CollectionMembersImageID FOREIGN KEY (ImageID) REFERENCES Images(ID), CONSTRAINT fk_CollectionMembersCollectionID FOREIGN KEY (CollectionID) REFERENCES Collections(ID));",
+		"CREATE INDEX CollectionMembersImageID ON CollectionMembers(ImageID);",
+		"CREATE TABLE CollectionTags (ID BIGSERIAL PRIMARY KEY, CollectionID BIGINT NOT NULL, TagID BIGINT NOT NULL, LinkerID BIGINT NOT NULL, LinkTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, CONSTRAINT CollectionTagPair UNIQUE (TagID,CollectionID), CONSTRAINT fk_CollectionTagsCollectionID FOREIGN KEY (CollectionID) REFERENCES Collections(ID), CONSTRAINT fk_CollectionTagsTagID FOREIGN KEY (TagID) REFERENCES Tags(ID));",
+		"CREATE INDEX CollectionTagsCollectionID ON CollectionTags(CollectionID);",
+		//Background jobs
+		jobsTable,
+		"CREATE INDEX JobsStatus ON Jobs(Status);",
+		//Functions, Triggers
+		//Postgres' own bit_count only accepts bit strings and bytea, this one counts the set bits of a BIGINT like MariaDB's does
+		`CREATE FUNCTION BIT_COUNT(value BIGINT) RETURNS BIGINT AS $$
+			SELECT CAST(length(replace(CAST(CAST(value AS BIT(64)) AS TEXT), '0', '')) AS BIGINT);
+		$$ LANGUAGE SQL IMMUTABLE STRICT;`,
+		`CREATE FUNCTION LinkCollTags(collID BIGINT) RETURNS BIGINT AS $$
+		DECLARE
+			addedRows BIGINT;
+			removedRows BIGINT;
+		BEGIN
+			-- Insert missing tags
+			INSERT INTO CollectionTags (TagID, CollectionID, LinkerID)
+			SELECT DISTINCT ON (ImageTags.TagID) ImageTags.TagID, collID, ImageTags.LinkerID
+			FROM ImageTags
+			INNER JOIN CollectionMembers on CollectionMembers.ImageID = ImageTags.ImageID
+			LEFT JOIN CollectionTags on CollectionTags.CollectionID = CollectionMembers.CollectionID AND CollectionTags.TagID = ImageTags.TagID
+			WHERE CollectionMembers.CollectionID = collID AND CollectionTags.CollectionID IS NULL
+			ON CONFLICT DO NOTHING;
+			GET DIAGNOSTICS addedRows = ROW_COUNT;
+			-- Remove extra tags
+			DELETE FROM CollectionTags
+			WHERE TagID NOT IN ( SELECT TagID
+									FROM ImageTags
+									INNER JOIN CollectionMembers on CollectionMembers.ImageID = ImageTags.ImageID
+									WHERE CollectionMembers.CollectionID = collID
+								)
+			AND CollectionID=collID;
+			GET DIAGNOSTICS removedRows = ROW_COUNT;
+			RETURN addedRows + removedRows;
+		END;
+		$$ LANGUAGE plpgsql;`,
+		`CREATE FUNCTION AddMissingCollectionImageTags(imgID BIGINT) RETURNS VOID AS $$
+		BEGIN
+			-- Insert missing tags
+			INSERT INTO CollectionTags(TagID, CollectionID, LinkerID)
+			SELECT DISTINCT ON (ImageTags.TagID, CollectionMembers.CollectionID) ImageTags.TagID, CollectionMembers.CollectionID, ImageTags.LinkerID
+			FROM ImageTags
+			INNER JOIN CollectionMembers ON CollectionMembers.ImageID = ImageTags.ImageID
+			LEFT JOIN CollectionTags ON CollectionTags.CollectionID = CollectionMembers.CollectionID AND CollectionTags.TagID = ImageTags.TagID
+			WHERE CollectionTags.CollectionID IS NULL AND ImageTags.ImageID = imgID
+			ON CONFLICT DO NOTHING;
+		END;
+		$$ LANGUAGE plpgsql;`,
+		`CREATE FUNCTION RemSurplusCollectionImageTags(collID BIGINT) RETURNS VOID AS $$
+		BEGIN
+			-- Remove extra tags
+			DELETE FROM CollectionTags
+			WHERE TagID NOT IN ( SELECT TagID
+									FROM ImageTags
+									INNER JOIN CollectionMembers on CollectionMembers.ImageID = ImageTags.ImageID
+									WHERE CollectionMembers.CollectionID = collID
+								)
+			AND CollectionID=collID;
+		END;
+		$$ LANGUAGE plpgsql;`,
+		`CREATE FUNCTION onCollectionDelete() RETURNS TRIGGER AS $$
+		BEGIN
+			DELETE FROM CollectionMembers WHERE CollectionID=OLD.ID;
+			DELETE FROM CollectionTags WHERE CollectionID=OLD.ID;
+			RETURN OLD;
+		END;
+		$$ LANGUAGE plpgsql;`,
+		`CREATE TRIGGER onCollectionDelete BEFORE DELETE ON Collections
+		FOR EACH ROW EXECUTE PROCEDURE onCollectionDelete();`,
+		`CREATE FUNCTION onCollectionMemberAdd() RETURNS TRIGGER AS $$
+		BEGIN
+			PERFORM AddMissingCollectionImageTags(NEW.ImageID);
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;`,
+		`CREATE TRIGGER onCollectionMemberAdd AFTER INSERT ON CollectionMembers
+		FOR EACH ROW EXECUTE PROCEDURE onCollectionMemberAdd();`,
+		`CREATE FUNCTION onCollectionMemberDelete() RETURNS TRIGGER AS $$
+		BEGIN
+			PERFORM RemSurplusCollectionImageTags(OLD.CollectionID);
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;`,
+		`CREATE TRIGGER onCollectionMemberDelete AFTER DELETE ON CollectionMembers
+		FOR EACH ROW EXECUTE PROCEDURE onCollectionMemberDelete();`,
+		`CREATE FUNCTION onImageTagDelete() RETURNS TRIGGER AS $$
+		DECLARE
+			collID BIGINT;
+		BEGIN
+			FOR collID IN SELECT CollectionID FROM CollectionMembers WHERE ImageID = OLD.ImageID LOOP
+				PERFORM RemSurplusCollectionImageTags(collID);
+			END LOOP;
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;`,
+		`CREATE TRIGGER onImageTagDelete AFTER DELETE ON ImageTags
+		FOR EACH ROW EXECUTE PROCEDURE onImageTagDelete();`,
+		imageDeleteFunction,
+		`CREATE TRIGGER onImageDelete BEFORE DELETE ON Images
+		FOR EACH ROW EXECUTE PROCEDURE onImageDelete();`,
+		`CREATE FUNCTION onImageTagInsert() RETURNS TRIGGER AS $$
+		BEGIN
+			PERFORM AddMissingCollectionImageTags(NEW.ImageID);
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;`,
+		`CREATE TRIGGER onImageTagInsert AFTER INSERT ON ImageTags
+		FOR EACH ROW EXECUTE PROCEDURE onImageTagInsert();`,
+		`CREATE FUNCTION onTagDelete() RETURNS TRIGGER AS $$
+		BEGIN
+			DELETE FROM ImageTags WHERE TagID=OLD.ID;
+			DELETE FROM CollectionTags WHERE TagID=OLD.ID;
+			RETURN OLD;
+		END;
+		$$ LANGUAGE plpgsql;`,
+		`CREATE TRIGGER onTagDelete BEFORE DELETE ON Tags
+		FOR EACH ROW EXECUTE PROCEDURE onTagDelete();`,
+	}
+
+	//Postgres supports transactional DDL, so a failure will not leave a half installed database
+	tx, err := DBConnection.DBHandle.Begin()
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/performFreshDBInstall", "0", logging.ResultFailure, []string{"Failed to install database", err.Error()})
+		return err
+	}
+	for _, sqlQuery := range installQueries {
+		if _, err := tx.Exec(sqlQuery); err != nil {
+			tx.Rollback()
+			logging.WriteLog(logging.LogLevelError, "PostgresPlugin/performFreshDBInstall", "0", logging.ResultFailure, []string{"Failed to install database", err.Error()})
+			return err
+		}
+	}
+	if _, err := tx.Exec("INSERT INTO DBVersion (version) VALUES ($1);", currentDBVersion); err != nil {
+		tx.Rollback()
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/performFreshDBInstall", "0", logging.ResultFailure, []string{"Failed to install database", err.Error()})
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/performFreshDBInstall", "0", logging.ResultFailure, []string{"Failed to install database", err.Error()})
+		return err
+	}
+	return nil
+}
+
+//jobsTable is shared by the fresh install and the upgrade to version 2
+const jobsTable = "CREATE TABLE Jobs (ID BIGSERIAL PRIMARY KEY, Type VARCHAR(40) NOT NULL, Payload VARCHAR(2000) NOT NULL DEFAULT '', Status VARCHAR(20) NOT NULL DEFAULT 'queued', Attempts BIGINT NOT NULL DEFAULT 0, MaxAttempts BIGINT NOT NULL DEFAULT 1, Progress BIGINT NOT NULL DEFAULT 0, Total BIGINT NOT NULL DEFAULT 0, LastError VARCHAR(2000) NOT NULL DEFAULT '', CreationTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, UpdateTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, RunAfter TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL);"
+
+//imageMetadataTable is shared by the fresh install and the upgrade to version 3
+const imageMetadataTable = "CREATE TABLE ImageMetadata (ImageID BIGINT NOT NULL PRIMARY KEY, CaptureTime TIMESTAMP NULL, CameraMake VARCHAR(255) NOT NULL DEFAULT '', CameraModel VARCHAR(255) NOT NULL DEFAULT '', LensModel VARCHAR(255) NOT NULL DEFAULT '', ExposureTime VARCHAR(40) NOT NULL DEFAULT '', FNumber VARCHAR(40) NOT NULL DEFAULT '', FocalLength VARCHAR(40) NOT NULL DEFAULT '', ISO BIGINT NOT NULL DEFAULT 0, Orientation BIGINT NOT NULL DEFAULT 0, CONSTRAINT fk_ImageMetadataImageID FOREIGN KEY (ImageID) REFERENCES Images(ID));"
+
+//imageMetadataAudioColumns is added by both the fresh install and the upgrade to version 5, so the upgrade to version 3 still creates the table it always has
+const imageMetadataAudioColumns = "ALTER TABLE ImageMetadata ADD COLUMN Title VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN Artist VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN Album VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN Genre VARCHAR(255) NOT NULL DEFAULT '';"
+
+//imageColorsTable is shared by the fresh install and the upgrade to version 6
+const imageColorsTable = "CREATE TABLE ImageColors (ID BIGSERIAL PRIMARY KEY, ImageID BIGINT NOT NULL, Red BIGINT NOT NULL, Green BIGINT NOT NULL, Blue BIGINT NOT NULL, Share DOUBLE PRECISION NOT NULL, CONSTRAINT fk_ImageColorsImageID FOREIGN KEY (ImageID) REFERENCES Images(ID));"
+
+//imageDeleteFunction is shared by the fresh install and the upgrades to version 3 and 6, which added ImageMetadata and ImageColors to it
+const imageDeleteFunction = `CREATE OR REPLACE FUNCTION onImageDelete() RETURNS TRIGGER AS $$
+		BEGIN
+			DELETE FROM ImageTags WHERE ImageID=OLD.ID;
+			DELETE FROM ImageUserScores WHERE ImageID=OLD.ID;
+			DELETE FROM CollectionMembers WHERE ImageID=OLD.ID;
+			DELETE FROM ImagedHashes WHERE ImageID=OLD.ID;
+			DELETE FROM ImageMetadata WHERE ImageID=OLD.ID;
+			DELETE FROM ImageColors WHERE ImageID=OLD.ID;
+			RETURN OLD;
+		END;
+		$$ LANGUAGE plpgsql;`
+
+//TODO: Add update code here
+func (DBConnection *PostgresPlugin) upgradeDatabase(version int64) (int64, error) {
+	//Update version 1->2
+	if version == 1 {
+		tx, err := DBConnection.DBHandle.Begin()
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "PostgresPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+			return version, err
+		}
+		for _, sqlQuery := range []string{jobsTable, "CREATE INDEX JobsStatus ON Jobs(Status);", "UPDATE DBVersion SET version = 2;"} {
+			if _, err := tx.Exec(sqlQuery); err != nil {
+				tx.Rollback()
+				logging.WriteLog(logging.LogLevelError, "PostgresPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+				return version, err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			logging.WriteLog(logging.LogLevelError, "PostgresPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+			return version, err
+		}
+		version = 2
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/InitDatabase", "0", logging.ResultInfo, []string{"Database schema updated to version", strconv.FormatInt(version, 10)})
+	}
+	//Update version 2->3
+	if version == 2 {
+		tx, err := DBConnection.DBHandle.Begin()
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "PostgresPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+			return version, err
+		}
+		//The trigger calls onImageDelete by name, so replacing the function is enough
+		for _, sqlQuery := range []string{imageMetadataTable, imageDeleteFunction, "UPDATE DBVersion SET version = 3;"} {
+			if _, err := tx.Exec(sqlQuery); err != nil {
+				tx.Rollback()
+				logging.WriteLog(logging.LogLevelError, "PostgresPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+				return version, err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			logging.WriteLog(logging.LogLevelError, "PostgresPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+			return version, err
+		}
+		version = 3
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/InitDatabase", "0", logging.ResultInfo, []string{"Database schema updated to version", strconv.FormatInt(version, 10)})
+	}
+	//Update version 3->4
+	if version == 3 {
+		tx, err := DBConnection.DBHandle.Begin()
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "PostgresPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+			return version, err
+		}
+		//Existing images are left at zero until the media-info job fills them in
+		for _, sqlQuery := range []string{
+			"ALTER TABLE Images ADD COLUMN Width BIGINT NOT NULL DEFAULT 0, ADD COLUMN Height BIGINT NOT NULL DEFAULT 0, ADD COLUMN FileSize BIGINT NOT NULL DEFAULT 0, ADD COLUMN MIMEType VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN Duration DOUBLE PRECISION NOT NULL DEFAULT 0;",
+			"UPDATE DBVersion SET version = 4;",
+		} {
+			if _, err := tx.Exec(sqlQuery); err != nil {
+				tx.Rollback()
+				logging.WriteLog(logging.LogLevelError, "PostgresPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+				return version, err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			logging.WriteLog(logging.LogLevelError, "PostgresPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+			return version, err
+		}
+		version = 4
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/InitDatabase", "0", logging.ResultInfo, []string{"Database schema updated to version", strconv.FormatInt(version, 10)})
+	}
+	//Update version 4->5
+	if version == 4 {
+		tx, err := DBConnection.DBHandle.Begin()
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "PostgresPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+			return version, err
+		}
+		//Existing audio keeps no tags, they are only read when a file is uploaded
+		for _, sqlQuery := range []string{
+			"ALTER TABLE Images ADD COLUMN Bitrate BIGINT NOT NULL DEFAULT 0;",
+			imageMetadataAudioColumns,
+			"UPDATE DBVersion SET version = 5;",
+		} {
+			if _, err := tx.Exec(sqlQuery); err != nil {
+				tx.Rollback()
+				logging.WriteLog(logging.LogLevelError, "PostgresPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+				return version, err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			logging.WriteLog(logging.LogLevelError, "PostgresPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+			return version, err
+		}
+		version = 5
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/InitDatabase", "0", logging.ResultInfo, []string{"Database schema updated to version", strconv.FormatInt(version, 10)})
+	}
+	//Update version 5->6
+	if version == 5 {
+		tx, err := DBConnection.DBHandle.Begin()
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "PostgresPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+			return version, err
+		}
+		//Existing images get their colours when the hash job next runs over them
+		for _, sqlQuery := range []string{
+			"ALTER TABLE Images ADD COLUMN BlurHash VARCHAR(64) NOT NULL DEFAULT '';",
+			imageColorsTable,
+			"CREATE INDEX ImageColorsImageID ON ImageColors(ImageID);",
+			imageDeleteFunction,
+			"UPDATE DBVersion SET version = 6;",
+		} {
+			if _, err := tx.Exec(sqlQuery); err != nil {
+				tx.Rollback()
+				logging.WriteLog(logging.LogLevelError, "PostgresPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+				return version, err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			logging.WriteLog(logging.LogLevelError, "PostgresPlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+			return version, err
+		}
+		version = 6
+		logging.WriteLog(logging.LogLevelError, "PostgresPlugin/InitDatabase", "0", logging.ResultInfo, []string{"Database schema updated to version", strconv.FormatInt(version, 10)})
+	}
+	return version, nil
+}
@@ -0,0 +1,47 @@
+package postgresplugin
+
+import (
+	"go-image-board/config"
+	"go-image-board/database/dbtest"
+	"go-image-board/interfaces"
+	"os"
+	"testing"
+)
+
+//TestConformance runs against the server in GIB_TEST_POSTGRES_HOST, the public schema of GIB_TEST_POSTGRES_NAME is dropped and recreated for every test
+func TestConformance(t *testing.T) {
+	if os.Getenv("GIB_TEST_POSTGRES_HOST") == "" {
+		t.Skip("GIB_TEST_POSTGRES_HOST not set, skipping PostgreSQL conformance tests")
+	}
+	config.Configuration.DBHost = os.Getenv("GIB_TEST_POSTGRES_HOST")
+	config.Configuration.DBPort = testSetting("GIB_TEST_POSTGRES_PORT", "5432")
+	config.Configuration.DBUser = testSetting("GIB_TEST_POSTGRES_USER", "postgres")
+	config.Configuration.DBPassword = os.Getenv("GIB_TEST_POSTGRES_PASSWORD")
+	config.Configuration.DBName = testSetting("GIB_TEST_POSTGRES_NAME", "gib_test")
+	config.Configuration.DBSSLMode = testSetting("GIB_TEST_POSTGRES_SSLMODE", "disable")
+
+	dbtest.RunConformance(t, func(t *testing.T) interfaces.DBInterface {
+		DBConnection := &PostgresPlugin{}
+		//Connect once to clear out the previous test, then again so the plugin performs a fresh install
+		if err := DBConnection.InitDatabase(); err != nil {
+			t.Fatalf("InitDatabase: %v", err)
+		}
+		if _, err := DBConnection.DBHandle.Exec("DROP SCHEMA public CASCADE; CREATE SCHEMA public;"); err != nil {
+			t.Fatalf("Failed to reset test database: %v", err)
+		}
+		DBConnection.DBHandle.Close()
+		if err := DBConnection.InitDatabase(); err != nil {
+			t.Fatalf("InitDatabase: %v", err)
+		}
+		t.Cleanup(func() { DBConnection.DBHandle.Close() })
+		return DBConnection
+	})
+}
+
+//testSetting returns an environment variable, or Default when it is not set
+func testSetting(Name string, Default string) string {
+	if value := os.Getenv(Name); value != "" {
+		return value
+	}
+	return Default
+}
@@ -0,0 +1,165 @@
+package s3storageplugin
+
+import (
+	"context"
+	"go-image-board/config"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"io"
+	"io/fs"
+	"mime"
+	"path"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+//partSize bounds how much of an upload of unknown length is buffered in memory at once
+const partSize = 16 << 20
+
+//S3StoragePlugin keeps files in a bucket of an S3 compatible object store, such as AWS S3 or MinIO
+type S3StoragePlugin struct {
+	Client *minio.Client
+	Bucket string
+}
+
+//Init connects to the object store, and creates the bucket if it does not exist yet
+func (Storage *S3StoragePlugin) Init() error {
+	client, err := minio.New(config.Configuration.S3Endpoint, &minio.Options{
+		Creds:        credentials.NewStaticV4(config.Configuration.S3AccessKeyID, config.Configuration.S3SecretAccessKey, ""),
+		Secure:       config.Configuration.S3UseSSL,
+		Region:       config.Configuration.S3Region,
+		BucketLookup: minio.BucketLookupPath, //Works with MinIO and other self hosted stores without wildcard DNS
+	})
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "S3StoragePlugin/Init", "0", logging.ResultFailure, []string{"Failed to create S3 client", config.Configuration.S3Endpoint, err.Error()})
+		return err
+	}
+	Storage.Client = client
+	Storage.Bucket = config.Configuration.S3Bucket
+
+	exists, err := client.BucketExists(context.Background(), Storage.Bucket)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "S3StoragePlugin/Init", "0", logging.ResultFailure, []string{"Failed to check bucket", Storage.Bucket, err.Error()})
+		return err
+	}
+	if exists == false {
+		if err := client.MakeBucket(context.Background(), Storage.Bucket, minio.MakeBucketOptions{Region: config.Configuration.S3Region}); err != nil {
+			logging.WriteLog(logging.LogLevelError, "S3StoragePlugin/Init", "0", logging.ResultFailure, []string{"Failed to create bucket", Storage.Bucket, err.Error()})
+			return err
+		}
+		logging.WriteLog(logging.LogLevelInfo, "S3StoragePlugin/Init", "0", logging.ResultSuccess, []string{"Created bucket", Storage.Bucket})
+	}
+	return nil
+}
+
+//objectKey converts a storage name into an object key, names are cleaned the same way the local plugin does
+func objectKey(Name string) string {
+	return strings.TrimPrefix(path.Clean("/"+Name), "/")
+}
+
+//translateError converts a missing object into an error matching fs.ErrNotExist
+func translateError(Name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey", "NotFound":
+		return &fs.PathError{Op: "open", Path: Name, Err: fs.ErrNotExist}
+	}
+	return err
+}
+
+//Save stores Content under Name, replacing any file already there
+func (Storage *S3StoragePlugin) Save(Name string, Content io.Reader) error {
+	_, err := Storage.Client.PutObject(context.Background(), Storage.Bucket, objectKey(Name), Content, -1, minio.PutObjectOptions{
+		ContentType: mime.TypeByExtension(path.Ext(Name)),
+		PartSize:    partSize,
+	})
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "S3StoragePlugin/Save", "0", logging.ResultFailure, []string{"Failed to save object", Name, err.Error()})
+	}
+	return err
+}
+
+//Open returns the contents of a file, the caller must close it
+func (Storage *S3StoragePlugin) Open(Name string) (io.ReadSeekCloser, error) {
+	object, err := Storage.Client.GetObject(context.Background(), Storage.Bucket, objectKey(Name), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, translateError(Name, err)
+	}
+	//GetObject does not contact the store until first use, so stat to find missing objects now
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		return nil, translateError(Name, err)
+	}
+	return object, nil
+}
+
+//Stat returns information about a file
+func (Storage *S3StoragePlugin) Stat(Name string) (interfaces.StorageFileInfo, error) {
+	objectInfo, err := Storage.Client.StatObject(context.Background(), Storage.Bucket, objectKey(Name), minio.StatObjectOptions{})
+	if err != nil {
+		return interfaces.StorageFileInfo{}, translateError(Name, err)
+	}
+	return interfaces.StorageFileInfo{Name: Name, Size: objectInfo.Size, ModTime: objectInfo.LastModified}, nil
+}
+
+//Remove deletes a file
+func (Storage *S3StoragePlugin) Remove(Name string) error {
+	//Deleting a missing object succeeds in S3, so check first to match the local plugin
+	if _, err := Storage.Stat(Name); err != nil {
+		return err
+	}
+	return translateError(Name, Storage.Client.RemoveObject(context.Background(), Storage.Bucket, objectKey(Name), minio.RemoveObjectOptions{}))
+}
+
+//Rename moves a file to a new name, replacing any file already there
+//Object stores cannot rename, so this copies then deletes the original
+func (Storage *S3StoragePlugin) Rename(OldName string, NewName string) error {
+	_, err := Storage.Client.CopyObject(context.Background(),
+		minio.CopyDestOptions{Bucket: Storage.Bucket, Object: objectKey(NewName)},
+		minio.CopySrcOptions{Bucket: Storage.Bucket, Object: objectKey(OldName)})
+	if err != nil {
+		return translateError(OldName, err)
+	}
+	if err := Storage.Client.RemoveObject(context.Background(), Storage.Bucket, objectKey(OldName), minio.RemoveObjectOptions{}); err != nil {
+		logging.WriteLog(logging.LogLevelError, "S3StoragePlugin/Rename", "0", logging.ResultFailure, []string{"Copied object but failed to remove original", OldName, NewName, err.Error()})
+		return err
+	}
+	return nil
+}
+
+//List returns the names, including Directory, of files directly inside Directory, use "" for the top level
+func (Storage *S3StoragePlugin) List(Directory string) ([]string, error) {
+	return Storage.listKeys(Directory, false)
+}
+
+//ListDirectories returns the names, including Directory, of directories directly inside Directory, use "" for the top level
+//Object stores have no real directories, so these are the prefixes shared by objects
+func (Storage *S3StoragePlugin) ListDirectories(Directory string) ([]string, error) {
+	return Storage.listKeys(Directory, true)
+}
+
+//listKeys lists the objects directly under Directory, or the sub folders when Directories is true
+func (Storage *S3StoragePlugin) listKeys(Directory string, Directories bool) ([]string, error) {
+	prefix := objectKey(Directory)
+	if prefix != "" {
+		prefix += "/"
+	}
+	//Cancelling stops the listing if we return early
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var ToReturn []string
+	for objectInfo := range Storage.Client.ListObjects(ctx, Storage.Bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: false}) {
+		if objectInfo.Err != nil {
+			return nil, objectInfo.Err
+		}
+		//Non-recursive listings return sub folders as keys ending in /
+		if strings.HasSuffix(objectInfo.Key, "/") == Directories {
+			ToReturn = append(ToReturn, strings.TrimSuffix(objectInfo.Key, "/"))
+		}
+	}
+	return ToReturn, nil
+}
@@ -0,0 +1,50 @@
+package s3storageplugin
+
+import (
+	"context"
+	"go-image-board/config"
+	"go-image-board/interfaces"
+	"go-image-board/storage/storagetest"
+	"os"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+)
+
+//TestConformance runs against the object store in GIB_TEST_S3_ENDPOINT, such as a local MinIO
+//The bucket in GIB_TEST_S3_BUCKET is emptied before every test
+func TestConformance(t *testing.T) {
+	if os.Getenv("GIB_TEST_S3_ENDPOINT") == "" {
+		t.Skip("GIB_TEST_S3_ENDPOINT not set, skipping S3 conformance tests")
+	}
+	config.Configuration.S3Endpoint = os.Getenv("GIB_TEST_S3_ENDPOINT")
+	config.Configuration.S3Bucket = testSetting("GIB_TEST_S3_BUCKET", "gib-test")
+	config.Configuration.S3Region = os.Getenv("GIB_TEST_S3_REGION")
+	config.Configuration.S3AccessKeyID = testSetting("GIB_TEST_S3_ACCESS_KEY_ID", "minioadmin")
+	config.Configuration.S3SecretAccessKey = testSetting("GIB_TEST_S3_SECRET_ACCESS_KEY", "minioadmin")
+	config.Configuration.S3UseSSL = os.Getenv("GIB_TEST_S3_USE_SSL") == "true"
+
+	storagetest.RunConformance(t, func(t *testing.T) interfaces.StorageInterface {
+		Storage := &S3StoragePlugin{}
+		if err := Storage.Init(); err != nil {
+			t.Fatalf("Init: %v", err)
+		}
+		for objectInfo := range Storage.Client.ListObjects(context.Background(), Storage.Bucket, minio.ListObjectsOptions{Recursive: true}) {
+			if objectInfo.Err != nil {
+				t.Fatalf("Failed to list test bucket: %v", objectInfo.Err)
+			}
+			if err := Storage.Client.RemoveObject(context.Background(), Storage.Bucket, objectInfo.Key, minio.RemoveObjectOptions{}); err != nil {
+				t.Fatalf("Failed to empty test bucket: %v", err)
+			}
+		}
+		return Storage
+	})
+}
+
+//testSetting returns an environment variable, or Default when it is not set
+func testSetting(Name string, Default string) string {
+	if value := os.Getenv(Name); value != "" {
+		return value
+	}
+	return Default
+}
@@ -0,0 +1,255 @@
+package sqliteplugin
+
+import (
+	"database/sql"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"strconv"
+	"time"
+)
+
+//Backup operations
+
+//backupTime formats a time from a backup the same way CURRENT_TIMESTAMP stores it, so restored rows sort with new ones
+func backupTime(Time time.Time) string {
+	if Time.IsZero() {
+		Time = time.Now()
+	}
+	return Time.UTC().Format("2006-01-02 15:04:05")
+}
+
+//GetUserBackups returns everything stored for users other than the system user, ordered by ID (Returns a list of users, the count of all users, and or error)
+func (DBConnection *SQLitePlugin) GetUserBackups(PageStart uint64, PageStride uint64) ([]interfaces.UserBackup, uint64, error) {
+	var MaxResults uint64
+	if err := DBConnection.handle().QueryRow("SELECT COUNT(*) FROM Users WHERE ID <> 0").Scan(&MaxResults); err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/GetUserBackups", "0", logging.ResultFailure, []string{"Failed to count users", err.Error()})
+		return nil, 0, err
+	}
+	queryArray := []interface{}{}
+	sqlQuery := "SELECT ID, Name, EMail, CreationTime, Disabled, Permissions, SearchFilter, PasswordHash, IFNULL(SecQuestionOne,''), IFNULL(SecQuestionTwo,''), IFNULL(SecQuestionThree,''), IFNULL(SecAnswerOne,''), IFNULL(SecAnswerTwo,''), IFNULL(SecAnswerThree,'') FROM Users WHERE ID <> 0 ORDER BY ID"
+	if PageStride > 0 {
+		sqlQuery += " LIMIT ? OFFSET ?;"
+		queryArray = append(queryArray, PageStride, PageStart)
+	}
+	rows, err := DBConnection.handle().Query(sqlQuery, queryArray...)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/GetUserBackups", "0", logging.ResultFailure, []string{"Failed to query users", err.Error()})
+		return nil, 0, err
+	}
+	defer rows.Close()
+	var ToReturn []interfaces.UserBackup
+	for rows.Next() {
+		var user interfaces.UserBackup
+		var CreationTime sql.NullTime
+		if err := rows.Scan(&user.ID, &user.Name, &user.EMail, &CreationTime, &user.Disabled, &user.Permissions, &user.SearchFilter, &user.PasswordHash, &user.SecQuestionOne, &user.SecQuestionTwo, &user.SecQuestionThree, &user.SecAnswerOne, &user.SecAnswerTwo, &user.SecAnswerThree); err != nil {
+			return nil, 0, err
+		}
+		if CreationTime.Valid {
+			user.CreationTime = CreationTime.Time
+		}
+		ToReturn = append(ToReturn, user)
+	}
+	return ToReturn, MaxResults, rows.Err()
+}
+
+//GetImageVotes returns every user's vote on an image
+func (DBConnection *SQLitePlugin) GetImageVotes(ImageID uint64) ([]interfaces.ImageVote, error) {
+	rows, err := DBConnection.handle().Query("SELECT UserID, Score, CreationTime FROM ImageUserScores WHERE ImageID=? ORDER BY UserID;", ImageID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/GetImageVotes", "0", logging.ResultFailure, []string{"Failed to query votes", strconv.FormatUint(ImageID, 10), err.Error()})
+		return nil, err
+	}
+	defer rows.Close()
+	var ToReturn []interfaces.ImageVote
+	for rows.Next() {
+		vote := interfaces.ImageVote{ImageID: ImageID}
+		var CreationTime sql.NullTime
+		if err := rows.Scan(&vote.UserID, &vote.Score, &CreationTime); err != nil {
+			return nil, err
+		}
+		if CreationTime.Valid {
+			vote.CreationTime = CreationTime.Time
+		}
+		ToReturn = append(ToReturn, vote)
+	}
+	return ToReturn, rows.Err()
+}
+
+//GetAuditLogs returns audit log entries ordered by ID (Returns a list of entries, the count of all entries, and or error)
+func (DBConnection *SQLitePlugin) GetAuditLogs(PageStart uint64, PageStride uint64) ([]interfaces.AuditLogInformation, uint64, error) {
+	var MaxResults uint64
+	if err := DBConnection.handle().QueryRow("SELECT COUNT(*) FROM AuditLogs").Scan(&MaxResults); err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/GetAuditLogs", "0", logging.ResultFailure, []string{"Failed to count audit logs", err.Error()})
+		return nil, 0, err
+	}
+	queryArray := []interface{}{}
+	sqlQuery := "SELECT ID, UserID, IFNULL(Type,''), Info, LogTime FROM AuditLogs ORDER BY ID"
+	if PageStride > 0 {
+		sqlQuery += " LIMIT ? OFFSET ?;"
+		queryArray = append(queryArray, PageStride, PageStart)
+	}
+	rows, err := DBConnection.handle().Query(sqlQuery, queryArray...)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/GetAuditLogs", "0", logging.ResultFailure, []string{"Failed to query audit logs", err.Error()})
+		return nil, 0, err
+	}
+	defer rows.Close()
+	var ToReturn []interfaces.AuditLogInformation
+	for rows.Next() {
+		var auditLog interfaces.AuditLogInformation
+		var LogTime sql.NullTime
+		if err := rows.Scan(&auditLog.ID, &auditLog.UserID, &auditLog.Type, &auditLog.Info, &LogTime); err != nil {
+			return nil, 0, err
+		}
+		if LogTime.Valid {
+			auditLog.LogTime = LogTime.Time
+		}
+		ToReturn = append(ToReturn, auditLog)
+	}
+	return ToReturn, MaxResults, rows.Err()
+}
+
+//GetImageTagLinks returns the tags applied to an image, with who applied them and when, ordered by TagID
+func (DBConnection *SQLitePlugin) GetImageTagLinks(ImageID uint64) ([]interfaces.ImageTagLink, error) {
+	rows, err := DBConnection.handle().Query("SELECT TagID, LinkerID, LinkTime FROM ImageTags WHERE ImageID=? ORDER BY TagID;", ImageID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/GetImageTagLinks", "0", logging.ResultFailure, []string{"Failed to query image tags", strconv.FormatUint(ImageID, 10), err.Error()})
+		return nil, err
+	}
+	defer rows.Close()
+	var ToReturn []interfaces.ImageTagLink
+	for rows.Next() {
+		link := interfaces.ImageTagLink{ImageID: ImageID}
+		var LinkTime sql.NullTime
+		if err := rows.Scan(&link.TagID, &link.LinkerID, &LinkTime); err != nil {
+			return nil, err
+		}
+		if LinkTime.Valid {
+			link.LinkTime = LinkTime.Time
+		}
+		ToReturn = append(ToReturn, link)
+	}
+	return ToReturn, rows.Err()
+}
+
+//GetCollectionMemberLinks returns the images in a collection, with who added them and when, ordered by OrderWeight
+func (DBConnection *SQLitePlugin) GetCollectionMemberLinks(CollectionID uint64) ([]interfaces.CollectionMemberLink, error) {
+	rows, err := DBConnection.handle().Query("SELECT ImageID, LinkerID, LinkTime, OrderWeight FROM CollectionMembers WHERE CollectionID=? ORDER BY OrderWeight, ImageID;", CollectionID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/GetCollectionMemberLinks", "0", logging.ResultFailure, []string{"Failed to query collection members", strconv.FormatUint(CollectionID, 10), err.Error()})
+		return nil, err
+	}
+	defer rows.Close()
+	var ToReturn []interfaces.CollectionMemberLink
+	for rows.Next() {
+		link := interfaces.CollectionMemberLink{CollectionID: CollectionID}
+		var LinkTime sql.NullTime
+		if err := rows.Scan(&link.ImageID, &link.LinkerID, &LinkTime, &link.OrderWeight); err != nil {
+			return nil, err
+		}
+		if LinkTime.Valid {
+			link.LinkTime = LinkTime.Time
+		}
+		ToReturn = append(ToReturn, link)
+	}
+	return ToReturn, rows.Err()
+}
+
+//RestoreUser adds a user from a backup, keeping their ID, creation time, and hashes as they are
+func (DBConnection *SQLitePlugin) RestoreUser(User interfaces.UserBackup) error {
+	_, err := DBConnection.handle().Exec("INSERT INTO Users (ID, Name, EMail, PasswordHash, SecQuestionOne, SecQuestionTwo, SecQuestionThree, SecAnswerOne, SecAnswerTwo, SecAnswerThree, CreationTime, Disabled, Permissions, SearchFilter) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);",
+		User.ID, User.Name, User.EMail, User.PasswordHash, User.SecQuestionOne, User.SecQuestionTwo, User.SecQuestionThree, User.SecAnswerOne, User.SecAnswerTwo, User.SecAnswerThree, backupTime(User.CreationTime), User.Disabled, User.Permissions, User.SearchFilter)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/RestoreUser", strconv.FormatUint(User.ID, 10), logging.ResultFailure, []string{"Failed to restore user", User.Name, err.Error()})
+	}
+	return err
+}
+
+//RestoreImage adds an image from a backup, keeping its ID, uploader, upload time, name, description, rating, source, location, media info, and blurhash
+func (DBConnection *SQLitePlugin) RestoreImage(Image interfaces.ImageInformation) error {
+	if Image.Rating == "" {
+		Image.Rating = "unrated"
+	}
+	_, err := DBConnection.handle().Exec("INSERT INTO Images (ID, UploaderID, Name, Description, Rating, Location, Source, UploadTime, Width, Height, FileSize, MIMEType, Duration, Bitrate, BlurHash) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);",
+		Image.ID, Image.UploaderID, Image.Name, Image.Description, Image.Rating, Image.Location, Image.Source, backupTime(Image.UploadTime), Image.Width, Image.Height, Image.FileSize, Image.MIMEType, Image.Duration, Image.Bitrate, Image.BlurHash)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/RestoreImage", strconv.FormatUint(Image.UploaderID, 10), logging.ResultFailure, []string{"Failed to restore image", strconv.FormatUint(Image.ID, 10), err.Error()})
+	}
+	return err
+}
+
+//RestoreTag adds a tag from a backup, keeping its ID, uploader, upload time, and alias as they are
+func (DBConnection *SQLitePlugin) RestoreTag(Tag interfaces.TagInformation) error {
+	_, err := DBConnection.handle().Exec("INSERT INTO Tags (ID, Name, Description, UploaderID, UploadTime, AliasedID, IsAlias) VALUES (?, ?, ?, ?, ?, ?, ?);",
+		Tag.ID, Tag.Name, Tag.Description, Tag.UploaderID, backupTime(Tag.UploadTime), Tag.AliasedID, Tag.IsAlias)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/RestoreTag", strconv.FormatUint(Tag.UploaderID, 10), logging.ResultFailure, []string{"Failed to restore tag", Tag.Name, err.Error()})
+	}
+	return err
+}
+
+//RestoreCollection adds a collection from a backup, keeping its ID, uploader, and upload time
+func (DBConnection *SQLitePlugin) RestoreCollection(Collection interfaces.CollectionInformation) error {
+	_, err := DBConnection.handle().Exec("INSERT INTO Collections (ID, Name, Description, UploaderID, UploadTime) VALUES (?, ?, ?, ?, ?);",
+		Collection.ID, Collection.Name, Collection.Description, Collection.UploaderID, backupTime(Collection.UploadTime))
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/RestoreCollection", strconv.FormatUint(Collection.UploaderID, 10), logging.ResultFailure, []string{"Failed to restore collection", Collection.Name, err.Error()})
+	}
+	return err
+}
+
+//RestoreAuditLog adds an audit log entry from a backup, keeping its time
+func (DBConnection *SQLitePlugin) RestoreAuditLog(Log interfaces.AuditLogInformation) error {
+	_, err := DBConnection.handle().Exec("INSERT INTO AuditLogs (UserID, Type, Info, LogTime) VALUES (?, ?, ?, ?);", Log.UserID, Log.Type, Log.Info, backupTime(Log.LogTime))
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/RestoreAuditLog", strconv.FormatUint(Log.UserID, 10), logging.ResultFailure, []string{"Failed to restore audit log", err.Error()})
+	}
+	return err
+}
+
+//RestoreImageTags applies tags from a backup, keeping their linker, time, and tag IDs even if they are now aliases
+func (DBConnection *SQLitePlugin) RestoreImageTags(Links []interfaces.ImageTagLink) error {
+	if len(Links) == 0 {
+		return nil
+	}
+	values := ""
+	queryArray := []interface{}{}
+	for _, link := range Links {
+		values += " (?, ?, ?, ?),"
+		queryArray = append(queryArray, link.TagID, link.ImageID, link.LinkerID, backupTime(link.LinkTime))
+	}
+	sqlQuery := "INSERT INTO ImageTags (TagID, ImageID, LinkerID, LinkTime) VALUES" + values[:len(values)-1] + ";"
+	if _, err := DBConnection.handle().Exec(sqlQuery, queryArray...); err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/RestoreImageTags", "0", logging.ResultFailure, []string{"Failed to restore image tags", strconv.FormatUint(Links[0].ImageID, 10), err.Error()})
+		return err
+	}
+	return nil
+}
+
+//RestoreCollectionMembers adds images to collections from a backup, keeping their linker, time, and OrderWeight
+func (DBConnection *SQLitePlugin) RestoreCollectionMembers(Links []interfaces.CollectionMemberLink) error {
+	if len(Links) == 0 {
+		return nil
+	}
+	values := ""
+	queryArray := []interface{}{}
+	for _, link := range Links {
+		values += " (?, ?, ?, ?, ?),"
+		queryArray = append(queryArray, link.CollectionID, link.ImageID, link.LinkerID, backupTime(link.LinkTime), link.OrderWeight)
+	}
+	sqlQuery := "INSERT INTO CollectionMembers (CollectionID, ImageID, LinkerID, LinkTime, OrderWeight) VALUES" + values[:len(values)-1] + ";"
+	if _, err := DBConnection.handle().Exec(sqlQuery, queryArray...); err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/RestoreCollectionMembers", "0", logging.ResultFailure, []string{"Failed to restore collection members", strconv.FormatUint(Links[0].CollectionID, 10), err.Error()})
+		return err
+	}
+	return nil
+}
+
+//RestoreImageVote adds a vote from a backup, keeping its time. The image's score is not updated, call UpdateScoreOnImage after
+func (DBConnection *SQLitePlugin) RestoreImageVote(Vote interfaces.ImageVote) error {
+	_, err := DBConnection.handle().Exec("INSERT INTO ImageUserScores (UserID, ImageID, Score, CreationTime) VALUES (?, ?, ?, ?);", Vote.UserID, Vote.ImageID, Vote.Score, backupTime(Vote.CreationTime))
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/RestoreImageVote", strconv.FormatUint(Vote.UserID, 10), logging.ResultFailure, []string{"Failed to restore vote", strconv.FormatUint(Vote.ImageID, 10), err.Error()})
+	}
+	return err
+}
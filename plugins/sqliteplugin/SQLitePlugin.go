@@ -0,0 +1,416 @@
+package sqliteplugin
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"go-image-board/config"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"math/bits"
+	"strconv"
+
+	"math/rand"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+//TODO: Increment this whenever we alter the DB Schema, ensure you attempt to add update code below
+var currentDBVersion int64 = 6
+
+//TODO: Increment this when we alter the db schema and don't add update code to compensate
+var minSupportedDBVersion int64 // 0 by default
+
+//SQLitePlugin acts as plugin between gib and a SQLite database file
+type SQLitePlugin struct {
+	DBHandle *sql.DB
+	//tx is set on the copy handed to RunInTransaction's Work, so its queries run inside the transaction
+	tx *sql.Tx
+}
+
+//sqlHandle is satisfied by both sql.DB and sql.Tx, so the same queries run inside and outside a transaction
+type sqlHandle interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+//handle returns the transaction when there is one, or the database otherwise
+func (DBConnection *SQLitePlugin) handle() sqlHandle {
+	if DBConnection.tx != nil {
+		return DBConnection.tx
+	}
+	return DBConnection.DBHandle
+}
+
+//background runs Work in a goroutine, or straight away inside a transaction as it cannot be used once committed
+func (DBConnection *SQLitePlugin) background(Work func()) {
+	if DBConnection.tx != nil {
+		Work()
+		return
+	}
+	go Work()
+}
+
+//RunInTransaction runs Work against a copy of the plugin bound to one transaction, committing if Work returns nil and rolling back otherwise
+func (DBConnection *SQLitePlugin) RunInTransaction(Work func(Transaction interfaces.DBInterface) error) error {
+	if DBConnection.tx != nil {
+		return Work(DBConnection) //Already in one
+	}
+	tx, err := DBConnection.DBHandle.Begin()
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/RunInTransaction", "0", logging.ResultFailure, []string{"Failed to begin transaction", err.Error()})
+		return err
+	}
+	defer tx.Rollback() //Does nothing once committed, but covers Work panicking
+	if err := Work(&SQLitePlugin{DBHandle: DBConnection.DBHandle, tx: tx}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/RunInTransaction", "0", logging.ResultFailure, []string{"Failed to commit transaction", err.Error()})
+		return err
+	}
+	return nil
+}
+
+func init() {
+	//SQLite has neither BIT_COUNT nor a bitwise xor operator, both are needed for similarity searches
+	sqlite.MustRegisterDeterministicScalarFunction("BIT_COUNT", 1, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		value, isInt := args[0].(int64)
+		if isInt == false {
+			return nil, nil
+		}
+		return int64(bits.OnesCount64(uint64(value))), nil
+	})
+	sqlite.MustRegisterDeterministicScalarFunction("BIT_XOR", 2, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		left, isInt := args[0].(int64)
+		if isInt == false {
+			return nil, nil
+		}
+		right, isInt := args[1].(int64)
+		if isInt == false {
+			return nil, nil
+		}
+		return left ^ right, nil
+	})
+}
+
+//InitDatabase connects to a database, and if needed, creates and or updates tables
+func (DBConnection *SQLitePlugin) InitDatabase() error {
+	rand.Seed(time.Now().UnixNano())
+	var err error
+	//https://pkg.go.dev/modernc.org/sqlite#Driver.Open
+	DBConnection.DBHandle, err = sql.Open("sqlite", "file:"+config.Configuration.DBPath+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_time_format=sqlite&_txlock=immediate")
+	if err == nil {
+		err = DBConnection.DBHandle.Ping() //Ping actually validates we can query database
+		if err == nil {
+			version, err := DBConnection.getDatabaseVersion()
+			if err == nil {
+				logging.WriteLog(logging.LogLevelError, "SQLitePlugin/InitDatabase", "0", logging.ResultInfo, []string{"DBVersion is " + strconv.FormatInt(version, 10)})
+				if version < minSupportedDBVersion {
+					return errors.New("database version is not supported and no update code was found to bring database up to current version")
+				} else if version < currentDBVersion {
+					version, err = DBConnection.upgradeDatabase(version)
+					if err != nil {
+						return err
+					}
+				}
+			} else {
+				logging.WriteLog(logging.LogLevelError, "SQLitePlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to get database version, assuming not installed. Will attempt to perform install.", err.Error()})
+				//Assume no database installed. Perform fresh install
+				if err := DBConnection.performFreshDBInstall(); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	return err
+}
+
+func (DBConnection *SQLitePlugin) getDatabaseVersion() (int64, error) {
+	var version int64
+	row := DBConnection.DBHandle.QueryRow("SELECT version FROM DBVersion")
+	err := row.Scan(&version)
+	return version, err
+}
+
+//performFreshDBInstall Installs the necessary tables for the application. This assumes that the database has not been created before
+func (DBConnection *SQLitePlugin) performFreshDBInstall() error {
+	//Text columns that MariaDB would compare case insensitively use NOCASE here, so lookups by name behave the same
+	installQueries := []string{
+		//DBVersion
+		"CREATE TABLE DBVersion (version BIGINT NOT NULL);",
+		//Images and tags
+		"CREATE TABLE Tags (ID INTEGER PRIMARY KEY AUTOINCREMENT, Name VARCHAR(255) NOT NULL UNIQUE COLLATE NOCASE, Description VARCHAR(255), UploaderID BIGINT NOT NULL, UploadTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, AliasedID BIGINT NOT NULL DEFAULT 0, IsAlias BOOL NOT NULL DEFAULT FALSE);",
+		//Users
+		"CREATE TABLE Users (ID INTEGER PRIMARY KEY AUTOINCREMENT, Name VARCHAR(40) NOT NULL UNIQUE COLLATE NOCASE, EMail VARCHAR(255) NOT NULL UNIQUE COLLATE NOCASE, PasswordHash VARCHAR(255) NOT NULL, TokenID VARCHAR(255), IP VARCHAR(50), SecQuestionOne VARCHAR(50), SecQuestionTwo VARCHAR(50), SecQuestionThree VARCHAR(50), SecAnswerOne VARCHAR(255), SecAnswerTwo VARCHAR(255), SecAnswerThree VARCHAR(255), CreationTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, Disabled BOOL NOT NULL DEFAULT FALSE, Permissions BIGINT NOT NULL DEFAULT 0, SearchFilter VARCHAR(255) NOT NULL DEFAULT '');",
+		//Images
+		"CREATE TABLE Images (ID INTEGER PRIMARY KEY AUTOINCREMENT, UploaderID BIGINT NOT NULL, Name VARCHAR(255) NOT NULL, Rating VARCHAR(255) DEFAULT 'unrated' COLLATE NOCASE, ScoreTotal BIGINT NOT NULL DEFAULT 0, ScoreAverage BIGINT NOT NULL DEFAULT 0, ScoreVoters BIGINT NOT NULL DEFAULT 0, Location VARCHAR(255) UNIQUE NOT NULL, Source VARCHAR(2000) NOT NULL DEFAULT '', UploadTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, Description TEXT NOT NULL DEFAULT '', Width BIGINT NOT NULL DEFAULT 0, Height BIGINT NOT NULL DEFAULT 0, FileSize BIGINT NOT NULL DEFAULT 0, MIMEType VARCHAR(255) NOT NULL DEFAULT '', Duration REAL NOT NULL DEFAULT 0, Bitrate BIGINT NOT NULL DEFAULT 0, BlurHash VARCHAR(64) NOT NULL DEFAULT '');",
+		"CREATE INDEX ImagesUploaderID ON Images(UploaderID);",
+		"CREATE INDEX ImagesRating ON Images(Rating);",
+		"CREATE INDEX ImagesUploadTime ON Images(UploadTime);",
+		"CREATE INDEX ImagesScoreAverage ON Images(ScoreAverage);",
+		"CREATE TABLE ImageTags (ID INTEGER PRIMARY KEY AUTOINCREMENT, ImageID BIGINT NOT NULL REFERENCES Images(ID), TagID BIGINT NOT NULL REFERENCES Tags(ID), LinkerID BIGINT NOT NULL, LinkTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, CONSTRAINT ImageTagPair UNIQUE (TagID,ImageID));",
+		"CREATE INDEX ImageTagsImageID ON ImageTags(ImageID);",
+		"CREATE INDEX ImageTagsLinkerID ON ImageTags(LinkerID);",
+		"CREATE TABLE ImagedHashes (ID INTEGER PRIMARY KEY AUTOINCREMENT, ImageID BIGINT NOT NULL UNIQUE REFERENCES Images(ID), vHash BIGINT NOT NULL, hHash BIGINT NOT NULL);",
+		"CREATE INDEX ImagedHashesvHash ON ImagedHashes(vHash);",
+		"CREATE INDEX ImagedHasheshHash ON ImagedHashes(hHash);",
+		imageMetadataTable,
+		imageColorsTable,
+		"CREATE INDEX ImageColorsImageID ON ImageColors(ImageID);",
+		"CREATE TABLE ImageUserScores (ID INTEGER PRIMARY KEY AUTOINCREMENT, UserID BIGINT NOT NULL, ImageID BIGINT NOT NULL, Score BIGINT NOT NULL, CreationTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, CONSTRAINT ImageUserPair UNIQUE (UserID,ImageID));",
+		//Reserve system for auditing
+		"INSERT INTO Users (ID, Name, EMail, PasswordHash, Disabled) VALUES (0, 'SYSTEM', '', '', true);",
+		//Auditing
+		"CREATE TABLE AuditLogs (ID INTEGER PRIMARY KEY AUTOINCREMENT, UserID BIGINT NOT NULL, Type VARCHAR(40), Info VARCHAR(10240) NOT NULL DEFAULT '', LogTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL);",
+		"CREATE INDEX AuditLogsLogTime ON AuditLogs(LogTime);",
+		//Collections
+		"CREATE TABLE Collections (ID INTEGER PRIMARY KEY AUTOINCREMENT, Name VARCHAR(255) NOT NULL UNIQUE COLLATE NOCASE, Description VARCHAR(255), UploaderID BIGINT NOT NULL, UploadTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL);",
+		"CREATE TABLE CollectionMembers (ID INTEGER PRIMARY KEY AUTOINCREMENT, ImageID BIGINT NOT NULL REFERENCES Images(ID), CollectionID BIGINT NOT NULL REFERENCES Collections(ID), LinkerID BIGINT NOT NULL, LinkTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, OrderWeight BIGINT NOT NULL, CONSTRAINT ImageCollectionPair UNIQUE (CollectionID,ImageID));",
+		"CREATE INDEX CollectionMembersImageID ON CollectionMembers(ImageID);",
+		"CREATE TABLE CollectionTags (ID INTEGER PRIMARY KEY AUTOINCREMENT, CollectionID BIGINT NOT NULL REFERENCES Collections(ID), TagID BIGINT NOT NULL REFERENCES Tags(ID), LinkerID BIGINT NOT NULL, LinkTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, CONSTRAINT CollectionTagPair UNIQUE (TagID,CollectionID));",
+		"CREATE INDEX CollectionTagsCollectionID ON CollectionTags(CollectionID);",
+		//Background jobs
+		jobsTable,
+		"CREATE INDEX JobsStatus ON Jobs(Status);",
+		//Triggers, SQLite has no stored procedures so AddMissingCollectionImageTags and RemSurplusCollectionImageTags are inlined into each trigger
+		`CREATE TRIGGER onCollectionDelete BEFORE DELETE ON Collections
+		FOR EACH ROW BEGIN
+			DELETE FROM CollectionMembers WHERE CollectionID=OLD.ID;
+			DELETE FROM CollectionTags WHERE CollectionID=OLD.ID;
+		END`,
+		`CREATE TRIGGER onCollectionMemberAdd AFTER INSERT ON CollectionMembers
+		FOR EACH ROW BEGIN
+			-- AddMissingCollectionImageTags(NEW.ImageID)
+			INSERT OR IGNORE INTO CollectionTags(TagID, CollectionID, LinkerID)
+			SELECT DISTINCT ImageTags.TagID, CollectionMembers.CollectionID, ImageTags.LinkerID
+			FROM ImageTags
+			INNER JOIN CollectionMembers ON CollectionMembers.ImageID = ImageTags.ImageID
+			LEFT JOIN CollectionTags ON CollectionTags.CollectionID = CollectionMembers.CollectionID AND CollectionTags.TagID = ImageTags.TagID
+			WHERE CollectionTags.CollectionID IS NULL AND ImageTags.ImageID = NEW.ImageID;
+		END`,
+		`CREATE TRIGGER onCollectionMemberDelete AFTER DELETE ON CollectionMembers
+		FOR EACH ROW BEGIN
+			-- RemSurplusCollectionImageTags(OLD.CollectionID)
+			DELETE FROM CollectionTags
+			WHERE TagID NOT IN ( SELECT TagID
+									FROM ImageTags
+									INNER JOIN CollectionMembers on CollectionMembers.ImageID = ImageTags.ImageID
+									WHERE CollectionMembers.CollectionID = OLD.CollectionID
+								)
+			AND CollectionID=OLD.CollectionID;
+		END`,
+		`CREATE TRIGGER onImageTagDelete AFTER DELETE ON ImageTags
+		FOR EACH ROW BEGIN
+			-- RemSurplusCollectionImageTags for every collection containing OLD.ImageID
+			DELETE FROM CollectionTags
+			WHERE CollectionID IN (SELECT CollectionID FROM CollectionMembers WHERE ImageID = OLD.ImageID)
+			AND TagID NOT IN ( SELECT TagID
+									FROM ImageTags
+									INNER JOIN CollectionMembers on CollectionMembers.ImageID = ImageTags.ImageID
+									WHERE CollectionMembers.CollectionID = CollectionTags.CollectionID
+								);
+		END`,
+		imageDeleteTrigger,
+		`CREATE TRIGGER onImageTagInsert AFTER INSERT ON ImageTags
+		FOR EACH ROW BEGIN
+			-- AddMissingCollectionImageTags(NEW.ImageID)
+			INSERT OR IGNORE INTO CollectionTags(TagID, CollectionID, LinkerID)
+			SELECT DISTINCT ImageTags.TagID, CollectionMembers.CollectionID, ImageTags.LinkerID
+			FROM ImageTags
+			INNER JOIN CollectionMembers ON CollectionMembers.ImageID = ImageTags.ImageID
+			LEFT JOIN CollectionTags ON CollectionTags.CollectionID = CollectionMembers.CollectionID AND CollectionTags.TagID = ImageTags.TagID
+			WHERE CollectionTags.CollectionID IS NULL AND ImageTags.ImageID = NEW.ImageID;
+		END`,
+		`CREATE TRIGGER onTagDelete BEFORE DELETE ON Tags
+		FOR EACH ROW BEGIN
+			DELETE FROM ImageTags WHERE TagID=OLD.ID;
+			DELETE FROM CollectionTags WHERE TagID=OLD.ID;
+		END`,
+	}
+	installQueries = append(installQueries, imageMetadataAudioColumns...)
+
+	//Run the whole install in one transaction so a failure does not leave a half installed database
+	tx, err := DBConnection.DBHandle.Begin()
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/performFreshDBInstall", "0", logging.ResultFailure, []string{"Failed to install database", err.Error()})
+		return err
+	}
+	for _, sqlQuery := range installQueries {
+		if _, err := tx.Exec(sqlQuery); err != nil {
+			tx.Rollback()
+			logging.WriteLog(logging.LogLevelError, "SQLitePlugin/performFreshDBInstall", "0", logging.ResultFailure, []string{"Failed to install database", err.Error()})
+			return err
+		}
+	}
+	if _, err := tx.Exec("INSERT INTO DBVersion (version) VALUES (?);", currentDBVersion); err != nil {
+		tx.Rollback()
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/performFreshDBInstall", "0", logging.ResultFailure, []string{"Failed to install database", err.Error()})
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/performFreshDBInstall", "0", logging.ResultFailure, []string{"Failed to install database", err.Error()})
+		return err
+	}
+	return nil
+}
+
+//jobsTable is shared by the fresh install and the upgrade to version 2
+const jobsTable = "CREATE TABLE Jobs (ID INTEGER PRIMARY KEY AUTOINCREMENT, Type VARCHAR(40) NOT NULL, Payload VARCHAR(2000) NOT NULL DEFAULT '', Status VARCHAR(20) NOT NULL DEFAULT 'queued', Attempts BIGINT NOT NULL DEFAULT 0, MaxAttempts BIGINT NOT NULL DEFAULT 1, Progress BIGINT NOT NULL DEFAULT 0, Total BIGINT NOT NULL DEFAULT 0, LastError VARCHAR(2000) NOT NULL DEFAULT '', CreationTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, UpdateTime TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, RunAfter TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL);"
+
+//imageMetadataTable is shared by the fresh install and the upgrade to version 3
+const imageMetadataTable = "CREATE TABLE ImageMetadata (ImageID BIGINT NOT NULL PRIMARY KEY REFERENCES Images(ID), CaptureTime TIMESTAMP NULL, CameraMake VARCHAR(255) NOT NULL DEFAULT '', CameraModel VARCHAR(255) NOT NULL DEFAULT '', LensModel VARCHAR(255) NOT NULL DEFAULT '', ExposureTime VARCHAR(40) NOT NULL DEFAULT '', FNumber VARCHAR(40) NOT NULL DEFAULT '', FocalLength VARCHAR(40) NOT NULL DEFAULT '', ISO BIGINT NOT NULL DEFAULT 0, Orientation BIGINT NOT NULL DEFAULT 0);"
+
+//imageMetadataAudioColumns are added by both the fresh install and the upgrade to version 5, as SQLite can only add one column at a time
+var imageMetadataAudioColumns = []string{
+	"ALTER TABLE ImageMetadata ADD COLUMN Title VARCHAR(255) NOT NULL DEFAULT '';",
+	"ALTER TABLE ImageMetadata ADD COLUMN Artist VARCHAR(255) NOT NULL DEFAULT '';",
+	"ALTER TABLE ImageMetadata ADD COLUMN Album VARCHAR(255) NOT NULL DEFAULT '';",
+	"ALTER TABLE ImageMetadata ADD COLUMN Genre VARCHAR(255) NOT NULL DEFAULT '';",
+}
+
+//imageColorsTable is shared by the fresh install and the upgrade to version 6
+const imageColorsTable = "CREATE TABLE ImageColors (ID INTEGER PRIMARY KEY AUTOINCREMENT, ImageID BIGINT NOT NULL REFERENCES Images(ID), Red BIGINT NOT NULL, Green BIGINT NOT NULL, Blue BIGINT NOT NULL, Share REAL NOT NULL);"
+
+//imageDeleteTrigger is shared by the fresh install and the upgrades to version 3 and 6, which added ImageMetadata and ImageColors to it
+const imageDeleteTrigger = `CREATE TRIGGER onImageDelete BEFORE DELETE ON Images
+		FOR EACH ROW BEGIN
+			DELETE FROM ImageTags WHERE ImageID=OLD.ID;
+			DELETE FROM ImageUserScores WHERE ImageID=OLD.ID;
+			DELETE FROM CollectionMembers WHERE ImageID=OLD.ID;
+			DELETE FROM ImagedHashes WHERE ImageID=OLD.ID;
+			DELETE FROM ImageMetadata WHERE ImageID=OLD.ID;
+			DELETE FROM ImageColors WHERE ImageID=OLD.ID;
+		END`
+
+//TODO: Add update code here
+func (DBConnection *SQLitePlugin) upgradeDatabase(version int64) (int64, error) {
+	//Update version 1->2
+	if version == 1 {
+		tx, err := DBConnection.DBHandle.Begin()
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "SQLitePlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+			return version, err
+		}
+		for _, sqlQuery := range []string{jobsTable, "CREATE INDEX JobsStatus ON Jobs(Status);", "UPDATE DBVersion SET version = 2;"} {
+			if _, err := tx.Exec(sqlQuery); err != nil {
+				tx.Rollback()
+				logging.WriteLog(logging.LogLevelError, "SQLitePlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+				return version, err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			logging.WriteLog(logging.LogLevelError, "SQLitePlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+			return version, err
+		}
+		version = 2
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/InitDatabase", "0", logging.ResultInfo, []string{"Database schema updated to version", strconv.FormatInt(version, 10)})
+	}
+	//Update version 2->3
+	if version == 2 {
+		tx, err := DBConnection.DBHandle.Begin()
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "SQLitePlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+			return version, err
+		}
+		for _, sqlQuery := range []string{imageMetadataTable, "DROP TRIGGER onImageDelete;", imageDeleteTrigger, "UPDATE DBVersion SET version = 3;"} {
+			if _, err := tx.Exec(sqlQuery); err != nil {
+				tx.Rollback()
+				logging.WriteLog(logging.LogLevelError, "SQLitePlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+				return version, err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			logging.WriteLog(logging.LogLevelError, "SQLitePlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+			return version, err
+		}
+		version = 3
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/InitDatabase", "0", logging.ResultInfo, []string{"Database schema updated to version", strconv.FormatInt(version, 10)})
+	}
+	//Update version 3->4
+	if version == 3 {
+		tx, err := DBConnection.DBHandle.Begin()
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "SQLitePlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+			return version, err
+		}
+		//Existing images are left at zero until the media-info job fills them in
+		for _, sqlQuery := range []string{
+			"ALTER TABLE Images ADD COLUMN Width BIGINT NOT NULL DEFAULT 0;",
+			"ALTER TABLE Images ADD COLUMN Height BIGINT NOT NULL DEFAULT 0;",
+			"ALTER TABLE Images ADD COLUMN FileSize BIGINT NOT NULL DEFAULT 0;",
+			"ALTER TABLE Images ADD COLUMN MIMEType VARCHAR(255) NOT NULL DEFAULT '';",
+			"ALTER TABLE Images ADD COLUMN Duration REAL NOT NULL DEFAULT 0;",
+			"UPDATE DBVersion SET version = 4;",
+		} {
+			if _, err := tx.Exec(sqlQuery); err != nil {
+				tx.Rollback()
+				logging.WriteLog(logging.LogLevelError, "SQLitePlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+				return version, err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			logging.WriteLog(logging.LogLevelError, "SQLitePlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+			return version, err
+		}
+		version = 4
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/InitDatabase", "0", logging.ResultInfo, []string{"Database schema updated to version", strconv.FormatInt(version, 10)})
+	}
+	//Update version 4->5
+	if version == 4 {
+		tx, err := DBConnection.DBHandle.Begin()
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "SQLitePlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+			return version, err
+		}
+		//Existing audio keeps no tags, they are only read when a file is uploaded
+		sqlQueries := append([]string{"ALTER TABLE Images ADD COLUMN Bitrate BIGINT NOT NULL DEFAULT 0;"}, imageMetadataAudioColumns...)
+		for _, sqlQuery := range append(sqlQueries, "UPDATE DBVersion SET version = 5;") {
+			if _, err := tx.Exec(sqlQuery); err != nil {
+				tx.Rollback()
+				logging.WriteLog(logging.LogLevelError, "SQLitePlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+				return version, err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			logging.WriteLog(logging.LogLevelError, "SQLitePlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+			return version, err
+		}
+		version = 5
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/InitDatabase", "0", logging.ResultInfo, []string{"Database schema updated to version", strconv.FormatInt(version, 10)})
+	}
+	//Update version 5->6
+	if version == 5 {
+		tx, err := DBConnection.DBHandle.Begin()
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "SQLitePlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+			return version, err
+		}
+		//Existing images get their colours when the hash job next runs over them
+		for _, sqlQuery := range []string{
+			"ALTER TABLE Images ADD COLUMN BlurHash VARCHAR(64) NOT NULL DEFAULT '';",
+			imageColorsTable,
+			"CREATE INDEX ImageColorsImageID ON ImageColors(ImageID);",
+			"DROP TRIGGER onImageDelete;",
+			imageDeleteTrigger,
+			"UPDATE DBVersion SET version = 6;",
+		} {
+			if _, err := tx.Exec(sqlQuery); err != nil {
+				tx.Rollback()
+				logging.WriteLog(logging.LogLevelError, "SQLitePlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+				return version, err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			logging.WriteLog(logging.LogLevelError, "SQLitePlugin/InitDatabase", "0", logging.ResultFailure, []string{"Failed to update database version", err.Error()})
+			return version, err
+		}
+		version = 6
+		logging.WriteLog(logging.LogLevelError, "SQLitePlugin/InitDatabase", "0", logging.ResultInfo, []string{"Database schema updated to version", strconv.FormatInt(version, 10)})
+	}
+	return version, nil
+}
@@ -0,0 +1,21 @@
+package sqliteplugin
+
+import (
+	"go-image-board/config"
+	"go-image-board/database/dbtest"
+	"go-image-board/interfaces"
+	"path/filepath"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	dbtest.RunConformance(t, func(t *testing.T) interfaces.DBInterface {
+		config.Configuration.DBPath = filepath.Join(t.TempDir(), "gib.db")
+		DBConnection := &SQLitePlugin{}
+		if err := DBConnection.InitDatabase(); err != nil {
+			t.Fatalf("InitDatabase: %v", err)
+		}
+		t.Cleanup(func() { DBConnection.DBHandle.Close() })
+		return DBConnection
+	})
+}
@@ -3,73 +3,92 @@ package main
 import (
 	"go-image-board/config"
 	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/jobs"
 	"go-image-board/logging"
+	"go-image-board/media"
 	"go-image-board/routers"
-	"os"
-	"path"
-	"path/filepath"
+	"go-image-board/storage"
 	"strconv"
 )
 
-func renameAllImages() {
+//renameAllImages renames every image to match the naming convention, stopping at the first error so the database and storage stay in sync
+func renameAllImages(Progress jobs.ProgressFunc) error {
 	_, maxCount, err := database.DBInterface.SearchImages(nil, 0, config.Configuration.PageStride)
 	if err != nil {
 		logging.WriteLog(logging.LogLevelError, "renameUtility/renameAllImages", "0", logging.ResultFailure, []string{"Failed to query for images", err.Error()})
-		return
+		return err
 	}
 	logging.WriteLog(logging.LogLevelInfo, "renameUtility/renameAllImages", "0", logging.ResultInfo, []string{"Images to process", strconv.FormatUint(maxCount, 10)})
 	//Loop through the images one page at a time
 	for count := uint64(0); count < maxCount; count += config.Configuration.PageStride {
-		logging.WriteLog(logging.LogLevelInfo, "renameUtility/renameAllImages", "0", logging.ResultInfo, []string{"Processing at", strconv.FormatUint(count, 10)})
+		Progress(count, maxCount)
 		images, _, err := database.DBInterface.SearchImages(nil, count, config.Configuration.PageStride)
 		if err != nil {
 			logging.WriteLog(logging.LogLevelCritical, "renameUtility/renameAllImages", "0", logging.ResultFailure, []string{"Failed to query for images", err.Error()})
-			return
+			return err
 		}
 		//Loop through the images in this page
 		for _, imageInfo := range images {
 			//Open file for reading
-			fileStream, err := os.Open(path.Join(config.Configuration.ImageDirectory, imageInfo.Location))
+			fileStream, err := storage.StorageInterface.Open(imageInfo.Location)
 			if err != nil {
 				logging.WriteLog(logging.LogLevelCritical, "renameUtility/renameAllImages", "0", logging.ResultFailure, []string{"Failed to open file", err.Error()})
-				return
+				return err
 			}
 
-			//Get new name
-			newName, err := routers.GetNewImageName(imageInfo.Location, fileStream)
+			//Get new name, with the extension of the type its content is when recognized
+			typedName := imageInfo.Location
+			if mediaType, err := media.Sniff(fileStream); err == nil {
+				typedName = mediaType.WithExtension(imageInfo.Location)
+			}
+			newName, err := routers.GetNewImageName(typedName, fileStream)
 			fileStream.Close()
 			if err != nil {
 				logging.WriteLog(logging.LogLevelCritical, "renameUtility/renameAllImages", "0", logging.ResultFailure, []string{"Error generating new name", err.Error()})
-				return //On error cancel out to keep db and image in sync
+				return err //On error cancel out to keep db and image in sync
 			}
-			if newName == imageInfo.Location {
-				logging.WriteLog(logging.LogLevelCritical, "renameUtility/renameAllImages", "0", logging.ResultInfo, []string{"Skipping due to same name", newName})
+			newLocation := storage.ImageLocation(newName)
+			if newLocation == imageInfo.Location {
+				logging.WriteLog(logging.LogLevelCritical, "renameUtility/renameAllImages", "0", logging.ResultInfo, []string{"Skipping due to same name", newLocation})
 				continue //Skip if same name
 			}
-			//Rename image
-			if err := os.Rename(path.Join(config.Configuration.ImageDirectory, imageInfo.Location), path.Join(config.Configuration.ImageDirectory, newName)); err != nil {
-				logging.WriteLog(logging.LogLevelCritical, "renameUtility/renameAllImages", "0", logging.ResultFailure, []string{"Error renaming file", err.Error()})
-				return //On error cancel out to keep db and image in sync
-			}
-			//Rename thumbnail
-			if err := os.Rename(path.Join(config.Configuration.ImageDirectory, "thumbs"+string(filepath.Separator)+imageInfo.Location+".png"), path.Join(config.Configuration.ImageDirectory, "thumbs"+string(filepath.Separator)+newName+".png")); err != nil {
-				logging.WriteLog(logging.LogLevelError, "renameUtility/renameAllImages", "0", logging.ResultFailure, []string{"Error renaming file", err.Error()})
-			}
-			//Update database
-			if err := database.DBInterface.UpdateImage(imageInfo.ID, nil, nil, nil, nil, nil, newName); err != nil {
-				//Rollback and cancel on error
-				logging.WriteLog(logging.LogLevelError, "renameUtility/renameAllImages", "0", logging.ResultFailure, []string{"Error adding renamed image to db, cancelling", err.Error()})
-				//Rename thumbnail
-				if err := os.Rename(path.Join(config.Configuration.ImageDirectory, "thumbs"+string(filepath.Separator)+newName+".png"), path.Join(config.Configuration.ImageDirectory, "thumbs"+string(filepath.Separator)+imageInfo.Location+".png")); err != nil {
-					logging.WriteLog(logging.LogLevelError, "renameUtility/renameAllImages", "0", logging.ResultFailure, []string{"Error renaming file", err.Error()})
-				}
-				//Rename image
-				if err := os.Rename(path.Join(config.Configuration.ImageDirectory, newName), path.Join(config.Configuration.ImageDirectory, imageInfo.Location)); err != nil {
-					logging.WriteLog(logging.LogLevelError, "renameUtility/renameAllImages", "0", logging.ResultFailure, []string{"Error renaming file", err.Error()})
-				}
-				return
+			if err := moveImage(imageInfo, newLocation); err != nil {
+				return err //On error cancel out to keep db and image in sync
 			}
-			logging.WriteLog(logging.LogLevelInfo, "renameUtility/renameAllImages", "0", logging.ResultInfo, []string{"Successfull rename", newName})
+			logging.WriteLog(logging.LogLevelInfo, "renameUtility/renameAllImages", "0", logging.ResultInfo, []string{"Successfull rename", newLocation})
+		}
+	}
+	Progress(maxCount, maxCount)
+	return nil
+}
+
+//moveImage moves an image and its thumbnail to NewLocation and updates the database, the files are moved back if the database cannot be updated
+func moveImage(imageInfo interfaces.ImageInformation, NewLocation string) error {
+	//Rename image
+	if err := storage.StorageInterface.Rename(imageInfo.Location, NewLocation); err != nil {
+		logging.WriteLog(logging.LogLevelCritical, "renameUtility/moveImage", "0", logging.ResultFailure, []string{"Error renaming file", err.Error()})
+		return err
+	}
+	//Rename thumbnail
+	if err := storage.StorageInterface.Rename(storage.ThumbnailName(imageInfo.Location), storage.ThumbnailName(NewLocation)); err != nil {
+		logging.WriteLog(logging.LogLevelError, "renameUtility/moveImage", "0", logging.ResultFailure, []string{"Error renaming file", err.Error()})
+	}
+	//Update database
+	if err := database.DBInterface.UpdateImage(imageInfo.ID, nil, nil, nil, nil, nil, NewLocation); err != nil {
+		//Rollback and cancel on error
+		logging.WriteLog(logging.LogLevelError, "renameUtility/moveImage", "0", logging.ResultFailure, []string{"Error adding renamed image to db, cancelling", err.Error()})
+		//Rename thumbnail
+		if err := storage.StorageInterface.Rename(storage.ThumbnailName(NewLocation), storage.ThumbnailName(imageInfo.Location)); err != nil {
+			logging.WriteLog(logging.LogLevelError, "renameUtility/moveImage", "0", logging.ResultFailure, []string{"Error renaming file", err.Error()})
+		}
+		//Rename image
+		if err := storage.StorageInterface.Rename(NewLocation, imageInfo.Location); err != nil {
+			logging.WriteLog(logging.LogLevelError, "renameUtility/moveImage", "0", logging.ResultFailure, []string{"Error renaming file", err.Error()})
 		}
+		return err
 	}
+	//Sized thumbnails are made again for the new name when next requested
+	storage.RemoveSizedThumbnails(imageInfo.Location)
+	return nil
 }
@@ -48,6 +48,36 @@ func CollectionGetAPIRouter(responseWriter http.ResponseWriter, request *http.Re
 	ReplyWithJSONError(responseWriter, request, "Please specify CollectionID", UserName, http.StatusBadRequest)
 }
 
+//CollectionDownloadAPIRouter serves get requests to /api/Collection/{CollectionID}/Download, replying with the collection as a cbz, or a zip if Format is zip
+func CollectionDownloadAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, _, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+
+	//Get variables for URL mux from Gorilla
+	urlVariables := mux.Vars(request)
+	parsedID, err := strconv.ParseUint(urlVariables["CollectionID"], 10, 32)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "CollectionID could not be parsed into a number", UserName, http.StatusBadRequest)
+		return
+	}
+	archive, err := routers.NewCollectionArchive(parsedID, request.FormValue("Format"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ReplyWithJSONError(responseWriter, request, "No collection by that ID", UserName, http.StatusNotFound)
+			return
+		} else if err == routers.ErrUnknownArchiveFormat {
+			ReplyWithJSONError(responseWriter, request, "Format must be cbz or zip", UserName, http.StatusBadRequest)
+			return
+		}
+		ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+		return
+	}
+	archive.Serve(responseWriter, UserName)
+}
+
 //CollectionDeleteAPIRouter serves delete requests to /api/Collection/{CollectionID}
 func CollectionDeleteAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
 	//Validate Logon
@@ -7,10 +7,8 @@ import (
 	"go-image-board/database"
 	"go-image-board/interfaces"
 	"go-image-board/routers"
+	"go-image-board/storage"
 	"net/http"
-	"os"
-	"path"
-	"path/filepath"
 	"strconv"
 
 	"github.com/gorilla/mux"
@@ -44,6 +42,17 @@ func ImageGetAPIRouter(responseWriter http.ResponseWriter, request *http.Request
 			ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
 			return
 		}
+		if metadata, err := database.DBInterface.GetImageMetadata(parsedID); err == nil {
+			image.Metadata = &metadata
+		} else if err != sql.ErrNoRows {
+			ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+			return
+		}
+		image.Colors, err = database.DBInterface.GetImageColors(parsedID)
+		if err != nil {
+			ReplyWithJSONError(responseWriter, request, "Interal Database Error", UserName, http.StatusInternalServerError)
+			return
+		}
 		ReplyWithJSON(responseWriter, request, image, UserName)
 		return
 	}
@@ -101,9 +110,9 @@ func ImageDeleteAPIRouter(responseWriter http.ResponseWriter, request *http.Requ
 		}
 		go routers.WriteAuditLogByName(UserName, "DELETE-IMAGE", UserName+" deleted image with API. "+requestedID+", "+imageInfo.Name+", "+imageInfo.Location)
 		//Third, delete Image from Disk
-		go os.Remove(path.Join(config.Configuration.ImageDirectory, imageInfo.Location))
-		//Last delete thumbnail from disk
-		go os.Remove(path.Join(config.Configuration.ImageDirectory, "thumbs"+string(filepath.Separator)+imageInfo.Location+".png"))
+		go storage.StorageInterface.Remove(imageInfo.Location)
+		//Last delete thumbnails from disk
+		go storage.RemoveThumbnails(imageInfo.Location)
 		//Reply Success
 		ReplyWithJSON(responseWriter, request, GenericResponse{Result: "Successfully deleted image " + requestedID}, UserName)
 		return
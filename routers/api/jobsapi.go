@@ -0,0 +1,151 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/jobs"
+	"go-image-board/logging"
+	"go-image-board/routers"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+//JobSearchResult response format for a job list
+type JobSearchResult struct {
+	Jobs         []interfaces.JobInformation
+	ResultCount  uint64
+	ServerStride uint64
+}
+
+type postJobInput struct {
+	//Type and Payload of a new job, ignored if JobID is set
+	Type    string
+	Payload string
+	//JobID of a job to run again with the same type and payload
+	JobID uint64
+}
+
+//validateAPIUserMaintenance checks the user may view and run jobs, and if not responds to user. Returns ShouldContinue
+func validateAPIUserMaintenance(responseWriter http.ResponseWriter, request *http.Request, UserName string) bool {
+	permissions, err := database.DBInterface.GetUserPermissionSet(UserName)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Could not validate your permission, internal database error", UserName, http.StatusForbidden)
+		return false
+	}
+	if interfaces.UserPermission(permissions).HasPermission(interfaces.RunMaintenance) != true {
+		go routers.WriteAuditLogByName(UserName, "RUN-JOB", UserName+" failed to query jobs. Insufficient permissions.")
+		ReplyWithJSONError(responseWriter, request, "Insufficient permissions to view or run jobs", UserName, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+//JobsGetAPIRouter serves get requests to /api/Jobs
+func JobsGetAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, _, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User either not logged in, or hit by throttle. Either way, already handled.
+	}
+	if validateAPIUserMaintenance(responseWriter, request, UserName) == false {
+		return //Already told
+	}
+
+	pageStart, _ := strconv.ParseUint(request.FormValue("PageStart"), 10, 64) //Either parses fine, or is 0, both works
+	pageStride := config.Configuration.PageStride
+
+	jobList, count, err := database.DBInterface.GetJobs(request.FormValue("Status"), pageStart, pageStride)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "jobsapi/JobsGetAPIRouter", UserName, logging.ResultFailure, []string{"Failed to query jobs", err.Error()})
+		ReplyWithJSONError(responseWriter, request, "Internal Database Error Occured", UserName, http.StatusInternalServerError)
+		return
+	}
+
+	ReplyWithJSON(responseWriter, request, JobSearchResult{Jobs: jobList, ResultCount: count, ServerStride: pageStride}, UserName)
+}
+
+//JobGetAPIRouter serves get requests to /api/Job/{JobID}
+func JobGetAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, _, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	if validateAPIUserMaintenance(responseWriter, request, UserName) == false {
+		return //Already told
+	}
+
+	parsedID, err := strconv.ParseUint(mux.Vars(request)["JobID"], 10, 64)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "JobID could not be parsed into a number", UserName, http.StatusBadRequest)
+		return
+	}
+	job, err := database.DBInterface.GetJob(parsedID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ReplyWithJSONError(responseWriter, request, "No job by that ID", UserName, http.StatusNotFound)
+			return
+		}
+		ReplyWithJSONError(responseWriter, request, "Internal database error", UserName, http.StatusInternalServerError)
+		return
+	}
+	ReplyWithJSON(responseWriter, request, job, UserName)
+}
+
+//JobsPostAPIRouter serves post requests to /api/Jobs, queueing a new job or running an old one again
+func JobsPostAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, UserID, UserName := ValidateAndThrottleAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+	//Validate Permission to use api
+	UserAPIWriteValidated, _ := ValidateAPIUserWriteAccess(responseWriter, request, UserName)
+	if !UserAPIWriteValidated {
+		return //User does not have API access and was already told
+	}
+	if validateAPIUserMaintenance(responseWriter, request, UserName) == false {
+		return //Already told
+	}
+
+	decoder := json.NewDecoder(request.Body)
+	var jobData postJobInput
+	if err := decoder.Decode(&jobData); err != nil {
+		ReplyWithJSONError(responseWriter, request, "Failed to parse request data", UserName, http.StatusBadRequest)
+		return
+	}
+	if jobData.JobID != 0 {
+		job, err := database.DBInterface.GetJob(jobData.JobID)
+		if err == sql.ErrNoRows {
+			ReplyWithJSONError(responseWriter, request, "No job by that ID", UserName, http.StatusNotFound)
+			return
+		} else if err != nil {
+			ReplyWithJSONError(responseWriter, request, "Internal database error", UserName, http.StatusInternalServerError)
+			return
+		}
+		jobData.Type = job.Type
+		jobData.Payload = job.Payload
+	}
+	if jobs.HasHandler(jobData.Type) == false {
+		ReplyWithJSONError(responseWriter, request, "Job type not recognized", UserName, http.StatusBadRequest)
+		return
+	}
+
+	jobID, err := jobs.Enqueue(jobData.Type, jobData.Payload)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Internal database error", UserName, http.StatusInternalServerError)
+		return
+	}
+	go routers.WriteAuditLog(UserID, "RUN-JOB", UserName+" queued job "+strconv.FormatUint(jobID, 10)+", "+jobData.Type+" "+jobData.Payload)
+	job, err := database.DBInterface.GetJob(jobID)
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Internal database error", UserName, http.StatusInternalServerError)
+		return
+	}
+	ReplyWithJSON(responseWriter, request, job, UserName)
+}
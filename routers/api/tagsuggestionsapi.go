@@ -0,0 +1,53 @@
+package api
+
+import (
+	"go-image-board/config"
+	"go-image-board/media"
+	"io"
+	"net/http"
+)
+
+//tagSuggestionBytes is how much of the start of a file is read for tags, enough for the ID3 tag of an MP3 with cover art
+const tagSuggestionBytes = 1 << 20
+
+//TagSuggestionsResult lists tags an upload could be given
+type TagSuggestionsResult struct {
+	Tags []string
+}
+
+//TagSuggestionsAPIRouter serves post requests to /api/TagSuggestions, returning tags named after the artist, album, and genre of the audio file in fileHead
+//Only the start of the file needs to be sent
+func TagSuggestionsAPIRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	//Validate Logon
+	UserAPIValidated, _, UserName := ValidateAPIUser(responseWriter, request)
+	if !UserAPIValidated {
+		return //User not logged in and was already handled
+	}
+
+	if config.Configuration.SuggestAudioTags == false {
+		ReplyWithJSONError(responseWriter, request, "Tag suggestions are not enabled", UserName, http.StatusNotFound)
+		return
+	}
+
+	//Leave room for the rest of the form
+	request.Body = http.MaxBytesReader(responseWriter, request.Body, tagSuggestionBytes+(64<<10))
+	file, _, err := request.FormFile("fileHead")
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Please specify fileHead", UserName, http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, tagSuggestionBytes))
+	if err != nil {
+		ReplyWithJSONError(responseWriter, request, "Failed to read fileHead", UserName, http.StatusBadRequest)
+		return
+	}
+
+	suggestions := TagSuggestionsResult{Tags: []string{}}
+	if mediaType, known := media.Detect(data); known && mediaType.Kind == media.KindAudio {
+		if metadata, found := media.ReadMetadata(data, mediaType); found {
+			suggestions.Tags = append(suggestions.Tags, media.AudioTagSuggestions(metadata)...)
+		}
+	}
+	ReplyWithJSON(responseWriter, request, suggestions, UserName)
+}
@@ -0,0 +1,246 @@
+package routers
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"go-image-board/storage"
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//ErrUnknownArchiveFormat is returned by NewCollectionArchive when asked for a format other than cbz or zip
+var ErrUnknownArchiveFormat = errors.New("archive format must be cbz or zip")
+
+//CollectionManifestName is the name of the manifest in zip downloads of a collection, cbz downloads use ComicInfo.xml
+const CollectionManifestName = "collection.json"
+
+//CollectionArchive is a collection ready to be downloaded as a cbz or zip, with its members in order
+type CollectionArchive struct {
+	Collection interfaces.CollectionInformation
+	//Format is either cbz or zip
+	Format string
+	Tags   []interfaces.TagInformation
+	Pages  []CollectionArchivePage
+}
+
+//CollectionArchivePage is one member of a collection, and the name it has in the archive
+type CollectionArchivePage struct {
+	File     string
+	ImageID  uint64
+	Name     string
+	Location string    `json:"-"`
+	Modified time.Time `json:"-"`
+}
+
+//collectionManifest is what is written to collection.json
+type collectionManifest struct {
+	Name        string
+	Description string
+	Tags        []string
+	Pages       []CollectionArchivePage
+}
+
+//comicInfo is the ComicInfo.xml read by comic book readers, of which only the fields a collection has are filled in
+type comicInfo struct {
+	XMLName   xml.Name        `xml:"ComicInfo"`
+	Title     string          `xml:"Title"`
+	Summary   string          `xml:"Summary,omitempty"`
+	Tags      string          `xml:"Tags,omitempty"`
+	PageCount int             `xml:"PageCount"`
+	Pages     []comicInfoPage `xml:"Pages>Page"`
+}
+
+type comicInfoPage struct {
+	Image int `xml:"Image,attr"`
+}
+
+//NewCollectionArchive gets the collection, its tags, and its members ready to be written as an archive in Format
+//Members whose files are missing from storage are left out. Returns sql.ErrNoRows if there is no such collection
+func NewCollectionArchive(CollectionID uint64, Format string) (CollectionArchive, error) {
+	Format = strings.ToLower(Format)
+	if Format == "" {
+		Format = "cbz"
+	}
+	if Format != "cbz" && Format != "zip" {
+		return CollectionArchive{}, ErrUnknownArchiveFormat
+	}
+	collection, err := database.DBInterface.GetCollection(CollectionID)
+	if err != nil {
+		return CollectionArchive{}, err
+	}
+	tags, err := database.DBInterface.GetCollectionTags(CollectionID)
+	if err != nil {
+		return CollectionArchive{}, err
+	}
+	members, _, err := database.DBInterface.GetCollectionMembers(CollectionID, 0, 0)
+	if err != nil {
+		return CollectionArchive{}, err
+	}
+	//Pages are numbered with enough digits for readers that sort by name rather than number
+	digits := len(strconv.Itoa(len(members)))
+	if digits < 3 {
+		digits = 3
+	}
+	ToReturn := CollectionArchive{Collection: collection, Format: Format, Tags: tags}
+	for _, member := range members {
+		fileInfo, err := storage.StorageInterface.Stat(member.Location)
+		if err != nil {
+			logging.WriteLog(logging.LogLevelWarning, "collectiondownloadrouter/NewCollectionArchive", "0", logging.ResultFailure, []string{"Leaving out member whose file could not be found", member.Location, err.Error()})
+			continue
+		}
+		ToReturn.Pages = append(ToReturn.Pages, CollectionArchivePage{
+			File:     fmt.Sprintf("%0*d%s", digits, len(ToReturn.Pages)+1, strings.ToLower(filepath.Ext(member.Location))),
+			ImageID:  member.ID,
+			Name:     member.Name,
+			Location: member.Location,
+			Modified: fileInfo.ModTime,
+		})
+	}
+	return ToReturn, nil
+}
+
+//FileName returns the name to download the archive as, being the collection's name
+func (Archive CollectionArchive) FileName() string {
+	name := strings.Map(func(Character rune) rune {
+		if Character < ' ' || strings.ContainsRune(`/\:*?"<>|`, Character) {
+			return '_'
+		}
+		return Character
+	}, strings.TrimSpace(Archive.Collection.Name))
+	if name == "" {
+		name = "collection-" + strconv.FormatUint(Archive.Collection.ID, 10)
+	}
+	return name + "." + Archive.Format
+}
+
+//ContentType returns the MIME type of the archive
+func (Archive CollectionArchive) ContentType() string {
+	if Archive.Format == "cbz" {
+		return "application/vnd.comicbook+zip"
+	}
+	return "application/zip"
+}
+
+//Stream writes the archive to Writer one member at a time, so the whole of it is never held in memory
+func (Archive CollectionArchive) Stream(Writer io.Writer) error {
+	zipWriter := zip.NewWriter(Writer)
+	if err := Archive.writeManifest(zipWriter); err != nil {
+		return err
+	}
+	for _, page := range Archive.Pages {
+		if err := writeArchivePage(zipWriter, page); err != nil {
+			return err
+		}
+	}
+	return zipWriter.Close()
+}
+
+//writeManifest adds ComicInfo.xml to cbz archives, and collection.json to zip archives
+func (Archive CollectionArchive) writeManifest(zipWriter *zip.Writer) error {
+	var tagNames []string
+	for _, tag := range Archive.Tags {
+		tagNames = append(tagNames, tag.Name)
+	}
+	if Archive.Format == "cbz" {
+		entry, err := zipWriter.CreateHeader(&zip.FileHeader{Name: "ComicInfo.xml", Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		info := comicInfo{Title: Archive.Collection.Name, Summary: Archive.Collection.Description, Tags: strings.Join(tagNames, ","), PageCount: len(Archive.Pages)}
+		for index := range Archive.Pages {
+			info.Pages = append(info.Pages, comicInfoPage{Image: index})
+		}
+		if _, err := io.WriteString(entry, xml.Header); err != nil {
+			return err
+		}
+		encoder := xml.NewEncoder(entry)
+		encoder.Indent("", "  ")
+		return encoder.Encode(info)
+	}
+	entry, err := zipWriter.CreateHeader(&zip.FileHeader{Name: CollectionManifestName, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(collectionManifest{Name: Archive.Collection.Name, Description: Archive.Collection.Description, Tags: tagNames, Pages: Archive.Pages})
+}
+
+//writeArchivePage copies a member's file from storage into the archive, without compression as images already are compressed
+func writeArchivePage(zipWriter *zip.Writer, Page CollectionArchivePage) error {
+	file, err := storage.StorageInterface.Open(Page.Location)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	entry, err := zipWriter.CreateHeader(&zip.FileHeader{Name: Page.File, Method: zip.Store, Modified: Page.Modified})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, file)
+	return err
+}
+
+//Serve sends the archive as a download
+//Once it has started, errors can only be logged, and the download is left incomplete so it is not mistaken for the whole collection
+func (Archive CollectionArchive) Serve(responseWriter http.ResponseWriter, UserName string) {
+	responseWriter.Header().Set("Content-Type", Archive.ContentType())
+	responseWriter.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": Archive.FileName()}))
+	if err := Archive.Stream(deadlineWriter{Writer: responseWriter, Controller: http.NewResponseController(responseWriter)}); err != nil {
+		logging.WriteLog(logging.LogLevelError, "collectiondownloadrouter/Serve", UserName, logging.ResultFailure, []string{"Failed to send collection archive", strconv.FormatUint(Archive.Collection.ID, 10), err.Error()})
+		return
+	}
+	logging.WriteLog(logging.LogLevelVerbose, "collectiondownloadrouter/Serve", UserName, logging.ResultSuccess, []string{"Sent collection archive", strconv.FormatUint(Archive.Collection.ID, 10), strconv.Itoa(len(Archive.Pages))})
+}
+
+//deadlineWriter pushes back the server's WriteTimeout on every write, so a large download is only cut off if the client stops reading
+type deadlineWriter struct {
+	Writer     io.Writer
+	Controller *http.ResponseController
+}
+
+func (Writer deadlineWriter) Write(Data []byte) (int, error) {
+	//Not every ResponseWriter supports deadlines, those without them have no timeout to push back
+	Writer.Controller.SetWriteDeadline(time.Now().Add(config.Configuration.WriteTimeout))
+	return Writer.Writer.Write(Data)
+}
+
+//CollectionDownloadRouter serves get requests to /collection/download
+func CollectionDownloadRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	TemplateInput := getTemplateInputFromRequest(responseWriter, request)
+	userQuery := TemplateInput.OldQuery
+
+	collectionID, err := strconv.ParseUint(request.FormValue("ID"), 10, 32)
+	if err != nil {
+		TemplateInput.HTMLMessage += template.HTML("Failed to parse requested collection ID.<br>")
+		redirectWithFlash(responseWriter, request, "/collections?SearchTerms="+url.QueryEscape(userQuery), TemplateInput.HTMLMessage, "CollectionError")
+		return
+	}
+	archive, err := NewCollectionArchive(collectionID, request.FormValue("Format"))
+	if err != nil {
+		if err == sql.ErrNoRows || err == ErrUnknownArchiveFormat {
+			TemplateInput.HTMLMessage += template.HTML("Failed to get the requested collection.<br>")
+		} else {
+			TemplateInput.HTMLMessage += template.HTML("Failed to get the requested collection, internal error.<br>")
+		}
+		logging.WriteLog(logging.LogLevelError, "collectiondownloadrouter/CollectionDownloadRouter", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to prepare collection archive", strconv.FormatUint(collectionID, 10), err.Error()})
+		redirectWithFlash(responseWriter, request, "/collections?SearchTerms="+url.QueryEscape(userQuery), TemplateInput.HTMLMessage, "CollectionError")
+		return
+	}
+	archive.Serve(responseWriter, TemplateInput.UserInformation.GetCompositeID())
+}
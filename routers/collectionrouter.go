@@ -6,12 +6,10 @@ import (
 	"go-image-board/database"
 	"go-image-board/interfaces"
 	"go-image-board/logging"
+	"go-image-board/storage"
 	"html/template"
 	"net/http"
 	"net/url"
-	"os"
-	"path"
-	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -391,26 +389,32 @@ func CollectionPostRouter(responseWriter http.ResponseWriter, request *http.Requ
 			return
 		}
 
-		//Permission validated, now delete (Collection)
-		if err := database.DBInterface.DeleteCollection(collectionID); err != nil {
+		//Permission validated, now delete the collection and its images together, so a failure leaves all of them in place
+		err = database.DBInterface.RunInTransaction(func(Transaction interfaces.DBInterface) error {
+			if err := Transaction.DeleteCollection(collectionID); err != nil {
+				return err
+			}
+			for _, ImageInfo := range CollectionMembers {
+				if err := Transaction.DeleteImage(ImageInfo.ID); err != nil {
+					TemplateInput.HTMLMessage += template.HTML("Failed to delete image " + strconv.FormatUint(ImageInfo.ID, 10) + ".<br>")
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
 			TemplateInput.HTMLMessage += template.HTML("Failed to delete collection. SQL Error.<br>")
 			go WriteAuditLogByName(TemplateInput.UserInformation.Name, "DELETE-COLLECTION", TemplateInput.UserInformation.Name+" failed to delete collection. "+request.FormValue("ID")+", "+err.Error())
 			redirectWithFlash(responseWriter, request, "/collection?ID="+strconv.FormatUint(collectionID, 10)+"&SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "DeleteFailed")
 			return
 		}
 
-		//Delete images
+		//Only remove files once nothing can refer to them
 		for _, ImageInfo := range CollectionMembers {
-			err = database.DBInterface.DeleteImage(ImageInfo.ID)
-			if err != nil {
-				TemplateInput.HTMLMessage += template.HTML("Failed to delete image " + strconv.FormatUint(ImageInfo.ID, 10) + ".<br>")
-				go WriteAuditLogByName(TemplateInput.UserInformation.Name, "DELETE-IMAGE", TemplateInput.UserInformation.Name+" failed to delete image.")
-			} else {
-				//Delete Image from Disk
-				go os.Remove(path.Join(config.Configuration.ImageDirectory, ImageInfo.Location))
-				//Delete thumbnail from disk
-				go os.Remove(path.Join(config.Configuration.ImageDirectory, "thumbs"+string(filepath.Separator)+ImageInfo.Location+".png"))
-			}
+			//Delete Image from Disk
+			go storage.StorageInterface.Remove(ImageInfo.Location)
+			//Delete thumbnails from disk
+			go storage.RemoveThumbnails(ImageInfo.Location)
 		}
 
 		go WriteAuditLogByName(TemplateInput.UserInformation.Name, "DELETE-COLLECTION", TemplateInput.UserInformation.Name+" deleted collection. "+request.FormValue("ID")+", "+CollectionInfo.Name)
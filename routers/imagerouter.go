@@ -1,18 +1,19 @@
 package routers
 
 import (
+	"database/sql"
 	"go-image-board/config"
 	"go-image-board/database"
 	"go-image-board/interfaces"
+	"go-image-board/jobs"
 	"go-image-board/logging"
+	"go-image-board/media"
 	"go-image-board/routers/templatecache"
+	"go-image-board/storage"
 	"html"
 	"html/template"
 	"net/http"
 	"net/url"
-	"os"
-	"path"
-	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -72,6 +73,19 @@ func ImageGetRouter(responseWriter http.ResponseWriter, request *http.Request) {
 		logging.WriteLog(logging.LogLevelError, "imagerouter/ImageRouter", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to get collection info for", strconv.FormatUint(requestedID, 10), err.Error()})
 	}
 
+	//Get camera details, most images have none
+	if metadata, err := database.DBInterface.GetImageMetadata(requestedID); err == nil {
+		imageInfo.Metadata = &metadata
+	} else if err != sql.ErrNoRows {
+		logging.WriteLog(logging.LogLevelError, "imagerouter/ImageRouter", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to get metadata for", strconv.FormatUint(requestedID, 10), err.Error()})
+	}
+
+	//Get dominant colours, empty until the image has been hashed
+	imageInfo.Colors, err = database.DBInterface.GetImageColors(requestedID)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "imagerouter/ImageRouter", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to get colors for", strconv.FormatUint(requestedID, 10), err.Error()})
+	}
+
 	//Get next and previous image based on query
 	userQTags := []interfaces.TagInformation{}
 	err = nil
@@ -131,7 +145,12 @@ func ImageGetRouter(responseWriter http.ResponseWriter, request *http.Request) {
 	}
 
 	//Get the image content information based on type (Img, vs video vs...)
-	TemplateInput.ImageContent = templatecache.GetEmbedForContent(imageInfo.Location)
+	TemplateInput.TranscodeStatus = getTranscodeStatus(imageInfo)
+	if TemplateInput.TranscodeStatus == transcodeReady {
+		TemplateInput.ImageContent = templatecache.GetEmbedForTranscodedVideo(imageInfo.Location, config.Configuration.TranscodeFormat)
+	} else {
+		TemplateInput.ImageContent = templatecache.GetEmbedForContent(imageInfo.Location)
+	}
 
 	TemplateInput.Tags, err = database.DBInterface.GetImageTags(imageInfo.ID)
 	if err != nil {
@@ -147,6 +166,34 @@ func ImageGetRouter(responseWriter http.ResponseWriter, request *http.Request) {
 	replyWithTemplate("image.html", TemplateInput, responseWriter, request)
 }
 
+//transcodeReady is the transcode status of a video whose version for browsers has been made
+const transcodeReady = "ready"
+
+//transcodeMissing is the transcode status of a video with neither a version for browsers nor a job making one
+const transcodeMissing = "missing"
+
+//getTranscodeStatus returns transcodeReady, transcodeMissing, or the status of the job transcoding a video browsers cannot play, or "" if they can play it as it is
+func getTranscodeStatus(ImageInfo interfaces.ImageInformation) string {
+	if mediaType, _ := media.ByName(ImageInfo.Location); mediaType.Kind != media.KindVideo || mediaType.Playable {
+		return ""
+	}
+	if exists, _ := storage.Exists(storage.TranscodeName(ImageInfo.Location, config.Configuration.TranscodeFormat)); exists {
+		return transcodeReady
+	}
+	job, err := database.DBInterface.GetLatestJobWithPayload(jobs.TranscodeVideo, strconv.FormatUint(ImageInfo.ID, 10))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			logging.WriteLog(logging.LogLevelError, "imagerouter/getTranscodeStatus", "0", logging.ResultFailure, []string{"Failed to get transcode job", ImageInfo.Location, err.Error()})
+		}
+		return transcodeMissing
+	}
+	//A job that finished without a file ran while FFMPEG was off
+	if job.Status == interfaces.JobDone {
+		return transcodeMissing
+	}
+	return job.Status
+}
+
 //ImagePostRouter serves post requests to /image
 func ImagePostRouter(responseWriter http.ResponseWriter, request *http.Request) {
 	TemplateInput := getTemplateInputFromRequest(responseWriter, request)
@@ -530,9 +577,9 @@ func ImagePostRouter(responseWriter http.ResponseWriter, request *http.Request)
 		}
 		go WriteAuditLogByName(TemplateInput.UserInformation.Name, "DELETE-IMAGE", TemplateInput.UserInformation.Name+" deleted image. "+request.FormValue("ID")+", "+ImageInfo.Name+", "+ImageInfo.Location)
 		//Third, delete Image from Disk
-		go os.Remove(path.Join(config.Configuration.ImageDirectory, ImageInfo.Location))
-		//Last delete thumbnail from disk
-		go os.Remove(path.Join(config.Configuration.ImageDirectory, "thumbs"+string(filepath.Separator)+ImageInfo.Location+".png"))
+		go storage.StorageInterface.Remove(ImageInfo.Location)
+		//Last delete thumbnails from disk
+		go storage.RemoveThumbnails(ImageInfo.Location)
 		TemplateInput.HTMLMessage += template.HTML("Deletion success.<br>")
 		redirectWithFlash(responseWriter, request, "/images?SearchTerms="+url.QueryEscape(TemplateInput.OldQuery), TemplateInput.HTMLMessage, "DeleteSuccess")
 		return
@@ -3,25 +3,39 @@ package routers
 import (
 	"bytes"
 	"crypto/sha256"
+	"database/sql"
 	"errors"
 	"fmt"
 	"go-image-board/config"
 	"go-image-board/database"
 	"go-image-board/interfaces"
+	"go-image-board/jobs"
 	"go-image-board/logging"
+	"go-image-board/media"
+	"go-image-board/storage"
 	"io"
 	"net/http"
-	"os"
 	"path"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 type uploadData struct {
-	Name string
-	ID   uint64
+	Name     string
+	ID       uint64
+	Location string
+}
+
+//pendingUpload is an uploaded file that has been saved, but not yet added to the database
+type pendingUpload struct {
+	Name     string
+	HashName string
+	Location string
+	//Metadata is nil if the file had none
+	Metadata *interfaces.ImageMetadata
 }
 
 func handleImageUpload(request *http.Request, userName string) (uint64, map[string]uint64, error) {
@@ -42,20 +56,9 @@ func handleImageUpload(request *http.Request, userName string) (uint64, map[stri
 	//ParseCollection
 	collectionName := strings.TrimSpace(request.FormValue("CollectionName"))
 	//CacheCollectionInfo
-	collectionInfo, err := database.DBInterface.GetCollectionByName(collectionName)
-	if collectionName != "" && err != nil {
-		//Want to add to collection, but the collection does not exist
-		if interfaces.UserPermission(userPermission).HasPermission(interfaces.AddCollections) != true {
-			go WriteAuditLog(userID, "IMAGE-UPLOAD", userName+" failed to upload image. No permissions to create collection.")
-			return 0, nil, errors.New("User does not have create permission for collections")
-		}
-	} else if collectionName != "" && err == nil {
-		//Want to add to a pre-existing collection
-		if interfaces.UserPermission(userPermission).HasPermission(interfaces.ModifyCollections) != true &&
-			(config.Configuration.UsersControlOwnObjects && collectionInfo.UploaderID != userID) {
-			go WriteAuditLog(userID, "IMAGE-UPLOAD", userName+" failed to upload image. No permissions to add members to collection.")
-			return 0, nil, errors.New("User does not have permission to update requested collection")
-		}
+	collectionInfo, err := checkUploadCollection(userID, userName, interfaces.UserPermission(userPermission), collectionName)
+	if err != nil {
+		return 0, nil, err
 	}
 
 	if interfaces.UserPermission(userPermission).HasPermission(interfaces.UploadImage) != true {
@@ -69,8 +72,8 @@ func handleImageUpload(request *http.Request, userName string) (uint64, map[stri
 
 	//Cache tags first, improves speed to calculate this once than for each image
 	//Get tags
-	var validatedUserTags []uint64 //Will contain tags the user is allowed to use
-	tagIDString := ""
+	var validatedUserTags []uint64          //Will contain tags the user is allowed to use
+	var newTags []interfaces.TagInformation //Will contain tags the user is allowed to create
 	userQTags, err := database.DBInterface.GetQueryTags(request.FormValue("SearchTags"), false)
 	if err != nil {
 		errorCompilation += "Failed to get tags from input"
@@ -85,7 +88,6 @@ func handleImageUpload(request *http.Request, userName string) (uint64, map[stri
 				// /ValidatePermission
 			} else {
 				validatedUserTags = append(validatedUserTags, tag.ID)
-				tagIDString = tagIDString + ", " + strconv.FormatUint(tag.ID, 10)
 			}
 		} else if tag.IsMeta == false {
 			//Create Tag
@@ -95,149 +97,284 @@ func handleImageUpload(request *http.Request, userName string) (uint64, map[stri
 				errorCompilation += "Unable to use tag " + tag.Name + " due to insufficient permissions of user to create tags. "
 				// /ValidatePermission
 			} else {
-				tagID, err := database.DBInterface.NewTag(tag.Name, tag.Description, userID)
-				if err != nil {
-					logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userName, logging.ResultFailure, []string{"error attempting to create tag", err.Error(), tag.Name})
-					errorCompilation += "Unable to use tag " + tag.Name + " due to a database error. "
-				} else {
-					go WriteAuditLog(userID, "CREATE-TAG", userName+" created a new tag. "+tag.Name)
-					validatedUserTags = append(validatedUserTags, tagID)
-					tagIDString = tagIDString + ", " + strconv.FormatUint(tagID, 10)
-				}
+				newTags = append(newTags, tag) //Created along with the images
 			}
 		}
 	}
 
-	var lastID uint64
-	var uploadedIDs []uploadData
+	groups := []uploadGroup{{CollectionName: collectionName, CollectionID: collectionInfo.ID}}
 	request.ParseMultipartForm(config.Configuration.MaxUploadBytes)
 	fileHeaders := request.MultipartForm.File["fileToUpload"]
 	source := request.FormValue("Source")
 	for _, fileHeader := range fileHeaders {
-		switch ext := strings.ToLower(filepath.Ext(fileHeader.Filename)); ext {
-		case ".jpg", ".jpeg", ".jfif", ".bmp", ".gif", ".png", ".svg", ".mpg", ".mov", ".webm", ".avi", ".mp4", ".mp3", ".ogg", ".wav", ".webp", ".tiff", ".tif":
-			//Passes filter
-		default:
-			logging.WriteLog(logging.LogLevelVerbose, "imagerouter/handleImageUpload", userName, logging.ResultFailure, []string{"Attempted to upload a file which did not pass filter", ext})
-			errorCompilation += fileHeader.Filename + " is not a recognized file. "
-			continue
-		}
 		fileStream, err := fileHeader.Open()
 		if err != nil {
 			logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userName, logging.ResultFailure, []string{"Upload image, could not open stream to save", err.Error()})
 			errorCompilation += fileHeader.Filename + " could not be opened. "
-		} else {
-			originalName := fileHeader.Filename
-			//Hash Image
-			hashName, err := GetNewImageName(originalName, fileStream)
+			continue
+		}
+		header := make([]byte, 4)
+		fileStream.ReadAt(header, 0)
+		if media.IsZip(header) {
+			groups = addArchiveUploads(groups, userID, userName, interfaces.UserPermission(userPermission), fileHeader.Filename, fileStream, fileHeader.Size, duplicateIDs, &errorCompilation)
+		} else if upload, saved := saveUpload(userName, fileHeader.Filename, fileStream, duplicateIDs, &errorCompilation); saved {
+			groups[0].Uploads = append(groups[0].Uploads, upload)
+		}
+		fileStream.Close()
+	}
+
+	//Now add everything to the database together
+	lastID, err := commitUploads(userID, userName, groups, source, validatedUserTags, newTags)
+	if err != nil {
+		errorCompilation += "Failed to add the upload to the database, nothing was uploaded. "
+	}
+
+	if errorCompilation != "" {
+		return lastID, duplicateIDs, errors.New(errorCompilation)
+	}
+	return lastID, duplicateIDs, nil
+}
+
+//uploadGroup is a set of saved uploads going into the same collection, or none if CollectionName is blank
+type uploadGroup struct {
+	CollectionName string
+	//CollectionID is 0 if the collection needs creating
+	CollectionID uint64
+	Uploads      []pendingUpload
+}
+
+//commitUploads adds saved uploads to the database in one transaction, along with the tags to create, their tags, and their collections, creating them where CollectionID is 0
+//If any of it fails none of it is kept, and the saved files are removed so they are not left behind. Returns the ID of the last upload
+func commitUploads(userID uint64, userName string, groups []uploadGroup, source string, tagIDs []uint64, newTags []interfaces.TagInformation) (uint64, error) {
+	var uploadedIDs []uploadData
+	var lastID uint64
+	tagIDs = append([]uint64(nil), tagIDs...)
+	err := database.DBInterface.RunInTransaction(func(Transaction interfaces.DBInterface) error {
+		for _, tag := range newTags {
+			tagID, err := Transaction.NewTag(tag.Name, tag.Description, userID)
 			if err != nil {
-				errorCompilation += err.Error()
-				fileStream.Close()
-				continue
+				logging.WriteLog(logging.LogLevelError, "imagerouter/commitUploads", userName, logging.ResultFailure, []string{"error attempting to create tag", err.Error(), tag.Name})
+				return err
 			}
+			tagIDs = append(tagIDs, tagID)
+		}
 
-			filePath := path.Join(config.Configuration.ImageDirectory, hashName)
-			//Check if file exists, if so, skip
-			if _, err := os.Stat(filePath); err == nil {
-				var duplicateID uint64
-				dupInfo, ierr := database.DBInterface.GetImageByFileName(hashName)
-				if ierr == nil {
-					duplicateID = dupInfo.ID
+		for _, group := range groups {
+			var groupIDs []uploadData
+			for _, upload := range group.Uploads {
+				imageID, err := Transaction.NewImage(upload.HashName, upload.Location, userID, source)
+				if err != nil {
+					logging.WriteLog(logging.LogLevelError, "imagerouter/commitUploads", userName, logging.ResultFailure, []string{"error attempting to add file to database", err.Error(), upload.Location})
+					return err
 				}
-				logging.WriteLog(logging.LogLevelInfo, "imagerouter/handleImageUpload", userName, logging.ResultInfo, []string{"Skipping as file is already uploaded", fileHeader.Filename, filePath, strconv.FormatUint(duplicateID, 10)})
-				if ierr == nil {
-					//errorCompilation += fileHeader.Filename + " has already been uploaded as ID " + strconv.FormatUint(duplicateID, 10) + ". "
-					duplicateIDs[fileHeader.Filename] = duplicateID
-				} else {
-					errorCompilation += fileHeader.Filename + " has already been uploaded. "
+				if upload.Metadata != nil {
+					metadata := *upload.Metadata
+					metadata.ImageID = imageID
+					if err := Transaction.SetImageMetadata(metadata); err != nil {
+						logging.WriteLog(logging.LogLevelError, "imagerouter/commitUploads", userName, logging.ResultFailure, []string{"failed to add metadata", err.Error(), strconv.FormatUint(imageID, 10)})
+						return err
+					}
 				}
-				fileStream.Close()
-				continue
+				if len(tagIDs) > 0 {
+					if err := Transaction.AddTag(tagIDs, imageID, userID); err != nil {
+						logging.WriteLog(logging.LogLevelError, "imagerouter/commitUploads", userName, logging.ResultFailure, []string{"failed to add tags", err.Error(), strconv.FormatUint(imageID, 10)})
+						return err
+					}
+				}
+				groupIDs = append(groupIDs, uploadData{Name: upload.Name, ID: imageID, Location: upload.Location})
+				lastID = imageID
 			}
+			uploadedIDs = append(uploadedIDs, groupIDs...)
 
-			saveStream, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE, 0660)
-			if err != nil {
-				logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userName, logging.ResultFailure, []string{"Upload image, failed to open new file", err.Error()})
-				errorCompilation += fileHeader.Filename + " could not be saved, internal error. "
-				saveStream.Close()
-				fileStream.Close()
+			//Now handle collection if requested
+			if group.CollectionName == "" || len(groupIDs) == 0 {
 				continue
 			}
-			//Save Image
-			_, err = fileStream.Seek(0, 0)
-			if err != nil {
-				logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userName, logging.ResultFailure, []string{"Upload image, failed to seek stream", err.Error()})
-				errorCompilation += fileHeader.Filename + " could not be saved, internal error. "
-				saveStream.Close()
-				fileStream.Close()
-				continue
+			collectionID := group.CollectionID
+			if collectionID == 0 {
+				var err error
+				collectionID, err = Transaction.NewCollection(group.CollectionName, "", userID)
+				if err != nil {
+					logging.WriteLog(logging.LogLevelError, "imagerouter/commitUploads", userName, logging.ResultFailure, []string{"error attempting to create collection", err.Error()})
+					return err
+				}
 			}
-			io.Copy(saveStream, fileStream)
-			saveStream.Close()
-			//Add image to Database
-
-			lastID, err = database.DBInterface.NewImage(hashName, hashName, userID, source)
-			if err != nil {
-				logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userName, logging.ResultFailure, []string{"error attempting to add file to database", err.Error(), filePath})
-				errorCompilation += fileHeader.Filename + " could not be added to database, internal error. "
-				//Attempt to cleanup file
-				if err := os.Remove(filePath); err != nil {
-					logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userName, logging.ResultFailure, []string{"error attempting to remove orphaned file", err.Error(), filePath})
+			//Sort uploads by name, so page2 comes before page10
+			sort.SliceStable(groupIDs, func(i, j int) bool {
+				return media.NaturalLess(groupIDs[i].Name, groupIDs[j].Name)
+			})
+			var ids []uint64
+			for _, v := range groupIDs {
+				ids = append(ids, v.ID)
+			}
+			if err := Transaction.AddCollectionMember(collectionID, ids, userID); err != nil {
+				logging.WriteLog(logging.LogLevelError, "imagerouter/commitUploads", userName, logging.ResultFailure, []string{"error adding image to collection", err.Error()})
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		//Nothing refers to the saved files now
+		for _, group := range groups {
+			for _, upload := range group.Uploads {
+				if err := storage.StorageInterface.Remove(upload.Location); err != nil {
+					logging.WriteLog(logging.LogLevelError, "imagerouter/commitUploads", userName, logging.ResultFailure, []string{"error attempting to remove orphaned file", err.Error(), upload.Location})
 				}
-				continue
 			}
+		}
+		go WriteAuditLog(userID, "IMAGE-UPLOAD", userName+" failed to upload images. "+err.Error())
+		return 0, err
+	}
 
-			uploadedIDs = append(uploadedIDs, uploadData{Name: originalName, ID: lastID})
-
-			//Add tags
-			if err := database.DBInterface.AddTag(validatedUserTags, lastID, userID); err != nil {
-				logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userName, logging.ResultFailure, []string{"failed to add tags", err.Error(), strconv.FormatUint(lastID, 10)})
-				errorCompilation += "Failed to add tags to " + fileHeader.Filename + ". "
-			} else {
+	for _, tag := range newTags {
+		go WriteAuditLog(userID, "CREATE-TAG", userName+" created a new tag. "+tag.Name)
+	}
+	tagIDString := ""
+	for _, tagID := range tagIDs {
+		tagIDString = tagIDString + ", " + strconv.FormatUint(tagID, 10)
+	}
+	for _, uploaded := range uploadedIDs {
+		if len(tagIDs) > 0 {
+			go WriteAuditLog(userID, "IMAGE-UPLOAD", userName+" tagged image "+strconv.FormatUint(uploaded.ID, 10)+" with "+tagIDString)
+		}
+		//Log success
+		go WriteAuditLog(userID, "IMAGE-UPLOAD", userName+" successfully uploaded an image. "+strconv.FormatUint(uploaded.ID, 10))
+		//Queue generating the thumbnail
+		processInBackground(uploaded.Location, uploaded.ID)
+	}
+	return lastID, nil
+}
 
-				go WriteAuditLog(userID, "IMAGE-UPLOAD", userName+" tagged image "+strconv.FormatUint(lastID, 10)+" with "+tagIDString)
-			}
+//checkUploadCollection checks the user may add uploads to the collection named, or create it if it does not exist
+//Returns the collection, which has an ID of 0 if it needs creating or no collection was named
+func checkUploadCollection(userID uint64, userName string, userPermission interfaces.UserPermission, collectionName string) (interfaces.CollectionInformation, error) {
+	if collectionName == "" {
+		return interfaces.CollectionInformation{}, nil
+	}
+	collectionInfo, err := database.DBInterface.GetCollectionByName(collectionName)
+	if err != nil {
+		//Want to add to collection, but the collection does not exist, so validate permissions to create collections
+		if userPermission.HasPermission(interfaces.AddCollections) != true {
+			go WriteAuditLog(userID, "IMAGE-UPLOAD", userName+" failed to upload image. No permissions to create collection.")
+			return interfaces.CollectionInformation{}, errors.New("User does not have create permission for collections")
+		}
+		return interfaces.CollectionInformation{}, nil
+	}
+	//Want to add to a pre-existing collection, validate permissions on the pre-existing collection
+	if userPermission.HasPermission(interfaces.ModifyCollections) != true &&
+		(config.Configuration.UsersControlOwnObjects && collectionInfo.UploaderID != userID) {
+		go WriteAuditLog(userID, "IMAGE-UPLOAD", userName+" failed to upload image. No permissions to add members to collection.")
+		return interfaces.CollectionInformation{}, errors.New("User does not have permission to update requested collection")
+	}
+	return collectionInfo, nil
+}
 
-			//Log success
-			go WriteAuditLog(userID, "IMAGE-UPLOAD", userName+" successfully uploaded an image. "+strconv.FormatUint(lastID, 10))
-			//Start go routine to generate thumbnail
-			go GenerateThumbnail(hashName)
-			go GeneratedHash(hashName, lastID)
+//saveUpload recognizes and saves one uploaded file, returning false if it was not saved
+//Duplicates of earlier uploads are recorded in duplicateIDs, and other problems are added to errorCompilation
+func saveUpload(userName string, originalName string, fileStream io.ReadSeeker, duplicateIDs map[string]uint64, errorCompilation *string) (pendingUpload, bool) {
+	//Recognize the file by its content, as the name may be wrong
+	mediaType, content, metadata, err := PrepareUpload(originalName, fileStream)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelVerbose, "imagerouter/saveUpload", userName, logging.ResultFailure, []string{"Attempted to upload a file which did not pass filter", err.Error()})
+		*errorCompilation += err.Error()
+		return pendingUpload{}, false
+	}
+	//Hash Image, named with the extension of its type
+	hashName, err := GetNewImageName(mediaType.WithExtension(originalName), content)
+	if err != nil {
+		*errorCompilation += err.Error()
+		return pendingUpload{}, false
+	}
+	imageLocation := storage.ImageLocation(hashName)
+
+	//Check if file exists, if so, skip
+	if exists, err := storage.Exists(imageLocation); err != nil {
+		logging.WriteLog(logging.LogLevelError, "imagerouter/saveUpload", userName, logging.ResultFailure, []string{"Upload image, failed to check for existing file", err.Error()})
+		*errorCompilation += originalName + " could not be saved, internal error. "
+		return pendingUpload{}, false
+	} else if exists {
+		var duplicateID uint64
+		dupInfo, ierr := database.DBInterface.GetImageByFileName(imageLocation)
+		if ierr == nil {
+			duplicateID = dupInfo.ID
 		}
-		fileStream.Close()
+		logging.WriteLog(logging.LogLevelInfo, "imagerouter/saveUpload", userName, logging.ResultInfo, []string{"Skipping as file is already uploaded", originalName, imageLocation, strconv.FormatUint(duplicateID, 10)})
+		if ierr == nil {
+			duplicateIDs[originalName] = duplicateID
+		} else {
+			*errorCompilation += originalName + " has already been uploaded. "
+		}
+		return pendingUpload{}, false
 	}
-	//Now handle collection if requested
 
-	if collectionName != "" {
-		if collectionInfo.ID == 0 {
-			collectionInfo.ID, err = database.DBInterface.NewCollection(collectionName, "", userID)
-			if err != nil {
-				errorCompilation += "Failed to create the collection requested, SQL error. "
-				logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userName, logging.ResultFailure, []string{"error attempting to create collection", err.Error()})
+	//Save Image
+	_, err = content.Seek(0, 0)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "imagerouter/saveUpload", userName, logging.ResultFailure, []string{"Upload image, failed to seek stream", err.Error()})
+		*errorCompilation += originalName + " could not be saved, internal error. "
+		return pendingUpload{}, false
+	}
+	if err := storage.StorageInterface.Save(imageLocation, content); err != nil {
+		logging.WriteLog(logging.LogLevelError, "imagerouter/saveUpload", userName, logging.ResultFailure, []string{"Upload image, failed to save file", err.Error()})
+		*errorCompilation += originalName + " could not be saved, internal error. "
+		return pendingUpload{}, false
+	}
+	return pendingUpload{Name: originalName, HashName: hashName, Location: imageLocation, Metadata: metadata}, true
+}
+
+//archiveMaxRatio is how many times its compressed size a file in an uploaded archive may unpack to
+const archiveMaxRatio = 100
+
+//addArchiveUploads unpacks a zip or cbz upload and saves each file in it, returning groups with them added
+//The files go into the collection of the first group if one was requested, otherwise into a collection named after the archive
+func addArchiveUploads(groups []uploadGroup, userID uint64, userName string, userPermission interfaces.UserPermission, archiveName string, archive io.ReaderAt, size int64, duplicateIDs map[string]uint64, errorCompilation *string) []uploadGroup {
+	entries, err := media.ReadZip(archive, size, media.ZipLimits{MaxEntries: config.Configuration.MaxArchiveEntries, MaxBytes: config.Configuration.MaxArchiveBytes, MaxRatio: archiveMaxRatio})
+	if err != nil {
+		logging.WriteLog(logging.LogLevelWarning, "imagerouter/addArchiveUploads", userName, logging.ResultFailure, []string{"Failed to unpack archive", archiveName, err.Error()})
+		*errorCompilation += archiveName + " could not be unpacked, " + err.Error() + ". "
+		return groups
+	}
+	index := 0
+	if groups[0].CollectionName == "" {
+		collectionName := archiveCollectionName(archiveName)
+		index = -1
+		for i, group := range groups {
+			if group.CollectionName == collectionName {
+				index = i
 			}
 		}
-		//If we had an error creating collection, this would still be 0, otherwise would have value or if collection already existed, would still have value other than 0
-		if collectionInfo.ID != 0 {
-			//Sort uploads by name
-			sort.Slice(uploadedIDs, func(i, j int) bool {
-				return uploadedIDs[i].Name < uploadedIDs[j].Name
-			})
-			var ids []uint64
-			for _, v := range uploadedIDs {
-				ids = append(ids, v.ID)
-			}
-			err = database.DBInterface.AddCollectionMember(collectionInfo.ID, ids, userID)
+		if index == -1 {
+			collectionInfo, err := checkUploadCollection(userID, userName, userPermission, collectionName)
 			if err != nil {
-				errorCompilation += "Failed to add images to collection. "
-				logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userName, logging.ResultFailure, []string{"error adding image to collection", err.Error()})
+				*errorCompilation += archiveName + " was not uploaded, " + err.Error() + ". "
+				return groups
 			}
+			groups = append(groups, uploadGroup{CollectionName: collectionName, CollectionID: collectionInfo.ID})
+			index = len(groups) - 1
+		}
+	}
+	for _, entry := range entries {
+		//Named with the archive so the files of different archives do not mix in the list of duplicates
+		if upload, saved := saveUpload(userName, path.Join(archiveName, entry.Name), bytes.NewReader(entry.Data), duplicateIDs, errorCompilation); saved {
+			groups[index].Uploads = append(groups[index].Uploads, upload)
 		}
 	}
+	return groups
+}
 
-	if errorCompilation != "" {
-		return lastID, duplicateIDs, errors.New(errorCompilation)
+//archiveCollectionName returns the name of the collection for the files of an archive, being the archive's name without its extension
+func archiveCollectionName(archiveName string) string {
+	name := strings.TrimSpace(path.Base(strings.ReplaceAll(archiveName, "\\", "/")))
+	if stem := strings.TrimSpace(strings.TrimSuffix(name, path.Ext(name))); len(stem) >= 3 {
+		name = stem
 	}
-	return lastID, duplicateIDs, nil
+	//Collection names are limited to 255 characters, cut without splitting a character
+	for len(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
 }
 
 //UploadingFile contains information on the Name and Data of a file to be uploaded
@@ -264,23 +401,9 @@ func HandleImageUploadRequest(request *http.Request, userInformation interfaces.
 	}
 
 	//CacheCollectionInfo if needed and verify permissions to create or update the collection
-	var collectionInfo interfaces.CollectionInformation
-	if collectionName != "" {
-		collectionInfo, err = database.DBInterface.GetCollectionByName(collectionName)
-		if err != nil {
-			//Want to add to collection, but the collection does not exist, so validate permissions to create collections
-			if interfaces.UserPermission(userPermission).HasPermission(interfaces.AddCollections) != true {
-				go WriteAuditLog(userInformation.ID, "IMAGE-UPLOAD", userInformation.Name+" failed to upload image. No permissions to create collection.")
-				return 0, nil, errors.New("User does not have create permission for collections")
-			}
-		} else {
-			//Want to add to a pre-existing collection, validate permissions on the pre-existing collection
-			if interfaces.UserPermission(userPermission).HasPermission(interfaces.ModifyCollections) != true &&
-				(config.Configuration.UsersControlOwnObjects && collectionInfo.UploaderID != userInformation.ID) {
-				go WriteAuditLog(userInformation.ID, "IMAGE-UPLOAD", userInformation.Name+" failed to upload image. No permissions to add members to collection.")
-				return 0, nil, errors.New("User does not have permission to update requested collection")
-			}
-		}
+	collectionInfo, err := checkUploadCollection(userInformation.ID, userInformation.Name, interfaces.UserPermission(userPermission), collectionName)
+	if err != nil {
+		return 0, nil, err
 	}
 	// /ValidatePermission
 
@@ -289,8 +412,8 @@ func HandleImageUploadRequest(request *http.Request, userInformation interfaces.
 
 	//Cache tags first, improves speed to calculate this once than for each image
 	//Get tags
-	var validatedUserTags []uint64 //Will contain tags the user is allowed to use
-	tagIDString := ""
+	var validatedUserTags []uint64          //Will contain tags the user is allowed to use
+	var newTags []interfaces.TagInformation //Will contain tags the user is allowed to create
 	userQTags, err := database.DBInterface.GetQueryTags(imageTags, false)
 	if err != nil {
 		errorCompilation += "Failed to get tags from input. "
@@ -305,7 +428,6 @@ func HandleImageUploadRequest(request *http.Request, userInformation interfaces.
 				// /ValidatePermission
 			} else {
 				validatedUserTags = append(validatedUserTags, tag.ID)
-				tagIDString = tagIDString + ", " + strconv.FormatUint(tag.ID, 10)
 			}
 		} else if tag.IsMeta == false {
 			//Create Tag
@@ -315,141 +437,147 @@ func HandleImageUploadRequest(request *http.Request, userInformation interfaces.
 				errorCompilation += "Unable to use tag " + tag.Name + " due to insufficient permissions of user to create tags. "
 				// /ValidatePermission
 			} else {
-				tagID, err := database.DBInterface.NewTag(tag.Name, tag.Description, userInformation.ID)
-				if err != nil {
-					logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userInformation.Name, logging.ResultFailure, []string{"error attempting to create tag", err.Error(), tag.Name})
-					errorCompilation += "Unable to use tag " + tag.Name + " due to a database error. "
-				} else {
-					go WriteAuditLog(userInformation.ID, "CREATE-TAG", userInformation.Name+" created a new tag. "+tag.Name)
-					validatedUserTags = append(validatedUserTags, tagID)
-					tagIDString = tagIDString + ", " + strconv.FormatUint(tagID, 10)
-				}
+				newTags = append(newTags, tag) //Created along with the images
 			}
 		}
 	}
 
-	var lastID uint64
-	var uploadedIDs []uploadData
+	groups := []uploadGroup{{CollectionName: collectionName, CollectionID: collectionInfo.ID}}
 	for _, toUpload := range files {
-		switch ext := strings.ToLower(filepath.Ext(toUpload.Name)); ext {
-		case ".jpg", ".jpeg", ".jfif", ".bmp", ".gif", ".png", ".svg", ".mpg", ".mov", ".webm", ".avi", ".mp4", ".mp3", ".ogg", ".wav", ".webp", ".tiff", ".tif":
-			//Passes filter
-		default:
-			logging.WriteLog(logging.LogLevelVerbose, "imagerouter/handleImageUpload", userInformation.Name, logging.ResultFailure, []string{"Attempted to upload a file which did not pass filter", ext})
-			errorCompilation += toUpload.Name + " is not a recognized file. "
-			continue
-		}
 		fileStream := bytes.NewReader(toUpload.Data)
-		if err != nil {
-			logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userInformation.Name, logging.ResultFailure, []string{"Upload image, could not open stream to save", err.Error()})
-			errorCompilation += toUpload.Name + " could not be opened. "
-		} else {
-			originalName := toUpload.Name
-			//Hash Image
-			hashName, err := GetNewImageName(originalName, fileStream)
-			if err != nil {
-				errorCompilation += err.Error()
-				continue
-			}
-
-			filePath := path.Join(config.Configuration.ImageDirectory, hashName)
-			//Check if file exists, if so, skip
-			if _, err := os.Stat(filePath); err == nil {
-				var duplicateID uint64
-				dupInfo, ierr := database.DBInterface.GetImageByFileName(hashName)
-				if ierr == nil {
-					duplicateID = dupInfo.ID
-				}
-				logging.WriteLog(logging.LogLevelInfo, "imagerouter/handleImageUpload", userInformation.Name, logging.ResultInfo, []string{"Skipping as file is already uploaded", toUpload.Name, filePath, strconv.FormatUint(duplicateID, 10)})
-				if ierr == nil {
-					//errorCompilation += fileHeader.Filename + " has already been uploaded as ID " + strconv.FormatUint(duplicateID, 10) + ". "
-					duplicateIDs[toUpload.Name] = duplicateID
-				} else {
-					errorCompilation += toUpload.Name + " has already been uploaded. "
-				}
-				continue
-			}
-
-			saveStream, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE, 0660)
-			if err != nil {
-				logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userInformation.Name, logging.ResultFailure, []string{"Upload image, failed to open new file", err.Error()})
-				errorCompilation += toUpload.Name + " could not be saved, internal error. "
-				saveStream.Close()
-				continue
-			}
-			//Save Image
-			_, err = fileStream.Seek(0, 0)
-			if err != nil {
-				logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userInformation.Name, logging.ResultFailure, []string{"Upload image, failed to seek stream", err.Error()})
-				errorCompilation += toUpload.Name + " could not be saved, internal error. "
-				saveStream.Close()
-				continue
-			}
-			io.Copy(saveStream, fileStream)
-			saveStream.Close()
-			//Add image to Database
+		if media.IsZip(toUpload.Data) {
+			groups = addArchiveUploads(groups, userInformation.ID, userInformation.Name, interfaces.UserPermission(userPermission), toUpload.Name, fileStream, fileStream.Size(), duplicateIDs, &errorCompilation)
+		} else if upload, saved := saveUpload(userInformation.Name, toUpload.Name, fileStream, duplicateIDs, &errorCompilation); saved {
+			groups[0].Uploads = append(groups[0].Uploads, upload)
+		}
+	}
 
-			lastID, err = database.DBInterface.NewImage(hashName, hashName, userInformation.ID, source)
-			if err != nil {
-				logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userInformation.Name, logging.ResultFailure, []string{"error attempting to add file to database", err.Error(), filePath})
-				errorCompilation += toUpload.Name + " could not be added to database, internal error. "
-				//Attempt to cleanup file
-				if err := os.Remove(filePath); err != nil {
-					logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userInformation.Name, logging.ResultFailure, []string{"error attempting to remove orphaned file", err.Error(), filePath})
-				}
-				continue
-			}
+	//Now add everything to the database together
+	lastID, err := commitUploads(userInformation.ID, userInformation.Name, groups, source, validatedUserTags, newTags)
+	if err != nil {
+		errorCompilation += "Failed to add the upload to the database, nothing was uploaded. "
+	}
 
-			uploadedIDs = append(uploadedIDs, uploadData{Name: originalName, ID: lastID})
+	if errorCompilation != "" {
+		return lastID, duplicateIDs, errors.New(errorCompilation)
+	}
+	return lastID, duplicateIDs, nil
+}
 
-			//Add tags
-			if err := database.DBInterface.AddTag(validatedUserTags, lastID, userInformation.ID); err != nil {
-				logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userInformation.Name, logging.ResultFailure, []string{"failed to add tags", err.Error(), strconv.FormatUint(lastID, 10)})
-				errorCompilation += "Failed to add tags to " + toUpload.Name + ". "
-			} else {
+//PrepareUpload recognizes an upload by its content, and checks files of that type may be uploaded
+//Returns the content to store, which for some types is rewritten, and the upload's camera details if it has any
+func PrepareUpload(Name string, Stream io.ReadSeeker) (media.Type, io.ReadSeeker, *interfaces.ImageMetadata, error) {
+	mediaType, err := media.Sniff(Stream)
+	if err == media.ErrUnrecognized {
+		return mediaType, nil, nil, errors.New(Name + " is not a recognized file. ")
+	} else if err != nil {
+		logging.WriteLog(logging.LogLevelError, "imagerouter/PrepareUpload", "0", logging.ResultFailure, []string{"Failed to read upload", Name, err.Error()})
+		return mediaType, nil, nil, errors.New(Name + " could not be read. ")
+	}
+	if media.IsAllowed(mediaType) == false {
+		return mediaType, nil, nil, errors.New(Name + " is a " + mediaType.Name + " file, which may not be uploaded. ")
+	}
+	if mediaType.Name != "svg" && mediaType.HasMetadata() == false {
+		return mediaType, Stream, nil, nil
+	}
+	original, err := io.ReadAll(Stream)
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "imagerouter/PrepareUpload", "0", logging.ResultFailure, []string{"Failed to read upload", Name, err.Error()})
+		return mediaType, nil, nil, errors.New(Name + " could not be read. ")
+	}
+	//SVG is served from the board's own site, so it must not be able to run script there
+	if mediaType.Name == "svg" {
+		sanitized, err := media.SanitizeSVG(original)
+		if err != nil {
+			return mediaType, nil, nil, errors.New(Name + " is not a valid SVG file. ")
+		}
+		return mediaType, bytes.NewReader(sanitized), nil, nil
+	}
+	//The useful details are kept in the database, as they may be about to be removed from the file
+	var metadata *interfaces.ImageMetadata
+	if readMetadata, found := media.ReadMetadata(original, mediaType); found {
+		metadata = &readMetadata
+	}
+	if config.Configuration.StripImageMetadata {
+		stripped, err := media.StripMetadata(original, mediaType)
+		if err != nil {
+			logging.WriteLog(logging.LogLevelVerbose, "imagerouter/PrepareUpload", "0", logging.ResultFailure, []string{"Failed to remove metadata", Name, err.Error()})
+			return mediaType, nil, nil, errors.New(Name + " could not have its metadata removed. ")
+		}
+		return mediaType, bytes.NewReader(stripped), metadata, nil
+	}
+	return mediaType, bytes.NewReader(original), metadata, nil
+}
 
-				go WriteAuditLog(userInformation.ID, "IMAGE-UPLOAD", userInformation.Name+" tagged image "+strconv.FormatUint(lastID, 10)+" with "+tagIDString)
-			}
+//processInBackground queues generating the thumbnail, preview, dHash, and colours of a new image, so the upload is not held up and failures are retried
+func processInBackground(Location string, ImageID uint64) {
+	if _, err := jobs.Enqueue(jobs.ProcessImage, strconv.FormatUint(ImageID, 10)); err != nil {
+		logging.WriteLog(logging.LogLevelError, "imagerouter/processInBackground", "0", logging.ResultFailure, []string{"Failed to queue processing of image", Location, err.Error()})
+	}
+}
 
-			//Log success
-			go WriteAuditLog(userInformation.ID, "IMAGE-UPLOAD", userInformation.Name+" successfully uploaded an image. "+strconv.FormatUint(lastID, 10))
-			//Start go routine to generate thumbnail
-			go GenerateThumbnail(hashName)
-			go GeneratedHash(hashName, lastID)
+//ProcessImageJob generates the thumbnail, preview, dHash, colours, and media info of the image whose ID is the job's payload, then queues transcoding it if browsers cannot play it
+func ProcessImageJob(Job interfaces.JobInformation, Progress jobs.ProgressFunc) error {
+	ImageID, err := strconv.ParseUint(Job.Payload, 10, 64)
+	if err != nil {
+		return err
+	}
+	imageInfo, err := database.DBInterface.GetImage(ImageID)
+	if err == sql.ErrNoRows {
+		return nil //Image was removed before it could be processed
+	} else if err != nil {
+		return err
+	}
+	if CanGenerateThumbnail(imageInfo.Location) {
+		if err := GenerateThumbnail(imageInfo.Location); err == media.ErrSVGTooComplex {
+			//Trying again would not help, so the image keeps the generic icon
+			logging.WriteLog(logging.LogLevelWarning, "imagerouter/ProcessImageJob", "0", logging.ResultFailure, []string{"Not drawing thumbnail", imageInfo.Location, err.Error()})
+		} else if err != nil {
+			return err
 		}
 	}
-	//Now handle collection if requested
-
-	if collectionName != "" {
-		if collectionInfo.ID == 0 {
-			collectionInfo.ID, err = database.DBInterface.NewCollection(collectionName, "", userInformation.ID)
-			if err != nil {
-				errorCompilation += "Failed to create the collection requested, SQL error. "
-				logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userInformation.Name, logging.ResultFailure, []string{"error attempting to create collection", err.Error()})
-			}
+	Progress(1, 4)
+	if CanGeneratePreview(imageInfo.Location) {
+		if err := GeneratePreview(imageInfo.Location); err != nil {
+			return err
 		}
-		//If we had an error creating collection, this would still be 0, otherwise would have value or if collection already existed, would still have value other than 0
-		if collectionInfo.ID != 0 {
-			//Sort uploads by name
-			sort.Slice(uploadedIDs, func(i, j int) bool {
-				return uploadedIDs[i].Name < uploadedIDs[j].Name
-			})
-			var ids []uint64
-			for _, v := range uploadedIDs {
-				ids = append(ids, v.ID)
-			}
-			err = database.DBInterface.AddCollectionMember(collectionInfo.ID, ids, userInformation.ID)
-			if err != nil {
-				errorCompilation += "Failed to add images to collection. "
-				logging.WriteLog(logging.LogLevelError, "imagerouter/handleImageUpload", userInformation.Name, logging.ResultFailure, []string{"error adding image to collection", err.Error()})
-			}
+	}
+	Progress(2, 4)
+	if CanGeneratedHash(imageInfo.Location) {
+		if err := GeneratedHash(imageInfo.Location, ImageID); err != nil {
+			return err
+		}
+	}
+	Progress(3, 4)
+	if err := GenerateMediaInfo(imageInfo.Location, ImageID); err != nil {
+		return err
+	}
+	Progress(4, 4)
+	//Transcoding takes far longer than the rest, so it is left to its own job
+	if CanTranscode(imageInfo.Location) {
+		if _, err := jobs.Enqueue(jobs.TranscodeVideo, Job.Payload); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	if errorCompilation != "" {
-		return lastID, duplicateIDs, errors.New(errorCompilation)
+//TranscodeVideoJob makes a version browsers can play of the video whose ID is the job's payload
+func TranscodeVideoJob(Job interfaces.JobInformation, Progress jobs.ProgressFunc) error {
+	ImageID, err := strconv.ParseUint(Job.Payload, 10, 64)
+	if err != nil {
+		return err
 	}
-	return lastID, duplicateIDs, nil
+	imageInfo, err := database.DBInterface.GetImage(ImageID)
+	if err == sql.ErrNoRows {
+		return nil //Image was removed before it could be transcoded
+	} else if err != nil {
+		return err
+	}
+	if CanTranscode(imageInfo.Location) == false {
+		return nil //FFMPEG has been turned off, or browsers can play the video as it is
+	}
+	return TranscodeVideo(imageInfo.Location, imageInfo.Duration, Progress)
 }
 
 //GetNewImageName uses the original filename and file contents to create a new name
@@ -466,5 +594,6 @@ func GetNewImageName(originalName string, fileStream io.Reader) (string, error)
 //UploadFormRouter shows the upload form upon request
 func UploadFormRouter(responseWriter http.ResponseWriter, request *http.Request) {
 	TemplateInput := getNewTemplateInput(responseWriter, request)
+	TemplateInput.SuggestAudioTags = config.Configuration.SuggestAudioTags
 	replyWithTemplate("uploadform.html", TemplateInput, responseWriter, request)
 }
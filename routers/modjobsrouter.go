@@ -0,0 +1,119 @@
+package routers
+
+import (
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/jobs"
+	"go-image-board/logging"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+//jobStatuses are the statuses counted on the modJobs page
+var jobStatuses = []string{interfaces.JobQueued, interfaces.JobRunning, interfaces.JobDone, interfaces.JobFailed}
+
+//ModJobsGetRouter serves get requests to /mod/jobs
+func ModJobsGetRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	TemplateInput := getTemplateInputFromRequest(responseWriter, request)
+	if TemplateInput.UserPermissions.HasPermission(interfaces.RunMaintenance) != true {
+		replyWithTemplate("modJobs.html", TemplateInput, responseWriter, request)
+		return
+	}
+
+	TemplateInput.JobStatus = request.FormValue("status")
+	pageStart, _ := strconv.ParseUint(request.FormValue("PageStart"), 10, 64) // Defaults to 0 on error, which is fine
+	pageStride := config.Configuration.PageStride
+
+	var err error
+	TemplateInput.JobList, TemplateInput.TotalResults, err = database.DBInterface.GetJobs(TemplateInput.JobStatus, pageStart, pageStride)
+	if err != nil {
+		TemplateInput.HTMLMessage += template.HTML("Error pulling jobs.<br>")
+		logging.WriteLog(logging.LogLevelError, "modjobsrouter/ModJobsGetRouter", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to pull jobs", err.Error()})
+	}
+	TemplateInput.JobCounts = make(map[string]uint64)
+	for _, status := range jobStatuses {
+		if _, count, err := database.DBInterface.GetJobs(status, 0, 1); err == nil {
+			TemplateInput.JobCounts[status] = count
+		}
+	}
+	TemplateInput.PageMenu, _ = generatePageMenu(int64(pageStart), int64(pageStride), int64(TemplateInput.TotalResults), "status="+url.QueryEscape(TemplateInput.JobStatus), "/mod/jobs")
+
+	replyWithTemplate("modJobs.html", TemplateInput, responseWriter, request)
+}
+
+//ModJobsPostRouter serves post requests to /mod/jobs
+func ModJobsPostRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	TemplateInput := getTemplateInputFromRequest(responseWriter, request)
+
+	//Check if logged in
+	if TemplateInput.UserInformation.ID == 0 {
+		TemplateInput.HTMLMessage += template.HTML("You must be logged in to perform that action.<br>")
+		redirectWithFlash(responseWriter, request, "/logon", TemplateInput.HTMLMessage, "LogonRequired")
+		return
+	}
+	//Check if has permissions
+	if TemplateInput.UserPermissions.HasPermission(interfaces.RunMaintenance) != true {
+		TemplateInput.HTMLMessage += template.HTML("User does not have permission to run maintenance.<br>")
+		go WriteAuditLog(TemplateInput.UserInformation.ID, "RUN-JOB", TemplateInput.UserInformation.Name+" failed to manage jobs, insufficient permissions.")
+		redirectWithFlash(responseWriter, request, "/mod/jobs", TemplateInput.HTMLMessage, "ModFailed")
+		return
+	}
+
+	//Get Command
+	switch cmd := request.FormValue("command"); cmd {
+	case "runJob":
+		jobType := request.FormValue("type")
+		if jobs.HasHandler(jobType) == false {
+			TemplateInput.HTMLMessage += template.HTML("Job type not recognized.<br>")
+			redirectWithFlash(responseWriter, request, "/mod/jobs", TemplateInput.HTMLMessage, "ModFailed")
+			return
+		}
+		queueJob(responseWriter, request, TemplateInput, jobType, request.FormValue("payload"))
+		return
+	case "rerunJob":
+		jobID, err := strconv.ParseUint(request.FormValue("jobID"), 10, 64)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to parse job ID.<br>")
+			redirectWithFlash(responseWriter, request, "/mod/jobs", TemplateInput.HTMLMessage, "ModFailed")
+			return
+		}
+		job, err := database.DBInterface.GetJob(jobID)
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to find job.<br>")
+			redirectWithFlash(responseWriter, request, "/mod/jobs", TemplateInput.HTMLMessage, "ModFailed")
+			return
+		}
+		queueJob(responseWriter, request, TemplateInput, job.Type, job.Payload)
+		return
+	case "clearFinished":
+		removed, err := database.DBInterface.RemoveJobs(interfaces.JobDone, time.Duration(0))
+		if err != nil {
+			TemplateInput.HTMLMessage += template.HTML("Failed to remove finished jobs.<br>")
+			redirectWithFlash(responseWriter, request, "/mod/jobs", TemplateInput.HTMLMessage, "ModFailed")
+			return
+		}
+		TemplateInput.HTMLMessage += template.HTML("Removed " + strconv.FormatInt(removed, 10) + " finished jobs.<br>")
+		redirectWithFlash(responseWriter, request, "/mod/jobs", TemplateInput.HTMLMessage, "ModSucceeded")
+		return
+	}
+
+	TemplateInput.HTMLMessage += template.HTML("Command not recognized or provided.<br>")
+	redirectWithFlash(responseWriter, request, "/mod/jobs", TemplateInput.HTMLMessage, "ModFail")
+}
+
+//queueJob queues a job for ModJobsPostRouter, audits it, and redirects back to the job list
+func queueJob(responseWriter http.ResponseWriter, request *http.Request, TemplateInput templateInput, Type string, Payload string) {
+	jobID, err := jobs.Enqueue(Type, Payload)
+	if err != nil {
+		TemplateInput.HTMLMessage += template.HTML("Failed to queue job.<br>")
+		redirectWithFlash(responseWriter, request, "/mod/jobs", TemplateInput.HTMLMessage, "ModFailed")
+		return
+	}
+	go WriteAuditLog(TemplateInput.UserInformation.ID, "RUN-JOB", TemplateInput.UserInformation.Name+" queued job "+strconv.FormatUint(jobID, 10)+", "+Type+" "+Payload)
+	TemplateInput.HTMLMessage += template.HTML("Queued job " + strconv.FormatUint(jobID, 10) + ".<br>")
+	redirectWithFlash(responseWriter, request, "/mod/jobs", TemplateInput.HTMLMessage, "ModSucceeded")
+}
@@ -1,23 +1,35 @@
 package routers
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
 	"errors"
 	"go-image-board/config"
 	"go-image-board/database"
+	"go-image-board/interfaces"
 	"go-image-board/logging"
+	"go-image-board/media"
+	"go-image-board/storage"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"math"
 	"net/http"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/disintegration/imageorient"
 
-	//Because all image processing will happen in this file
-	_ "image/gif"
-	_ "image/jpeg"
+	"image/gif"
 	"image/png"
 
 	"github.com/gorilla/mux"
@@ -46,107 +58,488 @@ func RedirectRouter(responseWriter http.ResponseWriter, request *http.Request) {
 //ResourceImageRouter handles requests to /images/{file}
 func ResourceImageRouter(responseWriter http.ResponseWriter, request *http.Request) {
 	urlVariables := mux.Vars(request)
-	http.ServeFile(responseWriter, request, path.Join(config.Configuration.ImageDirectory, urlVariables["file"]))
+	//Quarantined files are kept for inspection, not for viewing
+	if storage.IsQuarantined(urlVariables["file"]) {
+		http.NotFound(responseWriter, request)
+		return
+	}
+	restrictActiveContent(responseWriter, urlVariables["file"])
+	if err := storage.ServeFile(responseWriter, request, urlVariables["file"]); err != nil {
+		http.NotFound(responseWriter, request)
+	}
+}
+
+//restrictActiveContent stops browsers running script or loading anything from SVG files opened directly
+//Uploads are sanitized, this also covers those from before they were
+func restrictActiveContent(responseWriter http.ResponseWriter, Name string) {
+	if mediaType, _ := media.ByName(Name); mediaType.Name == "svg" {
+		responseWriter.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; img-src data:; sandbox")
+	}
 }
 
 //ThumbnailRouter handls requests to /thumbs
 func ThumbnailRouter(responseWriter http.ResponseWriter, request *http.Request) {
 	urlVariables := mux.Vars(request)
-	thumbnailPath := path.Join(config.Configuration.ImageDirectory, "thumbs"+string(filepath.Separator)+urlVariables["file"]+".png")
-	//Check if file does not exist
-	if _, err := os.Stat(thumbnailPath); err != nil {
-		switch ext := filepath.Ext(strings.ToLower(urlVariables["file"])); ext {
-		//If it does not, and it is an image, return the original image, more bandwidth but better looking site
-		case ".jpg", ".jpeg", ".bmp", ".gif", ".png", ".svg", ".webp", ".tiff", ".tif", ".jfif":
-			thumbnailPath = path.Join(config.Configuration.ImageDirectory, string(filepath.Separator)+urlVariables["file"])
-		//If a video or music file, pull up a play icon
-		case ".mpg", ".mov", ".webm", ".avi", ".mp4", ".mp3", ".ogg", ".wav":
-			thumbnailPath = path.Join(config.Configuration.HTTPRoot, "resources"+string(filepath.Separator)+"playicon.svg")
+	//Serve the thumbnail if it exists
+	if err := storage.ServeFile(responseWriter, request, storage.ThumbnailName(urlVariables["file"])); err == nil {
+		return
+	}
+	serveThumbnailFallback(responseWriter, request, urlVariables["file"])
+}
+
+//SizedThumbnailRouter handles requests to /thumbs/{size}/{file}, making the thumbnail the first time it is requested
+func SizedThumbnailRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	urlVariables := mux.Vars(request)
+	size, ok := config.Configuration.ThumbnailSizes[urlVariables["size"]]
+	if ok == false {
+		http.NotFound(responseWriter, request)
+		return
+	}
+	thumbnailName := storage.SizedThumbnailName(urlVariables["file"], size, config.Configuration.ThumbnailFormat)
+	if err := storage.ServeFile(responseWriter, request, thumbnailName); err == nil {
+		return
+	}
+	//Only make thumbnails for images on the board, not for any name that is asked for
+	if _, err := database.DBInterface.GetImageByFileName(urlVariables["file"]); err == nil && CanGenerateThumbnail(urlVariables["file"]) {
+		err := generateThumbnailOnce(thumbnailName, func() error {
+			return GenerateSizedThumbnail(urlVariables["file"], size)
+		})
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "resourcesrouters/SizedThumbnailRouter", "0", logging.ResultFailure, []string{"Failed to generate thumbnail", thumbnailName, err.Error()})
+		} else if err := storage.ServeFile(responseWriter, request, thumbnailName); err == nil {
+			return
 		}
 	}
-	//Final fallback, just return a no image type icon
-	if _, err := os.Stat(thumbnailPath); err != nil {
-		thumbnailPath = path.Join(config.Configuration.HTTPRoot, "resources"+string(filepath.Separator)+"noicon.svg")
+	serveThumbnailFallback(responseWriter, request, urlVariables["file"])
+}
+
+//PreviewRouter handles requests to /previews/{file}, serving the animated preview of a GIF or video, made the first time it is requested
+func PreviewRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	urlVariables := mux.Vars(request)
+	previewName := storage.PreviewName(urlVariables["file"])
+	if err := storage.ServeFile(responseWriter, request, previewName); err == nil {
+		return
+	}
+	//Only make previews for images on the board, not for any name that is asked for
+	if _, err := database.DBInterface.GetImageByFileName(urlVariables["file"]); err == nil && CanGeneratePreview(urlVariables["file"]) {
+		err := generateThumbnailOnce(previewName, func() error {
+			return GeneratePreview(urlVariables["file"])
+		})
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "resourcesrouters/PreviewRouter", "0", logging.ResultFailure, []string{"Failed to generate preview", previewName, err.Error()})
+		} else if err := storage.ServeFile(responseWriter, request, previewName); err == nil {
+			return
+		}
 	}
+	//Pages keep showing the still thumbnail
+	http.NotFound(responseWriter, request)
+}
 
-	http.ServeFile(responseWriter, request, thumbnailPath)
+//TranscodeRouter handles requests to /transcodes/{file}, serving the version of a video transcoded so browsers can play it
+func TranscodeRouter(responseWriter http.ResponseWriter, request *http.Request) {
+	urlVariables := mux.Vars(request)
+	if err := storage.ServeFile(responseWriter, request, storage.TranscodeName(urlVariables["file"], config.Configuration.TranscodeFormat)); err != nil {
+		http.NotFound(responseWriter, request)
+	}
+}
+
+//serveThumbnailFallback replies for an image that has no thumbnail
+func serveThumbnailFallback(responseWriter http.ResponseWriter, request *http.Request, Name string) {
+	iconPath := path.Join(config.Configuration.HTTPRoot, "resources"+string(filepath.Separator)+"noicon.svg")
+	mediaType, _ := media.ByName(Name)
+	switch {
+	//If it does not, and it is an image, return the original image, more bandwidth but better looking site
+	case mediaType.Kind == media.KindImage && storage.IsQuarantined(Name) == false:
+		restrictActiveContent(responseWriter, Name)
+		if err := storage.ServeFile(responseWriter, request, Name); err == nil {
+			return
+		}
+	//If a video or music file, pull up a play icon
+	case mediaType.Kind == media.KindVideo, mediaType.Kind == media.KindAudio:
+		iconPath = path.Join(config.Configuration.HTTPRoot, "resources"+string(filepath.Separator)+"playicon.svg")
+	}
+	//Final fallback, just return an icon for the type
+	http.ServeFile(responseWriter, request, iconPath)
+}
+
+//thumbnailsInProgress holds a channel for each thumbnail being made, closed once it is done, so requests for the same thumbnail wait instead of making it again
+var thumbnailsInProgress = make(map[string]chan struct{})
+var thumbnailsInProgressLock sync.Mutex
+
+//thumbnailWorkers limits how many thumbnails are made at once, as the first view of a page asks for many
+var thumbnailWorkers = make(chan struct{}, runtime.NumCPU())
+
+//generateThumbnailOnce runs Generate for the thumbnail Name, or waits for it if another request is already making it
+func generateThumbnailOnce(Name string, Generate func() error) error {
+	thumbnailsInProgressLock.Lock()
+	if done, ok := thumbnailsInProgress[Name]; ok {
+		thumbnailsInProgressLock.Unlock()
+		<-done
+		return nil
+	}
+	done := make(chan struct{})
+	thumbnailsInProgress[Name] = done
+	thumbnailsInProgressLock.Unlock()
+
+	thumbnailWorkers <- struct{}{}
+	err := Generate()
+	<-thumbnailWorkers
+
+	thumbnailsInProgressLock.Lock()
+	delete(thumbnailsInProgress, Name)
+	thumbnailsInProgressLock.Unlock()
+	close(done)
+	return err
+}
+
+//CanGenerateThumbnail returns whether GenerateThumbnail can make a thumbnail for the named file
+func CanGenerateThumbnail(Name string) bool {
+	mediaType, _ := media.ByName(Name)
+	if mediaType.Decodable || mediaType.Name == "svg" || mediaType.Name == "wav" {
+		return true
+	}
+	return (mediaType.Kind == media.KindVideo || mediaType.Kind == media.KindAudio) && config.Configuration.UseFFMPEG
+}
+
+//CanGeneratePreview returns whether GeneratePreview can make an animated preview for the named file
+func CanGeneratePreview(Name string) bool {
+	mediaType, _ := media.ByName(Name)
+	if mediaType.Animated && mediaType.Decodable {
+		return true
+	}
+	return mediaType.Kind == media.KindVideo && config.Configuration.UseFFMPEG
+}
+
+//CanTranscode returns whether TranscodeVideo should make a version of the named file browsers can play
+func CanTranscode(Name string) bool {
+	mediaType, _ := media.ByName(Name)
+	return mediaType.Kind == media.KindVideo && mediaType.Playable == false && config.Configuration.UseFFMPEG
+}
+
+//CanGeneratedHash returns whether GeneratedHash can hash the named file
+func CanGeneratedHash(Name string) bool {
+	mediaType, _ := media.ByName(Name)
+	return mediaType.Decodable
 }
 
 //GenerateThumbnail will attempt to generate a thumbnail for the specified resource
 func GenerateThumbnail(Name string) error {
-	//Switch on extension
-	//Each case will contain generators for that file type
-	switch ext := filepath.Ext(strings.ToLower(Name)); ext {
-	case ".jpg", ".jpeg", ".bmp", ".gif", ".png", ".webp", ".tiff", ".tif", ".jfif":
-		File, err := os.Open(path.Join(config.Configuration.ImageDirectory, Name))
+	thumbnailImage, err := drawThumbnail(Name, config.Configuration.MaxThumbnailWidth, config.Configuration.MaxThumbnailHeight)
+	if err != nil {
+		return err
+	}
+	var thumbnailBuffer bytes.Buffer
+	if err := png.Encode(&thumbnailBuffer, thumbnailImage); err != nil {
+		return err
+	}
+	return storage.StorageInterface.Save(storage.ThumbnailName(Name), &thumbnailBuffer)
+}
+
+//GenerateSizedThumbnail makes the thumbnail of an image for one of ThumbnailSizes, in ThumbnailFormat
+func GenerateSizedThumbnail(Name string, Size config.ThumbnailSize) error {
+	thumbnailImage, err := drawThumbnail(Name, Size.Width, Size.Height)
+	if err != nil {
+		return err
+	}
+	var thumbnailBuffer bytes.Buffer
+	switch config.Configuration.ThumbnailFormat {
+	case "png":
+		err = png.Encode(&thumbnailBuffer, thumbnailImage)
+	case "webp-lossless":
+		err = media.EncodeWebP(&thumbnailBuffer, thumbnailImage)
+	default:
+		//JPEG has no transparency, so transparent parts are shown on white as they would be on the page
+		flattened := image.NewRGBA(thumbnailImage.Bounds())
+		draw.Draw(flattened, flattened.Bounds(), image.White, image.Point{}, draw.Src)
+		draw.Draw(flattened, flattened.Bounds(), thumbnailImage, thumbnailImage.Bounds().Min, draw.Over)
+		err = jpeg.Encode(&thumbnailBuffer, flattened, &jpeg.Options{Quality: config.Configuration.ThumbnailQuality})
+	}
+	if err != nil {
+		return err
+	}
+	return storage.StorageInterface.Save(storage.SizedThumbnailName(Name, Size, config.Configuration.ThumbnailFormat), &thumbnailBuffer)
+}
+
+//previewMaxFrames is the most frames a preview of an animated image keeps
+const previewMaxFrames = 48
+
+//previewVideoFrames is how many frames are taken from across a video for its preview, and how long each is shown
+const previewVideoFrames = 10
+const previewVideoFrameDuration = 500 * time.Millisecond
+
+//GeneratePreview makes the animated preview shown in place of the thumbnail of a GIF or video, no larger than MaxThumbnailWidth by MaxThumbnailHeight
+//GIFs are cut down to a few dozen frames, videos give a strip of frames from across their length
+func GeneratePreview(Name string) error {
+	var frames []image.Image
+	var durations []time.Duration
+	mediaType, _ := media.ByName(Name)
+	switch {
+	case mediaType.Animated && mediaType.Decodable:
+		File, err := storage.StorageInterface.Open(Name)
+		if err != nil {
+			return err
+		}
 		defer File.Close()
+		animation, err := gif.DecodeAll(File)
 		if err != nil {
 			return err
 		}
-		originalImage, _, err := imageorient.Decode(File)
+		frames, durations = media.GIFFrames(animation, previewMaxFrames, config.Configuration.MaxThumbnailWidth, config.Configuration.MaxThumbnailHeight)
+	case mediaType.Kind == media.KindVideo && config.Configuration.UseFFMPEG:
+		var err error
+		frames, err = drawVideoPreview(Name, config.Configuration.MaxThumbnailWidth, config.Configuration.MaxThumbnailHeight)
 		if err != nil {
 			return err
 		}
-		newWidth := uint(originalImage.Bounds().Max.X)
-		newHeight := uint(originalImage.Bounds().Max.Y)
+		for range frames {
+			durations = append(durations, previewVideoFrameDuration)
+		}
+	default:
+		return errors.New("No preview method for file type")
+	}
+	var previewBuffer bytes.Buffer
+	if err := media.EncodeAnimatedWebP(&previewBuffer, frames, durations); err != nil {
+		return err
+	}
+	return storage.StorageInterface.Save(storage.PreviewName(Name), &previewBuffer)
+}
+
+//drawVideoPreview returns frames from evenly spaced points of the named video, no larger than MaxWidth by MaxHeight
+func drawVideoPreview(Name string, MaxWidth uint, MaxHeight uint) ([]image.Image, error) {
+	//FFMPEG needs real files, so work in a temporary directory
+	workDirectory, err := os.MkdirTemp("", "gib-preview-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDirectory)
+	videoPath := filepath.Join(workDirectory, "video"+filepath.Ext(Name))
+	if err := copyToFile(Name, videoPath); err != nil {
+		return nil, err
+	}
+	videoInfo, err := probeWithFFMPEG(Name, videoPath)
+	if err != nil {
+		return nil, err
+	}
+	duration := videoInfo.Duration
+	frameCount := previewVideoFrames
+	if duration <= 0 {
+		frameCount = 1 //Without a length only the start can be found
+	}
+	sizeParam := "scale=" + strconv.FormatUint(uint64(MaxWidth), 10) + ":" + strconv.FormatUint(uint64(MaxHeight), 10) + ":force_original_aspect_ratio=decrease"
+	var frames []image.Image
+	for index := 0; index < frameCount; index++ {
+		//Seeking before the input is quick, as FFMPEG skips to the nearest keyframe rather than decoding everything before it
+		position := strconv.FormatFloat(duration*(float64(index)+0.5)/float64(frameCount), 'f', 3, 64)
+		framePath := filepath.Join(workDirectory, "frame"+strconv.Itoa(index)+".png")
+		ffmpegCMD := exec.Command(config.Configuration.FFMPEGPath, "-ss", position, "-i", videoPath, "-vf", sizeParam, "-frames:v", "1", framePath)
+		if _, err := ffmpegCMD.Output(); err != nil {
+			logging.WriteLog(logging.LogLevelWarning, "resourcesrouters/drawVideoPreview", "0", logging.ResultFailure, []string{"Failed to use FFMPEG", Name, position, err.Error()})
+			continue
+		}
+		frameFile, err := os.Open(framePath)
+		if err != nil {
+			continue //Positions past the last frame give no output
+		}
+		frame, err := png.Decode(frameFile)
+		frameFile.Close()
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+	}
+	if len(frames) == 0 {
+		return nil, errors.New("FFMPEG gave no frames for preview")
+	}
+	return frames, nil
+}
 
-		if (newWidth >= newHeight) && newWidth > config.Configuration.MaxThumbnailWidth {
-			scale := float64(config.Configuration.MaxThumbnailWidth) / float64(newWidth)
-			newWidth = uint(float64(newWidth) * scale)
-			newHeight = uint(float64(newHeight) * scale)
+//drawThumbnail returns a picture of the named file no larger than MaxWidth by MaxHeight
+func drawThumbnail(Name string, MaxWidth uint, MaxHeight uint) (image.Image, error) {
+	//Switch on the type recorded in the extension
+	//Each case will contain generators for that file type
+	mediaType, _ := media.ByName(Name)
+	switch {
+	case mediaType.Decodable:
+		File, err := storage.StorageInterface.Open(Name)
+		if err != nil {
+			return nil, err
 		}
-		if (newHeight > newWidth) && newHeight > config.Configuration.MaxThumbnailHeight {
-			scale := float64(config.Configuration.MaxThumbnailHeight) / float64(newHeight)
-			newWidth = uint(float64(newWidth) * scale)
-			newHeight = uint(float64(newHeight) * scale)
+		defer File.Close()
+		originalImage, _, err := imageorient.Decode(File)
+		if err != nil {
+			return nil, err
 		}
-		//Open the specified file at Path
-		NewFile, err := os.OpenFile(path.Join(config.Configuration.ImageDirectory, "thumbs"+string(filepath.Separator)+Name+".png"), os.O_CREATE|os.O_RDWR, 0660)
-		defer NewFile.Close()
+		//Keeps the shape of the image, and leaves images that already fit alone
+		return resize.Thumbnail(MaxWidth, MaxHeight, originalImage, resize.Lanczos3), nil
+	case mediaType.Name == "svg":
+		//Drawn here rather than served as is, so the thumbnail is a plain picture
+		File, err := storage.StorageInterface.Open(Name)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		defer File.Close()
+		svgData, err := io.ReadAll(File)
+		if err != nil {
+			return nil, err
 		}
-		thumbnailImage := resize.Resize(uint(newWidth), uint(newHeight), originalImage, resize.Lanczos3)
-		return png.Encode(NewFile, thumbnailImage)
-	case ".mpg", ".mov", ".webm", ".avi", ".mp4":
-		logging.WriteLog(logging.LogLevelDebug, "resourcesrouters/GenerateThumbnail", "0", logging.ResultInfo, []string{"Video detected", Name})
+		return media.RasterizeSVG(svgData, MaxWidth, MaxHeight)
+	case mediaType.Kind == media.KindVideo:
+		logging.WriteLog(logging.LogLevelDebug, "resourcesrouters/drawThumbnail", "0", logging.ResultInfo, []string{"Video detected", Name})
 
 		//Short circuit if can't support with FFMPEG
 		if !config.Configuration.UseFFMPEG {
-			return errors.New("No thumbnail method for file type")
+			return nil, errors.New("No thumbnail method for file type")
+		}
+		//FFMPEG needs real files, so work in a temporary directory
+		workDirectory, err := os.MkdirTemp("", "gib-thumbnail-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(workDirectory)
+		videoPath := filepath.Join(workDirectory, "video"+filepath.Ext(Name))
+		thumbnailPath := filepath.Join(workDirectory, "thumb.png")
+		if err := copyToFile(Name, videoPath); err != nil {
+			return nil, err
 		}
 		//Spawn FFMPEG Process and save image file
-		//ffmpeg -i input.mp4 -vf  "thumbnail,scale=640:360" -frames:v 1 thumb.png
-		//Fire forget
-		sizeParam := "thumbnail,scale=" + strconv.FormatUint(uint64(config.Configuration.MaxThumbnailWidth), 10) + ":" + strconv.FormatUint(uint64(config.Configuration.MaxThumbnailHeight), 10)
-		ffmpegCMD := exec.Command(config.Configuration.FFMPEGPath, "-i", path.Join(config.Configuration.ImageDirectory, Name), "-vf", sizeParam, "-frames:v", "1", path.Join(config.Configuration.ImageDirectory, "thumbs"+string(filepath.Separator)+Name+".png"))
-		_, err := ffmpegCMD.Output()
+		//ffmpeg -i input.mp4 -vf  "thumbnail,scale=640:360:force_original_aspect_ratio=decrease" -frames:v 1 thumb.png
+		sizeParam := "thumbnail,scale=" + strconv.FormatUint(uint64(MaxWidth), 10) + ":" + strconv.FormatUint(uint64(MaxHeight), 10) + ":force_original_aspect_ratio=decrease"
+		ffmpegCMD := exec.Command(config.Configuration.FFMPEGPath, "-i", videoPath, "-vf", sizeParam, "-frames:v", "1", thumbnailPath)
+		if _, err := ffmpegCMD.Output(); err != nil {
+			logging.WriteLog(logging.LogLevelError, "resourcesrouters/drawThumbnail", "0", logging.ResultFailure, []string{"Failed to use FFMPEG", Name, err.Error()})
+			return nil, err
+		}
+		thumbnailFile, err := os.Open(thumbnailPath)
 		if err != nil {
-			logging.WriteLog(logging.LogLevelError, "resourcesrouters/GenerateThumbnail", "0", logging.ResultFailure, []string{"Failed to use FFMPEG", Name, err.Error()})
-			return err
+			return nil, err
 		}
-		logging.WriteLog(logging.LogLevelInfo, "resourcesrouters/GenerateThumbnail", "0", logging.ResultInfo, []string{"FFMPEG output success", Name})
-		return nil
+		defer thumbnailFile.Close()
+		logging.WriteLog(logging.LogLevelInfo, "resourcesrouters/drawThumbnail", "0", logging.ResultInfo, []string{"FFMPEG output success", Name})
+		return png.Decode(thumbnailFile)
+	case mediaType.Kind == media.KindAudio:
+		//Waveforms are read from left to right, so they are drawn wide
+		waveHeight := MaxHeight / 2
+		if waveHeight < 1 {
+			waveHeight = 1
+		}
+		wave, err := readWaveform(Name, mediaType)
+		if err != nil {
+			return nil, err
+		}
+		return wave.Draw(MaxWidth, waveHeight)
 	default:
-		return errors.New("No thumbnail method for file type")
+		return nil, errors.New("No thumbnail method for file type")
+	}
+}
+
+//waveformSampleRate is the sample rate FFMPEG resamples audio to for drawing its waveform, far more than a thumbnail can show
+const waveformSampleRate = 8000
+
+//readWaveform reads the named audio file into a Waveform, decoding WAV files directly and anything else with FFMPEG
+func readWaveform(Name string, MediaType media.Type) (*media.Waveform, error) {
+	if MediaType.Name == "wav" {
+		File, err := storage.StorageInterface.Open(Name)
+		if err != nil {
+			return nil, err
+		}
+		defer File.Close()
+		format, err := media.ReadWAVHeader(File)
+		if err == nil {
+			wave := media.NewWaveform(format.SampleRate)
+			return wave, media.ReadWAVSamples(File, format, wave)
+		}
+		//FFMPEG understands more encodings
+		if config.Configuration.UseFFMPEG == false {
+			return nil, err
+		}
+	}
+	if config.Configuration.UseFFMPEG == false {
+		return nil, errors.New("No thumbnail method for file type")
+	}
+	//FFMPEG needs real files, so work in a temporary directory
+	workDirectory, err := os.MkdirTemp("", "gib-waveform-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDirectory)
+	audioPath := filepath.Join(workDirectory, "audio"+filepath.Ext(Name))
+	if err := copyToFile(Name, audioPath); err != nil {
+		return nil, err
+	}
+	//Raw samples are read as FFMPEG writes them, so long files are never held in memory
+	ffmpegCMD := exec.Command(config.Configuration.FFMPEGPath, "-hide_banner", "-i", audioPath, "-vn", "-ac", "1", "-ar", strconv.Itoa(waveformSampleRate), "-f", "s16le", "pipe:1")
+	output, err := ffmpegCMD.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := ffmpegCMD.Start(); err != nil {
+		logging.WriteLog(logging.LogLevelError, "resourcesrouters/readWaveform", "0", logging.ResultFailure, []string{"Failed to use FFMPEG", Name, err.Error()})
+		return nil, err
 	}
+	wave := media.NewWaveform(waveformSampleRate)
+	reader := bufio.NewReader(output)
+	sample := make([]byte, 2)
+	for true {
+		if _, err := io.ReadFull(reader, sample); err != nil {
+			break
+		}
+		wave.Add(int16(binary.LittleEndian.Uint16(sample)))
+	}
+	if err := ffmpegCMD.Wait(); err != nil {
+		logging.WriteLog(logging.LogLevelError, "resourcesrouters/readWaveform", "0", logging.ResultFailure, []string{"Failed to use FFMPEG", Name, err.Error()})
+		return nil, err
+	}
+	return wave, nil
 }
 
-//GeneratedHash will attempt to generate a dHash for the given image
+//copyToFile copies a file out of storage to a local path, for tools that cannot read from storage directly
+func copyToFile(Name string, FilePath string) error {
+	File, err := storage.StorageInterface.Open(Name)
+	if err != nil {
+		return err
+	}
+	defer File.Close()
+	NewFile, err := os.OpenFile(FilePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0660)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(NewFile, File); err != nil {
+		NewFile.Close()
+		return err
+	}
+	return NewFile.Close()
+}
+
+//placeholderSize is the largest side, in pixels, of the copy placeholders are worked out from
+const placeholderSize = 32
+
+//paletteSize is how many dominant colours are kept for each image
+const paletteSize = 5
+
+//GeneratedHash will attempt to generate a dHash, blurhash, and dominant colours for the given image
 func GeneratedHash(Name string, ImageID uint64) error {
-	//Switch on extension
-	switch ext := filepath.Ext(strings.ToLower(Name)); ext {
-	case ".jpg", ".jpeg", ".bmp", ".gif", ".png", ".webp", ".tiff", ".tif", ".jfif":
+	//Only types the image package can decode can be hashed
+	if mediaType, _ := media.ByName(Name); mediaType.Decodable {
 		//Load image
-		File, err := os.Open(path.Join(config.Configuration.ImageDirectory, Name))
-		defer File.Close()
+		File, err := storage.StorageInterface.Open(Name)
 		if err != nil {
 			return err
 		}
+		defer File.Close()
 		originalImage, _, err := imageorient.Decode(File)
 		if err != nil {
 			return err
 		}
+		//Placeholders only need a rough copy, which also keeps the blurhash quick
+		smallImage := resize.Thumbnail(placeholderSize, placeholderSize, originalImage, resize.Bilinear)
+		blurHash, err := media.EncodeBlurHash(smallImage, 4, 3)
+		if err != nil {
+			return err
+		}
+		if err := database.DBInterface.SetImageColors(ImageID, blurHash, media.DominantColors(smallImage, paletteSize)); err != nil {
+			return err
+		}
+
 		//Scale it
 		const newWidth = 9
 		const newHeight = 9
@@ -185,7 +578,155 @@ func GeneratedHash(Name string, ImageID uint64) error {
 		}
 
 		return database.DBInterface.SetImagedHash(ImageID, hHash, vHash)
-	default:
+	}
+	return errors.New("Cannot process image of this type")
+}
+
+//GenerateMediaInfo records the dimensions, file size, MIME type, duration, and bitrate of the given image
+//Video and audio other than WAV only get dimensions, a duration, and a bitrate when FFMPEG is enabled
+func GenerateMediaInfo(Name string, ImageID uint64) error {
+	mediaType, found := media.ByName(Name)
+	if found == false {
 		return errors.New("Cannot process image of this type")
 	}
+	fileInfo, err := storage.StorageInterface.Stat(Name)
+	if err != nil {
+		return err
+	}
+	info := interfaces.ImageInformation{ID: ImageID, FileSize: uint64(fileInfo.Size), MIMEType: mediaType.MIME}
+	switch {
+	case mediaType.Decodable:
+		File, err := storage.StorageInterface.Open(Name)
+		if err != nil {
+			return err
+		}
+		defer File.Close()
+		imageData, err := io.ReadAll(File)
+		if err != nil {
+			return err
+		}
+		imageConfig, _, err := image.DecodeConfig(bytes.NewReader(imageData))
+		if err != nil {
+			return err
+		}
+		info.Width, info.Height = uint64(imageConfig.Width), uint64(imageConfig.Height)
+		//Orientations 5 to 8 turn the picture on its side, as imageorient does when drawing it
+		if metadata, found := media.ReadMetadata(imageData, mediaType); found && metadata.Orientation >= 5 && metadata.Orientation <= 8 {
+			info.Width, info.Height = info.Height, info.Width
+		}
+	case mediaType.Name == "svg":
+		File, err := storage.StorageInterface.Open(Name)
+		if err != nil {
+			return err
+		}
+		defer File.Close()
+		svgData, err := io.ReadAll(File)
+		if err != nil {
+			return err
+		}
+		width, height, err := media.SVGSize(svgData)
+		if err != nil {
+			return err
+		}
+		info.Width, info.Height = uint64(math.Round(width)), uint64(math.Round(height))
+	case mediaType.Name == "wav":
+		//The header says exactly how the samples are stored, so FFMPEG is not needed
+		File, err := storage.StorageInterface.Open(Name)
+		if err != nil {
+			return err
+		}
+		defer File.Close()
+		format, err := media.ReadWAVHeader(File)
+		if err != nil {
+			//Compressed WAV files are rare, they are recorded without a duration
+			logging.WriteLog(logging.LogLevelWarning, "resourcesrouters/GenerateMediaInfo", "0", logging.ResultFailure, []string{"Failed to read WAV header", Name, err.Error()})
+			break
+		}
+		info.Duration, info.Bitrate = format.Duration(), format.Bitrate()
+	case config.Configuration.UseFFMPEG:
+		//FFMPEG needs real files, so work in a temporary directory
+		workDirectory, err := os.MkdirTemp("", "gib-mediainfo-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(workDirectory)
+		mediaPath := filepath.Join(workDirectory, "media"+filepath.Ext(Name))
+		if err := copyToFile(Name, mediaPath); err != nil {
+			return err
+		}
+		ffmpegInfo, err := probeWithFFMPEG(Name, mediaPath)
+		if err != nil {
+			return err
+		}
+		info.Duration, info.Bitrate = ffmpegInfo.Duration, ffmpegInfo.Bitrate
+		//Cover art shows up as a video stream, but is not the size of the audio
+		if mediaType.Kind == media.KindVideo {
+			info.Width, info.Height = ffmpegInfo.Width, ffmpegInfo.Height
+		}
+	}
+	return database.DBInterface.SetImageMediaInfo(info)
+}
+
+//probeWithFFMPEG returns the dimensions, duration, and bitrate FFMPEG finds in the local file MediaPath, a copy of the named file
+func probeWithFFMPEG(Name string, MediaPath string) (media.FFMPEGInfo, error) {
+	//Without an output file FFMPEG prints what it found and exits with an error, so only failing to start is a failure
+	ffmpegCMD := exec.Command(config.Configuration.FFMPEGPath, "-hide_banner", "-i", MediaPath)
+	output, err := ffmpegCMD.CombinedOutput()
+	if _, exited := err.(*exec.ExitError); err != nil && exited == false {
+		logging.WriteLog(logging.LogLevelError, "resourcesrouters/probeWithFFMPEG", "0", logging.ResultFailure, []string{"Failed to use FFMPEG", Name, err.Error()})
+		return media.FFMPEGInfo{}, err
+	}
+	return media.ParseFFMPEGInfo(string(output)), nil
+}
+
+//transcodeProgressInterval is how often a transcode records how far it is, which also stops its job being thought lost
+const transcodeProgressInterval = 5 * time.Second
+
+//TranscodeVideo makes a version of the named video in TranscodeFormat that browsers can play, the original is kept for download
+//Progress is given the seconds transcoded out of Duration, which may be 0 if the length is not known
+func TranscodeVideo(Name string, Duration float64, Progress func(Done uint64, Total uint64)) error {
+	//FFMPEG needs real files, so work in a temporary directory
+	workDirectory, err := os.MkdirTemp("", "gib-transcode-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDirectory)
+	videoPath := filepath.Join(workDirectory, "video"+filepath.Ext(Name))
+	if err := copyToFile(Name, videoPath); err != nil {
+		return err
+	}
+	transcodePath := filepath.Join(workDirectory, "web."+config.Configuration.TranscodeFormat)
+	ffmpegCMD := exec.Command(config.Configuration.FFMPEGPath, media.TranscodeArgs(config.Configuration.TranscodeFormat, videoPath, transcodePath)...)
+	var errorOutput bytes.Buffer
+	ffmpegCMD.Stderr = &errorOutput
+	progressOutput, err := ffmpegCMD.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := ffmpegCMD.Start(); err != nil {
+		logging.WriteLog(logging.LogLevelError, "resourcesrouters/TranscodeVideo", "0", logging.ResultFailure, []string{"Failed to use FFMPEG", Name, err.Error()})
+		return err
+	}
+	lastProgress := time.Now()
+	progressScanner := bufio.NewScanner(progressOutput)
+	for progressScanner.Scan() {
+		seconds, found := media.ParseFFMPEGProgress(progressScanner.Text())
+		if found && time.Since(lastProgress) >= transcodeProgressInterval {
+			if Duration > 0 {
+				seconds = math.Min(seconds, Duration)
+			}
+			Progress(uint64(seconds), uint64(math.Ceil(Duration)))
+			lastProgress = time.Now()
+		}
+	}
+	if err := ffmpegCMD.Wait(); err != nil {
+		logging.WriteLog(logging.LogLevelWarning, "resourcesrouters/TranscodeVideo", "0", logging.ResultFailure, []string{"FFMPEG failed to transcode", Name, err.Error(), errorOutput.String()})
+		return errors.New("FFMPEG failed to transcode: " + strings.TrimSpace(errorOutput.String()))
+	}
+	transcodedFile, err := os.Open(transcodePath)
+	if err != nil {
+		return err
+	}
+	defer transcodedFile.Close()
+	return storage.StorageInterface.Save(storage.TranscodeName(Name, config.Configuration.TranscodeFormat), transcodedFile)
 }
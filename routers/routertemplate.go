@@ -59,6 +59,16 @@ type templateInput struct {
 	RequestTime int64
 	//ModUserData contains information for the modUser page
 	ModUserData interfaces.UserInformation
+	//JobList contains the jobs for the modJobs page
+	JobList []interfaces.JobInformation
+	//JobStatus is the status the modJobs page is filtered to, or "" for all
+	JobStatus string
+	//JobCounts is the count of jobs in each status for the modJobs page
+	JobCounts map[string]uint64
+	//SuggestAudioTags is set when the upload form should offer tags read from audio files
+	SuggestAudioTags bool
+	//TranscodeStatus is how far the version of a video browsers can play is on the image page, "" when the original can be played
+	TranscodeStatus string
 }
 
 func (ti templateInput) IsLoggedOn() bool {
@@ -134,7 +134,9 @@ func TagPostRouter(responseWriter http.ResponseWriter, request *http.Request) {
 		}
 
 		//Confirmed tags exist and are valid
-		err = database.DBInterface.BulkAddTag(userNewQTags[0].ID, userOldQTags[0].ID, TemplateInput.UserInformation.ID)
+		err = database.DBInterface.RunInTransaction(func(Transaction interfaces.DBInterface) error {
+			return Transaction.BulkAddTag(userNewQTags[0].ID, userOldQTags[0].ID, TemplateInput.UserInformation.ID)
+		})
 		if err != nil {
 			TemplateInput.HTMLMessage += template.HTML("Error adding tags (SQL).<br>")
 			logging.WriteLog(logging.LogLevelError, "tagrouter/TagRouter/bulkAddTag", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to bulk add tags due to a SQL error", err.Error(), newTagQuery, oldTagQuery})
@@ -175,8 +177,10 @@ func TagPostRouter(responseWriter http.ResponseWriter, request *http.Request) {
 			return
 		}
 
-		//Confirmed tags exist and are valid, now replace
-		err = database.DBInterface.ReplaceImageTags(userOldQTags[0].ID, userNewQTags[0].ID, TemplateInput.UserInformation.ID)
+		//Confirmed tags exist and are valid, now replace. Adding the new tag and removing the old one happen together, so a failure leaves the images as they were
+		err = database.DBInterface.RunInTransaction(func(Transaction interfaces.DBInterface) error {
+			return Transaction.ReplaceImageTags(userOldQTags[0].ID, userNewQTags[0].ID, TemplateInput.UserInformation.ID)
+		})
 		if err != nil {
 			TemplateInput.HTMLMessage += template.HTML("Error adding tags (SQL).<br>")
 			logging.WriteLog(logging.LogLevelError, "tagrouter/TagRouter/replaceTag", TemplateInput.UserInformation.GetCompositeID(), logging.ResultFailure, []string{"Failed to bulk replace tags due to a SQL error", err.Error(), newTagQuery, oldTagQuery})
@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"go-image-board/config"
 	"go-image-board/logging"
+	"go-image-board/media"
 	"html/template"
 	"io/ioutil"
+	"net/url"
 	"path"
-	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -32,16 +34,14 @@ func CacheTemplates() error {
 
 	//Add functions here
 	getImageType := func(path string) string {
-		text := ""
-		switch ext := filepath.Ext(strings.ToLower(path)); ext {
-		case ".wav", ".mp3", ".ogg":
-			text = "audio"
-		case ".mpg", ".mov", ".webm", ".avi", ".mp4", ".gif":
-			text = "video"
-		default:
-			text = "image"
+		mediaType, known := media.ByName(path)
+		if known == false {
+			return media.KindImage
 		}
-		return text
+		if mediaType.Animated {
+			return media.KindVideo //Marked to play like video
+		}
+		return mediaType.Kind
 	}
 	increment := func(value interface{}) interface{} {
 		switch value.(type) {
@@ -76,11 +76,21 @@ func CacheTemplates() error {
 	getEmbed := func(value interface{}) template.HTML {
 		return GetEmbedForContent(fmt.Sprintf("%v", value))
 	}
+	getThumbnailSrcset := func(value interface{}) string {
+		return GetThumbnailSrcset(fmt.Sprintf("%v", value))
+	}
+	getPreviewSrc := func(value interface{}) string {
+		return GetPreviewSrc(fmt.Sprintf("%v", value))
+	}
 	templates := template.New("")
 	templates = templates.Funcs(template.FuncMap{"getimagetype": getImageType})
 	templates = templates.Funcs(template.FuncMap{"inc": increment})
 	templates = templates.Funcs(template.FuncMap{"dec": decrement})
 	templates = templates.Funcs(template.FuncMap{"getEmbed": getEmbed})
+	templates = templates.Funcs(template.FuncMap{"getThumbnailSrcset": getThumbnailSrcset})
+	templates = templates.Funcs(template.FuncMap{"getPreviewSrc": getPreviewSrc})
+	templates = templates.Funcs(template.FuncMap{"formatDuration": FormatDuration})
+	templates = templates.Funcs(template.FuncMap{"formatBitrate": FormatBitrate})
 
 	templates, err = templates.ParseFiles(allFiles...)
 	if err != nil {
@@ -96,13 +106,14 @@ func CacheTemplates() error {
 func GetEmbedForContent(imageLocation string) template.HTML {
 	ToReturn := ""
 
-	switch ext := filepath.Ext(strings.ToLower(imageLocation)); ext {
-	case ".jpg", ".jpeg", ".bmp", ".gif", ".png", ".svg", ".webp", ".tiff", ".tif", ".jfif":
+	mediaType, _ := media.ByName(imageLocation)
+	switch mediaType.Kind {
+	case media.KindImage:
 		ToReturn = "<img src=\"/images/" + imageLocation + "\" alt=\"" + imageLocation + "\" id=\"IMGContent\" />"
-	case ".mpg", ".mov", ".webm", ".avi", ".mp4", ".mp3", ".ogg":
-		ToReturn = "<video controls loop> <source src=\"/images/" + imageLocation + "\" type=\"" + getMIME(ext, "video/mp4") + "\">Your browser does not support the video tag.</video>"
-	case ".wav":
-		ToReturn = "<audio controls loop> <source src=\"/images/" + imageLocation + "\" type=\"" + getMIME(ext, "audio/wav") + "\">Your browser does not support the audio tag.</audio>"
+	case media.KindVideo:
+		ToReturn = "<video controls loop> <source src=\"/images/" + imageLocation + "\" type=\"" + mediaType.MIME + "\">Your browser does not support the video tag.</video>"
+	case media.KindAudio:
+		ToReturn = "<audio controls loop> <source src=\"/images/" + imageLocation + "\" type=\"" + mediaType.MIME + "\">Your browser does not support the audio tag.</audio>"
 	default:
 		logging.WriteLog(logging.LogLevelError, "templatecache/GetEmbedForContent", "0", logging.ResultFailure, []string{"File uploaded, but did not match a filter during download", imageLocation})
 		ToReturn = "<p>File format not supported. Click download.</p>"
@@ -111,26 +122,51 @@ func GetEmbedForContent(imageLocation string) template.HTML {
 	return template.HTML(ToReturn)
 }
 
-//returns a mime given a file extension. This is only required for video and audio files so we can embed mime in video/audio element
-func getMIME(extension string, fallback string) string {
-	switch extension {
-	case ".mp4":
-		return "video/mp4"
-	case ".webm":
-		return "video/webm"
-	case ".avi":
-		return "video/avi"
-	case ".mpg":
-		return "video/mpeg"
-	case ".mov":
-		return "video/quicktime"
-	case ".ogg":
-		return "video/ogg"
-	case ".mp3":
-		return "audio/mpeg3"
-	case ".wav":
-		return "audio/wav"
-	default:
-		return fallback
+//GetEmbedForTranscodedVideo returns the html to embed a video through its version transcoded to Format, with the original as a fallback for browsers that can play it
+func GetEmbedForTranscodedVideo(imageLocation string, Format string) template.HTML {
+	originalType, _ := media.ByName(imageLocation)
+	transcodeType, _ := media.ByName("video." + Format)
+	return template.HTML("<video controls loop> <source src=\"/transcodes/" + imageLocation + "\" type=\"" + transcodeType.MIME + "\"><source src=\"/images/" + imageLocation + "\" type=\"" + originalType.MIME + "\">Your browser does not support the video tag.</video>")
+}
+
+//GetThumbnailSrcset returns a srcset listing the thumbnail of the specified file in each of ThumbnailSizes, from narrowest to widest
+func GetThumbnailSrcset(imageLocation string) string {
+	var names []string
+	for name := range config.Configuration.ThumbnailSizes {
+		names = append(names, name)
 	}
+	sort.Slice(names, func(i, j int) bool {
+		return config.Configuration.ThumbnailSizes[names[i]].Width < config.Configuration.ThumbnailSizes[names[j]].Width
+	})
+	//Commas and spaces separate candidates, so the location is escaped like any other path
+	escapedLocation := (&url.URL{Path: imageLocation}).EscapedPath()
+	var candidates []string
+	for _, name := range names {
+		candidates = append(candidates, "/thumbs/"+name+"/"+escapedLocation+" "+strconv.FormatUint(uint64(config.Configuration.ThumbnailSizes[name].Width), 10)+"w")
+	}
+	return strings.Join(candidates, ", ")
+}
+
+//GetPreviewSrc returns where the animated preview of the specified file is served, or an empty string if it cannot have one
+//This matches routers.CanGeneratePreview, which cannot be used here as routers imports this package
+func GetPreviewSrc(imageLocation string) string {
+	mediaType, _ := media.ByName(imageLocation)
+	if (mediaType.Animated && mediaType.Decodable) || (mediaType.Kind == media.KindVideo && config.Configuration.UseFFMPEG) {
+		return "/previews/" + (&url.URL{Path: imageLocation}).EscapedPath()
+	}
+	return ""
+}
+
+//FormatDuration returns a length in seconds as minutes and seconds, with hours in front when it is that long
+func FormatDuration(Seconds float64) string {
+	total := int64(Seconds + 0.5)
+	if total >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", total/3600, total/60%60, total%60)
+	}
+	return fmt.Sprintf("%d:%02d", total/60, total%60)
+}
+
+//FormatBitrate returns a rate in bits per second as kilobits per second, the way players show it
+func FormatBitrate(BitsPerSecond uint64) string {
+	return strconv.FormatUint((BitsPerSecond+500)/1000, 10) + " kb/s"
 }
@@ -0,0 +1,228 @@
+package storage
+
+import (
+	"errors"
+	"go-image-board/config"
+	"go-image-board/interfaces"
+	"go-image-board/media"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//StorageInterface is a global variable for access to stored images and thumbnails
+var StorageInterface interfaces.StorageInterface
+
+//ThumbnailDirectory is the directory thumbnails are kept in
+const ThumbnailDirectory = "thumbs"
+
+//QuarantineDirectory holds files moved aside by -verify -repair, under the names they had before
+const QuarantineDirectory = "quarantine"
+
+//LayoutFlat keeps every image directly in the top level directory
+const LayoutFlat = "flat"
+
+//LayoutSharded spreads images over two levels of directories named after the start of the file name, such as ab/cd/abcdef.png
+const LayoutSharded = "sharded"
+
+//ThumbnailName returns the name the thumbnail of an image is stored under
+func ThumbnailName(Name string) string {
+	return path.Join(ThumbnailDirectory, Name+".png")
+}
+
+//previewSuffix ends the name of an image's animated preview
+const previewSuffix = ".preview.webp"
+
+//PreviewName returns the name the animated preview of an image is stored under
+func PreviewName(Name string) string {
+	return path.Join(ThumbnailDirectory, Name+previewSuffix)
+}
+
+//transcodePattern matches the part of a transcode's name after ThumbnailDirectory, capturing the image name and format
+var transcodePattern = regexp.MustCompile(`^(.+)\.web\.(mp4|webm)$`)
+
+//TranscodeName returns the name the version of a video transcoded to Format for browsers is stored under
+func TranscodeName(Name string, Format string) string {
+	return path.Join(ThumbnailDirectory, Name+".web."+Format)
+}
+
+//ThumbnailFormats are the formats thumbnails for ThumbnailSizes may be made in
+//webp-lossless only suits small thumbnails of drawings, as photographs come out larger than as jpeg
+var ThumbnailFormats = []string{"jpeg", "png", "webp-lossless"}
+
+//ThumbnailExtension returns the extension thumbnails made in one of ThumbnailFormats are stored with
+func ThumbnailExtension(Format string) string {
+	if Format == "webp-lossless" {
+		return "webp"
+	}
+	return Format
+}
+
+//sizedThumbnailPattern matches the part of a sized thumbnail's name after ThumbnailDirectory, capturing the image name, dimensions and format
+var sizedThumbnailPattern = regexp.MustCompile(`^(.+)\.(\d+)x(\d+)\.(webp|jpeg|png)$`)
+
+//SizedThumbnailName returns the name the thumbnail of an image is stored under for one of ThumbnailSizes
+//The dimensions and format are part of the name, so changing a size makes new thumbnails rather than serving old ones
+func SizedThumbnailName(Name string, Size config.ThumbnailSize, Format string) string {
+	return path.Join(ThumbnailDirectory, Name+"."+strconv.FormatUint(uint64(Size.Width), 10)+"x"+strconv.FormatUint(uint64(Size.Height), 10)+"."+ThumbnailExtension(Format))
+}
+
+//IsOutdatedThumbnail returns whether Name is a sized thumbnail for dimensions or a format that is no longer configured, or a transcode to a format that is no longer configured
+func IsOutdatedThumbnail(Name string) bool {
+	if strings.HasPrefix(Name, ThumbnailDirectory+"/") == false {
+		return false
+	}
+	if match := transcodePattern.FindStringSubmatch(strings.TrimPrefix(Name, ThumbnailDirectory+"/")); match != nil {
+		return match[2] != config.Configuration.TranscodeFormat
+	}
+	match := sizedThumbnailPattern.FindStringSubmatch(strings.TrimPrefix(Name, ThumbnailDirectory+"/"))
+	if match == nil {
+		return false
+	}
+	if match[4] != ThumbnailExtension(config.Configuration.ThumbnailFormat) {
+		return true
+	}
+	for _, size := range config.Configuration.ThumbnailSizes {
+		if match[2] == strconv.FormatUint(uint64(size.Width), 10) && match[3] == strconv.FormatUint(uint64(size.Height), 10) {
+			return false
+		}
+	}
+	return true
+}
+
+//RemoveThumbnails removes every thumbnail and transcode of an image, errors are ignored as some may never have been made
+func RemoveThumbnails(Name string) {
+	StorageInterface.Remove(ThumbnailName(Name))
+	RemoveSizedThumbnails(Name)
+	RemoveTranscodes(Name)
+}
+
+//RemoveTranscodes removes the versions of a video transcoded for browsers, in every format
+func RemoveTranscodes(Name string) {
+	for _, format := range media.TranscodeFormats {
+		StorageInterface.Remove(TranscodeName(Name, format))
+	}
+}
+
+//RemoveSizedThumbnails removes the thumbnails of an image made for ThumbnailSizes and its preview, they are made again when next requested
+func RemoveSizedThumbnails(Name string) {
+	StorageInterface.Remove(PreviewName(Name))
+	for _, size := range config.Configuration.ThumbnailSizes {
+		StorageInterface.Remove(SizedThumbnailName(Name, size, config.Configuration.ThumbnailFormat))
+	}
+}
+
+//QuarantineName returns the name a file is kept under once quarantined
+func QuarantineName(Name string) string {
+	return path.Join(QuarantineDirectory, Name)
+}
+
+//IsQuarantined returns whether Name is inside the quarantine directory
+func IsQuarantined(Name string) bool {
+	return strings.HasPrefix(path.Clean("/"+Name), "/"+QuarantineDirectory+"/")
+}
+
+//ImageNameFromThumbnail returns the name of the image a thumbnail belongs to, or false if Name is not a thumbnail
+func ImageNameFromThumbnail(Name string) (string, bool) {
+	if strings.HasPrefix(Name, ThumbnailDirectory+"/") == false {
+		return "", false
+	}
+	Name = strings.TrimPrefix(Name, ThumbnailDirectory+"/")
+	if match := sizedThumbnailPattern.FindStringSubmatch(Name); match != nil {
+		return match[1], true
+	}
+	if match := transcodePattern.FindStringSubmatch(Name); match != nil {
+		return match[1], true
+	}
+	if strings.HasSuffix(Name, previewSuffix) {
+		return strings.TrimSuffix(Name, previewSuffix), true
+	}
+	if strings.HasSuffix(Name, ".png") == false {
+		return "", false
+	}
+	return strings.TrimSuffix(Name, ".png"), true
+}
+
+//ImageLocation returns where a file named FileName is stored using the configured StorageLayout
+func ImageLocation(FileName string) string {
+	return LayoutLocation(FileName, config.Configuration.StorageLayout)
+}
+
+//LayoutLocation returns where a file named FileName is stored in Layout, FileName may also be the file's current location
+func LayoutLocation(FileName string, Layout string) string {
+	FileName = path.Base(FileName)
+	if Layout != LayoutSharded {
+		return FileName
+	}
+	stem := strings.TrimSuffix(FileName, path.Ext(FileName))
+	if len(stem) < 4 {
+		return FileName
+	}
+	return path.Join(stem[0:2], stem[2:4], FileName)
+}
+
+//Exists returns whether a file is in storage, errors other than the file not existing are returned
+func Exists(Name string) (bool, error) {
+	_, err := StorageInterface.Stat(Name)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return false, err
+}
+
+//ListImages returns the names of every image in storage, including those in sub directories, but not thumbnails or quarantined files
+func ListImages() ([]string, error) {
+	return listTree("", ThumbnailDirectory, QuarantineDirectory)
+}
+
+//ListThumbnails returns the names of every thumbnail in storage
+func ListThumbnails() ([]string, error) {
+	return listTree(ThumbnailDirectory)
+}
+
+//listTree returns the names of every file under Directory, skipping the directories named in Skip
+func listTree(Directory string, Skip ...string) ([]string, error) {
+	ToReturn, err := StorageInterface.List(Directory)
+	if err != nil {
+		return nil, err
+	}
+	directories, err := StorageInterface.ListDirectories(Directory)
+	if err != nil {
+		return nil, err
+	}
+NextDirectory:
+	for _, directory := range directories {
+		for _, skipped := range Skip {
+			if directory == skipped {
+				continue NextDirectory
+			}
+		}
+		files, err := listTree(directory, Skip...)
+		if err != nil {
+			return nil, err
+		}
+		ToReturn = append(ToReturn, files...)
+	}
+	return ToReturn, nil
+}
+
+//ServeFile replies to a request with the contents of a stored file, supporting range and conditional requests
+func ServeFile(responseWriter http.ResponseWriter, request *http.Request, Name string) error {
+	fileInfo, err := StorageInterface.Stat(Name)
+	if err != nil {
+		return err
+	}
+	file, err := StorageInterface.Open(Name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	http.ServeContent(responseWriter, request, path.Base(Name), fileInfo.ModTime, file)
+	return nil
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"go-image-board/config"
+	"testing"
+)
+
+func TestLayoutLocation(t *testing.T) {
+	tests := []struct {
+		FileName string
+		Layout   string
+		Expected string
+	}{
+		{"abcdef.png", LayoutFlat, "abcdef.png"},
+		{"abcdef.png", LayoutSharded, "ab/cd/abcdef.png"},
+		{"ab/cd/abcdef.png", LayoutFlat, "abcdef.png"},
+		{"ab/cd/abcdef.png", LayoutSharded, "ab/cd/abcdef.png"},
+		{"abc.png", LayoutSharded, "abc.png"},
+		{"abcd", LayoutSharded, "ab/cd/abcd"},
+		{"abcdef.png", "", "abcdef.png"},
+	}
+	for _, test := range tests {
+		if got := LayoutLocation(test.FileName, test.Layout); got != test.Expected {
+			t.Errorf("LayoutLocation(%q, %q) = %q, expected %q", test.FileName, test.Layout, got, test.Expected)
+		}
+	}
+}
+
+func TestImageNameFromThumbnail(t *testing.T) {
+	for _, name := range []string{"abcdef.png", "ab/cd/abcdef.webm"} {
+		got, ok := ImageNameFromThumbnail(ThumbnailName(name))
+		if ok == false || got != name {
+			t.Errorf("ImageNameFromThumbnail(ThumbnailName(%q)) = %q, %v", name, got, ok)
+		}
+	}
+	for _, name := range []string{"abcdef.png", "ab/cd/abcdef.webm"} {
+		got, ok := ImageNameFromThumbnail(SizedThumbnailName(name, config.ThumbnailSize{Width: 201, Height: 129}, "webp-lossless"))
+		if ok == false || got != name {
+			t.Errorf("ImageNameFromThumbnail(SizedThumbnailName(%q)) = %q, %v", name, got, ok)
+		}
+		got, ok = ImageNameFromThumbnail(PreviewName(name))
+		if ok == false || got != name {
+			t.Errorf("ImageNameFromThumbnail(PreviewName(%q)) = %q, %v", name, got, ok)
+		}
+		got, ok = ImageNameFromThumbnail(TranscodeName(name, "mp4"))
+		if ok == false || got != name {
+			t.Errorf("ImageNameFromThumbnail(TranscodeName(%q)) = %q, %v", name, got, ok)
+		}
+	}
+	if _, ok := ImageNameFromThumbnail("abcdef.png"); ok {
+		t.Errorf("ImageNameFromThumbnail accepted an image")
+	}
+}
+
+func TestIsOutdatedThumbnail(t *testing.T) {
+	config.Configuration.ThumbnailSizes = map[string]config.ThumbnailSize{"small": {Width: 201, Height: 129}, "large": {Width: 804, Height: 516}}
+	config.Configuration.ThumbnailFormat = "webp-lossless"
+	config.Configuration.TranscodeFormat = "mp4"
+	defer func() {
+		config.Configuration.ThumbnailSizes = nil
+		config.Configuration.ThumbnailFormat = ""
+		config.Configuration.TranscodeFormat = ""
+	}()
+	tests := []struct {
+		Name     string
+		Outdated bool
+	}{
+		{SizedThumbnailName("ab/cd/abcdef.png", config.ThumbnailSize{Width: 804, Height: 516}, "webp-lossless"), false},
+		{SizedThumbnailName("abcdef.png", config.ThumbnailSize{Width: 201, Height: 129}, "webp-lossless"), false},
+		{SizedThumbnailName("abcdef.png", config.ThumbnailSize{Width: 201, Height: 129}, "jpeg"), true},
+		{SizedThumbnailName("abcdef.png", config.ThumbnailSize{Width: 400, Height: 129}, "webp-lossless"), true},
+		{ThumbnailName("abcdef.png"), false},
+		{PreviewName("abcdef.png"), false},
+		{TranscodeName("ab/cd/abcdef.avi", "mp4"), false},
+		{TranscodeName("abcdef.avi", "webm"), true},
+		{"abcdef.png.201x129.png", false},
+		{"abcdef.png.201x129.webp", false},
+	}
+	for _, test := range tests {
+		if got := IsOutdatedThumbnail(test.Name); got != test.Outdated {
+			t.Errorf("IsOutdatedThumbnail(%q) = %v, expected %v", test.Name, got, test.Outdated)
+		}
+	}
+}
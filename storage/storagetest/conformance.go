@@ -0,0 +1,245 @@
+package storagetest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"go-image-board/plugins"
+	"go-image-board/storage"
+	"io"
+	"io/fs"
+	"sort"
+	"testing"
+)
+
+// NewStorage returns an initialized and empty storage backend, it is called once for every conformance test
+type NewStorage func(t *testing.T) interfaces.StorageInterface
+
+// RunConformance runs the behaviour every storage plugin must share against backends created by newStorage
+func RunConformance(t *testing.T, newStorage NewStorage) {
+	if logging.LogInterface == nil {
+		quietLog := &plugins.STDLog{}
+		quietLog.Init(logging.LogLevelCritical, "", "")
+		logging.LogInterface = quietLog
+	}
+
+	tests := []struct {
+		Name string
+		Test func(t *testing.T, Storage interfaces.StorageInterface)
+	}{
+		{"SaveAndOpen", testSaveAndOpen},
+		{"Missing", testMissing},
+		{"Rename", testRename},
+		{"List", testList},
+		{"ListTree", testListTree},
+		{"Names", testNames},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			test.Test(t, newStorage(t))
+		})
+	}
+}
+
+func mustSave(t *testing.T, Storage interfaces.StorageInterface, Name string, Content string) {
+	t.Helper()
+	if err := Storage.Save(Name, bytes.NewBufferString(Content)); err != nil {
+		t.Fatalf("Save(%q): %v", Name, err)
+	}
+}
+
+// expectContent fails unless Name can be opened and holds Content
+func expectContent(t *testing.T, Storage interfaces.StorageInterface, Name string, Content string) {
+	t.Helper()
+	file, err := Storage.Open(Name)
+	if err != nil {
+		t.Errorf("Open(%q): %v", Name, err)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil || string(data) != Content {
+		t.Errorf("contents of %q: %q, %v, expected %q", Name, data, err, Content)
+	}
+}
+
+// expectMissing fails unless err reports a missing file
+func expectMissing(t *testing.T, what string, err error) {
+	t.Helper()
+	if errors.Is(err, fs.ErrNotExist) == false {
+		t.Errorf("%s: expected an error matching fs.ErrNotExist, got %v", what, err)
+	}
+}
+
+func expectNames(t *testing.T, what string, Got []string, Expected ...string) {
+	t.Helper()
+	sort.Strings(Got)
+	sort.Strings(Expected)
+	if fmt.Sprint(Got) != fmt.Sprint(Expected) {
+		t.Errorf("%s: got %v, expected %v", what, Got, Expected)
+	}
+}
+
+func testSaveAndOpen(t *testing.T, Storage interfaces.StorageInterface) {
+	mustSave(t, Storage, "image.png", "first")
+	expectContent(t, Storage, "image.png", "first")
+
+	//Saving again replaces the file
+	mustSave(t, Storage, "image.png", "second version")
+	expectContent(t, Storage, "image.png", "second version")
+	fileInfo, err := Storage.Stat("image.png")
+	if err != nil || fileInfo.Size != int64(len("second version")) || fileInfo.ModTime.IsZero() {
+		t.Errorf("Stat: %+v, %v", fileInfo, err)
+	}
+
+	//Opened files can seek, as ranged requests need to
+	file, err := Storage.Open("image.png")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer file.Close()
+	if _, err := file.Seek(7, io.SeekStart); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	data, err := io.ReadAll(file)
+	if err != nil || string(data) != "version" {
+		t.Errorf("read after seek: %q, %v", data, err)
+	}
+
+	//Thumbnails live in their own directory
+	mustSave(t, Storage, storage.ThumbnailName("image.png"), "thumbnail")
+	expectContent(t, Storage, storage.ThumbnailName("image.png"), "thumbnail")
+	expectContent(t, Storage, "image.png", "second version")
+
+	if err := Storage.Remove("image.png"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	_, err = Storage.Stat("image.png")
+	expectMissing(t, "Stat of a removed file", err)
+}
+
+func testMissing(t *testing.T, Storage interfaces.StorageInterface) {
+	_, err := Storage.Open("missing.png")
+	expectMissing(t, "Open", err)
+	_, err = Storage.Stat("missing.png")
+	expectMissing(t, "Stat", err)
+	expectMissing(t, "Remove", Storage.Remove("missing.png"))
+	expectMissing(t, "Rename", Storage.Rename("missing.png", "other.png"))
+
+	//A directory is not a file
+	mustSave(t, Storage, storage.ThumbnailName("image.png"), "thumbnail")
+	_, err = Storage.Stat(storage.ThumbnailDirectory)
+	expectMissing(t, "Stat of a directory", err)
+}
+
+func testRename(t *testing.T, Storage interfaces.StorageInterface) {
+	mustSave(t, Storage, "old.png", "image")
+	mustSave(t, Storage, "taken.png", "replaced")
+	if err := Storage.Rename("old.png", "taken.png"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	expectContent(t, Storage, "taken.png", "image")
+	_, err := Storage.Stat("old.png")
+	expectMissing(t, "Stat of the old name", err)
+
+	//Renaming into a directory that does not exist yet
+	if err := Storage.Rename("taken.png", "a/b/new.png"); err != nil {
+		t.Fatalf("Rename into a new directory: %v", err)
+	}
+	expectContent(t, Storage, "a/b/new.png", "image")
+}
+
+func testList(t *testing.T, Storage interfaces.StorageInterface) {
+	top, err := Storage.List("")
+	if err != nil {
+		t.Fatalf("List of empty storage: %v", err)
+	}
+	expectNames(t, "List of empty storage", top)
+
+	mustSave(t, Storage, "one.png", "1")
+	mustSave(t, Storage, "two.webm", "2")
+	mustSave(t, Storage, storage.ThumbnailName("one.png"), "thumbnail")
+	mustSave(t, Storage, storage.ThumbnailName("two.webm"), "thumbnail")
+
+	//Listing does not descend into directories
+	top, err = Storage.List("")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	expectNames(t, "List of top level", top, "one.png", "two.webm")
+	thumbnails, err := Storage.List(storage.ThumbnailDirectory)
+	if err != nil {
+		t.Fatalf("List of thumbnails: %v", err)
+	}
+	expectNames(t, "List of thumbnails", thumbnails, storage.ThumbnailName("one.png"), storage.ThumbnailName("two.webm"))
+
+	//Directories are listed separately
+	mustSave(t, Storage, "ab/cd/abcd.png", "sharded")
+	directories, err := Storage.ListDirectories("")
+	if err != nil {
+		t.Fatalf("ListDirectories: %v", err)
+	}
+	expectNames(t, "ListDirectories of top level", directories, "ab", storage.ThumbnailDirectory)
+	directories, err = Storage.ListDirectories("ab")
+	if err != nil {
+		t.Fatalf("ListDirectories of a sub directory: %v", err)
+	}
+	expectNames(t, "ListDirectories of a sub directory", directories, "ab/cd")
+	directories, err = Storage.ListDirectories("ab/cd")
+	if err != nil {
+		t.Fatalf("ListDirectories of a directory with only files: %v", err)
+	}
+	expectNames(t, "ListDirectories of a directory with only files", directories)
+}
+
+func testListTree(t *testing.T, Storage interfaces.StorageInterface) {
+	previous := storage.StorageInterface
+	storage.StorageInterface = Storage
+	defer func() { storage.StorageInterface = previous }()
+
+	flat := storage.LayoutLocation("abcdef.png", storage.LayoutFlat)
+	sharded := storage.LayoutLocation("012345.webm", storage.LayoutSharded)
+	for _, name := range []string{flat, sharded} {
+		mustSave(t, Storage, name, "image")
+		mustSave(t, Storage, storage.ThumbnailName(name), "thumbnail")
+	}
+	mustSave(t, Storage, storage.QuarantineName("bad.png"), "quarantined")
+
+	images, err := storage.ListImages()
+	if err != nil {
+		t.Fatalf("ListImages: %v", err)
+	}
+	expectNames(t, "ListImages", images, "abcdef.png", "01/23/012345.webm")
+	thumbnails, err := storage.ListThumbnails()
+	if err != nil {
+		t.Fatalf("ListThumbnails: %v", err)
+	}
+	expectNames(t, "ListThumbnails", thumbnails, "thumbs/abcdef.png.png", "thumbs/01/23/012345.webm.png")
+
+	//Moving the sharded image back to the flat layout
+	if err := Storage.Rename(sharded, storage.LayoutLocation(sharded, storage.LayoutFlat)); err != nil {
+		t.Fatalf("Rename out of a shard: %v", err)
+	}
+	images, err = storage.ListImages()
+	if err != nil {
+		t.Fatalf("ListImages after moving: %v", err)
+	}
+	expectNames(t, "ListImages after moving", images, "abcdef.png", "012345.webm")
+	//Emptied directories do not linger
+	directories, err := Storage.ListDirectories("")
+	if err != nil {
+		t.Fatalf("ListDirectories after moving: %v", err)
+	}
+	expectNames(t, "ListDirectories after moving", directories, storage.QuarantineDirectory, storage.ThumbnailDirectory)
+}
+
+func testNames(t *testing.T, Storage interfaces.StorageInterface) {
+	//Names cannot reach outside of storage
+	mustSave(t, Storage, "../escape.png", "contained")
+	expectContent(t, Storage, "escape.png", "contained")
+	mustSave(t, Storage, "/rooted.png", "contained")
+	expectContent(t, Storage, "rooted.png", "contained")
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/jobs"
+	"go-image-board/media"
+	"go-image-board/routers"
+	"go-image-board/storage"
+	"io"
+	"strings"
+	"testing"
+)
+
+//testArchive returns a zip holding each of Files, by name
+func testArchive(t *testing.T, Names []string, Files [][]byte) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for index, name := range Names {
+		fileWriter, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fileWriter.Write(Files[index])
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestUploadArchive(t *testing.T) {
+	setupImportTest(t)
+	//Thumbnails are still being made after the uploads return, and must finish before the image directory is removed
+	t.Cleanup(jobs.Wait)
+	config.Configuration.MaxArchiveEntries = 10
+	config.Configuration.MaxArchiveBytes = 1 << 20
+	userID, err := database.DBInterface.GetUserID("importer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := interfaces.UserInformation{Name: "importer", ID: userID}
+
+	comic := testArchive(t,
+		[]string{"pages/page10.png", "pages/page2.png", "pages/page1.png", "__MACOSX/pages/._page1.png", "ComicInfo.xml", "notes.txt"},
+		[][]byte{testPNG(t, 10), testPNG(t, 2), testPNG(t, 1), []byte("resource fork"), []byte("<ComicInfo/>"), []byte("not an image")})
+	_, _, err = routers.HandleImageUploadRequest(nil, user, "", "comic", []routers.UploadingFile{{Name: "My Comic.cbz", Data: comic}, {Name: "cover.png", Data: testPNG(t, 50)}}, "")
+	if err == nil || strings.Contains(err.Error(), "notes.txt") == false {
+		t.Errorf("expected notes.txt to be reported, got %v", err)
+	}
+	collection, err := database.DBInterface.GetCollectionByName("My Comic")
+	if err != nil {
+		t.Fatalf("GetCollectionByName: %v", err)
+	}
+	members, _, err := database.DBInterface.GetCollectionMembers(collection.ID, 0, 10)
+	if err != nil || len(members) != 3 {
+		t.Fatalf("GetCollectionMembers: %+v, %v", members, err)
+	}
+	for index, shade := range []uint8{1, 2, 10} {
+		if members[index].Location != storage.ImageLocation(hashName(t, testPNG(t, shade))) {
+			t.Errorf("page %d is %s, expected the page with shade %d", index+1, members[index].Location, shade)
+		}
+		if tags := imageTagNames(t, members[index].ID); tags != "comic" {
+			t.Errorf("page %d tags: %q", index+1, tags)
+		}
+	}
+	//Files outside the archive are not put in its collection
+	if _, count, err := database.DBInterface.SearchImages(nil, 0, 10); err != nil || count != 4 {
+		t.Errorf("images after upload: %d, %v", count, err)
+	}
+
+	//A requested collection is used instead of the archive's name
+	second := testArchive(t, []string{"b.png", "a.png"}, [][]byte{testPNG(t, 60), testPNG(t, 61)})
+	if _, _, err := routers.HandleImageUploadRequest(nil, user, "Chapter Two", "", []routers.UploadingFile{{Name: "ch2.zip", Data: second}}, ""); err != nil {
+		t.Errorf("HandleImageUploadRequest: %v", err)
+	}
+	if collection, err := database.DBInterface.GetCollectionByName("Chapter Two"); err != nil || collection.Members != 2 {
+		t.Errorf("requested collection: %+v, %v", collection, err)
+	}
+	if _, err := database.DBInterface.GetCollectionByName("ch2"); err == nil {
+		t.Errorf("collection named after the archive was created as well as the requested one")
+	}
+
+	//Archives that would escape or unpack too far are refused without uploading anything
+	unsafe := testArchive(t, []string{"ok.png", "../../evil.png"}, [][]byte{testPNG(t, 70), testPNG(t, 71)})
+	bomb := testArchive(t, []string{"zeros.png"}, [][]byte{make([]byte, 2<<20)})
+	for _, archive := range []routers.UploadingFile{{Name: "unsafe.zip", Data: unsafe}, {Name: "bomb.zip", Data: bomb}} {
+		if _, _, err := routers.HandleImageUploadRequest(nil, user, "", "", []routers.UploadingFile{archive}, ""); err == nil || strings.Contains(err.Error(), archive.Name+" could not be unpacked") == false {
+			t.Errorf("%s: expected it to be refused, got %v", archive.Name, err)
+		}
+	}
+	if _, count, err := database.DBInterface.SearchImages(nil, 0, 10); err != nil || count != 6 {
+		t.Errorf("images after refused archives: %d, %v", count, err)
+	}
+	if _, err := database.DBInterface.GetCollectionByName("unsafe"); err == nil {
+		t.Errorf("refused archive created a collection")
+	}
+}
+
+//failingInsertDB is a database that fails to add any image after the first few, as if the connection dropped part way through an upload
+type failingInsertDB struct {
+	interfaces.DBInterface
+	//Inserts is how many more images may be added before inserts fail, and is shared with transactions
+	Inserts *int
+}
+
+func (DBConnection failingInsertDB) RunInTransaction(Work func(Transaction interfaces.DBInterface) error) error {
+	return DBConnection.DBInterface.RunInTransaction(func(Transaction interfaces.DBInterface) error {
+		return Work(failingInsertDB{DBInterface: Transaction, Inserts: DBConnection.Inserts})
+	})
+}
+
+func (DBConnection failingInsertDB) NewImage(ImageName string, ImageFileName string, OwnerID uint64, Source string) (uint64, error) {
+	if *DBConnection.Inserts <= 0 {
+		return 0, errors.New("connection lost")
+	}
+	*DBConnection.Inserts--
+	return DBConnection.DBInterface.NewImage(ImageName, ImageFileName, OwnerID, Source)
+}
+
+func TestUploadRollback(t *testing.T) {
+	setupImportTest(t)
+	t.Cleanup(jobs.Wait)
+	userID, err := database.DBInterface.GetUserID("importer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := interfaces.UserInformation{Name: "importer", ID: userID}
+	inserts := 1
+	database.DBInterface = failingInsertDB{DBInterface: database.DBInterface, Inserts: &inserts}
+
+	files := [][]byte{testPNG(t, 1), testPNG(t, 2)}
+	if _, _, err := routers.HandleImageUploadRequest(nil, user, "", "rolledback", []routers.UploadingFile{{Name: "a.png", Data: files[0]}, {Name: "b.png", Data: files[1]}}, ""); err == nil {
+		t.Fatal("upload with a failing second insert did not fail")
+	}
+	if inserts != 0 {
+		t.Errorf("first image was not inserted before the failure, %d inserts left", inserts)
+	}
+	if _, count, err := database.DBInterface.SearchImages(nil, 0, 10); err != nil || count != 0 {
+		t.Errorf("images after failed upload: %d, %v", count, err)
+	}
+	if _, err := database.DBInterface.GetTagByName("rolledback"); err == nil {
+		t.Errorf("tag from failed upload was kept")
+	}
+	for index, file := range files {
+		if exists, err := storage.Exists(storage.ImageLocation(hashName(t, file))); err != nil || exists {
+			t.Errorf("file %d from failed upload was left in storage: %v", index+1, err)
+		}
+	}
+}
+
+func TestDownloadCollectionArchive(t *testing.T) {
+	setupImportTest(t)
+	t.Cleanup(jobs.Wait)
+	config.Configuration.MaxArchiveEntries = 10
+	config.Configuration.MaxArchiveBytes = 1 << 20
+	userID, err := database.DBInterface.GetUserID("importer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := interfaces.UserInformation{Name: "importer", ID: userID}
+	pages := [][]byte{testPNG(t, 1), testPNG(t, 2), testPNG(t, 3)}
+	comic := testArchive(t, []string{"page1.png", "page2.png", "page3.png"}, pages)
+	if _, _, err := routers.HandleImageUploadRequest(nil, user, "", "comic funny", []routers.UploadingFile{{Name: "Round Trip.cbz", Data: comic}}, ""); err != nil {
+		t.Fatalf("HandleImageUploadRequest: %v", err)
+	}
+	collection, err := database.DBInterface.GetCollectionByName("Round Trip")
+	if err != nil {
+		t.Fatalf("GetCollectionByName: %v", err)
+	}
+	if err := database.DBInterface.UpdateCollection(collection.ID, collection.Name, "A <short> story"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.DBInterface.FixCollectionTags(collection.ID); err != nil {
+		t.Fatal(err)
+	}
+	//Reordered, so the download follows the collection rather than the upload
+	members, _, _ := database.DBInterface.GetCollectionMembers(collection.ID, 0, 0)
+	if err := database.DBInterface.UpdateCollectionMember(collection.ID, members[0].ID, 10); err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]byte{pages[1], pages[2], pages[0]}
+
+	if _, err := routers.NewCollectionArchive(collection.ID, "rar"); err != routers.ErrUnknownArchiveFormat {
+		t.Errorf("NewCollectionArchive with rar: %v", err)
+	}
+	if _, err := routers.NewCollectionArchive(collection.ID+100, "zip"); err == nil {
+		t.Errorf("NewCollectionArchive of a missing collection did not fail")
+	}
+	for _, format := range []string{"", "zip"} {
+		archive, err := routers.NewCollectionArchive(collection.ID, format)
+		if err != nil {
+			t.Fatalf("NewCollectionArchive(%q): %v", format, err)
+		}
+		var buffer bytes.Buffer
+		if err := archive.Stream(&buffer); err != nil {
+			t.Fatalf("Stream(%q): %v", format, err)
+		}
+		reader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+		if err != nil {
+			t.Fatalf("%q download is not a zip: %v", format, err)
+		}
+		var names []string
+		files := make(map[string][]byte)
+		for _, file := range reader.File {
+			names = append(names, file.Name)
+			fileReader, _ := file.Open()
+			files[file.Name], _ = io.ReadAll(fileReader)
+			fileReader.Close()
+		}
+		manifest := "ComicInfo.xml"
+		if format == "zip" {
+			manifest = routers.CollectionManifestName
+		}
+		if strings.Join(names, " ") != manifest+" 001.png 002.png 003.png" || archive.FileName() != "Round Trip."+archive.Format {
+			t.Errorf("%q download %s holds %q", format, archive.FileName(), names)
+		}
+		for index, page := range expected {
+			if bytes.Equal(files[names[index+1]], page) == false {
+				t.Errorf("%q download page %d is not the member in that place", format, index+1)
+			}
+		}
+		for _, detail := range []string{"Round Trip", "short", "comic", "funny"} {
+			if strings.Contains(string(files[manifest]), detail) == false {
+				t.Errorf("%q manifest is missing %q: %s", format, detail, files[manifest])
+			}
+		}
+
+		//Downloads can be uploaded again, without the manifest being taken for a page
+		entries, err := media.ReadZip(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()), media.ZipLimits{MaxEntries: 10, MaxBytes: 1 << 20, MaxRatio: 100})
+		if err != nil || len(entries) != 3 {
+			t.Errorf("ReadZip of %q download: %d entries, %v", format, len(entries), err)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"errors"
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/interfaces"
+	"go-image-board/logging"
+	"go-image-board/routers"
+	"go-image-board/storage"
+	"image/png"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+)
+
+//hashNamePattern matches names given by routers.GetNewImageName, the SHA-256 of the content followed by the original extension
+var hashNamePattern = regexp.MustCompile(`^[0-9a-f]{64}(\.[^.]*)?$`)
+
+//verifyReport counts what verifyStorage found, and what it repaired
+type verifyReport struct {
+	Images            uint64
+	Corrupt           uint64
+	NotHashNamed      uint64
+	MissingFiles      uint64
+	OrphanFiles       uint64
+	MissingThumbnails uint64
+	StaleThumbnails   uint64
+	OrphanThumbnails  uint64
+	MissingdHashes    uint64
+	Repaired          uint64
+	RepairFailed      uint64
+}
+
+//String lists the counts for logging
+func (Report verifyReport) String() string {
+	return "images: " + strconv.FormatUint(Report.Images, 10) +
+		" corrupt: " + strconv.FormatUint(Report.Corrupt, 10) +
+		" not named by hash: " + strconv.FormatUint(Report.NotHashNamed, 10) +
+		" missing files: " + strconv.FormatUint(Report.MissingFiles, 10) +
+		" orphan files: " + strconv.FormatUint(Report.OrphanFiles, 10) +
+		" missing thumbnails: " + strconv.FormatUint(Report.MissingThumbnails, 10) +
+		" stale thumbnails: " + strconv.FormatUint(Report.StaleThumbnails, 10) +
+		" orphan thumbnails: " + strconv.FormatUint(Report.OrphanThumbnails, 10) +
+		" missing dhashes: " + strconv.FormatUint(Report.MissingdHashes, 10) +
+		" repaired: " + strconv.FormatUint(Report.Repaired, 10) +
+		" repairs failed: " + strconv.FormatUint(Report.RepairFailed, 10)
+}
+
+//verifyStorage re-hashes every image, and checks images, thumbnails, and dHashes against the database
+//With Repair, thumbnails and dHashes are regenerated, and corrupt and orphaned files are moved to the quarantine directory rather than deleted
+func verifyStorage(Repair bool) verifyReport {
+	var report verifyReport
+	logging.WriteLog(logging.LogLevelInfo, "verifyUtility/verifyStorage", "0", logging.ResultInfo, []string{"Verifying storage, repair", strconv.FormatBool(Repair)})
+	//Every location in the database, so files without a row can be found without asking for each
+	knownLocations := make(map[string]bool)
+	err := forEachImage(database.DBInterface, func(imageInfo interfaces.ImageInformation) error {
+		report.Images++
+		knownLocations[imageInfo.Location] = true
+		verifyImage(imageInfo, Repair, &report)
+		if report.Images%config.Configuration.PageStride == 0 {
+			logging.WriteLog(logging.LogLevelInfo, "verifyUtility/verifyStorage", "0", logging.ResultInfo, []string{"Verified", strconv.FormatUint(report.Images, 10), "images"})
+		}
+		return nil
+	})
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "verifyUtility/verifyStorage", "0", logging.ResultFailure, []string{"Failed to query for images", err.Error()})
+		return report
+	}
+
+	files, err := storage.ListImages()
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "verifyUtility/verifyStorage", "0", logging.ResultFailure, []string{"Failed to get images from storage", err.Error()})
+		return report
+	}
+	for _, file := range files {
+		if knownLocations[file] == false {
+			report.OrphanFiles++
+			logging.WriteLog(logging.LogLevelWarning, "verifyUtility/verifyStorage", "0", logging.ResultInfo, []string{"File has no image in the database", file})
+			if Repair {
+				quarantineFile(file, &report)
+			}
+		}
+	}
+	thumbnails, err := storage.ListThumbnails()
+	if err != nil {
+		logging.WriteLog(logging.LogLevelError, "verifyUtility/verifyStorage", "0", logging.ResultFailure, []string{"Failed to get thumbnails from storage", err.Error()})
+		return report
+	}
+	for _, thumbnail := range thumbnails {
+		imageName, ok := storage.ImageNameFromThumbnail(thumbnail)
+		if ok == false {
+			continue //Not a thumbnail, leave it alone
+		}
+		if storage.IsOutdatedThumbnail(thumbnail) {
+			report.StaleThumbnails++
+			logging.WriteLog(logging.LogLevelWarning, "verifyUtility/verifyStorage", "0", logging.ResultInfo, []string{"Thumbnail is for a size or format that is no longer configured", thumbnail})
+			if Repair {
+				recordRepair(storage.StorageInterface.Remove(thumbnail), "remove outdated thumbnail", thumbnail, &report)
+			}
+			continue
+		}
+		if knownLocations[imageName] == false {
+			report.OrphanThumbnails++
+			logging.WriteLog(logging.LogLevelWarning, "verifyUtility/verifyStorage", "0", logging.ResultInfo, []string{"Thumbnail has no image in the database", thumbnail})
+			if Repair {
+				quarantineFile(thumbnail, &report)
+			}
+		}
+	}
+	logging.WriteLog(logging.LogLevelInfo, "verifyUtility/verifyStorage", "0", logging.ResultSuccess, []string{"Finished verifying storage.", report.String()})
+	return report
+}
+
+//verifyImage checks one image's file, thumbnail, and dHash, adding what it finds to Report
+func verifyImage(ImageInfo interfaces.ImageInformation, Repair bool, Report *verifyReport) {
+	imageStat, err := storage.StorageInterface.Stat(ImageInfo.Location)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			Report.MissingFiles++
+			logging.WriteLog(logging.LogLevelWarning, "verifyUtility/verifyImage", "0", logging.ResultInfo, []string{"Image has no file", strconv.FormatUint(ImageInfo.ID, 10), ImageInfo.Location})
+		} else {
+			logging.WriteLog(logging.LogLevelError, "verifyUtility/verifyImage", "0", logging.ResultFailure, []string{"Failed to stat image, it will be skipped", ImageInfo.Location, err.Error()})
+		}
+		return
+	}
+
+	if hashNamePattern.MatchString(path.Base(ImageInfo.Location)) == false {
+		//Names from before the current naming convention, -renameonly gives them hash names
+		Report.NotHashNamed++
+		logging.WriteLog(logging.LogLevelInfo, "verifyUtility/verifyImage", "0", logging.ResultInfo, []string{"Image is not named by hash and cannot be checked, use -renameonly", ImageInfo.Location})
+	} else {
+		file, err := storage.StorageInterface.Open(ImageInfo.Location)
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "verifyUtility/verifyImage", "0", logging.ResultFailure, []string{"Failed to open image, it will be skipped", ImageInfo.Location, err.Error()})
+			return
+		}
+		hashName, err := routers.GetNewImageName(ImageInfo.Location, file)
+		file.Close()
+		if err != nil {
+			logging.WriteLog(logging.LogLevelError, "verifyUtility/verifyImage", "0", logging.ResultFailure, []string{"Failed to hash image, it will be skipped", ImageInfo.Location, err.Error()})
+			return
+		}
+		if hashName != path.Base(ImageInfo.Location) {
+			Report.Corrupt++
+			logging.WriteLog(logging.LogLevelWarning, "verifyUtility/verifyImage", "0", logging.ResultInfo, []string{"Image content does not match its name", strconv.FormatUint(ImageInfo.ID, 10), ImageInfo.Location, "hashes to", hashName})
+			if Repair {
+				quarantineFile(ImageInfo.Location, Report)
+				if exists, _ := storage.Exists(storage.ThumbnailName(ImageInfo.Location)); exists {
+					quarantineFile(storage.ThumbnailName(ImageInfo.Location), Report)
+				}
+				storage.RemoveSizedThumbnails(ImageInfo.Location)
+				storage.RemoveTranscodes(ImageInfo.Location)
+			}
+			return //Thumbnails and dHashes made from a corrupt file would be wrong too
+		}
+	}
+
+	thumbnailName := storage.ThumbnailName(ImageInfo.Location)
+	thumbnailProblem := ""
+	thumbnailStat, err := storage.StorageInterface.Stat(thumbnailName)
+	if err != nil && errors.Is(err, fs.ErrNotExist) && routers.CanGenerateThumbnail(ImageInfo.Location) {
+		Report.MissingThumbnails++
+		thumbnailProblem = "Image has no thumbnail"
+	} else if err == nil && thumbnailStale(thumbnailName, thumbnailStat, imageStat) {
+		Report.StaleThumbnails++
+		thumbnailProblem = "Thumbnail is older than its image, or larger than MaxThumbnailWidth and MaxThumbnailHeight"
+	} else if err != nil && errors.Is(err, fs.ErrNotExist) == false {
+		logging.WriteLog(logging.LogLevelError, "verifyUtility/verifyImage", "0", logging.ResultFailure, []string{"Failed to stat thumbnail", thumbnailName, err.Error()})
+	}
+	if thumbnailProblem != "" {
+		logging.WriteLog(logging.LogLevelWarning, "verifyUtility/verifyImage", "0", logging.ResultInfo, []string{thumbnailProblem, ImageInfo.Location})
+		if Repair {
+			recordRepair(routers.GenerateThumbnail(ImageInfo.Location), "regenerate thumbnail", ImageInfo.Location, Report)
+		}
+	}
+
+	if routers.CanGeneratedHash(ImageInfo.Location) {
+		if _, _, err := database.DBInterface.GetImagedHash(ImageInfo.ID); err != nil {
+			Report.MissingdHashes++
+			logging.WriteLog(logging.LogLevelWarning, "verifyUtility/verifyImage", "0", logging.ResultInfo, []string{"Image has no dHash", ImageInfo.Location})
+			if Repair {
+				recordRepair(routers.GeneratedHash(ImageInfo.Location, ImageInfo.ID), "regenerate dHash", ImageInfo.Location, Report)
+			}
+		}
+	}
+}
+
+//thumbnailStale returns whether a thumbnail predates its image, or is larger than thumbnails are now configured to be
+func thumbnailStale(ThumbnailName string, ThumbnailStat interfaces.StorageFileInfo, ImageStat interfaces.StorageFileInfo) bool {
+	if ThumbnailStat.ModTime.Before(ImageStat.ModTime) {
+		return true
+	}
+	file, err := storage.StorageInterface.Open(ThumbnailName)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	thumbnailConfig, err := png.DecodeConfig(file)
+	if err != nil {
+		return true //Not a readable thumbnail
+	}
+	return uint(thumbnailConfig.Width) > config.Configuration.MaxThumbnailWidth || uint(thumbnailConfig.Height) > config.Configuration.MaxThumbnailHeight
+}
+
+//quarantineFile moves a file into the quarantine directory, keeping its name beneath it so it can be inspected or put back by hand
+func quarantineFile(Name string, Report *verifyReport) {
+	recordRepair(storage.StorageInterface.Rename(Name, storage.QuarantineName(Name)), "quarantine", Name, Report)
+}
+
+//recordRepair counts and logs the result of a repair
+func recordRepair(Err error, Action string, Name string, Report *verifyReport) {
+	if Err != nil {
+		Report.RepairFailed++
+		logging.WriteLog(logging.LogLevelError, "verifyUtility/recordRepair", "0", logging.ResultFailure, []string{"Failed to " + Action, Name, Err.Error()})
+		return
+	}
+	Report.Repaired++
+	logging.WriteLog(logging.LogLevelInfo, "verifyUtility/recordRepair", "0", logging.ResultSuccess, []string{"Repaired, " + Action, Name})
+}
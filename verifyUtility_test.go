@@ -0,0 +1,69 @@
+package main
+
+import (
+	"go-image-board/config"
+	"go-image-board/database"
+	"go-image-board/storage"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyStorage(t *testing.T) {
+	setupImportTest(t)
+	importPath := t.TempDir()
+	writeTestFile(t, importPath, "a.png", testPNG(t, 10))
+	writeTestFile(t, importPath, "b.png", testPNG(t, 20))
+	writeTestFile(t, importPath, "c.png", testPNG(t, 30))
+	importDirectory(importPath, "importer", filepath.Join(t.TempDir(), "progress.tsv"), false)
+
+	if report := verifyStorage(false); report != (verifyReport{Images: 3}) {
+		t.Fatalf("report of a healthy board: %s", report)
+	}
+
+	corrupt, err := database.DBInterface.GetImageByFileName(hashName(t, testPNG(t, 10)))
+	if err != nil {
+		t.Fatalf("GetImageByFileName: %v", err)
+	}
+	unthumbnailed, err := database.DBInterface.GetImageByFileName(hashName(t, testPNG(t, 20)))
+	if err != nil {
+		t.Fatalf("GetImageByFileName: %v", err)
+	}
+	writeTestFile(t, config.Configuration.ImageDirectory, corrupt.Location, testPNG(t, 40))
+	if err := os.Remove(filepath.Join(config.Configuration.ImageDirectory, filepath.FromSlash(storage.ThumbnailName(unthumbnailed.Location)))); err != nil {
+		t.Fatal(err)
+	}
+	orphanName := hashName(t, testPNG(t, 50))
+	writeTestFile(t, config.Configuration.ImageDirectory, orphanName, testPNG(t, 50))
+	writeTestFile(t, config.Configuration.ImageDirectory, storage.ThumbnailName("gone.png"), testPNG(t, 60))
+
+	report := verifyStorage(false)
+	if report.Corrupt != 1 || report.MissingThumbnails != 1 || report.OrphanFiles != 1 || report.OrphanThumbnails != 1 || report.Repaired != 0 {
+		t.Fatalf("report of a damaged board: %s", report)
+	}
+	if exists, _ := storage.Exists(corrupt.Location); exists == false {
+		t.Fatalf("verifying without repair moved a corrupt file")
+	}
+
+	//Corrupt image, its thumbnail, the orphan, and the orphaned thumbnail are quarantined, and one thumbnail regenerated
+	report = verifyStorage(true)
+	if report.Repaired != 5 || report.RepairFailed != 0 {
+		t.Fatalf("report of repair: %s", report)
+	}
+	for _, name := range []string{corrupt.Location, storage.ThumbnailName(corrupt.Location), orphanName, storage.ThumbnailName("gone.png")} {
+		if exists, _ := storage.Exists(name); exists {
+			t.Errorf("%s was not moved", name)
+		}
+		if exists, _ := storage.Exists(storage.QuarantineName(name)); exists == false {
+			t.Errorf("%s is not in quarantine", name)
+		}
+	}
+	if exists, _ := storage.Exists(storage.ThumbnailName(unthumbnailed.Location)); exists == false {
+		t.Errorf("thumbnail was not regenerated")
+	}
+
+	//The corrupt image is now missing its file, everything else is repaired, and quarantine is not reported
+	if report := verifyStorage(false); report != (verifyReport{Images: 3, MissingFiles: 1}) {
+		t.Errorf("report after repair: %s", report)
+	}
+}